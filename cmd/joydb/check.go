@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// runCheck runs `joydb check`, which verifies the files of databases and
+// their WAL and lists every problem found, and returns the exit code: 1 if
+// a problem remains
+// Checking does not lock the databases, so a running server can keep them
+// open; -repair locks each database while repairing it.
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: joydb check [options] [DATABASE...]")
+		fmt.Fprintln(flags.Output(), "Checks the named databases, or all of them.")
+		flags.PrintDefaults()
+	}
+	basePath := flags.String("databases", "databases", "Directory holding the databases")
+	archiveRoot := flags.String("wal-archive", defaultWALArchive, "Directory WAL segments are archived to")
+	repair := flags.Bool("repair", false, "Apply the safe fixes: finish interrupted saves, correct row_count, last_insert_id and table lists, drop unreadable statistics and truncate a torn WAL tail")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	slog.SetDefault(quietLogger())
+
+	storageEngine, err := engine.NewFormatEngine(engine.StorageJSON, page.DefaultBufferPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check: %v\n", err)
+		return 1
+	}
+	registry := manager.NewRegistry(*basePath, storageEngine)
+	registry.EnableWAL(*archiveRoot, wal.DefaultSegmentSize)
+	defer registry.Close()
+
+	names := flags.Args()
+	if len(names) == 0 {
+		if names, err = registry.List(); err != nil {
+			fmt.Fprintf(os.Stderr, "check: %v\n", err)
+			return 1
+		}
+	}
+
+	code := 0
+	for _, name := range names {
+		report, err := registry.Check(name, *repair)
+		if err != nil {
+			if errors.Is(err, manager.ErrLocked) {
+				err = fmt.Errorf("%w; stop it to repair the database, or check without -repair", err)
+			}
+			fmt.Fprintf(os.Stderr, "check: database '%s': %v\n", name, err)
+			code = 1
+			continue
+		}
+
+		fmt.Printf("%s: %d tables, %d rows, %d WAL segments\n", name, report.Tables, report.Rows, report.Segments)
+		for _, p := range report.Problems {
+			switch {
+			case p.Repaired:
+				fmt.Printf("  %s: %s (repaired: %s)\n", p.Object, p.Message, p.Fix)
+			case p.Fix != "":
+				fmt.Printf("  %s: %s (-repair will %s)\n", p.Object, p.Message, p.Fix)
+			default:
+				fmt.Printf("  %s: %s\n", p.Object, p.Message)
+			}
+		}
+		if remaining := len(report.Remaining()); remaining > 0 {
+			fmt.Printf("%s: %d problems remain\n", name, remaining)
+			code = 1
+		} else {
+			fmt.Printf("%s: ok\n", name)
+		}
+	}
+	return code
+}
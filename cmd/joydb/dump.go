@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	queryEngine "github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/dump"
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+)
+
+// quietLogger logs warnings and errors to stderr, keeping stdout for the
+// output of dump and load
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+}
+
+// runDump runs `joydb dump`, which writes databases as SQL scripts that
+// `joydb load` or SOURCE recreate them from, and returns the exit code
+// The databases are read without locking them, so a running server can
+// keep them open; what is dumped is what it last saved.
+func runDump(args []string) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: joydb dump [options] [DATABASE...]")
+		fmt.Fprintln(flags.Output(), "Dumps the named databases, or all of them, as SQL.")
+		flags.PrintDefaults()
+	}
+	basePath := flags.String("databases", "databases", "Directory holding the databases")
+	output := flags.String("o", "", "File to write the dump to (default: standard output)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	slog.SetDefault(quietLogger())
+
+	storageEngine, err := engine.NewFormatEngine(engine.StorageJSON, page.DefaultBufferPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+		return 1
+	}
+	registry := manager.NewReadOnlyRegistry(*basePath, storageEngine)
+	defer registry.Close()
+
+	names := flags.Args()
+	if len(names) == 0 {
+		if names, err = registry.List(); err != nil {
+			fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+			return 1
+		}
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+			return 1
+		}
+	}
+	w := bufio.NewWriter(out)
+
+	for i, name := range names {
+		db, err := registry.Get(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dump: database '%s': %v\n", name, err)
+			return 1
+		}
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		if err := dump.Write(w, db); err != nil {
+			fmt.Fprintf(os.Stderr, "dump: database '%s': %v\n", name, err)
+			return 1
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+		return 1
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// runLoad runs `joydb load`, which runs a SQL script such as a dump as one
+// unit and saves the result, and returns the exit code
+// If a statement fails, the changes of the script are undone and nothing
+// is saved.
+func runLoad(args []string) int {
+	flags := flag.NewFlagSet("load", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: joydb load [options] FILE")
+		flags.PrintDefaults()
+	}
+	basePath := flags.String("databases", "databases", "Directory holding the databases")
+	storage := flags.String("storage", engine.StorageJSON, "Storage format of databases created without STORAGE ("+engine.StorageJSON+", "+engine.StoragePage+")")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	slog.SetDefault(quietLogger())
+
+	script, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load: %v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(*basePath, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "load: %v\n", err)
+		return 1
+	}
+
+	storageEngine, err := engine.NewFormatEngine(*storage, page.DefaultBufferPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load: %v\n", err)
+		return 2
+	}
+	registry := manager.NewRegistry(*basePath, storageEngine)
+	defer registry.Close()
+
+	eng := queryEngine.New(nil, registry)
+	result, err := eng.ExecuteScript(context.Background(), string(script))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load: %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	tx := transaction.NewTransaction()
+	registry.SaveAll(tx)
+	tx.Close()
+
+	fmt.Printf("%s (%d rows)\n", result.Message, result.RowsAffected)
+	return 0
+}
@@ -1,28 +1,74 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/leengari/mini-rdbms/databases"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
 	"github.com/leengari/mini-rdbms/internal/infrastructure/logging"
 	"github.com/leengari/mini-rdbms/internal/network"
 	"github.com/leengari/mini-rdbms/internal/repl"
 	"github.com/leengari/mini-rdbms/internal/storage/engine"
 	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/wal"
 )
 
 func main() {
+	// Point-in-time recovery, dumps, loads and checks run on their own and exit
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "restore":
+			os.Exit(runRestore(os.Args[2:]))
+		case "dump":
+			os.Exit(runDump(os.Args[2:]))
+		case "load":
+			os.Exit(runLoad(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		}
+	}
+
 	serverMode := flag.Bool("server", false, "Run in server mode")
 	port := flag.Int("port", 4444, "Port to listen on")
+	strategy := flag.String("strategy", executor.StrategyIterator, "Execution strategy for new sessions ("+strings.Join(executor.StrategyNames(), ", ")+")")
+	storage := flag.String("storage", engine.StorageJSON, "Storage format of new databases ("+engine.StorageJSON+", "+engine.StoragePage+")")
+	flushInterval := flag.Duration("flush-interval", 30*time.Second, "How often changes are saved in the background (0 saves only on shutdown)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long shutdown waits for running queries before cancelling them")
+	readOnly := flag.Bool("read-only", false, "Open databases read-only, alongside the process that has them loaded")
+	bufferPages := flag.Int("buffer-pages", page.DefaultBufferPages, "Pages of page-storage tables cached in memory ("+fmt.Sprint(page.Size)+" bytes each)")
+	walEnabled := flag.Bool("wal", false, "Log changes to a write-ahead log and archive its segments for point-in-time recovery")
+	walArchive := flag.String("wal-archive", defaultWALArchive, "Directory WAL segments are archived to, one subdirectory per database")
+	walSegmentSize := flag.Uint64("wal-segment-size", wal.DefaultSegmentSize, "Size in bytes past which a WAL segment is archived")
 	flag.Parse()
 
+	if *bufferPages < 1 {
+		fmt.Fprintln(os.Stderr, "-buffer-pages must be at least 1")
+		os.Exit(2)
+	}
+	if err := executor.SetDefaultStrategy(*strategy); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Each database is stored in the format recorded in its meta.json
+	storageEngine, err := engine.NewFormatEngine(*storage, *bufferPages)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	logger, closeFn := logging.SetupLogger()
 	defer closeFn()
 
@@ -39,11 +85,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create storage engine (currently JSON, can be swapped for binary)
-	storageEngine := engine.NewJSONEngine()
-
 	// Create Database Registry with storage engine
+	// Databases it loads are locked against other JoyDB processes unless it
+	// is read-only
 	registry := manager.NewRegistry(basePath, storageEngine)
+	if *readOnly {
+		registry = manager.NewReadOnlyRegistry(basePath, storageEngine)
+	}
+	if *walEnabled {
+		registry.EnableWAL(*walArchive, *walSegmentSize)
+	}
+	defer registry.Close()
 
 	// Save all loaded databases on shutdown
 	defer func() {
@@ -53,6 +105,21 @@ func main() {
 		registry.SaveAll(tx)
 	}()
 
+	// SIGINT and SIGTERM shut down gracefully; a second signal kills the
+	// process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if *flushInterval > 0 && !*readOnly {
+		flushCtx, stopFlushing := context.WithCancel(ctx)
+		defer stopFlushing()
+		go registry.FlushEvery(flushCtx, *flushInterval)
+	}
+
 	// Seed 'main' from embedded FS
 	if err := ensureDatabaseSeeded(basePath, databases.Content, "main"); err != nil {
 		slog.Error("Failed to seed main database", "error", err)
@@ -62,10 +129,27 @@ func main() {
 
 	if *serverMode {
 		slog.Info("Starting Server mode...")
-		network.Start(*port, registry)
+		server := network.NewServer(registry)
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			<-ctx.Done()
+			slog.Info("Signal received - draining running queries...")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("queries cancelled at shutdown", "error", err)
+			}
+		}()
+		if err := server.ListenAndServe(*port); err != nil {
+			slog.Error("Server stopped", "error", err)
+			return
+		}
+		// Wait for the running queries before saving
+		<-drained
 	} else {
 		slog.Info("Starting REPL mode...")
-		repl.Start(registry)
+		repl.StartContext(ctx, registry)
 	}
 }
 
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/infrastructure/logging"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/storage/backup"
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// defaultWALArchive is where WAL segments are archived, one directory per
+// database
+const defaultWALArchive = "wal-archive"
+
+// runRestore runs `joydb restore`, which recovers a database from a base
+// backup and its archived WAL up to a log position or a time, and returns
+// the exit code
+func runRestore(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: joydb restore -from BACKUP [-until-lsn N | -until-time TIME] [options]")
+		flags.PrintDefaults()
+	}
+	from := flags.String("from", "", "Base backup directory, written by BACKUP DATABASE with the WAL enabled")
+	name := flags.String("name", "", "Database to restore as, which must not exist (default: the database backed up)")
+	basePath := flags.String("databases", "databases", "Directory holding the databases")
+	archiveRoot := flags.String("wal-archive", defaultWALArchive, "Directory WAL segments were archived to")
+	untilLSN := flags.Uint64("until-lsn", 0, "Replay the transactions committed at or before this LSN (default: all)")
+	untilTime := flags.String("until-time", "", "Replay the transactions committed at or before this RFC 3339 time (default: all)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	target := wal.RecoveryTarget{LSN: *untilLSN}
+	if *untilTime != "" {
+		t, err := time.Parse(time.RFC3339, *untilTime)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "restore: -until-time: %v\n", err)
+			return 2
+		}
+		target.Time = t
+	}
+
+	logger, closeFn := logging.SetupLogger()
+	defer closeFn()
+	slog.SetDefault(logger)
+
+	manifest, err := backup.Verify(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+	if *name == "" {
+		*name = manifest.Database
+	}
+
+	// The log after the backup is in the archive, followed by the active
+	// segment of the database backed up if it still exists
+	segments, err := wal.ListSegments(filepath.Join(*archiveRoot, manifest.Database))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+	active, err := wal.ListSegments(filepath.Join(*basePath, manifest.Database, archive.WALDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+	archived := make(map[string]bool, len(segments))
+	for _, path := range segments {
+		archived[filepath.Base(path)] = true
+	}
+	for _, path := range active {
+		if !archived[filepath.Base(path)] {
+			segments = append(segments, path)
+		}
+	}
+
+	storageEngine, err := engine.NewFormatEngine(engine.StorageJSON, page.DefaultBufferPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+	registry := manager.NewRegistry(*basePath, storageEngine)
+	defer registry.Close()
+
+	_, result, err := registry.RestoreUntil(*name, *from, segments, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+		return 1
+	}
+
+	if result.TransactionsReplay == 0 {
+		fmt.Printf("Database '%s' restored from '%s' at LSN %d; no later transactions to replay\n",
+			*name, *from, result.StopLSN)
+	} else {
+		fmt.Printf("Database '%s' restored from '%s' and recovered to LSN %d, committed %s (%d transactions replayed)\n",
+			*name, *from, result.StopLSN, result.StopTime.Format(time.RFC3339Nano), result.TransactionsReplay)
+	}
+	if (target.LSN != 0 || !target.Time.IsZero()) && !result.TargetReached {
+		fmt.Println("The log ends before the target: every transaction it holds was replayed")
+	}
+	return 0
+}
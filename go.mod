@@ -2,12 +2,14 @@ module github.com/leengari/mini-rdbms
 
 go 1.25.4
 
-require github.com/sokkalf/slog-seq v0.5.1
+require (
+	github.com/google/uuid v1.6.0
+	github.com/sokkalf/slog-seq v0.5.1
+)
 
 require (
 	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
 	go.opentelemetry.io/otel v1.35.0 // indirect
 	go.opentelemetry.io/otel/metric v1.35.0 // indirect
@@ -1,8 +1,50 @@
 package data
 
+import "github.com/leengari/mini-rdbms/internal/index"
+
 // Index is an in-memory index on a single column
+// The backing structure is either a hash map (equality only) or an ordered
+// B+tree (equality, ranges, ordered scans, MIN/MAX)
 type Index struct {
+	Name   string // user-visible name, empty for implicit PK/UNIQUE indexes
 	Column string
-	Data   map[interface{}][]int // value → row positions
 	Unique bool
+	Store  index.Index // value → row positions
+}
+
+// NewIndex creates an empty index of the given kind on column
+func NewIndex(name, column string, kind index.Kind, unique bool) *Index {
+	return &Index{
+		Name:   name,
+		Column: column,
+		Unique: unique,
+		Store:  index.New(kind),
+	}
+}
+
+// Lookup returns the row positions holding value
+func (idx *Index) Lookup(value interface{}) []int {
+	return idx.Store.Lookup(value)
+}
+
+// Add records that the row at pos holds value
+func (idx *Index) Add(value interface{}, pos int) {
+	idx.Store.Insert(value, pos)
+}
+
+// Reset removes every entry, keeping the index definition
+func (idx *Index) Reset() {
+	idx.Store.Clear()
+}
+
+// Kind returns the backing structure type
+func (idx *Index) Kind() index.Kind {
+	return idx.Store.Kind()
+}
+
+// Ordered returns the backing structure as an ordered index
+// Returns false for hash indexes
+func (idx *Index) Ordered() (index.Ordered, bool) {
+	ordered, ok := idx.Store.(index.Ordered)
+	return ordered, ok
 }
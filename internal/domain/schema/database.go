@@ -3,7 +3,8 @@ package schema
 // Database represents a single database on disk
 // (a directory containing table subdirectories)
 type Database struct {
-	Name   string
-	Path   string // filesystem path to database directory
-	Tables map[string]*Table
+	Name    string
+	Path    string // filesystem path to database directory
+	Storage string // storage format recorded in meta.json, "" for JSON
+	Tables  map[string]*Table
 }
@@ -0,0 +1,33 @@
+package schema
+
+import "time"
+
+// TableStatistics holds planner statistics collected by ANALYZE
+// Statistics are a snapshot: they are not updated by INSERT/UPDATE/DELETE and
+// only become accurate again after the next ANALYZE.
+type TableStatistics struct {
+	RowCount   int64
+	AnalyzedAt time.Time
+	Columns    map[string]*ColumnStatistics
+}
+
+// ColumnStatistics describes the value distribution of a single column
+type ColumnStatistics struct {
+	DistinctCount int64       // number of distinct non-NULL values
+	NullFraction  float64     // fraction of rows where the column is NULL
+	Min           interface{} // smallest non-NULL value (nil if all NULL)
+	Max           interface{} // largest non-NULL value (nil if all NULL)
+
+	// Histogram holds equi-depth bucket boundaries: len(Histogram)-1 buckets,
+	// each containing roughly the same number of non-NULL values.
+	// Empty for BOOL columns and columns without values.
+	Histogram []interface{}
+}
+
+// ColumnStats returns statistics for a column, or nil if none were collected
+func (s *TableStatistics) ColumnStats(name string) *ColumnStatistics {
+	if s == nil {
+		return nil
+	}
+	return s.Columns[name]
+}
@@ -17,9 +17,12 @@ type Table struct {
 	Path         string // filesystem path to table directory
 	Schema       *TableSchema
 	Rows         []data.Row
+	Source       RowSource // rows kept on disk, nil once they are in Rows (see LoadRows)
 	Indexes      map[string]*data.Index
 	LastInsertID int64
-	Dirty        bool // tracks if table has unsaved changes
+	Stats        *TableStatistics // collected by ANALYZE, nil if never analyzed
+	Dirty        bool             // tracks if table has unsaved changes
+	changes      uint64           // counts changes, so a save only clears Dirty if none raced it
 }
 
 // MarkDirty marks the table as having unsaved changes
@@ -35,6 +38,31 @@ func (t *Table) MarkDirty() {
 // Use MarkDirty() if you don't hold the lock.
 func (t *Table) MarkDirtyUnsafe() {
 	t.Dirty = true
+	t.changes++
+}
+
+// IsDirty reports whether the table has unsaved changes
+func (t *Table) IsDirty() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.Dirty
+}
+
+// ChangesUnsafe returns the table's change count, to pass to MarkClean once
+// the state read along with it is saved
+// IMPORTANT: Only call this when you already hold the table lock!
+func (t *Table) ChangesUnsafe() uint64 {
+	return t.changes
+}
+
+// MarkClean clears the dirty flag after a save, unless the table changed
+// again after the saved state was read (changes is from ChangesUnsafe)
+func (t *Table) MarkClean(changes uint64) {
+	t.Lock()
+	defer t.Unlock()
+	if t.changes == changes {
+		t.Dirty = false
+	}
 }
 
 // Lock acquires an exclusive lock on the table for write operations
@@ -57,8 +85,59 @@ func (t *Table) RUnlock() {
 	t.mu.RUnlock()
 }
 
+// AdvanceSequence moves the auto-increment sequence of the table, the last
+// key it generated, forward to value and returns the value it replaces
+// A value below the sequence is refused: the keys up to it may be in use
+// and would be generated again.
+func (t *Table) AdvanceSequence(value int64) (int64, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	hasAutoIncrement := false
+	for _, col := range t.Schema.Columns {
+		if col.AutoIncrement && col.PrimaryKey {
+			hasAutoIncrement = true
+			break
+		}
+	}
+	if !hasAutoIncrement {
+		return 0, fmt.Errorf("table %s has no AUTO_INCREMENT column", t.Name)
+	}
+	if value < t.LastInsertID {
+		return 0, fmt.Errorf("cannot move the AUTO_INCREMENT sequence of %s back: it has reached %d", t.Name, t.LastInsertID)
+	}
+
+	previous := t.LastInsertID
+	t.LastInsertID = value
+	t.MarkDirtyUnsafe()
+	return previous, nil
+}
+
+// SetSequence sets the auto-increment sequence of the table, moving it back
+// as well, to undo or replay AdvanceSequence
+func (t *Table) SetSequence(value int64) {
+	t.Lock()
+	defer t.Unlock()
+	t.LastInsertID = value
+	t.MarkDirtyUnsafe()
+}
+
 // Insert adds a new row to the table with full validation and auto-increment support
 func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
+	return t.insert(mutRow, tx, false)
+}
+
+// Reinsert adds back a row that was deleted, keeping its auto-increment value
+// even though the sequence has passed it
+// It undoes a delete, or replays one being undone. The row is validated and
+// checked against unique constraints like any insert.
+func (t *Table) Reinsert(mutRow data.Row, tx *transaction.Transaction) error {
+	return t.insert(mutRow, tx, true)
+}
+
+// insert adds a row; with reuseID a provided auto-increment value may be at
+// or below the sequence, which only ever moves forward
+func (t *Table) insert(mutRow data.Row, tx *transaction.Transaction, reuseID bool) error {
 	row := mutRow.Copy() // prevent mutation of caller's data
 
 	// Acquire write lock for the entire operation
@@ -68,6 +147,9 @@ func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
 	if tx != nil {
 		slog.Debug("Insert operation", "table", t.Name, "tx_id", tx.ID)
 	}
+	if err := t.loadRowsUnsafe(); err != nil {
+		return err
+	}
 
 	// 1. Handle auto-increment primary key FIRST (before validation)
 	var autoIncCol *Column
@@ -95,7 +177,7 @@ func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
 				}
 			}
 			// Prevent sequence conflicts
-			if userID <= t.LastInsertID {
+			if userID <= t.LastInsertID && !reuseID {
 				return &errors.ConstraintError{
 					Table:      t.Name,
 					Column:     autoIncCol.Name,
@@ -109,7 +191,9 @@ func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
 
 		// Set the auto-increment value
 		row.Data[autoIncCol.Name] = nextID
-		t.LastInsertID = nextID
+		if nextID > t.LastInsertID {
+			t.LastInsertID = nextID
+		}
 	} else {
 		// If PK is not auto-increment, it must be provided
 		pkCol := t.Schema.GetPrimaryKeyColumn()
@@ -138,7 +222,7 @@ func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
 		}
 
 		if idx.Unique {
-			if _, found := idx.Data[val]; found {
+			if len(idx.Lookup(val)) > 0 {
 				return &errors.ConstraintError{
 					Table:      t.Name,
 					Column:     colName,
@@ -159,13 +243,17 @@ func (t *Table) Insert(mutRow data.Row, tx *transaction.Transaction) error {
 	// 6. Update all indexes
 	for colName, idx := range t.Indexes {
 		if val, exists := row.Data[colName]; exists {
-			idx.Data[val] = append(idx.Data[val], newRowPos)
+			idx.Add(val, newRowPos)
 		}
 	}
 
 	// 7. Mark table as dirty (has unsaved changes)
 	t.MarkDirtyUnsafe()
 
+	if tx.Recording() {
+		tx.Record(transaction.Change{Type: transaction.ChangeTypeInsert, Table: t.Name, Data: row.Copy().Data})
+	}
+
 	return nil
 }
 
@@ -183,6 +271,23 @@ func (t *Table) SelectAll(tx *transaction.Transaction) []data.Row {
 	return rows
 }
 
+// ScanRows returns the table's current rows without copying them, for
+// reading one at a time
+// A paged table has no rows in memory and is read through Cursor instead.
+// The slice must not be modified. Writers append to Rows or replace it rather
+// than moving rows within it, so the result keeps holding the rows present
+// when it was taken.
+func (t *Table) ScanRows(tx *transaction.Transaction) []data.Row {
+	t.RLock()
+	defer t.RUnlock()
+
+	if tx != nil {
+		slog.Debug("ScanRows operation", "table", t.Name, "tx_id", tx.ID)
+	}
+
+	return t.Rows[:len(t.Rows):len(t.Rows)]
+}
+
 // Select returns rows that match the given predicate
 func (t *Table) Select(predicate func(data.Row) bool, tx *transaction.Transaction) []data.Row {
 	t.RLock()
@@ -216,13 +321,8 @@ func (t *Table) SelectByIndex(colName string, value interface{}, tx *transaction
 		return data.Row{}, false
 	}
 
-	// Convert value to int64 if it's an integer type for comparison
-	if intVal, ok := value.(int); ok {
-		value = int64(intVal)
-	}
-
-	positions, found := idx.Data[value]
-	if !found || len(positions) == 0 {
+	positions := idx.Lookup(value)
+	if len(positions) == 0 {
 		return data.Row{}, false
 	}
 
@@ -238,10 +338,18 @@ func (t *Table) Update(predicate func(data.Row) bool, updates data.Row, tx *tran
 	if tx != nil {
 		slog.Debug("Update operation", "table", t.Name, "tx_id", tx.ID)
 	}
+	if err := t.loadRowsUnsafe(); err != nil {
+		return 0, err
+	}
 
+	recording := tx.Recording()
 	count := 0
 	for i, row := range t.Rows {
 		if predicate(row) {
+			var old data.Row
+			if recording {
+				old = row.Copy()
+			}
 			// Validate each update value against schema
 			for colName, newValue := range updates.Data {
 				// Find column in schema
@@ -262,6 +370,9 @@ func (t *Table) Update(predicate func(data.Row) bool, updates data.Row, tx *tran
 				// Type validation would go here if needed
 				t.Rows[i].Data[colName] = newValue
 			}
+			if recording {
+				tx.Record(transaction.Change{Type: transaction.ChangeTypeUpdate, Table: t.Name, Data: t.Rows[i].Copy().Data, OldData: old.Data})
+			}
 			count++
 		}
 	}
@@ -284,12 +395,19 @@ func (t *Table) Delete(predicate func(data.Row) bool, tx *transaction.Transactio
 	if tx != nil {
 		slog.Debug("Delete operation", "table", t.Name, "tx_id", tx.ID)
 	}
+	if err := t.loadRowsUnsafe(); err != nil {
+		return 0, err
+	}
 
 	var newRows []data.Row
 	deleted := 0
+	recording := tx.Recording()
 
 	for _, row := range t.Rows {
 		if predicate(row) {
+			if recording {
+				tx.Record(transaction.Change{Type: transaction.ChangeTypeDelete, Table: t.Name, OldData: row.Copy().Data})
+			}
 			deleted++
 		} else {
 			newRows = append(newRows, row)
@@ -364,14 +482,14 @@ func (t *Table) validateType(colName string, value interface{}, expectedType Col
 func (t *Table) rebuildIndexesUnsafe() {
 	// Clear existing indexes
 	for _, idx := range t.Indexes {
-		idx.Data = make(map[interface{}][]int)
+		idx.Reset()
 	}
 
 	// Rebuild from current rows
 	for rowPos, row := range t.Rows {
 		for colName, idx := range t.Indexes {
 			if val, exists := row.Data[colName]; exists {
-				idx.Data[val] = append(idx.Data[val], rowPos)
+				idx.Add(val, rowPos)
 			}
 		}
 	}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"log/slog"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/index"
+)
+
+// OrderedIndex returns the ordered (BTREE) index on colName, if one exists
+func (t *Table) OrderedIndex(colName string) (index.Ordered, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.orderedIndexUnsafe(colName)
+}
+
+// SelectByIndexValues retrieves the rows whose colName value equals any of
+// values, using any index on the column (hash or ordered, unique or not)
+// Rows are returned in key order of values; duplicates in values are ignored.
+// Returns false if the column has no index.
+func (t *Table) SelectByIndexValues(colName string, values []interface{}, tx *transaction.Transaction) ([]data.Row, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	if tx != nil {
+		slog.Debug("SelectByIndexValues operation", "table", t.Name, "column", colName, "tx_id", tx.ID)
+	}
+
+	idx, exists := t.Indexes[colName]
+	if !exists {
+		return nil, false
+	}
+
+	var result []data.Row
+	seen := make(map[int]bool)
+	for _, value := range values {
+		for _, pos := range idx.Lookup(value) {
+			if seen[pos] {
+				continue
+			}
+			seen[pos] = true
+			result = append(result, t.Rows[pos])
+		}
+	}
+	return result, true
+}
+
+// SelectRange retrieves rows whose colName value lies within [lower, upper]
+// using an ordered index. A nil bound is unbounded, so with neither bound
+// every row with a non-NULL colName value is returned.
+// Rows are returned in ascending key order, or descending if descending is
+// set; rows with equal keys keep their table order either way.
+// Returns false if the column has no ordered index.
+func (t *Table) SelectRange(colName string, lower, upper *index.Bound, descending bool, tx *transaction.Transaction) ([]data.Row, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	if tx != nil {
+		slog.Debug("SelectRange operation", "table", t.Name, "column", colName, "tx_id", tx.ID)
+	}
+
+	ordered, ok := t.orderedIndexUnsafe(colName)
+	if !ok {
+		return nil, false
+	}
+
+	var keys [][]int
+	ordered.Range(lower, upper, func(_ interface{}, positions []int) bool {
+		keys = append(keys, positions)
+		return true
+	})
+	if descending {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	var result []data.Row
+	for _, positions := range keys {
+		for _, pos := range positions {
+			result = append(result, t.Rows[pos])
+		}
+	}
+	return result, true
+}
+
+// MinMax returns the smallest and largest non-NULL values of colName using
+// an ordered index, without scanning the table
+// found is false when the column has no ordered index; empty is true when the
+// index holds no values (MIN/MAX are NULL).
+func (t *Table) MinMax(colName string) (min, max interface{}, empty bool, found bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	ordered, ok := t.orderedIndexUnsafe(colName)
+	if !ok {
+		return nil, nil, false, false
+	}
+
+	min, _, hasMin := ordered.Min()
+	max, _, _ = ordered.Max()
+	return min, max, !hasMin, true
+}
+
+// orderedIndexUnsafe looks up an ordered index without locking
+// IMPORTANT: Must be called while holding a lock!
+func (t *Table) orderedIndexUnsafe(colName string) (index.Ordered, bool) {
+	idx, exists := t.Indexes[colName]
+	if !exists {
+		return nil, false
+	}
+	return idx.Ordered()
+}
@@ -1,9 +1,41 @@
 package schema
 
+import "github.com/leengari/mini-rdbms/internal/index"
+
 // TableSchema represents table metadata (from meta.json)
 type TableSchema struct {
 	TableName string
 	Columns   []Column
+	Indexes   []IndexDefinition // user-defined indexes (CREATE INDEX)
+}
+
+// IndexDefinition describes a user-defined secondary index
+// Implicit indexes on PRIMARY KEY / UNIQUE columns are not listed here
+type IndexDefinition struct {
+	Name   string
+	Column string
+	Kind   index.Kind
+	Unique bool
+}
+
+// GetColumn returns the column with the given name, or nil if it does not exist
+func (s *TableSchema) GetColumn(name string) *Column {
+	for i := range s.Columns {
+		if s.Columns[i].Name == name {
+			return &s.Columns[i]
+		}
+	}
+	return nil
+}
+
+// GetIndexDefinition returns the index definition with the given name, or nil
+func (s *TableSchema) GetIndexDefinition(name string) *IndexDefinition {
+	for i := range s.Indexes {
+		if s.Indexes[i].Name == name {
+			return &s.Indexes[i]
+		}
+	}
+	return nil
 }
 
 // GetPrimaryKeyColumn returns the primary key column if it exists
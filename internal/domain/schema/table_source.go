@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"log/slog"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+)
+
+// RowSource holds the rows of a table that are kept on disk rather than in
+// Rows, such as a heap file read through a buffer pool
+type RowSource interface {
+	Len() int
+	Cursor() RowCursor
+}
+
+// RowCursor reads rows one at a time
+type RowCursor interface {
+	// Next returns the next row; ok is false once the rows are exhausted
+	Next() (row data.Row, ok bool, err error)
+	Close()
+}
+
+// Paged reports whether the table's rows are on disk, read through Source
+// rather than held in Rows
+func (t *Table) Paged() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.Source != nil
+}
+
+// RowCount returns the number of rows in the table
+func (t *Table) RowCount() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.RowCountUnsafe()
+}
+
+// RowCountUnsafe returns the number of rows without acquiring the lock
+// IMPORTANT: Only call this when you already hold the table lock!
+func (t *Table) RowCountUnsafe() int {
+	if t.Source != nil {
+		return t.Source.Len()
+	}
+	return len(t.Rows)
+}
+
+// Cursor returns a cursor over the rows of a paged table, which reads them
+// from disk as it goes; nil when the rows are in memory, to be read with
+// ScanRows
+func (t *Table) Cursor(tx *transaction.Transaction) RowCursor {
+	t.RLock()
+	defer t.RUnlock()
+
+	if t.Source == nil {
+		return nil
+	}
+	if tx != nil {
+		slog.Debug("Cursor operation", "table", t.Name, "tx_id", tx.ID)
+	}
+	return t.Source.Cursor()
+}
+
+// LoadRows reads the rows of a paged table into Rows
+// Operations that address rows by position - index lookups, joins on the
+// stored table, INSERT, UPDATE and DELETE - need the rows in memory; a table
+// stays loaded from then on. Does nothing for a table already in memory.
+func (t *Table) LoadRows() error {
+	t.Lock()
+	defer t.Unlock()
+	return t.loadRowsUnsafe()
+}
+
+// loadRowsUnsafe reads the rows of a paged table into Rows
+// Must be called while holding the write lock
+func (t *Table) loadRowsUnsafe() error {
+	if t.Source == nil {
+		return nil
+	}
+	rows := make([]data.Row, 0, t.Source.Len())
+	if err := t.ForEachRowUnsafe(func(_ int, row data.Row) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return err
+	}
+	t.Rows = rows
+	t.Source = nil
+
+	slog.Info("table rows loaded into memory",
+		slog.String("table", t.Name),
+		slog.Int("rows", len(rows)))
+	return nil
+}
+
+// ForEachRowUnsafe calls fn with every row and its position, reading a paged
+// table through its source, and stops at the first error
+// Positions match those the rows have in Rows once the table is loaded.
+// Must be called while holding the table lock.
+func (t *Table) ForEachRowUnsafe(fn func(pos int, row data.Row) error) error {
+	if t.Source == nil {
+		for pos, row := range t.Rows {
+			if err := fn(pos, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cursor := t.Source.Cursor()
+	defer cursor.Close()
+	for pos := 0; ; pos++ {
+		row, ok, err := cursor.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := fn(pos, row); err != nil {
+			return err
+		}
+	}
+}
@@ -30,6 +30,10 @@ type Transaction struct {
 	Active    bool      // Whether transaction is currently active
 	StartTime time.Time // When the transaction began
 	Changes   []Change  // Modifications made
+
+	// RecordChanges makes table operations record their changes in Changes,
+	// for the write-ahead log
+	RecordChanges bool
 }
 
 // NewTransaction creates a new transaction with a unique ID
@@ -46,3 +50,16 @@ func NewTransaction() *Transaction {
 func (tx *Transaction) Close() {
 	tx.Active = false
 }
+
+// Recording reports whether the transaction records its changes, so
+// callers can skip building changes nobody keeps
+func (tx *Transaction) Recording() bool {
+	return tx != nil && tx.RecordChanges
+}
+
+// Record appends a change made by the transaction if it records changes
+func (tx *Transaction) Record(change Change) {
+	if tx.Recording() {
+		tx.Changes = append(tx.Changes, change)
+	}
+}
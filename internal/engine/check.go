@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/storage/integrity"
+)
+
+// checkResult returns the problems of a CHECK DATABASE report as rows, with
+// a summary as the message
+func checkResult(report *integrity.Report) *executor.Result {
+	result := &executor.Result{
+		Columns: []string{"object", "problem", "fix", "repaired"},
+		Rows:    make([]data.Row, 0, len(report.Problems)),
+	}
+	repaired := 0
+	for _, p := range report.Problems {
+		result.Rows = append(result.Rows, data.NewRow(map[string]interface{}{
+			"object":   p.Object,
+			"problem":  p.Message,
+			"fix":      p.Fix,
+			"repaired": p.Repaired,
+		}))
+		if p.Repaired {
+			repaired++
+		}
+	}
+
+	result.Message = fmt.Sprintf("Database '%s': %d tables, %d rows and %d WAL segments checked, ",
+		report.Database, report.Tables, report.Rows, report.Segments)
+	switch {
+	case len(report.Problems) == 0:
+		result.Message += "no problems found"
+	case repaired > 0:
+		result.Message += fmt.Sprintf("%d problems found, %d repaired", len(report.Problems), repaired)
+	default:
+		result.Message += fmt.Sprintf("%d problems found", len(report.Problems))
+	}
+	return result
+}
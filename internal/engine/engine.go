@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"time"
 
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
@@ -11,7 +14,10 @@ import (
 	"github.com/leengari/mini-rdbms/internal/parser/ast"
 	"github.com/leengari/mini-rdbms/internal/parser/lexer"
 	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
 	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/wal"
 )
 
 // Engine is the main entry point for the database system
@@ -19,6 +25,12 @@ type Engine struct {
 	db        *schema.Database
 	registry  *manager.Registry
 	observers []Observer // Observers for lifecycle events
+
+	// Session settings (SET)
+	statementTimeout time.Duration // 0 means no timeout
+	config           *executor.ExecutionConfig
+
+	script *script // the script being run by SOURCE or ExecuteScript, nil otherwise
 }
 
 // New creates a new Engine instance
@@ -27,11 +39,32 @@ func New(db *schema.Database, registry *manager.Registry) *Engine {
 		db:        db,
 		registry:  registry,
 		observers: make([]Observer, 0),
+		config:    executor.DefaultExecutionConfig(),
 	}
 }
 
 // Execute processes a SQL string and returns the result
 func (e *Engine) Execute(sql string) (*executor.Result, error) {
+	return e.ExecuteContext(context.Background(), sql)
+}
+
+// ExecuteContext processes a SQL string like Execute, stopping the statement
+// when ctx is cancelled or the session's statement_timeout passes
+func (e *Engine) ExecuteContext(ctx context.Context, sql string) (*executor.Result, error) {
+	if e.statementTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.statementTimeout)
+		defer cancel()
+	}
+	result, err := e.execute(ctx, sql)
+	if err != nil && e.statementTimeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("statement timeout of %s exceeded: %w", e.statementTimeout, err)
+	}
+	return result, err
+}
+
+// execute runs one statement under ctx
+func (e *Engine) execute(ctx context.Context, sql string) (*executor.Result, error) {
 	// 0. Start Transaction
 	tx := transaction.NewTransaction()
 	defer tx.Close()
@@ -52,13 +85,36 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
 	e.notify(Event{Type: EventParseEnd, TxID: tx.ID, Data: fmt.Sprintf("%T", stmt)})
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("query cancelled: %w", err)
+	}
 
-	// 3. Handle Database Management Statements
+	if e.script != nil && !scriptable(stmt) {
+		return nil, fmt.Errorf("cannot run %s in a script: it cannot be undone if the script fails", stmt.TokenLiteral())
+	}
+
+	// 3. Handle Session and Database Management Statements
 	switch s := stmt.(type) {
+	case *ast.SetStatement:
+		if err := e.set(s.Name, s.Value); err != nil {
+			return nil, err
+		}
+		return &executor.Result{Message: "SET"}, nil
+
 	case *ast.CreateDatabaseStatement:
-		if err := e.registry.Create(s.Name); err != nil {
+		var err error
+		if s.Storage != "" {
+			err = e.registry.CreateWithStorage(s.Name, s.Storage)
+		} else {
+			err = e.registry.Create(s.Name)
+		}
+		if err != nil {
 			return nil, err
 		}
+		if e.script != nil {
+			name := s.Name
+			e.script.onUndo(func() error { return e.registry.Drop(name) })
+		}
 		return &executor.Result{Message: fmt.Sprintf("Database '%s' created", s.Name)}, nil
 
 	case *ast.DropDatabaseStatement:
@@ -72,6 +128,14 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 		return &executor.Result{Message: fmt.Sprintf("Database '%s' dropped", s.Name)}, nil
 
 	case *ast.AlterDatabaseStatement:
+		if s.Storage != "" {
+			// The converted database stays loaded, so the active one remains valid
+			if err := e.registry.SetStorage(s.Name, s.Storage); err != nil {
+				return nil, err
+			}
+			return &executor.Result{Message: fmt.Sprintf("Database '%s' now uses %s storage", s.Name, s.Storage)}, nil
+		}
+
 		// If renaming active DB, unload it (or update it, but unloading is safer for now)
 		if e.db != nil && e.db.Name == s.Name {
 			e.db = nil
@@ -81,14 +145,43 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 		}
 		return &executor.Result{Message: fmt.Sprintf("Database renamed from '%s' to '%s'", s.Name, s.NewName)}, nil
 
+	case *ast.BackupDatabaseStatement:
+		manifest, err := e.registry.Backup(s.Name, s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("backup of database '%s' failed: %w", s.Name, err)
+		}
+		return &executor.Result{Message: fmt.Sprintf("Database '%s' backed up to '%s' (%d tables)", s.Name, s.Path, len(manifest.Tables))}, nil
+
+	case *ast.RestoreDatabaseStatement:
+		manifest, err := e.registry.Restore(s.Name, s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("restore of database '%s' failed: %w", s.Name, err)
+		}
+		return &executor.Result{Message: fmt.Sprintf("Database '%s' restored from '%s' (%d tables, backed up %s)",
+			s.Name, s.Path, len(manifest.Tables), manifest.CreatedAt.Format(time.RFC3339))}, nil
+
+	case *ast.CheckDatabaseStatement:
+		report, err := e.registry.Check(s.Name, s.Repair)
+		if err != nil {
+			return nil, fmt.Errorf("check of database '%s' failed: %w", s.Name, err)
+		}
+		return checkResult(report), nil
+
 	case *ast.UseDatabaseStatement:
 		// Load/Get new DB from registry
 		newDB, err := e.registry.Get(s.Name)
 		if err != nil {
+			// A database that is there but does not load is checked for the cause
+			if !errors.Is(err, manager.ErrLocked) && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to load database '%s': %w; CHECK DATABASE %s lists every problem", s.Name, err, s.Name)
+			}
 			return nil, fmt.Errorf("failed to load database '%s': %w", s.Name, err)
 		}
 		e.db = newDB
 		return &executor.Result{Message: fmt.Sprintf("Switched to database '%s'", s.Name)}, nil
+
+	case *ast.SourceStatement:
+		return e.source(ctx, s.Path)
 	}
 
 	// 4. Ensure Database is Selected
@@ -96,6 +189,35 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 		return nil, fmt.Errorf("no database selected. Use 'USE <database_name>' to select one")
 	}
 
+	// Nothing a read-only registry loads is ever saved, so changes are refused
+	// rather than silently lost
+	if e.registry != nil && e.registry.ReadOnly() && changesData(stmt) {
+		return nil, fmt.Errorf("cannot run %s: %w", stmt.TokenLiteral(), manager.ErrReadOnly)
+	}
+
+	if s, ok := stmt.(*ast.CreateTableStatement); ok {
+		return e.createTable(s)
+	}
+	if s, ok := stmt.(*ast.AlterTableStatement); ok {
+		return e.alterTable(s)
+	}
+
+	// A logged database records the changes of a statement and writes them to
+	// its WAL, holding the writer lock from the first change to the commit
+	var log *wal.WAL
+	if e.registry != nil && changesData(stmt) {
+		log = e.registry.WAL(e.db.Name)
+	}
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+		tx.RecordChanges = true
+	}
+	// A script records them too, to undo them if a later statement fails
+	if e.script != nil {
+		tx.RecordChanges = true
+	}
+
 	// 5. Plan (for DML/DQL)
 	e.notify(Event{Type: EventPlanStart, TxID: tx.ID})
 	planNode, err := planner.Plan(stmt, e.db, tx)
@@ -106,10 +228,37 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 
 	// 6. Execute
 	e.notify(Event{Type: EventExecStart, TxID: tx.ID})
-	result, err := executor.Execute(planNode, e.db, tx)
+	result, err := executor.ExecuteWith(planNode, &executor.ExecutionContext{
+		Database:    e.db,
+		Transaction: tx,
+		Config:      e.config,
+		Context:     ctx,
+	})
+	if e.script != nil && len(tx.Changes) > 0 {
+		e.script.onUndo(e.undoChanges(e.db, tx.Changes))
+	}
+	// Changes made before a failure stay applied, so they are logged too
+	if log != nil && len(tx.Changes) > 0 {
+		if logErr := archive.Log(log, e.db, tx.Changes); logErr != nil {
+			return nil, fmt.Errorf("changes applied but not logged to the WAL: %w", logErr)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("execution error: %w", err)
 	}
+	if s, ok := stmt.(*ast.CreateIndexStatement); ok {
+		// An index is not a row change, so it is logged on its own
+		if log != nil {
+			table := e.db.Tables[s.TableName.Value]
+			if logErr := archive.LogCreateIndex(log, table.Name, *table.Schema.GetIndexDefinition(s.Name)); logErr != nil {
+				return nil, fmt.Errorf("index created but not logged to the WAL: %w", logErr)
+			}
+		}
+		if e.script != nil {
+			db, name := e.db, s.Name
+			e.script.onUndo(func() error { return e.dropIndex(db, s.TableName.Value, name) })
+		}
+	}
 	e.notify(Event{Type: EventExecEnd, TxID: tx.ID, Data: map[string]interface{}{
 		"rows_affected": result.RowsAffected,
 		"rows_returned": len(result.Rows),
@@ -118,6 +267,166 @@ func (e *Engine) Execute(sql string) (*executor.Result, error) {
 	return result, nil
 }
 
+// createTable creates a table in the current database
+// The table is saved at once; its rows are saved like those of any table.
+func (e *Engine) createTable(s *ast.CreateTableStatement) (*executor.Result, error) {
+	if e.registry == nil {
+		return nil, fmt.Errorf("cannot create table: the engine has no database registry")
+	}
+
+	name := s.Name.Value
+	tableSchema := &schema.TableSchema{TableName: name}
+	for _, def := range s.Columns {
+		if def.PrimaryKey && tableSchema.GetPrimaryKeyColumn() != nil {
+			return nil, fmt.Errorf("table %s has more than one PRIMARY KEY column", name)
+		}
+		tableSchema.Columns = append(tableSchema.Columns, schema.Column{
+			Name:          def.Name,
+			Type:          schema.ColumnType(def.Type),
+			PrimaryKey:    def.PrimaryKey,
+			Unique:        def.Unique,
+			NotNull:       def.NotNull,
+			AutoIncrement: def.AutoIncrement,
+		})
+	}
+
+	// The writer lock is taken before the table exists, so no change to it
+	// can be logged ahead of its creation
+	db := e.db
+	log := e.registry.WAL(db.Name)
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+	}
+	if _, err := e.registry.CreateTable(db, tableSchema); err != nil {
+		return nil, err
+	}
+	if log != nil {
+		if err := archive.LogCreateTable(log, tableSchema); err != nil {
+			return nil, fmt.Errorf("table created but not logged to the WAL: %w", err)
+		}
+	}
+	if e.script != nil {
+		e.script.onUndo(func() error { return e.dropTable(db, name) })
+	}
+	return &executor.Result{Message: fmt.Sprintf("Table '%s' created", name)}, nil
+}
+
+// alterTable sets the next value of the auto-increment key of a table in
+// the current database
+// The sequence only moves forward, so no key that may be in use is
+// generated again.
+func (e *Engine) alterTable(s *ast.AlterTableStatement) (*executor.Result, error) {
+	db, name := e.db, s.Name.Value
+	table, ok := db.Tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table %s not found", name)
+	}
+
+	var log *wal.WAL
+	if e.registry != nil {
+		log = e.registry.WAL(db.Name)
+	}
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+	}
+	previous, err := table.AdvanceSequence(s.AutoIncrement - 1)
+	if err != nil {
+		return nil, err
+	}
+	if log != nil {
+		if err := archive.LogSetSequence(log, name, s.AutoIncrement-1); err != nil {
+			return nil, fmt.Errorf("sequence set but not logged to the WAL: %w", err)
+		}
+	}
+	if e.script != nil {
+		e.script.onUndo(func() error { return e.setSequence(db, table, previous) })
+	}
+	return &executor.Result{Message: fmt.Sprintf("Table '%s' altered", name)}, nil
+}
+
+// setSequence sets the auto-increment sequence of a table back, for a
+// script that failed, logging it if the database is logged
+func (e *Engine) setSequence(db *schema.Database, table *schema.Table, sequence int64) error {
+	var log *wal.WAL
+	if e.registry != nil {
+		log = e.registry.WAL(db.Name)
+	}
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+	}
+	table.SetSequence(sequence)
+	if log != nil {
+		if err := archive.LogSetSequence(log, table.Name, sequence); err != nil {
+			return fmt.Errorf("sequence set back but not logged to the WAL: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropTable removes a table created by a script that failed, logging the
+// removal if the database is logged
+func (e *Engine) dropTable(db *schema.Database, name string) error {
+	log := e.registry.WAL(db.Name)
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+	}
+	if err := e.registry.DropTable(db, name); err != nil {
+		return err
+	}
+	if log != nil {
+		if err := archive.LogDropTable(log, name); err != nil {
+			return fmt.Errorf("table dropped but not logged to the WAL: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropIndex removes an index created by a script that failed, logging the
+// removal if the database is logged
+func (e *Engine) dropIndex(db *schema.Database, tableName, name string) error {
+	table, ok := db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+
+	var log *wal.WAL
+	if e.registry != nil {
+		log = e.registry.WAL(db.Name)
+	}
+	if log != nil {
+		log.LockWriter()
+		defer log.UnlockWriter()
+	}
+	if err := indexing.DropIndex(table, name); err != nil {
+		return err
+	}
+	if log != nil {
+		if err := archive.LogDropIndex(log, tableName, name); err != nil {
+			return fmt.Errorf("index dropped but not logged to the WAL: %w", err)
+		}
+	}
+	return nil
+}
+
+// changesData reports whether a statement changes the rows or schema of a
+// table when run
+func changesData(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.InsertStatement, *ast.UpdateStatement, *ast.DeleteStatement, *ast.CreateIndexStatement,
+		*ast.CreateTableStatement, *ast.AlterTableStatement:
+		return true
+	case *ast.ExplainStatement:
+		return s.Analyze && changesData(s.Statement)
+	case *ast.CopyStatement:
+		return !s.To
+	}
+	return false
+}
+
 // ListTables returns a list of tables in the currently selected database
 func (e *Engine) ListTables() ([]string, error) {
 	if e.db == nil {
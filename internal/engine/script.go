@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// script is the undo log of a SQL script being run, which the script's
+// statements add to as they change things
+// If a statement fails, everything the statements before it did is undone in
+// reverse order, so the script runs as one unit. Other sessions see its
+// changes as they are made: a script is atomic but not isolated.
+type script struct {
+	undo []func() error
+}
+
+// onUndo registers how to undo a change made by the script
+func (s *script) onUndo(undo func() error) {
+	s.undo = append(s.undo, undo)
+}
+
+// rollback undoes the script's changes, newest first
+func (s *script) rollback() error {
+	var errs []error
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		if err := s.undo[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// scriptStatement is one statement of a script and the line it starts on
+type scriptStatement struct {
+	SQL  string
+	Line int
+}
+
+// ExecuteScript runs the statements of a SQL script, separated by
+// semicolons, one after the other as a single unit
+// If a statement fails, the changes of the statements before it are undone
+// and the error names the line the failing statement starts on. Statements
+// whose effect cannot be undone (DROP DATABASE, ALTER DATABASE, BACKUP,
+// RESTORE and SOURCE) are refused. The session's database and settings are
+// put back as well, and kept as the script leaves them if it succeeds.
+func (e *Engine) ExecuteScript(ctx context.Context, sql string) (*executor.Result, error) {
+	if e.statementTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.statementTimeout)
+		defer cancel()
+	}
+	return e.runScript(ctx, sql)
+}
+
+// source runs the SQL script in the file at path
+func (e *Engine) source(ctx context.Context, path string) (*executor.Result, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read script: %w", err)
+	}
+	result, err := e.runScript(ctx, string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
+
+// runScript runs the statements of a script under ctx, undoing them all if
+// one fails
+func (e *Engine) runScript(ctx context.Context, sql string) (*executor.Result, error) {
+	if e.script != nil {
+		return nil, fmt.Errorf("cannot run a script from within a script")
+	}
+	statements, err := splitScript(sql)
+	if err != nil {
+		return nil, fmt.Errorf("lexer error: %w", err)
+	}
+
+	db, timeout, config := e.db, e.statementTimeout, *e.config
+	e.script = &script{}
+	defer func() { e.script = nil }()
+
+	rowsAffected := 0
+	for _, stmt := range statements {
+		result, err := e.execute(ctx, stmt.SQL)
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", stmt.Line, err)
+			if undoErr := e.script.rollback(); undoErr != nil {
+				return nil, fmt.Errorf("%w; undoing the script failed: %v", err, undoErr)
+			}
+			e.db, e.statementTimeout, *e.config = db, timeout, config
+			return nil, fmt.Errorf("%w; the script's changes were undone", err)
+		}
+		rowsAffected += result.RowsAffected
+	}
+
+	return &executor.Result{
+		Message:      fmt.Sprintf("Script executed: %d statements", len(statements)),
+		RowsAffected: rowsAffected,
+	}, nil
+}
+
+// splitScript splits a script into its statements, which end at semicolons
+// outside strings; comments and empty statements are dropped
+func splitScript(sql string) ([]scriptStatement, error) {
+	tokens, err := lexer.Tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens know their line and column; statements are cut from the text
+	lineStarts := []int{0}
+	for i := 0; i < len(sql); i++ {
+		if sql[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(tok lexer.Token) int {
+		return lineStarts[tok.Line-1] + tok.Column - 1
+	}
+
+	var statements []scriptStatement
+	start, line := -1, 0
+	for _, tok := range tokens {
+		if start < 0 {
+			if tok.Type == lexer.SEMICOLON {
+				continue
+			}
+			start, line = offset(tok), tok.Line
+		}
+		if tok.Type == lexer.SEMICOLON {
+			statements = append(statements, scriptStatement{SQL: strings.TrimSpace(sql[start:offset(tok)]), Line: line})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		statements = append(statements, scriptStatement{SQL: strings.TrimSpace(sql[start:]), Line: line})
+	}
+	return statements, nil
+}
+
+// scriptable reports whether a statement may run in a script, whose changes
+// are undone if a later statement fails
+func scriptable(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.DropDatabaseStatement, *ast.AlterDatabaseStatement, *ast.BackupDatabaseStatement,
+		*ast.RestoreDatabaseStatement, *ast.SourceStatement:
+		return false
+	case *ast.CheckDatabaseStatement:
+		return !s.Repair
+	}
+	return true
+}
+
+// undoChanges returns how to undo the row changes a statement made to db
+// A logged database logs the undoing changes too, so replaying its WAL ends
+// with the rows as they were before the script.
+func (e *Engine) undoChanges(db *schema.Database, changes []transaction.Change) func() error {
+	return func() error {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		var log *wal.WAL
+		if e.registry != nil {
+			log = e.registry.WAL(db.Name)
+		}
+		if log != nil {
+			log.LockWriter()
+			defer log.UnlockWriter()
+			tx.RecordChanges = true
+		}
+
+		var err error
+		for i := len(changes) - 1; i >= 0 && err == nil; i-- {
+			err = archive.Undo(db, changes[i], tx)
+		}
+		if log != nil && len(tx.Changes) > 0 {
+			if logErr := archive.Log(log, db, tx.Changes); logErr != nil {
+				return errors.Join(err, fmt.Errorf("undone changes not logged to the WAL: %w", logErr))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to undo changes to database '%s': %w", db.Name, err)
+		}
+		return nil
+	}
+}
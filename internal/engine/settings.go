@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/executor"
+)
+
+// set changes a session setting
+// statement_timeout takes a duration ('5s', '250ms') or a number of
+// milliseconds; 0 or DEFAULT turns the timeout off. memory_budget takes a
+// size ('64MB', '512kB') or a number of kilobytes; 0 removes the limit and
+// DEFAULT restores the default budget. parallel_workers is the number of
+// workers scans and hash joins use; 0 or 1 runs them serially and DEFAULT
+// uses one worker per CPU. execution_strategy names the executor strategy
+// ('iterator', 'tree_walking', 'vectorized'); DEFAULT restores the process default.
+func (e *Engine) set(name, value string) error {
+	switch name {
+	case "statement_timeout":
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for statement_timeout: %w", err)
+		}
+		e.statementTimeout = timeout
+		return nil
+	case "memory_budget":
+		budget, err := parseMemorySize(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for memory_budget: %w", err)
+		}
+		e.config.MemoryBudget = budget
+		return nil
+	case "parallel_workers":
+		if strings.EqualFold(value, "default") {
+			e.config.ParallelScans, e.config.Workers = true, 0
+			return nil
+		}
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers < 0 {
+			return fmt.Errorf("invalid value for parallel_workers: %q is not a number of workers", value)
+		}
+		e.config.ParallelScans, e.config.Workers = workers > 1, workers
+		return nil
+	case "execution_strategy":
+		if strings.EqualFold(value, "default") {
+			value = executor.DefaultExecutionConfig().Strategy
+		}
+		return e.SetExecutionStrategy(strings.ToLower(value))
+	default:
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+}
+
+// StatementTimeout returns the session's statement timeout, 0 if none
+func (e *Engine) StatementTimeout() time.Duration {
+	return e.statementTimeout
+}
+
+// MemoryBudget returns the session's per-query memory budget in bytes, 0 if
+// unlimited
+func (e *Engine) MemoryBudget() int64 {
+	return e.config.MemoryBudget
+}
+
+// ExecutionStrategy returns the name of the session's execution strategy
+func (e *Engine) ExecutionStrategy() string {
+	return e.config.Strategy
+}
+
+// SetExecutionStrategy selects the strategy that executes the session's
+// statements, so different execution engines can be compared on the same
+// plans
+func (e *Engine) SetExecutionStrategy(name string) error {
+	if _, ok := executor.LookupStrategy(name); !ok {
+		return fmt.Errorf("invalid value for execution_strategy: %q is not one of %s", name, strings.Join(executor.StrategyNames(), ", "))
+	}
+	e.config.Strategy = name
+	return nil
+}
+
+// ParallelWorkers returns the number of workers the session's queries use,
+// 1 if they run serially
+func (e *Engine) ParallelWorkers() int {
+	return e.config.Parallelism()
+}
+
+// parseTimeout parses a statement_timeout value
+func parseTimeout(value string) (time.Duration, error) {
+	if strings.EqualFold(value, "default") {
+		return 0, nil
+	}
+	var timeout time.Duration
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		timeout = time.Duration(ms) * time.Millisecond
+	} else if timeout, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("%q is not a duration", value)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+	return timeout, nil
+}
+
+// memoryUnits are the size suffixes accepted by memory_budget
+var memoryUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"kb", 1 << 10},
+	{"mb", 1 << 20},
+	{"gb", 1 << 30},
+	{"b", 1},
+}
+
+// parseMemorySize parses a memory_budget value
+func parseMemorySize(value string) (int64, error) {
+	if strings.EqualFold(value, "default") {
+		return executor.DefaultMemoryBudget, nil
+	}
+	number, unit := strings.TrimSpace(value), int64(1<<10)
+	lower := strings.ToLower(number)
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(lower, u.suffix) {
+			number, unit = strings.TrimSpace(number[:len(number)-len(u.suffix)]), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a size", value)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+	return n * unit, nil
+}
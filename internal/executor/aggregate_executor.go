@@ -0,0 +1,308 @@
+package executor
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
+)
+
+const (
+	// spillFanout is the number of partitions a spilling hash operator
+	// splits its input into
+	spillFanout = 16
+	// maxSpillDepth is how many times a partition that still does not fit
+	// is repartitioned before it is processed over budget
+	maxSpillDepth = 4
+)
+
+// aggregateOperator groups its input rows and computes aggregates per group
+//
+// It is a hybrid hash aggregation: groups are kept in a hash table while the
+// memory budget allows. Once a new group would exceed it, rows of groups not
+// yet in the table are written to partition run files by the hash of their
+// group key, and each partition is aggregated on its own after the input is
+// exhausted. Output rows hold the group columns under their GROUP BY names
+// and the aggregates under their names; without GROUP BY there is exactly one
+// row, even for empty input.
+type aggregateOperator struct {
+	input      Operator
+	ctx        *ExecutionContext
+	node       *plan.SelectNode
+	schema     *schema.TableSchema
+	groups     []*group
+	reserved   int64
+	pending    []*partitionCursor
+	partitions int // spilled partitions aggregated so far
+}
+
+// group is the running state of one group
+type group struct {
+	row  map[string]interface{} // group column values
+	accs []accumulator
+}
+
+// accumulator is the running state of one aggregate in a group
+type accumulator struct {
+	count   int64
+	sumInt  int64
+	sum     float64
+	isFloat bool
+	value   interface{} // MIN / MAX
+}
+
+// partitionCursor is a set of spilled partitions still to be aggregated
+type partitionCursor struct {
+	parts *spill.Partitions
+	next  int
+}
+
+func newAggregateOperator(input Operator, node *plan.SelectNode, ctx *ExecutionContext) *aggregateOperator {
+	aggSchema := &schema.TableSchema{}
+	for _, ref := range node.GroupBy {
+		aggSchema.Columns = append(aggSchema.Columns, schema.Column{Name: columnKey(ref), Type: schema.ColumnTypeText})
+	}
+	for _, agg := range node.Aggregates {
+		aggSchema.Columns = append(aggSchema.Columns, schema.Column{Name: agg.Name, Type: schema.ColumnTypeText})
+	}
+	return &aggregateOperator{input: input, ctx: ctx, node: node, schema: aggSchema}
+}
+
+func (o *aggregateOperator) Open() error {
+	if err := o.input.Open(); err != nil {
+		return err
+	}
+	return o.aggregate(o.input.Next, 0)
+}
+
+// aggregate reads every row of next into groups
+// Rows of new groups go to partitions at the given level once the memory
+// budget is exhausted, unless that level is too deep to spill again.
+func (o *aggregateOperator) aggregate(next func() (data.Row, bool, error), level int) error {
+	budget := o.ctx.memory()
+	byKey := make(map[string]*group)
+	o.groups = nil
+
+	if len(o.node.GroupBy) == 0 {
+		g := o.newGroup(data.Row{})
+		byKey[""] = g
+		o.groups = append(o.groups, g)
+	}
+
+	var parts *spill.Partitions
+	rows := 0
+	for {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		rows++
+		if rows%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return err
+			}
+		}
+
+		key := o.groupKey(row)
+		g, exists := byKey[key]
+		if !exists {
+			if parts != nil {
+				if err := parts.Write(parts.Of(key), row); err != nil {
+					return err
+				}
+				continue
+			}
+			g = o.newGroup(row)
+			size := spill.RowSize(data.Row{Data: g.row}) + int64(64*len(g.accs))
+			if !budget.Reserve(size) {
+				if level < maxSpillDepth {
+					parts = spill.NewPartitions(budget, o.ctx.spillDir(), spillFanout, level+1)
+					slog.Debug("Aggregation spilling to partitions",
+						slog.Int("groups", len(o.groups)),
+						slog.Int("level", level+1),
+					)
+					if err := parts.Write(parts.Of(key), row); err != nil {
+						return err
+					}
+					continue
+				}
+				budget.Force(size)
+			}
+			o.reserved += size
+			byKey[key] = g
+			o.groups = append(o.groups, g)
+		}
+		if err := o.accumulate(g, row); err != nil {
+			return err
+		}
+	}
+
+	if parts != nil {
+		o.pending = append(o.pending, &partitionCursor{parts: parts})
+	}
+	return nil
+}
+
+func (o *aggregateOperator) newGroup(row data.Row) *group {
+	g := &group{row: make(map[string]interface{}, len(o.node.GroupBy)), accs: make([]accumulator, len(o.node.Aggregates))}
+	for _, ref := range o.node.GroupBy {
+		g.row[columnKey(ref)] = columnValue(row, ref)
+	}
+	return g
+}
+
+// groupKey encodes the GROUP BY values of a row; NULLs form one group
+func (o *aggregateOperator) groupKey(row data.Row) string {
+	var b strings.Builder
+	for _, ref := range o.node.GroupBy {
+		value := index.NormalizeKey(columnValue(row, ref))
+		fmt.Fprintf(&b, "%T:%v\x00", value, value)
+	}
+	return b.String()
+}
+
+// accumulate adds a row to every aggregate of its group
+func (o *aggregateOperator) accumulate(g *group, row data.Row) error {
+	for i, agg := range o.node.Aggregates {
+		if agg.Column.Column == "*" {
+			g.accs[i].count++
+			continue
+		}
+		if err := accumulateValue(&g.accs[i], agg, columnValue(row, agg.Column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accumulateValue adds one value to the running state of an aggregate;
+// NULLs are ignored
+func accumulateValue(acc *accumulator, agg plan.Aggregate, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch agg.Function {
+	case "SUM", "AVG":
+		switch v := index.NormalizeKey(value).(type) {
+		case int64:
+			acc.sumInt += v
+			acc.sum += float64(v)
+		case float64:
+			acc.isFloat = true
+			acc.sum += v
+		default:
+			return fmt.Errorf("%s requires numeric values, got %T in %s", agg.Function, value, agg.Name)
+		}
+	case "MIN":
+		if acc.count == 0 || index.Compare(value, acc.value) < 0 {
+			acc.value = value
+		}
+	case "MAX":
+		if acc.count == 0 || index.Compare(value, acc.value) > 0 {
+			acc.value = value
+		}
+	}
+	acc.count++
+	return nil
+}
+
+// result returns the output row of a group
+func (o *aggregateOperator) result(g *group) data.Row {
+	values := make(map[string]interface{}, len(g.row)+len(g.accs))
+	for k, v := range g.row {
+		values[k] = v
+	}
+	for i, agg := range o.node.Aggregates {
+		acc := g.accs[i]
+		var value interface{}
+		switch agg.Function {
+		case "COUNT":
+			value = acc.count
+		case "SUM":
+			switch {
+			case acc.count == 0:
+			case acc.isFloat:
+				value = acc.sum
+			default:
+				value = acc.sumInt
+			}
+		case "AVG":
+			if acc.count > 0 {
+				value = acc.sum / float64(acc.count)
+			}
+		case "MIN", "MAX":
+			value = acc.value
+		}
+		values[agg.Name] = value
+	}
+	return data.NewRow(values)
+}
+
+func (o *aggregateOperator) Next() (data.Row, bool, error) {
+	for {
+		if len(o.groups) > 0 {
+			g := o.groups[0]
+			o.groups = o.groups[1:]
+			return o.result(g), true, nil
+		}
+		o.ctx.memory().Release(o.reserved)
+		o.reserved = 0
+
+		if len(o.pending) == 0 {
+			return data.Row{}, false, nil
+		}
+		cursor := o.pending[len(o.pending)-1]
+		if cursor.next >= cursor.parts.Count() {
+			o.pending = o.pending[:len(o.pending)-1]
+			if err := cursor.parts.Remove(); err != nil {
+				return data.Row{}, false, err
+			}
+			continue
+		}
+		i := cursor.next
+		cursor.next++
+		rows, err := cursor.parts.Open(i)
+		if err != nil {
+			return data.Row{}, false, err
+		}
+		// Every group of a partition is new: its rows were spilled because
+		// their groups were not in memory
+		o.partitions++
+		if err := o.aggregate(rows.Next, cursor.parts.Level()); err != nil {
+			return data.Row{}, false, err
+		}
+	}
+}
+
+func (o *aggregateOperator) Close() error {
+	o.ctx.memory().Release(o.reserved)
+	o.groups, o.reserved = nil, 0
+
+	var err error
+	for _, cursor := range o.pending {
+		if removeErr := cursor.parts.Remove(); err == nil {
+			err = removeErr
+		}
+	}
+	o.pending = nil
+	if o.partitions > 0 {
+		slog.Debug("Aggregation closed", slog.Int("spilled_partitions", o.partitions))
+	}
+	if closeErr := o.input.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (o *aggregateOperator) Schema() *schema.TableSchema {
+	return o.schema
+}
@@ -0,0 +1,29 @@
+package executor
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/statistics"
+)
+
+// executeAnalyzeNode handles ANALYZE using tree-walking pattern
+func executeAnalyzeNode(node *plan.AnalyzeNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+	for _, name := range node.TableNames {
+		table, ok := ctx.Database.Tables[name]
+		if !ok {
+			return nil, newTableNotFoundError(name)
+		}
+		if _, err := statistics.Analyze(table); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IntermediateResult{
+		Rows:   []data.Row{},
+		Schema: nil,
+		Metadata: map[string]interface{}{
+			"operation":       "ANALYZE",
+			"tables_analyzed": len(node.TableNames),
+		},
+	}, nil
+}
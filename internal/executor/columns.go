@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
+)
+
+// columnKey returns the name a column reference is stored under in a row
+// built from it, such as the group columns of an aggregated row
+func columnKey(ref projection.ColumnRef) string {
+	if ref.Table != "" {
+		return ref.Table + "." + ref.Column
+	}
+	return ref.Column
+}
+
+// columnValue looks up a column in a row of a table or of a join result
+// A qualified reference matches "table.column", then the plain column name of
+// a single-table row; an unqualified one matches the plain name, then the
+// only "table.column" with that column name. A missing column is NULL.
+func columnValue(row data.Row, ref projection.ColumnRef) interface{} {
+	if ref.Table != "" {
+		if value, ok := row.Data[ref.Table+"."+ref.Column]; ok {
+			return value
+		}
+	}
+	if value, ok := row.Data[ref.Column]; ok {
+		return value
+	}
+	if ref.Table != "" {
+		return nil
+	}
+
+	var found interface{}
+	matches := 0
+	for key, value := range row.Data {
+		if strings.HasSuffix(key, "."+ref.Column) {
+			found = value
+			matches++
+		}
+	}
+	if matches != 1 {
+		return nil
+	}
+	return found
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/copying"
+)
+
+// executeCopyFromNode handles COPY ... FROM using tree-walking pattern
+// The whole file is read, converted and validated before the first row is
+// inserted, so a bad record inserts nothing. A record that breaks a unique
+// key is found while inserting; like INSERT, the rows before it are then
+// removed again.
+func executeCopyFromNode(node *plan.CopyFromNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+	table, ok := ctx.Database.Tables[node.TableName]
+	if !ok {
+		return nil, newTableNotFoundError(node.TableName)
+	}
+
+	file, err := os.Open(node.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read COPY file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := copying.Read(file, table, node.Columns, node.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", node.Path, err)
+	}
+	if err := ctx.checkCancelled(); err != nil {
+		return nil, err
+	}
+
+	rows := make([]data.Row, len(records))
+	for i, record := range records {
+		rows[i] = record.Row
+	}
+	if i, err := insertRows(ctx.Database, table, rows, ctx.Transaction); err != nil {
+		return nil, fmt.Errorf("%s: line %d: %w", node.Path, records[i].Line, err)
+	}
+
+	return &IntermediateResult{
+		Rows:   []data.Row{},
+		Schema: nil,
+		Metadata: map[string]interface{}{
+			"operation":     "COPY",
+			"rows_affected": len(records),
+		},
+	}, nil
+}
+
+// executeCopyToNode handles COPY ... TO using tree-walking pattern
+// The file holds the query's columns in the order SELECT returns them. A
+// failed COPY removes the file rather than leave part of the rows in it.
+func executeCopyToNode(node *plan.CopyToNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+	intermediate, err := executeNode(node.Query, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := formatSelectResult(node.Query, intermediate, ctx.Database)
+
+	file, err := os.Create(node.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write COPY file: %w", err)
+	}
+	err = copying.Write(file, result.Columns, result.Rows, node.Options)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("%s: %w", node.Path, err), os.Remove(node.Path))
+	}
+
+	return &IntermediateResult{
+		Rows:   []data.Row{},
+		Schema: nil,
+		Metadata: map[string]interface{}{
+			"operation":     "COPY",
+			"rows_affected": len(result.Rows),
+		},
+	}, nil
+}
@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+)
+
+// executeCreateIndexNode handles CREATE INDEX using tree-walking pattern
+func executeCreateIndexNode(node *plan.CreateIndexNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+	table, ok := ctx.Database.Tables[node.TableName]
+	if !ok {
+		return nil, newTableNotFoundError(node.TableName)
+	}
+
+	def := schema.IndexDefinition{
+		Name:   node.IndexName,
+		Column: node.Column,
+		Kind:   node.Kind,
+		Unique: node.Unique,
+	}
+	if err := indexing.CreateIndex(table, def); err != nil {
+		return nil, err
+	}
+
+	return &IntermediateResult{
+		Rows:   []data.Row{},
+		Schema: nil,
+		Metadata: map[string]interface{}{
+			"operation": "CREATE INDEX",
+			"index":     node.IndexName,
+		},
+	}, nil
+}
@@ -1,7 +1,9 @@
 package executor
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
@@ -41,14 +43,34 @@ func newTableNotFoundError(tableName string) error {
 // Execute is the main entry point for executing execution plans
 // It dispatches to the appropriate executor based on node type using tree walking
 func Execute(node plan.Node, db *schema.Database, tx *transaction.Transaction) (*Result, error) {
-	ctx := &ExecutionContext{
+	return ExecuteContext(context.Background(), node, db, tx)
+}
+
+// ExecuteContext executes a plan like Execute, stopping with an error wrapping
+// the context's error once goCtx is cancelled or times out
+func ExecuteContext(goCtx context.Context, node plan.Node, db *schema.Database, tx *transaction.Transaction) (*Result, error) {
+	return ExecuteWith(node, &ExecutionContext{
 		Database:    db,
 		Transaction: tx,
 		Config:      DefaultExecutionConfig(),
-	}
+		Context:     goCtx,
+	})
+}
+
+// ExecuteWith executes a plan with a caller-provided context, such as a
+// session's configuration or a memory budget shared with the caller
+func ExecuteWith(node plan.Node, ctx *ExecutionContext) (*Result, error) {
+	db := ctx.Database
 
-	// Execute the plan tree recursively
-	intermediate, err := executeNode(node, ctx)
+	// Execute the plan tree with the configured strategy
+	intermediate, err := (&DefaultStrategy{}).Execute(node, ctx)
+	if ctx.Memory != nil && ctx.Memory.SpilledRuns() > 0 {
+		slog.Debug("Query spilled to disk",
+			slog.Int("runs", ctx.Memory.SpilledRuns()),
+			slog.Int64("bytes", ctx.Memory.SpilledBytes()),
+			slog.Int64("peak_memory", ctx.Memory.Peak()),
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +85,14 @@ func Execute(node plan.Node, db *schema.Database, tx *transaction.Transaction) (
 		return formatUpdateResult(intermediate), nil
 	case *plan.DeleteNode:
 		return formatDeleteResult(intermediate), nil
+	case *plan.CreateIndexNode:
+		return formatCreateIndexResult(intermediate), nil
+	case *plan.AnalyzeNode:
+		return formatAnalyzeResult(intermediate), nil
+	case *plan.ExplainNode:
+		return formatExplainResult(intermediate), nil
+	case *plan.CopyFromNode, *plan.CopyToNode:
+		return formatCopyResult(intermediate), nil
 	default:
 		return nil, fmt.Errorf("unsupported plan node type: %T", node)
 	}
@@ -77,20 +107,30 @@ func executeNode(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, er
 			Metadata: map[string]interface{}{},
 		}, nil
 	}
+	if err := ctx.checkCancelled(); err != nil {
+		return nil, err
+	}
 
 	switch n := node.(type) {
-	case *plan.ScanNode:
-		return executeScan(n, ctx)
-	case *plan.JoinNode:
-		return executeJoinNode(n, ctx)
-	case *plan.SelectNode:
-		return executeSelectNode(n, ctx)
+	case *plan.ScanNode, *plan.IndexScanNode, *plan.JoinNode, *plan.SelectNode:
+		// Row-producing nodes run as a pipeline of operators
+		return executeQuery(n, ctx)
 	case *plan.InsertNode:
 		return executeInsertNode(n, ctx)
 	case *plan.UpdateNode:
 		return executeUpdateNode(n, ctx)
 	case *plan.DeleteNode:
 		return executeDeleteNode(n, ctx)
+	case *plan.CreateIndexNode:
+		return executeCreateIndexNode(n, ctx)
+	case *plan.AnalyzeNode:
+		return executeAnalyzeNode(n, ctx)
+	case *plan.ExplainNode:
+		return executeExplainNode(n, ctx)
+	case *plan.CopyFromNode:
+		return executeCopyFromNode(n, ctx)
+	case *plan.CopyToNode:
+		return executeCopyToNode(n, ctx)
 	default:
 		return nil, fmt.Errorf("unsupported plan node type: %T", node)
 	}
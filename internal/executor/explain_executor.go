@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/plan"
+)
+
+// explainColumn is the single column of EXPLAIN results
+const explainColumn = "QUERY PLAN"
+
+// nodeStats is what one plan node actually did during EXPLAIN ANALYZE
+type nodeStats struct {
+	rows    int64
+	loops   int64 // times the node's operator was opened
+	elapsed time.Duration
+}
+
+// nodeStats returns the statistics of node, creating them on first use
+func (c *ExecutionContext) nodeStats(node plan.Node) *nodeStats {
+	stats, ok := c.analyze[node]
+	if !ok {
+		stats = &nodeStats{}
+		c.analyze[node] = stats
+	}
+	return stats
+}
+
+// executeExplainNode handles EXPLAIN [ANALYZE] using tree-walking pattern
+// With Analyze the explained plan is run first, and what each node did is
+// attached to its metadata before the tree is rendered.
+func executeExplainNode(node *plan.ExplainNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+	if node.Analyze {
+		if err := analyzePlan(node, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []data.Row
+	if node.Format == "JSON" {
+		tree := map[string]any{"plan": plan.ExplainTree(node.Plan)}
+		if node.Analyze {
+			tree["execution_time_ms"] = node.Metadata()["execution_time_ms"]
+		}
+		out, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode plan: %w", err)
+		}
+		rows = append(rows, explainRow(string(out)))
+	} else {
+		for _, line := range plan.ExplainLines(node.Plan) {
+			rows = append(rows, explainRow(line))
+		}
+		if node.Analyze {
+			rows = append(rows, explainRow(fmt.Sprintf("Execution Time: %.3f ms", node.Metadata()["execution_time_ms"])))
+		}
+	}
+
+	return &IntermediateResult{
+		Rows: rows,
+		Schema: &schema.TableSchema{
+			Columns: []schema.Column{{Name: explainColumn, Type: schema.ColumnTypeText}},
+		},
+		Metadata: map[string]interface{}{
+			"operation": "EXPLAIN",
+		},
+	}, nil
+}
+
+// analyzePlan runs the explained plan with every operator measured and
+// records actual_rows, actual_loops and actual_time_ms on each node
+// Nodes that never ran get actual_loops 0.
+func analyzePlan(node *plan.ExplainNode, ctx *ExecutionContext) error {
+	ctx.analyze = make(map[plan.Node]*nodeStats)
+	defer func() { ctx.analyze = nil }()
+
+	start := time.Now()
+	result, err := executeNode(node.Plan, ctx)
+	elapsed := time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	// INSERT, UPDATE and DELETE do not run as operators and are measured as
+	// a whole
+	if _, ok := ctx.analyze[node.Plan]; !ok {
+		affected, _ := result.Metadata["rows_affected"].(int)
+		ctx.analyze[node.Plan] = &nodeStats{rows: int64(affected), loops: 1, elapsed: elapsed}
+	}
+
+	plan.WalkTree(node.Plan, func(n plan.Node) error {
+		stats := ctx.nodeStats(n)
+		n.Metadata()["actual_rows"] = stats.rows
+		n.Metadata()["actual_loops"] = stats.loops
+		n.Metadata()["actual_time_ms"] = milliseconds(stats.elapsed)
+		return nil
+	})
+	node.Metadata()["execution_time_ms"] = milliseconds(elapsed)
+	return nil
+}
+
+// milliseconds converts d to milliseconds, rounded to microseconds
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())) / 1000
+}
+
+// explainRow creates a result row holding one line of the plan
+func explainRow(line string) data.Row {
+	return data.NewRow(map[string]interface{}{explainColumn: line})
+}
+
+// analyzedOperator measures the rows, loops and time of the operator of one
+// plan node
+// Time includes the node's inputs, as they run inside its Open and Next.
+type analyzedOperator struct {
+	input Operator
+	stats *nodeStats
+}
+
+func (o *analyzedOperator) Open() error {
+	start := time.Now()
+	o.stats.loops++
+	err := o.input.Open()
+	o.stats.elapsed += time.Since(start)
+	return err
+}
+
+func (o *analyzedOperator) Next() (data.Row, bool, error) {
+	start := time.Now()
+	row, ok, err := o.input.Next()
+	o.stats.elapsed += time.Since(start)
+	if ok {
+		o.stats.rows++
+	}
+	return row, ok, err
+}
+
+func (o *analyzedOperator) Close() error {
+	start := time.Now()
+	err := o.input.Close()
+	o.stats.elapsed += time.Since(start)
+	return err
+}
+
+func (o *analyzedOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"log/slog"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
+)
+
+// graceInput is a join input being partitioned: rows already read into
+// memory followed by the unread rest of an open operator (nil if none)
+type graceInput struct {
+	rows []data.Row
+	rest Operator
+}
+
+// graceJoin is the state of a grace hash join
+// Both inputs are split into the same number of partitions by the hash of
+// their join keys, so rows that join always land in partitions with the same
+// number. Each pair is then joined with an in-memory hash join on the inner
+// partition. Rows with a NULL key join nothing and go to partition 0, where
+// outer joins still NULL-extend them.
+type graceJoin struct {
+	left, right           *spill.Partitions
+	leftTable, rightTable *schema.Table // name and schema of each input
+	inner                 join.Side
+	next                  int   // next partition to join
+	reserved              int64 // memory budget held by the loaded inner partition
+}
+
+// openGrace partitions both inputs to disk and starts joining the first
+// partition pair
+// The rest operators are read to the end and closed.
+func (o *joinOperator) openGrace(leftTable, rightTable *schema.Table, left, right graceInput, inner join.Side) (err error) {
+	defer func() {
+		for _, op := range []Operator{left.rest, right.rest} {
+			if op == nil {
+				continue
+			}
+			if closeErr := op.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}()
+
+	leftColumns, rightColumns, err := join.KeyColumns(leftTable, rightTable, o.cond, o.node.JoinType)
+	if err != nil {
+		return err
+	}
+
+	budget, dir := o.ctx.memory(), o.ctx.spillDir()
+	o.grace = &graceJoin{
+		left:       spill.NewPartitions(budget, dir, spillFanout, 0),
+		right:      spill.NewPartitions(budget, dir, spillFanout, 0),
+		leftTable:  &schema.Table{Name: leftTable.Name, Schema: leftTable.Schema},
+		rightTable: &schema.Table{Name: rightTable.Name, Schema: rightTable.Schema},
+		inner:      inner,
+	}
+	slog.Debug(o.node.JoinType.String()+" exceeds the memory budget, partitioning inputs",
+		slog.String("inner", inner.String()),
+		slog.Int("partitions", spillFanout),
+	)
+
+	if err := o.partition(o.grace.left, left, leftColumns); err != nil {
+		return err
+	}
+	if err := o.partition(o.grace.right, right, rightColumns); err != nil {
+		return err
+	}
+	// The rows buffered before the join spilled are on disk now
+	budget.Release(o.reserved)
+	o.reserved = 0
+
+	_, err = o.nextPartition()
+	return err
+}
+
+// partition writes every row of an input to the partition of its join key
+func (o *joinOperator) partition(parts *spill.Partitions, input graceInput, columns []string) error {
+	write := func(row data.Row) error {
+		i := 0
+		if key, ok := join.HashKey(row, columns); ok {
+			i = parts.Of(key)
+		}
+		return parts.Write(i, row)
+	}
+
+	for _, row := range input.rows {
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	if input.rest == nil {
+		return nil
+	}
+	for count := 1; ; count++ {
+		row, ok, err := input.rest.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if count%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return err
+			}
+		}
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+}
+
+// nextPartition loads the next inner partition and streams the matching
+// outer partition against it
+// Returns false once every partition pair has been joined. A partition is
+// loaded whole, even if it alone exceeds the memory budget.
+func (o *joinOperator) nextPartition() (bool, error) {
+	g := o.grace
+	budget := o.ctx.memory()
+	budget.Release(g.reserved)
+	g.reserved = 0
+
+	innerParts, outerParts := g.right, g.left
+	innerTable, outerTable := g.rightTable, g.leftTable
+	if g.inner == join.SideLeft {
+		innerParts, outerParts = outerParts, innerParts
+		innerTable, outerTable = outerTable, innerTable
+	}
+
+	for ; g.next < innerParts.Count(); g.next++ {
+		i := g.next
+		if innerParts.Run(i) == nil && outerParts.Run(i) == nil {
+			continue
+		}
+		if err := o.ctx.checkCancelled(); err != nil {
+			return false, err
+		}
+
+		innerRows, err := innerParts.Open(i)
+		if err != nil {
+			return false, err
+		}
+		rows := make([]data.Row, 0)
+		for {
+			row, ok, err := innerRows.Next()
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				break
+			}
+			size := spill.RowSize(row)
+			if !budget.Reserve(size) {
+				budget.Force(size)
+			}
+			g.reserved += size
+			rows = append(rows, row)
+		}
+		outerRows, err := outerParts.Open(i)
+		if err != nil {
+			return false, err
+		}
+
+		partitionInner := &schema.Table{Name: innerTable.Name, Schema: innerTable.Schema, Rows: rows}
+		left, right := outerTable, partitionInner
+		if g.inner == join.SideLeft {
+			left, right = partitionInner, outerTable
+		}
+		stream, err := join.NewStream(left, right, o.cond, o.node.JoinType,
+			join.Strategy{Algorithm: join.AlgorithmHash, Inner: g.inner})
+		if err != nil {
+			return false, err
+		}
+		o.stream, o.nextOuter, o.done = stream, outerRows.Next, false
+		g.next++
+		return true, nil
+	}
+	return false, nil
+}
+
+// close deletes the partition files and releases the loaded partition
+func (g *graceJoin) close(budget *spill.Budget) error {
+	budget.Release(g.reserved)
+	g.reserved = 0
+	err := g.left.Remove()
+	if rightErr := g.right.Remove(); err == nil {
+		err = rightErr
+	}
+	return err
+}
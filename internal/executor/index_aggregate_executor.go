@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/plan"
+)
+
+// indexAggregateOperator produces the single row of a SELECT whose
+// aggregates are MIN and MAX of columns with ordered indexes, reading each
+// from the ends of its index
+// Only index keys are read, so a paged table stays on disk. Falls back to
+// scanning and aggregating the table when indexes are disabled or an index
+// no longer exists.
+type indexAggregateOperator struct {
+	node     *plan.SelectNode
+	ctx      *ExecutionContext
+	table    *schema.Table
+	row      *data.Row
+	scan     *aggregateOperator
+	fallback bool
+}
+
+func newIndexAggregateOperator(node *plan.SelectNode, ctx *ExecutionContext) (*indexAggregateOperator, error) {
+	scan, err := newScanOperator(&plan.ScanNode{
+		TableName:   node.TableName,
+		Transaction: node.Transaction,
+	}, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &indexAggregateOperator{
+		node:  node,
+		ctx:   ctx,
+		table: scan.table,
+		scan:  newAggregateOperator(scan, node, ctx),
+	}, nil
+}
+
+func (o *indexAggregateOperator) Open() error {
+	o.row, o.fallback = nil, false
+
+	if o.ctx.Config.UseIndexes {
+		values := make(map[string]interface{}, len(o.node.Aggregates))
+		found := true
+		for _, agg := range o.node.Aggregates {
+			min, max, _, ok := o.table.MinMax(agg.Column.Column)
+			if !ok {
+				found = false
+				break
+			}
+			if agg.Function == "MIN" {
+				values[agg.Name] = min
+			} else {
+				values[agg.Name] = max
+			}
+		}
+		if found {
+			row := data.NewRow(values)
+			o.row = &row
+			return nil
+		}
+	}
+
+	o.fallback = true
+	return o.scan.Open()
+}
+
+func (o *indexAggregateOperator) Next() (data.Row, bool, error) {
+	if o.fallback {
+		return o.scan.Next()
+	}
+	if o.row == nil {
+		return data.Row{}, false, nil
+	}
+	row := *o.row
+	o.row = nil
+	return row, true, nil
+}
+
+func (o *indexAggregateOperator) Close() error {
+	o.row = nil
+	if o.fallback {
+		return o.scan.Close()
+	}
+	return nil
+}
+
+func (o *indexAggregateOperator) Schema() *schema.TableSchema {
+	return o.scan.Schema()
+}
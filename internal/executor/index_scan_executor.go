@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
+)
+
+// indexScanOperator reads a table through an index (leaf operator)
+// Open fetches the rows the index selects; Next applies the residual
+// predicate. Index positions address rows in memory, so a paged table is
+// loaded first. Falls back to a sequential scan with the full predicate when
+// indexes are disabled or the index no longer exists; the rows of an ordered
+// scan are then sorted by the index column.
+type indexScanOperator struct {
+	node     *plan.IndexScanNode
+	ctx      *ExecutionContext
+	table    *schema.Table
+	rows     []data.Row
+	pos      int
+	fallback Operator
+}
+
+func newIndexScanOperator(node *plan.IndexScanNode, ctx *ExecutionContext) (*indexScanOperator, error) {
+	table, ok := ctx.Database.Tables[node.TableName]
+	if !ok {
+		return nil, newTableNotFoundError(node.TableName)
+	}
+	return &indexScanOperator{node: node, ctx: ctx, table: table}, nil
+}
+
+func (o *indexScanOperator) Open() error {
+	o.rows, o.pos, o.fallback = nil, 0, nil
+
+	found := false
+	if o.ctx.Config.UseIndexes {
+		if err := o.table.LoadRows(); err != nil {
+			return err
+		}
+		switch {
+		case o.node.IsRange():
+			o.rows, found = o.table.SelectRange(o.node.IndexColumn, o.node.Lower, o.node.Upper, o.node.Descending, o.ctx.Transaction)
+		case len(o.node.Values) == 1 && o.hasUniqueIndex():
+			// Point lookup on a primary key / unique column
+			if row, ok := o.table.SelectByIndex(o.node.IndexColumn, o.node.Values[0], o.ctx.Transaction); ok {
+				o.rows = []data.Row{row}
+			}
+			found = true
+		default:
+			o.rows, found = o.table.SelectByIndexValues(o.node.IndexColumn, o.node.Values, o.ctx.Transaction)
+		}
+	}
+	if found {
+		return nil
+	}
+
+	o.fallback = &scanOperator{
+		node: &plan.ScanNode{
+			TableName:   o.node.TableName,
+			Predicate:   o.node.Predicate,
+			Transaction: o.node.Transaction,
+		},
+		ctx:   o.ctx,
+		table: o.table,
+	}
+	if o.node.Ordered {
+		o.fallback = &sortOperator{
+			input: o.fallback,
+			ctx:   o.ctx,
+			keys:  []plan.SortKey{{Column: projection.ColumnRef{Column: o.node.IndexColumn}, Desc: o.node.Descending}},
+		}
+	}
+	return o.fallback.Open()
+}
+
+func (o *indexScanOperator) Next() (data.Row, bool, error) {
+	if o.fallback != nil {
+		return o.fallback.Next()
+	}
+	for o.pos < len(o.rows) {
+		if o.pos%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return data.Row{}, false, err
+			}
+		}
+		row := o.rows[o.pos]
+		o.pos++
+		if o.node.Residual == nil || o.node.Residual(row) {
+			return row, true, nil
+		}
+	}
+	return data.Row{}, false, nil
+}
+
+func (o *indexScanOperator) Close() error {
+	o.rows = nil
+	if o.fallback != nil {
+		return o.fallback.Close()
+	}
+	return nil
+}
+
+func (o *indexScanOperator) Schema() *schema.TableSchema {
+	return o.table.Schema
+}
+
+// hasUniqueIndex reports whether the scanned column has a unique index
+func (o *indexScanOperator) hasUniqueIndex() bool {
+	o.table.RLock()
+	defer o.table.RUnlock()
+
+	idx, exists := o.table.Indexes[o.node.IndexColumn]
+	return exists && idx.Unique
+}
@@ -1,8 +1,14 @@
 package executor
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
 	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
 )
 
 // executeInsertNode handles INSERT using tree-walking pattern
@@ -12,8 +18,12 @@ func executeInsertNode(node *plan.InsertNode, ctx *ExecutionContext) (*Intermedi
 		return nil, newTableNotFoundError(node.TableName)
 	}
 
-	// Insert the row using domain model
-	if err := table.Insert(node.Row, ctx.Transaction); err != nil {
+	// Insert the rows using domain model
+	// A failing row stops the statement and leaves the table unchanged.
+	if i, err := insertRows(ctx.Database, table, node.Rows, ctx.Transaction); err != nil {
+		if len(node.Rows) > 1 {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
 		return nil, err
 	}
 
@@ -22,7 +32,44 @@ func executeInsertNode(node *plan.InsertNode, ctx *ExecutionContext) (*Intermedi
 		Schema: nil,
 		Metadata: map[string]interface{}{
 			"operation":     "INSERT",
-			"rows_affected": 1,
+			"rows_affected": len(node.Rows),
 		},
 	}, nil
 }
+
+// insertRows inserts rows into a table of db as one unit: if a row fails,
+// the rows inserted before it are deleted again, and the index of the
+// failing row is returned with its error
+// The inserts are recorded in tx to be undone even when tx does not record
+// changes otherwise. When it does, the deletes undoing them are recorded
+// too, so the log replays to the same rows. The auto-increment sequence is
+// not moved back.
+func insertRows(db *schema.Database, table *schema.Table, rows []data.Row, tx *transaction.Transaction) (int, error) {
+	if tx == nil {
+		tx = transaction.NewTransaction()
+		defer tx.Close()
+	}
+	recording, start := tx.RecordChanges, len(tx.Changes)
+	tx.RecordChanges = true
+	defer func() {
+		if !recording {
+			tx.Changes = tx.Changes[:start]
+		}
+		tx.RecordChanges = recording
+	}()
+
+	for i, row := range rows {
+		err := table.Insert(row, tx)
+		if err == nil {
+			continue
+		}
+		inserted := tx.Changes[start:]
+		for j := len(inserted) - 1; j >= 0; j-- {
+			if undoErr := archive.Undo(db, inserted[j], tx); undoErr != nil {
+				return i, errors.Join(err, fmt.Errorf("failed to remove the rows inserted before it: %w", undoErr))
+			}
+		}
+		return i, err
+	}
+	return len(rows), nil
+}
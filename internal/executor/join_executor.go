@@ -2,84 +2,406 @@ package executor
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/plan"
 	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
 )
 
-// executeJoinNode recursively executes JOIN using tree-walking pattern
-// This enables multi-way JOINs by recursively executing left/right children
-func executeJoinNode(node *plan.JoinNode, ctx *ExecutionContext) (*IntermediateResult, error) {
-	// Recursively execute left child
-	leftResult, err := executeNode(node.Left(), ctx)
+// joinOperator joins the rows of its two child operators
+// The inner input (the hash join build side, or the side probed through its
+// index) is held in memory while the outer input streams through one row at a
+// time - or, with parallel execution on, one batch at a time probed on
+// several workers. Merge joins sort both inputs, so they read both completely
+// before producing any rows.
+//
+// An inner input read from a child operator counts against the query's
+// memory budget. If it does not fit, the join becomes a grace hash join: both
+// inputs are partitioned to disk by the hash of their join keys and joined
+// one partition pair at a time (see grace_join_executor.go). Stored tables
+// joined directly are not copied and not counted.
+type joinOperator struct {
+	node        *plan.JoinNode
+	ctx         *ExecutionContext
+	left, right Operator
+	schema      *schema.TableSchema
+	strategy    join.Strategy
+	cond        join.Condition
+
+	outer     Operator // streamed input (nil for merge joins)
+	nextOuter func() (data.Row, bool, error)
+	stream    *join.Stream
+	grace     *graceJoin    // set once the join spilled
+	reserved  int64         // memory budget held by the inner rows
+	locked    *schema.Table // stored inner table, read-locked until Close
+	pending   []data.JoinedRow
+	merged    []data.JoinedRow // merge join result
+	done      bool             // outer input exhausted
+	rows      int              // rows produced so far
+	probes    int              // outer rows probed so far
+}
+
+func newJoinOperator(node *plan.JoinNode, ctx *ExecutionContext) (*joinOperator, error) {
+	left, err := buildOperator(node.Left(), ctx)
 	if err != nil {
 		return nil, fmt.Errorf("left child execution failed: %w", err)
 	}
-
-	// Recursively execute right child
-	rightResult, err := executeNode(node.Right(), ctx)
+	right, err := buildOperator(node.Right(), ctx)
 	if err != nil {
 		return nil, fmt.Errorf("right child execution failed: %w", err)
 	}
 
-	// Get table names from metadata (for qualified column names)
-	leftTableName := extractTableName(node.Left())
-	rightTableName := extractTableName(node.Right())
-
-	// Create temporary in-memory tables from child results using the propagated schema
-	leftTable := createTempTable(leftTableName, leftResult.Rows, leftResult.Schema)
-	rightTable := createTempTable(rightTableName, rightResult.Rows, rightResult.Schema)
+	strategy := node.Strategy
+	if ctx.Config.JoinAlgorithm != "" {
+		algorithm, ok := join.ParseAlgorithm(ctx.Config.JoinAlgorithm)
+		if !ok {
+			return nil, fmt.Errorf("unknown join algorithm: %s", ctx.Config.JoinAlgorithm)
+		}
+		strategy.Algorithm = algorithm
+	}
+	strategy.Workers = ctx.workers()
 
-	// Execute JOIN using existing join operations
-	joinedRows, err := join.ExecuteJoin(
-		leftTable,
-		rightTable,
-		node.LeftOnCol,
-		node.RightOnCol,
-		node.JoinType,
-		nil, // No additional predicate at this level
-		nil, // No projection at this level
-		ctx.Transaction,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("JOIN execution failed: %w", err)
+	cond := join.Condition{Keys: node.Keys()}
+	if len(cond.Keys) == 0 {
+		// Without join keys every pair of rows has to be compared
+		strategy.Algorithm = join.AlgorithmNestedLoop
+	}
+	if node.OnPredicate != nil {
+		cond.Filter = func(row data.JoinedRow) bool {
+			return node.OnPredicate(data.NewRow(row.Data))
+		}
 	}
 
 	// Build the schema for the joined result (qualified names)
+	leftSchema, rightSchema := left.Schema(), right.Schema()
 	joinedSchema := &schema.TableSchema{
-		Columns: make([]schema.Column, 0, len(leftTable.Schema.Columns)+len(rightTable.Schema.Columns)),
+		Columns: make([]schema.Column, 0, len(leftSchema.Columns)+len(rightSchema.Columns)),
 	}
-	for _, col := range leftTable.Schema.Columns {
+	for _, col := range leftSchema.Columns {
 		joinedSchema.Columns = append(joinedSchema.Columns, schema.Column{
-			Name: fmt.Sprintf("%s.%s", leftTableName, col.Name),
+			Name: join.QualifiedName(extractTableName(node.Left()), col.Name),
 			Type: col.Type,
 		})
 	}
-	for _, col := range rightTable.Schema.Columns {
+	for _, col := range rightSchema.Columns {
 		joinedSchema.Columns = append(joinedSchema.Columns, schema.Column{
-			Name: fmt.Sprintf("%s.%s", rightTableName, col.Name),
+			Name: join.QualifiedName(extractTableName(node.Right()), col.Name),
 			Type: col.Type,
 		})
 	}
 
-	// Convert JoinedRow back to Row
-	rows := make([]data.Row, len(joinedRows))
-	for i, jr := range joinedRows {
-		rows[i] = data.NewRow(jr.Data)
+	return &joinOperator{
+		node:     node,
+		ctx:      ctx,
+		left:     left,
+		right:    right,
+		schema:   joinedSchema,
+		strategy: strategy,
+		cond:     cond,
+	}, nil
+}
+
+func (o *joinOperator) Open() error {
+	o.pending, o.merged, o.done, o.rows, o.probes = nil, nil, false, 0, 0
+
+	if o.strategy.Algorithm == join.AlgorithmMerge {
+		return o.openMerge()
 	}
 
-	return &IntermediateResult{
+	// The outer input is opened first, before the inner table is locked
+	outerNode, innerNode := o.node.Left(), o.node.Right()
+	o.outer = o.left
+	innerOp := o.right
+	if o.strategy.Inner == join.SideLeft {
+		outerNode, innerNode = innerNode, outerNode
+		o.outer, innerOp = innerOp, o.outer
+	}
+	if err := o.outer.Open(); err != nil {
+		return err
+	}
+	o.nextOuter = o.outer.Next
+
+	inner, spilled, err := o.joinInput(innerNode, innerOp, true)
+	if err != nil {
+		return err
+	}
+	outer := &schema.Table{Name: extractTableName(outerNode), Schema: o.outer.Schema()}
+
+	if spilled {
+		innerInput := graceInput{rows: inner.Rows, rest: innerOp}
+		outerInput := graceInput{rest: o.outer}
+		o.outer = nil
+		if o.strategy.Inner == join.SideLeft {
+			return o.openGrace(inner, outer, innerInput, outerInput, join.SideLeft)
+		}
+		return o.openGrace(outer, inner, outerInput, innerInput, join.SideRight)
+	}
+
+	leftTable, rightTable := outer, inner
+	if o.strategy.Inner == join.SideLeft {
+		leftTable, rightTable = inner, outer
+	}
+	stream, err := join.NewStream(leftTable, rightTable, o.cond, o.node.JoinType, o.strategy)
+	if err != nil {
+		return fmt.Errorf("JOIN execution failed: %w", err)
+	}
+	o.stream = stream
+	return nil
+}
+
+// openMerge runs a merge join, which needs both inputs before the first row
+// Inputs that do not fit the memory budget are joined with a grace hash join
+// instead.
+func (o *joinOperator) openMerge() error {
+	leftTable, spilled, err := o.joinInput(o.node.Left(), o.left, false)
+	if err != nil {
+		return fmt.Errorf("left child execution failed: %w", err)
+	}
+	if spilled {
+		if err := o.right.Open(); err != nil {
+			o.right.Close()
+			return fmt.Errorf("right child execution failed: %w", err)
+		}
+		rightTable := &schema.Table{Name: extractTableName(o.node.Right()), Schema: o.right.Schema()}
+		return o.openGrace(leftTable, rightTable,
+			graceInput{rows: leftTable.Rows, rest: o.left}, graceInput{rest: o.right}, join.SideRight)
+	}
+	rightTable, spilled, err := o.joinInput(o.node.Right(), o.right, false)
+	if err != nil {
+		return fmt.Errorf("right child execution failed: %w", err)
+	}
+	if spilled {
+		return o.openGrace(leftTable, rightTable,
+			graceInput{rows: leftTable.Rows}, graceInput{rows: rightTable.Rows, rest: o.right}, join.SideRight)
+	}
+	if err := o.ctx.checkCancelled(); err != nil {
+		return err
+	}
+
+	joinedRows, err := join.ExecuteJoinOn(
+		leftTable,
+		rightTable,
+		o.cond,
+		o.node.JoinType,
+		o.strategy,
+		nil, // No additional predicate at this level
+		nil, // No projection at this level
+		o.ctx.Transaction,
+	)
+	if err != nil {
+		return fmt.Errorf("JOIN execution failed: %w", err)
+	}
+	o.merged = joinedRows
+	return nil
+}
+
+// joinInput produces the table a join holds one of its inputs in
+// An unfiltered scan joins the stored table directly so its indexes can serve
+// index nested-loop and merge joins; lock keeps it read-locked until Close.
+// A paged table is not in memory to join directly; it and any other input
+// is read into a table of its own while the memory budget allows. When a row does not fit, spilled is true: the table holds the rows
+// read so far and op is left open with the rest unread. Inputs without join
+// keys cannot be partitioned and are read completely regardless.
+func (o *joinOperator) joinInput(node plan.Node, op Operator, lock bool) (table *schema.Table, spilled bool, err error) {
+	if scan, ok := node.(*plan.ScanNode); ok && scan.Predicate == nil {
+		table, ok := o.ctx.Database.Tables[scan.TableName]
+		if !ok {
+			return nil, false, newTableNotFoundError(scan.TableName)
+		}
+		if !table.Paged() {
+			if lock {
+				table.RLock()
+				o.locked = table
+			}
+			if o.ctx.analyze != nil {
+				// The scan's operator is bypassed, so its rows are counted here
+				stats := o.ctx.nodeStats(node)
+				stats.loops++
+				stats.rows += int64(len(table.Rows))
+			}
+			return table, false, nil
+		}
+	}
+
+	if err := op.Open(); err != nil {
+		op.Close()
+		return nil, false, err
+	}
+	budget := o.ctx.memory()
+	rows := make([]data.Row, 0)
+	for {
+		row, ok, err := op.Next()
+		if err != nil {
+			op.Close()
+			return nil, false, err
+		}
+		if !ok {
+			break
+		}
+		size := spill.RowSize(row)
+		if !budget.Reserve(size) {
+			if len(o.cond.Keys) > 0 {
+				// Kept unreserved: it is partitioned with the rest
+				rows = append(rows, row)
+				spilled = true
+				break
+			}
+			budget.Force(size)
+		}
+		o.reserved += size
+		rows = append(rows, row)
+	}
+	if !spilled {
+		if err := op.Close(); err != nil {
+			return nil, false, err
+		}
+	}
+	return &schema.Table{
+		Name:   extractTableName(node),
 		Rows:   rows,
-		Schema: joinedSchema,
-		Metadata: map[string]interface{}{
-			"join_type":   node.JoinType,
-			"left_rows":   len(leftResult.Rows),
-			"right_rows":  len(rightResult.Rows),
-			"result_rows": len(rows),
-		},
-	}, nil
+		Schema: op.Schema(),
+	}, spilled, nil
+}
+
+func (o *joinOperator) Next() (data.Row, bool, error) {
+	if o.stream == nil {
+		if len(o.merged) == 0 {
+			return data.Row{}, false, nil
+		}
+		row := o.merged[0]
+		o.merged = o.merged[1:]
+		o.rows++
+		return data.NewRow(row.Data), true, nil
+	}
+
+	for {
+		if len(o.pending) > 0 {
+			row := o.pending[0]
+			o.pending = o.pending[1:]
+			o.rows++
+			return data.NewRow(row.Data), true, nil
+		}
+		if !o.done && o.strategy.Workers > 1 {
+			if err := o.probeBatch(); err != nil {
+				return data.Row{}, false, err
+			}
+			continue
+		}
+		if !o.done {
+			row, ok, err := o.nextOuter()
+			if err != nil {
+				return data.Row{}, false, err
+			}
+			if ok {
+				if err := o.checkCancelled(); err != nil {
+					return data.Row{}, false, err
+				}
+				o.pending = o.stream.Probe(row)
+				continue
+			}
+			o.done = true
+		}
+
+		// Outer input exhausted: NULL-extend the unmatched inner rows
+		row, ok := o.stream.NextUnmatched()
+		if ok {
+			o.rows++
+			return data.NewRow(row.Data), true, nil
+		}
+		if o.grace == nil {
+			return data.Row{}, false, nil
+		}
+		more, err := o.nextPartition()
+		if err != nil || !more {
+			return data.Row{}, false, err
+		}
+	}
+}
+
+// probeBatch reads a batch of outer rows and probes them on the join's
+// workers, queueing the joined rows in outer row order
+// Nested-loop probes compare each row with the whole inner input, so their
+// batches hold a few rows per worker to keep cancellation prompt.
+func (o *joinOperator) probeBatch() error {
+	size := morselSize
+	if o.stream.Algorithm() == join.AlgorithmNestedLoop {
+		size = 4 * o.strategy.Workers
+	}
+	if err := o.ctx.checkCancelled(); err != nil {
+		return err
+	}
+
+	batch := make([]data.Row, 0, size)
+	for len(batch) < size {
+		row, ok, err := o.nextOuter()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		batch = append(batch, row)
+	}
+	if len(batch) == 0 {
+		o.done = true
+		return nil
+	}
+	o.probes += len(batch)
+	for _, joined := range o.stream.ProbeBatch(batch) {
+		o.pending = append(o.pending, joined...)
+	}
+	return nil
+}
+
+// checkCancelled checks for cancellation before an outer row is probed
+// A nested-loop probe compares the row with every inner row, so that join is
+// checked on every probe rather than every cancelCheckInterval rows.
+func (o *joinOperator) checkCancelled() error {
+	o.probes++
+	if o.stream.Algorithm() != join.AlgorithmNestedLoop && o.probes%cancelCheckInterval != 0 {
+		return nil
+	}
+	return o.ctx.checkCancelled()
+}
+
+func (o *joinOperator) Close() error {
+	var err error
+	if o.outer != nil {
+		err = o.outer.Close()
+		o.outer = nil
+	}
+	if o.locked != nil {
+		o.locked.RUnlock()
+		o.locked = nil
+	}
+	if o.grace != nil {
+		if closeErr := o.grace.close(o.ctx.memory()); err == nil {
+			err = closeErr
+		}
+	}
+	if o.stream != nil {
+		attrs := []any{
+			slog.String("algorithm", string(o.stream.Algorithm())),
+			slog.Int("result_rows", o.rows),
+			slog.Bool("exhausted", o.done),
+		}
+		if o.grace != nil {
+			attrs = append(attrs, slog.Int("spilled_partitions", o.grace.left.Count()))
+		}
+		slog.Debug(o.node.JoinType.String()+" closed", attrs...)
+		o.stream = nil
+	}
+	o.grace = nil
+	o.ctx.memory().Release(o.reserved)
+	o.pending, o.merged, o.reserved = nil, nil, 0
+	return err
+}
+
+func (o *joinOperator) Schema() *schema.TableSchema {
+	return o.schema
 }
 
 // extractTableName extracts table name from a plan node
@@ -87,34 +409,14 @@ func extractTableName(node plan.Node) string {
 	switch n := node.(type) {
 	case *plan.ScanNode:
 		return n.TableName
+	case *plan.IndexScanNode:
+		return n.TableName
 	case *plan.SelectNode:
 		return n.TableName
 	case *plan.JoinNode:
-		// Recursive join names can be complex, use a placeholder
-		return fmt.Sprintf("join_%p", n)
+		// A join result is named after the tables it joins, e.g. "orders_users"
+		return extractTableName(n.Left()) + "_" + extractTableName(n.Right())
 	default:
 		return "temp_table"
 	}
 }
-
-// createTempTable creates an in-memory table from rows and explicit schema
-func createTempTable(tableName string, rows []data.Row, tableSchema *schema.TableSchema) *schema.Table {
-	if tableSchema == nil {
-		// Fallback for nodes that don't provide schema (should be fixed in all nodes)
-		tableSchema = &schema.TableSchema{Columns: []schema.Column{}}
-		if len(rows) > 0 {
-			for colName := range rows[0].Data {
-				tableSchema.Columns = append(tableSchema.Columns, schema.Column{
-					Name: colName,
-					Type: schema.ColumnTypeText,
-				})
-			}
-		}
-	}
-
-	return &schema.Table{
-		Name:   tableName,
-		Rows:   rows,
-		Schema: tableSchema,
-	}
-}
@@ -0,0 +1,234 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/plan"
+)
+
+// Operator is a pull-based (Volcano-style) iterator over the rows of a plan
+// node
+//
+// Open prepares the operator and opens its inputs, Next returns one row at a
+// time (ok is false once the rows are exhausted) and Close releases whatever
+// Open acquired; Close is also called after a failed Open. Rows flow through
+// a tree of operators without being collected at every node, and a consumer
+// that stops early - such as LIMIT - stops all upstream work with it.
+type Operator interface {
+	Open() error
+	Next() (row data.Row, ok bool, err error)
+	Close() error
+	// Schema describes the columns of the rows produced
+	Schema() *schema.TableSchema
+}
+
+// buildOperator creates the operator tree for a row-producing plan node
+// TreeWalkingStrategy wraps every operator to materialize its output, and
+// EXPLAIN ANALYZE to measure its node.
+func buildOperator(node plan.Node, ctx *ExecutionContext) (Operator, error) {
+	op, err := newOperator(node, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.materialize {
+		op = &materializeOperator{input: op}
+	}
+	if ctx.analyze != nil {
+		op = &analyzedOperator{input: op, stats: ctx.nodeStats(node)}
+	}
+	return op, nil
+}
+
+// newOperator creates the operator of a row-producing plan node
+func newOperator(node plan.Node, ctx *ExecutionContext) (Operator, error) {
+	switch n := node.(type) {
+	case *plan.ScanNode:
+		return newScanOperator(n, ctx)
+	case *plan.IndexScanNode:
+		return newIndexScanOperator(n, ctx)
+	case *plan.JoinNode:
+		return newJoinOperator(n, ctx)
+	case *plan.SelectNode:
+		return newSelectOperator(n, ctx)
+	default:
+		return nil, fmt.Errorf("plan node does not produce rows: %T", node)
+	}
+}
+
+// executeQuery runs a row-producing plan node and collects its rows
+func executeQuery(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error) {
+	op, err := buildOperator(node, ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := drain(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IntermediateResult{
+		Rows:   rows,
+		Schema: op.Schema(),
+		Metadata: map[string]interface{}{
+			"row_count": len(rows),
+		},
+	}, nil
+}
+
+// drain opens op, reads all of its rows and closes it
+func drain(op Operator) (rows []data.Row, err error) {
+	if err := op.Open(); err != nil {
+		op.Close()
+		return nil, err
+	}
+	defer func() {
+		if closeErr := op.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	rows = make([]data.Row, 0)
+	for {
+		row, ok, err := op.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return rows, nil
+		}
+		rows = append(rows, row)
+	}
+}
+
+// filterOperator passes on the input rows that match a predicate
+type filterOperator struct {
+	input     Operator
+	predicate func(data.Row) bool
+}
+
+func (o *filterOperator) Open() error {
+	return o.input.Open()
+}
+
+func (o *filterOperator) Next() (data.Row, bool, error) {
+	for {
+		row, ok, err := o.input.Next()
+		if err != nil || !ok {
+			return row, ok, err
+		}
+		if o.predicate(row) {
+			return row, true, nil
+		}
+	}
+}
+
+func (o *filterOperator) Close() error {
+	return o.input.Close()
+}
+
+func (o *filterOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
+
+// mapOperator transforms every input row
+type mapOperator struct {
+	input Operator
+	fn    func(data.Row) data.Row
+}
+
+func (o *mapOperator) Open() error {
+	return o.input.Open()
+}
+
+func (o *mapOperator) Next() (data.Row, bool, error) {
+	row, ok, err := o.input.Next()
+	if err != nil || !ok {
+		return row, ok, err
+	}
+	return o.fn(row), true, nil
+}
+
+func (o *mapOperator) Close() error {
+	return o.input.Close()
+}
+
+func (o *mapOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
+
+// limitOperator stops after a number of rows without reading further input
+type limitOperator struct {
+	input    Operator
+	limit    int
+	returned int
+}
+
+func (o *limitOperator) Open() error {
+	o.returned = 0
+	if o.limit == 0 {
+		// Nothing will be read
+		return nil
+	}
+	return o.input.Open()
+}
+
+func (o *limitOperator) Next() (data.Row, bool, error) {
+	if o.returned >= o.limit {
+		return data.Row{}, false, nil
+	}
+	row, ok, err := o.input.Next()
+	if err != nil || !ok {
+		return row, ok, err
+	}
+	o.returned++
+	return row, true, nil
+}
+
+func (o *limitOperator) Close() error {
+	if o.limit == 0 {
+		return nil
+	}
+	return o.input.Close()
+}
+
+func (o *limitOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
+
+// materializeOperator reads all rows of its input when opened and returns
+// them from memory
+type materializeOperator struct {
+	input Operator
+	rows  []data.Row
+	pos   int
+}
+
+func (o *materializeOperator) Open() error {
+	rows, err := drain(o.input)
+	if err != nil {
+		return err
+	}
+	o.rows, o.pos = rows, 0
+	return nil
+}
+
+func (o *materializeOperator) Next() (data.Row, bool, error) {
+	if o.pos >= len(o.rows) {
+		return data.Row{}, false, nil
+	}
+	row := o.rows[o.pos]
+	o.pos++
+	return row, true, nil
+}
+
+func (o *materializeOperator) Close() error {
+	// The input was closed once drained
+	o.rows = nil
+	return nil
+}
+
+func (o *materializeOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
@@ -0,0 +1,127 @@
+package executor
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+const (
+	// morselSize is the number of rows a parallel worker filters at a time
+	morselSize = 1024
+	// minParallelScanRows is the smallest table a scan filters in parallel;
+	// below it starting workers costs more than it saves
+	minParallelScanRows = 4 * morselSize
+)
+
+// morsel is the filtered rows of one morselSize slice of a scan
+type morsel struct {
+	rows []data.Row
+	err  error
+}
+
+// parallelFilter applies a scan's predicate on several workers
+//
+// The rows are split into morsels that workers claim in order. Each morsel's
+// matches are delivered through its own channel and read back in morsel
+// order, so the rows come out in exactly the order a sequential scan returns
+// them. Workers run at most a window of morsels ahead of the consumer, which
+// bounds the memory held by filtered rows that have not been read yet.
+type parallelFilter struct {
+	ctx       *ExecutionContext
+	rows      []data.Row
+	predicate func(data.Row) bool
+	results   []chan morsel
+	window    chan struct{}
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	claimed   atomic.Int64
+
+	next    int // next morsel to read
+	current []data.Row
+	pos     int
+}
+
+// newParallelFilter starts workers filtering rows with predicate
+func newParallelFilter(ctx *ExecutionContext, rows []data.Row, predicate func(data.Row) bool, workers int) *parallelFilter {
+	count := (len(rows) + morselSize - 1) / morselSize
+	f := &parallelFilter{
+		ctx:       ctx,
+		rows:      rows,
+		predicate: predicate,
+		results:   make([]chan morsel, count),
+		window:    make(chan struct{}, 2*workers),
+		stop:      make(chan struct{}),
+	}
+	for i := range f.results {
+		f.results[i] = make(chan morsel, 1)
+	}
+	for w := 0; w < workers; w++ {
+		f.wg.Add(1)
+		go f.work()
+	}
+	return f
+}
+
+// work filters morsels until none are left or the filter is closed
+func (f *parallelFilter) work() {
+	defer f.wg.Done()
+	for {
+		select {
+		case <-f.stop:
+			return
+		default:
+		}
+		// Wait for room in the window before claiming a morsel
+		select {
+		case f.window <- struct{}{}:
+		case <-f.stop:
+			return
+		}
+		i := int(f.claimed.Add(1)) - 1
+		if i >= len(f.results) {
+			<-f.window
+			return
+		}
+
+		if err := f.ctx.checkCancelled(); err != nil {
+			f.results[i] <- morsel{err: err}
+			continue
+		}
+		start, end := i*morselSize, min((i+1)*morselSize, len(f.rows))
+		var matches []data.Row
+		for _, row := range f.rows[start:end] {
+			if f.predicate(row) {
+				matches = append(matches, row)
+			}
+		}
+		f.results[i] <- morsel{rows: matches}
+	}
+}
+
+// Next returns the next matching row in table order
+func (f *parallelFilter) Next() (data.Row, bool, error) {
+	for f.pos >= len(f.current) {
+		if f.next >= len(f.results) {
+			return data.Row{}, false, nil
+		}
+		m := <-f.results[f.next]
+		f.next++
+		<-f.window
+		if m.err != nil {
+			return data.Row{}, false, m.err
+		}
+		f.current, f.pos = m.rows, 0
+	}
+	row := f.current[f.pos]
+	f.pos++
+	return row, true, nil
+}
+
+// Close stops the workers and waits for them to exit
+func (f *parallelFilter) Close() {
+	close(f.stop)
+	f.wg.Wait()
+	f.current = nil
+}
@@ -27,7 +27,7 @@ func formatInsertResult(intermediate *IntermediateResult) *Result {
 	}
 
 	return &Result{
-		Message:      "INSERT 1",
+		Message:      fmt.Sprintf("INSERT %d", rowsAffected),
 		RowsAffected: rowsAffected,
 	}
 }
@@ -52,6 +52,42 @@ func formatDeleteResult(intermediate *IntermediateResult) *Result {
 	}
 }
 
+// formatCreateIndexResult creates a Result for CREATE INDEX operations
+func formatCreateIndexResult(intermediate *IntermediateResult) *Result {
+	return &Result{
+		Message: "CREATE INDEX",
+	}
+}
+
+// formatAnalyzeResult creates a Result for ANALYZE operations
+func formatAnalyzeResult(intermediate *IntermediateResult) *Result {
+	tables, _ := intermediate.Metadata["tables_analyzed"].(int)
+
+	return &Result{
+		Message:      fmt.Sprintf("ANALYZE %d", tables),
+		RowsAffected: tables,
+	}
+}
+
+// formatCopyResult creates a Result for COPY operations, counting the rows
+// copied in either direction
+func formatCopyResult(intermediate *IntermediateResult) *Result {
+	rowsAffected, _ := intermediate.Metadata["rows_affected"].(int)
+
+	return &Result{
+		Message:      fmt.Sprintf("COPY %d", rowsAffected),
+		RowsAffected: rowsAffected,
+	}
+}
+
+// formatExplainResult creates a Result for EXPLAIN, one row per line of the
+// plan
+func formatExplainResult(intermediate *IntermediateResult) *Result {
+	return formatResult(intermediate, []string{explainColumn}, []ColumnMetadata{
+		{Name: explainColumn, Type: string(schema.ColumnTypeText)},
+	})
+}
+
 // formatSelectResult handles column and metadata calculation for SELECT queries
 func formatSelectResult(node *plan.SelectNode, intermediate *IntermediateResult, db *schema.Database) *Result {
 	var columns []string
@@ -63,7 +99,7 @@ func formatSelectResult(node *plan.SelectNode, intermediate *IntermediateResult,
 	table, hasTable := db.Tables[node.TableName]
 
 	if proj.SelectAll {
-		if hasTable && len(node.Children()) == 0 {
+		if hasTable && !hasJoin(node) {
 			// Simple SELECT *
 			for _, col := range table.Schema.Columns {
 				columns = append(columns, col.Name)
@@ -74,7 +110,11 @@ func formatSelectResult(node *plan.SelectNode, intermediate *IntermediateResult,
 			}
 		} else {
 			// JOIN or complex result - extract from rows
+			// Columns merged by USING / NATURAL joins come first
 			columns = extractColumnsFromRows(intermediate.Rows)
+			if len(node.Coalesce) > 0 {
+				columns = coalescedFirst(columns, node.Coalesce)
+			}
 			for _, colName := range columns {
 				metadata = append(metadata, ColumnMetadata{
 					Name: colName,
@@ -95,7 +135,9 @@ func formatSelectResult(node *plan.SelectNode, intermediate *IntermediateResult,
 
 			// Try to find type if table is known
 			var colType = "TEXT"
-			if hasTable && colRef.Table == node.TableName {
+			if aggType, ok := aggregateType(node, colRef.Column); ok {
+				colType = aggType
+			} else if hasTable && colRef.Table == node.TableName {
 				for _, c := range table.Schema.Columns {
 					if c.Name == colRef.Column {
 						colType = string(c.Type)
@@ -119,6 +161,34 @@ func formatSelectResult(node *plan.SelectNode, intermediate *IntermediateResult,
 	}
 }
 
+// aggregateType returns the result type of the aggregate named name, if the
+// type does not depend on its argument
+func aggregateType(node *plan.SelectNode, name string) (string, bool) {
+	for _, agg := range node.Aggregates {
+		if agg.Name != name {
+			continue
+		}
+		switch agg.Function {
+		case "COUNT":
+			return string(schema.ColumnTypeInt), true
+		case "AVG":
+			return string(schema.ColumnTypeFloat), true
+		}
+	}
+	return "", false
+}
+
+// hasJoin reports whether the SELECT reads from a JOIN tree rather than a
+// single table
+func hasJoin(node *plan.SelectNode) bool {
+	for _, child := range node.Children() {
+		if _, ok := child.(*plan.JoinNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // extractColumnsFromRows extracts column names from rows
 // Used when columns aren't explicitly provided
 func extractColumnsFromRows(rows []data.Row) []string {
@@ -135,3 +205,19 @@ func extractColumnsFromRows(rows []data.Row) []string {
 
 	return columns
 }
+
+// coalescedFirst moves the merged join columns to the front, in join order
+func coalescedFirst(columns []string, coalesced []plan.CoalescedColumn) []string {
+	merged := make(map[string]bool, len(coalesced))
+	ordered := make([]string, 0, len(columns))
+	for _, c := range coalesced {
+		merged[c.Name] = true
+		ordered = append(ordered, c.Name)
+	}
+	for _, col := range columns {
+		if !merged[col] {
+			ordered = append(ordered, col)
+		}
+	}
+	return ordered
+}
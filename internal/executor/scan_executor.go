@@ -2,31 +2,100 @@ package executor
 
 import (
 	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/plan"
 )
 
-// executeScan executes a ScanNode (leaf operation)
-// This is the base case for recursive tree walking
-func executeScan(node *plan.ScanNode, ctx *ExecutionContext) (*IntermediateResult, error) {
+// scanOperator reads a table sequentially (leaf operator)
+// Rows are read one at a time from a snapshot of the table taken by Open, so
+// a scan never copies the table. With parallel execution on, the predicate of
+// a large table is applied on several workers; rows still come out in table
+// order. A paged table is read through a cursor instead, one page at a time,
+// and filtered sequentially.
+type scanOperator struct {
+	node     *plan.ScanNode
+	ctx      *ExecutionContext
+	table    *schema.Table
+	rows     []data.Row
+	pos      int
+	cursor   schema.RowCursor // reads a paged table, nil otherwise
+	parallel *parallelFilter
+}
+
+func newScanOperator(node *plan.ScanNode, ctx *ExecutionContext) (*scanOperator, error) {
 	table, ok := ctx.Database.Tables[node.TableName]
 	if !ok {
 		return nil, newTableNotFoundError(node.TableName)
 	}
+	return &scanOperator{node: node, ctx: ctx, table: table}, nil
+}
 
-	var rows []data.Row
-	if node.Predicate == nil {
-		rows = table.SelectAll(ctx.Transaction)
-	} else {
-		rows = table.Select(node.Predicate, ctx.Transaction)
+func (o *scanOperator) Open() error {
+	o.pos = 0
+	if o.cursor = o.table.Cursor(o.ctx.Transaction); o.cursor != nil {
+		return nil
+	}
+	o.rows = o.table.ScanRows(o.ctx.Transaction)
+	if workers := o.ctx.workers(); workers > 1 && o.node.Predicate != nil && len(o.rows) >= minParallelScanRows {
+		o.parallel = newParallelFilter(o.ctx, o.rows, o.node.Predicate, workers)
+	}
+	return nil
+}
+
+func (o *scanOperator) Next() (data.Row, bool, error) {
+	if o.parallel != nil {
+		return o.parallel.Next()
+	}
+	if o.cursor != nil {
+		return o.nextFromCursor()
+	}
+	for o.pos < len(o.rows) {
+		if o.pos%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return data.Row{}, false, err
+			}
+		}
+		row := o.rows[o.pos]
+		o.pos++
+		if o.node.Predicate == nil || o.node.Predicate(row) {
+			return row, true, nil
+		}
 	}
+	return data.Row{}, false, nil
+}
+
+// nextFromCursor returns the next matching row of a paged table
+func (o *scanOperator) nextFromCursor() (data.Row, bool, error) {
+	for {
+		if o.pos%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return data.Row{}, false, err
+			}
+		}
+		row, ok, err := o.cursor.Next()
+		if err != nil || !ok {
+			return data.Row{}, false, err
+		}
+		o.pos++
+		if o.node.Predicate == nil || o.node.Predicate(row) {
+			return row, true, nil
+		}
+	}
+}
+
+func (o *scanOperator) Close() error {
+	if o.parallel != nil {
+		o.parallel.Close()
+		o.parallel = nil
+	}
+	if o.cursor != nil {
+		o.cursor.Close()
+		o.cursor = nil
+	}
+	o.rows = nil
+	return nil
+}
 
-	return &IntermediateResult{
-		Rows:   rows,
-		Schema: table.Schema,
-		Metadata: map[string]interface{}{
-			"table":     node.TableName,
-			"scan_type": "sequential",
-			"row_count": len(rows),
-		},
-	}, nil
+func (o *scanOperator) Schema() *schema.TableSchema {
+	return o.table.Schema
 }
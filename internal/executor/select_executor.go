@@ -2,67 +2,122 @@ package executor
 
 import (
 	"github.com/leengari/mini-rdbms/internal/domain/data"
-	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/plan"
 	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
 )
 
-// executeSelectNode handles SelectNode using tree-walking pattern
-// Returns IntermediateResult for composition with other nodes
-func executeSelectNode(node *plan.SelectNode, ctx *ExecutionContext) (*IntermediateResult, error) {
-	var rows []data.Row
+// newSelectOperator builds the operator pipeline of a SELECT
+// The child (JOIN tree or index scan) - or a scan of the table - feeds the
+// WHERE filter, GROUP BY aggregation, ORDER BY, the projection and finally
+// LIMIT.
+func newSelectOperator(node *plan.SelectNode, ctx *ExecutionContext) (Operator, error) {
+	op, projected, err := newSelectInput(node, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// SELECT * shows each merged column once, in place of its sources
+	if node.Projection.SelectAll && len(node.Coalesce) > 0 {
+		op = &mapOperator{input: op, fn: func(row data.Row) data.Row {
+			for _, c := range node.Coalesce {
+				for _, source := range c.Sources {
+					delete(row.Data, source)
+				}
+			}
+			return row
+		}}
+	}
+
+	if len(node.OrderBy) > 0 {
+		op = &sortOperator{input: op, ctx: ctx, keys: node.OrderBy}
+	}
+
+	// Apply projection
+	if !projected {
+		op = &mapOperator{input: op, fn: func(row data.Row) data.Row {
+			// Convert Row to JoinedRow for projector (ProjectJoinedRow handles qualified names)
+			joined := data.JoinedRow{Data: row.Data}
+			projectedJoined := projection.ProjectJoinedRow(joined, node.Projection)
+			return data.Row{Data: projectedJoined.Data}
+		}}
+	}
+
+	if node.Limit != nil {
+		op = &limitOperator{input: op, limit: *node.Limit}
+	}
+	return op, nil
+}
+
+// newSelectInput builds the operators producing the filtered - and with
+// GROUP BY or aggregates, grouped - rows of a SELECT
+// MIN and MAX read from indexes need neither. The vectorized strategy runs
+// what it can on batches; projected reports whether the projection was
+// applied as well.
+func newSelectInput(node *plan.SelectNode, ctx *ExecutionContext) (op Operator, projected bool, err error) {
+	if node.IndexAggregates {
+		agg, err := newIndexAggregateOperator(node, ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		return agg, false, nil
+	}
+	if ctx.vectorize {
+		op, projected, ok, err := newVectorSelectOperator(node, ctx)
+		if err != nil || ok {
+			return op, projected, err
+		}
+	}
 
-	var resultSchema *schema.TableSchema
 	if len(node.Children()) > 0 {
-		// Execute child (JOIN tree or other operation) recursively
-		childResult, err := executeNode(node.Children()[0], ctx)
+		// Build child (JOIN tree or other operation) recursively
+		child, err := buildOperator(node.Children()[0], ctx)
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+		op = child
+
+		// Merge USING / NATURAL join columns before filtering, so WHERE can
+		// reference them unqualified
+		if len(node.Coalesce) > 0 {
+			op = &mapOperator{input: op, fn: func(row data.Row) data.Row {
+				coalesceColumns(row, node.Coalesce)
+				return row
+			}}
+		}
+
+		if node.Predicate != nil {
+			op = &filterOperator{input: op, predicate: node.Predicate}
 		}
-		rows = childResult.Rows
-		resultSchema = childResult.Schema
 	} else {
-		// No children - simple table scan
-		scanNode := &plan.ScanNode{
+		// No children - simple table scan applying the predicate
+		scan, err := newScanOperator(&plan.ScanNode{
 			TableName:   node.TableName,
 			Predicate:   node.Predicate,
 			Transaction: node.Transaction,
-		}
-		scanResult, err := executeScan(scanNode, ctx)
+		}, ctx)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
-		rows = scanResult.Rows
-		resultSchema = scanResult.Schema
+		op = scan
 	}
 
-	// Apply predicate
-	if node.Predicate != nil {
-		filteredRows := make([]data.Row, 0)
-		for _, row := range rows {
-			if node.Predicate(row) {
-				filteredRows = append(filteredRows, row)
-			}
-		}
-		rows = filteredRows
+	if len(node.GroupBy) > 0 || len(node.Aggregates) > 0 {
+		op = newAggregateOperator(op, node, ctx)
 	}
+	return op, false, nil
+}
 
-	// Apply projection
-	projectedRows := make([]data.Row, len(rows))
-	for i, row := range rows {
-		// Convert Row to JoinedRow for projector (ProjectJoinedRow handles qualified names)
-		joined := data.JoinedRow{Data: row.Data}
-		projectedJoined := projection.ProjectJoinedRow(joined, node.Projection)
-		projectedRows[i] = data.Row{Data: projectedJoined.Data}
+// coalesceColumns sets each merged join column to the first non-NULL value
+// among its sources
+func coalesceColumns(row data.Row, columns []plan.CoalescedColumn) {
+	for _, c := range columns {
+		var value interface{}
+		for _, source := range c.Sources {
+			if v, ok := row.Data[source]; ok && v != nil {
+				value = v
+				break
+			}
+		}
+		row.Data[c.Name] = value
 	}
-
-	return &IntermediateResult{
-		Rows:   projectedRows,
-		Schema: resultSchema,
-		Metadata: map[string]interface{}{
-			"projection": node.Projection,
-			"row_count":  len(projectedRows),
-			"filtered":   node.Predicate != nil,
-		},
-	}, nil
 }
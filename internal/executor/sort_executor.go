@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"log/slog"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
+)
+
+// sortOperator orders its input rows by the ORDER BY keys
+// The whole input is read on Open. Rows are sorted in memory while the
+// query's memory budget allows; beyond it sorted runs are written to disk and
+// merged (an external merge sort). NULLs sort after every value, so they come
+// last in ascending order and first in descending order.
+type sortOperator struct {
+	input  Operator
+	ctx    *ExecutionContext
+	keys   []plan.SortKey
+	sorter *spill.Sorter
+	rows   spill.Iterator
+}
+
+func (o *sortOperator) Open() error {
+	if err := o.input.Open(); err != nil {
+		return err
+	}
+
+	o.sorter = spill.NewSorter(o.ctx.memory(), o.ctx.spillDir(), o.compare)
+	count := 0
+	for {
+		row, ok, err := o.input.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		count++
+		if count%cancelCheckInterval == 0 {
+			if err := o.ctx.checkCancelled(); err != nil {
+				return err
+			}
+		}
+		if err := o.sorter.Add(row); err != nil {
+			return err
+		}
+	}
+
+	rows, err := o.sorter.Sort()
+	if err != nil {
+		return err
+	}
+	if o.sorter.Runs() > 0 {
+		slog.Debug("Sort spilled to disk",
+			slog.Int("rows", count),
+			slog.Int("runs", o.sorter.Runs()),
+		)
+	}
+	o.rows = rows
+	return nil
+}
+
+// compare orders two rows by the sort keys
+func (o *sortOperator) compare(a, b data.Row) int {
+	for _, key := range o.keys {
+		va, vb := columnValue(a, key.Column), columnValue(b, key.Column)
+		var c int
+		switch {
+		case va == nil && vb == nil:
+			c = 0
+		case va == nil:
+			c = 1
+		case vb == nil:
+			c = -1
+		default:
+			c = index.Compare(va, vb)
+		}
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (o *sortOperator) Next() (data.Row, bool, error) {
+	if o.rows == nil {
+		return data.Row{}, false, nil
+	}
+	return o.rows.Next()
+}
+
+func (o *sortOperator) Close() error {
+	var err error
+	if o.sorter != nil {
+		err = o.sorter.Close()
+		o.sorter, o.rows = nil, nil
+	}
+	if closeErr := o.input.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (o *sortOperator) Schema() *schema.TableSchema {
+	return o.input.Schema()
+}
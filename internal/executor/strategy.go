@@ -1,15 +1,87 @@
 package executor
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
 	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
 )
 
 // ExecutionStrategy defines how a plan node is executed
-// Scaffold: Interface defined but not used yet
+// Every strategy runs the same plans with the same ExecutionContext and must
+// return the same rows; ExecuteWith formats their IntermediateResult into the
+// user-facing Result. Strategies differ only in how rows move between nodes,
+// which lets execution engines be compared against each other.
 type ExecutionStrategy interface {
-	Execute(node plan.Node, ctx *ExecutionContext) (*Result, error)
+	// Name identifies the strategy in ExecutionConfig.Strategy and settings
+	Name() string
+	Execute(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error)
+}
+
+// Execution strategy names
+const (
+	// StrategyIterator pulls rows through a pipeline of operators one at a
+	// time (the default)
+	StrategyIterator = "iterator"
+	// StrategyTreeWalking runs every plan node to completion, children
+	// first, and hands its whole output to the parent
+	StrategyTreeWalking = "tree_walking"
+	// StrategyVectorized filters, projects and aggregates single-table
+	// queries on batches of typed column vectors
+	StrategyVectorized = "vectorized"
+)
+
+// strategies holds every available execution strategy by name
+var strategies = map[string]ExecutionStrategy{
+	StrategyIterator:    &IteratorStrategy{},
+	StrategyTreeWalking: &TreeWalkingStrategy{},
+	StrategyVectorized:  &VectorizedStrategy{},
+}
+
+// LookupStrategy returns the execution strategy called name
+func LookupStrategy(name string) (ExecutionStrategy, bool) {
+	strategy, ok := strategies[name]
+	return strategy, ok
+}
+
+// StrategyNames returns the names of all execution strategies, sorted
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultStrategy is the strategy of configurations from
+// DefaultExecutionConfig
+// Sessions may start while it is set, so it is guarded by defaultStrategyMu.
+var (
+	defaultStrategyMu sync.RWMutex
+	defaultStrategy   = StrategyIterator
+)
+
+// SetDefaultStrategy selects the strategy of configurations created from now
+// on, such as those of new sessions
+// It is meant to be called at startup, before queries run.
+func SetDefaultStrategy(name string) error {
+	if _, ok := strategies[name]; !ok {
+		return fmt.Errorf("unknown execution strategy: %s (expected one of %s)", name, strings.Join(StrategyNames(), ", "))
+	}
+	defaultStrategyMu.Lock()
+	defaultStrategy = name
+	defaultStrategyMu.Unlock()
+	return nil
 }
 
 // ExecutionContext provides resources for execution
@@ -17,31 +89,182 @@ type ExecutionContext struct {
 	Database    *schema.Database
 	Transaction *transaction.Transaction
 	Config      *ExecutionConfig
+	Context     context.Context // cancels the query when done; nil never cancels
+	// Memory is the query's memory budget; nil creates one from
+	// Config.MemoryBudget when an operator first needs it
+	Memory *spill.Budget
+
+	// analyze collects what every plan node did during EXPLAIN ANALYZE; nil
+	// otherwise
+	analyze map[plan.Node]*nodeStats
+	// materialize runs every plan node's operator to completion before its
+	// parent reads it (TreeWalkingStrategy)
+	materialize bool
+	// vectorize runs SELECTs on batches of column vectors where possible
+	// (VectorizedStrategy)
+	vectorize bool
+}
+
+// memory returns the query's memory budget
+func (c *ExecutionContext) memory() *spill.Budget {
+	if c.Memory == nil {
+		c.Memory = spill.NewBudget(c.Config.MemoryBudget)
+	}
+	return c.Memory
+}
+
+// workers returns the number of workers a parallel operator may use
+func (c *ExecutionContext) workers() int {
+	return c.Config.Parallelism()
+}
+
+// spillDir returns the directory for temporary run files: Config.TempDir,
+// else a .tmp directory inside the database directory
+// In-memory databases without a directory spill to the system temp directory.
+func (c *ExecutionContext) spillDir() string {
+	switch {
+	case c.Config.TempDir != "":
+		return c.Config.TempDir
+	case c.Database != nil && c.Database.Path != "":
+		return filepath.Join(c.Database.Path, ".tmp")
+	default:
+		return filepath.Join(os.TempDir(), "joydb-spill")
+	}
+}
+
+// cancelCheckInterval is how many rows a loop reads between cancellation
+// checks
+const cancelCheckInterval = 256
+
+// checkCancelled returns an error once the query's context is cancelled or
+// its deadline has passed
+// Scans and joins call it as they read rows, so a cancelled query stops
+// partway through its input.
+func (c *ExecutionContext) checkCancelled() error {
+	if c.Context == nil {
+		return nil
+	}
+	if err := c.Context.Err(); err != nil {
+		return fmt.Errorf("query cancelled: %w", err)
+	}
+	return nil
 }
 
 // ExecutionConfig holds execution parameters
 type ExecutionConfig struct {
 	UseIndexes    bool
-	ParallelScans bool
-	JoinAlgorithm string // "hash", "nested_loop", "merge"
+	ParallelScans bool   // filter large scans and build / probe hash joins on several workers
+	Workers       int    // degree of parallelism; 0 uses one worker per CPU
+	JoinAlgorithm string // "hash", "merge", "index_nested_loop"; empty uses the planner's choice
 	BufferSize    int
+	MemoryBudget  int64  // bytes a query's sorts, hash joins and aggregations may hold before spilling; 0 for no limit
+	TempDir       string // directory for spilled run files; empty uses the database directory
+	Strategy      string // execution strategy name; empty uses the iterator strategy
 }
 
+// Parallelism returns the number of workers parallel operators use, 1 when
+// parallel execution is off
+func (c *ExecutionConfig) Parallelism() int {
+	switch {
+	case !c.ParallelScans:
+		return 1
+	case c.Workers > 0:
+		return c.Workers
+	default:
+		return runtime.GOMAXPROCS(0)
+	}
+}
+
+// DefaultMemoryBudget is the default per-query memory budget (64 MiB)
+const DefaultMemoryBudget = 64 << 20
+
 // DefaultExecutionConfig returns default configuration
 func DefaultExecutionConfig() *ExecutionConfig {
+	defaultStrategyMu.RLock()
+	defer defaultStrategyMu.RUnlock()
 	return &ExecutionConfig{
-		UseIndexes:    false, // Scaffold: always false
-		ParallelScans: false,
-		JoinAlgorithm: "nested_loop", // Scaffold: always nested loop
+		UseIndexes:    true,
+		ParallelScans: true,
+		Workers:       0,  // One per CPU
+		JoinAlgorithm: "", // Planner chooses from table statistics
 		BufferSize:    4096,
+		MemoryBudget:  DefaultMemoryBudget,
+		Strategy:      defaultStrategy,
+	}
+}
+
+// strategy returns the execution strategy selected by the configuration
+func (c *ExecutionConfig) strategy() (ExecutionStrategy, error) {
+	if c.Strategy == "" {
+		return strategies[StrategyIterator], nil
 	}
+	strategy, ok := strategies[c.Strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown execution strategy: %s", c.Strategy)
+	}
+	return strategy, nil
 }
 
-// DefaultStrategy is a naive implementation
-// Integrated with tree-walking executor
+// DefaultStrategy runs a plan with the strategy selected by the context's
+// configuration
 type DefaultStrategy struct{}
 
+func (ds *DefaultStrategy) Name() string {
+	return "default"
+}
+
 func (ds *DefaultStrategy) Execute(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error) {
+	strategy, err := ctx.Config.strategy()
+	if err != nil {
+		return nil, err
+	}
+	return strategy.Execute(node, ctx)
+}
+
+// IteratorStrategy streams rows through Volcano-style operators: a node
+// produces its next row only when its parent asks for one, so LIMIT and
+// cancellation stop all upstream work
+type IteratorStrategy struct{}
+
+func (s *IteratorStrategy) Name() string {
+	return StrategyIterator
+}
+
+func (s *IteratorStrategy) Execute(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error) {
 	// Delegate to the recursive tree walker (implemented in executor.go)
 	return executeNode(node, ctx)
 }
+
+// TreeWalkingStrategy executes the plan tree node by node: each node reads
+// the complete output of its children before producing its own
+// It runs the same operators as IteratorStrategy, but every node's output is
+// materialized, so LIMIT does not cut the work of the nodes below it.
+type TreeWalkingStrategy struct{}
+
+func (s *TreeWalkingStrategy) Name() string {
+	return StrategyTreeWalking
+}
+
+func (s *TreeWalkingStrategy) Execute(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error) {
+	ctx.materialize = true
+	defer func() { ctx.materialize = false }()
+	return executeNode(node, ctx)
+}
+
+// VectorizedStrategy processes table scans in batches of vector.BatchSize
+// rows held in typed column vectors: the WHERE clause, projection and
+// aggregates of a single-table SELECT work on whole vectors instead of
+// looking up and unboxing every value of every row
+// Rows are built only as they leave the batches. Plans it cannot vectorize,
+// such as joins and index scans, run on the iterator operators.
+type VectorizedStrategy struct{}
+
+func (s *VectorizedStrategy) Name() string {
+	return StrategyVectorized
+}
+
+func (s *VectorizedStrategy) Execute(node plan.Node, ctx *ExecutionContext) (*IntermediateResult, error) {
+	ctx.vectorize = true
+	defer func() { ctx.vectorize = false }()
+	return executeNode(node, ctx)
+}
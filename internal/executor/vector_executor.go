@@ -0,0 +1,461 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
+	"github.com/leengari/mini-rdbms/internal/query/vector"
+)
+
+// newVectorSelectOperator builds the vectorized input of a SELECT: a batch
+// scan of its table applying the WHERE clause, followed by the aggregation
+// or, when nothing else needs the rows, the projection
+// Rows are only built when they leave it, for ORDER BY, LIMIT and the
+// result. ok is false for SELECTs it cannot run - joins, index scans and
+// USING / NATURAL columns - which use the row operators instead; projected
+// reports whether the projection was applied.
+func newVectorSelectOperator(node *plan.SelectNode, ctx *ExecutionContext) (op Operator, projected bool, ok bool, err error) {
+	if len(node.Children()) > 0 || len(node.Coalesce) > 0 {
+		return nil, false, false, nil
+	}
+	table, exists := ctx.Database.Tables[node.TableName]
+	if !exists {
+		return nil, false, false, newTableNotFoundError(node.TableName)
+	}
+
+	var filter *vector.Filter
+	if node.Where != nil {
+		if filter, err = vector.CompileFilter(node.Where); err != nil {
+			return nil, false, false, nil
+		}
+	} else if node.Predicate != nil {
+		// A predicate without its expression cannot be compiled
+		return nil, false, false, nil
+	}
+
+	var project []projection.ColumnRef
+	aggregated := len(node.GroupBy) > 0 || len(node.Aggregates) > 0
+	if !aggregated && len(node.OrderBy) == 0 && !node.Projection.SelectAll && unqualified(node.Projection.Columns) {
+		project = node.Projection.Columns
+	}
+
+	scan := newVectorScan(table, filter, ctx)
+	if aggregated {
+		return newVectorAggregateOperator(scan, node, ctx), false, true, nil
+	}
+	return &vectorRowsOperator{scan: scan, project: project}, project != nil, true, nil
+}
+
+// unqualified reports whether no column reference names a table
+func unqualified(refs []projection.ColumnRef) bool {
+	for _, ref := range refs {
+		if ref.Table != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// vectorScan reads a table in batches of vector.BatchSize rows
+// The filter narrows each batch's selection, reading columns through vectors
+// of their schema type. Rows are read from a snapshot of the table
+// taken by open, like scanOperator, or from a paged table through a cursor.
+type vectorScan struct {
+	ctx    *ExecutionContext
+	table  *schema.Table
+	filter *vector.Filter
+	batch  *vector.Batch
+	rows   []data.Row
+	pos    int
+	cursor schema.RowCursor // reads a paged table, nil otherwise
+}
+
+func newVectorScan(table *schema.Table, filter *vector.Filter, ctx *ExecutionContext) *vectorScan {
+	kinds := make(map[string]vector.Kind, len(table.Schema.Columns))
+	for _, col := range table.Schema.Columns {
+		kinds[col.Name] = vector.KindOf(col.Type)
+	}
+	return &vectorScan{ctx: ctx, table: table, filter: filter, batch: vector.NewBatch(kinds)}
+}
+
+func (s *vectorScan) open() {
+	s.rows, s.pos = nil, 0
+	if s.cursor = s.table.Cursor(s.ctx.Transaction); s.cursor == nil {
+		s.rows = s.table.ScanRows(s.ctx.Transaction)
+	}
+}
+
+// next returns the next batch with selected rows, nil once the table is
+// exhausted
+// The batch is reused by the following call.
+func (s *vectorScan) next() (*vector.Batch, error) {
+	for {
+		if err := s.ctx.checkCancelled(); err != nil {
+			return nil, err
+		}
+		rows, err := s.read()
+		if err != nil || len(rows) == 0 {
+			return nil, err
+		}
+		s.batch.Load(rows)
+		if s.filter != nil {
+			s.filter.Apply(s.batch)
+		}
+		if len(s.batch.Sel) > 0 {
+			return s.batch, nil
+		}
+	}
+}
+
+// read returns the rows of the next batch, none once the table is exhausted
+func (s *vectorScan) read() ([]data.Row, error) {
+	if s.cursor == nil {
+		end := min(s.pos+vector.BatchSize, len(s.rows))
+		rows := s.rows[s.pos:end]
+		s.pos = end
+		return rows, nil
+	}
+
+	rows := make([]data.Row, 0, vector.BatchSize)
+	for len(rows) < vector.BatchSize {
+		row, ok, err := s.cursor.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *vectorScan) close() {
+	s.rows = nil
+	if s.cursor != nil {
+		s.cursor.Close()
+		s.cursor = nil
+	}
+}
+
+// vectorRowsOperator returns the selected rows of a vectorScan: the stored
+// rows, or with project set new rows of the projected columns built from the
+// vectors
+type vectorRowsOperator struct {
+	scan    *vectorScan
+	project []projection.ColumnRef
+	batch   *vector.Batch
+	pos     int
+}
+
+func (o *vectorRowsOperator) Open() error {
+	o.scan.open()
+	o.batch, o.pos = nil, 0
+	return nil
+}
+
+func (o *vectorRowsOperator) Next() (data.Row, bool, error) {
+	for o.batch == nil || o.pos >= len(o.batch.Sel) {
+		batch, err := o.scan.next()
+		if err != nil || batch == nil {
+			return data.Row{}, false, err
+		}
+		o.batch, o.pos = batch, 0
+		for _, ref := range o.project {
+			batch.Vector(ref.Column, batch.Sel)
+		}
+	}
+	i := o.batch.Sel[o.pos]
+	o.pos++
+	if o.project == nil {
+		return o.batch.Rows[i], true, nil
+	}
+
+	values := make(map[string]interface{}, len(o.project))
+	for _, ref := range o.project {
+		v := o.batch.Vector(ref.Column, nil)
+		if v.IsMissing(i) {
+			// Like the row projection, a column the row lacks is left out
+			continue
+		}
+		key := ref.Column
+		if ref.Alias != "" {
+			key = ref.Alias
+		}
+		values[key] = v.Value(i)
+	}
+	return data.NewRow(values), true, nil
+}
+
+func (o *vectorRowsOperator) Close() error {
+	o.scan.close()
+	o.batch = nil
+	return nil
+}
+
+func (o *vectorRowsOperator) Schema() *schema.TableSchema {
+	return o.scan.table.Schema
+}
+
+// errGroupsOverBudget stops a vectorized aggregation whose groups do not fit
+// in the memory budget
+var errGroupsOverBudget = errors.New("aggregation groups exceed the memory budget")
+
+// vectorAggregateOperator groups the selected rows of a vectorScan and
+// computes the aggregates one vector at a time
+//
+// Each batch first assigns every selected row to its group, then feeds each
+// aggregate's column to the groups' accumulators in a loop over the typed
+// values. Groups are kept in memory; if they outgrow the memory budget the
+// aggregation restarts as a row aggregation, which can spill to disk.
+// Output rows are those of aggregateOperator, in the same order.
+type vectorAggregateOperator struct {
+	scan     *vectorScan
+	ctx      *ExecutionContext
+	node     *plan.SelectNode
+	rows     *aggregateOperator // formats the groups, and runs instead once over budget
+	fallback bool
+	groups   []*group
+	reserved int64
+}
+
+func newVectorAggregateOperator(scan *vectorScan, node *plan.SelectNode, ctx *ExecutionContext) *vectorAggregateOperator {
+	return &vectorAggregateOperator{
+		scan: scan,
+		ctx:  ctx,
+		node: node,
+		rows: newAggregateOperator(&vectorRowsOperator{scan: scan}, node, ctx),
+	}
+}
+
+func (o *vectorAggregateOperator) Open() error {
+	o.scan.open()
+	err := o.aggregate()
+	o.scan.close()
+	if !errors.Is(err, errGroupsOverBudget) {
+		return err
+	}
+
+	o.release()
+	o.fallback = true
+	return o.rows.Open()
+}
+
+// aggregate reads every batch of the scan into groups
+func (o *vectorAggregateOperator) aggregate() error {
+	budget := o.ctx.memory()
+	byKey := make(map[string]int32)
+	o.groups = nil
+	if len(o.node.GroupBy) == 0 {
+		o.groups = append(o.groups, o.rows.newGroup(data.Row{}))
+	}
+
+	var key []byte
+	var groupOf []int32 // group of each selected row of the batch
+	for {
+		batch, err := o.scan.next()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			return nil
+		}
+
+		groups := make([]*vector.Vector, len(o.node.GroupBy))
+		for g, ref := range o.node.GroupBy {
+			groups[g] = batch.Vector(ref.Column, batch.Sel)
+		}
+		groupOf = groupOf[:0]
+		for _, i := range batch.Sel {
+			if len(o.node.GroupBy) == 0 {
+				groupOf = append(groupOf, 0)
+				continue
+			}
+			key = key[:0]
+			for _, v := range groups {
+				key = appendGroupKey(key, v, i)
+			}
+			g, exists := byKey[string(key)]
+			if !exists {
+				values := make(map[string]interface{}, len(o.node.GroupBy))
+				for g, ref := range o.node.GroupBy {
+					values[columnKey(ref)] = groups[g].Value(i)
+				}
+				size := spill.RowSize(data.Row{Data: values}) + int64(64*len(o.node.Aggregates))
+				if !budget.Reserve(size) {
+					return errGroupsOverBudget
+				}
+				o.reserved += size
+				g = int32(len(o.groups))
+				byKey[string(key)] = g
+				o.groups = append(o.groups, &group{row: values, accs: make([]accumulator, len(o.node.Aggregates))})
+			}
+			groupOf = append(groupOf, g)
+		}
+
+		for slot, agg := range o.node.Aggregates {
+			if agg.Column.Column == "*" {
+				for _, g := range groupOf {
+					o.groups[g].accs[slot].count++
+				}
+				continue
+			}
+			if err := o.accumulate(slot, agg, batch.Vector(agg.Column.Column, batch.Sel), batch.Sel, groupOf); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// appendGroupKey appends the group key encoding of the value at i
+// Keys equal those of aggregateOperator.groupKey, so equal values group
+// together whatever vector kind they were loaded in.
+func appendGroupKey(key []byte, v *vector.Vector, i int) []byte {
+	switch {
+	case v.IsNull(i):
+		key = append(key, "<nil>:<nil>"...)
+	case v.Kind == vector.KindInt64:
+		key = strconv.AppendInt(append(key, "int64:"...), v.Ints[i], 10)
+	case v.Kind == vector.KindString:
+		key = append(append(key, "string:"...), v.Strings[i]...)
+	default:
+		value := index.NormalizeKey(v.Value(i))
+		key = fmt.Appendf(key, "%T:%v", value, value)
+	}
+	return append(key, 0)
+}
+
+// accumulate adds the selected values of v to one aggregate of their groups
+// Typed vectors are summed, counted and compared without boxing.
+func (o *vectorAggregateOperator) accumulate(slot int, agg plan.Aggregate, v *vector.Vector, sel []int, groupOf []int32) error {
+	acc := func(k int) *accumulator {
+		return &o.groups[groupOf[k]].accs[slot]
+	}
+
+	switch {
+	case agg.Function == "COUNT":
+		for k, i := range sel {
+			if !v.IsNull(i) {
+				acc(k).count++
+			}
+		}
+	case (agg.Function == "SUM" || agg.Function == "AVG") && v.Kind == vector.KindInt64:
+		for k, i := range sel {
+			if !v.IsNull(i) {
+				a := acc(k)
+				a.sumInt += v.Ints[i]
+				a.sum += float64(v.Ints[i])
+				a.count++
+			}
+		}
+	case (agg.Function == "SUM" || agg.Function == "AVG") && v.Kind == vector.KindFloat64:
+		for k, i := range sel {
+			if !v.IsNull(i) {
+				a := acc(k)
+				a.isFloat = true
+				a.sum += v.Floats[i]
+				a.count++
+			}
+		}
+	case (agg.Function == "MIN" || agg.Function == "MAX") && len(o.node.GroupBy) == 0 && v.Kind != vector.KindAny:
+		// One group: find the batch's extreme on the typed values, then
+		// compare it with the running one
+		best, count := extreme(v, sel, agg.Function == "MIN")
+		if best < 0 {
+			return nil
+		}
+		a := acc(0)
+		if err := accumulateValue(a, agg, v.Value(best)); err != nil {
+			return err
+		}
+		a.count += int64(count - 1)
+	default:
+		for k, i := range sel {
+			if err := accumulateValue(acc(k), agg, v.Value(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extreme returns the position of the first smallest (or largest) non-NULL
+// selected value of v and the number of non-NULL values, -1 if all are NULL
+func extreme(v *vector.Vector, sel []int, smallest bool) (best int, count int) {
+	best = -1
+	for _, i := range sel {
+		if v.IsNull(i) {
+			continue
+		}
+		count++
+		if best < 0 {
+			best = i
+			continue
+		}
+		var c int
+		switch v.Kind {
+		case vector.KindInt64:
+			c = compareOrdered(v.Ints[i], v.Ints[best])
+		case vector.KindFloat64:
+			c = compareOrdered(v.Floats[i], v.Floats[best])
+		case vector.KindString:
+			c = compareOrdered(v.Strings[i], v.Strings[best])
+		default:
+			c = index.Compare(v.Value(i), v.Value(best))
+		}
+		if smallest && c < 0 || !smallest && c > 0 {
+			best = i
+		}
+	}
+	return best, count
+}
+
+// compareOrdered compares like index.Compare: -1, 0 or 1, with unordered
+// floats equal
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (o *vectorAggregateOperator) Next() (data.Row, bool, error) {
+	if o.fallback {
+		return o.rows.Next()
+	}
+	if len(o.groups) == 0 {
+		o.release()
+		return data.Row{}, false, nil
+	}
+	g := o.groups[0]
+	o.groups = o.groups[1:]
+	return o.rows.result(g), true, nil
+}
+
+// release returns the memory reserved for groups
+func (o *vectorAggregateOperator) release() {
+	o.ctx.memory().Release(o.reserved)
+	o.groups, o.reserved = nil, 0
+}
+
+func (o *vectorAggregateOperator) Close() error {
+	o.release()
+	if o.fallback {
+		return o.rows.Close()
+	}
+	return nil
+}
+
+func (o *vectorAggregateOperator) Schema() *schema.TableSchema {
+	return o.rows.Schema()
+}
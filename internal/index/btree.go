@@ -0,0 +1,297 @@
+package index
+
+import "sort"
+
+// DefaultBTreeOrder is the maximum number of keys held by a B+tree node
+const DefaultBTreeOrder = 64
+
+// BTree is an ordered index implemented as an in-memory B+tree
+//
+// Inner nodes only hold separator keys; every key and its row positions live
+// in the leaves, which are doubly linked so range scans and ordered iteration
+// walk the leaf chain without revisiting inner nodes.
+//
+// NULL keys are not indexed: a NULL never satisfies a range or equality
+// predicate, so callers fall back to a sequential scan for IS NULL checks.
+type BTree struct {
+	root  *bnode
+	first *bnode // leftmost leaf
+	last  *bnode // rightmost leaf
+	order int
+	size  int // number of distinct keys
+}
+
+// bnode is either an inner node (children set) or a leaf (values set)
+//
+// Inner node invariant: every key in children[i] is < keys[i] and every key in
+// children[i+1] is >= keys[i].
+type bnode struct {
+	leaf     bool
+	keys     []interface{}
+	children []*bnode // inner nodes only
+	values   [][]int  // leaves only, parallel to keys
+	prev     *bnode   // leaves only
+	next     *bnode   // leaves only
+}
+
+// NewBTree creates an empty B+tree with the default order
+func NewBTree() *BTree {
+	return NewBTreeWithOrder(DefaultBTreeOrder)
+}
+
+// NewBTreeWithOrder creates an empty B+tree whose nodes split above order keys
+func NewBTreeWithOrder(order int) *BTree {
+	if order < 3 {
+		order = 3
+	}
+	t := &BTree{order: order}
+	t.Clear()
+	return t
+}
+
+func (t *BTree) Kind() Kind {
+	return KindBTree
+}
+
+func (t *BTree) Len() int {
+	return t.size
+}
+
+func (t *BTree) Clear() {
+	leaf := &bnode{leaf: true}
+	t.root = leaf
+	t.first = leaf
+	t.last = leaf
+	t.size = 0
+}
+
+// Insert adds pos to the position list of key
+func (t *BTree) Insert(key interface{}, pos int) {
+	if key == nil {
+		return
+	}
+	key = NormalizeKey(key)
+
+	sepKey, right := t.insert(t.root, key, pos)
+	if right == nil {
+		return
+	}
+
+	// Root was split - grow the tree by one level
+	t.root = &bnode{
+		keys:     []interface{}{sepKey},
+		children: []*bnode{t.root, right},
+	}
+}
+
+// insert descends into n and returns a separator key and new right sibling
+// if n had to be split
+func (t *BTree) insert(n *bnode, key interface{}, pos int) (interface{}, *bnode) {
+	if n.leaf {
+		i := lowerBound(n.keys, key)
+		if i < len(n.keys) && Compare(n.keys[i], key) == 0 {
+			n.values[i] = append(n.values[i], pos)
+			return nil, nil
+		}
+
+		n.keys = insertAt(n.keys, i, key)
+		n.values = insertPositionsAt(n.values, i, []int{pos})
+		t.size++
+
+		if len(n.keys) <= t.order {
+			return nil, nil
+		}
+		return t.splitLeaf(n)
+	}
+
+	i := upperBound(n.keys, key)
+	sepKey, right := t.insert(n.children[i], key, pos)
+	if right == nil {
+		return nil, nil
+	}
+
+	n.keys = insertAt(n.keys, i, sepKey)
+	n.children = insertChildAt(n.children, i+1, right)
+
+	if len(n.keys) <= t.order {
+		return nil, nil
+	}
+	return splitInner(n)
+}
+
+// splitLeaf moves the upper half of a leaf into a new right sibling
+func (t *BTree) splitLeaf(n *bnode) (interface{}, *bnode) {
+	mid := len(n.keys) / 2
+
+	right := &bnode{
+		leaf:   true,
+		keys:   append([]interface{}{}, n.keys[mid:]...),
+		values: append([][]int{}, n.values[mid:]...),
+		prev:   n,
+		next:   n.next,
+	}
+	n.keys = n.keys[:mid:mid]
+	n.values = n.values[:mid:mid]
+
+	if n.next != nil {
+		n.next.prev = right
+	} else {
+		t.last = right
+	}
+	n.next = right
+
+	return right.keys[0], right
+}
+
+// splitInner moves the upper half of an inner node into a new right sibling
+// The middle key is promoted to the parent and kept in neither half
+func splitInner(n *bnode) (interface{}, *bnode) {
+	mid := len(n.keys) / 2
+	sepKey := n.keys[mid]
+
+	right := &bnode{
+		keys:     append([]interface{}{}, n.keys[mid+1:]...),
+		children: append([]*bnode{}, n.children[mid+1:]...),
+	}
+	n.keys = n.keys[:mid:mid]
+	n.children = n.children[: mid+1 : mid+1]
+
+	return sepKey, right
+}
+
+// Lookup returns the positions stored under key
+func (t *BTree) Lookup(key interface{}) []int {
+	if key == nil {
+		return nil
+	}
+	key = NormalizeKey(key)
+
+	leaf := t.findLeaf(key)
+	i := lowerBound(leaf.keys, key)
+	if i < len(leaf.keys) && Compare(leaf.keys[i], key) == 0 {
+		return leaf.values[i]
+	}
+	return nil
+}
+
+// Range iterates keys within the given bounds in ascending order
+func (t *BTree) Range(lower, upper *Bound, fn func(key interface{}, positions []int) bool) {
+	leaf := t.first
+	i := 0
+	if lower != nil {
+		lowerKey := NormalizeKey(lower.Value)
+		leaf = t.findLeaf(lowerKey)
+		if lower.Inclusive {
+			i = lowerBound(leaf.keys, lowerKey)
+		} else {
+			i = upperBound(leaf.keys, lowerKey)
+		}
+	}
+
+	var upperKey interface{}
+	if upper != nil {
+		upperKey = NormalizeKey(upper.Value)
+	}
+
+	for leaf != nil {
+		for ; i < len(leaf.keys); i++ {
+			if upper != nil {
+				c := Compare(leaf.keys[i], upperKey)
+				if c > 0 || (c == 0 && !upper.Inclusive) {
+					return
+				}
+			}
+			if !fn(leaf.keys[i], leaf.values[i]) {
+				return
+			}
+		}
+		leaf = leaf.next
+		i = 0
+	}
+}
+
+// Ascend iterates all keys in ascending order
+func (t *BTree) Ascend(fn func(key interface{}, positions []int) bool) {
+	t.Range(nil, nil, fn)
+}
+
+// Descend iterates all keys in descending order
+func (t *BTree) Descend(fn func(key interface{}, positions []int) bool) {
+	for leaf := t.last; leaf != nil; leaf = leaf.prev {
+		for i := len(leaf.keys) - 1; i >= 0; i-- {
+			if !fn(leaf.keys[i], leaf.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Min returns the smallest key
+func (t *BTree) Min() (interface{}, []int, bool) {
+	if t.size == 0 {
+		return nil, nil, false
+	}
+	return t.first.keys[0], t.first.values[0], true
+}
+
+// Max returns the largest key
+func (t *BTree) Max() (interface{}, []int, bool) {
+	if t.size == 0 {
+		return nil, nil, false
+	}
+	n := len(t.last.keys) - 1
+	return t.last.keys[n], t.last.values[n], true
+}
+
+// Height returns the number of levels in the tree (1 for a single leaf)
+func (t *BTree) Height() int {
+	h := 1
+	for n := t.root; !n.leaf; n = n.children[0] {
+		h++
+	}
+	return h
+}
+
+// findLeaf returns the leaf that would contain key
+func (t *BTree) findLeaf(key interface{}) *bnode {
+	n := t.root
+	for !n.leaf {
+		n = n.children[upperBound(n.keys, key)]
+	}
+	return n
+}
+
+// lowerBound returns the first index whose key is >= key
+func lowerBound(keys []interface{}, key interface{}) int {
+	return sort.Search(len(keys), func(i int) bool {
+		return Compare(keys[i], key) >= 0
+	})
+}
+
+// upperBound returns the first index whose key is > key
+func upperBound(keys []interface{}, key interface{}) int {
+	return sort.Search(len(keys), func(i int) bool {
+		return Compare(keys[i], key) > 0
+	})
+}
+
+func insertAt(keys []interface{}, i int, key interface{}) []interface{} {
+	keys = append(keys, nil)
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	return keys
+}
+
+func insertPositionsAt(values [][]int, i int, positions []int) [][]int {
+	values = append(values, nil)
+	copy(values[i+1:], values[i:])
+	values[i] = positions
+	return values
+}
+
+func insertChildAt(children []*bnode, i int, child *bnode) []*bnode {
+	children = append(children, nil)
+	copy(children[i+1:], children[i:])
+	children[i] = child
+	return children
+}
@@ -0,0 +1,142 @@
+package index
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBTreeInsertLookup(t *testing.T) {
+	tree := NewBTreeWithOrder(4) // small order forces many splits
+
+	for i := 0; i < 1000; i++ {
+		tree.Insert(i%500, i)
+	}
+
+	if tree.Len() != 500 {
+		t.Fatalf("Expected 500 distinct keys, got %d", tree.Len())
+	}
+	if tree.Height() < 3 {
+		t.Errorf("Expected tree to have split into at least 3 levels, got %d", tree.Height())
+	}
+
+	positions := tree.Lookup(int64(42))
+	if len(positions) != 2 || positions[0] != 42 || positions[1] != 542 {
+		t.Errorf("Expected positions [42 542], got %v", positions)
+	}
+
+	// int and int64 keys are interchangeable
+	if len(tree.Lookup(42)) != 2 {
+		t.Error("Expected int key lookup to match int64 key")
+	}
+
+	if tree.Lookup(9999) != nil {
+		t.Error("Expected nil for missing key")
+	}
+}
+
+func TestBTreeAscendDescend(t *testing.T) {
+	tree := NewBTreeWithOrder(4)
+
+	keys := rand.New(rand.NewSource(1)).Perm(300)
+	for pos, k := range keys {
+		tree.Insert(k, pos)
+	}
+
+	var ascending []int64
+	tree.Ascend(func(key interface{}, _ []int) bool {
+		ascending = append(ascending, key.(int64))
+		return true
+	})
+	if len(ascending) != 300 {
+		t.Fatalf("Expected 300 keys, got %d", len(ascending))
+	}
+	if !sort.SliceIsSorted(ascending, func(i, j int) bool { return ascending[i] < ascending[j] }) {
+		t.Error("Ascend did not return keys in ascending order")
+	}
+
+	var descending []int64
+	tree.Descend(func(key interface{}, _ []int) bool {
+		descending = append(descending, key.(int64))
+		return len(descending) < 5
+	})
+	expected := []int64{299, 298, 297, 296, 295}
+	for i := range expected {
+		if descending[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, descending)
+		}
+	}
+}
+
+func TestBTreeRange(t *testing.T) {
+	tree := NewBTreeWithOrder(4)
+	for i := 0; i < 100; i++ {
+		tree.Insert(i*2, i) // even numbers 0..198
+	}
+
+	collect := func(lower, upper *Bound) []int64 {
+		var keys []int64
+		tree.Range(lower, upper, func(key interface{}, _ []int) bool {
+			keys = append(keys, key.(int64))
+			return true
+		})
+		return keys
+	}
+
+	tests := []struct {
+		name         string
+		lower, upper *Bound
+		first, last  int64
+		count        int
+	}{
+		{"inclusive", &Bound{Value: 10, Inclusive: true}, &Bound{Value: 20, Inclusive: true}, 10, 20, 6},
+		{"exclusive", &Bound{Value: 10}, &Bound{Value: 20}, 12, 18, 4},
+		{"odd bounds", &Bound{Value: 11, Inclusive: true}, &Bound{Value: 19, Inclusive: true}, 12, 18, 4},
+		{"lower only", &Bound{Value: 190, Inclusive: true}, nil, 190, 198, 5},
+		{"upper only", nil, &Bound{Value: 4}, 0, 2, 2},
+		{"float bound", &Bound{Value: 9.5, Inclusive: true}, &Bound{Value: 12.5, Inclusive: true}, 10, 12, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := collect(tt.lower, tt.upper)
+			if len(keys) != tt.count {
+				t.Fatalf("Expected %d keys, got %d (%v)", tt.count, len(keys), keys)
+			}
+			if keys[0] != tt.first || keys[len(keys)-1] != tt.last {
+				t.Errorf("Expected range %d..%d, got %d..%d", tt.first, tt.last, keys[0], keys[len(keys)-1])
+			}
+		})
+	}
+
+	if keys := collect(&Bound{Value: 500, Inclusive: true}, nil); len(keys) != 0 {
+		t.Errorf("Expected empty range, got %v", keys)
+	}
+}
+
+func TestBTreeMinMax(t *testing.T) {
+	tree := NewBTree()
+
+	if _, _, ok := tree.Min(); ok {
+		t.Error("Expected no minimum in empty tree")
+	}
+
+	for _, s := range []string{"m", "c", "x", "a", "q"} {
+		tree.Insert(s, 0)
+	}
+	tree.Insert(nil, 1) // NULLs are not indexed
+
+	min, _, _ := tree.Min()
+	max, _, _ := tree.Max()
+	if min != "a" || max != "x" {
+		t.Errorf("Expected min a and max x, got %v and %v", min, max)
+	}
+	if tree.Len() != 5 {
+		t.Errorf("Expected 5 keys, got %d", tree.Len())
+	}
+
+	tree.Clear()
+	if tree.Len() != 0 {
+		t.Errorf("Expected empty tree after Clear, got %d keys", tree.Len())
+	}
+}
@@ -0,0 +1,96 @@
+package index
+
+import "strings"
+
+// Compare orders two index keys
+// Returns -1 if a < b, 0 if a == b, 1 if a > b
+//
+// Ordering rules:
+//   - NULL (nil) sorts before every other value
+//   - Numbers (int, int64, float64) compare numerically regardless of Go type
+//   - Strings compare lexicographically (DATE/TIME/EMAIL are stored as strings,
+//     and ISO dates sort correctly as text)
+//   - false sorts before true
+//   - Values of different kinds are ordered by kind: nil < bool < number < string
+func Compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch ra {
+	case rankNil:
+		return 0
+	case rankBool:
+		ab, bb := a.(bool), b.(bool)
+		switch {
+		case ab == bb:
+			return 0
+		case !ab:
+			return -1
+		default:
+			return 1
+		}
+	case rankNumber:
+		// Compare int64 values exactly to avoid float precision loss
+		if ai, ok := a.(int64); ok {
+			if bi, ok := b.(int64); ok {
+				switch {
+				case ai < bi:
+					return -1
+				case ai > bi:
+					return 1
+				}
+				return 0
+			}
+		}
+		af, bf := toFloat(a), toFloat(b)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case rankString:
+		return strings.Compare(a.(string), b.(string))
+	}
+	return 0
+}
+
+const (
+	rankNil = iota
+	rankBool
+	rankNumber
+	rankString
+	rankOther
+)
+
+func rank(v interface{}) int {
+	switch NormalizeKey(v).(type) {
+	case nil:
+		return rankNil
+	case bool:
+		return rankBool
+	case int64, float64, float32:
+		return rankNumber
+	case string:
+		return rankString
+	}
+	return rankOther
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := NormalizeKey(v).(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package index
+
+// Hash is an unordered index backed by a Go map
+// It answers equality lookups in O(1) but cannot serve range predicates
+type Hash struct {
+	data map[interface{}][]int // value → row positions
+}
+
+// NewHash creates an empty hash index
+func NewHash() *Hash {
+	return &Hash{data: make(map[interface{}][]int)}
+}
+
+func (h *Hash) Insert(key interface{}, pos int) {
+	key = NormalizeKey(key)
+	h.data[key] = append(h.data[key], pos)
+}
+
+func (h *Hash) Lookup(key interface{}) []int {
+	return h.data[NormalizeKey(key)]
+}
+
+func (h *Hash) Len() int {
+	return len(h.data)
+}
+
+func (h *Hash) Clear() {
+	h.data = make(map[interface{}][]int)
+}
+
+func (h *Hash) Kind() Kind {
+	return KindHash
+}
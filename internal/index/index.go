@@ -1 +1,94 @@
 package index
+
+import "strings"
+
+// Kind identifies the data structure backing an index
+type Kind string
+
+const (
+	KindHash  Kind = "HASH"  // Unordered hash map, equality lookups only
+	KindBTree Kind = "BTREE" // Ordered B+tree, supports range scans and ordered iteration
+)
+
+// ParseKind converts a USING clause value (case-insensitive) into a Kind
+// Returns false if the kind is not recognised
+func ParseKind(s string) (Kind, bool) {
+	switch Kind(strings.ToUpper(s)) {
+	case KindHash:
+		return KindHash, true
+	case KindBTree:
+		return KindBTree, true
+	}
+	return "", false
+}
+
+// Index maps column values to the positions of the rows holding them
+// All implementations normalize integer keys to int64 so that values coming
+// from SQL literals (int) and from JSON (int64 after validation) match.
+type Index interface {
+	// Insert records that the row at pos holds key
+	Insert(key interface{}, pos int)
+
+	// Lookup returns the row positions holding key (nil if none)
+	Lookup(key interface{}) []int
+
+	// Len returns the number of distinct keys
+	Len() int
+
+	// Clear removes every entry
+	Clear()
+
+	// Kind returns the structure type
+	Kind() Kind
+}
+
+// Ordered is an Index that keeps its keys sorted
+// It supports range predicates, ordered scans and MIN/MAX lookups
+type Ordered interface {
+	Index
+
+	// Range calls fn for every key within [lower, upper] in ascending order
+	// A nil bound is unbounded. Iteration stops when fn returns false.
+	Range(lower, upper *Bound, fn func(key interface{}, positions []int) bool)
+
+	// Ascend calls fn for every key in ascending order until fn returns false
+	Ascend(fn func(key interface{}, positions []int) bool)
+
+	// Descend calls fn for every key in descending order until fn returns false
+	Descend(fn func(key interface{}, positions []int) bool)
+
+	// Min returns the smallest key and its positions
+	Min() (interface{}, []int, bool)
+
+	// Max returns the largest key and its positions
+	Max() (interface{}, []int, bool)
+}
+
+// Bound is one end of a range scan
+type Bound struct {
+	Value     interface{}
+	Inclusive bool // true for >= / <=, false for > / <
+}
+
+// New creates an empty index of the given kind
+func New(kind Kind) Index {
+	if kind == KindBTree {
+		return NewBTree()
+	}
+	return NewHash()
+}
+
+// NormalizeKey converts integer types to int64 so keys compare consistently
+func NormalizeKey(key interface{}) interface{} {
+	switch v := key.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int8:
+		return int64(v)
+	}
+	return key
+}
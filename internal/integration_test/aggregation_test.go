@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/query/operations/testutil"
+)
+
+// TestOrderByAndGroupBy tests ORDER BY, GROUP BY and the aggregate functions
+func TestOrderByAndGroupBy(t *testing.T) {
+	sales := &schema.Table{
+		Name: "sales",
+		Schema: &schema.TableSchema{
+			TableName: "sales",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "user_id", Type: schema.ColumnTypeInt},
+				{Name: "region", Type: schema.ColumnTypeText},
+				{Name: "amount", Type: schema.ColumnTypeInt},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	for _, row := range []map[string]interface{}{
+		{"id": int64(1), "user_id": int64(1), "region": "north", "amount": int64(10)},
+		{"id": int64(2), "user_id": int64(2), "region": "south", "amount": int64(40)},
+		{"id": int64(3), "user_id": int64(1), "region": "north", "amount": int64(30)},
+		{"id": int64(4), "user_id": int64(3), "region": "east", "amount": nil},
+		{"id": int64(5), "user_id": int64(2), "region": "south", "amount": int64(20)},
+		{"id": int64(6), "user_id": nil, "region": nil, "amount": int64(5)},
+		{"id": int64(7), "user_id": int64(1), "region": "north", "amount": int64(20)},
+	} {
+		sales.Rows = append(sales.Rows, data.NewRow(row))
+	}
+	if err := indexing.BuildIndexes(sales); err != nil {
+		t.Fatalf("Failed to build indexes: %v", err)
+	}
+
+	db := &schema.Database{
+		Name: "aggregation",
+		Tables: map[string]*schema.Table{
+			"users":  testutil.CreateUsersTable(),
+			"orders": testutil.CreateOrdersTable(),
+			"sales":  sales,
+		},
+	}
+
+	// query runs a SELECT and returns its result
+	query := func(t *testing.T, sql string) (*executor.Result, error) {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		node, err := planner.Plan(stmt, db, nil)
+		if err != nil {
+			return nil, err
+		}
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		return executor.Execute(node, db, tx)
+	}
+
+	// format renders the result rows in column order, one string per row
+	format := func(result *executor.Result) []string {
+		rows := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			values := make([]string, len(result.Columns))
+			for j, col := range result.Columns {
+				values[j] = fmt.Sprint(row.Data[col])
+			}
+			rows[i] = strings.Join(values, " ")
+		}
+		return rows
+	}
+
+	t.Run("Results in order", func(t *testing.T) {
+		tests := []struct {
+			sql      string
+			expected []string
+		}{
+			{
+				sql:      "SELECT id, amount FROM sales ORDER BY amount",
+				expected: []string{"6 5", "1 10", "5 20", "7 20", "3 30", "2 40", "4 <nil>"},
+			},
+			{
+				sql:      "SELECT id, amount FROM sales ORDER BY amount DESC LIMIT 3",
+				expected: []string{"4 <nil>", "2 40", "3 30"},
+			},
+			{
+				sql:      "SELECT id FROM sales WHERE region = 'north' ORDER BY amount DESC, id",
+				expected: []string{"3", "7", "1"},
+			},
+			{
+				sql:      "SELECT id FROM sales ORDER BY region, id DESC",
+				expected: []string{"4", "7", "3", "1", "5", "2", "6"},
+			},
+			{
+				sql:      "SELECT region, COUNT(*), SUM(amount), MIN(amount), MAX(amount) FROM sales GROUP BY region ORDER BY region",
+				expected: []string{"east 1 <nil> <nil> <nil>", "north 3 60 10 30", "south 2 60 20 40", "<nil> 1 5 5 5"},
+			},
+			{
+				sql:      "SELECT region, AVG(amount), COUNT(amount) FROM sales GROUP BY region ORDER BY region",
+				expected: []string{"east <nil> 0", "north 20 3", "south 30 2", "<nil> 5 1"},
+			},
+			{
+				sql:      "SELECT region FROM sales GROUP BY region ORDER BY COUNT(*) DESC, region",
+				expected: []string{"north", "south", "east", "<nil>"},
+			},
+			{
+				sql:      "SELECT COUNT(*), SUM(amount), MAX(region) FROM sales",
+				expected: []string{"7 125 south"},
+			},
+			{
+				sql:      "SELECT COUNT(*) FROM sales",
+				expected: []string{"7"},
+			},
+			{
+				sql:      "SELECT COUNT(*), SUM(amount) FROM sales WHERE amount > 100",
+				expected: []string{"0 <nil>"},
+			},
+			{
+				sql:      "SELECT SUM(orders.amount) FROM orders",
+				expected: []string{"1100.49"},
+			},
+			{
+				sql:      "SELECT users.username, COUNT(*) FROM users JOIN sales ON users.id = sales.user_id GROUP BY users.username ORDER BY users.username",
+				expected: []string{"alice 3", "bob 2", "charlie 1"},
+			},
+		}
+
+		for _, tt := range tests {
+			result, err := query(t, tt.sql)
+			if err != nil {
+				t.Errorf("%s: %v", tt.sql, err)
+				continue
+			}
+			if got := format(result); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("%s:\n got  %q\n want %q", tt.sql, got, tt.expected)
+			}
+		}
+	})
+
+	t.Run("Aggregate column names", func(t *testing.T) {
+		result, err := query(t, "SELECT region, count(*), sum(amount) FROM sales GROUP BY region")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		expected := []string{"region", "count(*)", "sum(amount)"}
+		if fmt.Sprint(result.Columns) != fmt.Sprint(expected) {
+			t.Errorf("Expected columns %v, got %v", expected, result.Columns)
+		}
+		if len(result.Rows) != 4 {
+			t.Errorf("Expected 4 groups, got %d", len(result.Rows))
+		}
+	})
+
+	t.Run("Invalid grouping", func(t *testing.T) {
+		tests := []struct {
+			sql     string
+			message string
+		}{
+			{"SELECT * FROM sales GROUP BY region", "SELECT * is not allowed"},
+			{"SELECT id, COUNT(*) FROM sales GROUP BY region", "column id must appear in GROUP BY"},
+			{"SELECT id, COUNT(*) FROM sales", "column id must appear in GROUP BY"},
+			{"SELECT region FROM sales GROUP BY region ORDER BY amount", "ORDER BY column amount"},
+			{"SELECT SUM(region) FROM sales", "SUM requires numeric values"},
+		}
+		for _, tt := range tests {
+			_, err := query(t, tt.sql)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.sql, tt.message, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/backup"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestBackupRestore tests that BACKUP DATABASE snapshots the loaded state of
+// a database and RESTORE DATABASE recreates it after checking the backup
+func TestBackupRestore(t *testing.T) {
+	basePath := t.TempDir()
+	backups := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	formats, err := storageEngine.NewFormatEngine(storageEngine.StorageJSON, 16)
+	if err != nil {
+		t.Fatalf("Failed to create storage engine: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, formats)
+	eng := engine.New(nil, registry)
+
+	mustExec := func(t *testing.T, sql string) {
+		t.Helper()
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+	}
+	usernames := func(t *testing.T, database string) string {
+		t.Helper()
+		mustExec(t, "USE "+database)
+		result, err := eng.Execute("SELECT username FROM users ORDER BY id")
+		if err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row.Data["username"].(string))
+		}
+		return strings.Join(names, ",")
+	}
+
+	mustExec(t, "USE shop")
+	// Not saved yet: the backup takes the rows in memory
+	mustExec(t, "INSERT INTO users (username, email) VALUES ('carol', 'carol@example.com')")
+	want := usernames(t, "shop")
+	full := filepath.Join(backups, "full")
+
+	t.Run("Backup and restore", func(t *testing.T) {
+		mustExec(t, "BACKUP DATABASE shop TO '"+full+"'")
+		if _, err := backup.Verify(full); err != nil {
+			t.Fatalf("Expected a valid backup, got %v", err)
+		}
+
+		mustExec(t, "RESTORE DATABASE shop_copy FROM '"+full+"'")
+		if got := usernames(t, "shop_copy"); got != want {
+			t.Errorf("Expected the restored users %q, got %q", want, got)
+		}
+		// The restored database is independent of the original
+		mustExec(t, "DELETE FROM users")
+		if got := usernames(t, "shop"); got != want {
+			t.Errorf("Expected the original users %q, got %q", want, got)
+		}
+
+		if _, err := eng.Execute("RESTORE DATABASE shop_copy FROM '" + full + "'"); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("Expected restoring over a database to fail, got %v", err)
+		}
+		if _, err := eng.Execute("BACKUP DATABASE shop TO '" + full + "'"); err == nil || !strings.Contains(err.Error(), "not empty") {
+			t.Errorf("Expected a backup into a used directory to fail, got %v", err)
+		}
+	})
+
+	t.Run("Damaged backups are refused", func(t *testing.T) {
+		damaged := filepath.Join(backups, "damaged")
+		mustExec(t, "BACKUP DATABASE shop TO '"+damaged+"'")
+		dataPath := filepath.Join(damaged, "users", "data.json")
+		content, err := os.ReadFile(dataPath)
+		if err != nil {
+			t.Fatalf("Failed to read backup: %v", err)
+		}
+		if err := os.WriteFile(dataPath, []byte(strings.Replace(string(content), "carol", "carla", 1)), 0644); err != nil {
+			t.Fatalf("Failed to damage backup: %v", err)
+		}
+
+		_, err = eng.Execute("RESTORE DATABASE shop_damaged FROM '" + damaged + "'")
+		if err == nil || !strings.Contains(err.Error(), "users/data.json does not match its checksum") {
+			t.Errorf("Expected a checksum error, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(basePath, "shop_damaged")); !os.IsNotExist(err) {
+			t.Errorf("Expected no database from a damaged backup, got %v", err)
+		}
+
+		os.Remove(filepath.Join(damaged, backup.ManifestName))
+		if _, err := eng.Execute("RESTORE DATABASE shop_damaged FROM '" + damaged + "'"); err == nil || !strings.Contains(err.Error(), "manifest.json") {
+			t.Errorf("Expected a backup without manifest to be refused, got %v", err)
+		}
+	})
+
+	t.Run("Page storage", func(t *testing.T) {
+		mustExec(t, "ALTER DATABASE shop SET STORAGE = page")
+		paged := filepath.Join(backups, "paged")
+		mustExec(t, "BACKUP DATABASE shop TO '"+paged+"'")
+
+		mustExec(t, "RESTORE DATABASE shop_paged FROM '"+paged+"'")
+		if got := usernames(t, "shop_paged"); got != want {
+			t.Errorf("Expected the restored users %q, got %q", want, got)
+		}
+		db, _ := registry.Get("shop_paged")
+		if db.Storage != storageEngine.StoragePage {
+			t.Errorf("Expected the restored database in page storage, got %q", db.Storage)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/network"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// slowQuery cross joins ticks and tocks without matching a single pair, so it
+// runs for seconds without producing rows
+const slowQuery = "SELECT * FROM ticks, tocks WHERE ticks.n < tocks.n"
+
+// addSlowTables adds the ticks and tocks tables slowQuery reads to db
+func addSlowTables(t *testing.T, db *schema.Database) {
+	t.Helper()
+	const rowCount = 5000
+
+	for i, name := range []string{"ticks", "tocks"} {
+		// Every tick is larger than every tock
+		rows := make([]map[string]interface{}, rowCount)
+		for n := range rows {
+			rows[n] = map[string]interface{}{"n": int64(n + (1-i)*rowCount)}
+		}
+		db.Tables[name] = newTable(t, name, []schema.Column{{Name: "n", Type: schema.ColumnTypeInt}}, rows...)
+	}
+}
+
+// TestQueryCancellation tests that a cancelled context or a statement timeout
+// stops a running query
+func TestQueryCancellation(t *testing.T) {
+	db := &schema.Database{Name: "cancellation", Tables: map[string]*schema.Table{}}
+	addSlowTables(t, db)
+
+	run := func(ctx context.Context, node plan.Node) error {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		_, err := executor.ExecuteContext(ctx, node, db, tx)
+		return err
+	}
+
+	t.Run("Cancelled before execution", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := run(ctx, planQuery(t, db, "SELECT * FROM ticks")); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Cancelled during a scan", func(t *testing.T) {
+		if executor.DefaultExecutionConfig().Strategy == executor.StrategyVectorized {
+			t.Skip("the vectorized strategy filters whole batches with the compiled WHERE clause, not the row predicate")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		reads := 0
+		query := planQuery(t, db, "SELECT * FROM ticks WHERE n > 0")
+		pred := query.Predicate
+		query.Predicate = func(row data.Row) bool {
+			reads++
+			if reads == 1000 {
+				cancel()
+			}
+			return pred(row)
+		}
+
+		if err := run(ctx, query); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if reads >= 2000 {
+			t.Errorf("Expected the scan to stop soon after the cancel, read %d rows", reads)
+		}
+	})
+
+	t.Run("Cancelled during a join", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		query := planQuery(t, db, slowQuery)
+		joinNode := query.Children()[0].(*plan.JoinNode)
+		probes := 0
+		filter := joinNode.OnPredicate
+		joinNode.OnPredicate = func(row data.Row) bool {
+			probes++
+			if probes == 10000 {
+				cancel()
+			}
+			return filter(row)
+		}
+
+		start := time.Now()
+		if err := run(ctx, query); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		// One outer row is compared with every inner row between checks
+		if probes > 20000 {
+			t.Errorf("Expected the join to stop within one outer row, compared %d pairs", probes)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the join to stop promptly, took %s", elapsed)
+		}
+	})
+
+	t.Run("statement_timeout", func(t *testing.T) {
+		eng := engine.New(db, nil)
+		if _, err := eng.Execute("SET statement_timeout = '20ms'"); err != nil {
+			t.Fatalf("SET failed: %v", err)
+		}
+		if eng.StatementTimeout() != 20*time.Millisecond {
+			t.Errorf("Expected a 20ms timeout, got %s", eng.StatementTimeout())
+		}
+
+		start := time.Now()
+		_, err := eng.Execute(slowQuery)
+		if err == nil || !strings.Contains(err.Error(), "statement timeout of 20ms exceeded") {
+			t.Fatalf("Expected a statement timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the timeout to stop the query promptly, took %s", elapsed)
+		}
+
+		// Quick statements are unaffected
+		if _, err := eng.Execute("SELECT * FROM ticks LIMIT 1"); err != nil {
+			t.Errorf("Expected a quick query to finish, got %v", err)
+		}
+
+		// A bare number is milliseconds; 0 and DEFAULT turn the timeout off
+		values := []struct {
+			value string
+			want  time.Duration
+		}{
+			{"1500", 1500 * time.Millisecond},
+			{"'2m'", 2 * time.Minute},
+			{"0", 0},
+			{"'1s'", time.Second},
+			{"DEFAULT", 0},
+		}
+		for _, tt := range values {
+			if _, err := eng.Execute("SET statement_timeout = " + tt.value); err != nil {
+				t.Fatalf("SET statement_timeout = %s failed: %v", tt.value, err)
+			}
+			if eng.StatementTimeout() != tt.want {
+				t.Errorf("SET statement_timeout = %s: expected %s, got %s", tt.value, tt.want, eng.StatementTimeout())
+			}
+		}
+
+		for _, sql := range []string{
+			"SET statement_timeout = 'soon'",
+			"SET statement_timeout = '-1s'",
+			"SET search_path = 'public'",
+		} {
+			if _, err := eng.Execute(sql); err == nil {
+				t.Errorf("Expected an error for %q", sql)
+			}
+		}
+	})
+}
+
+// TestServerCancellation tests that a client can cancel its running query
+// with a cancel request, and that disconnecting stops it too
+func TestServerCancellation(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer teardownTestDB(t, testDB)
+
+	registry := manager.NewRegistry(filepath.Dir(testDBPath), storageEngine.NewJSONEngine())
+	db, err := registry.Get("testdb_integration")
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+	addSlowTables(t, db)
+
+	addr := startServer(t, registry)
+
+	connect := func(t *testing.T) (net.Conn, *json.Encoder, *json.Decoder) {
+		t.Helper()
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		encoder, decoder := json.NewEncoder(conn), json.NewDecoder(conn)
+		if err := encoder.Encode(network.Request{Query: "USE testdb_integration"}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+		var res Result
+		if err := decoder.Decode(&res); err != nil || res.Error != "" {
+			t.Fatalf("USE failed: %v %s", err, res.Error)
+		}
+		return conn, encoder, decoder
+	}
+
+	t.Run("Cancel request", func(t *testing.T) {
+		conn, encoder, decoder := connect(t)
+		defer conn.Close()
+
+		start := time.Now()
+		if err := encoder.Encode(network.Request{Query: slowQuery}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if err := encoder.Encode(network.Request{Cancel: true}); err != nil {
+			t.Fatalf("Failed to send cancel: %v", err)
+		}
+
+		var res Result
+		if err := decoder.Decode(&res); err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+		if !strings.Contains(res.Error, "query cancelled") {
+			t.Errorf("Expected the query to be cancelled, got %+v", res)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the cancel to stop the query promptly, took %s", elapsed)
+		}
+
+		// The connection keeps working
+		if err := encoder.Encode(network.Request{Query: "SELECT * FROM ticks LIMIT 2"}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+		res = Result{}
+		if err := decoder.Decode(&res); err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+		if res.Error != "" || len(res.Rows) != 2 {
+			t.Errorf("Expected 2 rows after the cancel, got %+v", res)
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		conn, encoder, _ := connect(t)
+		if err := encoder.Encode(network.Request{Query: slowQuery}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+
+		// The running join holds a read lock on its inner table until it stops,
+		// so a write goes through only once the query is gone
+		written := make(chan error, 1)
+		go func() {
+			tx := transaction.NewTransaction()
+			defer tx.Close()
+			written <- db.Tables["tocks"].Insert(data.NewRow(map[string]interface{}{"n": int64(-1)}), tx)
+		}()
+		select {
+		case err := <-written:
+			if err != nil {
+				t.Errorf("Insert failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected the disconnect to stop the query")
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// TestCheckDatabase tests that CHECK DATABASE reports every problem of a
+// damaged database and that REPAIR applies only the safe fixes
+func TestCheckDatabase(t *testing.T) {
+	basePath := t.TempDir()
+	archiveRoot := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	storageEng, err := storageEngine.NewFormatEngine(storageEngine.StorageJSON, page.DefaultBufferPages)
+	if err != nil {
+		t.Fatalf("Failed to create storage engine: %v", err)
+	}
+	open := func() (*manager.Registry, *engine.Engine) {
+		registry := manager.NewRegistry(basePath, storageEng)
+		// Small segments, so the log spans the archive and the active segment
+		registry.EnableWAL(archiveRoot, 512)
+		return registry, engine.New(nil, registry)
+	}
+	registry, eng := open()
+
+	mustExec := func(t *testing.T, sql string) *executor.Result {
+		t.Helper()
+		result, err := eng.Execute(sql)
+		if err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+		return result
+	}
+	problems := func(result *executor.Result) string {
+		var out []string
+		for _, row := range result.Rows {
+			line := row.Data["object"].(string) + ": " + row.Data["problem"].(string)
+			if row.Data["repaired"] == true {
+				line += " (repaired)"
+			}
+			out = append(out, line)
+		}
+		return strings.Join(out, "\n")
+	}
+	closeRegistry := func() {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		registry.SaveAll(tx)
+		registry.Close()
+	}
+
+	mustExec(t, "USE shop")
+	for _, name := range []string{"carol", "dave", "erin", "frank"} {
+		mustExec(t, "INSERT INTO users (username, email) VALUES ('"+name+"', '"+name+"@example.com')")
+	}
+
+	t.Run("A sound database has no problems", func(t *testing.T) {
+		result := mustExec(t, "CHECK DATABASE shop")
+		if len(result.Rows) != 0 {
+			t.Fatalf("Expected no problems, got\n%s", problems(result))
+		}
+		if !strings.Contains(result.Message, "no problems found") {
+			t.Errorf("Unexpected message %q", result.Message)
+		}
+		if _, err := eng.Execute("CHECK DATABASE shop REPAIR"); err == nil {
+			t.Errorf("Expected a loaded database not to be repaired")
+		}
+	})
+
+	closeRegistry()
+
+	// Damage the table files and the log
+	usersDir := filepath.Join(basePath, "shop", "users")
+	var rows []map[string]interface{}
+	readJSON(t, filepath.Join(usersDir, "data.json"), &rows)
+	writeJSON(t, filepath.Join(usersDir, "data.json"), append(rows, rows[0]))
+	var meta map[string]interface{}
+	readJSON(t, filepath.Join(usersDir, "meta.json"), &meta)
+	meta["last_insert_id"] = 2
+	writeJSON(t, filepath.Join(usersDir, "meta.json"), meta)
+
+	archived, _ := wal.ListSegments(filepath.Join(archiveRoot, "shop"))
+	active, _ := wal.ListSegments(filepath.Join(basePath, "shop", archive.WALDir))
+	if len(archived) == 0 || len(active) != 1 {
+		t.Fatalf("Expected archived segments and one active segment, got %v and %v", archived, active)
+	}
+	content, err := os.ReadFile(archived[0])
+	if err != nil {
+		t.Fatalf("Failed to read segment: %v", err)
+	}
+	content[len(content)-1] ^= 0xff
+	os.WriteFile(archived[0], content, 0644)
+	f, err := os.OpenFile(active[0], os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	f.Write([]byte("torn record"))
+	f.Close()
+
+	registry, eng = open()
+	defer func() { registry.Close() }()
+
+	t.Run("USE points to CHECK DATABASE", func(t *testing.T) {
+		_, err := eng.Execute("USE shop")
+		if err == nil || !strings.Contains(err.Error(), "CHECK DATABASE shop") {
+			t.Errorf("Expected the load error to point to CHECK DATABASE, got %v", err)
+		}
+	})
+
+	expected := []string{
+		"users: rows 0 and 6 have the same id 1",
+		"users: rows 0 and 6 have the same username admin",
+		"users: last_insert_id is 2 but rows go up to id 6",
+		"users: row_count is 6 but the table holds 7 rows",
+		filepath.Base(archived[0]) + ": CRC mismatch",
+		filepath.Base(active[0]) + ": incomplete header",
+	}
+
+	t.Run("Every problem is reported", func(t *testing.T) {
+		result := mustExec(t, "CHECK DATABASE shop")
+		got := problems(result)
+		for _, want := range expected {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected a problem %q, got\n%s", want, got)
+			}
+		}
+		if strings.Contains(got, "(repaired)") {
+			t.Errorf("Expected nothing to be repaired without REPAIR, got\n%s", got)
+		}
+	})
+
+	t.Run("REPAIR applies the safe fixes", func(t *testing.T) {
+		result := mustExec(t, "CHECK DATABASE shop REPAIR")
+		got := problems(result)
+		for _, line := range strings.Split(got, "\n") {
+			safe := strings.Contains(line, "last_insert_id") || strings.Contains(line, "row_count") ||
+				strings.Contains(line, "incomplete header")
+			if safe != strings.HasSuffix(line, "(repaired)") {
+				t.Errorf("Expected only the safe fixes to be repaired, got\n%s", got)
+				break
+			}
+		}
+		result = mustExec(t, "CHECK DATABASE shop")
+		got = problems(result)
+		if len(result.Rows) != 4 || strings.Contains(got, "row_count") || strings.Contains(got, "incomplete") {
+			t.Errorf("Expected only the duplicates and the archived segment left, got\n%s", got)
+		}
+	})
+
+	t.Run("A fixed database loads and continues its ids", func(t *testing.T) {
+		writeJSON(t, filepath.Join(usersDir, "data.json"), rows)
+		mustExec(t, "CHECK DATABASE shop REPAIR")
+		mustExec(t, "USE shop")
+		mustExec(t, "INSERT INTO users (username, email) VALUES ('grace', 'grace@example.com')")
+		result := mustExec(t, "SELECT id FROM users WHERE username = 'grace'")
+		if len(result.Rows) != 1 || result.Rows[0].Data["id"] != int64(7) {
+			t.Errorf("Expected grace to get id 7, got %v", result.Rows)
+		}
+	})
+
+	t.Run("Damaged pages are reported", func(t *testing.T) {
+		if err := registry.CreateWithStorage("paged", storageEngine.StoragePage); err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		mustExec(t, "USE paged")
+		mustExec(t, "CREATE TABLE notes (id INT PRIMARY KEY AUTO_INCREMENT, body TEXT)")
+		mustExec(t, "INSERT INTO notes (body) VALUES ('one'), ('two')")
+		tx := transaction.NewTransaction()
+		registry.SaveAll(tx)
+		tx.Close()
+		if result := mustExec(t, "CHECK DATABASE paged"); len(result.Rows) != 0 {
+			t.Fatalf("Expected no problems, got\n%s", problems(result))
+		}
+
+		heapPath := filepath.Join(basePath, "paged", "notes", page.FileName)
+		content, err := os.ReadFile(heapPath)
+		if err != nil {
+			t.Fatalf("Failed to read heap: %v", err)
+		}
+		content[page.Size+page.Size/2] ^= 0xff
+		os.WriteFile(heapPath, content, 0644)
+
+		got := problems(mustExec(t, "CHECK DATABASE paged"))
+		if !strings.Contains(got, "notes: cannot read rows") {
+			t.Errorf("Expected the damaged page to be reported, got\n%s", got)
+		}
+	})
+}
+
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		t.Fatalf("Failed to parse %s: %v", path, err)
+	}
+}
+
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,148 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/engine"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestCopy tests loading CSV and JSON Lines files into tables with COPY and
+// writing tables and queries back out
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	registry := manager.NewRegistry(t.TempDir(), storageEngine.NewJSONEngine())
+	defer registry.Close()
+	eng := engine.New(nil, registry)
+
+	mustExec := func(t *testing.T, sql string) string {
+		t.Helper()
+		result, err := eng.Execute(sql)
+		if err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+		return result.Message
+	}
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(content)
+	}
+	contents := func(t *testing.T, table string) string {
+		t.Helper()
+		result, err := eng.Execute("SELECT * FROM " + table + " ORDER BY id")
+		if err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		var out []string
+		for _, row := range result.Rows {
+			out = append(out, fmt.Sprint(row.Data))
+		}
+		return strings.Join(out, "\n")
+	}
+
+	mustExec(t, "CREATE DATABASE shop")
+	mustExec(t, "USE shop")
+	for _, table := range []string{"items", "csv_copy", "jsonl_copy"} {
+		mustExec(t, "CREATE TABLE "+table+" (id INT PRIMARY KEY AUTO_INCREMENT, name TEXT NOT NULL, price FLOAT, stocked BOOL, added DATE, contact EMAIL UNIQUE)")
+	}
+
+	csvPath := writeFile(t, "items.csv", `name,price,stocked,added,contact
+widget,2,true,2024-01-02,sales@example.com
+"gadget, large",1.5,,2024-02-03,
+"say ""hi""",,false,,
+`)
+	if got := mustExec(t, "COPY items FROM '"+csvPath+"' WITH (HEADER)"); got != "COPY 3" {
+		t.Fatalf("Expected COPY 3, got %q", got)
+	}
+	jsonlPath := writeFile(t, "items.jsonl", `{"name": "sprocket", "price": 3, "contact": null}
+
+{"name": "spring", "added": "2024-05-06", "stocked": true}
+`)
+	mustExec(t, "COPY items (name, price, stocked, added, contact) FROM '"+jsonlPath+"' WITH (FORMAT JSONL)")
+	loaded := contents(t, "items")
+
+	t.Run("Values are converted to column types", func(t *testing.T) {
+		result, err := eng.Execute("SELECT * FROM items WHERE id = 1")
+		if err != nil || len(result.Rows) != 1 {
+			t.Fatalf("Expected one row, got %v (%v)", result, err)
+		}
+		row := result.Rows[0].Data
+		if row["price"] != 2.0 || row["stocked"] != true || row["added"] != "2024-01-02" {
+			t.Errorf("Unexpected row %v", row)
+		}
+		if _, ok := row["contact"]; !ok {
+			t.Errorf("Expected contact to be set, got %v", row)
+		}
+		result, _ = eng.Execute("SELECT * FROM items WHERE id = 2")
+		if _, ok := result.Rows[0].Data["stocked"]; ok {
+			t.Errorf("Expected an empty field to be NULL, got %v", result.Rows[0].Data)
+		}
+	})
+
+	t.Run("Query to CSV", func(t *testing.T) {
+		out := filepath.Join(dir, "cheap.csv")
+		mustExec(t, "COPY (SELECT id, name, price FROM items WHERE price < 3 ORDER BY id) TO '"+out+"' WITH (HEADER, DELIMITER ';', NULL 'NULL')")
+		expected := "id;name;price\n1;widget;2\n2;gadget, large;1.5\n"
+		if got := readFile(t, out); got != expected {
+			t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+		}
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		for _, format := range []string{"CSV", "JSONL"} {
+			copyTable := strings.ToLower(format) + "_copy"
+			out := filepath.Join(dir, "items."+strings.ToLower(format))
+			mustExec(t, "COPY items TO '"+out+"' WITH (FORMAT "+format+")")
+			if got := mustExec(t, "COPY "+copyTable+" FROM '"+out+"' WITH (FORMAT "+format+")"); got != "COPY 5" {
+				t.Errorf("%s: expected COPY 5, got %q", format, got)
+			}
+			if got := contents(t, copyTable); got != loaded {
+				t.Errorf("%s: expected the copy to match\n%s\ngot\n%s", format, loaded, got)
+			}
+		}
+	})
+
+	t.Run("Bad records", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			sql     string
+			content string
+			line    string
+		}{
+			{"bad number", "COPY items (name, price) FROM '%s'", "a,1\nb,cheap\n", "line 2"},
+			{"bad date", "COPY items (name, added) FROM '%s' WITH (HEADER)", "name,added\na,2024-01-02\nb,2024-13-01\n", "line 3"},
+			{"missing NOT NULL", "COPY items (name, price) FROM '%s' WITH (NULL '-')", "a,1\n-,2\n", "line 2"},
+			{"wrong field count", "COPY items (name, price) FROM '%s'", "a,1\nb\n", "line 2"},
+			{"duplicate key", "COPY items (name, contact) FROM '%s'", "a,new@example.com\nb,sales@example.com\n", "line 2"},
+			{"unknown JSON column", "COPY items FROM '%s' WITH (FORMAT JSONL)", "{\"name\": \"a\"}\n{\"colour\": \"red\"}\n", "line 2"},
+			{"invalid JSON", "COPY items FROM '%s' WITH (FORMAT JSONL)", "{\"name\": \"a\"}\n\n{\"name\": \n", "line 3"},
+		}
+		for _, tt := range tests {
+			path := writeFile(t, "bad.txt", tt.content)
+			_, err := eng.Execute(fmt.Sprintf(tt.sql, path))
+			if err == nil || !strings.Contains(err.Error(), tt.line) {
+				t.Errorf("%s: expected an error at %s, got %v", tt.name, tt.line, err)
+			}
+		}
+		if got := contents(t, "items"); got != loaded {
+			t.Errorf("Expected bad files to insert nothing else\n%s\ngot\n%s", loaded, got)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	"github.com/leengari/mini-rdbms/internal/storage/dump"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// TestDumpAndLoad tests that a dumped database is recreated by loading the
+// dump, and that a script which fails part way leaves nothing behind
+func TestDumpAndLoad(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+	defer registry.Close()
+	registry.EnableWAL(t.TempDir(), wal.DefaultSegmentSize)
+	eng := engine.New(nil, registry)
+
+	mustExec := func(t *testing.T, sql string) {
+		t.Helper()
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+	}
+	dumpOf := func(t *testing.T, r *manager.Registry, name string) string {
+		t.Helper()
+		db, err := r.Get(name)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := dump.Write(&buf, db); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		return buf.String()
+	}
+	// Undoing a delete puts the row back at the end, so contents are
+	// compared in key order
+	contents := func(t *testing.T) string {
+		t.Helper()
+		mustExec(t, "USE shop")
+		var out []string
+		for _, table := range []string{"users", "notes"} {
+			result, err := eng.Execute("SELECT * FROM " + table + " ORDER BY id")
+			if err != nil {
+				t.Fatalf("SELECT failed: %v", err)
+			}
+			for _, row := range result.Rows {
+				out = append(out, fmt.Sprint(row.Data))
+			}
+		}
+		return strings.Join(out, "\n")
+	}
+
+	mustExec(t, "USE shop")
+	mustExec(t, "CREATE TABLE notes (id INT PRIMARY KEY AUTO_INCREMENT, body TEXT NOT NULL, score FLOAT, done BOOL, due DATE)")
+	mustExec(t, "INSERT INTO notes (body, score, done, due) VALUES ('it''s -- not a comment; really', -1.5, TRUE, '2024-01-02'), ('whole', 2.0, FALSE, '2024-02-03')")
+	mustExec(t, "INSERT INTO notes (body) VALUES ('no optional values')")
+	mustExec(t, "CREATE INDEX idx_notes_score ON notes (score) USING BTREE")
+	original := dumpOf(t, registry, "shop")
+	before := contents(t)
+
+	t.Run("Load recreates the dump", func(t *testing.T) {
+		target := manager.NewRegistry(t.TempDir(), storageEngine.NewJSONEngine())
+		defer target.Close()
+		result, err := engine.New(nil, target).ExecuteScript(context.Background(), original)
+		if err != nil {
+			t.Fatalf("ExecuteScript failed: %v", err)
+		}
+		if result.RowsAffected == 0 {
+			t.Error("Expected the load to insert rows")
+		}
+		if got := dumpOf(t, target, "shop"); got != original {
+			t.Errorf("Expected the loaded database to dump the same\n--- original\n%s\n--- loaded\n%s", original, got)
+		}
+	})
+
+	t.Run("Failing script is undone", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "change.sql")
+		os.WriteFile(script, []byte(`-- Changes to undo
+UPDATE users SET email = 'changed@example.com' WHERE username = 'admin';
+DELETE FROM notes WHERE body = 'whole';
+INSERT INTO notes (body) VALUES ('added');
+CREATE INDEX idx_notes_done ON notes (done);
+CREATE DATABASE scratch;
+USE scratch;
+CREATE TABLE t (id INT PRIMARY KEY);
+INSERT INTO t (id) VALUES (1);
+INSERT INTO t (id)
+  VALUES (1);
+`), 0644)
+
+		_, err := eng.Execute("SOURCE '" + script + "'")
+		if err == nil || !strings.Contains(err.Error(), "line 10") {
+			t.Fatalf("Expected the script to fail at line 10, got %v", err)
+		}
+		// The session is back on the database it used before the script
+		if _, err := eng.Execute("SELECT * FROM notes WHERE id = 1"); err != nil {
+			t.Errorf("Expected the session to stay on shop, got %v", err)
+		}
+		if got := contents(t); got != before {
+			t.Errorf("Expected the script's changes to be undone\n--- before\n%s\n--- after\n%s", before, got)
+		}
+		if strings.Contains(dumpOf(t, registry, "shop"), "idx_notes_done") {
+			t.Error("Expected the index the script created to be dropped")
+		}
+		if names, _ := registry.List(); strings.Contains(strings.Join(names, ","), "scratch") {
+			t.Errorf("Expected the database the script created to be removed, got %v", names)
+		}
+	})
+
+	t.Run("Statements that cannot be undone are refused", func(t *testing.T) {
+		_, err := eng.ExecuteScript(context.Background(), "INSERT INTO notes (body) VALUES ('x');\nDROP DATABASE shop;")
+		if err == nil || !strings.Contains(err.Error(), "cannot be undone") {
+			t.Fatalf("Expected DROP DATABASE to be refused, got %v", err)
+		}
+		if got := contents(t); got != before {
+			t.Error("Expected the insert before the refused statement to be undone")
+		}
+	})
+
+	t.Run("Failing multi-row INSERT inserts nothing", func(t *testing.T) {
+		for _, sql := range []string{
+			"INSERT INTO notes (id, body) VALUES (100, 'first'), (50, 'second')",
+			"INSERT INTO users (username, email) VALUES ('first', 'first@example.com'), ('second', 'first@example.com')",
+		} {
+			_, err := eng.Execute(sql)
+			if err == nil || !strings.Contains(err.Error(), "row 2") {
+				t.Errorf("%s: expected row 2 to fail, got %v", sql, err)
+			}
+		}
+		if got := contents(t); got != before {
+			t.Errorf("Expected the rows before the failing one to be removed\n--- before\n%s\n--- after\n%s", before, got)
+		}
+	})
+	t.Run("Load keeps the sequence", func(t *testing.T) {
+		mustExec(t, "USE shop")
+		mustExec(t, "ALTER TABLE notes AUTO_INCREMENT = 201")
+		if _, err := eng.Execute("ALTER TABLE notes AUTO_INCREMENT = 150"); err == nil || !strings.Contains(err.Error(), "back") {
+			t.Errorf("Expected moving the sequence back to be refused, got %v", err)
+		}
+		script := dumpOf(t, registry, "shop")
+		if !strings.Contains(script, "ALTER TABLE notes AUTO_INCREMENT = 201;") {
+			t.Fatalf("Expected the dump to set the sequence, got\n%s", script)
+		}
+
+		target := manager.NewRegistry(t.TempDir(), storageEngine.NewJSONEngine())
+		defer target.Close()
+		loaded := engine.New(nil, target)
+		if _, err := loaded.ExecuteScript(context.Background(), script); err != nil {
+			t.Fatalf("ExecuteScript failed: %v", err)
+		}
+		if _, err := loaded.Execute("INSERT INTO notes (body) VALUES ('next')"); err != nil {
+			t.Fatalf("INSERT failed: %v", err)
+		}
+		result, err := loaded.Execute("SELECT id FROM notes WHERE body = 'next'")
+		if err != nil || len(result.Rows) != 1 || fmt.Sprint(result.Rows[0].Data["id"]) != "201" {
+			t.Errorf("Expected the next key after the dumped sequence, 201, got %v (%v)", result, err)
+		}
+	})
+}
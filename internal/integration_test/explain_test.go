@@ -0,0 +1,214 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+)
+
+// actualPattern matches the EXPLAIN ANALYZE annotation of a plan line
+var actualPattern = regexp.MustCompile(`\(actual time=[0-9.]+ ms rows=(\d+) loops=(\d+)\)`)
+
+// TestExplain tests EXPLAIN, EXPLAIN (FORMAT JSON) and EXPLAIN ANALYZE
+func TestExplain(t *testing.T) {
+	customers := &schema.Table{
+		Name: "customers",
+		Schema: &schema.TableSchema{
+			TableName: "customers",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "name", Type: schema.ColumnTypeText},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	purchases := &schema.Table{
+		Name: "purchases",
+		Schema: &schema.TableSchema{
+			TableName: "purchases",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "customer_id", Type: schema.ColumnTypeInt},
+				{Name: "amount", Type: schema.ColumnTypeInt},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	for i := 1; i <= 10; i++ {
+		customers.Rows = append(customers.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "name": fmt.Sprintf("customer%02d", i),
+		}))
+	}
+	for i := 1; i <= 40; i++ {
+		purchases.Rows = append(purchases.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "customer_id": int64(i%12 + 1), "amount": int64(i * 5),
+		}))
+	}
+	for _, table := range []*schema.Table{customers, purchases} {
+		if err := indexing.BuildIndexes(table); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+	}
+	db := &schema.Database{
+		Name:   "explain",
+		Tables: map[string]*schema.Table{"customers": customers, "purchases": purchases},
+	}
+	eng := engine.New(db, nil)
+
+	// lines executes an EXPLAIN and returns its plan lines
+	lines := func(t *testing.T, sql string) []string {
+		t.Helper()
+		result, err := eng.Execute(sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(result.Columns) != 1 || result.Columns[0] != "QUERY PLAN" {
+			t.Fatalf("Expected a single QUERY PLAN column, got %v", result.Columns)
+		}
+		out := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			out[i] = row.Data["QUERY PLAN"].(string)
+		}
+		return out
+	}
+
+	const query = "SELECT customers.name, purchases.amount FROM customers JOIN purchases ON customers.id = purchases.customer_id WHERE purchases.amount > 50"
+	expected, err := eng.Execute(query)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	t.Run("EXPLAIN shows the plan without running it", func(t *testing.T) {
+		plan := lines(t, "EXPLAIN "+query)
+		if len(plan) != 4 {
+			t.Fatalf("Expected SELECT, JOIN and two scans, got:\n%s", strings.Join(plan, "\n"))
+		}
+		if !strings.HasPrefix(plan[0], "SELECT customers") || !strings.Contains(plan[0], "cost=") {
+			t.Errorf("Expected the SELECT with its cost first, got %q", plan[0])
+		}
+		if !strings.HasPrefix(plan[1], "  -> INNER JOIN (") || !strings.Contains(plan[1], "ON customers.id = purchases.customer_id") {
+			t.Errorf("Expected the join below the SELECT, got %q", plan[1])
+		}
+		if !strings.Contains(plan[3], "SCAN purchases WHERE (purchases.amount > 50)") {
+			t.Errorf("Expected the pushed down filter on the purchases scan, got %q", plan[3])
+		}
+		for _, line := range plan {
+			if strings.Contains(line, "actual") {
+				t.Errorf("EXPLAIN without ANALYZE reported actual rows: %q", line)
+			}
+		}
+	})
+
+	t.Run("EXPLAIN ANALYZE reports actual rows", func(t *testing.T) {
+		plan := lines(t, "EXPLAIN ANALYZE "+query)
+		if len(plan) != 5 || !strings.HasPrefix(plan[4], "Execution Time: ") {
+			t.Fatalf("Expected four nodes and the execution time, got:\n%s", strings.Join(plan, "\n"))
+		}
+		for _, line := range plan[:4] {
+			if !actualPattern.MatchString(line) {
+				t.Errorf("Expected actual rows, loops and time on %q", line)
+			}
+		}
+		m := actualPattern.FindStringSubmatch(plan[0])
+		if m == nil || m[1] != fmt.Sprint(len(expected.Rows)) || m[2] != "1" {
+			t.Errorf("Expected the SELECT to report %d rows in 1 loop, got %q", len(expected.Rows), plan[0])
+		}
+		m = actualPattern.FindStringSubmatch(plan[3])
+		if m == nil || m[1] != "30" {
+			t.Errorf("Expected the purchases scan to return the 30 purchases over 50, got %q", plan[3])
+		}
+	})
+
+	t.Run("EXPLAIN ANALYZE marks nodes that never ran", func(t *testing.T) {
+		plan := lines(t, "EXPLAIN ANALYZE "+query+" LIMIT 0")
+		if !strings.Contains(plan[0], "rows=0 loops=1") {
+			t.Errorf("Expected the SELECT to run and return nothing, got %q", plan[0])
+		}
+		if !strings.HasSuffix(plan[1], "(never executed)") {
+			t.Errorf("Expected the join to never run under LIMIT 0, got %q", plan[1])
+		}
+	})
+
+	t.Run("EXPLAIN (FORMAT JSON)", func(t *testing.T) {
+		plan := lines(t, "EXPLAIN (ANALYZE, FORMAT JSON) "+query)
+		if len(plan) != 1 {
+			t.Fatalf("Expected the JSON document in a single row, got %d rows", len(plan))
+		}
+		var doc struct {
+			Plan struct {
+				NodeType      string  `json:"node_type"`
+				EstimatedRows int64   `json:"estimated_rows"`
+				ActualRows    int64   `json:"actual_rows"`
+				ActualLoops   int64   `json:"actual_loops"`
+				ActualTime    float64 `json:"actual_time_ms"`
+				Plans         []struct {
+					NodeType  string           `json:"node_type"`
+					Algorithm string           `json:"algorithm"`
+					Plans     []map[string]any `json:"plans"`
+				} `json:"plans"`
+			} `json:"plan"`
+			ExecutionTime *float64 `json:"execution_time_ms"`
+		}
+		if err := json.Unmarshal([]byte(plan[0]), &doc); err != nil {
+			t.Fatalf("Invalid JSON plan: %v\n%s", err, plan[0])
+		}
+		if doc.Plan.NodeType != "SELECT" || doc.Plan.ActualRows != int64(len(expected.Rows)) || doc.Plan.ActualLoops != 1 {
+			t.Errorf("Unexpected root node: %+v", doc.Plan)
+		}
+		if len(doc.Plan.Plans) != 1 || doc.Plan.Plans[0].NodeType != "JOIN" || doc.Plan.Plans[0].Algorithm == "" {
+			t.Fatalf("Expected a JOIN below the SELECT, got %+v", doc.Plan.Plans)
+		}
+		if len(doc.Plan.Plans[0].Plans) != 2 || doc.Plan.Plans[0].Plans[0]["table"] != "customers" {
+			t.Errorf("Expected the two scans below the JOIN, got %v", doc.Plan.Plans[0].Plans)
+		}
+		if doc.ExecutionTime == nil {
+			t.Error("Expected the execution time")
+		}
+
+		plain := lines(t, "EXPLAIN (FORMAT JSON) SELECT * FROM customers WHERE id = 3")
+		if strings.Contains(plain[0], "actual") || strings.Contains(plain[0], "execution_time_ms") {
+			t.Errorf("EXPLAIN without ANALYZE reported actual rows:\n%s", plain[0])
+		}
+		if !strings.Contains(plain[0], `"node_type": "INDEX_SCAN"`) {
+			t.Errorf("Expected the index scan in the plan:\n%s", plain[0])
+		}
+	})
+
+	t.Run("EXPLAIN ANALYZE runs statements", func(t *testing.T) {
+		if _, err := eng.Execute("EXPLAIN DELETE FROM purchases WHERE amount <= 25"); err != nil {
+			t.Fatalf("EXPLAIN DELETE failed: %v", err)
+		}
+		if result, err := eng.Execute("SELECT id FROM purchases"); err != nil || len(result.Rows) != 40 {
+			t.Fatalf("Expected EXPLAIN without ANALYZE to delete nothing (%v)", err)
+		}
+
+		plan := lines(t, "EXPLAIN ANALYZE DELETE FROM purchases WHERE amount <= 25")
+		if !strings.HasPrefix(plan[0], "DELETE purchases") || !strings.Contains(plan[0], "rows=5 loops=1") {
+			t.Errorf("Expected the DELETE to report 5 rows, got %q", plan[0])
+		}
+		result, err := eng.Execute("SELECT id FROM purchases")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Rows) != 35 {
+			t.Errorf("Expected EXPLAIN ANALYZE to delete 5 rows, %d left", len(result.Rows))
+		}
+	})
+
+	t.Run("EXPLAIN of unsupported statements", func(t *testing.T) {
+		if _, err := eng.Execute("EXPLAIN SELECT * FROM missing"); err == nil {
+			t.Error("Expected a planning error for a missing table")
+		}
+		if _, err := eng.Execute("EXPLAIN ANALYZE ANALYZE"); err == nil {
+			t.Error("Expected a parse error for EXPLAIN ANALYZE ANALYZE")
+		}
+	})
+
+}
@@ -0,0 +1,272 @@
+package integration
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestIndexScanSelection tests that WHERE clauses answerable by an index
+// are planned as IndexScan and return the same rows as a sequential scan
+func TestIndexScanSelection(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	registry := manager.NewRegistry(filepath.Dir(db.Path), storageEngine.NewJSONEngine())
+	eng := engine.New(db, registry)
+
+	for _, sql := range []string{
+		"INSERT INTO users (username, email, is_active) VALUES ('carol', 'carol@example.com', true);",
+		"INSERT INTO users (username, email, is_active) VALUES ('bob', 'bob@example.com', true);",
+		"INSERT INTO users (username, email, is_active) VALUES ('dave', 'dave@example.com', false);",
+		"CREATE INDEX idx_users_id ON users (id) USING BTREE;",
+		"CREATE INDEX idx_users_username ON users (username) USING HASH;",
+	} {
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("Setup failed for %q: %v", sql, err)
+		}
+	}
+
+	// Filler rows (ids 6..45) so index lookups are cheaper than a full scan
+	for i := 6; i <= 45; i++ {
+		sql := fmt.Sprintf("INSERT INTO users (username, email) VALUES ('user%02d', 'user%02d@example.com');", i, i)
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("Setup failed for %q: %v", sql, err)
+		}
+	}
+
+	// planFor returns the scan node chosen for a single-table SELECT
+	planFor := func(t *testing.T, sql string) (*plan.SelectNode, *plan.IndexScanNode) {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		node, err := planner.Plan(stmt, db, tx)
+		if err != nil {
+			t.Fatalf("Planning error: %v", err)
+		}
+		selectNode := node.(*plan.SelectNode)
+		if len(selectNode.Children()) == 0 {
+			return selectNode, nil
+		}
+		indexScan, _ := selectNode.Children()[0].(*plan.IndexScanNode)
+		return selectNode, indexScan
+	}
+
+	tests := []struct {
+		name        string
+		sql         string
+		useIndex    bool
+		indexColumn string
+		expectedIDs []int64
+	}{
+		{"Primary key equality", "SELECT * FROM users WHERE id = 5", true, "id", []int64{5}},
+		{"Missing key", "SELECT * FROM users WHERE id = 99", true, "id", nil},
+		{"Unique text column", "SELECT * FROM users WHERE username = 'bob'", true, "username", []int64{4}},
+		{"IN list", "SELECT * FROM users WHERE id IN (1, 3, 99)", true, "id", []int64{1, 3}},
+		{"Range on BTREE", "SELECT * FROM users WHERE id > 1 AND id <= 4", true, "id", []int64{2, 3, 4}},
+		{"BETWEEN on BTREE", "SELECT * FROM users WHERE id BETWEEN 2 AND 3", true, "id", []int64{2, 3}},
+		{"Residual predicate", "SELECT * FROM users WHERE id >= 2 AND is_active = true", true, "id", []int64{3, 4}},
+		{"Equality preferred over range", "SELECT * FROM users WHERE id > 1 AND email = 'dave@example.com'", true, "email", []int64{5}},
+		{"Range on hash index", "SELECT * FROM users WHERE username > 'c' AND username < 'e'", false, "", []int64{3, 5}},
+		{"Unindexed column", "SELECT * FROM users WHERE is_active = false", false, "", []int64{2, 5}},
+		{"OR is not indexable", "SELECT * FROM users WHERE id = 1 OR id = 2", false, "", []int64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, indexScan := planFor(t, tt.sql)
+			if tt.useIndex {
+				if indexScan == nil {
+					t.Fatal("Expected IndexScan, got sequential scan")
+				}
+				if indexScan.IndexColumn != tt.indexColumn {
+					t.Errorf("Expected index on %s, got %s", tt.indexColumn, indexScan.IndexColumn)
+				}
+			} else if indexScan != nil {
+				t.Fatalf("Expected sequential scan, got IndexScan on %s", indexScan.IndexColumn)
+			}
+
+			result, err := eng.Execute(tt.sql)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if len(result.Rows) != len(tt.expectedIDs) {
+				t.Fatalf("Expected %d rows, got %d", len(tt.expectedIDs), len(result.Rows))
+			}
+
+			ids := make(map[int64]bool)
+			for _, row := range result.Rows {
+				id, _ := row.Data["id"].(int64)
+				ids[id] = true
+			}
+			for _, id := range tt.expectedIDs {
+				if !ids[id] {
+					t.Errorf("Expected row with id %d in result", id)
+				}
+			}
+			if len(result.Columns) != 4 {
+				t.Errorf("Expected 4 columns for SELECT *, got %d", len(result.Columns))
+			}
+		})
+	}
+
+	// ids returns the ids of result rows in order
+	ids := func(rows []data.Row) []int64 {
+		out := make([]int64, len(rows))
+		for i, row := range rows {
+			out[i], _ = row.Data["id"].(int64)
+		}
+		return out
+	}
+
+	t.Run("ORDER BY reads the index in order", func(t *testing.T) {
+		orderTests := []struct {
+			name        string
+			sql         string
+			indexColumn string
+			descending  bool
+			expectedIDs []int64
+		}{
+			{"Primary key", "SELECT id FROM users ORDER BY id LIMIT 3", "id", false, []int64{1, 2, 3}},
+			{"Primary key descending", "SELECT id FROM users ORDER BY users.id DESC LIMIT 3", "id", true, []int64{45, 44, 43}},
+			{"Range on the ORDER BY column", "SELECT id FROM users WHERE id BETWEEN 2 AND 4 ORDER BY id DESC", "id", true, []int64{4, 3, 2}},
+			{"Residual predicate", "SELECT id FROM users WHERE is_active = true ORDER BY id DESC", "id", true, []int64{4, 3, 1}},
+			{"Implicit unique index", "SELECT id FROM users WHERE email >= 'user4' ORDER BY email DESC LIMIT 2", "email", true, []int64{45, 44}},
+		}
+		for _, tt := range orderTests {
+			t.Run(tt.name, func(t *testing.T) {
+				selectNode, indexScan := planFor(t, tt.sql)
+				if indexScan == nil || !indexScan.Ordered {
+					t.Fatalf("Expected an ordered IndexScan, got %v", indexScan)
+				}
+				if indexScan.IndexColumn != tt.indexColumn || indexScan.Descending != tt.descending {
+					t.Errorf("Expected ordered scan of %s (descending %v), got %s (descending %v)",
+						tt.indexColumn, tt.descending, indexScan.IndexColumn, indexScan.Descending)
+				}
+				if len(selectNode.OrderBy) != 0 {
+					t.Errorf("Expected the sort to be dropped, got %v", selectNode.OrderBy)
+				}
+
+				result, err := eng.Execute(tt.sql)
+				if err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+				if got := ids(result.Rows); fmt.Sprint(got) != fmt.Sprint(tt.expectedIDs) {
+					t.Errorf("Expected ids %v, got %v", tt.expectedIDs, got)
+				}
+			})
+		}
+
+		sortTests := []struct {
+			name string
+			sql  string
+		}{
+			{"Nullable column", "SELECT id FROM users ORDER BY is_active"},
+			{"Hash index", "SELECT id FROM users ORDER BY username"},
+			{"Lookup on another column", "SELECT id FROM users WHERE email = 'bob@example.com' ORDER BY id"},
+			{"Several keys", "SELECT id FROM users ORDER BY id, username"},
+		}
+		for _, tt := range sortTests {
+			t.Run(tt.name, func(t *testing.T) {
+				selectNode, indexScan := planFor(t, tt.sql)
+				if indexScan != nil && indexScan.Ordered {
+					t.Fatalf("Expected a sort, got an ordered IndexScan on %s", indexScan.IndexColumn)
+				}
+				if len(selectNode.OrderBy) == 0 {
+					t.Error("Expected the sort to be kept")
+				}
+			})
+		}
+	})
+
+	t.Run("MIN and MAX read the index", func(t *testing.T) {
+		sql := "SELECT MIN(id), MAX(id), MAX(email) FROM users"
+		selectNode, _ := planFor(t, sql)
+		if !selectNode.IndexAggregates {
+			t.Fatal("Expected MIN/MAX to be read from the indexes")
+		}
+		result, err := eng.Execute(sql)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(result.Rows) != 1 {
+			t.Fatalf("Expected one row, got %d", len(result.Rows))
+		}
+		row := result.Rows[0].Data
+		got := fmt.Sprintf("%v %v %v", row[result.Columns[0]], row[result.Columns[1]], row[result.Columns[2]])
+		if got != "1 45 user45@example.com" {
+			t.Errorf("Expected 1 45 user45@example.com, got %s", got)
+		}
+
+		for _, sql := range []string{
+			"SELECT MIN(id) FROM users WHERE is_active = true",
+			"SELECT MIN(id), COUNT(*) FROM users",
+			"SELECT MAX(username) FROM users",
+			"SELECT MIN(is_active) FROM users",
+		} {
+			if selectNode, _ := planFor(t, sql); selectNode.IndexAggregates {
+				t.Errorf("%s: expected the rows to be aggregated", sql)
+			}
+		}
+	})
+
+	t.Run("Indexes disabled falls back to sequential scan", func(t *testing.T) {
+		selectNode, indexScan := planFor(t, "SELECT * FROM users WHERE id BETWEEN 2 AND 4 AND is_active = true")
+		if indexScan == nil {
+			t.Fatal("Expected IndexScan")
+		}
+
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.UseIndexes = false
+		strategy := &executor.DefaultStrategy{}
+		result, err := strategy.Execute(selectNode, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(result.Rows) != 2 {
+			t.Errorf("Expected 2 rows, got %d", len(result.Rows))
+		}
+
+		// An ordered scan sorts its rows instead, and MIN/MAX aggregate them
+		selectNode, _ = planFor(t, "SELECT id FROM users WHERE is_active = true ORDER BY id DESC")
+		result, err = strategy.Execute(selectNode, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if got := ids(result.Rows); fmt.Sprint(got) != "[4 3 1]" {
+			t.Errorf("Expected ids [4 3 1], got %v", got)
+		}
+
+		selectNode, _ = planFor(t, "SELECT MIN(id), MAX(id) FROM users")
+		result, err = strategy.Execute(selectNode, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		row := result.Rows[0].Data
+		if got := fmt.Sprint(row[selectNode.Aggregates[0].Name], row[selectNode.Aggregates[1].Name]); got != "1 45" {
+			t.Errorf("Expected 1 45, got %s", got)
+		}
+	})
+}
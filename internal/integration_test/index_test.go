@@ -0,0 +1,151 @@
+package integration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/loader"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// TestCreateBTreeIndex tests CREATE INDEX ... USING BTREE end-to-end,
+// including range scans, ordered scans, MIN/MAX and persistence
+func TestCreateBTreeIndex(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	registry := manager.NewRegistry(filepath.Dir(db.Path), storageEngine.NewJSONEngine())
+	eng := engine.New(db, registry)
+
+	for _, sql := range []string{
+		"INSERT INTO users (username, email, is_active) VALUES ('carol', 'carol@example.com', true);",
+		"INSERT INTO users (username, email, is_active) VALUES ('bob', 'bob@example.com', true);",
+		"INSERT INTO users (username, email, is_active) VALUES ('dave', 'dave@example.com', false);",
+	} {
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	users := db.Tables["users"]
+
+	t.Run("CREATE INDEX USING BTREE", func(t *testing.T) {
+		result, err := eng.Execute("CREATE INDEX idx_users_id ON users (id) USING BTREE;")
+		if err != nil {
+			t.Fatalf("CREATE INDEX failed: %v", err)
+		}
+		if result.Message != "CREATE INDEX" {
+			t.Errorf("Expected 'CREATE INDEX', got '%s'", result.Message)
+		}
+
+		if users.Indexes["id"].Kind() != index.KindBTree {
+			t.Errorf("Expected BTREE index on id, got %s", users.Indexes["id"].Kind())
+		}
+		if !users.Indexes["id"].Unique {
+			t.Error("Expected primary key index to stay unique")
+		}
+	})
+
+	t.Run("Range scan", func(t *testing.T) {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		rows, ok := users.SelectRange("id",
+			&index.Bound{Value: 2, Inclusive: true},
+			&index.Bound{Value: 4, Inclusive: false}, false, tx)
+		if !ok {
+			t.Fatal("Expected ordered index on id")
+		}
+		if len(rows) != 2 {
+			t.Fatalf("Expected 2 rows with 2 <= id < 4, got %d", len(rows))
+		}
+		if rows[0].Data["username"] != "guest" || rows[1].Data["username"] != "carol" {
+			t.Errorf("Unexpected range result: %v, %v", rows[0].Data, rows[1].Data)
+		}
+	})
+
+	t.Run("Ordered scan on secondary index", func(t *testing.T) {
+		if _, err := eng.Execute("CREATE INDEX idx_users_username ON users (username) USING BTREE"); err != nil {
+			t.Fatalf("CREATE INDEX failed: %v", err)
+		}
+
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		rows, ok := users.SelectRange("username", nil, nil, false, tx)
+		if !ok {
+			t.Fatal("Expected ordered index on username")
+		}
+		expected := []string{"admin", "bob", "carol", "dave", "guest"}
+		for i, name := range expected {
+			if rows[i].Data["username"] != name {
+				t.Errorf("Row %d: expected %s, got %v", i, name, rows[i].Data["username"])
+			}
+		}
+	})
+
+	t.Run("MIN/MAX lookup", func(t *testing.T) {
+		min, max, empty, ok := users.MinMax("id")
+		if !ok || empty {
+			t.Fatal("Expected MIN/MAX from ordered index")
+		}
+		if min != int64(1) || max != int64(5) {
+			t.Errorf("Expected min 1 and max 5, got %v and %v", min, max)
+		}
+	})
+
+	t.Run("Index maintained after INSERT and DELETE", func(t *testing.T) {
+		if _, err := eng.Execute("INSERT INTO users (username, email) VALUES ('eve', 'eve@example.com');"); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		if _, err := eng.Execute("DELETE FROM users WHERE username = 'admin';"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		min, max, _, _ := users.MinMax("id")
+		if min != int64(2) || max != int64(6) {
+			t.Errorf("Expected min 2 and max 6, got %v and %v", min, max)
+		}
+
+		if _, err := eng.Execute("INSERT INTO users (username, email) VALUES ('eve', 'eve2@example.com');"); err == nil {
+			t.Error("Expected unique violation on username")
+		}
+	})
+
+	t.Run("Duplicate index name rejected", func(t *testing.T) {
+		if _, err := eng.Execute("CREATE INDEX idx_users_id ON users (email)"); err == nil {
+			t.Error("Expected error for duplicate index name")
+		}
+	})
+
+	t.Run("Index definitions persisted", func(t *testing.T) {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		if err := writer.SaveTable(users, tx); err != nil {
+			t.Fatalf("Failed to save table: %v", err)
+		}
+
+		reloaded, err := loader.LoadTable(users.Path)
+		if err != nil {
+			t.Fatalf("Failed to reload table: %v", err)
+		}
+		if len(reloaded.Schema.Indexes) != 2 {
+			t.Fatalf("Expected 2 index definitions, got %d", len(reloaded.Schema.Indexes))
+		}
+		if err := indexing.BuildIndexes(reloaded); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+		if _, ok := reloaded.OrderedIndex("username"); !ok {
+			t.Error("Expected BTREE index on username after reload")
+		}
+		if reloaded.Indexes["email"].Kind() != index.KindBTree {
+			t.Errorf("Expected implicit BTREE index on email, got %s", reloaded.Indexes["email"].Kind())
+		}
+	})
+}
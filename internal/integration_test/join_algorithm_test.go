@@ -0,0 +1,251 @@
+package integration
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/operations/testutil"
+)
+
+// TestJoinAlgorithms tests that the planner picks hash, merge and index
+// nested-loop joins from table sizes and indexes, and that every algorithm
+// returns the same rows for all JOIN types
+func TestJoinAlgorithms(t *testing.T) {
+	// 50 orders spread over users 1, 2 and a user that does not exist (4);
+	// every order except the last ten has one shipment, plus one orphan shipment
+	var orderRows, shipmentRows []map[string]interface{}
+	for i := 1; i <= 50; i++ {
+		userID := interface{}(int64(i%3 + 1))
+		if i%3 == 2 {
+			userID = int64(4)
+		}
+		if i == 50 {
+			userID = nil
+		}
+		orderRows = append(orderRows, map[string]interface{}{
+			"id": int64(i), "user_id": userID, "product": fmt.Sprintf("product%02d", i),
+		})
+	}
+	for i := 1; i <= 41; i++ {
+		orderID := int64(i)
+		if i == 41 {
+			orderID = 99
+		}
+		shipmentRows = append(shipmentRows, map[string]interface{}{
+			"id": int64(i), "order_id": orderID, "carrier": fmt.Sprintf("carrier%d", i%4),
+		})
+	}
+	orders := newTable(t, "orders", []schema.Column{
+		{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+		{Name: "user_id", Type: schema.ColumnTypeInt},
+		{Name: "product", Type: schema.ColumnTypeText},
+	}, orderRows...)
+	shipments := newTable(t, "shipments", []schema.Column{
+		{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+		{Name: "order_id", Type: schema.ColumnTypeInt},
+		{Name: "carrier", Type: schema.ColumnTypeText},
+	}, shipmentRows...)
+
+	db := &schema.Database{
+		Name: "joins",
+		Tables: map[string]*schema.Table{
+			"users":     testutil.CreateUsersTable(),
+			"orders":    orders,
+			"shipments": shipments,
+		},
+	}
+
+	// topJoin returns the outermost JOIN of a SELECT plan
+	topJoin := func(t *testing.T, node plan.Node) *plan.JoinNode {
+		t.Helper()
+		joinNode, ok := node.Children()[0].(*plan.JoinNode)
+		if !ok {
+			t.Fatalf("Expected JoinNode under SELECT, got %T", node.Children()[0])
+		}
+		return joinNode
+	}
+
+	// run executes a plan, optionally forcing the join algorithm, and returns
+	// its rows as sorted strings for comparison
+	run := func(t *testing.T, node plan.Node, algorithm string) []string {
+		t.Helper()
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.JoinAlgorithm = algorithm
+		strategy := &executor.DefaultStrategy{}
+		result, err := strategy.Execute(node, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+
+		rows := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			keys := make([]string, 0, len(row.Data))
+			for k := range row.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			s := ""
+			for _, k := range keys {
+				s += fmt.Sprintf("%s=%v ", k, row.Data[k])
+			}
+			rows[i] = s
+		}
+		sort.Strings(rows)
+		return rows
+	}
+
+	t.Run("Every algorithm returns the same rows", func(t *testing.T) {
+		queries := []struct {
+			sql          string
+			expectedRows int
+		}{
+			{"SELECT * FROM users INNER JOIN orders ON users.id = orders.user_id", 33},
+			{"SELECT * FROM users LEFT JOIN orders ON users.id = orders.user_id", 34},
+			{"SELECT * FROM users RIGHT JOIN orders ON users.id = orders.user_id", 50},
+			{"SELECT * FROM users FULL JOIN orders ON users.id = orders.user_id", 51},
+			{"SELECT * FROM orders INNER JOIN shipments ON orders.id = shipments.order_id", 40},
+			{"SELECT * FROM orders FULL JOIN shipments ON orders.id = shipments.order_id", 51},
+		}
+
+		for _, q := range queries {
+			node, err := planSQL(db, q.sql)
+			if err != nil {
+				t.Fatalf("Planning %q failed: %v", q.sql, err)
+			}
+
+			expected := run(t, node, string(join.AlgorithmHash))
+			if len(expected) != q.expectedRows {
+				t.Errorf("%s: expected %d rows, got %d", q.sql, q.expectedRows, len(expected))
+			}
+
+			for _, algorithm := range []join.Algorithm{join.AlgorithmMerge, join.AlgorithmIndexNestedLoop, ""} {
+				got := run(t, node, string(algorithm))
+				if fmt.Sprint(got) != fmt.Sprint(expected) {
+					t.Errorf("%s: %q join returned different rows than hash join", q.sql, algorithm)
+				}
+			}
+		}
+	})
+
+	t.Run("Planner choice", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			setup     []schema.IndexDefinition
+			sql       string
+			algorithm join.Algorithm
+			inner     join.Side
+		}{
+			{
+				name:      "Hash join builds on the smaller input",
+				sql:       "SELECT * FROM users JOIN orders ON users.username = orders.product",
+				algorithm: join.AlgorithmHash,
+				inner:     join.SideLeft,
+			},
+			{
+				name:      "Index nested-loop probes primary key",
+				sql:       "SELECT * FROM users JOIN orders ON users.id = orders.user_id",
+				algorithm: join.AlgorithmIndexNestedLoop,
+				inner:     join.SideLeft,
+			},
+			{
+				name:      "Index nested-loop probes the large input",
+				setup:     []schema.IndexDefinition{{Name: "idx_orders_user", Column: "user_id", Kind: index.KindBTree}},
+				sql:       "SELECT * FROM users JOIN orders ON users.id = orders.user_id",
+				algorithm: join.AlgorithmIndexNestedLoop,
+				inner:     join.SideRight,
+			},
+			{
+				name: "Merge join over ordered indexes",
+				setup: []schema.IndexDefinition{
+					{Name: "idx_orders_id", Column: "id", Kind: index.KindBTree, Unique: true},
+					{Name: "idx_shipments_order", Column: "order_id", Kind: index.KindBTree},
+				},
+				sql:       "SELECT * FROM orders JOIN shipments ON orders.id = shipments.order_id",
+				algorithm: join.AlgorithmMerge,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for _, def := range tt.setup {
+					table := db.Tables["orders"]
+					if def.Column == "order_id" {
+						table = db.Tables["shipments"]
+					}
+					if err := indexing.CreateIndex(table, def); err != nil {
+						t.Fatalf("CREATE INDEX failed: %v", err)
+					}
+				}
+
+				node, err := planSQL(db, tt.sql)
+				if err != nil {
+					t.Fatalf("Planning failed: %v", err)
+				}
+				joinNode := topJoin(t, node)
+				if joinNode.Strategy.Algorithm != tt.algorithm {
+					t.Fatalf("Expected %s join, got %s", tt.algorithm, joinNode.Strategy.Algorithm)
+				}
+				if tt.algorithm != join.AlgorithmMerge && joinNode.Strategy.Inner != tt.inner {
+					t.Errorf("Expected inner side %s, got %s", tt.inner, joinNode.Strategy.Inner)
+				}
+				if joinNode.Metadata()["algorithm"] != string(tt.algorithm) {
+					t.Errorf("Expected algorithm metadata %s, got %v", tt.algorithm, joinNode.Metadata()["algorithm"])
+				}
+			})
+		}
+	})
+
+	t.Run("ON condition written right to left", func(t *testing.T) {
+		node, err := planSQL(db, "SELECT * FROM users JOIN orders ON orders.user_id = users.id")
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		joinNode := topJoin(t, node)
+		if joinNode.LeftOnCol != "id" || joinNode.RightOnCol != "user_id" {
+			t.Errorf("Expected id = user_id, got %s = %s", joinNode.LeftOnCol, joinNode.RightOnCol)
+		}
+		if rows := run(t, node, ""); len(rows) != 33 {
+			t.Errorf("Expected 33 rows, got %d", len(rows))
+		}
+	})
+
+	t.Run("Three-way join qualifies left columns", func(t *testing.T) {
+		node, err := planSQL(db, "SELECT * FROM users JOIN orders ON users.id = orders.user_id JOIN shipments ON shipments.order_id = orders.id")
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		joinNode := topJoin(t, node)
+		if joinNode.LeftOnCol != "orders.id" || joinNode.RightOnCol != "order_id" {
+			t.Errorf("Expected orders.id = order_id, got %s = %s", joinNode.LeftOnCol, joinNode.RightOnCol)
+		}
+
+		// Orders 1..40 have shipments; 27 of them belong to users 1 and 2
+		rows := run(t, node, "")
+		if len(rows) != 27 {
+			t.Errorf("Expected 27 rows, got %d", len(rows))
+		}
+		for _, algorithm := range []join.Algorithm{join.AlgorithmHash, join.AlgorithmMerge, join.AlgorithmIndexNestedLoop} {
+			if got := run(t, node, string(algorithm)); fmt.Sprint(got) != fmt.Sprint(rows) {
+				t.Errorf("%s join returned different rows", algorithm)
+			}
+		}
+	})
+
+	t.Run("Ambiguous column in multi-way join", func(t *testing.T) {
+		_, err := planSQL(db, "SELECT * FROM users JOIN orders ON users.id = orders.user_id JOIN shipments ON id = order_id")
+		if err == nil {
+			t.Error("Expected ambiguous column error")
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package integration
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+)
+
+// TestJoinConditions tests JOIN ON conditions beyond a single equality:
+// composite keys, extra filters and non-equi conditions, for all join types
+func TestJoinConditions(t *testing.T) {
+	idCol := schema.Column{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true}
+	text := func(name string) schema.Column { return schema.Column{Name: name, Type: schema.ColumnTypeText} }
+	integer := func(name string) schema.Column { return schema.Column{Name: name, Type: schema.ColumnTypeInt} }
+
+	db := &schema.Database{Name: "conditions", Tables: map[string]*schema.Table{
+		// Sales 4 and 5 have no price for their region, sale 6 has no region
+		"sales": newTable(t, "sales", []schema.Column{idCol, text("product"), text("region"), integer("ts")},
+			map[string]interface{}{"id": int64(1), "product": "apple", "region": "north", "ts": int64(5)},
+			map[string]interface{}{"id": int64(2), "product": "apple", "region": "south", "ts": int64(15)},
+			map[string]interface{}{"id": int64(3), "product": "pear", "region": "north", "ts": int64(25)},
+			map[string]interface{}{"id": int64(4), "product": "pear", "region": "east", "ts": int64(35)},
+			map[string]interface{}{"id": int64(5), "product": "plum", "region": "north", "ts": int64(45)},
+			map[string]interface{}{"id": int64(6), "product": "apple", "region": nil, "ts": int64(55)},
+		),
+		// The apple price for the south is inactive; nobody buys kiwis
+		"prices": newTable(t, "prices", []schema.Column{idCol, text("product"), text("region"), {Name: "active", Type: schema.ColumnTypeBool}},
+			map[string]interface{}{"id": int64(1), "product": "apple", "region": "north", "active": true},
+			map[string]interface{}{"id": int64(2), "product": "apple", "region": "south", "active": false},
+			map[string]interface{}{"id": int64(3), "product": "pear", "region": "north", "active": true},
+			map[string]interface{}{"id": int64(4), "product": "kiwi", "region": "west", "active": true},
+		),
+		// Overlapping periods; nothing sold in the late one
+		"periods": newTable(t, "periods", []schema.Column{idCol, text("name"), integer("start_ts"), integer("end_ts")},
+			map[string]interface{}{"id": int64(1), "name": "early", "start_ts": int64(0), "end_ts": int64(20)},
+			map[string]interface{}{"id": int64(2), "name": "mid", "start_ts": int64(10), "end_ts": int64(30)},
+			map[string]interface{}{"id": int64(3), "name": "late", "start_ts": int64(100), "end_ts": int64(200)},
+		),
+	}}
+
+	// run executes a plan, optionally forcing the join algorithm, and returns
+	// its rows as sorted strings for comparison
+	run := func(t *testing.T, node plan.Node, algorithm join.Algorithm) []string {
+		t.Helper()
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.JoinAlgorithm = string(algorithm)
+		result, err := (&executor.DefaultStrategy{}).Execute(node, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+
+		rows := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			keys := make([]string, 0, len(row.Data))
+			for k := range row.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			s := ""
+			for _, k := range keys {
+				s += fmt.Sprintf("%s=%v ", k, row.Data[k])
+			}
+			rows[i] = s
+		}
+		sort.Strings(rows)
+		return rows
+	}
+
+	composite := "sales.product = prices.product AND sales.region = prices.region"
+	filtered := composite + " AND prices.active = true"
+	between := "sales.ts BETWEEN periods.start_ts AND periods.end_ts"
+
+	tests := []struct {
+		name string
+		from string
+		on   string
+		rows map[string]int // expected rows per join type
+	}{
+		{
+			name: "Composite key",
+			from: "sales %s JOIN prices",
+			on:   composite,
+			rows: map[string]int{"INNER": 3, "LEFT": 6, "RIGHT": 4, "FULL": 7},
+		},
+		{
+			// The inactive south price must not drop sale 2 from a LEFT JOIN
+			name: "Composite key with filter",
+			from: "sales %s JOIN prices",
+			on:   filtered,
+			rows: map[string]int{"INNER": 2, "LEFT": 6, "RIGHT": 4, "FULL": 8},
+		},
+		{
+			name: "BETWEEN",
+			from: "sales %s JOIN periods",
+			on:   between,
+			rows: map[string]int{"INNER": 4, "LEFT": 7, "RIGHT": 5, "FULL": 8},
+		},
+		{
+			name: "Range with filter",
+			from: "sales %s JOIN periods",
+			on:   "periods.start_ts <= sales.ts AND sales.ts < periods.end_ts AND periods.name != 'late'",
+			rows: map[string]int{"INNER": 4, "LEFT": 7, "RIGHT": 5, "FULL": 8},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, joinType := range []string{"INNER", "LEFT", "RIGHT", "FULL"} {
+			t.Run(tt.name+"/"+joinType, func(t *testing.T) {
+				sql := fmt.Sprintf("SELECT * FROM "+tt.from+" ON %s", joinType, tt.on)
+				node := planQuery(t, db, sql)
+
+				expected := run(t, node, "")
+				if len(expected) != tt.rows[joinType] {
+					t.Errorf("Expected %d rows, got %d", tt.rows[joinType], len(expected))
+				}
+
+				// Forcing another algorithm must not change the result
+				algorithms := []join.Algorithm{join.AlgorithmHash, join.AlgorithmMerge, join.AlgorithmIndexNestedLoop, join.AlgorithmNestedLoop}
+				for _, algorithm := range algorithms {
+					if got := run(t, node, algorithm); fmt.Sprint(got) != fmt.Sprint(expected) {
+						t.Errorf("%s join returned different rows", algorithm)
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("Plan for composite key with filter", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM sales LEFT JOIN prices ON "+filtered)
+		joinNode := node.Children()[0].(*plan.JoinNode)
+
+		if joinNode.Strategy.Algorithm == join.AlgorithmNestedLoop {
+			t.Error("Expected a keyed join algorithm, got nested loop")
+		}
+		keys := joinNode.Keys()
+		if len(keys) != 2 || keys[0] != (join.Key{Left: "product", Right: "product"}) || keys[1] != (join.Key{Left: "region", Right: "region"}) {
+			t.Errorf("Expected keys on product and region, got %v", keys)
+		}
+		// A filter on the null-extended side of a LEFT JOIN is applied
+		// while scanning it
+		if joinNode.On != nil {
+			t.Errorf("Expected no join filter, got %s", joinNode.On)
+		}
+		if scan, ok := joinNode.Right().(*plan.ScanNode); !ok || scan.Predicate == nil {
+			t.Errorf("Expected filtered scan of prices, got %T", joinNode.Right())
+		}
+	})
+
+	t.Run("Filter on the preserved side stays in the join", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM sales RIGHT JOIN prices ON "+filtered)
+		joinNode := node.Children()[0].(*plan.JoinNode)
+		if joinNode.On == nil {
+			t.Error("Expected prices.active to be evaluated by the join")
+		}
+		if scan, ok := joinNode.Right().(*plan.ScanNode); !ok || scan.Predicate != nil {
+			t.Error("Expected unfiltered scan of prices")
+		}
+	})
+
+	t.Run("Plan for non-equi join", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM sales JOIN periods ON "+between)
+		joinNode := node.Children()[0].(*plan.JoinNode)
+		if len(joinNode.Keys()) != 0 || joinNode.On == nil {
+			t.Errorf("Expected a keyless join with a filter, got keys %v", joinNode.Keys())
+		}
+		if joinNode.Strategy.Algorithm != join.AlgorithmNestedLoop {
+			t.Errorf("Expected nested-loop join, got %s", joinNode.Strategy.Algorithm)
+		}
+	})
+
+	t.Run("Non-equi condition in a multi-way join", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM sales JOIN prices ON sales.product = prices.product JOIN periods ON "+between)
+
+		// 8 sale/price pairs; the matching periods of their sales add up to 7
+		if rows := run(t, node, ""); len(rows) != 7 {
+			t.Errorf("Expected 7 rows, got %d", len(rows))
+		}
+	})
+
+	t.Run("Column comparison in WHERE", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM sales JOIN prices ON sales.product = prices.product WHERE sales.region = prices.region")
+		if node.Predicate == nil {
+			t.Error("Expected the cross-table WHERE to stay above the join")
+		}
+		if rows := run(t, node, ""); len(rows) != 3 {
+			t.Errorf("Expected 3 rows, got %d", len(rows))
+		}
+	})
+
+	t.Run("Unknown column in ON", func(t *testing.T) {
+		tokens, _ := lexer.Tokenize("SELECT * FROM sales JOIN prices ON sales.product = prices.product AND prices.missing = 1")
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		if _, err := planner.Plan(stmt, db, nil); err == nil {
+			t.Error("Expected an error for an unknown column")
+		}
+	})
+}
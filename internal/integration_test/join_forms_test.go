@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/plan"
+)
+
+// TestJoinForms tests CROSS JOIN, comma-separated tables, USING and NATURAL
+// joins, including how USING columns are merged in the output
+func TestJoinForms(t *testing.T) {
+	text := func(name string) schema.Column { return schema.Column{Name: name, Type: schema.ColumnTypeText} }
+	integer := func(name string) schema.Column { return schema.Column{Name: name, Type: schema.ColumnTypeInt} }
+
+	db := &schema.Database{Name: "forms", Tables: map[string]*schema.Table{
+		// Cy has written nothing
+		"authors": newTable(t, "authors", []schema.Column{integer("author_id"), text("name")},
+			map[string]interface{}{"author_id": int64(1), "name": "ann"},
+			map[string]interface{}{"author_id": int64(2), "name": "bob"},
+			map[string]interface{}{"author_id": int64(3), "name": "cy"},
+		),
+		// Book 13 belongs to an unknown author
+		"books": newTable(t, "books", []schema.Column{integer("book_id"), integer("author_id"), text("title")},
+			map[string]interface{}{"book_id": int64(10), "author_id": int64(1), "title": "alpha"},
+			map[string]interface{}{"book_id": int64(11), "author_id": int64(1), "title": "beta"},
+			map[string]interface{}{"book_id": int64(12), "author_id": int64(2), "title": "gamma"},
+			map[string]interface{}{"book_id": int64(13), "author_id": int64(4), "title": "delta"},
+		),
+		// Award 3 is for a book that does not exist
+		"awards": newTable(t, "awards", []schema.Column{integer("book_id"), integer("year")},
+			map[string]interface{}{"book_id": int64(10), "year": int64(2020)},
+			map[string]interface{}{"book_id": int64(12), "year": int64(2021)},
+			map[string]interface{}{"book_id": int64(99), "year": int64(2022)},
+		),
+	}}
+
+	run := func(t *testing.T, sql string) (*plan.SelectNode, *executor.Result) {
+		t.Helper()
+		node := planQuery(t, db, sql)
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		result, err := executor.Execute(node, db, tx)
+		if err != nil {
+			t.Fatalf("Execute %q failed: %v", sql, err)
+		}
+		return node, result
+	}
+
+	// values returns column col of every row, sorted
+	values := func(result *executor.Result, col string) []string {
+		var out []string
+		for _, row := range result.Rows {
+			out = append(out, fmt.Sprint(row.Data[col]))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	t.Run("CROSS JOIN", func(t *testing.T) {
+		for _, sql := range []string{
+			"SELECT * FROM authors CROSS JOIN awards",
+			"SELECT * FROM authors, awards",
+		} {
+			node, result := run(t, sql)
+			if len(result.Rows) != 9 {
+				t.Errorf("%s: expected 9 rows, got %d", sql, len(result.Rows))
+			}
+			joinNode := node.Children()[0].(*plan.JoinNode)
+			if joinNode.Keys() != nil {
+				t.Errorf("%s: expected no join keys, got %v", sql, joinNode.Keys())
+			}
+		}
+	})
+
+	t.Run("Implicit join condition in WHERE", func(t *testing.T) {
+		node, result := run(t, "SELECT * FROM authors, books WHERE authors.author_id = books.author_id AND books.book_id > 10")
+		if got := values(result, "books.title"); !reflect.DeepEqual(got, []string{"beta", "gamma"}) {
+			t.Errorf("Expected titles [beta gamma], got %v", got)
+		}
+		// The equality becomes the join key instead of filtering a cross product
+		joinNode := node.Children()[0].(*plan.JoinNode)
+		if len(joinNode.Keys()) != 1 {
+			t.Errorf("Expected the WHERE equality as join key, got keys %v", joinNode.Keys())
+		}
+		if node.Predicate != nil {
+			t.Errorf("Expected no residual WHERE predicate, got %v", node.Where)
+		}
+	})
+
+	t.Run("USING merges the join column", func(t *testing.T) {
+		_, result := run(t, "SELECT * FROM authors JOIN books USING (author_id)")
+		want := []string{"author_id", "authors.name", "books.book_id", "books.title"}
+		if !reflect.DeepEqual(result.Columns, want) {
+			t.Errorf("Expected columns %v, got %v", want, result.Columns)
+		}
+		if got := values(result, "author_id"); !reflect.DeepEqual(got, []string{"1", "1", "2"}) {
+			t.Errorf("Expected author_id [1 1 2], got %v", got)
+		}
+	})
+
+	t.Run("NATURAL JOIN", func(t *testing.T) {
+		_, using := run(t, "SELECT * FROM authors JOIN books USING (author_id)")
+		_, natural := run(t, "SELECT * FROM authors NATURAL JOIN books")
+		if !reflect.DeepEqual(natural.Columns, using.Columns) || len(natural.Rows) != len(using.Rows) {
+			t.Errorf("NATURAL JOIN differs from USING: %v (%d rows) vs %v (%d rows)",
+				natural.Columns, len(natural.Rows), using.Columns, len(using.Rows))
+		}
+
+		// Chained natural joins match each new table on all shared columns
+		_, chained := run(t, "SELECT * FROM authors NATURAL JOIN books NATURAL JOIN awards")
+		want := []string{"author_id", "book_id", "authors.name", "awards.year", "books.title"}
+		if !reflect.DeepEqual(chained.Columns, want) {
+			t.Errorf("Expected columns %v, got %v", want, chained.Columns)
+		}
+		if got := values(chained, "book_id"); !reflect.DeepEqual(got, []string{"10", "12"}) {
+			t.Errorf("Expected book_id [10 12], got %v", got)
+		}
+
+		// Without common columns a natural join is a cross join
+		_, cross := run(t, "SELECT * FROM authors NATURAL JOIN awards")
+		if len(cross.Rows) != 9 {
+			t.Errorf("Expected 9 rows, got %d", len(cross.Rows))
+		}
+	})
+
+	t.Run("Outer joins coalesce the USING column", func(t *testing.T) {
+		_, full := run(t, "SELECT * FROM authors FULL JOIN books USING (author_id)")
+		if got := values(full, "author_id"); !reflect.DeepEqual(got, []string{"1", "1", "2", "3", "4"}) {
+			t.Errorf("Expected author_id [1 1 2 3 4], got %v", got)
+		}
+
+		// WHERE sees the merged value: author 4 only exists in books
+		_, right := run(t, "SELECT author_id, title FROM authors RIGHT JOIN books USING (author_id) WHERE author_id = 4")
+		if len(right.Rows) != 1 || right.Rows[0].Data["title"] != "delta" {
+			t.Errorf("Expected the delta row, got %v", right.Rows)
+		}
+
+		// The source columns stay available to an explicit projection
+		_, left := run(t, "SELECT author_id, books.author_id FROM authors LEFT JOIN books USING (author_id) WHERE author_id = 3")
+		if len(left.Rows) != 1 || left.Rows[0].Data["author_id"] != int64(3) || left.Rows[0].Data["books.author_id"] != nil {
+			t.Errorf("Expected author 3 without books, got %v", left.Rows)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			sql     string
+			wantErr string
+		}{
+			{"SELECT * FROM authors JOIN books USING (title)", "column not found"},
+			{"SELECT * FROM authors JOIN books USING (author_id, author_id)", "more than once"},
+			{"SELECT * FROM authors, books JOIN awards USING (name)", "column not found"},
+			{"SELECT * FROM authors FULL JOIN books USING (author_id) JOIN awards USING (author_id)", "column not found"},
+		}
+		for _, tt := range tests {
+			_, err := planSQL(db, tt.sql)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.sql, tt.wantErr, err)
+			}
+		}
+	})
+}
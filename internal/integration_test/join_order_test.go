@@ -0,0 +1,161 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/statistics"
+)
+
+// TestJoinReordering tests that inner joins are reordered to keep
+// intermediate results small while outer joins keep their written position
+func TestJoinReordering(t *testing.T) {
+	// analyzedTable creates an analyzed table whose rows are produced by row(i)
+	analyzedTable := func(t *testing.T, name string, columns []schema.Column, n int, row func(i int) map[string]interface{}) *schema.Table {
+		t.Helper()
+		rows := make([]map[string]interface{}, 0, n)
+		for i := 1; i <= n; i++ {
+			rows = append(rows, row(i))
+		}
+		table := newTable(t, name, columns, rows...)
+		if _, err := statistics.Analyze(table); err != nil {
+			t.Fatalf("ANALYZE failed: %v", err)
+		}
+		return table
+	}
+
+	idCol := schema.Column{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true}
+	userCol := schema.Column{Name: "user_id", Type: schema.ColumnTypeInt}
+
+	// 10 users: 500 log lines and 100 orders each spread evenly, 2 VIPs
+	db := &schema.Database{Name: "reorder", Tables: map[string]*schema.Table{
+		"logs": analyzedTable(t, "logs", []schema.Column{idCol, userCol}, 500, func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": int64(i), "user_id": int64(i%10 + 1)}
+		}),
+		"orders": analyzedTable(t, "orders", []schema.Column{idCol, userCol}, 100, func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": int64(i), "user_id": int64(i%10 + 1)}
+		}),
+		"vip": analyzedTable(t, "vip", []schema.Column{idCol, {Name: "user_id", Type: schema.ColumnTypeInt, Unique: true}}, 2, func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": int64(i), "user_id": int64(i)}
+		}),
+	}}
+
+	execute := func(t *testing.T, node plan.Node) []data.Row {
+		t.Helper()
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		result, err := executor.Execute(node, db, tx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result.Rows
+	}
+
+	t.Run("Selective join runs first", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM logs JOIN orders ON logs.user_id = orders.user_id JOIN vip ON vip.user_id = orders.user_id")
+
+		order, _ := node.Metadata()["join_order"].([]string)
+		if len(order) != 3 || order[2] != "logs" {
+			t.Fatalf("Expected logs to be joined last, got order %v", order)
+		}
+
+		// 20 VIP orders x 50 log lines per user
+		rows := execute(t, node)
+		if len(rows) != 1000 {
+			t.Fatalf("Expected 1000 rows, got %d", len(rows))
+		}
+		for _, col := range []string{"logs.id", "orders.id", "vip.id"} {
+			if _, ok := rows[0].Data[col]; !ok {
+				t.Errorf("Expected column %s in joined row", col)
+			}
+		}
+	})
+
+	t.Run("Outer join is a barrier", func(t *testing.T) {
+		node := planQuery(t, db, "SELECT * FROM logs LEFT JOIN orders ON logs.user_id = orders.user_id JOIN vip ON vip.user_id = orders.user_id")
+
+		order, _ := node.Metadata()["join_order"].([]string)
+		if strings.Join(order, ",") != "logs,orders,vip" {
+			t.Fatalf("Expected written order logs,orders,vip, got %v", order)
+		}
+		top := node.Children()[0].(*plan.JoinNode)
+		if left, ok := top.Left().(*plan.JoinNode); !ok || left.JoinType != join.JoinTypeLeft {
+			t.Fatalf("Expected LEFT JOIN below the VIP join, got %T", top.Left())
+		}
+
+		if rows := execute(t, node); len(rows) != 1000 {
+			t.Errorf("Expected 1000 rows, got %d", len(rows))
+		}
+	})
+
+	t.Run("Inner joins before an outer join are reordered", func(t *testing.T) {
+		db.Tables["vip_notes"] = analyzedTable(t, "vip_notes", []schema.Column{idCol, {Name: "vip_id", Type: schema.ColumnTypeInt}}, 1, func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": int64(i), "vip_id": int64(i)}
+		})
+
+		node := planQuery(t, db, "SELECT * FROM logs JOIN orders ON logs.user_id = orders.user_id JOIN vip ON vip.user_id = orders.user_id LEFT JOIN vip_notes ON vip_notes.vip_id = vip.id")
+
+		order, _ := node.Metadata()["join_order"].([]string)
+		if len(order) != 4 || order[2] != "logs" || order[3] != "vip_notes" {
+			t.Fatalf("Expected logs joined last among inner joins and vip_notes after it, got %v", order)
+		}
+		top := node.Children()[0].(*plan.JoinNode)
+		if top.JoinType != join.JoinTypeLeft || top.LeftOnCol != "vip.id" {
+			t.Errorf("Expected LEFT JOIN on vip.id at the top, got %s on %s", top.JoinType, top.LeftOnCol)
+		}
+
+		rows := execute(t, node)
+		if len(rows) != 1000 {
+			t.Fatalf("Expected 1000 rows, got %d", len(rows))
+		}
+		notes := 0
+		for _, row := range rows {
+			if row.Data["vip_notes.id"] != nil {
+				notes++
+			}
+		}
+		if notes != 500 {
+			t.Errorf("Expected 500 rows with a VIP note, got %d", notes)
+		}
+	})
+
+	t.Run("Greedy ordering beyond the DP threshold", func(t *testing.T) {
+		// chain t0 -> t1 -> ... -> t9 where t5 holds a single row
+		var from strings.Builder
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("t%d", i)
+			n := 20
+			if i == 5 {
+				n = 1
+			}
+			db.Tables[name] = analyzedTable(t, name, []schema.Column{idCol, {Name: "next_id", Type: schema.ColumnTypeInt}}, n, func(i int) map[string]interface{} {
+				return map[string]interface{}{"id": int64(i), "next_id": int64(i)}
+			})
+			if i == 0 {
+				from.WriteString("SELECT * FROM t0")
+			} else {
+				fmt.Fprintf(&from, " JOIN t%d ON t%d.next_id = t%d.id", i, i-1, i)
+			}
+		}
+
+		node := planQuery(t, db, from.String())
+		order, _ := node.Metadata()["join_order"].([]string)
+		if len(order) != 10 {
+			t.Fatalf("Expected 10 joined tables, got %v", order)
+		}
+		if order[0] != "t5" && order[1] != "t5" {
+			t.Errorf("Expected the single-row table to start the join, got %v", order)
+		}
+
+		if rows := execute(t, node); len(rows) != 1 {
+			t.Errorf("Expected 1 row, got %d", len(rows))
+		}
+	})
+}
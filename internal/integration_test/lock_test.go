@@ -0,0 +1,110 @@
+//go:build unix
+
+package integration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestDatabaseLock tests that a database loaded by one process cannot be
+// loaded by another, except read-only
+func TestDatabaseLock(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	lockPath := filepath.Join(basePath, "shop", manager.LockFileName)
+
+	// flockOther takes the lock as another process would, through its own
+	// open file; false when it is held
+	flockOther := func(t *testing.T, pid string) (*os.File, bool) {
+		t.Helper()
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatalf("Failed to open lock file: %v", err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			return nil, false
+		}
+		f.Truncate(0)
+		f.WriteAt([]byte(pid+"\n"), 0)
+		return f, true
+	}
+
+	t.Run("Held by another process", func(t *testing.T) {
+		other, ok := flockOther(t, "4242")
+		if !ok {
+			t.Fatal("Expected the lock to be free")
+		}
+		defer other.Close()
+
+		registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+		_, err := registry.Get("shop")
+		if !errors.Is(err, manager.ErrLocked) || !strings.Contains(err.Error(), "process 4242") {
+			t.Errorf("Expected a lock error naming pid 4242, got %v", err)
+		}
+		if err := registry.Drop("shop"); !errors.Is(err, manager.ErrLocked) {
+			t.Errorf("Expected DROP to be refused, got %v", err)
+		}
+
+		// A read-only registry loads it without the lock and changes nothing
+		eng := engine.New(nil, manager.NewReadOnlyRegistry(basePath, storageEngine.NewJSONEngine()))
+		if _, err := eng.Execute("USE shop"); err != nil {
+			t.Fatalf("Expected a read-only USE to succeed, got %v", err)
+		}
+		if result, err := eng.Execute("SELECT * FROM users"); err != nil || len(result.Rows) != 2 {
+			t.Errorf("Expected to read 2 users, got %v", err)
+		}
+		for _, sql := range []string{
+			"INSERT INTO users (username, email) VALUES ('x', 'x@example.com')",
+			"DELETE FROM users",
+			"EXPLAIN ANALYZE UPDATE users SET username = 'y'",
+			"CREATE DATABASE other",
+			"DROP DATABASE shop",
+		} {
+			if _, err := eng.Execute(sql); !errors.Is(err, manager.ErrReadOnly) {
+				t.Errorf("Expected %q to be refused read-only, got %v", sql, err)
+			}
+		}
+	})
+
+	t.Run("Held by this process", func(t *testing.T) {
+		registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+		if _, err := registry.Get("shop"); err != nil {
+			t.Fatalf("Expected the free lock to be taken, got %v", err)
+		}
+		content, _ := os.ReadFile(lockPath)
+		if strings.TrimSpace(string(content)) != strconv.Itoa(os.Getpid()) {
+			t.Errorf("Expected the lock file to hold our pid, got %q", content)
+		}
+
+		// Registries of one process share the lock
+		second := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+		if _, err := second.Get("shop"); err != nil {
+			t.Errorf("Expected a second registry of this process to load it, got %v", err)
+		}
+		second.Close()
+		if _, ok := flockOther(t, "4242"); ok {
+			t.Fatal("Expected the lock to stay held by the first registry")
+		}
+
+		registry.Close()
+		other, ok := flockOther(t, "4242")
+		if !ok {
+			t.Fatal("Expected Close to release the lock")
+		}
+		other.Close()
+	})
+}
@@ -0,0 +1,27 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/executor"
+)
+
+// TestMain runs the integration tests once for every execution strategy, so
+// that all strategies are held to the same results
+// Tests that build their own ExecutionConfig or Engine get the strategy
+// through DefaultExecutionConfig.
+func TestMain(m *testing.M) {
+	for _, name := range executor.StrategyNames() {
+		if err := executor.SetDefaultStrategy(name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("=== execution strategy: %s\n", name)
+		if code := m.Run(); code != 0 {
+			os.Exit(code)
+		}
+	}
+	os.Exit(0)
+}
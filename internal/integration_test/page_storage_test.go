@@ -0,0 +1,229 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+)
+
+// TestPageStorage tests converting a database to page storage and that its
+// rows survive a restart with their types
+func TestPageStorage(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+
+	// openWith returns an engine on a fresh registry, as after a restart,
+	// caching bufferPages pages of page storage
+	openWith := func(t *testing.T, bufferPages int) (*engine.Engine, *manager.Registry) {
+		t.Helper()
+		storageEng, err := storageEngine.NewFormatEngine(storageEngine.StorageJSON, bufferPages)
+		if err != nil {
+			t.Fatalf("NewFormatEngine failed: %v", err)
+		}
+		registry := manager.NewRegistry(basePath, storageEng)
+		return engine.New(nil, registry), registry
+	}
+	open := func(t *testing.T) (*engine.Engine, *manager.Registry) {
+		t.Helper()
+		return openWith(t, page.DefaultBufferPages)
+	}
+	exec := func(t *testing.T, eng *engine.Engine, sql string) string {
+		t.Helper()
+		result, err := eng.Execute(sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var out strings.Builder
+		for _, row := range result.Rows {
+			for _, col := range result.Columns {
+				fmt.Fprintf(&out, "%s=%#v ", col, row.Data[col])
+			}
+			out.WriteString("\n")
+		}
+		return out.String()
+	}
+	storageOf := func(t *testing.T, name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(basePath, name, "meta.json"))
+		if err != nil {
+			t.Fatalf("Failed to read meta.json: %v", err)
+		}
+		var meta metadata.DatabaseMeta
+		if err := json.Unmarshal(content, &meta); err != nil {
+			t.Fatalf("Failed to parse meta.json: %v", err)
+		}
+		return meta.Storage
+	}
+	saveAll := func(registry *manager.Registry) {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		registry.SaveAll(tx)
+	}
+
+	const query = "SELECT * FROM users ORDER BY id"
+	usersPath := filepath.Join(basePath, "shop", "users")
+
+	eng, registry := open(t)
+	exec(t, eng, "USE shop")
+	for i := 0; i < 300; i++ {
+		exec(t, eng, fmt.Sprintf("INSERT INTO users (username, email, is_active) VALUES ('user%03d', 'user%03d@example.com', %v)", i, i, i%2 == 0))
+	}
+	exec(t, eng, "INSERT INTO users (username, email) VALUES ('noflag', 'noflag@example.com')")
+	saveAll(registry)
+	expected := exec(t, eng, query)
+
+	t.Run("Convert to page storage", func(t *testing.T) {
+		result, err := eng.Execute("ALTER DATABASE shop SET STORAGE = page")
+		if err != nil {
+			t.Fatalf("ALTER DATABASE failed: %v", err)
+		}
+		if result.Message != "Database 'shop' now uses page storage" {
+			t.Errorf("Unexpected message: %s", result.Message)
+		}
+		if storage := storageOf(t, "shop"); storage != storageEngine.StoragePage {
+			t.Errorf("Expected meta.json to record page storage, got %q", storage)
+		}
+		if _, err := os.Stat(filepath.Join(usersPath, page.FileName)); err != nil {
+			t.Errorf("Expected %s: %v", page.FileName, err)
+		}
+		if _, err := os.Stat(filepath.Join(usersPath, "data.json")); !os.IsNotExist(err) {
+			t.Errorf("Expected data.json to be removed, got %v", err)
+		}
+		if got := exec(t, eng, query); got != expected {
+			t.Errorf("Rows changed by the conversion")
+		}
+	})
+
+	t.Run("Rows survive a restart", func(t *testing.T) {
+		eng, registry = open(t)
+		exec(t, eng, "USE shop")
+		if got := exec(t, eng, query); got != expected {
+			t.Fatalf("Expected the rows saved before the restart, got:\n%s", got)
+		}
+
+		exec(t, eng, "DELETE FROM users WHERE id < 100")
+		exec(t, eng, "UPDATE users SET is_active = false WHERE id = 200")
+		exec(t, eng, "INSERT INTO users (username, email) VALUES ('late', 'late@example.com')")
+		expected = exec(t, eng, query)
+		saveAll(registry)
+
+		eng, registry = open(t)
+		exec(t, eng, "USE shop")
+		if got := exec(t, eng, query); got != expected {
+			t.Errorf("Expected the changed rows after a restart, got:\n%s", got)
+		}
+	})
+
+	t.Run("Corrupt page is reported", func(t *testing.T) {
+		path := filepath.Join(usersPath, page.FileName)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read pages: %v", err)
+		}
+		content[page.Size+100] ^= 0xff
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to write pages: %v", err)
+		}
+		defer func() {
+			content[page.Size+100] ^= 0xff
+			os.WriteFile(path, content, 0644)
+		}()
+
+		eng, _ := open(t)
+		if _, err := eng.Execute("USE shop"); err == nil || !strings.Contains(err.Error(), "checksum") {
+			t.Errorf("Expected a checksum error, got %v", err)
+		}
+	})
+
+	t.Run("Table larger than the buffer pool", func(t *testing.T) {
+		for i := 0; i < 2000; i++ {
+			exec(t, eng, fmt.Sprintf("INSERT INTO users (username, email, is_active) VALUES ('bulk%04d', 'bulk%04d@example.com', %v)", i, i, i%3 == 0))
+		}
+		saveAll(registry)
+		expected = exec(t, eng, query)
+
+		const (
+			filtered = "SELECT username FROM users WHERE is_active = true ORDER BY id"
+			joined   = "SELECT COUNT(*) FROM users a JOIN users b ON a.id = b.id"
+		)
+		wantFiltered, wantJoined := exec(t, eng, filtered), exec(t, eng, joined)
+
+		// Two cached pages hold a fraction of the table
+		eng, registry = openWith(t, 2)
+		exec(t, eng, "USE shop")
+		db, err := registry.Get("shop")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		users := db.Tables["users"]
+		if !users.Paged() {
+			t.Fatal("Expected the rows of a page table to stay on disk")
+		}
+		if pages, _ := os.Stat(filepath.Join(usersPath, page.FileName)); pages.Size() < 10*page.Size {
+			t.Fatalf("Expected a heap of at least 10 pages, got %d bytes", pages.Size())
+		}
+
+		if got := exec(t, eng, filtered); got != wantFiltered {
+			t.Errorf("Filtered scan returned different rows:\n%s", got)
+		}
+		if got := exec(t, eng, joined); got != wantJoined {
+			t.Errorf("Expected %s from the join, got %s", wantJoined, got)
+		}
+		exec(t, eng, "ANALYZE users")
+		if !users.Paged() {
+			t.Error("Expected scans, joins and ANALYZE to leave the rows on disk")
+		}
+
+		// Writing loads the table, which is then saved as before
+		exec(t, eng, "DELETE FROM users WHERE username = 'bulk0001'")
+		if users.Paged() {
+			t.Error("Expected DELETE to load the rows")
+		}
+		expected = exec(t, eng, query)
+		saveAll(registry)
+		eng, registry = openWith(t, 2)
+		exec(t, eng, "USE shop")
+		if got := exec(t, eng, query); got != expected {
+			t.Errorf("Expected the rows saved from the loaded table")
+		}
+	})
+
+	t.Run("Convert back to JSON", func(t *testing.T) {
+		if _, err := eng.Execute("ALTER DATABASE shop SET STORAGE = json"); err != nil {
+			t.Fatalf("ALTER DATABASE failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(usersPath, page.FileName)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", page.FileName, err)
+		}
+		eng, _ := open(t)
+		exec(t, eng, "USE shop")
+		if got := exec(t, eng, query); got != expected {
+			t.Errorf("Rows changed by the conversion back")
+		}
+	})
+
+	t.Run("Create with page storage", func(t *testing.T) {
+		if _, err := eng.Execute("CREATE DATABASE paged STORAGE = page"); err != nil {
+			t.Fatalf("CREATE DATABASE failed: %v", err)
+		}
+		if storage := storageOf(t, "paged"); storage != storageEngine.StoragePage {
+			t.Errorf("Expected meta.json to record page storage, got %q", storage)
+		}
+		if _, err := eng.Execute("CREATE DATABASE other STORAGE = xml"); err == nil {
+			t.Error("Expected an unknown storage format to fail")
+		}
+	})
+}
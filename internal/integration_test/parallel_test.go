@@ -0,0 +1,194 @@
+package integration
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+)
+
+// TestParallelExecution tests that parallel scans and hash joins return
+// exactly the rows, in exactly the order, of serial execution
+func TestParallelExecution(t *testing.T) {
+	const eventCount, codeCount = 20000, 6000
+
+	events := &schema.Table{
+		Name: "events",
+		Schema: &schema.TableSchema{
+			TableName: "events",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "code", Type: schema.ColumnTypeInt},
+				{Name: "kind", Type: schema.ColumnTypeText},
+				{Name: "value", Type: schema.ColumnTypeInt},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	codes := &schema.Table{
+		Name: "codes",
+		Schema: &schema.TableSchema{
+			TableName: "codes",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "code", Type: schema.ColumnTypeInt},
+				{Name: "kind", Type: schema.ColumnTypeText},
+				{Name: "label", Type: schema.ColumnTypeText},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	limits := &schema.Table{
+		Name: "limits",
+		Schema: &schema.TableSchema{
+			TableName: "limits",
+			Columns:   []schema.Column{{Name: "n", Type: schema.ColumnTypeInt}},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	// Codes repeat, so joins match several rows per key; every 97th event
+	// has no code and codes above 7000 match no event
+	for i := 1; i <= eventCount; i++ {
+		code := interface{}(int64(i * 13 % 7000))
+		if i%97 == 0 {
+			code = nil
+		}
+		events.Rows = append(events.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "code": code, "kind": fmt.Sprintf("k%d", i%5), "value": int64(i * 7 % 1000),
+		}))
+	}
+	for i := 1; i <= codeCount; i++ {
+		codes.Rows = append(codes.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "code": int64(i * 3 % 7500), "kind": fmt.Sprintf("k%d", i%3), "label": fmt.Sprintf("label%d", i),
+		}))
+	}
+	for _, n := range []int64{5, 500, 995} {
+		limits.Rows = append(limits.Rows, data.NewRow(map[string]interface{}{"n": n}))
+	}
+	for _, table := range []*schema.Table{events, codes, limits} {
+		if err := indexing.BuildIndexes(table); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+	}
+	db := &schema.Database{
+		Name:   "parallel",
+		Tables: map[string]*schema.Table{"events": events, "codes": codes, "limits": limits},
+	}
+
+	// run executes a query with the given number of workers and returns its
+	// rows as strings in result order
+	run := func(t *testing.T, sql string, workers int, algorithm string) []string {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		node, err := planner.Plan(stmt, db, nil)
+		if err != nil {
+			t.Fatalf("Planning %q failed: %v", sql, err)
+		}
+
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.ParallelScans = workers > 1
+		ctx.Config.Workers = workers
+		ctx.Config.JoinAlgorithm = algorithm
+		result, err := executor.ExecuteWith(node.(*plan.SelectNode), ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+
+		rows := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			values := make([]string, len(result.Columns))
+			for j, col := range result.Columns {
+				values[j] = fmt.Sprint(row.Data[col])
+			}
+			rows[i] = strings.Join(values, " ")
+		}
+		return rows
+	}
+
+	queries := []struct {
+		name      string
+		sql       string
+		algorithm string
+	}{
+		{"Filtered scan", "SELECT id, value FROM events WHERE value > 500 AND kind <> 'k3'", ""},
+		{"Filtered scan with LIMIT", "SELECT id FROM events WHERE value < 100 LIMIT 25", ""},
+		{"Filtered scan with ORDER BY", "SELECT id, kind FROM events WHERE value >= 10 ORDER BY kind DESC", ""},
+		{"Hash join", "SELECT events.id, codes.label FROM events JOIN codes ON events.code = codes.code", "hash"},
+		{"Composite key hash join", "SELECT events.id, codes.id FROM events JOIN codes ON events.code = codes.code AND events.kind = codes.kind", "hash"},
+		{"Left join", "SELECT events.id, codes.label FROM events LEFT JOIN codes ON events.code = codes.code WHERE events.value < 300", "hash"},
+		{"Full join", "SELECT events.id, codes.id FROM events FULL JOIN codes ON events.code = codes.code", "hash"},
+		{"Index nested-loop join", "SELECT events.id, codes.label FROM events JOIN codes ON events.value = codes.id", "index_nested_loop"},
+		{"Nested-loop join", "SELECT events.id, limits.n FROM limits JOIN events ON events.value > limits.n WHERE events.kind = 'k1'", ""},
+		{"Join with GROUP BY and ORDER BY", "SELECT codes.kind, COUNT(*), SUM(events.value) FROM events JOIN codes ON events.code = codes.code GROUP BY codes.kind ORDER BY codes.kind", "hash"},
+	}
+
+	for _, q := range queries {
+		t.Run(q.name, func(t *testing.T) {
+			expected := run(t, q.sql, 1, q.algorithm)
+			if len(expected) == 0 {
+				t.Fatalf("Expected rows from %s", q.sql)
+			}
+			for _, workers := range []int{2, 4, 7} {
+				got := run(t, q.sql, workers, q.algorithm)
+				if len(got) != len(expected) {
+					t.Fatalf("%d workers: expected %d rows, got %d", workers, len(expected), len(got))
+				}
+				for i := range got {
+					if got[i] != expected[i] {
+						t.Fatalf("%d workers: row %d is %q, serial execution returned %q", workers, i, got[i], expected[i])
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("Workers stop with the query", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		for i := 0; i < 20; i++ {
+			run(t, "SELECT id FROM events WHERE value > 1 LIMIT 1", 8, "")
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Expected scan workers to exit, goroutines grew from %d to %d", before, after)
+		}
+	})
+
+	t.Run("parallel_workers setting", func(t *testing.T) {
+		eng := engine.New(nil, nil)
+		if eng.ParallelWorkers() != runtime.GOMAXPROCS(0) {
+			t.Errorf("Expected one worker per CPU by default, got %d", eng.ParallelWorkers())
+		}
+		for value, expected := range map[string]int{"4": 4, "1": 1, "0": 1, "'3'": 3, "DEFAULT": runtime.GOMAXPROCS(0)} {
+			if _, err := eng.Execute("SET parallel_workers = " + value); err != nil {
+				t.Fatalf("SET parallel_workers = %s failed: %v", value, err)
+			}
+			if eng.ParallelWorkers() != expected {
+				t.Errorf("SET parallel_workers = %s: expected %d workers, got %d", value, expected, eng.ParallelWorkers())
+			}
+		}
+		for _, value := range []string{"-1", "'many'"} {
+			if _, err := eng.Execute("SET parallel_workers = " + value); err == nil {
+				t.Errorf("Expected an error for parallel_workers = %s", value)
+			}
+		}
+	})
+}
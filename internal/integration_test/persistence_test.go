@@ -0,0 +1,212 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestIncrementalSave tests that saving skips clean tables and clears the
+// dirty flag of the ones it writes
+func TestIncrementalSave(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+	eng := engine.New(nil, registry)
+	saveAll := func() {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		registry.SaveAll(tx)
+	}
+
+	usersPath := filepath.Join(basePath, "shop", "users")
+	files := []string{
+		filepath.Join(usersPath, "data.json"),
+		filepath.Join(usersPath, "meta.json"),
+		filepath.Join(basePath, "shop", "meta.json"),
+	}
+	// age sets the modification time of the files to an hour ago
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	age := func() {
+		for _, path := range files {
+			if err := os.Chtimes(path, past, past); err != nil {
+				t.Fatalf("Chtimes failed: %v", err)
+			}
+		}
+	}
+	modified := func(path string) bool {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		return !info.ModTime().Equal(past)
+	}
+
+	if _, err := eng.Execute("USE shop"); err != nil {
+		t.Fatalf("USE failed: %v", err)
+	}
+	// The first save writes the database meta in its saved form
+	saveAll()
+	age()
+
+	t.Run("Clean tables are not written", func(t *testing.T) {
+		if _, err := eng.Execute("SELECT * FROM users"); err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		saveAll()
+		for _, path := range files {
+			if modified(path) {
+				t.Errorf("Expected %s not to be written", path)
+			}
+		}
+	})
+
+	t.Run("Dirty tables are written once", func(t *testing.T) {
+		if _, err := eng.Execute("INSERT INTO users (username, email) VALUES ('new', 'new@example.com')"); err != nil {
+			t.Fatalf("INSERT failed: %v", err)
+		}
+		db, _ := registry.Get("shop")
+		users := db.Tables["users"]
+		if !users.IsDirty() {
+			t.Fatal("Expected INSERT to mark the table dirty")
+		}
+
+		saveAll()
+		if !modified(files[0]) || !modified(files[1]) {
+			t.Error("Expected the table files to be written")
+		}
+		if modified(files[2]) {
+			t.Error("Expected the unchanged database meta not to be written")
+		}
+		if users.IsDirty() {
+			t.Error("Expected the save to clear the dirty flag")
+		}
+
+		age()
+		saveAll()
+		if modified(files[0]) {
+			t.Error("Expected a second save to write nothing")
+		}
+	})
+}
+
+// TestInterruptedSave tests that loading a table finishes a save that
+// crashed after committing and undoes one that crashed before
+func TestInterruptedSave(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	usersPath := filepath.Join(basePath, "shop", "users")
+
+	// Temp files as a save leaves them part way: one new row and a meta.json
+	// whose sequence covers it
+	const newRows = `[{"id": 3, "username": "carol", "email": "carol@example.com", "is_active": true}]`
+	stage := func(t *testing.T) {
+		t.Helper()
+		meta, err := os.ReadFile(filepath.Join(usersPath, "meta.json"))
+		if err != nil {
+			t.Fatalf("Failed to read meta.json: %v", err)
+		}
+		meta = []byte(strings.Replace(string(meta), `"last_insert_id": 2`, `"last_insert_id": 3`, 1))
+		for name, content := range map[string]string{"data.json.tmp": newRows, "meta.json.tmp": string(meta)} {
+			if err := os.WriteFile(filepath.Join(usersPath, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+	}
+	load := func(t *testing.T) string {
+		t.Helper()
+		eng := engine.New(nil, manager.NewRegistry(basePath, storageEngine.NewJSONEngine()))
+		if _, err := eng.Execute("USE shop"); err != nil {
+			t.Fatalf("USE failed: %v", err)
+		}
+		result, err := eng.Execute("SELECT username FROM users ORDER BY id")
+		if err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row.Data["username"].(string))
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(usersPath, "*.tmp"))
+		if len(leftovers) > 0 {
+			t.Errorf("Expected the temp files to be cleaned up, got %v", leftovers)
+		}
+		return strings.Join(names, ",")
+	}
+
+	before := load(t)
+
+	t.Run("Crash before the commit record", func(t *testing.T) {
+		stage(t)
+		if got := load(t); got != before {
+			t.Errorf("Expected the old rows %q, got %q", before, got)
+		}
+	})
+
+	t.Run("Crash after the commit record", func(t *testing.T) {
+		stage(t)
+		record := `{"files": ["data.json", "meta.json"]}`
+		if err := os.WriteFile(filepath.Join(usersPath, "commit.json"), []byte(record), 0644); err != nil {
+			t.Fatalf("Failed to write commit record: %v", err)
+		}
+		if got := load(t); got != "carol" {
+			t.Errorf("Expected the committed rows, got %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(usersPath, "commit.json")); !os.IsNotExist(err) {
+			t.Errorf("Expected the commit record to be removed, got %v", err)
+		}
+	})
+}
+
+// TestBackgroundFlush tests that the background flusher saves changes
+// without an explicit save
+func TestBackgroundFlush(t *testing.T) {
+	basePath := t.TempDir()
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+	eng := engine.New(nil, registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.FlushEvery(ctx, 10*time.Millisecond)
+
+	if _, err := eng.Execute("USE shop"); err != nil {
+		t.Fatalf("USE failed: %v", err)
+	}
+	if _, err := eng.Execute("INSERT INTO users (username, email) VALUES ('flushed', 'flushed@example.com')"); err != nil {
+		t.Fatalf("INSERT failed: %v", err)
+	}
+
+	dataPath := filepath.Join(basePath, "shop", "users", "data.json")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		content, err := os.ReadFile(dataPath)
+		if err == nil && strings.Contains(string(content), "flushed") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the flusher to save the inserted row")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	db, _ := registry.Get("shop")
+	if db.Tables["users"].IsDirty() {
+		t.Error("Expected the flushed table to be clean")
+	}
+}
@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// TestPointInTimeRecovery tests that a database is recovered from a base
+// backup and its archived WAL up to an LSN or a time, leaving out the
+// changes committed after it
+func TestPointInTimeRecovery(t *testing.T) {
+	basePath := t.TempDir()
+	archiveRoot := t.TempDir()
+	base := filepath.Join(t.TempDir(), "base")
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+	// Small segments, so the log spans the archive and the active segment
+	registry.EnableWAL(archiveRoot, 512)
+	eng := engine.New(nil, registry)
+
+	mustExec := func(t *testing.T, sql string) {
+		t.Helper()
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+	}
+	users := func(t *testing.T, database string) string {
+		t.Helper()
+		mustExec(t, "USE "+database)
+		result, err := eng.Execute("SELECT username, email FROM users ORDER BY id")
+		if err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row.Data["username"].(string)+" "+row.Data["email"].(string))
+		}
+		return strings.Join(names, ",")
+	}
+
+	mustExec(t, "USE shop")
+	mustExec(t, "INSERT INTO users (username, email) VALUES ('carol', 'carol@example.com')")
+	mustExec(t, "BACKUP DATABASE shop TO '"+base+"'")
+
+	mustExec(t, "INSERT INTO users (username, email) VALUES ('dave', 'dave@example.com')")
+	mustExec(t, "UPDATE users SET email = 'dave@example.org' WHERE username = 'dave'")
+	want := users(t, "shop")
+	targetLSN := registry.WAL("shop").NextLSN() - 1
+	time.Sleep(10 * time.Millisecond)
+	targetTime := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	// The mistake recovery goes back to just before
+	mustExec(t, "DELETE FROM users WHERE username = 'admin'")
+	mustExec(t, "INSERT INTO users (username, email) VALUES ('eve', 'eve@example.com')")
+	latest := users(t, "shop")
+
+	archived, _ := wal.ListSegments(filepath.Join(archiveRoot, "shop"))
+	active, _ := wal.ListSegments(filepath.Join(basePath, "shop", archive.WALDir))
+	if len(archived) == 0 || len(active) != 1 {
+		t.Fatalf("Expected archived segments and one active segment, got %v and %v", archived, active)
+	}
+	segments := append(archived, active...)
+
+	tests := []struct {
+		name   string
+		target wal.RecoveryTarget
+		want   string
+	}{
+		{"Until an LSN", wal.RecoveryTarget{LSN: targetLSN}, want},
+		{"Until a time", wal.RecoveryTarget{Time: targetTime}, want},
+		{"Whole log", wal.RecoveryTarget{}, latest},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "shop_pitr" + string(rune('a'+i))
+			_, result, err := registry.RestoreUntil(name, base, segments, tt.target)
+			if err != nil {
+				t.Fatalf("RestoreUntil failed: %v", err)
+			}
+			if got := users(t, name); got != tt.want {
+				t.Errorf("Expected users %q, got %q", tt.want, got)
+			}
+			if tt.target.LSN != 0 && result.StopLSN != tt.target.LSN {
+				t.Errorf("Expected recovery to stop at LSN %d, got %d", tt.target.LSN, result.StopLSN)
+			}
+		})
+	}
+
+	t.Run("Recovered database is saved", func(t *testing.T) {
+		reopened := engine.New(nil, manager.NewRegistry(basePath, storageEngine.NewJSONEngine()))
+		registry.Close()
+		if _, err := reopened.Execute("USE shop_pitra"); err != nil {
+			t.Fatalf("USE failed: %v", err)
+		}
+		result, err := reopened.Execute("SELECT email FROM users WHERE username = 'dave'")
+		if err != nil || len(result.Rows) != 1 || result.Rows[0].Data["email"] != "dave@example.org" {
+			t.Errorf("Expected the recovered update on disk, got %v (%v)", result, err)
+		}
+	})
+
+	t.Run("Missing segment", func(t *testing.T) {
+		registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+		defer registry.Close()
+		if _, _, err := registry.RestoreUntil("shop_gap", base, segments[1:], wal.RecoveryTarget{}); err == nil || !strings.Contains(err.Error(), "missing") {
+			t.Errorf("Expected a log missing its first segment to fail, got %v", err)
+		}
+	})
+
+	t.Run("Backup without the WAL", func(t *testing.T) {
+		registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+		defer registry.Close()
+		plain := filepath.Join(t.TempDir(), "plain")
+		if _, err := registry.Backup("shop_pitra", plain); err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		if _, _, err := registry.RestoreUntil("shop_plain", plain, segments, wal.RecoveryTarget{}); err == nil || !strings.Contains(err.Error(), "without the WAL") {
+			t.Errorf("Expected a backup taken without the WAL to be refused, got %v", err)
+		}
+	})
+}
+
+// TestPointInTimeRecoveryOfSchemaChanges tests that tables and indexes
+// created after the base backup are recreated before the rows logged in
+// them are replayed, along with sequences set, and that a script undoing
+// them is replayed too
+func TestPointInTimeRecoveryOfSchemaChanges(t *testing.T) {
+	basePath := t.TempDir()
+	archiveRoot := t.TempDir()
+	base := filepath.Join(t.TempDir(), "base")
+	if err := bootstrap.EnsureDatabase(filepath.Join(basePath, "shop"), "shop"); err != nil {
+		t.Fatalf("Failed to bootstrap database: %v", err)
+	}
+	registry := manager.NewRegistry(basePath, storageEngine.NewJSONEngine())
+	defer registry.Close()
+	registry.EnableWAL(archiveRoot, 0)
+	eng := engine.New(nil, registry)
+
+	mustExec := func(t *testing.T, sql string) {
+		t.Helper()
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("%s failed: %v", sql, err)
+		}
+	}
+
+	mustExec(t, "USE shop")
+	mustExec(t, "BACKUP DATABASE shop TO '"+base+"'")
+
+	mustExec(t, "CREATE TABLE notes (id INT PRIMARY KEY AUTO_INCREMENT, body TEXT)")
+	mustExec(t, "CREATE INDEX idx_notes_body ON notes (body)")
+	mustExec(t, "INSERT INTO notes (body) VALUES ('first'), ('second')")
+	mustExec(t, "ALTER TABLE notes AUTO_INCREMENT = 50")
+	// A failed script drops the table and index it created
+	if _, err := eng.ExecuteScript(context.Background(),
+		"CREATE TABLE drafts (id INT PRIMARY KEY, body TEXT); CREATE INDEX idx_users_email ON users (email); INSERT INTO missing (id) VALUES (1);"); err == nil {
+		t.Fatal("Expected the script to fail")
+	}
+
+	segments, _ := wal.ListSegments(filepath.Join(basePath, "shop", archive.WALDir))
+	if _, _, err := registry.RestoreUntil("shop_pitr", base, segments, wal.RecoveryTarget{}); err != nil {
+		t.Fatalf("RestoreUntil failed: %v", err)
+	}
+
+	mustExec(t, "USE shop_pitr")
+	result, err := eng.Execute("SELECT body FROM notes ORDER BY id")
+	if err != nil {
+		t.Fatalf("SELECT failed: %v", err)
+	}
+	if len(result.Rows) != 2 || result.Rows[0].Data["body"] != "first" || result.Rows[1].Data["body"] != "second" {
+		t.Errorf("Expected the rows logged after the table was created, got %v", result.Rows)
+	}
+
+	db, err := registry.Get("shop_pitr")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if db.Tables["notes"].Schema.GetIndexDefinition("idx_notes_body") == nil {
+		t.Error("Expected idx_notes_body to be recreated")
+	}
+	if got := db.Tables["notes"].LastInsertID; got != 49 {
+		t.Errorf("Expected the sequence set after the inserts, 49, got %d", got)
+	}
+	if _, ok := db.Tables["drafts"]; ok {
+		t.Error("Expected drafts, dropped by the failed script, not to be recreated")
+	}
+	if db.Tables["users"].Schema.GetIndexDefinition("idx_users_email") != nil {
+		t.Error("Expected idx_users_email, dropped by the failed script, not to be recreated")
+	}
+}
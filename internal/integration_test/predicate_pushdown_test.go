@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/testutil"
+)
+
+// TestPredicatePushdown tests that single-table WHERE conjuncts are applied
+// while scanning below a JOIN, except on tables an outer join null-extends
+func TestPredicatePushdown(t *testing.T) {
+	// users: alice (1), bob (2), charlie (3); orders: alice has two, bob one
+	db := &schema.Database{
+		Name: "pushdown",
+		Tables: map[string]*schema.Table{
+			"users":  testutil.CreateUsersTable(),
+			"orders": testutil.CreateOrdersTable(),
+		},
+	}
+
+	execute := func(t *testing.T, node plan.Node) []data.Row {
+		t.Helper()
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		result, err := executor.Execute(node, db, tx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result.Rows
+	}
+
+	// scanFilter reports whether the scan of tableName under node filters rows
+	scanFilter := func(t *testing.T, node plan.Node, tableName string) bool {
+		t.Helper()
+		filtered, found := false, false
+		plan.WalkTree(node, func(n plan.Node) error {
+			switch scan := n.(type) {
+			case *plan.ScanNode:
+				if scan.TableName == tableName {
+					found, filtered = true, scan.Predicate != nil
+				}
+			case *plan.IndexScanNode:
+				if scan.TableName == tableName {
+					found, filtered = true, true
+				}
+			}
+			return nil
+		})
+		if !found {
+			t.Fatalf("No scan of %s in plan", tableName)
+		}
+		return filtered
+	}
+
+	tests := []struct {
+		name     string
+		sql      string
+		pushed   []string // tables whose scan filters rows
+		residual bool     // whether a filter remains above the join
+		rows     int
+	}{
+		{
+			name:   "Qualified column pushed to its table",
+			sql:    "SELECT * FROM users JOIN orders ON users.id = orders.user_id WHERE users.id = 1",
+			pushed: []string{"users"},
+			rows:   2,
+		},
+		{
+			name:   "Unqualified column pushed to its owner",
+			sql:    "SELECT * FROM users JOIN orders ON users.id = orders.user_id WHERE product = 'Mouse'",
+			pushed: []string{"orders"},
+			rows:   1,
+		},
+		{
+			name:   "Conjuncts pushed to both sides",
+			sql:    "SELECT * FROM users JOIN orders ON users.id = orders.user_id WHERE users.username = 'alice' AND orders.amount > 100",
+			pushed: []string{"users", "orders"},
+			rows:   1,
+		},
+		{
+			name:     "Cross-table OR stays above the join",
+			sql:      "SELECT * FROM users JOIN orders ON users.id = orders.user_id WHERE users.username = 'bob' OR product = 'Laptop'",
+			residual: true,
+			rows:     2,
+		},
+		{
+			name:   "LEFT JOIN pushes the preserved side",
+			sql:    "SELECT * FROM users LEFT JOIN orders ON users.id = orders.user_id WHERE users.id = 3",
+			pushed: []string{"users"},
+			rows:   1,
+		},
+		{
+			// Filtering orders first would null-extend alice and charlie
+			// instead of removing them
+			name:     "LEFT JOIN keeps the nullable side above the join",
+			sql:      "SELECT * FROM users LEFT JOIN orders ON users.id = orders.user_id WHERE orders.product = 'Laptop'",
+			residual: true,
+			rows:     1,
+		},
+		{
+			name:     "RIGHT JOIN keeps the nullable side above the join",
+			sql:      "SELECT * FROM users RIGHT JOIN orders ON users.id = orders.user_id WHERE users.username = 'alice' AND orders.id > 1",
+			pushed:   []string{"orders"},
+			residual: true,
+			rows:     1,
+		},
+		{
+			name:     "FULL JOIN pushes nothing",
+			sql:      "SELECT * FROM users FULL JOIN orders ON users.id = orders.user_id WHERE users.id = 2",
+			residual: true,
+			rows:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := planQuery(t, db, tt.sql)
+
+			pushed := make(map[string]bool)
+			for _, name := range tt.pushed {
+				pushed[name] = true
+			}
+			for _, name := range []string{"users", "orders"} {
+				if got := scanFilter(t, node, name); got != pushed[name] {
+					t.Errorf("Scan of %s filtered = %v, want %v", name, got, pushed[name])
+				}
+			}
+			if got := node.Predicate != nil; got != tt.residual {
+				t.Errorf("Residual filter = %v, want %v", got, tt.residual)
+			}
+
+			if rows := execute(t, node); len(rows) != tt.rows {
+				t.Errorf("Expected %d rows, got %d", tt.rows, len(rows))
+			}
+		})
+	}
+}
@@ -1,12 +1,10 @@
 package integration
 
 import (
-	"fmt"
 	"net"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
 	"encoding/json"
 
@@ -35,19 +33,13 @@ func TestServerJSON(t *testing.T) {
 	db := setupTestDB(t)
 	defer teardownTestDB(t, db)
 
-	port := 54321
-
 	basePath := filepath.Dir(testDBPath)
 	storageEng := storageEngine.NewJSONEngine()
 	registry := manager.NewRegistry(basePath, storageEng)
 
-	// Start server in goroutine
-	go network.Start(port, registry)
-
-	// Wait a bit for server
-	time.Sleep(100 * time.Millisecond)
+	addr := startServer(t, registry)
 
-	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	conn, err := net.Dial("tcp", addr)
 	if err != nil {
 		t.Fatalf("Failed to connect: %v", err)
 	}
@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/network"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestServerShutdown tests that shutting the server down refuses new
+// connections, closes idle ones and lets a running query answer first
+func TestServerShutdown(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer teardownTestDB(t, testDB)
+
+	registry := manager.NewRegistry(filepath.Dir(testDBPath), storageEngine.NewJSONEngine())
+	db, err := registry.Get("testdb_integration")
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+	addSlowTables(t, db)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := network.NewServer(registry)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	connect := func(t *testing.T) (net.Conn, *json.Encoder, *json.Decoder) {
+		t.Helper()
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		return conn, json.NewEncoder(conn), json.NewDecoder(conn)
+	}
+	query := func(t *testing.T, encoder *json.Encoder, decoder *json.Decoder, sql string) Result {
+		t.Helper()
+		if err := encoder.Encode(network.Request{Query: sql}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+		var res Result
+		if err := decoder.Decode(&res); err != nil {
+			t.Fatalf("Failed to decode JSON: %v", err)
+		}
+		return res
+	}
+
+	idle, _, idleDecoder := connect(t)
+	defer idle.Close()
+
+	busy, encoder, decoder := connect(t)
+	defer busy.Close()
+	query(t, encoder, decoder, "USE testdb_integration")
+	query(t, encoder, decoder, "SET statement_timeout = '300ms'")
+	if err := encoder.Encode(network.Request{Query: slowQuery}); err != nil {
+		t.Fatalf("Failed to send query: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the running query to drain, got %v", err)
+	}
+
+	// The running query answered before its connection closed
+	var res Result
+	if err := decoder.Decode(&res); err != nil {
+		t.Fatalf("Expected the running query to answer, got %v", err)
+	}
+	if !strings.Contains(res.Error, "statement timeout") {
+		t.Errorf("Expected the query to run to its timeout, got %+v", res)
+	}
+	if err := decoder.Decode(&res); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the busy connection to close after answering, got %v", err)
+	}
+	if err := idleDecoder.Decode(&res); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the idle connection to be closed, got %v", err)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("Expected ListenAndServe to return nil, got %v", err)
+	}
+	if conn, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("Expected new connections to be refused")
+	}
+}
+
+// TestServerShutdownTimeout tests that a shutdown whose context ends first
+// cancels the queries still running
+func TestServerShutdownTimeout(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer teardownTestDB(t, testDB)
+
+	registry := manager.NewRegistry(filepath.Dir(testDBPath), storageEngine.NewJSONEngine())
+	db, err := registry.Get("testdb_integration")
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+	addSlowTables(t, db)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := network.NewServer(registry)
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	encoder := json.NewEncoder(conn)
+	for _, sql := range []string{"USE testdb_integration", slowQuery} {
+		if err := encoder.Encode(network.Request{Query: sql}); err != nil {
+			t.Fatalf("Failed to send query: %v", err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the running query to be cancelled promptly, took %s", elapsed)
+	}
+}
@@ -0,0 +1,251 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/query/spill"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/loader"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+)
+
+// TestMemoryBoundedExecution tests that sorts, aggregations and hash joins
+// that exceed the memory budget spill to disk and return the same rows as
+// when they run in memory
+func TestMemoryBoundedExecution(t *testing.T) {
+	const itemCount, customerCount = 3000, 800
+
+	customers := &schema.Table{
+		Name: "customers",
+		Schema: &schema.TableSchema{
+			TableName: "customers",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "name", Type: schema.ColumnTypeText},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	items := &schema.Table{
+		Name: "items",
+		Schema: &schema.TableSchema{
+			TableName: "items",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "customer_id", Type: schema.ColumnTypeInt},
+				{Name: "category", Type: schema.ColumnTypeText},
+				{Name: "price", Type: schema.ColumnTypeFloat},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	for i := 1; i <= customerCount; i++ {
+		customers.Rows = append(customers.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "name": fmt.Sprintf("customer%04d", i),
+		}))
+	}
+	// Every 50th item has no customer, and customer ids run past the last
+	// customer so some items join nothing
+	for i := 1; i <= itemCount; i++ {
+		customerID := interface{}(int64(i*7%(customerCount+100) + 1))
+		if i%50 == 0 {
+			customerID = nil
+		}
+		items.Rows = append(items.Rows, data.NewRow(map[string]interface{}{
+			"id":          int64(i),
+			"customer_id": customerID,
+			"category":    fmt.Sprintf("category%03d", i%300),
+			"price":       float64(i%97) + 0.25,
+		}))
+	}
+	for _, table := range []*schema.Table{customers, items} {
+		if err := indexing.BuildIndexes(table); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+	}
+	db := &schema.Database{
+		Name:   "spill",
+		Tables: map[string]*schema.Table{"customers": customers, "items": items},
+	}
+
+	// run executes a query with a memory budget and returns its rows as
+	// strings, in result order, together with the budget it ran with
+	run := func(t *testing.T, sql string, budget int64, algorithm string) ([]string, *spill.Budget) {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		node, err := planner.Plan(stmt, db, nil)
+		if err != nil {
+			t.Fatalf("Planning %q failed: %v", sql, err)
+		}
+
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		tempDir := t.TempDir()
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.MemoryBudget = budget
+		ctx.Config.TempDir = tempDir
+		ctx.Config.JoinAlgorithm = algorithm
+		result, err := executor.ExecuteWith(node.(*plan.SelectNode), ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+
+		if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+			t.Errorf("%s: expected run files to be removed, found %d (%v)", sql, len(entries), err)
+		}
+		if used := ctx.Memory.Used(); used != 0 {
+			t.Errorf("%s: expected the memory budget to be released, %d bytes still used", sql, used)
+		}
+
+		rows := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			values := make([]string, len(result.Columns))
+			for j, col := range result.Columns {
+				values[j] = fmt.Sprint(row.Data[col])
+			}
+			rows[i] = strings.Join(values, " ")
+		}
+		return rows, ctx.Memory
+	}
+
+	const tinyBudget = 16 << 10
+
+	queries := []struct {
+		name      string
+		sql       string
+		algorithm string
+		ordered   bool // the query's row order is deterministic
+		rows      int  // expected row count, if easily known
+	}{
+		{
+			name:    "External merge sort",
+			sql:     "SELECT id, price FROM items ORDER BY price DESC, category",
+			ordered: true,
+			rows:    itemCount,
+		},
+		{
+			name: "Spilling hash aggregation",
+			sql:  "SELECT category, COUNT(*), SUM(price), MIN(id), MAX(customer_id) FROM items GROUP BY category",
+			rows: 300,
+		},
+		{
+			name:    "Spilling aggregation with ORDER BY",
+			sql:     "SELECT customer_id, COUNT(*) FROM items GROUP BY customer_id ORDER BY COUNT(*) DESC, customer_id",
+			ordered: true,
+		},
+		{
+			name: "Grace hash join",
+			sql: "SELECT items.id, customers.name FROM items JOIN customers ON items.customer_id = customers.id " +
+				"WHERE customers.id > 0 AND items.price > 1",
+		},
+		{
+			name: "Grace hash join preserving unmatched rows",
+			sql: "SELECT items.id, customers.name FROM items LEFT JOIN customers ON items.customer_id = customers.id " +
+				"AND customers.id > 10 WHERE items.price > 1",
+		},
+		{
+			name:      "Merge join falls back to grace hash join",
+			sql:       "SELECT items.id, customers.name FROM items JOIN customers ON items.customer_id = customers.id WHERE customers.id > 0",
+			algorithm: "merge",
+		},
+	}
+
+	for _, q := range queries {
+		t.Run(q.name, func(t *testing.T) {
+			expected, inMemory := run(t, q.sql, 0, q.algorithm)
+			if inMemory.SpilledRuns() != 0 {
+				t.Errorf("Expected no spilling without a memory limit, got %d runs", inMemory.SpilledRuns())
+			}
+			if (q.rows > 0 && len(expected) != q.rows) || len(expected) == 0 {
+				t.Errorf("Expected %d rows, got %d", q.rows, len(expected))
+			}
+
+			got, budget := run(t, q.sql, tinyBudget, q.algorithm)
+			if budget.SpilledRuns() == 0 {
+				t.Errorf("Expected the query to spill with a %d byte budget", tinyBudget)
+			}
+			if !q.ordered {
+				sort.Strings(expected)
+				sort.Strings(got)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("Spilled query returned different rows than in memory (%d vs %d rows)", len(got), len(expected))
+			}
+		})
+	}
+
+	t.Run("memory_budget setting", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer teardownTestDB(t, testDB)
+
+		registry := manager.NewRegistry("../../databases", storageEngine.NewJSONEngine())
+		eng := engine.New(testDB, registry)
+		if eng.MemoryBudget() != executor.DefaultMemoryBudget {
+			t.Errorf("Expected default budget %d, got %d", executor.DefaultMemoryBudget, eng.MemoryBudget())
+		}
+
+		for value, expected := range map[string]int64{
+			"'1MB'":   1 << 20,
+			"'256kB'": 256 << 10,
+			"64":      64 << 10,
+			"0":       0,
+			"DEFAULT": executor.DefaultMemoryBudget,
+			"'100 b'": 100,
+		} {
+			if _, err := eng.Execute("SET memory_budget = " + value); err != nil {
+				t.Fatalf("SET memory_budget = %s failed: %v", value, err)
+			}
+			if eng.MemoryBudget() != expected {
+				t.Errorf("SET memory_budget = %s: expected %d bytes, got %d", value, expected, eng.MemoryBudget())
+			}
+		}
+		if _, err := eng.Execute("SET memory_budget = 'lots'"); err == nil {
+			t.Error("Expected an error for an invalid memory_budget")
+		}
+
+		// Spill runs go to a hidden directory of the database, which is not
+		// loaded as a table
+		if _, err := eng.Execute("SET memory_budget = '100 b'"); err != nil {
+			t.Fatalf("SET failed: %v", err)
+		}
+		result, err := eng.Execute("SELECT username FROM users ORDER BY username DESC, id")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Rows) != 2 || result.Rows[0].Data["username"] != "guest" {
+			t.Errorf("Expected guest first, got %v", result.Rows)
+		}
+		if _, err := os.Stat(filepath.Join(testDBPath, ".tmp")); err != nil {
+			t.Errorf("Expected the spill directory in the database directory: %v", err)
+		}
+		reloaded, err := loader.LoadDatabase(testDBPath)
+		if err != nil {
+			t.Fatalf("Failed to reload database: %v", err)
+		}
+		if len(reloaded.Tables) != 1 {
+			t.Errorf("Expected only the users table after reload, got %d tables", len(reloaded.Tables))
+		}
+	})
+}
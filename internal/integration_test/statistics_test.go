@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	storageEngine "github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/loader"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// TestAnalyzeStatistics tests that ANALYZE collects and persists column
+// statistics and that the planner uses them to choose between scan types
+func TestAnalyzeStatistics(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	registry := manager.NewRegistry(filepath.Dir(db.Path), storageEngine.NewJSONEngine())
+	eng := engine.New(db, registry)
+
+	if _, err := eng.Execute("CREATE INDEX idx_users_id ON users (id) USING BTREE;"); err != nil {
+		t.Fatalf("CREATE INDEX failed: %v", err)
+	}
+	for i := 3; i <= 50; i++ {
+		sql := fmt.Sprintf("INSERT INTO users (username, email) VALUES ('user%02d', 'user%02d@example.com');", i, i)
+		if _, err := eng.Execute(sql); err != nil {
+			t.Fatalf("Setup failed for %q: %v", sql, err)
+		}
+	}
+
+	users := db.Tables["users"]
+
+	// planFor returns the single-table plan for sql
+	planFor := func(t *testing.T, sql string) *plan.SelectNode {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		node, err := planner.Plan(stmt, db, tx)
+		if err != nil {
+			t.Fatalf("Planning error: %v", err)
+		}
+		return node.(*plan.SelectNode)
+	}
+
+	t.Run("ANALYZE table", func(t *testing.T) {
+		result, err := eng.Execute("ANALYZE users;")
+		if err != nil {
+			t.Fatalf("ANALYZE failed: %v", err)
+		}
+		if result.Message != "ANALYZE 1" {
+			t.Errorf("Expected 'ANALYZE 1', got '%s'", result.Message)
+		}
+
+		stats := users.Stats
+		if stats == nil {
+			t.Fatal("Expected table statistics after ANALYZE")
+		}
+		if stats.RowCount != 50 {
+			t.Errorf("Expected row count 50, got %d", stats.RowCount)
+		}
+
+		id := stats.ColumnStats("id")
+		if id.DistinctCount != 50 || id.NullFraction != 0 {
+			t.Errorf("Expected 50 distinct non-NULL ids, got %d (null fraction %v)", id.DistinctCount, id.NullFraction)
+		}
+		if id.Min != int64(1) || id.Max != int64(50) {
+			t.Errorf("Expected id range [1, 50], got [%v, %v]", id.Min, id.Max)
+		}
+		if len(id.Histogram) != 11 {
+			t.Errorf("Expected 10 histogram buckets (11 bounds), got %d bounds", len(id.Histogram))
+		}
+
+		active := stats.ColumnStats("is_active")
+		if active.NullFraction != 48.0/50.0 {
+			t.Errorf("Expected is_active null fraction 0.96, got %v", active.NullFraction)
+		}
+		if active.Histogram != nil {
+			t.Error("Expected no histogram for BOOL column")
+		}
+	})
+
+	t.Run("ANALYZE without table analyzes every table", func(t *testing.T) {
+		result, err := eng.Execute("ANALYZE")
+		if err != nil {
+			t.Fatalf("ANALYZE failed: %v", err)
+		}
+		if result.RowsAffected != len(db.Tables) {
+			t.Errorf("Expected %d tables analyzed, got %d", len(db.Tables), result.RowsAffected)
+		}
+	})
+
+	t.Run("Unknown table", func(t *testing.T) {
+		if _, err := eng.Execute("ANALYZE missing"); err == nil {
+			t.Error("Expected error for unknown table")
+		}
+	})
+
+	t.Run("Histogram drives scan choice", func(t *testing.T) {
+		broad := planFor(t, "SELECT * FROM users WHERE id >= 2")
+		if broad.Metadata()["scan_type"] != "sequential" {
+			t.Errorf("Expected sequential scan for id >= 2, got %v", broad.Metadata()["scan_type"])
+		}
+
+		narrow := planFor(t, "SELECT * FROM users WHERE id > 45")
+		if narrow.Metadata()["scan_type"] != "index" {
+			t.Errorf("Expected index scan for id > 45, got %v", narrow.Metadata()["scan_type"])
+		}
+		rows, _ := narrow.Metadata()["estimated_rows"].(int64)
+		if rows < 3 || rows > 7 {
+			t.Errorf("Expected about 5 estimated rows for id > 45, got %d", rows)
+		}
+	})
+
+	t.Run("Out-of-range equality", func(t *testing.T) {
+		node := planFor(t, "SELECT * FROM users WHERE id = 1000")
+		if rows, _ := node.Metadata()["estimated_rows"].(int64); rows != 1 {
+			t.Errorf("Expected 1 estimated row, got %d", rows)
+		}
+	})
+
+	t.Run("Statistics persisted", func(t *testing.T) {
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		if err := writer.SaveTable(users, tx); err != nil {
+			t.Fatalf("Failed to save table: %v", err)
+		}
+
+		reloaded, err := loader.LoadTable(users.Path)
+		if err != nil {
+			t.Fatalf("Failed to reload table: %v", err)
+		}
+		if reloaded.Stats == nil {
+			t.Fatal("Expected statistics after reload")
+		}
+		if reloaded.Stats.RowCount != 50 {
+			t.Errorf("Expected row count 50, got %d", reloaded.Stats.RowCount)
+		}
+		id := reloaded.Stats.ColumnStats("id")
+		if id.Min != int64(1) || id.Max != int64(50) {
+			t.Errorf("Expected INT min/max to reload as int64, got %T %v / %T %v", id.Min, id.Min, id.Max, id.Max)
+		}
+		if name := reloaded.Stats.ColumnStats("username"); name.Min != "admin" {
+			t.Errorf("Expected username min 'admin', got %v", name.Min)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/engine"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+)
+
+// TestExecutionStrategies tests that every execution strategy returns the
+// same result for the same plan, and that sessions can switch strategies
+func TestExecutionStrategies(t *testing.T) {
+	teams := &schema.Table{
+		Name: "teams",
+		Schema: &schema.TableSchema{
+			TableName: "teams",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "name", Type: schema.ColumnTypeText},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	players := &schema.Table{
+		Name: "players",
+		Schema: &schema.TableSchema{
+			TableName: "players",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+				{Name: "team_id", Type: schema.ColumnTypeInt},
+				{Name: "score", Type: schema.ColumnTypeFloat},
+			},
+		},
+		Indexes: make(map[string]*data.Index),
+	}
+	for i := 1; i <= 6; i++ {
+		teams.Rows = append(teams.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "name": fmt.Sprintf("team%d", i),
+		}))
+	}
+	for i := 1; i <= 3000; i++ {
+		teamID := interface{}(int64(i%8 + 1))
+		switch {
+		case i%25 == 0:
+			teamID = nil
+		case i%7 == 0:
+			// Inserted values of INT columns are int, loaded ones int64
+			teamID = i%8 + 1
+		}
+		players.Rows = append(players.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "team_id": teamID, "score": float64(i%17) / 2,
+		}))
+	}
+	for _, table := range []*schema.Table{teams, players} {
+		if err := indexing.BuildIndexes(table); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+	}
+	db := &schema.Database{
+		Name:   "strategies",
+		Tables: map[string]*schema.Table{"teams": teams, "players": players},
+	}
+
+	// planSQL parses and plans a query
+	planSQL := func(t *testing.T, sql string) plan.Node {
+		t.Helper()
+		tokens, err := lexer.Tokenize(sql)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		node, err := planner.Plan(stmt, db, nil)
+		if err != nil {
+			t.Fatalf("Planning %q failed: %v", sql, err)
+		}
+		return node
+	}
+
+	// run executes a query with the named strategy and returns its columns
+	// and rows as one string
+	run := func(t *testing.T, sql, strategy string, memoryBudget int64) string {
+		t.Helper()
+		node := planSQL(t, sql)
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.Strategy = strategy
+		ctx.Config.MemoryBudget = memoryBudget
+		ctx.Config.TempDir = t.TempDir()
+		result, err := executor.ExecuteWith(node, ctx)
+		if err != nil {
+			t.Fatalf("%s with %s: %v", sql, strategy, err)
+		}
+
+		var out strings.Builder
+		fmt.Fprintln(&out, strings.Join(result.Columns, " | "))
+		for _, row := range result.Rows {
+			values := make([]string, len(result.Columns))
+			for i, col := range result.Columns {
+				values[i] = fmt.Sprint(row.Data[col])
+			}
+			fmt.Fprintln(&out, strings.Join(values, " | "))
+		}
+		return out.String()
+	}
+
+	queries := []string{
+		"SELECT * FROM players WHERE score > 3 AND team_id <> 2",
+		"SELECT id, score FROM players WHERE id BETWEEN 10 AND 40 ORDER BY score DESC, id",
+		"SELECT players.id, teams.name FROM players JOIN teams ON players.team_id = teams.id WHERE players.score < 2",
+		"SELECT players.id, teams.name FROM players LEFT JOIN teams ON players.team_id = teams.id ORDER BY players.id LIMIT 12",
+		"SELECT teams.name, COUNT(*), AVG(players.score) FROM teams FULL JOIN players ON teams.id = players.team_id GROUP BY teams.name ORDER BY teams.name",
+		"SELECT * FROM teams CROSS JOIN players WHERE players.id < 4",
+		"EXPLAIN SELECT * FROM players JOIN teams USING (id)",
+		"SELECT id, team_id FROM players WHERE team_id <> 3 AND 2500 < id",
+		"SELECT * FROM players WHERE team_id IN (1, 4) OR score BETWEEN 2 AND 3 LIMIT 50",
+		"SELECT team_id, COUNT(*), COUNT(team_id), SUM(score), AVG(id), MIN(score), MAX(id) FROM players WHERE id > 20 GROUP BY team_id ORDER BY team_id",
+		"SELECT COUNT(*), SUM(id), SUM(score), MIN(score), MAX(id), MIN(team_id), MAX(team_id) FROM players WHERE team_id = 2 OR score >= 7.5",
+		"SELECT COUNT(*), MIN(name), MAX(name) FROM teams WHERE id > 2",
+		"SELECT COUNT(*), MIN(name) FROM teams WHERE id > 100",
+	}
+
+	for _, sql := range queries {
+		t.Run(sql, func(t *testing.T) {
+			expected := run(t, sql, executor.StrategyIterator, 0)
+			for _, strategy := range executor.StrategyNames() {
+				if got := run(t, sql, strategy, 0); got != expected {
+					t.Errorf("%s returned a different result than %s:\n%s\nexpected:\n%s", strategy, executor.StrategyIterator, got, expected)
+				}
+			}
+		})
+	}
+
+	t.Run("Vectorized aggregation over the memory budget", func(t *testing.T) {
+		// The groups do not fit, so the aggregation restarts on rows and
+		// spills
+		const sql = "SELECT id, COUNT(*), SUM(score) FROM players WHERE team_id <> 5 GROUP BY id"
+		expected := run(t, sql, executor.StrategyIterator, 4<<10)
+		if got := run(t, sql, executor.StrategyVectorized, 4<<10); got != expected {
+			t.Errorf("Vectorized aggregation returned a different result:\n%s\nexpected:\n%s", got, expected)
+		}
+	})
+
+	t.Run("Unknown strategy", func(t *testing.T) {
+		ctx := &executor.ExecutionContext{Database: db, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.Strategy = "quantum"
+		if _, err := executor.ExecuteWith(planSQL(t, "SELECT * FROM teams"), ctx); err == nil {
+			t.Error("Expected an error for an unknown strategy")
+		}
+		if err := executor.SetDefaultStrategy("quantum"); err == nil {
+			t.Error("Expected SetDefaultStrategy to reject an unknown strategy")
+		}
+	})
+
+	t.Run("execution_strategy setting", func(t *testing.T) {
+		eng := engine.New(db, nil)
+		if eng.ExecutionStrategy() != executor.DefaultExecutionConfig().Strategy {
+			t.Errorf("Expected the default strategy, got %s", eng.ExecutionStrategy())
+		}
+		for _, strategy := range executor.StrategyNames() {
+			if _, err := eng.Execute("SET execution_strategy = '" + strings.ToUpper(strategy) + "'"); err != nil {
+				t.Fatalf("SET execution_strategy = %s failed: %v", strategy, err)
+			}
+			if eng.ExecutionStrategy() != strategy {
+				t.Errorf("Expected %s, got %s", strategy, eng.ExecutionStrategy())
+			}
+			result, err := eng.Execute("SELECT name FROM teams WHERE id > 4")
+			if err != nil || len(result.Rows) != 2 {
+				t.Errorf("%s: expected 2 teams, got %v (%v)", strategy, result, err)
+			}
+		}
+		if _, err := eng.Execute("SET execution_strategy = DEFAULT"); err != nil {
+			t.Fatalf("SET execution_strategy = DEFAULT failed: %v", err)
+		}
+		if eng.ExecutionStrategy() != executor.DefaultExecutionConfig().Strategy {
+			t.Errorf("Expected DEFAULT to restore the default strategy, got %s", eng.ExecutionStrategy())
+		}
+		if err := eng.SetExecutionStrategy("quantum"); err == nil {
+			t.Error("Expected an error for an unknown strategy")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/executor"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+)
+
+// TestStreamingExecution tests that rows stream through the executor: LIMIT
+// stops reading its input early, and streamed joins return the same rows as
+// before
+func TestStreamingExecution(t *testing.T) {
+	const eventCount = 10000
+
+	events := &schema.Table{
+		Name: "events",
+		Schema: &schema.TableSchema{TableName: "events", Columns: []schema.Column{
+			{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true, NotNull: true},
+			{Name: "kind", Type: schema.ColumnTypeInt},
+		}},
+		Indexes: make(map[string]*data.Index),
+	}
+	for i := 1; i <= eventCount; i++ {
+		events.Rows = append(events.Rows, data.NewRow(map[string]interface{}{
+			"id": int64(i), "kind": int64(i % 4),
+		}))
+	}
+	kinds := &schema.Table{
+		Name: "kinds",
+		Schema: &schema.TableSchema{TableName: "kinds", Columns: []schema.Column{
+			{Name: "kind", Type: schema.ColumnTypeInt},
+			{Name: "label", Type: schema.ColumnTypeText},
+		}},
+		Indexes: make(map[string]*data.Index),
+		Rows: []data.Row{
+			data.NewRow(map[string]interface{}{"kind": int64(0), "label": "zero"}),
+			data.NewRow(map[string]interface{}{"kind": int64(1), "label": "one"}),
+		},
+	}
+	for _, table := range []*schema.Table{events, kinds} {
+		if err := indexing.BuildIndexes(table); err != nil {
+			t.Fatalf("Failed to build indexes: %v", err)
+		}
+	}
+	db := &schema.Database{Name: "streaming", Tables: map[string]*schema.Table{"events": events, "kinds": kinds}}
+
+	run := func(t *testing.T, node plan.Node, algorithm join.Algorithm) []data.Row {
+		t.Helper()
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+
+		ctx := &executor.ExecutionContext{Database: db, Transaction: tx, Config: executor.DefaultExecutionConfig()}
+		ctx.Config.JoinAlgorithm = string(algorithm)
+		result, err := (&executor.DefaultStrategy{}).Execute(node, ctx)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result.Rows
+	}
+
+	// countReads wraps the predicate of the events scan to count the rows it
+	// reads
+	countReads := func(t *testing.T, node plan.Node) *int {
+		t.Helper()
+		reads := new(int)
+		var wrap func(plan.Node) bool
+		wrap = func(n plan.Node) bool {
+			if scan, ok := n.(*plan.ScanNode); ok && scan.TableName == "events" && scan.Predicate != nil {
+				pred := scan.Predicate
+				scan.Predicate = func(row data.Row) bool {
+					*reads++
+					return pred(row)
+				}
+				return true
+			}
+			for _, child := range n.Children() {
+				if wrap(child) {
+					return true
+				}
+			}
+			return false
+		}
+		if !wrap(node) {
+			t.Fatal("No filtered scan of events in the plan")
+		}
+		return reads
+	}
+
+	t.Run("LIMIT", func(t *testing.T) {
+		if rows := run(t, planQuery(t, db, "SELECT * FROM events LIMIT 5"), ""); len(rows) != 5 {
+			t.Errorf("Expected 5 rows, got %d", len(rows))
+		}
+		if rows := run(t, planQuery(t, db, "SELECT * FROM events LIMIT 0"), ""); len(rows) != 0 {
+			t.Errorf("Expected no rows, got %d", len(rows))
+		}
+		if rows := run(t, planQuery(t, db, "SELECT * FROM kinds LIMIT 10"), ""); len(rows) != 2 {
+			t.Errorf("Expected 2 rows, got %d", len(rows))
+		}
+	})
+
+	t.Run("LIMIT stops the scan", func(t *testing.T) {
+		if executor.DefaultExecutionConfig().Strategy == executor.StrategyVectorized {
+			t.Skip("the vectorized strategy filters whole batches with the compiled WHERE clause, not the row predicate")
+		}
+		reads := 0
+		query := planQuery(t, db, "SELECT * FROM events WHERE kind = 2 LIMIT 3")
+		pred := query.Predicate
+		query.Predicate = func(row data.Row) bool {
+			reads++
+			return pred(row)
+		}
+
+		rows := run(t, query, "")
+		if len(rows) != 3 {
+			t.Fatalf("Expected 3 rows, got %d", len(rows))
+		}
+		// Every fourth event matches; the scan stops at the third match
+		if reads != 10 {
+			t.Errorf("Expected 10 rows read, got %d", reads)
+		}
+	})
+
+	t.Run("LIMIT stops the outer side of a join", func(t *testing.T) {
+		if executor.DefaultExecutionConfig().Strategy == executor.StrategyTreeWalking {
+			t.Skip("the tree-walking strategy reads every node's input completely")
+		}
+		for _, algorithm := range []join.Algorithm{join.AlgorithmHash, join.AlgorithmIndexNestedLoop, join.AlgorithmNestedLoop} {
+			query := planQuery(t, db, "SELECT * FROM events JOIN kinds ON events.kind = kinds.kind WHERE events.kind >= 0 LIMIT 4")
+			joinNode := query.Children()[0].(*plan.JoinNode)
+			if joinNode.Strategy.Inner != join.SideRight {
+				t.Fatalf("Expected kinds to be the inner side, got %s", joinNode.Strategy.Inner)
+			}
+			reads := countReads(t, query)
+
+			rows := run(t, query, algorithm)
+			if len(rows) != 4 {
+				t.Fatalf("%s: expected 4 rows, got %d", algorithm, len(rows))
+			}
+			if *reads >= eventCount/10 {
+				t.Errorf("%s: expected the events scan to stop early, read %d rows", algorithm, *reads)
+			}
+		}
+	})
+
+	t.Run("Outer joins", func(t *testing.T) {
+		tests := []struct {
+			sql  string
+			rows int
+		}{
+			{"SELECT * FROM events JOIN kinds ON events.kind = kinds.kind", eventCount / 2},
+			{"SELECT * FROM events LEFT JOIN kinds ON events.kind = kinds.kind", eventCount},
+			{"SELECT * FROM kinds LEFT JOIN events ON events.kind = kinds.kind AND events.id < 3", 2},
+			{"SELECT * FROM events RIGHT JOIN kinds ON events.kind = kinds.kind AND events.id < 3", 2},
+			{"SELECT * FROM events FULL JOIN kinds ON events.kind = kinds.kind AND events.id <= 4", eventCount},
+		}
+		for _, tt := range tests {
+			query := planQuery(t, db, tt.sql)
+			for _, algorithm := range []join.Algorithm{"", join.AlgorithmHash, join.AlgorithmMerge, join.AlgorithmIndexNestedLoop, join.AlgorithmNestedLoop} {
+				if rows := run(t, query, algorithm); len(rows) != tt.rows {
+					t.Errorf("%s (%s): expected %d rows, got %d", tt.sql, algorithm, tt.rows, len(rows))
+				}
+			}
+		}
+	})
+}
@@ -1,14 +1,24 @@
 package integration
 
 import (
+	"context"
+	"net"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/network"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner"
 	"github.com/leengari/mini-rdbms/internal/query/indexing"
 	"github.com/leengari/mini-rdbms/internal/storage/bootstrap"
 	"github.com/leengari/mini-rdbms/internal/storage/loader"
+	"github.com/leengari/mini-rdbms/internal/storage/manager"
 	"github.com/leengari/mini-rdbms/internal/storage/writer"
 )
 
@@ -56,3 +66,75 @@ func teardownTestDB(t *testing.T, db *schema.Database) {
 		t.Logf("Warning: Failed to remove test database: %v", err)
 	}
 }
+
+// newTable creates an in-memory table holding rows, with its indexes built
+func newTable(t *testing.T, name string, columns []schema.Column, rows ...map[string]interface{}) *schema.Table {
+	t.Helper()
+	table := &schema.Table{
+		Name:    name,
+		Schema:  &schema.TableSchema{TableName: name, Columns: columns},
+		Indexes: make(map[string]*data.Index),
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, data.NewRow(row))
+	}
+	if err := indexing.BuildIndexes(table); err != nil {
+		t.Fatalf("Failed to build indexes: %v", err)
+	}
+	return table
+}
+
+// planSQL tokenizes, parses and plans sql against db
+func planSQL(db *schema.Database, sql string) (plan.Node, error) {
+	tokens, err := lexer.Tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := parser.New(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return planner.Plan(stmt, db, nil)
+}
+
+// planQuery plans a SELECT against db, failing the test if it does not plan
+func planQuery(t *testing.T, db *schema.Database, sql string) *plan.SelectNode {
+	t.Helper()
+	node, err := planSQL(db, sql)
+	if err != nil {
+		t.Fatalf("Planning %q failed: %v", sql, err)
+	}
+	selectNode, ok := node.(*plan.SelectNode)
+	if !ok {
+		t.Fatalf("Expected %q to plan a SELECT, got %T", sql, node)
+	}
+	return selectNode
+}
+
+// startServer serves registry on a free local port and returns its address
+// The server is shut down and the registry closed when the test ends, so
+// nothing is left listening for the next run of the tests.
+func startServer(t *testing.T, registry *manager.Registry) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := network.NewServer(registry)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			t.Logf("Warning: server shutdown: %v", err)
+		}
+		if err := <-served; err != nil {
+			t.Logf("Warning: server stopped: %v", err)
+		}
+		registry.Close()
+	})
+	return listener.Addr().String()
+}
@@ -1,11 +1,14 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"sync"
 
 	"github.com/leengari/mini-rdbms/internal/engine"
 	"github.com/leengari/mini-rdbms/internal/executor"
@@ -14,34 +17,187 @@ import (
 
 type Request struct {
 	Query string `json:"query"`
+	// Cancel stops the query running on the connection instead of starting
+	// one; the cancelled query answers with an error and Cancel gets no reply
+	Cancel bool `json:"cancel,omitempty"`
 }
 
-// Start starts the TCP database server
+// message is a request read from a connection, or the error that ended it
+type message struct {
+	req    Request
+	ctx    context.Context // the query's context, cancelled by a Cancel request
+	cancel context.CancelFunc
+	err    error
+}
+
+// Start starts the TCP database server and serves until it fails
 func Start(port int, registry *manager.Registry) {
+	if err := NewServer(registry).ListenAndServe(port); err != nil {
+		slog.Error("Server stopped", "port", port, "error", err)
+	}
+}
+
+// Server is a TCP database server that can be shut down gracefully
+type Server struct {
+	registry *manager.Registry
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*serverConn]struct{}
+	closing  bool
+	wg       sync.WaitGroup // connection handlers
+}
+
+// serverConn is a connection being served
+type serverConn struct {
+	conn net.Conn
+	// ctx is cancelled when the client disconnects, the handler returns or
+	// the server closes the connection, stopping any query still running
+	ctx        context.Context
+	disconnect context.CancelFunc
+	busy       bool // a query is running
+}
+
+// NewServer creates a server executing queries against registry
+func NewServer(registry *manager.Registry) *Server {
+	return &Server{registry: registry, conns: make(map[*serverConn]struct{})}
+}
+
+// ListenAndServe accepts connections on port until Shutdown is called,
+// returning nil then
+func (s *Server) ListenAndServe(port int) error {
 	addr := fmt.Sprintf(":%d", port)
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		slog.Error("Failed to bind to port", "port", port, "error", err)
-		return
+		return fmt.Errorf("failed to bind to port %d: %w", port, err)
 	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener until Shutdown is called,
+// returning nil then
+// The listener is closed when Serve returns.
+func (s *Server) Serve(listener net.Listener) error {
 	defer listener.Close()
 
-	slog.Info("Running on port", "port", port)
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	slog.Info("Running on address", "address", listener.Addr().String())
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
 			slog.Error("Failed to accept connection", "error", err)
 			continue
 		}
-		go handleConnection(conn, registry)
+
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		c := &serverConn{conn: conn}
+		c.ctx, c.disconnect = context.WithCancel(context.Background())
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handleConnection(c)
+		}()
 	}
 }
 
-func handleConnection(conn net.Conn, registry *manager.Registry) {
-	defer conn.Close()
+// Shutdown stops accepting connections, closes idle ones and waits for the
+// running queries to finish and answer
+// When ctx ends first, the queries still running are cancelled and their
+// connections closed, and ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	idle, busy := 0, 0
+	for c := range s.conns {
+		if c.busy {
+			busy++
+			continue
+		}
+		idle++
+		c.disconnect()
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	slog.Info("Server shutting down", "idle_connections", idle, "running_queries", busy)
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.disconnect()
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	<-drained
+	return ctx.Err()
+}
+
+// begin marks a connection busy before it runs a query; false when the
+// server is shutting down and the query must not start
+func (s *Server) begin(c *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	c.busy = true
+	return true
+}
 
-	dbEngine := engine.New(nil, registry)
+// end marks a connection idle after it answered a query; false when the
+// server is shutting down and the connection must close
+func (s *Server) end(c *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.busy = false
+	return !s.closing
+}
+
+func (s *Server) handleConnection(c *serverConn) {
+	conn := c.conn
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	defer c.disconnect()
+
+	dbEngine := engine.New(nil, s.registry)
 	
 	// Register logging observer for lifecycle tracing
 	loggingObserver := engine.NewLoggingObserver()
@@ -51,42 +207,89 @@ func handleConnection(conn net.Conn, registry *manager.Registry) {
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
 
-	for {
-		var req Request
-		// Decode directly from the connection
-		if err := decoder.Decode(&req); err != nil {
-			if err == io.EOF {
-				return // Connection closed gracefully
-			}
-			slog.Error("decode error", "error", err)
+	// Requests are read on their own goroutine so a Cancel request or a
+	// disconnect is seen while a query runs
+	messages := make(chan message)
+	done := make(chan struct{})
+	defer close(done)
+	go readRequests(c.ctx, c.disconnect, decoder, messages, done)
+
+	for msg := range messages {
+		if msg.err != nil {
+			slog.Error("decode error", "error", msg.err)
 			
 			// Send error back to client
 			errResult := &executor.Result{
-				Error: fmt.Sprintf("Invalid request format: %v", err),
+				Error: fmt.Sprintf("Invalid request format: %v", msg.err),
 			}
 			_ = encoder.Encode(errResult)
 			return
 		}
 
-		if req.Query == "exit" || req.Query == "\\q" {
+		if msg.req.Query == "exit" || msg.req.Query == "\\q" {
 			return
 		}
 
-		result, err := dbEngine.Execute(req.Query)
+		if !s.begin(c) {
+			msg.cancel()
+			return
+		}
+		result, err := dbEngine.ExecuteContext(msg.ctx, msg.req.Query)
+		msg.cancel()
 		if err != nil {
 			// Return error as a Result object
-			errResult := &executor.Result{
+			result = &executor.Result{
 				Error: err.Error(),
 			}
-			if err := encoder.Encode(errResult); err != nil {
-				slog.Error("encode error", "error", err)
-				return
+		}
+		if err := encoder.Encode(result); err != nil {
+			slog.Error("encode error", "error", err)
+			s.end(c)
+			return
+		}
+		if !s.end(c) {
+			return
+		}
+	}
+}
+
+// readRequests decodes requests from the connection and passes each query on
+// with a context of its own until done is closed
+// Cancel requests cancel the most recent query. When reading fails - the
+// client disconnected or sent something that isn't a request - connCtx is
+// cancelled and messages is closed, after passing on any error but io.EOF.
+func readRequests(connCtx context.Context, disconnect context.CancelFunc, decoder *json.Decoder, messages chan<- message, done <-chan struct{}) {
+	defer close(messages)
+
+	cancelQuery := context.CancelFunc(func() {})
+	for {
+		var req Request
+		// Decode directly from the connection
+		if err := decoder.Decode(&req); err != nil {
+			disconnect()
+			if err != io.EOF {
+				select {
+				case messages <- message{err: err}:
+				case <-done:
+				}
 			}
+			return
+		}
+
+		if req.Cancel {
+			slog.Info("Cancelling query on client request")
+			cancelQuery()
 			continue
 		}
 
-		if err := encoder.Encode(result); err != nil {
-			slog.Error("encode error", "error", err)
+		var msg message
+		msg.req = req
+		msg.ctx, msg.cancel = context.WithCancel(connCtx)
+		cancelQuery = msg.cancel
+		select {
+		case messages <- msg:
+		case <-done:
+			msg.cancel()
 			return
 		}
 	}
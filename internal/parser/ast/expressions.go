@@ -1,20 +1,27 @@
 package ast
 
+import "strings"
+
 // Identifier represents a column or table name
 // Can be qualified (table.column) or unqualified (column)
 type Identifier struct {
 	TokenLiteralValue string // The token literal (e.g. "users" or "users.id")
 	Value             string // The column/table name (e.g. "users" or "id")
 	Table             string // Optional table qualifier (e.g. "users" in "users.id")
+	Aggregate         string // Aggregate applied in a SELECT list (COUNT, SUM, AVG, MIN, MAX); Value is "*" for COUNT(*)
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.TokenLiteralValue }
 func (i *Identifier) String() string {
+	name := i.Value
 	if i.Table != "" {
-		return i.Table + "." + i.Value
+		name = i.Table + "." + i.Value
+	}
+	if i.Aggregate != "" {
+		return strings.ToLower(i.Aggregate) + "(" + name + ")"
 	}
-	return i.Value
+	return name
 }
 
 // LiteralKind represents the type of a literal value
@@ -1,6 +1,9 @@
 package ast
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // BinaryExpression: Left Operator Right (e.g. id = 1)
 type BinaryExpression struct {
@@ -29,3 +32,32 @@ func (e *LogicalExpression) TokenLiteral() string { return e.Operator }
 func (e *LogicalExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", e.Left.String(), e.Operator, e.Right.String())
 }
+
+// InExpression: Left IN (v1, v2, ...) (e.g. status IN ('new', 'paid'))
+type InExpression struct {
+	Left   Expression
+	Values []Expression
+}
+
+func (e *InExpression) expressionNode()      {}
+func (e *InExpression) TokenLiteral() string { return "IN" }
+func (e *InExpression) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.String()
+	}
+	return fmt.Sprintf("(%s IN (%s))", e.Left.String(), strings.Join(values, ", "))
+}
+
+// BetweenExpression: Left BETWEEN Lower AND Upper (inclusive on both ends)
+type BetweenExpression struct {
+	Left  Expression
+	Lower Expression
+	Upper Expression
+}
+
+func (e *BetweenExpression) expressionNode()      {}
+func (e *BetweenExpression) TokenLiteral() string { return "BETWEEN" }
+func (e *BetweenExpression) String() string {
+	return fmt.Sprintf("(%s BETWEEN %s AND %s)", e.Left.String(), e.Lower.String(), e.Upper.String())
+}
@@ -1,14 +1,33 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"fmt"
+)
 
-// SelectStatement: SELECT fields FROM table [JOIN ...] [WHERE condition]
-// Represents a SELECT SQL query with optional JOINs and WHERE clause
+// SelectStatement: SELECT fields FROM table [, table | JOIN ...] [WHERE condition] [LIMIT n]
+// Represents a SELECT SQL query with optional JOINs, WHERE and LIMIT clauses
 type SelectStatement struct {
 	Fields    []*Identifier
 	TableName *Identifier
-	Joins     []*JoinClause // Optional JOIN clauses
-	Where     Expression    // Optional WHERE clause
+	Joins     []*JoinClause  // Optional JOIN clauses
+	Where     Expression     // Optional WHERE clause
+	GroupBy   []*Identifier  // Optional GROUP BY columns
+	OrderBy   []*OrderByItem // Optional ORDER BY keys
+	Limit     *int           // Optional LIMIT row count
+}
+
+// OrderByItem is one ORDER BY key: a column or aggregate and its direction
+type OrderByItem struct {
+	Column *Identifier
+	Desc   bool
+}
+
+func (o *OrderByItem) String() string {
+	if o.Desc {
+		return o.Column.String() + " DESC"
+	}
+	return o.Column.String()
 }
 
 func (s *SelectStatement) statementNode()       {}
@@ -35,32 +54,72 @@ func (s *SelectStatement) String() string {
 		out.WriteString(" WHERE ")
 		out.WriteString(s.Where.String())
 	}
+	if len(s.GroupBy) > 0 {
+		out.WriteString(" GROUP BY ")
+		for i, col := range s.GroupBy {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(col.String())
+		}
+	}
+	if len(s.OrderBy) > 0 {
+		out.WriteString(" ORDER BY ")
+		for i, item := range s.OrderBy {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(item.String())
+		}
+	}
+	if s.Limit != nil {
+		out.WriteString(fmt.Sprintf(" LIMIT %d", *s.Limit))
+	}
 	return out.String()
 }
 
 // JoinClause represents a JOIN operation in a SELECT statement
 // Example: INNER JOIN orders ON users.id = orders.user_id
+// A CROSS JOIN (also written as a comma in FROM) has no condition.
 type JoinClause struct {
-	JoinType    string      // "INNER", "LEFT", "RIGHT", "FULL"
-	RightTable  *Identifier // Table to join with
-	OnCondition Expression  // JOIN condition (e.g., users.id = orders.user_id)
+	JoinType    string        // "INNER", "LEFT", "RIGHT", "FULL", "CROSS"
+	RightTable  *Identifier   // Table to join with
+	OnCondition Expression    // JOIN condition (e.g., users.id = orders.user_id)
+	Using       []*Identifier // USING (col, ...) columns
+	Natural     bool          // NATURAL JOIN: USING every common column
 }
 
 func (j *JoinClause) String() string {
 	var out bytes.Buffer
+	if j.Natural {
+		out.WriteString("NATURAL ")
+	}
 	out.WriteString(j.JoinType)
 	out.WriteString(" JOIN ")
 	out.WriteString(j.RightTable.String())
-	out.WriteString(" ON ")
-	out.WriteString(j.OnCondition.String())
+	switch {
+	case j.OnCondition != nil:
+		out.WriteString(" ON ")
+		out.WriteString(j.OnCondition.String())
+	case len(j.Using) > 0:
+		out.WriteString(" USING (")
+		for i, col := range j.Using {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(col.String())
+		}
+		out.WriteString(")")
+	}
 	return out.String()
 }
 
-// InsertStatement: INSERT INTO table (col1, col2) VALUES (val1, val2)
+// InsertStatement: INSERT INTO table (col1, col2) VALUES (val1, val2) [, (val1, val2) ...]
+// Each entry of Rows holds the values of one row, in the order of Columns.
 type InsertStatement struct {
 	TableName *Identifier
 	Columns   []*Identifier
-	Values    []Expression
+	Rows      [][]Expression
 }
 
 func (s *InsertStatement) statementNode()       {}
@@ -76,14 +135,20 @@ func (s *InsertStatement) String() string {
 			out.WriteString(", ")
 		}
 	}
-	out.WriteString(") VALUES (")
-	for i, v := range s.Values {
-		out.WriteString(v.String())
-		if i < len(s.Values)-1 {
+	out.WriteString(") VALUES ")
+	for r, values := range s.Rows {
+		if r > 0 {
 			out.WriteString(", ")
 		}
+		out.WriteString("(")
+		for i, v := range values {
+			out.WriteString(v.String())
+			if i < len(values)-1 {
+				out.WriteString(", ")
+			}
+		}
+		out.WriteString(")")
 	}
-	out.WriteString(")")
 	return out.String()
 }
 
@@ -145,14 +210,18 @@ func (s *DeleteStatement) String() string {
 	return out.String()
 }
 
-// CreateDatabaseStatement: CREATE DATABASE name
+// CreateDatabaseStatement: CREATE DATABASE name [STORAGE = format]
 type CreateDatabaseStatement struct {
-	Name string
+	Name    string
+	Storage string // storage format, "" for the server default
 }
 
 func (s *CreateDatabaseStatement) statementNode()       {}
 func (s *CreateDatabaseStatement) TokenLiteral() string { return "CREATE" }
 func (s *CreateDatabaseStatement) String() string {
+	if s.Storage != "" {
+		return "CREATE DATABASE " + s.Name + " STORAGE = " + s.Storage
+	}
 	return "CREATE DATABASE " + s.Name
 }
 
@@ -168,14 +237,19 @@ func (s *DropDatabaseStatement) String() string {
 }
 
 // AlterDatabaseStatement: ALTER DATABASE name RENAME TO newName
+// or ALTER DATABASE name SET STORAGE = format
 type AlterDatabaseStatement struct {
 	Name    string
 	NewName string
+	Storage string // set instead of NewName when converting the storage format
 }
 
 func (s *AlterDatabaseStatement) statementNode()       {}
 func (s *AlterDatabaseStatement) TokenLiteral() string { return "ALTER" }
 func (s *AlterDatabaseStatement) String() string {
+	if s.Storage != "" {
+		return "ALTER DATABASE " + s.Name + " SET STORAGE = " + s.Storage
+	}
 	return "ALTER DATABASE " + s.Name + " RENAME TO " + s.NewName
 }
 
@@ -189,3 +263,251 @@ func (s *UseDatabaseStatement) TokenLiteral() string { return "USE" }
 func (s *UseDatabaseStatement) String() string {
 	return "USE " + s.Name
 }
+
+// BackupDatabaseStatement: BACKUP DATABASE name TO 'path'
+type BackupDatabaseStatement struct {
+	Name string
+	Path string // directory the backup is written to
+}
+
+func (s *BackupDatabaseStatement) statementNode()       {}
+func (s *BackupDatabaseStatement) TokenLiteral() string { return "BACKUP" }
+func (s *BackupDatabaseStatement) String() string {
+	return "BACKUP DATABASE " + s.Name + " TO '" + s.Path + "'"
+}
+
+// RestoreDatabaseStatement: RESTORE DATABASE name FROM 'path'
+// Creates the database name from the backup in Path
+type RestoreDatabaseStatement struct {
+	Name string
+	Path string
+}
+
+func (s *RestoreDatabaseStatement) statementNode()       {}
+func (s *RestoreDatabaseStatement) TokenLiteral() string { return "RESTORE" }
+func (s *RestoreDatabaseStatement) String() string {
+	return "RESTORE DATABASE " + s.Name + " FROM '" + s.Path + "'"
+}
+
+// CheckDatabaseStatement: CHECK DATABASE name [REPAIR]
+// Verifies the files of the database, applying the safe fixes with REPAIR
+type CheckDatabaseStatement struct {
+	Name   string
+	Repair bool
+}
+
+func (s *CheckDatabaseStatement) statementNode()       {}
+func (s *CheckDatabaseStatement) TokenLiteral() string { return "CHECK" }
+func (s *CheckDatabaseStatement) String() string {
+	if s.Repair {
+		return "CHECK DATABASE " + s.Name + " REPAIR"
+	}
+	return "CHECK DATABASE " + s.Name
+}
+
+// CreateIndexStatement: CREATE [UNIQUE] INDEX name ON table (column) [USING BTREE|HASH]
+type CreateIndexStatement struct {
+	Name      string
+	TableName *Identifier
+	Column    *Identifier
+	Using     string // BTREE or HASH, empty for the default
+	Unique    bool
+}
+
+func (s *CreateIndexStatement) statementNode()       {}
+func (s *CreateIndexStatement) TokenLiteral() string { return "CREATE" }
+func (s *CreateIndexStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("CREATE ")
+	if s.Unique {
+		out.WriteString("UNIQUE ")
+	}
+	out.WriteString("INDEX " + s.Name + " ON ")
+	out.WriteString(s.TableName.String())
+	out.WriteString(" (" + s.Column.String() + ")")
+	if s.Using != "" {
+		out.WriteString(" USING " + s.Using)
+	}
+	return out.String()
+}
+
+// CreateTableStatement: CREATE TABLE name (column TYPE [constraints], ...)
+type CreateTableStatement struct {
+	Name    *Identifier
+	Columns []ColumnDefinition
+}
+
+// ColumnDefinition is one column of CREATE TABLE
+// Type is the column type as written in upper case, such as INT or EMAIL.
+type ColumnDefinition struct {
+	Name          string
+	Type          string
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+	NotNull       bool
+}
+
+func (s *CreateTableStatement) statementNode()       {}
+func (s *CreateTableStatement) TokenLiteral() string { return "CREATE" }
+func (s *CreateTableStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("CREATE TABLE " + s.Name.String() + " (")
+	for i, col := range s.Columns {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(col.String())
+	}
+	out.WriteString(")")
+	return out.String()
+}
+
+// String returns the column definition as written in CREATE TABLE
+func (c ColumnDefinition) String() string {
+	def := c.Name + " " + c.Type
+	if c.PrimaryKey {
+		def += " PRIMARY KEY"
+	}
+	if c.AutoIncrement {
+		def += " AUTO_INCREMENT"
+	}
+	if c.Unique {
+		def += " UNIQUE"
+	}
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// AlterTableStatement: ALTER TABLE name AUTO_INCREMENT = value
+// AutoIncrement is the next value the table's auto-increment key takes.
+type AlterTableStatement struct {
+	Name          *Identifier
+	AutoIncrement int64
+}
+
+func (s *AlterTableStatement) statementNode()       {}
+func (s *AlterTableStatement) TokenLiteral() string { return "ALTER" }
+func (s *AlterTableStatement) String() string {
+	return fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", s.Name.String(), s.AutoIncrement)
+}
+
+// SourceStatement: SOURCE 'path'
+// Runs the SQL script in the file at Path as one unit
+type SourceStatement struct {
+	Path string
+}
+
+func (s *SourceStatement) statementNode()       {}
+func (s *SourceStatement) TokenLiteral() string { return "SOURCE" }
+func (s *SourceStatement) String() string {
+	return "SOURCE '" + s.Path + "'"
+}
+
+// CopyStatement: COPY table [(columns)] FROM 'path' [WITH (option, ...)],
+// COPY table [(columns)] TO 'path' [WITH (option, ...)] or
+// COPY (query) TO 'path' [WITH (option, ...)]
+// Copies rows between a table or query and a CSV or JSON Lines file. Format
+// is "CSV" or "JSONL"; Header, Delimiter and Null apply to CSV.
+type CopyStatement struct {
+	TableName *Identifier      // nil when copying a query
+	Columns   []*Identifier    // optional column list of a table
+	Query     *SelectStatement // nil when copying a table
+	To        bool             // TO a file rather than FROM one
+	Path      string
+	Format    string
+	Header    bool
+	Delimiter string
+	Null      string
+}
+
+func (s *CopyStatement) statementNode()       {}
+func (s *CopyStatement) TokenLiteral() string { return "COPY" }
+func (s *CopyStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("COPY ")
+	if s.Query != nil {
+		out.WriteString("(" + s.Query.String() + ")")
+	} else {
+		out.WriteString(s.TableName.String())
+		if len(s.Columns) > 0 {
+			out.WriteString(" (")
+			for i, c := range s.Columns {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				out.WriteString(c.String())
+			}
+			out.WriteString(")")
+		}
+	}
+	if s.To {
+		out.WriteString(" TO '" + s.Path + "'")
+	} else {
+		out.WriteString(" FROM '" + s.Path + "'")
+	}
+	out.WriteString(" WITH (FORMAT " + s.Format)
+	if s.Header {
+		out.WriteString(", HEADER")
+	}
+	if s.Format == "CSV" {
+		out.WriteString(fmt.Sprintf(", DELIMITER '%s', NULL '%s'", s.Delimiter, s.Null))
+	}
+	out.WriteString(")")
+	return out.String()
+}
+
+// AnalyzeStatement: ANALYZE [table]
+// Collects planner statistics for one table, or every table if TableName is nil
+type AnalyzeStatement struct {
+	TableName *Identifier
+}
+
+func (s *AnalyzeStatement) statementNode()       {}
+func (s *AnalyzeStatement) TokenLiteral() string { return "ANALYZE" }
+func (s *AnalyzeStatement) String() string {
+	if s.TableName == nil {
+		return "ANALYZE"
+	}
+	return "ANALYZE " + s.TableName.String()
+}
+
+// SetStatement: SET name = value
+// Changes a session setting such as statement_timeout; Value is the literal
+// as written
+type SetStatement struct {
+	Name  string
+	Value string
+}
+
+func (s *SetStatement) statementNode()       {}
+func (s *SetStatement) TokenLiteral() string { return "SET" }
+func (s *SetStatement) String() string {
+	return fmt.Sprintf("SET %s = '%s'", s.Name, s.Value)
+}
+
+// ExplainStatement: EXPLAIN [ANALYZE] [(option, ...)] statement
+// Shows the plan of Statement; with Analyze the statement is also run and
+// every plan node reports what it actually did. Format is "TEXT" or "JSON".
+type ExplainStatement struct {
+	Statement Statement
+	Analyze   bool
+	Format    string
+}
+
+func (s *ExplainStatement) statementNode()       {}
+func (s *ExplainStatement) TokenLiteral() string { return "EXPLAIN" }
+func (s *ExplainStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("EXPLAIN ")
+	if s.Analyze {
+		out.WriteString("ANALYZE ")
+	}
+	if s.Format != "" && s.Format != "TEXT" {
+		out.WriteString("(FORMAT " + s.Format + ") ")
+	}
+	out.WriteString(s.Statement.String())
+	return out.String()
+}
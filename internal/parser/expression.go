@@ -60,7 +60,7 @@ func (p *Parser) parseAndExpression() (ast.Expression, error) {
 }
 
 // parseComparisonExpression handles comparison operations (highest precedence)
-// Supports: =, <, >, <=, >=, !=, <>
+// Supports: =, <, >, <=, >=, !=, <>, IN (...), BETWEEN ... AND ...
 // Also handles parenthesized expressions for grouping
 func (p *Parser) parseComparisonExpression() (ast.Expression, error) {
 	// Handle parentheses for grouping
@@ -94,5 +94,63 @@ func (p *Parser) parseComparisonExpression() (ast.Expression, error) {
 		return &ast.BinaryExpression{Left: left, Operator: op, Right: right}, nil
 	}
 
+	switch p.curTok.Type {
+	case lexer.IN:
+		return p.parseInExpression(left)
+	case lexer.BETWEEN:
+		return p.parseBetweenExpression(left)
+	}
+
 	return left, nil
 }
+
+// parseInExpression parses the value list of left IN (v1, v2, ...)
+// Current token is IN
+func (p *Parser) parseInExpression(left ast.Expression) (ast.Expression, error) {
+	p.nextToken()
+	if p.curTok.Type != lexer.PAREN_OPEN {
+		return nil, fmt.Errorf("expected ( after IN, got %s", p.curTok.Literal)
+	}
+	p.nextToken()
+
+	expr := &ast.InExpression{Left: left}
+	for {
+		value, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		expr.Values = append(expr.Values, value)
+
+		if p.curTok.Type == lexer.COMMA {
+			p.nextToken()
+			continue
+		}
+		if p.curTok.Type != lexer.PAREN_CLOSE {
+			return nil, fmt.Errorf("expected , or ) in IN list, got %s", p.curTok.Literal)
+		}
+		p.nextToken()
+		return expr, nil
+	}
+}
+
+// parseBetweenExpression parses left BETWEEN lower AND upper
+// Current token is BETWEEN. The AND here belongs to BETWEEN, not to a logical expression.
+func (p *Parser) parseBetweenExpression(left ast.Expression) (ast.Expression, error) {
+	p.nextToken()
+	lower, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.curTok.Type != lexer.AND {
+		return nil, fmt.Errorf("expected AND in BETWEEN, got %s", p.curTok.Literal)
+	}
+	p.nextToken()
+
+	upper, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.BetweenExpression{Left: left, Lower: lower, Upper: upper}, nil
+}
@@ -38,10 +38,20 @@ const (
 	RIGHT
 	FULL
 	OUTER
+	CROSS
+	NATURAL
 	ON
 	DATE
 	TIME
 	EMAIL
+	IN
+	BETWEEN
+	LIMIT
+	ORDER
+	GROUP
+	BY
+	ASC
+	DESC
 
 	// DDL & Database Management
 	CREATE
@@ -51,6 +61,13 @@ const (
 	USE
 	RENAME
 	TO
+	INDEX
+	USING
+	UNIQUE
+	ANALYZE
+	EXPLAIN
+	BACKUP
+	RESTORE
 
 	// Operators & Punctuation
 	ASTERISK    // *
@@ -87,10 +104,20 @@ var keywords = map[string]TokenType{
 	"RIGHT":  RIGHT,
 	"FULL":   FULL,
 	"OUTER":  OUTER,
+	"CROSS":  CROSS,
+	"NATURAL": NATURAL,
 	"ON":     ON,
 	"DATE":   DATE,
 	"TIME":   TIME,
 	"EMAIL":  EMAIL,
+	"IN":     IN,
+	"BETWEEN": BETWEEN,
+	"LIMIT":  LIMIT,
+	"ORDER":  ORDER,
+	"GROUP":  GROUP,
+	"BY":     BY,
+	"ASC":    ASC,
+	"DESC":   DESC,
 	"CREATE": CREATE,
 	"DROP":   DROP,
 	"ALTER":  ALTER,
@@ -98,6 +125,13 @@ var keywords = map[string]TokenType{
 	"USE":    USE,
 	"RENAME": RENAME,
 	"TO":     TO,
+	"INDEX":  INDEX,
+	"USING":  USING,
+	"UNIQUE": UNIQUE,
+	"ANALYZE": ANALYZE,
+	"EXPLAIN": EXPLAIN,
+	"BACKUP": BACKUP,
+	"RESTORE": RESTORE,
 }
 
 type Token struct {
@@ -207,6 +241,15 @@ func (l *Lexer) NextToken() Token {
 		tok.Type = STRING
 		tok.Literal = l.readString()
 		return tok
+	case '-':
+		// A minus sign only starts a negative number
+		if isDigit(l.peekChar()) {
+			l.readChar()
+			tok.Type = NUMBER
+			tok.Literal = "-" + l.readNumber()
+			return tok
+		}
+		tok = newToken(ILLEGAL, l.ch, l.line, l.column)
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
@@ -228,13 +271,24 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
+// skipWhitespace skips whitespace and -- comments, which run to the end of
+// the line
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		if l.ch == '\n' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '\n':
+			l.readChar()
 			l.line++
-			l.column = 0
+			l.column = 1
+		case l.ch == '-' && l.peekChar() == '-':
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		default:
+			return
 		}
-		l.readChar()
 	}
 }
 
@@ -261,22 +315,34 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
+// readString reads a quoted string, in which a doubled quote ('') stands for
+// one quote
 func (l *Lexer) readString() string {
-	position := l.position + 1
+	var lit strings.Builder
 	for {
 		l.readChar()
-		if l.ch == '\'' || l.ch == 0 {
+		if l.ch == 0 {
 			break
 		}
+		if l.ch == '\'' {
+			if l.peekChar() != '\'' {
+				break
+			}
+			l.readChar()
+		}
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
+		}
+		lit.WriteByte(l.ch)
 	}
-	lit := l.input[position:l.position]
-	
+
 	// Consume the closing quote
 	if l.ch == '\'' {
 		l.readChar()
 	}
-	
-	return lit
+
+	return lit.String()
 }
 
 func newToken(tokenType TokenType, ch byte, line, col int) Token {
@@ -55,3 +55,51 @@ INSERT INTO items (name, price) VALUES ('apple', 1.23);`
 		}
 	}
 }
+
+func TestScriptTokens(t *testing.T) {
+	input := `-- a dump header
+INSERT INTO t (a, b) VALUES ('it''s', -12.5); -- trailing
+SELECT 'two
+lines' FROM t`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{INSERT, "INSERT", 2},
+		{INTO, "INTO", 2},
+		{IDENTIFIER, "t", 2},
+		{PAREN_OPEN, "(", 2},
+		{IDENTIFIER, "a", 2},
+		{COMMA, ",", 2},
+		{IDENTIFIER, "b", 2},
+		{PAREN_CLOSE, ")", 2},
+		{VALUES, "VALUES", 2},
+		{PAREN_OPEN, "(", 2},
+		{STRING, "it's", 2},
+		{COMMA, ",", 2},
+		{NUMBER, "-12.5", 2},
+		{PAREN_CLOSE, ")", 2},
+		{SEMICOLON, ";", 2},
+		{SELECT, "SELECT", 3},
+		{STRING, "two\nlines", 3},
+		{FROM, "FROM", 4},
+		{IDENTIFIER, "t", 4},
+		{EOF, "", 4},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %d %q, got %d %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d", i, tt.expectedLine, tok.Line)
+		}
+	}
+}
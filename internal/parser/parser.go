@@ -2,6 +2,7 @@
 
 	import (
 		"fmt"
+		"strings"
 
 		"github.com/leengari/mini-rdbms/internal/parser/ast"
 		"github.com/leengari/mini-rdbms/internal/parser/lexer"
@@ -56,9 +57,26 @@
 			return p.parseAlter()
 		case lexer.USE:
 			return p.parseUse()
-		default:
-			return nil, fmt.Errorf("unexpected token %v, expected a valid SQL statement (SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, USE)", p.curTok.Type)
+		case lexer.ANALYZE:
+			return p.parseAnalyze()
+		case lexer.SET:
+			return p.parseSet()
+		case lexer.EXPLAIN:
+			return p.parseExplain()
+		case lexer.BACKUP, lexer.RESTORE:
+			return p.parseBackup()
+		case lexer.IDENTIFIER:
+			if strings.EqualFold(p.curTok.Literal, "SOURCE") {
+				return p.parseSource()
+			}
+			if strings.EqualFold(p.curTok.Literal, "COPY") {
+				return p.parseCopy()
+			}
+			if strings.EqualFold(p.curTok.Literal, "CHECK") {
+				return p.parseCheck()
+			}
 		}
+		return nil, fmt.Errorf("unexpected token %v, expected a valid SQL statement (SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, USE, ANALYZE, SET, EXPLAIN, BACKUP, RESTORE, SOURCE, COPY, CHECK)", p.curTok.Type)
 	}
 
 	// expectPeek checks if the next token is of 	the expected type
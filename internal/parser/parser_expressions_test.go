@@ -219,13 +219,13 @@ func TestParseTypedLiterals(t *testing.T) {
 					t.Fatalf("Expected Literal on right side, got %T", binExpr.Right)
 				}
 			case *ast.InsertStatement:
-				if len(s.Values) < 2 {
+				if len(s.Rows[0]) < 2 {
 					t.Fatal("Expected at least 2 values")
 				}
 				var ok bool
-				lit, ok = s.Values[1].(*ast.Literal)
+				lit, ok = s.Rows[0][1].(*ast.Literal)
 				if !ok {
-					t.Fatalf("Expected Literal, got %T", s.Values[1])
+					t.Fatalf("Expected Literal, got %T", s.Rows[0][1])
 				}
 			default:
 				t.Fatalf("Unexpected statement type: %T", stmt)
@@ -241,3 +241,62 @@ func TestParseTypedLiterals(t *testing.T) {
 		})
 	}
 }
+
+// TestParseInAndBetween tests parsing of IN lists and BETWEEN ranges
+func TestParseInAndBetween(t *testing.T) {
+	t.Run("IN list", func(t *testing.T) {
+		tokens, err := lexer.Tokenize("SELECT * FROM users WHERE id IN (1, 2, 3) AND active = true;")
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parser error: %v", err)
+		}
+
+		logical, ok := stmt.(*ast.SelectStatement).Where.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("Expected LogicalExpression, got %T", stmt.(*ast.SelectStatement).Where)
+		}
+		in, ok := logical.Left.(*ast.InExpression)
+		if !ok {
+			t.Fatalf("Expected InExpression on the left, got %T", logical.Left)
+		}
+		if len(in.Values) != 3 {
+			t.Errorf("Expected 3 IN values, got %d", len(in.Values))
+		}
+	})
+
+	t.Run("BETWEEN followed by AND", func(t *testing.T) {
+		tokens, err := lexer.Tokenize("SELECT * FROM orders WHERE amount BETWEEN 10 AND 20 AND user_id = 1")
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parser error: %v", err)
+		}
+
+		logical, ok := stmt.(*ast.SelectStatement).Where.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("Expected LogicalExpression, got %T", stmt.(*ast.SelectStatement).Where)
+		}
+		between, ok := logical.Left.(*ast.BetweenExpression)
+		if !ok {
+			t.Fatalf("Expected BetweenExpression on the left, got %T", logical.Left)
+		}
+		if between.Lower.(*ast.Literal).Value != 10 || between.Upper.(*ast.Literal).Value != 20 {
+			t.Errorf("Expected BETWEEN 10 AND 20, got %s", between)
+		}
+	})
+
+	t.Run("unterminated IN list", func(t *testing.T) {
+		tokens, err := lexer.Tokenize("SELECT * FROM users WHERE id IN (1, 2")
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Error("Expected error for unterminated IN list")
+		}
+	})
+}
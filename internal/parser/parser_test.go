@@ -88,18 +88,18 @@ func TestParseInsert(t *testing.T) {
 		t.Errorf("Expected col 0 to be name, got %s", ins.Columns[0].Value)
 	}
 
-	if len(ins.Values) != 2 {
-		t.Fatalf("Expected 2 values, got %d", len(ins.Values))
+	if len(ins.Rows[0]) != 2 {
+		t.Fatalf("Expected 2 values, got %d", len(ins.Rows[0]))
 	}
 	
-	val1, ok := ins.Values[0].(*ast.Literal)
+	val1, ok := ins.Rows[0][0].(*ast.Literal)
 	if !ok || val1.Value != "apple" {
-		t.Errorf("Expected value 0 to be 'apple', got %v", ins.Values[0])
+		t.Errorf("Expected value 0 to be 'apple', got %v", ins.Rows[0][0])
 	}
 
-	val2, ok := ins.Values[1].(*ast.Literal)
+	val2, ok := ins.Rows[0][1].(*ast.Literal)
 	if !ok || val2.Value != 1.23 {
-		t.Errorf("Expected value 1 to be 1.23, got %v", ins.Values[1])
+		t.Errorf("Expected value 1 to be 1.23, got %v", ins.Rows[0][1])
 	}
 }
 
@@ -228,3 +228,591 @@ func TestParseDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCreateIndex(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedName  string
+		expectedTable string
+		expectedCol   string
+		expectedUsing string
+		unique        bool
+	}{
+		{
+			name:          "CREATE INDEX with USING BTREE",
+			input:         "CREATE INDEX idx_created ON orders (created_at) USING BTREE;",
+			expectedName:  "idx_created",
+			expectedTable: "orders",
+			expectedCol:   "created_at",
+			expectedUsing: "BTREE",
+		},
+		{
+			name:          "CREATE UNIQUE INDEX with lowercase USING hash",
+			input:         "CREATE UNIQUE INDEX idx_email ON users (email) USING hash",
+			expectedName:  "idx_email",
+			expectedTable: "users",
+			expectedCol:   "email",
+			expectedUsing: "HASH",
+			unique:        true,
+		},
+		{
+			name:          "CREATE INDEX without USING",
+			input:         "CREATE INDEX idx_name ON users (name)",
+			expectedName:  "idx_name",
+			expectedTable: "users",
+			expectedCol:   "name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexer.Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Lexer error: %v", err)
+			}
+
+			p := New(tokens)
+			stmt, err := p.Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			ci, ok := stmt.(*ast.CreateIndexStatement)
+			if !ok {
+				t.Fatalf("Expected CreateIndexStatement, got %T", stmt)
+			}
+
+			if ci.Name != tt.expectedName {
+				t.Errorf("Expected index name %s, got %s", tt.expectedName, ci.Name)
+			}
+			if ci.TableName.Value != tt.expectedTable {
+				t.Errorf("Expected table %s, got %s", tt.expectedTable, ci.TableName.Value)
+			}
+			if ci.Column.Value != tt.expectedCol {
+				t.Errorf("Expected column %s, got %s", tt.expectedCol, ci.Column.Value)
+			}
+			if ci.Using != tt.expectedUsing {
+				t.Errorf("Expected USING %q, got %q", tt.expectedUsing, ci.Using)
+			}
+			if ci.Unique != tt.unique {
+				t.Errorf("Expected unique=%v, got %v", tt.unique, ci.Unique)
+			}
+		})
+	}
+
+	t.Run("unsupported index type", func(t *testing.T) {
+		tokens, err := lexer.Tokenize("CREATE INDEX idx ON users (id) USING GIST")
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Error("Expected error for unsupported index type")
+		}
+	})
+}
+
+func TestParseAnalyze(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedTable string
+	}{
+		{"ANALYZE users;", "users"},
+		{"analyze orders", "orders"},
+		{"ANALYZE;", ""},
+		{"ANALYZE", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens, err := lexer.Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Lexer error: %v", err)
+			}
+
+			stmt, err := New(tokens).Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			analyze, ok := stmt.(*ast.AnalyzeStatement)
+			if !ok {
+				t.Fatalf("Expected AnalyzeStatement, got %T", stmt)
+			}
+
+			table := ""
+			if analyze.TableName != nil {
+				table = analyze.TableName.Value
+			}
+			if table != tt.expectedTable {
+				t.Errorf("Expected table %q, got %q", tt.expectedTable, table)
+			}
+		})
+	}
+}
+
+func TestParseJoinForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		joinType string
+		table    string
+		using    []string
+		natural  bool
+		hasOn    bool
+	}{
+		{name: "CROSS JOIN", input: "SELECT * FROM users CROSS JOIN orders", joinType: "CROSS", table: "orders"},
+		{name: "comma", input: "SELECT * FROM users, orders WHERE users.id = orders.user_id", joinType: "CROSS", table: "orders"},
+		{name: "USING", input: "SELECT * FROM orders JOIN shipments USING (order_id, region)", joinType: "INNER", table: "shipments", using: []string{"order_id", "region"}},
+		{name: "LEFT JOIN USING", input: "SELECT * FROM orders LEFT OUTER JOIN shipments USING (order_id)", joinType: "LEFT", table: "shipments", using: []string{"order_id"}},
+		{name: "NATURAL JOIN", input: "SELECT * FROM orders NATURAL JOIN shipments", joinType: "INNER", table: "shipments", natural: true},
+		{name: "NATURAL FULL JOIN", input: "SELECT * FROM orders NATURAL FULL OUTER JOIN shipments", joinType: "FULL", table: "shipments", natural: true},
+		{name: "ON", input: "SELECT * FROM users JOIN orders ON users.id = orders.user_id", joinType: "INNER", table: "orders", hasOn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexer.Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Lexer error: %v", err)
+			}
+			stmt, err := New(tokens).Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+
+			sel := stmt.(*ast.SelectStatement)
+			if len(sel.Joins) != 1 {
+				t.Fatalf("Expected 1 join, got %d", len(sel.Joins))
+			}
+			join := sel.Joins[0]
+			if join.JoinType != tt.joinType || join.RightTable.Value != tt.table {
+				t.Errorf("Expected %s JOIN %s, got %s JOIN %s", tt.joinType, tt.table, join.JoinType, join.RightTable.Value)
+			}
+			if join.Natural != tt.natural {
+				t.Errorf("Expected natural = %v", tt.natural)
+			}
+			if (join.OnCondition != nil) != tt.hasOn {
+				t.Errorf("Expected ON condition = %v, got %v", tt.hasOn, join.OnCondition)
+			}
+			if len(join.Using) != len(tt.using) {
+				t.Fatalf("Expected USING %v, got %d columns", tt.using, len(join.Using))
+			}
+			for i, col := range tt.using {
+				if join.Using[i].Value != col {
+					t.Errorf("Expected USING column %s, got %s", col, join.Using[i].Value)
+				}
+			}
+		})
+	}
+
+	errors := []string{
+		"SELECT * FROM users CROSS JOIN orders ON users.id = orders.user_id",
+		"SELECT * FROM users NATURAL JOIN orders USING (id)",
+		"SELECT * FROM users JOIN orders",
+		"SELECT * FROM users JOIN orders USING ()",
+		"SELECT * FROM users, WHERE id = 1",
+	}
+	for _, input := range errors {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		limit int
+	}{
+		{"SELECT * FROM users LIMIT 10", 10},
+		{"SELECT * FROM users WHERE age > 18 LIMIT 0;", 0},
+		{"SELECT * FROM users JOIN orders ON users.id = orders.user_id LIMIT 3", 3},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		sel := stmt.(*ast.SelectStatement)
+		if sel.Limit == nil || *sel.Limit != tt.limit {
+			t.Errorf("%q: expected LIMIT %d, got %v", tt.input, tt.limit, sel.Limit)
+		}
+	}
+
+	for _, input := range []string{"SELECT * FROM users LIMIT", "SELECT * FROM users LIMIT 1.5", "SELECT * FROM users LIMIT 'a'"} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		value string
+	}{
+		{"SET statement_timeout = '5s'", "statement_timeout", "5s"},
+		{"SET statement_timeout TO 250;", "statement_timeout", "250"},
+		{"set Statement_Timeout = DEFAULT", "statement_timeout", "DEFAULT"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		set, ok := stmt.(*ast.SetStatement)
+		if !ok {
+			t.Fatalf("Expected SetStatement, got %T", stmt)
+		}
+		if set.Name != tt.name || set.Value != tt.value {
+			t.Errorf("%q: expected %s = %q, got %s = %q", tt.input, tt.name, tt.value, set.Name, set.Value)
+		}
+	}
+
+	for _, input := range []string{"SET", "SET statement_timeout", "SET statement_timeout = ", "SET statement_timeout = '1s' '2s'"} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseGroupByOrderBy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"SELECT * FROM users ORDER BY name",
+			"SELECT * FROM users ORDER BY name",
+		},
+		{
+			"SELECT region, COUNT(*), sum(orders.amount) FROM orders GROUP BY region ORDER BY COUNT(*) DESC, region ASC LIMIT 5;",
+			"SELECT region, count(*), sum(orders.amount) FROM orders GROUP BY region ORDER BY count(*) DESC, region LIMIT 5",
+		},
+		{
+			"SELECT users.name, AVG(age) FROM users WHERE age > 18 GROUP BY users.name, users.id",
+			"SELECT users.name, avg(age) FROM users WHERE (age > 18) GROUP BY users.name, users.id",
+		},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"SELECT * FROM users ORDER name",
+		"SELECT * FROM users GROUP BY",
+		"SELECT * FROM users ORDER BY DESC",
+		"SELECT LOWER(name) FROM users",
+		"SELECT SUM(*) FROM users",
+		"SELECT COUNT(name FROM users",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseExplain(t *testing.T) {
+	tests := []struct {
+		input    string
+		analyze  bool
+		format   string
+		expected string
+	}{
+		{"EXPLAIN SELECT * FROM users", false, "TEXT", "SELECT * FROM users"},
+		{"EXPLAIN ANALYZE SELECT name FROM users WHERE age > 18;", true, "TEXT", "SELECT name FROM users WHERE (age > 18)"},
+		{"EXPLAIN (FORMAT JSON) SELECT * FROM users", false, "JSON", "SELECT * FROM users"},
+		{"EXPLAIN (ANALYZE, FORMAT json) DELETE FROM users WHERE id = 1", true, "JSON", "DELETE FROM users WHERE (id = 1)"},
+		{"EXPLAIN ANALYZE (ANALYZE FALSE, FORMAT TEXT) SELECT * FROM users", false, "TEXT", "SELECT * FROM users"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		explain, ok := stmt.(*ast.ExplainStatement)
+		if !ok {
+			t.Fatalf("%q: expected *ast.ExplainStatement, got %T", tt.input, stmt)
+		}
+		if explain.Analyze != tt.analyze || explain.Format != tt.format {
+			t.Errorf("%q: expected analyze=%v format=%s, got analyze=%v format=%s",
+				tt.input, tt.analyze, tt.format, explain.Analyze, explain.Format)
+		}
+		if got := explain.Statement.String(); got != tt.expected {
+			t.Errorf("%q: expected statement %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"EXPLAIN",
+		"EXPLAIN SET statement_timeout = 0",
+		"EXPLAIN (FORMAT XML) SELECT * FROM users",
+		"EXPLAIN (VERBOSE) SELECT * FROM users",
+		"EXPLAIN (FORMAT JSON SELECT * FROM users",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseDatabaseStorage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CREATE DATABASE shop", "CREATE DATABASE shop"},
+		{"CREATE DATABASE shop STORAGE = page;", "CREATE DATABASE shop STORAGE = page"},
+		{"CREATE DATABASE shop storage 'PAGE'", "CREATE DATABASE shop STORAGE = page"},
+		{"ALTER DATABASE shop SET STORAGE = json", "ALTER DATABASE shop SET STORAGE = json"},
+		{"ALTER DATABASE shop RENAME TO store", "ALTER DATABASE shop RENAME TO store"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"CREATE DATABASE shop STORAGE",
+		"CREATE DATABASE shop STORAGE = 5",
+		"ALTER DATABASE shop SET",
+		"ALTER DATABASE shop SET FORMAT = page",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseBackup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"BACKUP DATABASE shop TO '/backups/shop'", "BACKUP DATABASE shop TO '/backups/shop'"},
+		{"backup database shop to 'shop-2024';", "BACKUP DATABASE shop TO 'shop-2024'"},
+		{"RESTORE DATABASE shop_copy FROM '/backups/shop';", "RESTORE DATABASE shop_copy FROM '/backups/shop'"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"BACKUP shop TO '/backups/shop'",
+		"BACKUP DATABASE shop",
+		"BACKUP DATABASE shop FROM '/backups/shop'",
+		"BACKUP DATABASE shop TO /backups",
+		"RESTORE DATABASE shop TO '/backups/shop'",
+		"RESTORE DATABASE shop FROM ''",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			continue
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CHECK DATABASE shop", "CHECK DATABASE shop"},
+		{"check database shop repair;", "CHECK DATABASE shop REPAIR"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"CHECK shop", "CHECK DATABASE", "CHECK DATABASE 'shop'"} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			continue
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseScriptStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CREATE TABLE notes (id INT PRIMARY KEY AUTO_INCREMENT, body text not null, email EMAIL UNIQUE);",
+			"CREATE TABLE notes (id INT PRIMARY KEY AUTO_INCREMENT, body TEXT NOT NULL, email EMAIL UNIQUE)"},
+		{"CREATE TABLE t (date DATE, score FLOAT)", "CREATE TABLE t (date DATE, score FLOAT)"},
+		{"INSERT INTO t (a, b) VALUES (1, 'x'), (-2, 'it''s')",
+			"INSERT INTO t (a, b) VALUES (1, x), (-2, it's)"},
+		{"ALTER TABLE notes AUTO_INCREMENT = 103;", "ALTER TABLE notes AUTO_INCREMENT = 103"},
+		{"alter table notes auto_increment 7", "ALTER TABLE notes AUTO_INCREMENT = 7"},
+		{"SOURCE 'dump.sql';", "SOURCE 'dump.sql'"},
+		{"source '/tmp/load.sql'", "SOURCE '/tmp/load.sql'"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"CREATE TABLE t ()",
+		"CREATE TABLE t (id)",
+		"CREATE TABLE t (id NUMBER)",
+		"CREATE TABLE t (id INT PRIMARY)",
+		"CREATE TABLE t (id INT NOT)",
+		"CREATE TABLE t (id INT, id TEXT)",
+		"CREATE TABLE t (name TEXT AUTO_INCREMENT)",
+		"INSERT INTO t (a) VALUES (1),",
+		"ALTER TABLE t",
+		"ALTER TABLE t AUTO_INCREMENT = 0",
+		"ALTER TABLE t AUTO_INCREMENT = 1.5",
+		"SOURCE dump.sql",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			continue
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
+
+func TestParseCopy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"COPY users FROM 'users.csv'", "COPY users FROM 'users.csv' WITH (FORMAT CSV, DELIMITER ',', NULL '')"},
+		{"copy users (name, email) from '/tmp/u.csv' with (header, delimiter ';', null 'NULL');",
+			"COPY users (name, email) FROM '/tmp/u.csv' WITH (FORMAT CSV, HEADER, DELIMITER ';', NULL 'NULL')"},
+		{"COPY users FROM 'u.jsonl' WITH (FORMAT jsonl)", "COPY users FROM 'u.jsonl' WITH (FORMAT JSONL)"},
+		{"COPY users TO 'u.csv' WITH (HEADER FALSE)", "COPY users TO 'u.csv' WITH (FORMAT CSV, DELIMITER ',', NULL '')"},
+		{"COPY (SELECT id, name FROM users WHERE id > 1) TO 'u.jsonl' WITH (FORMAT JSONL)",
+			"COPY (SELECT id, name FROM users WHERE (id > 1)) TO 'u.jsonl' WITH (FORMAT JSONL)"},
+	}
+	for _, tt := range tests {
+		tokens, err := lexer.Tokenize(tt.input)
+		if err != nil {
+			t.Fatalf("Lexer error: %v", err)
+		}
+		stmt, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("Parse error for %q: %v", tt.input, err)
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{
+		"COPY users",
+		"COPY users FROM users.csv",
+		"COPY users INTO 'u.csv'",
+		"COPY (SELECT * FROM users) FROM 'u.csv'",
+		"COPY (SELECT * FROM users TO 'u.csv'",
+		"COPY users FROM 'u.csv' WITH (FORMAT xml)",
+		"COPY users FROM 'u.csv' WITH (DELIMITER ';;')",
+		"COPY users FROM 'u.csv' WITH (QUOTE '\"')",
+		"COPY users FROM 'u.jsonl' WITH (FORMAT JSONL, HEADER)",
+		"COPY users FROM 'u.csv' WITH (HEADER",
+	} {
+		tokens, err := lexer.Tokenize(input)
+		if err != nil {
+			continue
+		}
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Errorf("Expected parse error for %q", input)
+		}
+	}
+}
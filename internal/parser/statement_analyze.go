@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseAnalyze parses ANALYZE [table]
+func (p *Parser) parseAnalyze() (ast.Statement, error) {
+	stmt := &ast.AnalyzeStatement{}
+
+	// Optional table name
+	if p.peekTok.Type == lexer.IDENTIFIER {
+		p.nextToken()
+		stmt.TableName = &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal}
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseBackup parses BACKUP DATABASE name TO 'path' and
+// RESTORE DATABASE name FROM 'path'
+func (p *Parser) parseBackup() (ast.Statement, error) {
+	keyword := p.curTok.Literal
+	restore := p.curTok.Type == lexer.RESTORE
+
+	// Expect DATABASE token
+	if !p.expectPeek(lexer.DATABASE) {
+		return nil, fmt.Errorf("expected DATABASE after %s, got %s", keyword, p.peekTok.Literal)
+	}
+
+	// Expect identifier (database name)
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected database name, got %s", p.peekTok.Literal)
+	}
+	name := p.curTok.Literal
+
+	// TO 'path' for a backup, FROM 'path' for a restore
+	direction, word := lexer.TO, "TO"
+	if restore {
+		direction, word = lexer.FROM, "FROM"
+	}
+	if !p.expectPeek(direction) {
+		return nil, fmt.Errorf("expected %s after database name, got %s", word, p.peekTok.Literal)
+	}
+	if !p.expectPeek(lexer.STRING) {
+		return nil, fmt.Errorf("expected a quoted path after %s, got %s", word, p.peekTok.Literal)
+	}
+	path := p.curTok.Literal
+	if path == "" {
+		return nil, fmt.Errorf("expected a non-empty path after %s", word)
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	if restore {
+		return &ast.RestoreDatabaseStatement{Name: name, Path: path}, nil
+	}
+	return &ast.BackupDatabaseStatement{Name: name, Path: path}, nil
+}
@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseCheck parses CHECK DATABASE name [REPAIR]
+// CHECK and REPAIR are soft keywords.
+func (p *Parser) parseCheck() (ast.Statement, error) {
+	if !p.expectPeek(lexer.DATABASE) {
+		return nil, fmt.Errorf("expected DATABASE after CHECK, got %s", p.peekTok.Literal)
+	}
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected database name, got %s", p.peekTok.Literal)
+	}
+	stmt := &ast.CheckDatabaseStatement{Name: p.curTok.Literal}
+
+	if p.peekIsWord("REPAIR") {
+		p.nextToken()
+		stmt.Repair = true
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
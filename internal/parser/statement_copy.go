@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseCopy parses
+//
+//	COPY table [(columns)] FROM 'path' [WITH (option, ...)]
+//	COPY table [(columns)] TO 'path' [WITH (option, ...)]
+//	COPY (query) TO 'path' [WITH (option, ...)]
+//
+// Options are FORMAT { CSV | JSONL }, HEADER [TRUE | FALSE], DELIMITER 'c'
+// and NULL 'text'. COPY, WITH and the option names are soft keywords.
+func (p *Parser) parseCopy() (ast.Statement, error) {
+	stmt := &ast.CopyStatement{Format: "CSV", Delimiter: ","}
+
+	// COPY keyword - already consumed by Parse()
+	p.nextToken()
+
+	switch p.curTok.Type {
+	case lexer.PAREN_OPEN:
+		p.nextToken()
+		if p.curTok.Type != lexer.SELECT {
+			return nil, fmt.Errorf("expected SELECT after COPY (, got %s", p.curTok.Literal)
+		}
+		query, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		if p.curTok.Type != lexer.PAREN_CLOSE {
+			return nil, fmt.Errorf("expected ) after the query of COPY, got %s", p.curTok.Literal)
+		}
+		stmt.Query = query
+		p.nextToken()
+	case lexer.IDENTIFIER:
+		stmt.TableName = &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal}
+		p.nextToken()
+		if p.curTok.Type == lexer.PAREN_OPEN {
+			cols, err := p.parseIdentifierList()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = cols
+		}
+	default:
+		return nil, fmt.Errorf("expected table name or (query) after COPY, got %s", p.curTok.Literal)
+	}
+
+	switch p.curTok.Type {
+	case lexer.FROM:
+		if stmt.Query != nil {
+			return nil, fmt.Errorf("a query can only be copied TO a file")
+		}
+	case lexer.TO:
+		stmt.To = true
+	default:
+		return nil, fmt.Errorf("expected FROM or TO, got %s", p.curTok.Literal)
+	}
+	if !p.expectPeek(lexer.STRING) {
+		return nil, fmt.Errorf("expected a quoted path after %s, got %s", p.curTok.Literal, p.peekTok.Literal)
+	}
+	stmt.Path = p.curTok.Literal
+	if stmt.Path == "" {
+		return nil, fmt.Errorf("expected a non-empty path")
+	}
+
+	if p.peekIsWord("WITH") {
+		p.nextToken()
+		if !p.expectPeek(lexer.PAREN_OPEN) {
+			return nil, fmt.Errorf("expected ( after WITH, got %s", p.peekTok.Literal)
+		}
+		for {
+			if err := p.parseCopyOption(stmt); err != nil {
+				return nil, err
+			}
+			if p.peekTok.Type != lexer.COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(lexer.PAREN_CLOSE) {
+			return nil, fmt.Errorf("expected ) after COPY options, got %s", p.peekTok.Literal)
+		}
+	}
+	if stmt.Format != "CSV" && (stmt.Header || stmt.Delimiter != "," || stmt.Null != "") {
+		return nil, fmt.Errorf("HEADER, DELIMITER and NULL only apply to FORMAT CSV")
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
+
+// parseCopyOption parses one option of COPY ... WITH (option, ...)
+func (p *Parser) parseCopyOption(stmt *ast.CopyStatement) error {
+	p.nextToken()
+	if p.curTok.Type != lexer.IDENTIFIER {
+		return fmt.Errorf("unknown COPY option: %s", p.curTok.Literal)
+	}
+
+	switch option := strings.ToUpper(p.curTok.Literal); option {
+	case "FORMAT":
+		if !p.expectPeek(lexer.IDENTIFIER) {
+			return fmt.Errorf("expected CSV or JSONL after FORMAT, got %s", p.peekTok.Literal)
+		}
+		format := strings.ToUpper(p.curTok.Literal)
+		if format != "CSV" && format != "JSONL" {
+			return fmt.Errorf("unsupported COPY format: %s", p.curTok.Literal)
+		}
+		stmt.Format = format
+	case "HEADER":
+		stmt.Header = true
+		switch p.peekTok.Type {
+		case lexer.TRUE:
+			p.nextToken()
+		case lexer.FALSE:
+			p.nextToken()
+			stmt.Header = false
+		}
+	case "DELIMITER":
+		if !p.expectPeek(lexer.STRING) {
+			return fmt.Errorf("expected a quoted character after DELIMITER, got %s", p.peekTok.Literal)
+		}
+		delimiter := p.curTok.Literal
+		if utf8.RuneCountInString(delimiter) != 1 || strings.ContainsAny(delimiter, "\"\r\n") {
+			return fmt.Errorf("DELIMITER must be a single character other than a double quote or line break")
+		}
+		stmt.Delimiter = delimiter
+	case "NULL":
+		if !p.expectPeek(lexer.STRING) {
+			return fmt.Errorf("expected a quoted string after NULL, got %s", p.peekTok.Literal)
+		}
+		stmt.Null = p.curTok.Literal
+	default:
+		return fmt.Errorf("unknown COPY option: %s", p.curTok.Literal)
+	}
+	return nil
+}
@@ -2,13 +2,22 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/leengari/mini-rdbms/internal/parser/ast"
 	"github.com/leengari/mini-rdbms/internal/parser/lexer"
 )
 
-// parseCreate parses CREATE DATABASE statement
+// parseCreate parses CREATE DATABASE [STORAGE = format], CREATE TABLE and
+// CREATE [UNIQUE] INDEX statements
 func (p *Parser) parseCreate() (ast.Statement, error) {
+	if p.peekTok.Type == lexer.INDEX || p.peekTok.Type == lexer.UNIQUE {
+		return p.parseCreateIndex()
+	}
+	if p.peekIsWord("TABLE") {
+		return p.parseCreateTable()
+	}
+
 	// Expect DATABASE token
 	if !p.expectPeek(lexer.DATABASE) {
 		return nil, fmt.Errorf("expected DATABASE after CREATE, got %s", p.peekTok.Literal)
@@ -23,6 +32,15 @@ func (p *Parser) parseCreate() (ast.Statement, error) {
 		Name: p.curTok.Literal,
 	}
 
+	if p.peekTok.Type == lexer.IDENTIFIER && strings.EqualFold(p.peekTok.Literal, "STORAGE") {
+		p.nextToken()
+		storage, err := p.parseStorageFormat()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Storage = storage
+	}
+
 	// Optional semicolon
 	if p.peekTok.Type == lexer.SEMICOLON {
 		p.nextToken()
@@ -74,8 +92,13 @@ func (p *Parser) parseUse() (ast.Statement, error) {
 	return stmt, nil
 }
 
-// parseAlter parses ALTER DATABASE statement
+// parseAlter parses ALTER DATABASE name RENAME TO newName,
+// ALTER DATABASE name SET STORAGE = format and ALTER TABLE statements
 func (p *Parser) parseAlter() (ast.Statement, error) {
+	if p.peekIsWord("TABLE") {
+		return p.parseAlterTable()
+	}
+
 	// Expect DATABASE token
 	if !p.expectPeek(lexer.DATABASE) {
 		return nil, fmt.Errorf("expected DATABASE after ALTER, got %s", p.peekTok.Literal)
@@ -87,6 +110,25 @@ func (p *Parser) parseAlter() (ast.Statement, error) {
 	}
 	dbName := p.curTok.Literal
 
+	// SET STORAGE = format converts the database
+	if p.peekTok.Type == lexer.SET {
+		p.nextToken()
+		if p.peekTok.Type != lexer.IDENTIFIER || !strings.EqualFold(p.peekTok.Literal, "STORAGE") {
+			return nil, fmt.Errorf("expected STORAGE after SET, got %s", p.peekTok.Literal)
+		}
+		p.nextToken()
+		storage, err := p.parseStorageFormat()
+		if err != nil {
+			return nil, err
+		}
+
+		// Optional semicolon
+		if p.peekTok.Type == lexer.SEMICOLON {
+			p.nextToken()
+		}
+		return &ast.AlterDatabaseStatement{Name: dbName, Storage: storage}, nil
+	}
+
 	// Expect RENAME token
 	if !p.expectPeek(lexer.RENAME) {
 		return nil, fmt.Errorf("expected RENAME after database name, got %s", p.peekTok.Literal)
@@ -115,3 +157,18 @@ func (p *Parser) parseAlter() (ast.Statement, error) {
 
 	return stmt, nil
 }
+
+// parseStorageFormat parses the [=] format following STORAGE
+// The format is a bare word or a quoted string, such as page or 'page'.
+func (p *Parser) parseStorageFormat() (string, error) {
+	if p.peekTok.Type == lexer.EQUALS {
+		p.nextToken()
+	}
+	switch p.peekTok.Type {
+	case lexer.IDENTIFIER, lexer.STRING:
+		p.nextToken()
+		return strings.ToLower(p.curTok.Literal), nil
+	default:
+		return "", fmt.Errorf("expected storage format after STORAGE, got %s", p.peekTok.Literal)
+	}
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseExplain parses EXPLAIN [ANALYZE] [(option, ...)] statement
+// Options are ANALYZE [TRUE | FALSE] and FORMAT { TEXT | JSON }.
+func (p *Parser) parseExplain() (ast.Statement, error) {
+	stmt := &ast.ExplainStatement{Format: "TEXT"}
+
+	if p.peekTok.Type == lexer.ANALYZE {
+		p.nextToken()
+		stmt.Analyze = true
+	}
+
+	if p.peekTok.Type == lexer.PAREN_OPEN {
+		p.nextToken()
+		for {
+			if err := p.parseExplainOption(stmt); err != nil {
+				return nil, err
+			}
+			if p.peekTok.Type != lexer.COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(lexer.PAREN_CLOSE) {
+			return nil, fmt.Errorf("expected ) after EXPLAIN options, got %s", p.peekTok.Literal)
+		}
+	}
+
+	// Only statements that are planned can be explained
+	switch p.peekTok.Type {
+	case lexer.SELECT, lexer.INSERT, lexer.UPDATE, lexer.DELETE:
+	default:
+		return nil, fmt.Errorf("expected SELECT, INSERT, UPDATE or DELETE after EXPLAIN, got %s", p.peekTok.Literal)
+	}
+	p.nextToken()
+
+	inner, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Statement = inner
+	return stmt, nil
+}
+
+// parseExplainOption parses one option of EXPLAIN (option, ...)
+func (p *Parser) parseExplainOption(stmt *ast.ExplainStatement) error {
+	p.nextToken()
+	switch {
+	case p.curTok.Type == lexer.ANALYZE:
+		stmt.Analyze = true
+		switch p.peekTok.Type {
+		case lexer.TRUE:
+			p.nextToken()
+		case lexer.FALSE:
+			p.nextToken()
+			stmt.Analyze = false
+		}
+		return nil
+	case p.curTok.Type == lexer.IDENTIFIER && strings.EqualFold(p.curTok.Literal, "FORMAT"):
+		if !p.expectPeek(lexer.IDENTIFIER) {
+			return fmt.Errorf("expected TEXT or JSON after FORMAT, got %s", p.peekTok.Literal)
+		}
+		format := strings.ToUpper(p.curTok.Literal)
+		if format != "TEXT" && format != "JSON" {
+			return fmt.Errorf("unsupported EXPLAIN format: %s", p.curTok.Literal)
+		}
+		stmt.Format = format
+		return nil
+	default:
+		return fmt.Errorf("unknown EXPLAIN option: %s", p.curTok.Literal)
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseCreateIndex parses CREATE [UNIQUE] INDEX name ON table (column) [USING BTREE|HASH]
+// Current token is CREATE
+func (p *Parser) parseCreateIndex() (ast.Statement, error) {
+	stmt := &ast.CreateIndexStatement{}
+
+	// Optional UNIQUE
+	if p.peekTok.Type == lexer.UNIQUE {
+		p.nextToken()
+		stmt.Unique = true
+	}
+
+	// Expect INDEX token
+	if !p.expectPeek(lexer.INDEX) {
+		return nil, fmt.Errorf("expected INDEX after CREATE UNIQUE, got %s", p.peekTok.Literal)
+	}
+
+	// Expect identifier (index name)
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected index name, got %s", p.peekTok.Literal)
+	}
+	stmt.Name = p.curTok.Literal
+
+	// Expect ON table
+	if !p.expectPeek(lexer.ON) {
+		return nil, fmt.Errorf("expected ON after index name, got %s", p.peekTok.Literal)
+	}
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected table name after ON, got %s", p.peekTok.Literal)
+	}
+	stmt.TableName = &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal}
+
+	// Expect (column)
+	if !p.expectPeek(lexer.PAREN_OPEN) {
+		return nil, fmt.Errorf("expected ( after table name, got %s", p.peekTok.Literal)
+	}
+	p.nextToken()
+	if !isIdentifierOrKeyword(p.curTok.Type) {
+		return nil, fmt.Errorf("expected column name, got %s", p.curTok.Literal)
+	}
+	stmt.Column = &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal}
+	if !p.expectPeek(lexer.PAREN_CLOSE) {
+		return nil, fmt.Errorf("expected ) after column name (only single-column indexes are supported), got %s", p.peekTok.Literal)
+	}
+
+	// Optional USING BTREE|HASH
+	if p.peekTok.Type == lexer.USING {
+		p.nextToken()
+		if !p.expectPeek(lexer.IDENTIFIER) {
+			return nil, fmt.Errorf("expected index type after USING, got %s", p.peekTok.Literal)
+		}
+		using := strings.ToUpper(p.curTok.Literal)
+		if using != "BTREE" && using != "HASH" {
+			return nil, fmt.Errorf("unsupported index type %s (expected BTREE or HASH)", p.curTok.Literal)
+		}
+		stmt.Using = using
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // parseInsert parses an INSERT statement
-// Grammar: INSERT INTO table (columns) VALUES (values)
+// Grammar: INSERT INTO table (columns) VALUES (values) [, (values) ...]
 func (p *Parser) parseInsert() (*ast.InsertStatement, error) {
 	stmt := &ast.InsertStatement{}
 
@@ -44,17 +44,24 @@ func (p *Parser) parseInsert() (*ast.InsertStatement, error) {
 	}
 	p.nextToken()
 
-	// (
-	if p.curTok.Type != lexer.PAREN_OPEN {
-		return nil, fmt.Errorf("expected (, got %s", p.curTok.Literal)
-	}
-	
-	// Parse Values List
-	values, err := p.parseExpressionList()
-	if err != nil {
-		return nil, err
+	// One parenthesized values list per row, separated by commas
+	for {
+		if p.curTok.Type != lexer.PAREN_OPEN {
+			return nil, fmt.Errorf("expected (, got %s", p.curTok.Literal)
+		}
+
+		// Parse Values List
+		values, err := p.parseExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Rows = append(stmt.Rows, values)
+
+		if p.curTok.Type != lexer.COMMA {
+			break
+		}
+		p.nextToken()
 	}
-	stmt.Values = values
 
 	// Semicolon (Optional)
 	if p.curTok.Type == lexer.SEMICOLON {
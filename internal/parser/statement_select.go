@@ -2,13 +2,17 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/leengari/mini-rdbms/internal/parser/ast"
 	"github.com/leengari/mini-rdbms/internal/parser/lexer"
 )
 
 // parseSelect parses a SELECT statement
-// Grammar: SELECT fields FROM table [JOIN ...] [WHERE condition]
+// Grammar: SELECT fields FROM table [, table | JOIN ...] [WHERE condition]
+//          [GROUP BY columns] [ORDER BY column [ASC|DESC], ...] [LIMIT n]
+// A comma-separated table is a CROSS JOIN; its join condition goes in WHERE.
 func (p *Parser) parseSelect() (*ast.SelectStatement, error) {
 	stmt := &ast.SelectStatement{}
 
@@ -16,7 +20,7 @@ func (p *Parser) parseSelect() (*ast.SelectStatement, error) {
 	p.nextToken()
 
 	// Fields
-	fields, err := p.parseIdentifierList()
+	fields, err := p.parseSelectList()
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +40,20 @@ func (p *Parser) parseSelect() (*ast.SelectStatement, error) {
 	p.nextToken()
 
 	// JOINs (Optional, can have multiple)
-	for isJoinKeyword(p.curTok.Type) {
+	for isJoinKeyword(p.curTok.Type) || p.curTok.Type == lexer.COMMA {
+		if p.curTok.Type == lexer.COMMA {
+			p.nextToken()
+			if p.curTok.Type != lexer.IDENTIFIER {
+				return nil, fmt.Errorf("expected table name after comma, got %s", p.curTok.Literal)
+			}
+			stmt.Joins = append(stmt.Joins, &ast.JoinClause{
+				JoinType:   "CROSS",
+				RightTable: &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal},
+			})
+			p.nextToken()
+			continue
+		}
+
 		join, err := p.parseJoin()
 		if err != nil {
 			return nil, err
@@ -54,6 +71,63 @@ func (p *Parser) parseSelect() (*ast.SelectStatement, error) {
 		stmt.Where = expr
 	}
 
+	// GROUP BY (Optional)
+	if p.curTok.Type == lexer.GROUP {
+		if !p.expectPeek(lexer.BY) {
+			return nil, fmt.Errorf("expected BY after GROUP, got %s", p.peekTok.Literal)
+		}
+		p.nextToken()
+		for {
+			col, err := p.parseQualifiedIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			stmt.GroupBy = append(stmt.GroupBy, col)
+			if p.curTok.Type != lexer.COMMA {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	// ORDER BY (Optional)
+	if p.curTok.Type == lexer.ORDER {
+		if !p.expectPeek(lexer.BY) {
+			return nil, fmt.Errorf("expected BY after ORDER, got %s", p.peekTok.Literal)
+		}
+		p.nextToken()
+		for {
+			col, err := p.parseSelectItem()
+			if err != nil {
+				return nil, err
+			}
+			item := &ast.OrderByItem{Column: col}
+			switch p.curTok.Type {
+			case lexer.DESC:
+				item.Desc = true
+				p.nextToken()
+			case lexer.ASC:
+				p.nextToken()
+			}
+			stmt.OrderBy = append(stmt.OrderBy, item)
+			if p.curTok.Type != lexer.COMMA {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	// LIMIT (Optional)
+	if p.curTok.Type == lexer.LIMIT {
+		p.nextToken()
+		limit, err := strconv.Atoi(p.curTok.Literal)
+		if p.curTok.Type != lexer.NUMBER || err != nil || limit < 0 {
+			return nil, fmt.Errorf("expected non-negative integer after LIMIT, got %s", p.curTok.Literal)
+		}
+		stmt.Limit = &limit
+		p.nextToken()
+	}
+
 	// Semicolon (Optional)
 	if p.curTok.Type == lexer.SEMICOLON {
 		p.nextToken()
@@ -62,14 +136,86 @@ func (p *Parser) parseSelect() (*ast.SelectStatement, error) {
 	return stmt, nil
 }
 
+// parseSelectList parses the fields of a SELECT: * or a comma-separated list
+// of columns and aggregates
+func (p *Parser) parseSelectList() ([]*ast.Identifier, error) {
+	if p.curTok.Type == lexer.ASTERISK {
+		p.nextToken()
+		return []*ast.Identifier{{TokenLiteralValue: "*", Value: "*"}}, nil
+	}
+
+	var fields []*ast.Identifier
+	for {
+		field, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		if p.curTok.Type != lexer.COMMA {
+			return fields, nil
+		}
+		p.nextToken()
+	}
+}
+
+// parseSelectItem parses a column or an aggregate call
+// Grammar: column | table.column | COUNT(*) | (COUNT|SUM|AVG|MIN|MAX)(column)
+func (p *Parser) parseSelectItem() (*ast.Identifier, error) {
+	if p.curTok.Type != lexer.IDENTIFIER || p.peekTok.Type != lexer.PAREN_OPEN {
+		return p.parseQualifiedIdentifier()
+	}
+
+	function := strings.ToUpper(p.curTok.Literal)
+	switch function {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+	default:
+		return nil, fmt.Errorf("unknown function %s", p.curTok.Literal)
+	}
+	p.nextToken() // (
+	p.nextToken()
+
+	var arg *ast.Identifier
+	if p.curTok.Type == lexer.ASTERISK {
+		if function != "COUNT" {
+			return nil, fmt.Errorf("%s(*) is not allowed, only COUNT(*)", function)
+		}
+		arg = &ast.Identifier{TokenLiteralValue: "*", Value: "*"}
+		p.nextToken()
+	} else {
+		col, err := p.parseQualifiedIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		arg = col
+	}
+
+	if p.curTok.Type != lexer.PAREN_CLOSE {
+		return nil, fmt.Errorf("expected ) after %s argument, got %s", function, p.curTok.Literal)
+	}
+	p.nextToken()
+
+	arg.Aggregate = function
+	arg.TokenLiteralValue = arg.String()
+	return arg, nil
+}
+
 // parseJoin parses a JOIN clause
-// Grammar: [INNER|LEFT|RIGHT|FULL] [OUTER] JOIN table ON condition
+// Grammar:
+//   [INNER|LEFT|RIGHT|FULL] [OUTER] JOIN table (ON condition | USING (col, ...))
+//   NATURAL [INNER|LEFT|RIGHT|FULL] [OUTER] JOIN table
+//   CROSS JOIN table
 // Examples:
 //   - INNER JOIN orders ON users.id = orders.user_id
 //   - LEFT OUTER JOIN orders ON users.id = orders.user_id
+//   - JOIN orders USING (user_id)
 func (p *Parser) parseJoin() (*ast.JoinClause, error) {
 	join := &ast.JoinClause{}
 
+	if p.curTok.Type == lexer.NATURAL {
+		join.Natural = true
+		p.nextToken()
+	}
+
 	// Determine JOIN type
 	switch p.curTok.Type {
 	case lexer.INNER:
@@ -84,6 +230,12 @@ func (p *Parser) parseJoin() (*ast.JoinClause, error) {
 	case lexer.FULL:
 		join.JoinType = "FULL"
 		p.nextToken()
+	case lexer.CROSS:
+		if join.Natural {
+			return nil, fmt.Errorf("NATURAL CROSS JOIN is not allowed")
+		}
+		join.JoinType = "CROSS"
+		p.nextToken()
 	case lexer.JOIN:
 		// Default to INNER JOIN if no type specified
 		join.JoinType = "INNER"
@@ -109,9 +261,27 @@ func (p *Parser) parseJoin() (*ast.JoinClause, error) {
 	join.RightTable = &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal}
 	p.nextToken()
 
+	// CROSS and NATURAL joins have no explicit condition
+	if join.JoinType == "CROSS" || join.Natural {
+		if p.curTok.Type == lexer.ON || p.curTok.Type == lexer.USING {
+			return nil, fmt.Errorf("unexpected %s after %s JOIN", p.curTok.Literal, joinKind(join))
+		}
+		return join, nil
+	}
+
+	// USING (col, ...)
+	if p.curTok.Type == lexer.USING {
+		columns, err := p.parseUsingColumns()
+		if err != nil {
+			return nil, err
+		}
+		join.Using = columns
+		return join, nil
+	}
+
 	// ON keyword
 	if p.curTok.Type != lexer.ON {
-		return nil, fmt.Errorf("expected ON, got %s", p.curTok.Literal)
+		return nil, fmt.Errorf("expected ON or USING, got %s", p.curTok.Literal)
 	}
 	p.nextToken()
 
@@ -125,7 +295,45 @@ func (p *Parser) parseJoin() (*ast.JoinClause, error) {
 	return join, nil
 }
 
+// parseUsingColumns parses the column list of USING (col, ...)
+// Current token is USING
+func (p *Parser) parseUsingColumns() ([]*ast.Identifier, error) {
+	p.nextToken()
+	if p.curTok.Type != lexer.PAREN_OPEN {
+		return nil, fmt.Errorf("expected ( after USING, got %s", p.curTok.Literal)
+	}
+	p.nextToken()
+
+	var columns []*ast.Identifier
+	for {
+		if p.curTok.Type != lexer.IDENTIFIER {
+			return nil, fmt.Errorf("expected column name in USING, got %s", p.curTok.Literal)
+		}
+		columns = append(columns, &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal})
+		p.nextToken()
+
+		if p.curTok.Type == lexer.COMMA {
+			p.nextToken()
+			continue
+		}
+		if p.curTok.Type != lexer.PAREN_CLOSE {
+			return nil, fmt.Errorf("expected , or ) in USING, got %s", p.curTok.Literal)
+		}
+		p.nextToken()
+		return columns, nil
+	}
+}
+
+// joinKind names a condition-less join for error messages
+func joinKind(join *ast.JoinClause) string {
+	if join.Natural {
+		return "NATURAL"
+	}
+	return join.JoinType
+}
+
 // isJoinKeyword checks if the current token starts a JOIN clause
 func isJoinKeyword(t lexer.TokenType) bool {
-	return t == lexer.INNER || t == lexer.LEFT || t == lexer.RIGHT || t == lexer.FULL || t == lexer.JOIN
+	return t == lexer.INNER || t == lexer.LEFT || t == lexer.RIGHT || t == lexer.FULL || t == lexer.JOIN ||
+		t == lexer.CROSS || t == lexer.NATURAL
 }
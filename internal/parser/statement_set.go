@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseSet parses SET name { = | TO } value
+func (p *Parser) parseSet() (ast.Statement, error) {
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected setting name after SET, got %s", p.peekTok.Literal)
+	}
+	stmt := &ast.SetStatement{Name: strings.ToLower(p.curTok.Literal)}
+
+	if p.peekTok.Type != lexer.EQUALS && p.peekTok.Type != lexer.TO {
+		return nil, fmt.Errorf("expected = or TO after %s, got %s", stmt.Name, p.peekTok.Literal)
+	}
+	p.nextToken()
+
+	// The value is a quoted string, a number or a bare word such as DEFAULT
+	switch p.peekTok.Type {
+	case lexer.STRING, lexer.NUMBER, lexer.IDENTIFIER:
+		p.nextToken()
+		stmt.Value = p.curTok.Literal
+	default:
+		return nil, fmt.Errorf("expected value for %s, got %s", stmt.Name, p.peekTok.Literal)
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+	if p.peekTok.Type != lexer.EOF {
+		return nil, fmt.Errorf("unexpected %s after SET %s", p.peekTok.Literal, stmt.Name)
+	}
+
+	return stmt, nil
+}
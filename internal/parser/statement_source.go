@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// parseSource parses SOURCE 'path'
+// SOURCE is a soft keyword, so tables and columns may still be named source.
+func (p *Parser) parseSource() (ast.Statement, error) {
+	if !p.expectPeek(lexer.STRING) {
+		return nil, fmt.Errorf("expected a quoted path after SOURCE, got %s", p.peekTok.Literal)
+	}
+	stmt := &ast.SourceStatement{Path: p.curTok.Literal}
+	if stmt.Path == "" {
+		return nil, fmt.Errorf("expected a non-empty path after SOURCE")
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+)
+
+// columnTypes are the column types CREATE TABLE accepts
+var columnTypes = map[string]bool{
+	"INT": true, "FLOAT": true, "TEXT": true, "BOOL": true,
+	"DATE": true, "TIME": true, "EMAIL": true,
+}
+
+// parseCreateTable parses CREATE TABLE name (column TYPE [constraints], ...)
+// where the constraints of a column are any of PRIMARY KEY, AUTO_INCREMENT,
+// UNIQUE and NOT NULL
+// Current token is CREATE, followed by TABLE
+func (p *Parser) parseCreateTable() (ast.Statement, error) {
+	p.nextToken() // TABLE
+
+	// Expect identifier (table name)
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected table name after CREATE TABLE, got %s", p.peekTok.Literal)
+	}
+	stmt := &ast.CreateTableStatement{
+		Name: &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal},
+	}
+
+	if !p.expectPeek(lexer.PAREN_OPEN) {
+		return nil, fmt.Errorf("expected ( after table name, got %s", p.peekTok.Literal)
+	}
+	for {
+		p.nextToken()
+		col, err := p.parseColumnDefinition()
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range stmt.Columns {
+			if existing.Name == col.Name {
+				return nil, fmt.Errorf("column %s is defined twice", col.Name)
+			}
+		}
+		stmt.Columns = append(stmt.Columns, col)
+
+		if p.peekTok.Type != lexer.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(lexer.PAREN_CLOSE) {
+		return nil, fmt.Errorf("expected , or ) after column definition, got %s", p.peekTok.Literal)
+	}
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
+
+// parseAlterTable parses ALTER TABLE name AUTO_INCREMENT [=] value, which
+// sets the next value of the table's auto-increment key
+// Current token is ALTER, followed by TABLE
+func (p *Parser) parseAlterTable() (ast.Statement, error) {
+	p.nextToken() // TABLE
+
+	// Expect identifier (table name)
+	if !p.expectPeek(lexer.IDENTIFIER) {
+		return nil, fmt.Errorf("expected table name after ALTER TABLE, got %s", p.peekTok.Literal)
+	}
+	stmt := &ast.AlterTableStatement{
+		Name: &ast.Identifier{TokenLiteralValue: p.curTok.Literal, Value: p.curTok.Literal},
+	}
+
+	if !p.peekIsWord("AUTO_INCREMENT") {
+		return nil, fmt.Errorf("expected AUTO_INCREMENT after table name, got %s", p.peekTok.Literal)
+	}
+	p.nextToken()
+	if p.peekTok.Type == lexer.EQUALS {
+		p.nextToken()
+	}
+	if !p.expectPeek(lexer.NUMBER) {
+		return nil, fmt.Errorf("expected a number after AUTO_INCREMENT, got %s", p.peekTok.Literal)
+	}
+	value, err := strconv.ParseInt(p.curTok.Literal, 10, 64)
+	if err != nil || value < 1 {
+		return nil, fmt.Errorf("AUTO_INCREMENT must be a positive integer, got %s", p.curTok.Literal)
+	}
+	stmt.AutoIncrement = value
+
+	// Optional semicolon
+	if p.peekTok.Type == lexer.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
+
+// parseColumnDefinition parses one column of CREATE TABLE
+// Current token is the column name; it is left on the column's last token.
+func (p *Parser) parseColumnDefinition() (ast.ColumnDefinition, error) {
+	if !isIdentifierOrKeyword(p.curTok.Type) {
+		return ast.ColumnDefinition{}, fmt.Errorf("expected column name, got %s", p.curTok.Literal)
+	}
+	col := ast.ColumnDefinition{Name: p.curTok.Literal}
+
+	p.nextToken()
+	col.Type = strings.ToUpper(p.curTok.Literal)
+	if !isIdentifierOrKeyword(p.curTok.Type) || !columnTypes[col.Type] {
+		return ast.ColumnDefinition{}, fmt.Errorf("expected a column type (INT, FLOAT, TEXT, BOOL, DATE, TIME or EMAIL) for column %s, got %s", col.Name, p.curTok.Literal)
+	}
+
+	for {
+		switch {
+		case p.peekIsWord("PRIMARY"):
+			p.nextToken()
+			if !p.peekIsWord("KEY") {
+				return ast.ColumnDefinition{}, fmt.Errorf("expected KEY after PRIMARY, got %s", p.peekTok.Literal)
+			}
+			p.nextToken()
+			col.PrimaryKey = true
+		case p.peekIsWord("AUTO_INCREMENT"):
+			p.nextToken()
+			col.AutoIncrement = true
+		case p.peekTok.Type == lexer.UNIQUE:
+			p.nextToken()
+			col.Unique = true
+		case p.peekIsWord("NOT"):
+			p.nextToken()
+			if !p.peekIsWord("NULL") {
+				return ast.ColumnDefinition{}, fmt.Errorf("expected NULL after NOT, got %s", p.peekTok.Literal)
+			}
+			p.nextToken()
+			col.NotNull = true
+		default:
+			if col.AutoIncrement && (!col.PrimaryKey || col.Type != "INT") {
+				return ast.ColumnDefinition{}, fmt.Errorf("AUTO_INCREMENT column %s must be an INT PRIMARY KEY", col.Name)
+			}
+			return col, nil
+		}
+	}
+}
+
+// peekIsWord reports whether the next token is the given soft keyword, a
+// word only reserved where it appears, such as PRIMARY in a column
+// definition
+func (p *Parser) peekIsWord(word string) bool {
+	return p.peekTok.Type == lexer.IDENTIFIER && strings.EqualFold(p.peekTok.Literal, word)
+}
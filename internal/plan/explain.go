@@ -0,0 +1,179 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
+)
+
+// ExplainLines renders the plan tree as text, one indented line per node
+// Each line shows the node, its estimated cost and rows and, once the plan
+// has been analyzed, what the node actually did.
+func ExplainLines(node Node) []string {
+	var lines []string
+	explainLinesHelper(node, 0, &lines)
+	return lines
+}
+
+func explainLinesHelper(node Node, depth int, lines *[]string) {
+	if node == nil {
+		return
+	}
+
+	line := strings.Repeat("  ", depth)
+	if depth > 0 {
+		line += "-> "
+	}
+	line += Describe(node)
+
+	meta := node.Metadata()
+	if cost, ok := meta["estimated_cost"]; ok {
+		line += fmt.Sprintf("  (cost=%.2f rows=%v)", cost, meta["estimated_rows"])
+	}
+	if loops, ok := meta["actual_loops"]; ok {
+		if loops == int64(0) {
+			line += " (never executed)"
+		} else {
+			line += fmt.Sprintf(" (actual time=%.3f ms rows=%v loops=%v)",
+				meta["actual_time_ms"], meta["actual_rows"], loops)
+		}
+	}
+	*lines = append(*lines, line)
+
+	for _, child := range node.Children() {
+		explainLinesHelper(child, depth+1, lines)
+	}
+}
+
+// ExplainTree converts the plan tree into nested maps, ready to be encoded as
+// JSON
+// Every node carries its node_type, description and metadata; children are
+// listed under "plans".
+func ExplainTree(node Node) map[string]any {
+	tree := map[string]any{
+		"node_type":   node.NodeType(),
+		"description": Describe(node),
+	}
+	for key, value := range node.Metadata() {
+		if key == "cost_estimated" {
+			continue
+		}
+		tree[key] = value
+	}
+	if children := node.Children(); len(children) > 0 {
+		plans := make([]map[string]any, 0, len(children))
+		for _, child := range children {
+			if child != nil {
+				plans = append(plans, ExplainTree(child))
+			}
+		}
+		tree["plans"] = plans
+	}
+	return tree
+}
+
+// Describe returns a one-line description of what a node does, such as
+// "SCAN users WHERE (age > 18)"
+func Describe(node Node) string {
+	switch n := node.(type) {
+	case *ScanNode:
+		desc := "SCAN " + n.TableName
+		if n.Where != nil {
+			desc += " WHERE " + n.Where.String()
+		}
+		return desc
+	case *IndexScanNode:
+		desc := fmt.Sprintf("INDEX_SCAN %s USING %s", n.TableName, n.IndexColumn)
+		if n.IsRange() {
+			desc += " (range)"
+		} else {
+			desc += fmt.Sprintf(" (%d lookups)", len(n.Values))
+		}
+		if n.Ordered {
+			desc += " ORDER BY " + n.IndexColumn
+			if n.Descending {
+				desc += " DESC"
+			}
+		}
+		if n.Where != nil {
+			desc += " WHERE " + n.Where.String()
+		}
+		return desc
+	case *JoinNode:
+		desc := fmt.Sprintf("%s (%s)", n.JoinType, n.Strategy.Algorithm)
+		// Keys are only qualified when their input is itself a join; the
+		// input is otherwise the single table recorded by the planner
+		leftTable, _ := n.Metadata()["left_table"].(string)
+		rightTable, _ := n.Metadata()["right_table"].(string)
+		var conds []string
+		for _, key := range n.Keys() {
+			conds = append(conds, qualify(leftTable, key.Left)+" = "+qualify(rightTable, key.Right))
+		}
+		if n.On != nil {
+			conds = append(conds, n.On.String())
+		}
+		if len(conds) > 0 {
+			desc += " ON " + strings.Join(conds, " AND ")
+		}
+		return desc
+	case *SelectNode:
+		desc := "SELECT " + n.TableName
+		if n.Where != nil {
+			desc += " WHERE " + n.Where.String()
+		}
+		if len(n.GroupBy) > 0 {
+			desc += " GROUP BY " + columnList(n.GroupBy)
+		}
+		if n.IndexAggregates {
+			desc += " (MIN/MAX from index)"
+		}
+		if len(n.OrderBy) > 0 {
+			keys := make([]string, len(n.OrderBy))
+			for i, key := range n.OrderBy {
+				keys[i] = columnName(key.Column)
+				if key.Desc {
+					keys[i] += " DESC"
+				}
+			}
+			desc += " ORDER BY " + strings.Join(keys, ", ")
+		}
+		if n.Limit != nil {
+			desc += fmt.Sprintf(" LIMIT %d", *n.Limit)
+		}
+		return desc
+	case *InsertNode:
+		return "INSERT " + n.TableName
+	case *UpdateNode:
+		return "UPDATE " + n.TableName
+	case *DeleteNode:
+		return "DELETE " + n.TableName
+	default:
+		return node.NodeType()
+	}
+}
+
+// columnList joins column references with commas
+func columnList(columns []projection.ColumnRef) string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = columnName(col)
+	}
+	return strings.Join(names, ", ")
+}
+
+// columnName returns a column reference as written, qualified if it was
+func columnName(col projection.ColumnRef) string {
+	if col.Table != "" {
+		return col.Table + "." + col.Column
+	}
+	return col.Column
+}
+
+// qualify prefixes an unqualified join key column with its table
+func qualify(table, column string) string {
+	if table == "" || strings.Contains(column, ".") {
+		return column
+	}
+	return table + "." + column
+}
@@ -3,6 +3,9 @@ package plan
 import (
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/query/copying"
 	"github.com/leengari/mini-rdbms/internal/query/operations/join"
 	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
 )
@@ -22,6 +25,7 @@ type Node interface {
 // ScanNode represents a table scan operation (leaf node)
 type ScanNode struct {
 	TableName   string
+	Where       ast.Expression // filter pushed into the scan (nil if none)
 	Predicate   func(data.Row) bool
 	Transaction *transaction.Transaction
 	
@@ -43,11 +47,68 @@ func (n *ScanNode) NodeType() string {
 	return "SCAN"
 }
 
+// IndexScanNode reads a table through an index instead of scanning every row
+// (leaf node)
+//
+// Exactly one access path is used:
+//   - Values set: equality / IN lookup of each value (hash or BTREE index)
+//   - Lower and/or Upper set: range scan (BTREE index only)
+//   - neither set: every row with a non-NULL key, in key order (BTREE only)
+//
+// Ordered makes a range scan return its rows in key order - descending if
+// Descending is set - so that the scan answers ORDER BY IndexColumn.
+// Residual holds the WHERE conjuncts the index does not answer and is applied
+// to every fetched row. Predicate is the full WHERE clause, used instead when
+// index usage is disabled at execution time.
+type IndexScanNode struct {
+	TableName   string
+	IndexColumn string
+	Values      []interface{}
+	Lower       *index.Bound
+	Upper       *index.Bound
+	Ordered     bool
+	Descending  bool
+	Residual    func(data.Row) bool
+	Predicate   func(data.Row) bool
+	Where       ast.Expression // full WHERE clause, for estimation
+	Transaction *transaction.Transaction
+
+	metadata map[string]any
+}
+
+func (n *IndexScanNode) Children() []Node {
+	return nil // Leaf node has no children
+}
+
+func (n *IndexScanNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *IndexScanNode) NodeType() string {
+	return "INDEX_SCAN"
+}
+
+// IsRange reports whether the node performs a range scan rather than lookups
+func (n *IndexScanNode) IsRange() bool {
+	return n.Values == nil
+}
+
 // JoinNode represents a JOIN operation (composite node with two children)
 type JoinNode struct {
 	JoinType    join.JoinType
-	LeftOnCol   string
+	LeftOnCol   string // first equi-join key ("" if the ON condition has none)
 	RightOnCol  string
+	// MoreKeys holds the remaining pairs of a composite equi-join key
+	MoreKeys    []join.Key
+	// On is the rest of the ON condition, evaluated on each pair of rows
+	// whose keys match (nil if none)
+	On          ast.Expression
+	OnPredicate func(data.Row) bool
+	// Strategy is the physical join algorithm chosen by the planner
+	Strategy    join.Strategy
 	
 	// Tree structure - JOIN has two children
 	left  Node
@@ -66,6 +127,15 @@ func NewJoinNode(left, right Node, joinType join.JoinType, leftCol, rightCol str
 	}
 }
 
+// Keys returns every equi-join key pair of the ON condition
+func (n *JoinNode) Keys() []join.Key {
+	if n.LeftOnCol == "" {
+		return nil
+	}
+	keys := []join.Key{{Left: n.LeftOnCol, Right: n.RightOnCol}}
+	return append(keys, n.MoreKeys...)
+}
+
 func (n *JoinNode) Left() Node {
 	return n.left
 }
@@ -89,13 +159,51 @@ func (n *JoinNode) NodeType() string {
 	return "JOIN"
 }
 
+// CoalescedColumn is a join column shared by the tables of a USING or NATURAL
+// join, output once under its unqualified name
+// Its value is the first non-NULL value of Sources (qualified column names).
+type CoalescedColumn struct {
+	Name    string
+	Sources []string
+}
+
+// Aggregate is an aggregate function computed for every group of a SELECT
+// Column.Column is "*" for COUNT(*); Name is the output column, such as
+// "sum(amount)".
+type Aggregate struct {
+	Function string // COUNT, SUM, AVG, MIN or MAX
+	Column   projection.ColumnRef
+	Name     string
+}
+
+// SortKey is one ORDER BY key: a column, or the Name of an aggregate
+type SortKey struct {
+	Column projection.ColumnRef
+	Desc   bool
+}
+
 // SelectNode represents a SELECT operation
 type SelectNode struct {
 	TableName string
+	// Where is the analyzable WHERE expression the predicate was built from
+	Where ast.Expression
 	// Predicate filters rows. If nil, all rows are selected.
 	Predicate func(data.Row) bool
 	// Projection defines which columns to return.
 	Projection *projection.Projection
+	// Coalesce lists the columns merged by USING and NATURAL joins
+	Coalesce []CoalescedColumn
+	// GroupBy and Aggregates group the filtered rows; with aggregates but no
+	// GroupBy all rows form one group
+	GroupBy    []projection.ColumnRef
+	Aggregates []Aggregate
+	// OrderBy sorts the rows before projection and LIMIT
+	OrderBy []SortKey
+	// IndexAggregates reads the aggregates - each a MIN or MAX of a column
+	// with an ordered index - from the ends of the indexes instead of the rows
+	IndexAggregates bool
+	// Limit is the maximum number of rows to return (nil if unlimited)
+	Limit *int
 	// Transaction context
 	Transaction *transaction.Transaction
 	
@@ -127,7 +235,7 @@ func (n *SelectNode) NodeType() string {
 // InsertNode represents an INSERT operation
 type InsertNode struct {
 	TableName string
-	Row       data.Row // The row to insert (already parsed/converted)
+	Rows      []data.Row // The rows to insert, in order (already parsed/converted)
 	// Transaction context
 	Transaction *transaction.Transaction
 	
@@ -202,3 +310,132 @@ func (n *DeleteNode) Metadata() map[string]any {
 func (n *DeleteNode) NodeType() string {
 	return "DELETE"
 }
+
+// CreateIndexNode represents a CREATE INDEX operation
+type CreateIndexNode struct {
+	TableName string
+	IndexName string
+	Column    string
+	Kind      index.Kind
+	Unique    bool
+	// Transaction context
+	Transaction *transaction.Transaction
+
+	metadata map[string]any
+}
+
+func (n *CreateIndexNode) Children() []Node {
+	return nil
+}
+
+func (n *CreateIndexNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *CreateIndexNode) NodeType() string {
+	return "CREATE_INDEX"
+}
+
+// AnalyzeNode represents an ANALYZE operation
+type AnalyzeNode struct {
+	TableNames []string // tables to analyze
+	// Transaction context
+	Transaction *transaction.Transaction
+
+	metadata map[string]any
+}
+
+func (n *AnalyzeNode) Children() []Node {
+	return nil
+}
+
+func (n *AnalyzeNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *AnalyzeNode) NodeType() string {
+	return "ANALYZE"
+}
+
+// ExplainNode represents an EXPLAIN of another plan
+// With Analyze the explained plan is executed first and every node's metadata
+// receives actual_rows, actual_loops and actual_time_ms.
+type ExplainNode struct {
+	Plan    Node
+	Analyze bool
+	Format  string // TEXT or JSON
+
+	metadata map[string]any
+}
+
+func (n *ExplainNode) Children() []Node {
+	return []Node{n.Plan}
+}
+
+func (n *ExplainNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *ExplainNode) NodeType() string {
+	return "EXPLAIN"
+}
+
+// CopyFromNode represents a COPY of the records of a file into a table
+type CopyFromNode struct {
+	TableName string
+	Columns   []string // columns of the file's values; empty for all or a CSV header
+	Path      string
+	Options   copying.Options
+	// Transaction context
+	Transaction *transaction.Transaction
+
+	metadata map[string]any
+}
+
+func (n *CopyFromNode) Children() []Node {
+	return nil
+}
+
+func (n *CopyFromNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *CopyFromNode) NodeType() string {
+	return "COPY_FROM"
+}
+
+// CopyToNode represents a COPY of the rows of a query into a file
+type CopyToNode struct {
+	Query   *SelectNode
+	Path    string
+	Options copying.Options
+
+	metadata map[string]any
+}
+
+func (n *CopyToNode) Children() []Node {
+	return []Node{n.Query}
+}
+
+func (n *CopyToNode) Metadata() map[string]any {
+	if n.metadata == nil {
+		n.metadata = make(map[string]any)
+	}
+	return n.metadata
+}
+
+func (n *CopyToNode) NodeType() string {
+	return "COPY_TO"
+}
@@ -0,0 +1,30 @@
+package planner
+
+import "github.com/leengari/mini-rdbms/internal/parser/ast"
+
+// splitConjuncts flattens a WHERE expression into its top-level AND terms
+// e.g. (a = 1 AND (b > 2 AND c < 3)) → [a = 1, b > 2, c < 3]
+// OR expressions are kept whole since they cannot be split safely.
+func splitConjuncts(expr ast.Expression) []ast.Expression {
+	if expr == nil {
+		return nil
+	}
+	if logical, ok := expr.(*ast.LogicalExpression); ok && logical.Operator == "AND" {
+		return append(splitConjuncts(logical.Left), splitConjuncts(logical.Right)...)
+	}
+	return []ast.Expression{expr}
+}
+
+// joinConjuncts rebuilds an AND expression from a list of terms
+// Returns nil for an empty list.
+func joinConjuncts(conjuncts []ast.Expression) ast.Expression {
+	var result ast.Expression
+	for _, c := range conjuncts {
+		if result == nil {
+			result = c
+			continue
+		}
+		result = &ast.LogicalExpression{Left: result, Operator: "AND", Right: c}
+	}
+	return result
+}
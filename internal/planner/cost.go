@@ -1,29 +1,251 @@
 package planner
 
-import "github.com/leengari/mini-rdbms/internal/plan"
+import (
+	"math"
 
-// estimateCost estimates the cost of executing a plan node
-// Scaffold: Always returns 1.0 (naive implementation)
-func estimateCost(node plan.Node) float64 {
-	// Future: Implement real cost estimation based on:
-	// - Table sizes
-	// - Index availability
-	// - Join selectivity
-	// - Predicate selectivity
-	return 1.0
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/statistics"
+)
+
+// Cost units are relative: reading and filtering one row sequentially costs 1.
+const (
+	seqRowCost     = 1.0  // read + evaluate predicate on one row
+	indexRowCost   = 1.5  // fetch one row through an index (position indirection)
+	indexProbeBase = 0.5  // fixed cost of one index lookup
+	indexProbeStep = 0.25 // per level of a log2-sized index
+	hashBuildCost  = 1.5  // insert one row into a join hash table
+	hashProbeCost  = 1.0  // probe the hash table with one row
+	sortRowCost    = 0.2  // per row and per log2 level of an in-memory sort
+	mergeRowCost   = 0.5  // advance a merge join past one input row
+	pairRowCost    = 0.5  // compare one pair of rows in a nested-loop join
+	outputRowCost  = 0.1  // combine / project one output row
+)
+
+// estimateCost estimates the cost of executing a plan node, including its
+// children
+func estimateCost(node plan.Node, db *schema.Database) float64 {
+	switch n := node.(type) {
+	case *plan.ScanNode:
+		return tableRowCount(db, n.TableName) * seqRowCost
+
+	case *plan.IndexScanNode:
+		table, ok := db.Tables[n.TableName]
+		if !ok {
+			return 0
+		}
+		access := &indexAccess{column: n.IndexColumn, values: n.Values, lower: n.Lower, upper: n.Upper}
+		return indexScanCost(table, access)
+
+	case *plan.JoinNode:
+		if cost, ok := joinCost(n, n.Strategy, db); ok {
+			return cost
+		}
+		// No usable strategy chosen yet - executes as a hash join, or as a
+		// nested loop without join keys
+		cost, ok := joinCost(n, join.Strategy{Algorithm: join.AlgorithmHash}, db)
+		if !ok {
+			cost, _ = joinCost(n, join.Strategy{Algorithm: join.AlgorithmNestedLoop}, db)
+		}
+		return cost
+
+	case *plan.SelectNode:
+		if len(n.Children()) == 0 {
+			return tableRowCount(db, n.TableName)*seqRowCost +
+				estimateRowCount(n, db)*outputRowCost
+		}
+		cost := 0.0
+		for _, child := range n.Children() {
+			cost += estimateCost(child, db)
+		}
+		return cost + estimateRowCount(n, db)*outputRowCost
+	}
+
+	return 0
 }
 
-// attachCostEstimate attaches cost metadata to a node
-// Scaffold: Attaches naive cost estimate
-func attachCostEstimate(node plan.Node) {
-	cost := estimateCost(node)
-	node.Metadata()["estimated_cost"] = cost
-	node.Metadata()["cost_estimated"] = true
+// attachCostEstimate attaches row and cost estimates to a node and all of its
+// descendants
+func attachCostEstimate(node plan.Node, db *schema.Database) {
+	plan.WalkTree(node, func(n plan.Node) error {
+		n.Metadata()["estimated_rows"] = int64(math.Round(estimateRowCount(n, db)))
+		n.Metadata()["estimated_cost"] = math.Round(estimateCost(n, db)*100) / 100
+		n.Metadata()["cost_estimated"] = true
+		return nil
+	})
 }
 
 // estimateRowCount estimates the number of rows a node will return
-// Scaffold: Always returns 1000 (naive implementation)
-func estimateRowCount(node plan.Node) int64 {
-	// Future: Implement real row count estimation
-	return 1000
+// Uses ANALYZE statistics when available and default selectivities otherwise.
+func estimateRowCount(node plan.Node, db *schema.Database) float64 {
+	switch n := node.(type) {
+	case *plan.ScanNode:
+		rows := tableRowCount(db, n.TableName)
+		if table, ok := db.Tables[n.TableName]; ok && n.Where != nil {
+			rows *= statistics.Selectivity(n.Where, statistics.TableResolver(table))
+		} else if n.Predicate != nil {
+			rows *= statistics.DefaultSelectivity
+		}
+		return clampRows(rows)
+
+	case *plan.IndexScanNode:
+		table, ok := db.Tables[n.TableName]
+		if !ok {
+			return 0
+		}
+		return clampRows(tableRowCount(db, n.TableName) * statistics.Selectivity(n.Where, statistics.TableResolver(table)))
+
+	case *plan.JoinNode:
+		left := estimateRowCount(n.Left(), db)
+		right := estimateRowCount(n.Right(), db)
+		matched := left * right * joinSelectivity(n, db)
+
+		switch n.JoinType {
+		case join.JoinTypeLeft:
+			matched = math.Max(matched, left)
+		case join.JoinTypeRight:
+			matched = math.Max(matched, right)
+		case join.JoinTypeFull:
+			matched = math.Max(matched, math.Max(left, right))
+		}
+		return clampRows(matched)
+
+	case *plan.SelectNode:
+		var rows float64
+		if len(n.Children()) == 0 {
+			table, ok := db.Tables[n.TableName]
+			if !ok {
+				return 0
+			}
+			rows = tableRowCount(db, n.TableName) * statistics.Selectivity(n.Where, statistics.TableResolver(table))
+		} else {
+			child := n.Children()[0]
+			rows = estimateRowCount(child, db)
+			if n.Predicate != nil {
+				// WHERE over a JOIN - resolve columns against every joined table
+				rows *= statistics.Selectivity(n.Where, treeResolver(child, db))
+			}
+		}
+		if len(n.Aggregates) > 0 && len(n.GroupBy) == 0 {
+			// All rows aggregate into one
+			rows = 1
+		}
+		if n.Limit != nil {
+			rows = math.Min(rows, float64(*n.Limit))
+		}
+		return clampRows(rows)
+	}
+
+	return 0
+}
+
+// indexScanCost estimates the cost of reading a table through an index
+func indexScanCost(table *schema.Table, access *indexAccess) float64 {
+	info, _ := statistics.TableColumnInfo(table, access.column)
+	rows := float64(info.RowCount)
+
+	probes := 1.0
+	selectivity := 0.0
+	if access.values != nil {
+		probes = float64(len(access.values))
+		for _, v := range access.values {
+			selectivity += statistics.EqualitySelectivity(info, v)
+		}
+		selectivity = math.Min(selectivity, 1)
+	} else {
+		selectivity = statistics.RangeSelectivity(info, access.lower, access.upper)
+	}
+
+	return probes*indexProbeCost(rows) + clampRows(rows*selectivity)*indexRowCost
+}
+
+// seqScanCost estimates the cost of reading every row of a table
+func seqScanCost(table *schema.Table) float64 {
+	table.RLock()
+	defer table.RUnlock()
+	return float64(table.RowCountUnsafe()) * seqRowCost
+}
+
+// joinSelectivity estimates the fraction of the cross product matched by the
+// join's ON condition
+// Key pairs and filters are assumed independent.
+func joinSelectivity(n *plan.JoinNode, db *schema.Database) float64 {
+	resolveLeft := treeResolver(n.Left(), db)
+	resolveRight := treeResolver(n.Right(), db)
+
+	selectivity := 1.0
+	for _, key := range n.Keys() {
+		leftInfo, okLeft := resolveLeft(columnIdent(key.Left))
+		rightInfo, okRight := resolveRight(columnIdent(key.Right))
+		if !okLeft || !okRight {
+			selectivity *= statistics.DefaultEqualitySelectivity
+			continue
+		}
+		selectivity *= statistics.JoinSelectivity(leftInfo, rightInfo)
+	}
+	if n.On != nil {
+		selectivity *= statistics.Selectivity(n.On, treeResolver(n, db))
+	}
+	return selectivity
+}
+
+// treeResolver resolves columns against the tables scanned under node
+// Qualified identifiers must name one of those tables; unqualified ones
+// resolve to the first table that has the column.
+func treeResolver(node plan.Node, db *schema.Database) statistics.Resolver {
+	tables := scannedTables(node, db)
+	return func(ident *ast.Identifier) (statistics.ColumnInfo, bool) {
+		for _, table := range tables {
+			if ident.Table != "" && ident.Table != table.Name {
+				continue
+			}
+			if info, ok := statistics.TableColumnInfo(table, ident.Value); ok {
+				return info, true
+			}
+		}
+		return statistics.ColumnInfo{}, false
+	}
+}
+
+// scannedTables returns the tables read by the leaves of a plan subtree,
+// left to right
+func scannedTables(node plan.Node, db *schema.Database) []*schema.Table {
+	var tables []*schema.Table
+	plan.WalkTree(node, func(n plan.Node) error {
+		var name string
+		switch leaf := n.(type) {
+		case *plan.ScanNode:
+			name = leaf.TableName
+		case *plan.IndexScanNode:
+			name = leaf.TableName
+		default:
+			return nil
+		}
+		if table, ok := db.Tables[name]; ok {
+			tables = append(tables, table)
+		}
+		return nil
+	})
+	return tables
+}
+
+func tableRowCount(db *schema.Database, tableName string) float64 {
+	table, ok := db.Tables[tableName]
+	if !ok {
+		return 0
+	}
+	table.RLock()
+	defer table.RUnlock()
+	return float64(table.RowCountUnsafe())
+}
+
+// clampRows keeps row estimates at or above one row, since a plan that
+// expects zero rows is rarely right and makes every alternative look equal
+func clampRows(rows float64) float64 {
+	if rows < 1 {
+		return 1
+	}
+	return rows
 }
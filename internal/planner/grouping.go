@@ -0,0 +1,83 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
+)
+
+// planGrouping sets the GROUP BY columns, aggregates and ORDER BY keys of a
+// SELECT
+// In a grouped query - one with GROUP BY or an aggregate - every plain column
+// in the field list or ORDER BY must be a GROUP BY column. Aggregates used
+// only in ORDER BY are computed as well, then dropped by the projection.
+func planGrouping(stmt *ast.SelectStatement, node *plan.SelectNode) error {
+	seen := make(map[string]bool)
+	addAggregate := func(ident *ast.Identifier) {
+		name := ident.String()
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		node.Aggregates = append(node.Aggregates, plan.Aggregate{
+			Function: ident.Aggregate,
+			Column:   projection.ColumnRef{Table: ident.Table, Column: ident.Value},
+			Name:     name,
+		})
+	}
+	for _, field := range stmt.Fields {
+		if field.Aggregate != "" {
+			addAggregate(field)
+		}
+	}
+	for _, item := range stmt.OrderBy {
+		if item.Column.Aggregate != "" {
+			addAggregate(item.Column)
+		}
+	}
+	for _, col := range stmt.GroupBy {
+		node.GroupBy = append(node.GroupBy, projection.ColumnRef{Table: col.Table, Column: col.Value})
+	}
+
+	if len(node.GroupBy) > 0 || len(node.Aggregates) > 0 {
+		if node.Projection.SelectAll {
+			return fmt.Errorf("SELECT * is not allowed with GROUP BY or aggregates")
+		}
+		for _, field := range stmt.Fields {
+			if field.Aggregate == "" && !isGroupColumn(field, stmt.GroupBy) {
+				return fmt.Errorf("column %s must appear in GROUP BY or be used in an aggregate", field)
+			}
+		}
+		for _, item := range stmt.OrderBy {
+			if item.Column.Aggregate == "" && !isGroupColumn(item.Column, stmt.GroupBy) {
+				return fmt.Errorf("ORDER BY column %s must appear in GROUP BY or be used in an aggregate", item.Column)
+			}
+		}
+	}
+
+	for _, item := range stmt.OrderBy {
+		key := plan.SortKey{
+			Column: projection.ColumnRef{Table: item.Column.Table, Column: item.Column.Value},
+			Desc:   item.Desc,
+		}
+		if item.Column.Aggregate != "" {
+			// Aggregated rows hold the value under the aggregate's name
+			key.Column = projection.ColumnRef{Column: item.Column.String()}
+		}
+		node.OrderBy = append(node.OrderBy, key)
+	}
+	return nil
+}
+
+// isGroupColumn reports whether col names one of the GROUP BY columns
+// An unqualified name matches a qualified GROUP BY column and vice versa.
+func isGroupColumn(col *ast.Identifier, groupBy []*ast.Identifier) bool {
+	for _, g := range groupBy {
+		if g.Value == col.Value && (g.Table == col.Table || g.Table == "" || col.Table == "") {
+			return true
+		}
+	}
+	return false
+}
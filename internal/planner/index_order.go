@@ -0,0 +1,76 @@
+package planner
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+)
+
+// indexOrder returns the ORDER BY key of a single-table SELECT that an
+// ordered index could produce: a single key on a column of the table with an
+// ordered index, in a query without grouping
+func indexOrder(table *schema.Table, node *plan.SelectNode) (plan.SortKey, bool) {
+	if len(node.OrderBy) != 1 || len(node.GroupBy) > 0 || len(node.Aggregates) > 0 {
+		return plan.SortKey{}, false
+	}
+	key := node.OrderBy[0]
+	if key.Column.Table != "" && key.Column.Table != table.Name {
+		return plan.SortKey{}, false
+	}
+	if _, ok := table.OrderedIndex(key.Column.Column); !ok {
+		return plan.SortKey{}, false
+	}
+	return key, true
+}
+
+// orderedScan returns the access path reading every row of table in the
+// order of column, or nil if column can hold NULLs or the table is paged
+// NULL keys are not indexed, so the rows holding them would be missed; a
+// paged table would be loaded whole, where a sort streams its pages.
+func orderedScan(table *schema.Table, column string) *indexAccess {
+	col := table.Schema.GetColumn(column)
+	if col == nil || !(col.PrimaryKey || col.NotNull) || table.Paged() {
+		return nil
+	}
+	return &indexAccess{
+		column:   column,
+		unique:   col.PrimaryKey || col.Unique,
+		consumed: make(map[ast.Expression]bool),
+	}
+}
+
+// orderByIndex makes an index scan answer the ORDER BY of a SELECT when it
+// reads a range of the ORDER BY column, dropping the sort
+// Lookups of several values return rows in the order of the values, so only
+// ranges qualify.
+func orderByIndex(table *schema.Table, node *plan.SelectNode, scan *plan.IndexScanNode) {
+	key, ok := indexOrder(table, node)
+	if !ok || !scan.IsRange() || scan.IndexColumn != key.Column.Column {
+		return
+	}
+	scan.Ordered = true
+	scan.Descending = key.Desc
+	scan.Metadata()["ordered"] = true
+	node.OrderBy = nil
+}
+
+// indexAggregates reports whether every aggregate of a SELECT can be read
+// from an ordered index: a query without WHERE or GROUP BY whose aggregates
+// are all MIN or MAX of indexed columns
+func indexAggregates(table *schema.Table, stmt *ast.SelectStatement, node *plan.SelectNode) bool {
+	if stmt.Where != nil || len(node.GroupBy) > 0 || len(node.Aggregates) == 0 {
+		return false
+	}
+	for _, agg := range node.Aggregates {
+		if agg.Function != "MIN" && agg.Function != "MAX" {
+			return false
+		}
+		if agg.Column.Table != "" && agg.Column.Table != table.Name {
+			return false
+		}
+		if _, ok := table.OrderedIndex(agg.Column.Column); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package planner
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/planner/predicate"
+)
+
+// joinKey is an equality between a column of an already joined table and a
+// column of the table being joined
+type joinKey struct {
+	leftTable, leftColumn   string
+	rightTable, rightColumn string
+}
+
+// reversed returns the key with its sides swapped
+func (k joinKey) reversed() joinKey {
+	return joinKey{
+		leftTable:   k.rightTable,
+		leftColumn:  k.rightColumn,
+		rightTable:  k.leftTable,
+		rightColumn: k.leftColumn,
+	}
+}
+
+// joinFilter is an ON conjunct that is not a join key, evaluated on each pair
+// of rows whose keys match
+type joinFilter struct {
+	expr   ast.Expression // columns qualified with their table
+	pred   func(data.Row) bool
+	tables []string // tables whose columns expr uses
+}
+
+// onCondition is a JOIN ON condition split by how each conjunct is evaluated
+type onCondition struct {
+	keys    []joinKey
+	filters []joinFilter
+	// right holds the conjuncts on the joined table alone, applied while
+	// scanning it
+	right []ast.Expression
+}
+
+// splitOnCondition splits the ON condition joining right onto leftTables
+//
+// Equalities between a column of leftTables and a column of right become join
+// keys. Conjuncts using only right's columns are pushed into its scan when
+// pushRight is set: filtering the joined table first is equivalent for INNER
+// and LEFT joins, where its unmatched rows are dropped anyway. Everything
+// else is a filter on the joined rows.
+func splitOnCondition(leftTables []*schema.Table, right *schema.Table, cond ast.Expression, pushRight bool) (*onCondition, error) {
+	tables := append(append([]*schema.Table{}, leftTables...), right)
+	result := &onCondition{}
+
+	for _, conjunct := range splitConjuncts(cond) {
+		if key, ok := equiJoinKey(leftTables, right, conjunct); ok {
+			result.keys = append(result.keys, key)
+			continue
+		}
+
+		referenced, err := referencedTables(conjunct, tables)
+		if err != nil {
+			return nil, err
+		}
+		if pushRight && len(referenced) == 1 && referenced[0] == right.Name {
+			result.right = append(result.right, conjunct)
+			continue
+		}
+
+		expr := qualifyColumns(conjunct, tables)
+		pred, err := predicate.Build(expr)
+		if err != nil {
+			return nil, err
+		}
+		result.filters = append(result.filters, joinFilter{expr: expr, pred: pred, tables: referenced})
+	}
+	return result, nil
+}
+
+// equiJoinKey reports whether conjunct is an equality between a column of
+// leftTables and a column of right, written in either order
+func equiJoinKey(leftTables []*schema.Table, right *schema.Table, conjunct ast.Expression) (joinKey, bool) {
+	binExpr, ok := conjunct.(*ast.BinaryExpression)
+	if !ok || binExpr.Operator != "=" {
+		return joinKey{}, false
+	}
+	if _, ok := binExpr.Left.(*ast.Identifier); !ok {
+		return joinKey{}, false
+	}
+	if _, ok := binExpr.Right.(*ast.Identifier); !ok {
+		return joinKey{}, false
+	}
+
+	owner, leftCol, rightCol, err := resolveJoinColumns(leftTables, right, binExpr)
+	if err != nil {
+		return joinKey{}, false
+	}
+	return joinKey{
+		leftTable:   owner.Name,
+		leftColumn:  leftCol,
+		rightTable:  right.Name,
+		rightColumn: rightCol,
+	}, true
+}
@@ -0,0 +1,508 @@
+package planner
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/planner/predicate"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+)
+
+// dpJoinThreshold is the largest number of relations whose join order is
+// found by exhaustive dynamic programming; larger groups are ordered greedily
+const dpJoinThreshold = 8
+
+// joinRelation is one input of an inner-join group: a table scan, or a
+// subtree that must be joined as a unit (everything left of an outer join)
+type joinRelation struct {
+	node plan.Node
+}
+
+// joinEdge is an equi-join key between two relations of a group
+type joinEdge struct {
+	left, right int // relation indexes
+	key         joinKey
+}
+
+// groupFilter is an ON filter of an inner join and the relations it uses
+type groupFilter struct {
+	joinFilter
+	relations uint64 // bit set of relation indexes
+}
+
+// joinGroup collects consecutive inner joins, which may be executed in any
+// order
+type joinGroup struct {
+	relations []joinRelation
+	edges     []joinEdge
+	filters   []groupFilter
+	// unconditioned is set when a relation was added without a join
+	// condition (CROSS JOIN or a comma-separated table)
+	unconditioned bool
+	db            *schema.Database
+}
+
+// planJoins builds the JOIN tree for a SELECT
+// Runs of inner joins are reordered by estimated cost. Outer joins are
+// barriers: everything written before an outer join is joined first and
+// null-extended as a unit, so outer-join semantics are preserved.
+// pushed holds the WHERE conjuncts pushed down to each table's scan. When the
+// final inner-join group has a table joined without a condition, as in
+// "FROM a, b WHERE a.id = b.a_id", residual conjuncts joining its tables
+// become join conditions and are removed from pushed.residual.
+// Returns the columns merged by USING and NATURAL joins.
+func planJoins(tableName string, joins []*ast.JoinClause, pushed *pushdown, db *schema.Database, tx *transaction.Transaction) (plan.Node, []plan.CoalescedColumn, error) {
+	leftScan, err := newScanNode(tableName, pushed.scans[tableName], db, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	group := &joinGroup{db: db}
+	group.relations = append(group.relations, joinRelation{node: leftScan})
+	var coalesced []plan.CoalescedColumn
+
+	for i, joinClause := range joins {
+		// Validate join table
+		joinTableName := joinClause.RightTable.Value
+		joinTable, ok := db.Tables[joinTableName]
+		if !ok {
+			return nil, nil, fmt.Errorf("right table not found: %s", joinTableName)
+		}
+
+		jt, err := parseJoinType(joinClause.JoinType)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// USING and NATURAL joins are equalities on the shared columns
+		cond := joinClause.OnCondition
+		if joinClause.Natural || len(joinClause.Using) > 0 {
+			columns, err := usingColumns(joinClause, group.tables(), joinTable)
+			if err != nil {
+				return nil, nil, err
+			}
+			cond, coalesced, err = usingCondition(columns, group.tables(), joinTable, coalesced, nullableTables(tableName, joins[:i]))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Split the ON condition into join keys and filters
+		pushRight := jt == join.JoinTypeInner || jt == join.JoinTypeLeft
+		on, err := splitOnCondition(group.tables(), joinTable, cond, pushRight)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rightFilter := joinConjuncts(append(splitConjuncts(pushed.scans[joinTableName]), on.right...))
+		rightScan, err := newScanNode(joinTableName, rightFilter, db, tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if jt == join.JoinTypeInner {
+			group.add(rightScan, on)
+			continue
+		}
+
+		joinNode := newJoinNode(group.order(), rightScan, jt, on.keys, on.filters, db)
+		group = &joinGroup{db: db}
+		group.relations = append(group.relations, joinRelation{node: joinNode})
+	}
+
+	if group.unconditioned {
+		residual, err := group.absorb(pushed.residual)
+		if err != nil {
+			return nil, nil, err
+		}
+		pushed.residual = residual
+	}
+
+	return group.order(), coalesced, nil
+}
+
+// absorb turns the WHERE conjuncts that join two or more of the group's
+// relations into join keys and filters, and returns the remaining conjuncts
+// The group's joins sit above every outer join, so filtering there is
+// equivalent to filtering the joined rows.
+func (g *joinGroup) absorb(where ast.Expression) (ast.Expression, error) {
+	tables := g.tables()
+	var remaining []ast.Expression
+	for _, conjunct := range splitConjuncts(where) {
+		referenced, err := referencedTables(conjunct, tables)
+		var relations uint64
+		for _, name := range referenced {
+			relations |= 1 << g.relationOf(name)
+		}
+		if err != nil || bits.OnesCount64(relations) < 2 {
+			remaining = append(remaining, conjunct)
+			continue
+		}
+
+		if key, ok := g.whereKey(conjunct, tables); ok {
+			g.edges = append(g.edges, joinEdge{left: g.relationOf(key.leftTable), right: g.relationOf(key.rightTable), key: key})
+			continue
+		}
+
+		pred, err := predicate.Build(conjunct)
+		if err != nil {
+			return nil, err
+		}
+		g.filters = append(g.filters, groupFilter{
+			joinFilter: joinFilter{expr: conjunct, pred: pred, tables: referenced},
+			relations:  relations,
+		})
+	}
+	return joinConjuncts(remaining), nil
+}
+
+// whereKey reports whether conjunct is an equality between columns of two
+// different relations of the group
+func (g *joinGroup) whereKey(conjunct ast.Expression, tables []*schema.Table) (joinKey, bool) {
+	binExpr, ok := conjunct.(*ast.BinaryExpression)
+	if !ok || binExpr.Operator != "=" {
+		return joinKey{}, false
+	}
+	leftIdent, ok := binExpr.Left.(*ast.Identifier)
+	if !ok {
+		return joinKey{}, false
+	}
+	rightIdent, ok := binExpr.Right.(*ast.Identifier)
+	if !ok {
+		return joinKey{}, false
+	}
+
+	leftOwner, err := joinColumnOwner(tables, leftIdent)
+	if err != nil {
+		return joinKey{}, false
+	}
+	rightOwner, err := joinColumnOwner(tables, rightIdent)
+	if err != nil || g.relationOf(leftOwner.Name) == g.relationOf(rightOwner.Name) {
+		return joinKey{}, false
+	}
+	return joinKey{
+		leftTable:   leftOwner.Name,
+		leftColumn:  leftIdent.Value,
+		rightTable:  rightOwner.Name,
+		rightColumn: rightIdent.Value,
+	}, true
+}
+
+// add adds an inner-joined relation with the keys and filters of its ON
+// condition
+func (g *joinGroup) add(node plan.Node, on *onCondition) {
+	g.relations = append(g.relations, joinRelation{node: node})
+	r := len(g.relations) - 1
+	if len(on.keys) == 0 && len(on.filters) == 0 {
+		g.unconditioned = true
+	}
+
+	for _, key := range on.keys {
+		g.edges = append(g.edges, joinEdge{left: g.relationOf(key.leftTable), right: r, key: key})
+	}
+	for _, filter := range on.filters {
+		var relations uint64
+		for _, name := range filter.tables {
+			relations |= 1 << g.relationOf(name)
+		}
+		g.filters = append(g.filters, groupFilter{joinFilter: filter, relations: relations})
+	}
+}
+
+// tables returns every table joined by the group so far
+func (g *joinGroup) tables() []*schema.Table {
+	var tables []*schema.Table
+	for _, rel := range g.relations {
+		tables = append(tables, scannedTables(rel.node, g.db)...)
+	}
+	return tables
+}
+
+// relationOf returns the index of the relation that reads tableName
+func (g *joinGroup) relationOf(tableName string) int {
+	for i, rel := range g.relations {
+		for _, t := range scannedTables(rel.node, g.db) {
+			if t.Name == tableName {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// order returns the cheapest left-deep join tree over the group's relations
+func (g *joinGroup) order() plan.Node {
+	switch {
+	case len(g.relations) == 1:
+		return g.relations[0].node
+	case len(g.relations) <= dpJoinThreshold:
+		if best := g.orderDP(); best != nil {
+			return best
+		}
+	case len(g.relations) <= 64:
+		if best := g.orderGreedy(); best != nil {
+			return best
+		}
+	}
+	return g.orderWritten()
+}
+
+// orderDP finds the cheapest left-deep tree by dynamic programming over
+// subsets of relations
+// best[set] is the cheapest plan joining exactly the relations in set; every
+// plan for a set extends the best plan of a smaller set by one relation that
+// shares a join condition with it, so cross products are never considered.
+func (g *joinGroup) orderDP() plan.Node {
+	n := len(g.relations)
+	best := make(map[uint64]plan.Node)
+	bestCost := make(map[uint64]float64)
+	for i, rel := range g.relations {
+		best[1<<i] = rel.node
+	}
+
+	// Subsets of a set are numerically smaller, so they are always solved first.
+	// The latest written relation is tried first so ties keep the written order.
+	full := uint64(1)<<n - 1
+	for set := uint64(1); set <= full; set++ {
+		if bits.OnesCount64(set) < 2 {
+			continue
+		}
+		for r := n - 1; r >= 0; r-- {
+			if set&(1<<r) == 0 {
+				continue
+			}
+			rest := set &^ (1 << r)
+			left, ok := best[rest]
+			if !ok {
+				continue
+			}
+			candidate := g.extend(left, rest, r)
+			if candidate == nil {
+				continue
+			}
+			cost := estimateCost(candidate, g.db)
+			if _, seen := best[set]; !seen || cost < bestCost[set] {
+				best[set] = candidate
+				bestCost[set] = cost
+			}
+		}
+	}
+
+	return best[full]
+}
+
+// orderGreedy builds a left-deep tree starting from the cheapest two-relation
+// join and then repeatedly adding the relation that keeps the plan cheapest
+func (g *joinGroup) orderGreedy() plan.Node {
+	n := len(g.relations)
+
+	var current plan.Node
+	var set uint64
+	currentCost := 0.0
+	for i := 0; i < n; i++ {
+		for r := 0; r < n; r++ {
+			if r == i {
+				continue
+			}
+			candidate := g.extend(g.relations[i].node, 1<<i, r)
+			if candidate == nil {
+				continue
+			}
+			if cost := estimateCost(candidate, g.db); current == nil || cost < currentCost {
+				current, currentCost = candidate, cost
+				set = 1<<i | 1<<r
+			}
+		}
+	}
+
+	for current != nil && bits.OnesCount64(set) < n {
+		var next plan.Node
+		nextRel := -1
+		nextCost := 0.0
+		for r := 0; r < n; r++ {
+			if set&(1<<r) != 0 {
+				continue
+			}
+			candidate := g.extend(current, set, r)
+			if candidate == nil {
+				continue
+			}
+			if cost := estimateCost(candidate, g.db); next == nil || cost < nextCost {
+				next, nextRel, nextCost = candidate, r, cost
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+		set |= 1 << nextRel
+	}
+	return current
+}
+
+// orderWritten joins the relations in the order the JOIN clauses were written
+// Relations without a join condition are joined as a cross product.
+func (g *joinGroup) orderWritten() plan.Node {
+	current := g.relations[0].node
+	set := uint64(1)
+	for r := 1; r < len(g.relations); r++ {
+		current = g.join(current, set, r)
+		set |= 1 << r
+	}
+	return current
+}
+
+// extend joins relation r onto left, a plan covering the relations in set
+// Returns nil if no join condition connects r to set.
+func (g *joinGroup) extend(left plan.Node, set uint64, r int) *plan.JoinNode {
+	connected := false
+	for _, e := range g.edges {
+		if (e.right == r && set&(1<<e.left) != 0) || (e.left == r && set&(1<<e.right) != 0) {
+			connected = true
+		}
+	}
+	for _, f := range g.filters {
+		if f.relations&(1<<r) != 0 && f.relations&set != 0 && f.relations&^(set|1<<r) == 0 {
+			connected = true
+		}
+	}
+	if !connected {
+		return nil
+	}
+	return g.join(left, set, r)
+}
+
+// join joins relation r onto left, a plan covering the relations in set,
+// using every key between them and every filter they complete
+// A filter is applied at the first join covering all of its relations.
+func (g *joinGroup) join(left plan.Node, set uint64, r int) *plan.JoinNode {
+	var keys []joinKey
+	for _, e := range g.edges {
+		switch {
+		case e.right == r && set&(1<<e.left) != 0:
+			keys = append(keys, e.key)
+		case e.left == r && set&(1<<e.right) != 0:
+			keys = append(keys, e.key.reversed())
+		}
+	}
+
+	var filters []joinFilter
+	first := bits.OnesCount64(set) == 1
+	for _, f := range g.filters {
+		covered := f.relations&^(set|1<<r) == 0
+		if covered && (f.relations&(1<<r) != 0 || first) {
+			filters = append(filters, f.joinFilter)
+		}
+	}
+
+	return newJoinNode(left, g.relations[r].node, join.JoinTypeInner, keys, filters, g.db)
+}
+
+// newJoinNode creates a JOIN node and chooses its algorithm
+// Join columns are qualified with their table when that input is itself a
+// join, so they cannot be confused with a same-named column of another table.
+// The first key drives the join algorithm; without keys the join is a
+// nested loop evaluating the filters on every pair of rows.
+func newJoinNode(left, right plan.Node, jt join.JoinType, keys []joinKey, filters []joinFilter, db *schema.Database) *plan.JoinNode {
+	qualifyLeft := len(scannedTables(left, db)) > 1
+	qualifyRight := len(scannedTables(right, db)) > 1
+
+	joinKeys := make([]join.Key, len(keys))
+	for i, key := range keys {
+		joinKeys[i] = join.Key{Left: key.leftColumn, Right: key.rightColumn}
+		if qualifyLeft {
+			joinKeys[i].Left = join.QualifiedName(key.leftTable, key.leftColumn)
+		}
+		if qualifyRight {
+			joinKeys[i].Right = join.QualifiedName(key.rightTable, key.rightColumn)
+		}
+	}
+
+	joinNode := plan.NewJoinNode(left, right, jt, "", "")
+	if len(joinKeys) > 0 {
+		joinNode.LeftOnCol, joinNode.RightOnCol = joinKeys[0].Left, joinKeys[0].Right
+		if len(joinKeys) > 1 {
+			joinNode.MoreKeys = joinKeys[1:]
+		}
+		joinNode.Metadata()["left_table"] = keys[0].leftTable
+		joinNode.Metadata()["right_table"] = keys[0].rightTable
+	}
+
+	if len(filters) > 0 {
+		exprs := make([]ast.Expression, len(filters))
+		preds := make([]func(data.Row) bool, len(filters))
+		for i, f := range filters {
+			exprs[i], preds[i] = f.expr, f.pred
+		}
+		joinNode.On = joinConjuncts(exprs)
+		joinNode.OnPredicate = func(row data.Row) bool {
+			for _, pred := range preds {
+				if !pred(row) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	joinNode.Metadata()["keys"] = len(joinKeys)
+	joinNode.Metadata()["has_filter"] = joinNode.On != nil
+
+	joinNode.Strategy = selectJoinAlgorithm(joinNode, db)
+	joinNode.Metadata()["algorithm"] = string(joinNode.Strategy.Algorithm)
+	if joinNode.Strategy.Algorithm != join.AlgorithmMerge && joinNode.Strategy.Algorithm != join.AlgorithmNestedLoop {
+		joinNode.Metadata()["inner_side"] = joinNode.Strategy.Inner.String()
+	}
+	return joinNode
+}
+
+// newScanNode creates the scan feeding one table into a join
+// filter holds the WHERE conjuncts pushed down to the table; when one of them
+// can be answered through an index that wins on cost, the table is read with
+// an index scan instead of a sequential one.
+func newScanNode(tableName string, filter ast.Expression, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
+	var pred func(data.Row) bool
+	if filter != nil {
+		p, err := predicate.Build(filter)
+		if err != nil {
+			return nil, err
+		}
+		pred = p
+
+		if table, ok := db.Tables[tableName]; ok {
+			if _, access := selectScanType(table, filter); access != nil {
+				return buildIndexScan(tableName, access, filter, pred, tx)
+			}
+		}
+	}
+
+	scan := &plan.ScanNode{
+		TableName:   tableName,
+		Where:       filter,
+		Predicate:   pred,
+		Transaction: tx,
+	}
+	scan.Metadata()["scan_type"] = "sequential"
+	scan.Metadata()["table"] = tableName
+	scan.Metadata()["has_predicate"] = pred != nil
+	return scan, nil
+}
+
+// parseJoinType converts the parser's JOIN type into a join.JoinType
+func parseJoinType(joinType string) (join.JoinType, error) {
+	switch joinType {
+	case "INNER", "CROSS":
+		// A CROSS JOIN is an inner join without a condition
+		return join.JoinTypeInner, nil
+	case "LEFT":
+		return join.JoinTypeLeft, nil
+	case "RIGHT":
+		return join.JoinTypeRight, nil
+	case "FULL":
+		return join.JoinTypeFull, nil
+	default:
+		return 0, fmt.Errorf("unsupported JOIN type: %s", joinType)
+	}
+}
@@ -0,0 +1,222 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+)
+
+// joinCandidates lists every physical strategy considered for a join
+var joinCandidates = []join.Strategy{
+	{Algorithm: join.AlgorithmHash, Inner: join.SideRight},
+	{Algorithm: join.AlgorithmHash, Inner: join.SideLeft},
+	{Algorithm: join.AlgorithmMerge},
+	{Algorithm: join.AlgorithmIndexNestedLoop, Inner: join.SideRight},
+	{Algorithm: join.AlgorithmIndexNestedLoop, Inner: join.SideLeft},
+	{Algorithm: join.AlgorithmNestedLoop},
+}
+
+// selectJoinAlgorithm determines which join algorithm to use
+// Every applicable strategy is costed from the estimated input and output
+// sizes; the cheapest wins. Ties keep the earlier candidate, so an even
+// comparison falls back to the classic hash join building on the right.
+func selectJoinAlgorithm(joinNode *plan.JoinNode, db *schema.Database) join.Strategy {
+	var best join.Strategy
+	bestCost := math.Inf(1)
+
+	for _, strategy := range joinCandidates {
+		cost, ok := joinCost(joinNode, strategy, db)
+		if ok && cost < bestCost {
+			best, bestCost = strategy, cost
+		}
+	}
+	return best
+}
+
+// joinCost estimates the cost of a join using strategy, including its inputs
+// Returns false if the strategy cannot be used (e.g. no index to probe).
+// A nested loop is only used for joins without keys, where it is the only
+// choice.
+func joinCost(n *plan.JoinNode, strategy join.Strategy, db *schema.Database) (float64, bool) {
+	left, right := n.Left(), n.Right()
+	leftRows := estimateRowCount(left, db)
+	rightRows := estimateRowCount(right, db)
+	outputRows := estimateRowCount(n, db)
+	output := outputRows * outputRowCost
+
+	if n.LeftOnCol == "" {
+		if strategy.Algorithm != join.AlgorithmNestedLoop {
+			return 0, false
+		}
+		return estimateCost(left, db) + estimateCost(right, db) +
+			leftRows*rightRows*pairRowCost + output, true
+	}
+
+	switch strategy.Algorithm {
+	case join.AlgorithmHash:
+		buildRows, probeRows := rightRows, leftRows
+		if strategy.Inner == join.SideLeft {
+			buildRows, probeRows = leftRows, rightRows
+		}
+		return estimateCost(left, db) + estimateCost(right, db) +
+			buildRows*hashBuildCost + probeRows*hashProbeCost + output, true
+
+	case join.AlgorithmMerge:
+		cost := estimateCost(left, db) + estimateCost(right, db) +
+			(leftRows+rightRows)*mergeRowCost + output
+		if !orderedJoinInput(left, n.LeftOnCol, db) {
+			cost += sortCost(leftRows)
+		}
+		if !orderedJoinInput(right, n.RightOnCol, db) {
+			cost += sortCost(rightRows)
+		}
+		return cost, true
+
+	case join.AlgorithmIndexNestedLoop:
+		outer, inner, innerCol := left, right, n.RightOnCol
+		if strategy.Inner == join.SideLeft {
+			outer, inner, innerCol = right, left, n.LeftOnCol
+		}
+		table, ok := indexedJoinInput(inner, innerCol, db)
+		if !ok {
+			return 0, false
+		}
+		// The inner input is never scanned: each outer row is one index probe
+		return estimateCost(outer, db) +
+			estimateRowCount(outer, db)*indexProbeCost(tableRowCount(db, table.Name)) +
+			outputRows*indexRowCost + output, true
+	}
+
+	return 0, false
+}
+
+// baseJoinInput returns the stored table behind a join input that the
+// executor joins directly (an unfiltered sequential scan)
+func baseJoinInput(node plan.Node, db *schema.Database) (*schema.Table, bool) {
+	scan, ok := node.(*plan.ScanNode)
+	if !ok || scan.Predicate != nil {
+		return nil, false
+	}
+	table, ok := db.Tables[scan.TableName]
+	return table, ok
+}
+
+// indexedJoinInput returns the stored table behind a join input if it has an
+// index on the join column
+func indexedJoinInput(node plan.Node, column string, db *schema.Database) (*schema.Table, bool) {
+	table, ok := baseJoinInput(node, db)
+	if !ok {
+		return nil, false
+	}
+	table.RLock()
+	defer table.RUnlock()
+	_, ok = table.Indexes[column]
+	return table, ok
+}
+
+// orderedJoinInput reports whether a join input can be read in join key order
+// through an ordered index, so a merge join needs not sort it
+func orderedJoinInput(node plan.Node, column string, db *schema.Database) bool {
+	table, ok := baseJoinInput(node, db)
+	if !ok {
+		return false
+	}
+	_, ok = table.OrderedIndex(column)
+	return ok
+}
+
+// resolveJoinColumns matches the two sides of an equi-join condition to the
+// join inputs, whichever order they are written in
+// leftTables are the tables joined so far. Returns the table owning the left
+// column and the unqualified column names on each side.
+func resolveJoinColumns(leftTables []*schema.Table, right *schema.Table, cond *ast.BinaryExpression) (*schema.Table, string, string, error) {
+	leftIdent, ok := cond.Left.(*ast.Identifier)
+	if !ok {
+		return nil, "", "", fmt.Errorf("left side of JOIN condition must be an identifier")
+	}
+	rightIdent, ok := cond.Right.(*ast.Identifier)
+	if !ok {
+		return nil, "", "", fmt.Errorf("right side of JOIN condition must be an identifier")
+	}
+
+	inLeft := func(ident *ast.Identifier) bool {
+		_, err := joinColumnOwner(leftTables, ident)
+		return err == nil
+	}
+	inRight := func(ident *ast.Identifier) bool {
+		return (ident.Table == "" || ident.Table == right.Name) && right.Schema.GetColumn(ident.Value) != nil
+	}
+
+	// Swap when the condition is written right-to-left (ON orders.user_id = users.id).
+	// Unqualified columns present on both sides keep their written position.
+	firstLeftOnly := inLeft(leftIdent) && !inRight(leftIdent)
+	firstRightOnly := inRight(leftIdent) && !inLeft(leftIdent)
+	secondLeftOnly := inLeft(rightIdent) && !inRight(rightIdent)
+	secondRightOnly := inRight(rightIdent) && !inLeft(rightIdent)
+	if (firstRightOnly && !secondRightOnly) || (secondLeftOnly && !firstLeftOnly) {
+		leftIdent, rightIdent = rightIdent, leftIdent
+	}
+
+	owner, err := joinColumnOwner(leftTables, leftIdent)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !inRight(rightIdent) {
+		return nil, "", "", fmt.Errorf("column not found: %s.%s", right.Name, rightIdent.Value)
+	}
+	return owner, leftIdent.Value, rightIdent.Value, nil
+}
+
+// joinColumnOwner finds the table among tables that a column reference
+// belongs to
+// Unqualified columns must belong to exactly one of the tables.
+func joinColumnOwner(tables []*schema.Table, ident *ast.Identifier) (*schema.Table, error) {
+	var owner *schema.Table
+	for _, table := range tables {
+		if ident.Table != "" && ident.Table != table.Name {
+			continue
+		}
+		if table.Schema.GetColumn(ident.Value) == nil {
+			continue
+		}
+		if owner != nil {
+			return nil, fmt.Errorf("column reference %q is ambiguous: exists in %s and %s", ident.Value, owner.Name, table.Name)
+		}
+		owner = table
+	}
+
+	if owner == nil {
+		if ident.Table != "" {
+			return nil, fmt.Errorf("column not found: %s.%s", ident.Table, ident.Value)
+		}
+		return nil, fmt.Errorf("column not found: %s", ident.Value)
+	}
+	return owner, nil
+}
+
+// columnIdent converts a (possibly qualified) column name back into an
+// identifier
+func columnIdent(name string) *ast.Identifier {
+	if table, column, ok := strings.Cut(name, "."); ok {
+		return &ast.Identifier{Table: table, Value: column}
+	}
+	return &ast.Identifier{Value: name}
+}
+
+// indexProbeCost is the cost of one lookup in an index over rows entries
+func indexProbeCost(rows float64) float64 {
+	return indexProbeBase + indexProbeStep*math.Log2(rows+1)
+}
+
+// sortCost is the cost of sorting rows in memory
+func sortCost(rows float64) float64 {
+	if rows < 2 {
+		return 0
+	}
+	return rows * math.Log2(rows) * sortRowCost
+}
@@ -0,0 +1,103 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+)
+
+// usingColumns returns the columns a USING or NATURAL join matches on
+// A NATURAL join uses every column of right that also exists in an already
+// joined table, in right's column order.
+func usingColumns(joinClause *ast.JoinClause, leftTables []*schema.Table, right *schema.Table) ([]string, error) {
+	if !joinClause.Natural {
+		seen := make(map[string]bool)
+		columns := make([]string, 0, len(joinClause.Using))
+		for _, col := range joinClause.Using {
+			if seen[col.Value] {
+				return nil, fmt.Errorf("column %q appears more than once in USING", col.Value)
+			}
+			seen[col.Value] = true
+			columns = append(columns, col.Value)
+		}
+		return columns, nil
+	}
+
+	var columns []string
+	for _, col := range right.Schema.Columns {
+		for _, table := range leftTables {
+			if table.Schema.GetColumn(col.Name) != nil {
+				columns = append(columns, col.Name)
+				break
+			}
+		}
+	}
+	return columns, nil
+}
+
+// usingCondition builds the ON condition equivalent to a USING or NATURAL
+// join and adds its columns to coalesced
+//
+// Each column is matched against the joined table that holds it. When the
+// column was already merged by an earlier USING join, the first of its
+// tables that no outer join may null-extend is used, so "a JOIN b USING (id)
+// JOIN c USING (id)" matches c.id against a.id.
+// nullable holds the tables an earlier outer join may fill with NULLs.
+func usingCondition(columns []string, leftTables []*schema.Table, right *schema.Table, coalesced []plan.CoalescedColumn, nullable map[string]bool) (ast.Expression, []plan.CoalescedColumn, error) {
+	var conjuncts []ast.Expression
+	for _, name := range columns {
+		if right.Schema.GetColumn(name) == nil {
+			return nil, nil, fmt.Errorf("column not found: %s.%s", right.Name, name)
+		}
+
+		merged := -1
+		for i, c := range coalesced {
+			if c.Name == name {
+				merged = i
+				break
+			}
+		}
+
+		var leftTable string
+		if merged >= 0 {
+			for _, source := range coalesced[merged].Sources {
+				table, _, _ := strings.Cut(source, ".")
+				if !nullable[table] {
+					leftTable = table
+					break
+				}
+			}
+			if leftTable == "" {
+				return nil, nil, fmt.Errorf("USING column %q is NULL-extended by an outer join on both sides", name)
+			}
+		} else {
+			owner, err := joinColumnOwner(leftTables, &ast.Identifier{Value: name})
+			if err != nil {
+				return nil, nil, err
+			}
+			leftTable = owner.Name
+		}
+
+		leftSource := join.QualifiedName(leftTable, name)
+		rightSource := join.QualifiedName(right.Name, name)
+		conjuncts = append(conjuncts, &ast.BinaryExpression{
+			Left:     &ast.Identifier{TokenLiteralValue: leftSource, Table: leftTable, Value: name},
+			Operator: "=",
+			Right:    &ast.Identifier{TokenLiteralValue: rightSource, Table: right.Name, Value: name},
+		})
+
+		if merged >= 0 {
+			coalesced[merged].Sources = append(coalesced[merged].Sources, rightSource)
+		} else {
+			coalesced = append(coalesced, plan.CoalescedColumn{
+				Name:    name,
+				Sources: []string{leftSource, rightSource},
+			})
+		}
+	}
+	return joinConjuncts(conjuncts), coalesced, nil
+}
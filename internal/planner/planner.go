@@ -2,14 +2,17 @@ package planner
 
 import (
 	"fmt"
+	"sort"
+	"unicode/utf8"
 
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/index"
 	"github.com/leengari/mini-rdbms/internal/parser/ast"
 	"github.com/leengari/mini-rdbms/internal/plan"
 	"github.com/leengari/mini-rdbms/internal/planner/predicate"
-	"github.com/leengari/mini-rdbms/internal/query/operations/join"
+	"github.com/leengari/mini-rdbms/internal/query/copying"
 	"github.com/leengari/mini-rdbms/internal/query/operations/projection"
 	"github.com/leengari/mini-rdbms/internal/util/types"
 )
@@ -25,6 +28,14 @@ func Plan(stmt ast.Statement, db *schema.Database, tx *transaction.Transaction)
 		return planUpdate(s, db, tx)
 	case *ast.DeleteStatement:
 		return planDelete(s, db, tx)
+	case *ast.CreateIndexStatement:
+		return planCreateIndex(s, db, tx)
+	case *ast.AnalyzeStatement:
+		return planAnalyze(s, db, tx)
+	case *ast.ExplainStatement:
+		return planExplain(s, db, tx)
+	case *ast.CopyStatement:
+		return planCopy(s, db, tx)
 	default:
 		return nil, fmt.Errorf("unsupported statement type: %T", stmt)
 	}
@@ -33,7 +44,7 @@ func Plan(stmt ast.Statement, db *schema.Database, tx *transaction.Transaction)
 func planSelect(stmt *ast.SelectStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
 	// 1. Validate tables exist
 	tableName := stmt.TableName.Value
-	_, ok := db.Tables[tableName]
+	table, ok := db.Tables[tableName]
 	if !ok {
 		return nil, fmt.Errorf("table not found: %s", tableName)
 	}
@@ -50,7 +61,7 @@ func planSelect(stmt *ast.SelectStatement, db *schema.Database, tx *transaction.
 
 	// 3. Build Projection
 	var proj *projection.Projection
-	if len(stmt.Fields) == 1 && stmt.Fields[0].Value == "*" {
+	if len(stmt.Fields) == 1 && stmt.Fields[0].Value == "*" && stmt.Fields[0].Aggregate == "" {
 		proj = projection.NewProjection()
 	} else {
 		proj = &projection.Projection{
@@ -62,107 +73,130 @@ func planSelect(stmt *ast.SelectStatement, db *schema.Database, tx *transaction.
 				Table:  f.Table,
 				Column: f.Value,
 			}
+			if f.Aggregate != "" {
+				// Aggregated rows hold the value under the aggregate's name
+				proj.Columns[i] = projection.ColumnRef{Column: f.String()}
+			}
 		}
 	}
 
 	// 4. Build tree structure
 	selectNode := &plan.SelectNode{
 		TableName:   tableName,
+		Where:       stmt.Where,
 		Predicate:   pred,
 		Projection:  proj,
+		Limit:       stmt.Limit,
 		Transaction: tx,
 	}
 
+	if err := planGrouping(stmt, selectNode); err != nil {
+		return nil, err
+	}
+
 	// Attach metadata
 	selectNode.Metadata()["source_table"] = tableName
 	selectNode.Metadata()["has_predicate"] = pred != nil
-	selectNode.Metadata()["estimated_rows"] = 1000 // Scaffold: naive estimate
+
+	// Single-table queries may be answered through an index: MIN and MAX
+	// from its ends, WHERE through lookups or a range, and ORDER BY by reading
+	// the rows in key order
+	if len(stmt.Joins) == 0 && indexAggregates(table, stmt, selectNode) {
+		selectNode.IndexAggregates = true
+		selectNode.Metadata()["scan_type"] = "index"
+	} else if len(stmt.Joins) == 0 {
+		scanType, access := selectScanType(table, stmt.Where)
+		if key, ok := indexOrder(table, selectNode); ok && access == nil {
+			if access = orderedScan(table, key.Column.Column); access != nil {
+				scanType = "index"
+			}
+		}
+		selectNode.Metadata()["scan_type"] = scanType
+		if access != nil {
+			indexScan, err := buildIndexScan(tableName, access, stmt.Where, pred, tx)
+			if err != nil {
+				return nil, err
+			}
+			orderByIndex(table, selectNode, indexScan)
+			// The index scan applies the WHERE clause itself
+			selectNode.Predicate = nil
+			selectNode.AddChild(indexScan)
+		}
+	}
 
 	// 5. Build JOINs as tree children
 	if len(stmt.Joins) > 0 {
-		// Create base scan node for left table
-		// Note: We don't push down the full filter if there are joins,
-		// because the filter likely contains columns from other tables.
-		leftScan := &plan.ScanNode{
-			TableName:   tableName,
-			Predicate:   nil,
-			Transaction: tx,
+		// Single-table conjuncts are filtered while scanning; only the rest
+		// is evaluated on the joined rows
+		pushed := pushdownPredicates(tableName, stmt.Joins, stmt.Where, db)
+		joinTree, coalesced, err := planJoins(tableName, stmt.Joins, pushed, db, tx)
+		if err != nil {
+			return nil, err
 		}
-		leftScan.Metadata()["scan_type"] = "sequential" // Scaffold: always sequential
-		leftScan.Metadata()["table"] = tableName
-
-		// Build JOIN tree
-		currentNode := plan.Node(leftScan)
+		selectNode.Coalesce = coalesced
 
-		for _, joinClause := range stmt.Joins {
-			// Validate join table
-			joinTableName := joinClause.RightTable.Value
-			_, ok := db.Tables[joinTableName]
-			if !ok {
-				return nil, fmt.Errorf("right table not found: %s", joinTableName)
+		selectNode.Where = pushed.residual
+		selectNode.Predicate = nil
+		if pushed.residual != nil {
+			p, err := predicate.Build(pushed.residual)
+			if err != nil {
+				return nil, err
 			}
+			selectNode.Predicate = p
+		}
+		selectNode.Metadata()["has_predicate"] = selectNode.Predicate != nil
 
-			// Parse ON condition
-			binExpr, ok := joinClause.OnCondition.(*ast.BinaryExpression)
-			if !ok {
-				return nil, fmt.Errorf("JOIN ON condition must be a comparison expression")
-			}
-			if binExpr.Operator != "=" {
-				return nil, fmt.Errorf("JOIN ON condition must use = operator")
-			}
+		order := make([]string, 0, len(stmt.Joins)+1)
+		for _, t := range scannedTables(joinTree, db) {
+			order = append(order, t.Name)
+		}
+		selectNode.Metadata()["join_order"] = order
 
-			leftIdent, ok := binExpr.Left.(*ast.Identifier)
-			if !ok {
-				return nil, fmt.Errorf("left side of JOIN condition must be an identifier")
-			}
-			rightIdent, ok := binExpr.Right.(*ast.Identifier)
-			if !ok {
-				return nil, fmt.Errorf("right side of JOIN condition must be an identifier")
-			}
+		// Add the final JOIN tree as child of SelectNode
+		selectNode.AddChild(joinTree)
+	}
 
-			// Convert string type to enum
-			var jt join.JoinType
-			switch joinClause.JoinType {
-			case "INNER":
-				jt = join.JoinTypeInner
-			case "LEFT":
-				jt = join.JoinTypeLeft
-			case "RIGHT":
-				jt = join.JoinTypeRight
-			case "FULL":
-				jt = join.JoinTypeFull
-			default:
-				return nil, fmt.Errorf("unsupported JOIN type: %s", joinClause.JoinType)
-			}
+	// 6. Estimate rows and cost for every node from table statistics
+	attachCostEstimate(selectNode, db)
 
-			// Create scan node for right table
-			rightScan := &plan.ScanNode{
-				TableName:   joinTableName,
-				Transaction: tx,
-			}
-			rightScan.Metadata()["scan_type"] = "sequential" // Scaffold: always sequential
-			rightScan.Metadata()["table"] = joinTableName
-
-			// Create JOIN node with left and right children
-			joinNode := plan.NewJoinNode(
-				currentNode,
-				rightScan,
-				jt,
-				leftIdent.Value,
-				rightIdent.Value,
-			)
-			joinNode.Metadata()["join_algorithm"] = "nested_loop" // Scaffold: always nested loop
-			joinNode.Metadata()["left_table"] = tableName
-			joinNode.Metadata()["right_table"] = joinTableName
-
-			currentNode = joinNode
+	return selectNode, nil
+}
+
+// buildIndexScan creates an IndexScanNode for the chosen access path
+// The WHERE conjuncts not answered by the index become the residual predicate.
+func buildIndexScan(tableName string, access *indexAccess, where ast.Expression, pred func(data.Row) bool, tx *transaction.Transaction) (*plan.IndexScanNode, error) {
+	var remaining []ast.Expression
+	for _, conjunct := range splitConjuncts(where) {
+		if !access.consumed[conjunct] {
+			remaining = append(remaining, conjunct)
 		}
+	}
 
-		// Add the final JOIN tree as child of SelectNode
-		selectNode.AddChild(currentNode)
+	var residual func(data.Row) bool
+	if residualExpr := joinConjuncts(remaining); residualExpr != nil {
+		p, err := predicate.Build(residualExpr)
+		if err != nil {
+			return nil, err
+		}
+		residual = p
 	}
 
-	return selectNode, nil
+	node := &plan.IndexScanNode{
+		TableName:   tableName,
+		IndexColumn: access.column,
+		Values:      access.values,
+		Lower:       access.lower,
+		Upper:       access.upper,
+		Residual:    residual,
+		Predicate:   pred,
+		Where:       where,
+		Transaction: tx,
+	}
+	node.Metadata()["table"] = tableName
+	node.Metadata()["scan_type"] = "index"
+	node.Metadata()["index_column"] = access.column
+	node.Metadata()["has_residual"] = residual != nil
+	return node, nil
 }
 
 func planInsert(stmt *ast.InsertStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
@@ -172,32 +206,36 @@ func planInsert(stmt *ast.InsertStatement, db *schema.Database, tx *transaction.
 		return nil, fmt.Errorf("table not found: %s", tableName)
 	}
 
-	if len(stmt.Columns) != len(stmt.Values) {
-		return nil, fmt.Errorf("column count (%d) does not match value count (%d)", len(stmt.Columns), len(stmt.Values))
-	}
-
-	row := make(map[string]interface{})
-	for i, col := range stmt.Columns {
-		lit, ok := stmt.Values[i].(*ast.Literal)
-		if !ok {
-			return nil, fmt.Errorf("only literals supported in VALUES")
+	rows := make([]data.Row, 0, len(stmt.Rows))
+	for _, values := range stmt.Rows {
+		if len(stmt.Columns) != len(values) {
+			return nil, fmt.Errorf("column count (%d) does not match value count (%d)", len(stmt.Columns), len(values))
 		}
 
-		schemaCol := findColumnInSchema(table, col.Value)
-		if schemaCol != nil {
-			convertedLit, err := types.ConvertLiteralToSchemaType(lit, schemaCol.Type)
-			if err != nil {
-				return nil, fmt.Errorf("column '%s': %w", col.Value, err)
+		row := make(map[string]interface{})
+		for i, col := range stmt.Columns {
+			lit, ok := values[i].(*ast.Literal)
+			if !ok {
+				return nil, fmt.Errorf("only literals supported in VALUES")
+			}
+
+			schemaCol := findColumnInSchema(table, col.Value)
+			if schemaCol != nil {
+				convertedLit, err := types.ConvertLiteralToSchemaType(lit, schemaCol.Type)
+				if err != nil {
+					return nil, fmt.Errorf("column '%s': %w", col.Value, err)
+				}
+				row[col.Value] = convertedLit.Value
+			} else {
+				row[col.Value] = lit.Value
 			}
-			row[col.Value] = convertedLit.Value
-		} else {
-			row[col.Value] = lit.Value
 		}
+		rows = append(rows, data.NewRow(row))
 	}
 
 	return &plan.InsertNode{
 		TableName:   tableName,
-		Row:         data.NewRow(row),
+		Rows:        rows,
 		Transaction: tx,
 	}, nil
 }
@@ -292,3 +330,114 @@ func findColumnInSchema(table *schema.Table, colName string) *schema.Column {
 	}
 	return nil
 }
+
+func planCreateIndex(stmt *ast.CreateIndexStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
+	tableName := stmt.TableName.Value
+	table, ok := db.Tables[tableName]
+	if !ok {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+
+	if table.Schema.GetColumn(stmt.Column.Value) == nil {
+		return nil, fmt.Errorf("column not found: %s.%s", tableName, stmt.Column.Value)
+	}
+
+	// B+tree is the default, like most relational databases
+	kind := index.KindBTree
+	if stmt.Using != "" {
+		k, ok := index.ParseKind(stmt.Using)
+		if !ok {
+			return nil, fmt.Errorf("unsupported index type: %s", stmt.Using)
+		}
+		kind = k
+	}
+
+	return &plan.CreateIndexNode{
+		TableName:   tableName,
+		IndexName:   stmt.Name,
+		Column:      stmt.Column.Value,
+		Kind:        kind,
+		Unique:      stmt.Unique,
+		Transaction: tx,
+	}, nil
+}
+
+func planAnalyze(stmt *ast.AnalyzeStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
+	node := &plan.AnalyzeNode{Transaction: tx}
+
+	if stmt.TableName != nil {
+		if _, ok := db.Tables[stmt.TableName.Value]; !ok {
+			return nil, fmt.Errorf("table not found: %s", stmt.TableName.Value)
+		}
+		node.TableNames = []string{stmt.TableName.Value}
+		return node, nil
+	}
+
+	for name := range db.Tables {
+		node.TableNames = append(node.TableNames, name)
+	}
+	sort.Strings(node.TableNames)
+	return node, nil
+}
+
+func planExplain(stmt *ast.ExplainStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
+	explained, err := Plan(stmt.Statement, db, tx)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.ExplainNode{
+		Plan:    explained,
+		Analyze: stmt.Analyze,
+		Format:  stmt.Format,
+	}, nil
+}
+
+func planCopy(stmt *ast.CopyStatement, db *schema.Database, tx *transaction.Transaction) (plan.Node, error) {
+	opts := copying.Options{
+		Format: copying.Format(stmt.Format),
+		Header: stmt.Header,
+		Null:   stmt.Null,
+	}
+	opts.Delimiter, _ = utf8.DecodeRuneInString(stmt.Delimiter)
+
+	if !stmt.To {
+		tableName := stmt.TableName.Value
+		table, ok := db.Tables[tableName]
+		if !ok {
+			return nil, fmt.Errorf("table not found: %s", tableName)
+		}
+		columns := make([]string, len(stmt.Columns))
+		for i, col := range stmt.Columns {
+			if table.Schema.GetColumn(col.Value) == nil {
+				return nil, fmt.Errorf("column not found: %s.%s", tableName, col.Value)
+			}
+			columns[i] = col.Value
+		}
+		return &plan.CopyFromNode{
+			TableName:   tableName,
+			Columns:     columns,
+			Path:        stmt.Path,
+			Options:     opts,
+			Transaction: tx,
+		}, nil
+	}
+
+	// Copying a table to a file copies SELECT columns FROM table
+	query := stmt.Query
+	if query == nil {
+		fields := stmt.Columns
+		if len(fields) == 0 {
+			fields = []*ast.Identifier{{TokenLiteralValue: "*", Value: "*"}}
+		}
+		query = &ast.SelectStatement{Fields: fields, TableName: stmt.TableName}
+	}
+	selectNode, err := planSelect(query, db, tx)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.CopyToNode{
+		Query:   selectNode.(*plan.SelectNode),
+		Path:    stmt.Path,
+		Options: opts,
+	}, nil
+}
@@ -15,6 +15,8 @@ type PredicateFunc func(data.Row) bool
 // Supports:
 //   - Comparison operators: =, <, >, <=, >=, !=, <>
 //   - Logical operators: AND, OR
+//   - Column-to-column comparisons (users.id = orders.user_id)
+//   - IN (value list) and BETWEEN value AND value
 //   - Nested expressions with parentheses
 // Returns a function that tests whether a row matches the condition
 func Build(expr ast.Expression) (PredicateFunc, error) {
@@ -26,6 +28,12 @@ func Build(expr ast.Expression) (PredicateFunc, error) {
 	case *ast.LogicalExpression:
 		// Handle logical expressions (expr AND/OR expr)
 		return buildLogical(e)
+
+	case *ast.InExpression:
+		return buildIn(e)
+
+	case *ast.BetweenExpression:
+		return buildBetween(e)
 		
 	default:
 		return nil, fmt.Errorf("unsupported expression type in WHERE clause: %T", expr)
@@ -33,48 +41,184 @@ func Build(expr ast.Expression) (PredicateFunc, error) {
 }
 
 // buildComparison builds a predicate for comparison expressions
+// Either side may be a column; a literal on the left is moved to the right
+// (5 < age becomes age > 5). Comparing two columns never matches a NULL.
 func buildComparison(binExpr *ast.BinaryExpression) (PredicateFunc, error) {
-	leftIdent, ok := binExpr.Left.(*ast.Identifier)
-	if !ok {
-		return nil, fmt.Errorf("left side of comparison must be an identifier")
+	left, right, operator := binExpr.Left, binExpr.Right, binExpr.Operator
+	if _, ok := left.(*ast.Literal); ok {
+		left, right, operator = right, left, flipOperator(operator)
 	}
 
-	rightLit, ok := binExpr.Right.(*ast.Literal)
+	leftIdent, ok := left.(*ast.Identifier)
 	if !ok {
-		return nil, fmt.Errorf("right side of comparison must be a literal")
+		return nil, fmt.Errorf("comparison must reference a column")
 	}
 
 	// Get column name (may be qualified like "orders.amount" or unqualified like "amount")
 	colName := leftIdent.Value
 	tableName := leftIdent.Table
-	operator := binExpr.Operator
-	targetVal := rightLit.Value
 
-	return func(row data.Row) bool {
-		var val interface{}
-		var ok bool
+	switch r := right.(type) {
+	case *ast.Literal:
+		targetVal := r.Value
+		return func(row data.Row) bool {
+			val, ok := columnValue(row, tableName, colName)
+			if !ok {
+				return false
+			}
+
+			// Use types.CompareValues to handle all comparison operators
+			return types.CompareValues(val, operator, targetVal)
+		}, nil
+
+	case *ast.Identifier:
+		other := operand(r)
+		return func(row data.Row) bool {
+			val, ok := columnValue(row, tableName, colName)
+			if !ok || val == nil {
+				return false
+			}
+			target, ok := other(row)
+			if !ok {
+				return false
+			}
+			return types.CompareValues(val, operator, target)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("right side of comparison must be a column or a literal")
+	}
+}
+
+// buildIn builds a predicate for col IN (v1, v2, ...)
+// List items may be literals or other columns.
+func buildIn(inExpr *ast.InExpression) (PredicateFunc, error) {
+	leftIdent, ok := inExpr.Left.(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("left side of IN must be an identifier")
+	}
 
-		// Try qualified name first if table is specified (e.g., "orders.amount")
-		if tableName != "" {
-			qualifiedName := tableName + "." + colName
-			val, ok = row.Data[qualifiedName]
+	targets := make([]valueFunc, len(inExpr.Values))
+	for i, v := range inExpr.Values {
+		target, err := buildOperand(v)
+		if err != nil {
+			return nil, fmt.Errorf("IN list must contain only literals and columns")
 		}
+		targets[i] = target
+	}
+
+	colName := leftIdent.Value
+	tableName := leftIdent.Table
 
-		// If not found with qualified name, try unqualified (e.g., "amount")
+	return func(row data.Row) bool {
+		val, ok := columnValue(row, tableName, colName)
 		if !ok {
-			val, ok = row.Data[colName]
+			return false
 		}
+		for _, target := range targets {
+			if t, ok := target(row); ok && types.CompareValues(val, "=", t) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// buildBetween builds a predicate for col BETWEEN lower AND upper (inclusive)
+// The bounds may be literals or other columns.
+func buildBetween(betweenExpr *ast.BetweenExpression) (PredicateFunc, error) {
+	leftIdent, ok := betweenExpr.Left.(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("left side of BETWEEN must be an identifier")
+	}
+
+	lower, err := buildOperand(betweenExpr.Lower)
+	if err != nil {
+		return nil, fmt.Errorf("BETWEEN lower bound must be a literal or a column")
+	}
+	upper, err := buildOperand(betweenExpr.Upper)
+	if err != nil {
+		return nil, fmt.Errorf("BETWEEN upper bound must be a literal or a column")
+	}
 
-		// If still not found, return false
+	colName := leftIdent.Value
+	tableName := leftIdent.Table
+
+	return func(row data.Row) bool {
+		val, ok := columnValue(row, tableName, colName)
 		if !ok {
 			return false
 		}
-		
-		// Use types.CompareValues to handle all comparison operators
-		return types.CompareValues(val, operator, targetVal)
+		lo, okLower := lower(row)
+		hi, okUpper := upper(row)
+		if !okLower || !okUpper {
+			return false
+		}
+		return types.CompareValues(val, ">=", lo) && types.CompareValues(val, "<=", hi)
 	}, nil
 }
 
+// valueFunc returns the value of an operand for a row
+// Returns false if the value is unknown: a missing or NULL column.
+type valueFunc func(data.Row) (interface{}, bool)
+
+// buildOperand builds a valueFunc for a literal or a column reference
+func buildOperand(expr ast.Expression) (valueFunc, error) {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		value := e.Value
+		return func(data.Row) (interface{}, bool) {
+			return value, true
+		}, nil
+	case *ast.Identifier:
+		return operand(e), nil
+	default:
+		return nil, fmt.Errorf("unsupported operand: %T", expr)
+	}
+}
+
+// operand returns a valueFunc reading a column
+func operand(ident *ast.Identifier) valueFunc {
+	tableName, colName := ident.Table, ident.Value
+	return func(row data.Row) (interface{}, bool) {
+		val, ok := columnValue(row, tableName, colName)
+		if !ok || val == nil {
+			return nil, false
+		}
+		return val, true
+	}
+}
+
+// flipOperator returns the operator that gives the same result with the
+// operands swapped
+func flipOperator(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case ">":
+		return "<"
+	case "<=":
+		return ">="
+	case ">=":
+		return "<="
+	default:
+		return op
+	}
+}
+
+// columnValue looks up a column in a row
+// Tries the qualified name first if table is specified (e.g., "orders.amount"),
+// then the unqualified name (e.g., "amount")
+func columnValue(row data.Row, tableName, colName string) (interface{}, bool) {
+	if tableName != "" {
+		if val, ok := row.Data[tableName+"."+colName]; ok {
+			return val, true
+		}
+	}
+	val, ok := row.Data[colName]
+	return val, ok
+}
+
 // buildLogical builds a predicate for logical expressions (AND/OR)
 // Recursively builds predicates for left and right sub-expressions
 func buildLogical(logExpr *ast.LogicalExpression) (PredicateFunc, error) {
@@ -0,0 +1,175 @@
+package planner
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+)
+
+// pushdown is a JOIN query's WHERE clause split by where each term is
+// evaluated
+type pushdown struct {
+	// scans holds the conjuncts evaluated while scanning each table
+	scans map[string]ast.Expression
+	// residual holds the conjuncts evaluated on the joined rows
+	residual ast.Expression
+}
+
+// pushdownPredicates splits a WHERE clause over a JOIN into per-table filters
+// and a residual filter applied after the join
+//
+// A conjunct referencing a single table is pushed into that table's scan,
+// unless an outer join may null-extend the table: filtering such a table before
+// the join would turn rows the WHERE clause rejects into NULL-extended rows
+// instead of removing them. Everything else stays above the join, with
+// unqualified columns qualified so they resolve against the joined rows.
+func pushdownPredicates(tableName string, joins []*ast.JoinClause, where ast.Expression, db *schema.Database) *pushdown {
+	result := &pushdown{scans: make(map[string]ast.Expression)}
+	if where == nil {
+		return result
+	}
+
+	var tables []*schema.Table
+	if table, ok := db.Tables[tableName]; ok {
+		tables = append(tables, table)
+	}
+	for _, joinClause := range joins {
+		if table, ok := db.Tables[joinClause.RightTable.Value]; ok {
+			tables = append(tables, table)
+		}
+	}
+	nullable := nullableTables(tableName, joins)
+
+	scanConjuncts := make(map[string][]ast.Expression)
+	var residual []ast.Expression
+	for _, conjunct := range splitConjuncts(where) {
+		referenced, err := referencedTables(conjunct, tables)
+		if err == nil && len(referenced) == 1 && !nullable[referenced[0]] {
+			scanConjuncts[referenced[0]] = append(scanConjuncts[referenced[0]], conjunct)
+			continue
+		}
+		residual = append(residual, qualifyColumns(conjunct, tables))
+	}
+
+	for name, conjuncts := range scanConjuncts {
+		result.scans[name] = joinConjuncts(conjuncts)
+	}
+	result.residual = joinConjuncts(residual)
+	return result
+}
+
+// nullableTables returns the tables whose columns an outer join may fill
+// with NULLs: the right side of a LEFT JOIN, everything joined before a RIGHT
+// JOIN, and both sides of a FULL JOIN
+func nullableTables(tableName string, joins []*ast.JoinClause) map[string]bool {
+	nullable := make(map[string]bool)
+	joined := []string{tableName}
+
+	for _, joinClause := range joins {
+		right := joinClause.RightTable.Value
+		switch joinClause.JoinType {
+		case "LEFT":
+			nullable[right] = true
+		case "RIGHT":
+			for _, name := range joined {
+				nullable[name] = true
+			}
+		case "FULL":
+			nullable[right] = true
+			for _, name := range joined {
+				nullable[name] = true
+			}
+		}
+		joined = append(joined, right)
+	}
+	return nullable
+}
+
+// referencedTables returns the names of the tables whose columns expr uses
+// Returns an error if a column cannot be attributed to exactly one table.
+func referencedTables(expr ast.Expression, tables []*schema.Table) ([]string, error) {
+	var names []string
+	var err error
+	seen := make(map[string]bool)
+
+	walkIdentifiers(expr, func(ident *ast.Identifier) {
+		owner, ownerErr := joinColumnOwner(tables, ident)
+		if ownerErr != nil {
+			if err == nil {
+				err = ownerErr
+			}
+			return
+		}
+		if !seen[owner.Name] {
+			seen[owner.Name] = true
+			names = append(names, owner.Name)
+		}
+	})
+	return names, err
+}
+
+// walkIdentifiers calls fn for every column reference in expr
+func walkIdentifiers(expr ast.Expression, fn func(*ast.Identifier)) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		fn(e)
+	case *ast.BinaryExpression:
+		walkIdentifiers(e.Left, fn)
+		walkIdentifiers(e.Right, fn)
+	case *ast.LogicalExpression:
+		walkIdentifiers(e.Left, fn)
+		walkIdentifiers(e.Right, fn)
+	case *ast.InExpression:
+		walkIdentifiers(e.Left, fn)
+		for _, v := range e.Values {
+			walkIdentifiers(v, fn)
+		}
+	case *ast.BetweenExpression:
+		walkIdentifiers(e.Left, fn)
+		walkIdentifiers(e.Lower, fn)
+		walkIdentifiers(e.Upper, fn)
+	}
+}
+
+// qualifyColumns returns a copy of expr in which every unqualified column
+// belonging to exactly one of tables is qualified with that table's name
+// Joined rows only hold qualified column names ("users.id").
+func qualifyColumns(expr ast.Expression, tables []*schema.Table) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if e.Table != "" {
+			return e
+		}
+		owner, err := joinColumnOwner(tables, e)
+		if err != nil {
+			return e
+		}
+		qualified := *e
+		qualified.Table = owner.Name
+		return &qualified
+	case *ast.BinaryExpression:
+		copied := *e
+		copied.Left = qualifyColumns(e.Left, tables)
+		copied.Right = qualifyColumns(e.Right, tables)
+		return &copied
+	case *ast.LogicalExpression:
+		copied := *e
+		copied.Left = qualifyColumns(e.Left, tables)
+		copied.Right = qualifyColumns(e.Right, tables)
+		return &copied
+	case *ast.InExpression:
+		copied := *e
+		copied.Left = qualifyColumns(e.Left, tables)
+		copied.Values = make([]ast.Expression, len(e.Values))
+		for i, v := range e.Values {
+			copied.Values[i] = qualifyColumns(v, tables)
+		}
+		return &copied
+	case *ast.BetweenExpression:
+		copied := *e
+		copied.Left = qualifyColumns(e.Left, tables)
+		copied.Lower = qualifyColumns(e.Lower, tables)
+		copied.Upper = qualifyColumns(e.Upper, tables)
+		return &copied
+	}
+	return expr
+}
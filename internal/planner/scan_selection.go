@@ -1,40 +1,276 @@
 package planner
 
 import (
-	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
-	"github.com/leengari/mini-rdbms/internal/plan"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
 )
 
+// indexAccess describes how a table can be read through one of its indexes
+type indexAccess struct {
+	column string
+	unique bool
+
+	// Equality / IN lookup keys (nil for range scans)
+	values []interface{}
+
+	// Range bounds (nil when unbounded)
+	lower *index.Bound
+	upper *index.Bound
+
+	// WHERE conjuncts fully answered by the index
+	consumed map[ast.Expression]bool
+}
+
+// rank orders access paths from most to least selective (lower is better)
+// Only used to break ties between access paths of equal estimated cost.
+func (a *indexAccess) rank() int {
+	switch {
+	case a.values != nil && len(a.values) == 1 && a.unique:
+		return 0
+	case a.values != nil && len(a.values) == 1:
+		return 1
+	case a.values != nil:
+		return 2
+	case a.lower != nil && a.upper != nil:
+		return 3
+	default:
+		return 4
+	}
+}
+
 // selectScanType determines whether to use index or sequential scan
-// Scaffold: Always returns "sequential" (naive implementation)
-func selectScanType(tableName string, predicate func(data.Row) bool, db *schema.Database) string {
-	// Future: Implement real scan selection based on:
-	// - Index availability
-	// - Predicate selectivity
-	// - Table size
-	// - Index statistics
-	return "sequential"
-}
-
-// selectJoinAlgorithm determines which join algorithm to use
-// Scaffold: Always returns "nested_loop" (naive implementation)
-func selectJoinAlgorithm(joinNode *plan.JoinNode, db *schema.Database) string {
-	// Future: Implement real join algorithm selection based on:
-	// - Table sizes
-	// - Available memory
-	// - Join selectivity
-	// - Index availability
-	// Options: "nested_loop", "hash", "merge"
-	return "nested_loop"
-}
-
-// shouldUseIndex determines if an index should be used for a predicate
-// Scaffold: Always returns false (naive implementation)
-func shouldUseIndex(tableName string, predicate func(data.Row) bool, db *schema.Database) bool {
-	// Future: Implement real index selection based on:
-	// - Index availability
-	// - Predicate analysis
-	// - Index selectivity
-	return false
+// Every usable access path is costed against a full sequential scan using
+// table statistics; the cheapest wins.
+// Returns "index" with the chosen access path, or "sequential" and nil
+func selectScanType(table *schema.Table, where ast.Expression) (string, *indexAccess) {
+	if where == nil {
+		return "sequential", nil
+	}
+
+	var best *indexAccess
+	bestCost := 0.0
+	for _, access := range indexCandidates(table, splitConjuncts(where)) {
+		if !shouldUseIndex(table, access) {
+			continue
+		}
+		cost := indexScanCost(table, access)
+		if best == nil || cost < bestCost || (cost == bestCost && access.rank() < best.rank()) {
+			best = access
+			bestCost = cost
+		}
+	}
+
+	if best == nil {
+		return "sequential", nil
+	}
+	return "index", best
+}
+
+// shouldUseIndex determines if an index should serve an access path
+// Equality and IN lookups work on any index; ranges need an ordered index.
+// The index is only worth using if it is cheaper than a sequential scan,
+// which is not the case for ranges that match most of the table.
+func shouldUseIndex(table *schema.Table, access *indexAccess) bool {
+	idx, exists := table.Indexes[access.column]
+	if !exists {
+		return false
+	}
+	if access.values == nil {
+		if _, ordered := idx.Ordered(); !ordered {
+			return false
+		}
+	}
+	return indexScanCost(table, access) < seqScanCost(table)
+}
+
+// indexCandidates analyzes WHERE conjuncts and returns every access path
+// available through the table's indexes
+// Equality and IN conjuncts produce one candidate each; all range conjuncts on
+// the same column are merged into a single bounded range.
+func indexCandidates(table *schema.Table, conjuncts []ast.Expression) []*indexAccess {
+	var candidates []*indexAccess
+	ranges := make(map[string]*indexAccess)
+	var rangeOrder []string
+
+	newAccess := func(col string) *indexAccess {
+		return &indexAccess{
+			column:   col,
+			unique:   table.Indexes[col] != nil && table.Indexes[col].Unique,
+			consumed: make(map[ast.Expression]bool),
+		}
+	}
+
+	rangeFor := func(col string) *indexAccess {
+		if access, ok := ranges[col]; ok {
+			return access
+		}
+		access := newAccess(col)
+		ranges[col] = access
+		rangeOrder = append(rangeOrder, col)
+		return access
+	}
+
+	for _, conjunct := range conjuncts {
+		switch e := conjunct.(type) {
+		case *ast.BinaryExpression:
+			col, ok := indexedColumn(table, e.Left)
+			if !ok {
+				continue
+			}
+			key, ok := indexKey(e.Right, col)
+			if !ok {
+				continue
+			}
+
+			switch e.Operator {
+			case "=":
+				access := newAccess(col.Name)
+				access.values = []interface{}{key}
+				access.consumed[conjunct] = true
+				candidates = append(candidates, access)
+			case ">", ">=":
+				if col.Type == schema.ColumnTypeBool {
+					continue
+				}
+				access := rangeFor(col.Name)
+				access.lower = tighterLower(access.lower, &index.Bound{Value: key, Inclusive: e.Operator == ">="})
+				access.consumed[conjunct] = true
+			case "<", "<=":
+				if col.Type == schema.ColumnTypeBool {
+					continue
+				}
+				access := rangeFor(col.Name)
+				access.upper = tighterUpper(access.upper, &index.Bound{Value: key, Inclusive: e.Operator == "<="})
+				access.consumed[conjunct] = true
+			}
+
+		case *ast.InExpression:
+			col, ok := indexedColumn(table, e.Left)
+			if !ok {
+				continue
+			}
+			keys := make([]interface{}, 0, len(e.Values))
+			for _, v := range e.Values {
+				key, ok := indexKey(v, col)
+				if !ok {
+					keys = nil
+					break
+				}
+				keys = append(keys, key)
+			}
+			if keys == nil {
+				continue
+			}
+			access := newAccess(col.Name)
+			access.values = keys
+			access.consumed[conjunct] = true
+			candidates = append(candidates, access)
+
+		case *ast.BetweenExpression:
+			col, ok := indexedColumn(table, e.Left)
+			if !ok || col.Type == schema.ColumnTypeBool {
+				continue
+			}
+			lower, okLower := indexKey(e.Lower, col)
+			upper, okUpper := indexKey(e.Upper, col)
+			if !okLower || !okUpper {
+				continue
+			}
+			access := rangeFor(col.Name)
+			access.lower = tighterLower(access.lower, &index.Bound{Value: lower, Inclusive: true})
+			access.upper = tighterUpper(access.upper, &index.Bound{Value: upper, Inclusive: true})
+			access.consumed[conjunct] = true
+		}
+	}
+
+	for _, col := range rangeOrder {
+		candidates = append(candidates, ranges[col])
+	}
+	return candidates
+}
+
+// indexedColumn resolves an expression to a column of table that has an index
+func indexedColumn(table *schema.Table, expr ast.Expression) (*schema.Column, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	if ident.Table != "" && ident.Table != table.Name {
+		return nil, false
+	}
+	if _, exists := table.Indexes[ident.Value]; !exists {
+		return nil, false
+	}
+	col := table.Schema.GetColumn(ident.Value)
+	return col, col != nil
+}
+
+// indexKey converts a literal into the key type stored in the column's index
+// Returns false if the literal cannot be compared with the column through an
+// index (e.g. a string compared against an INT column), in which case the
+// sequential scan decides the result.
+func indexKey(expr ast.Expression, col *schema.Column) (interface{}, bool) {
+	lit, ok := expr.(*ast.Literal)
+	if !ok || lit.Value == nil {
+		return nil, false
+	}
+
+	switch col.Type {
+	case schema.ColumnTypeInt:
+		switch v := lit.Value.(type) {
+		case int:
+			return int64(v), true
+		case int64:
+			return v, true
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v), true
+			}
+			return v, true // only usable for ranges; no INT key equals it
+		}
+	case schema.ColumnTypeFloat:
+		switch v := lit.Value.(type) {
+		case int:
+			return float64(v), true
+		case int64:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+	case schema.ColumnTypeBool:
+		if v, ok := lit.Value.(bool); ok {
+			return v, true
+		}
+	default:
+		// TEXT, DATE, TIME and EMAIL are stored as strings
+		if v, ok := lit.Value.(string); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// tighterLower returns the more restrictive of two lower bounds
+func tighterLower(current, candidate *index.Bound) *index.Bound {
+	if current == nil {
+		return candidate
+	}
+	c := index.Compare(candidate.Value, current.Value)
+	if c > 0 || (c == 0 && !candidate.Inclusive) {
+		return candidate
+	}
+	return current
+}
+
+// tighterUpper returns the more restrictive of two upper bounds
+func tighterUpper(current, candidate *index.Bound) *index.Bound {
+	if current == nil {
+		return candidate
+	}
+	c := index.Compare(candidate.Value, current.Value)
+	if c < 0 || (c == 0 && !candidate.Inclusive) {
+		return candidate
+	}
+	return current
 }
@@ -0,0 +1,89 @@
+package copying
+
+import (
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/query/validation"
+	"github.com/leengari/mini-rdbms/internal/util/types"
+)
+
+// Format is the file format of COPY
+type Format string
+
+const (
+	FormatCSV   Format = "CSV"   // comma-separated values
+	FormatJSONL Format = "JSONL" // JSON Lines: one object per line
+)
+
+// Options are the WITH options of COPY
+type Options struct {
+	Format    Format
+	Header    bool   // CSV: the first line holds the column names
+	Delimiter rune   // CSV: the field separator
+	Null      string // CSV: the text of a NULL value
+}
+
+// Record is a row read from a file and the line it starts on
+type Record struct {
+	Line int
+	Row  data.Row
+}
+
+// converter turns the values of a file into a row of a table, with the
+// conversions INSERT applies to literals
+type converter struct {
+	table   *schema.Table
+	columns []*schema.Column
+}
+
+// newConverter returns a converter for values of the named columns
+func newConverter(table *schema.Table, names []string) (*converter, error) {
+	c := &converter{table: table, columns: make([]*schema.Column, len(names))}
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		col := table.Schema.GetColumn(name)
+		if col == nil {
+			return nil, fmt.Errorf("column not found: %s.%s", table.Name, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %s given more than once", name)
+		}
+		seen[name] = true
+		c.columns[i] = col
+	}
+	return c, nil
+}
+
+// set converts lit to the type of column i and stores it in row
+func (c *converter) set(row map[string]interface{}, i int, lit *ast.Literal) error {
+	col := c.columns[i]
+	converted, err := types.ConvertLiteralToSchemaType(lit, col.Type)
+	if err != nil {
+		return fmt.Errorf("column '%s': %w", col.Name, err)
+	}
+	value := converted.Value
+	// FLOAT columns accept integers but store them as floats
+	if col.Type == schema.ColumnTypeFloat {
+		if n, ok := types.NormalizeToFloat(value); ok {
+			value = n
+		}
+	}
+	row[col.Name] = value
+	return nil
+}
+
+// validate checks a converted row against the table's schema
+func (c *converter) validate(row data.Row) error {
+	check := row
+	// An auto-increment key left out is generated on insert
+	if pk := c.table.Schema.GetPrimaryKeyColumn(); pk != nil && pk.AutoIncrement {
+		if _, ok := row.Data[pk.Name]; !ok {
+			check = row.Copy()
+			check.Data[pk.Name] = int64(0)
+		}
+	}
+	return validation.ValidateRow(c.table, check, -1)
+}
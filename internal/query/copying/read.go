@@ -0,0 +1,220 @@
+package copying
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+)
+
+// Read reads the records of a file to be copied into table
+// Every value is converted to its column's type as INSERT converts literals,
+// and every row is validated against the schema, so a file with a bad record
+// is rejected as a whole; the error names the line the record starts on.
+// columns are the columns the values are for. Without them a CSV header names
+// the columns, or else the values are for all columns in schema order. A JSON
+// Lines object names its columns itself, which must be among columns if given.
+// A NULL value, or a column left out, leaves the column without a value.
+func Read(r io.Reader, table *schema.Table, columns []string, opts Options) ([]Record, error) {
+	switch opts.Format {
+	case FormatCSV:
+		return readCSV(r, table, columns, opts)
+	case FormatJSONL:
+		return readJSONL(r, table, columns)
+	default:
+		return nil, fmt.Errorf("unsupported COPY format: %s", opts.Format)
+	}
+}
+
+func readCSV(r io.Reader, table *schema.Table, columns []string, opts Options) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Delimiter
+	reader.ReuseRecord = true
+
+	if opts.Header {
+		header, err := reader.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			columns = append([]string(nil), header...)
+		} else if len(header) != len(columns) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: header has %d columns, expected %d", line, len(header), len(columns))
+		}
+	}
+	if len(columns) == 0 {
+		for _, col := range table.Schema.Columns {
+			columns = append(columns, col.Name)
+		}
+	}
+	conv, err := newConverter(table, columns)
+	if err != nil {
+		return nil, err
+	}
+	reader.FieldsPerRecord = len(columns)
+
+	var records []Record
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := make(map[string]interface{}, len(fields))
+		for i, field := range fields {
+			if field == opts.Null {
+				continue
+			}
+			lit, err := fieldLiteral(conv.columns[i], field)
+			if err == nil {
+				err = conv.set(row, i, lit)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		record := Record{Line: line, Row: data.NewRow(row)}
+		if err := conv.validate(record.Row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// fieldLiteral reads a CSV field as a literal for a column of col's type
+// Numbers and booleans are parsed; everything else is a string, which DATE,
+// TIME and EMAIL columns convert like string literals.
+func fieldLiteral(col *schema.Column, field string) (*ast.Literal, error) {
+	switch col.Type {
+	case schema.ColumnTypeInt:
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': cannot convert '%s' to INT", col.Name, field)
+		}
+		return &ast.Literal{TokenLiteralValue: field, Value: n, Kind: ast.LiteralInt}, nil
+	case schema.ColumnTypeFloat:
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': cannot convert '%s' to FLOAT", col.Name, field)
+		}
+		return &ast.Literal{TokenLiteralValue: field, Value: f, Kind: ast.LiteralFloat}, nil
+	case schema.ColumnTypeBool:
+		b, err := strconv.ParseBool(field)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': cannot convert '%s' to BOOL", col.Name, field)
+		}
+		return &ast.Literal{TokenLiteralValue: field, Value: b, Kind: ast.LiteralBool}, nil
+	default:
+		return &ast.Literal{TokenLiteralValue: field, Value: field, Kind: ast.LiteralString}, nil
+	}
+}
+
+func readJSONL(r io.Reader, table *schema.Table, columns []string) ([]Record, error) {
+	if len(columns) == 0 {
+		for _, col := range table.Schema.Columns {
+			columns = append(columns, col.Name)
+		}
+	}
+	conv, err := newConverter(table, columns)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		known[name] = true
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var records []Record
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		if object == nil || decoder.More() {
+			return nil, fmt.Errorf("line %d: expected one JSON object", line)
+		}
+
+		for name := range object {
+			if !known[name] {
+				return nil, fmt.Errorf("line %d: unexpected column %s", line, name)
+			}
+		}
+		// Columns are converted in order, so the same error is reported for
+		// the same record every time
+		row := make(map[string]interface{}, len(object))
+		for i, name := range columns {
+			value := object[name]
+			if value == nil {
+				continue
+			}
+			lit, err := jsonLiteral(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: column '%s': %w", line, name, err)
+			}
+			if err := conv.set(row, i, lit); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		record := Record{Line: line, Row: data.NewRow(row)}
+		if err := conv.validate(record.Row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("line %d: line too long", line+1)
+		}
+		return nil, err
+	}
+	return records, nil
+}
+
+// jsonLiteral returns a decoded JSON value as a literal
+func jsonLiteral(value interface{}) (*ast.Literal, error) {
+	switch v := value.(type) {
+	case string:
+		return &ast.Literal{TokenLiteralValue: v, Value: v, Kind: ast.LiteralString}, nil
+	case bool:
+		return &ast.Literal{TokenLiteralValue: strconv.FormatBool(v), Value: v, Kind: ast.LiteralBool}, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return &ast.Literal{TokenLiteralValue: v.String(), Value: n, Kind: ast.LiteralInt}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", v)
+		}
+		return &ast.Literal{TokenLiteralValue: v.String(), Value: f, Kind: ast.LiteralFloat}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value %v", value)
+	}
+}
@@ -0,0 +1,103 @@
+package copying
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Write writes rows as a file holding the values of columns, in order
+// A row without a value for a column is written with the NULL text in CSV
+// and as null in JSON Lines.
+func Write(w io.Writer, columns []string, rows []data.Row, opts Options) error {
+	switch opts.Format {
+	case FormatCSV:
+		return writeCSV(w, columns, rows, opts)
+	case FormatJSONL:
+		return writeJSONL(w, columns, rows)
+	default:
+		return fmt.Errorf("unsupported COPY format: %s", opts.Format)
+	}
+}
+
+func writeCSV(w io.Writer, columns []string, rows []data.Row, opts Options) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.Delimiter
+
+	if opts.Header {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	fields := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			value, ok := row.Data[col]
+			if !ok || value == nil {
+				fields[i] = opts.Null
+				continue
+			}
+			fields[i] = fieldText(value)
+		}
+		if err := writer.Write(fields); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// fieldText returns a value as the text of a CSV field, which Read parses
+// back to the same value
+func fieldText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func writeJSONL(w io.Writer, columns []string, rows []data.Row) error {
+	out := bufio.NewWriter(w)
+
+	// Objects are written key by key to keep the columns in order
+	names := make([][]byte, len(columns))
+	for i, col := range columns {
+		name, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		names[i] = name
+	}
+	for _, row := range rows {
+		out.WriteByte('{')
+		for i := range columns {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			value, err := json.Marshal(row.Data[columns[i]])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", columns[i], err)
+			}
+			out.Write(names[i])
+			out.WriteByte(':')
+			out.Write(value)
+		}
+		out.WriteString("}\n")
+	}
+	return out.Flush()
+}
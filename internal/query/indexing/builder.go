@@ -7,9 +7,11 @@ import (
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/errors"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
 )
 
-// BuildIndexes rebuilds all indexes for primary/unique columns
+// BuildIndexes rebuilds all indexes for primary/unique columns and
+// user-defined secondary indexes
 // Returns error on constraint violation or data inconsistency
 func BuildIndexes(table *schema.Table) error {
 	// Acquire write lock for index building
@@ -24,69 +26,181 @@ func BuildIndexes(table *schema.Table) error {
 			continue
 		}
 
-		idx := &data.Index{
-			Column: col.Name,
-			Data:   make(map[interface{}][]int),
-			Unique: col.PrimaryKey || col.Unique,
+		// Implicit constraint indexes are ordered, so ranges, ORDER BY and
+		// MIN/MAX on keys use them, unless the user asked for a hash index
+		kind := index.KindBTree
+		name := ""
+		for _, def := range table.Schema.Indexes {
+			if def.Column == col.Name {
+				kind = def.Kind
+				name = def.Name
+				break
+			}
 		}
 
-		for rowPos, row := range table.Rows {
-			val, ok := row.Data[col.Name]
-			if !ok {
-				if col.NotNull {
-					return errors.NewNotNullViolation(table.Name, col.Name, rowPos)
-				}
-				continue
-			}
+		idx := data.NewIndex(name, col.Name, kind, true)
+		if err := populateIndex(table, idx, &col); err != nil {
+			return err
+		}
+		table.Indexes[col.Name] = idx
+	}
+
+	for _, def := range table.Schema.Indexes {
+		if _, exists := table.Indexes[def.Column]; exists {
+			continue
+		}
+
+		col := table.Schema.GetColumn(def.Column)
+		if col == nil {
+			return fmt.Errorf("index %s on table %s: column %s does not exist", def.Name, table.Name, def.Column)
+		}
+
+		idx := data.NewIndex(def.Name, def.Column, def.Kind, def.Unique)
+		if err := populateIndex(table, idx, col); err != nil {
+			return err
+		}
+		table.Indexes[def.Column] = idx
+	}
+
+	return nil
+}
+
+// CreateIndex adds a user-defined index to the table schema and builds it
+// Only one index is kept per column: creating an index on a primary key or
+// unique column replaces its implicit index, keeping it unique.
+// Must NOT be called while holding the table lock.
+func CreateIndex(table *schema.Table, def schema.IndexDefinition) error {
+	table.Lock()
+	col := table.Schema.GetColumn(def.Column)
+	if col == nil {
+		table.Unlock()
+		return &errors.ColumnNotFoundError{TableName: table.Name, ColumnName: def.Column}
+	}
+	if table.Schema.GetIndexDefinition(def.Name) != nil {
+		table.Unlock()
+		return fmt.Errorf("index %s already exists on table %s", def.Name, table.Name)
+	}
+	for _, existing := range table.Schema.Indexes {
+		if existing.Column == def.Column {
+			table.Unlock()
+			return fmt.Errorf("column %s.%s is already indexed by %s", table.Name, def.Column, existing.Name)
+		}
+	}
 
-			// Optional: normalize numeric keys for auto-increment
-			if col.AutoIncrement && col.PrimaryKey {
-				switch v := val.(type) {
-				case float64:
-					val = int64(v) // JSON numbers come as float64
-				case int64, int:
-					// already good
-				default:
-					return fmt.Errorf("invalid auto-increment value in %s row %d: %v (want integer)",
-						col.Name, rowPos, val)
-				}
+	// Validate by building the index before touching the schema
+	idx := data.NewIndex(def.Name, def.Column, def.Kind, def.Unique || col.PrimaryKey || col.Unique)
+	if err := populateIndex(table, idx, col); err != nil {
+		table.Unlock()
+		return err
+	}
+
+	table.Schema.Indexes = append(table.Schema.Indexes, def)
+	table.Indexes[def.Column] = idx
+	table.MarkDirtyUnsafe()
+	table.Unlock()
+
+	slog.Info("index created",
+		slog.String("table", table.Name),
+		slog.String("index", def.Name),
+		slog.String("column", def.Column),
+		slog.String("type", string(def.Kind)))
+
+	return nil
+}
+
+// DropIndex removes a user-defined index from the table schema and
+// rebuilds the table's indexes without it
+// A primary key or unique column keeps its implicit ordered index.
+// Must NOT be called while holding the table lock.
+func DropIndex(table *schema.Table, name string) error {
+	table.Lock()
+	kept := make([]schema.IndexDefinition, 0, len(table.Schema.Indexes))
+	for _, def := range table.Schema.Indexes {
+		if def.Name != name {
+			kept = append(kept, def)
+		}
+	}
+	if len(kept) == len(table.Schema.Indexes) {
+		table.Unlock()
+		return fmt.Errorf("index %s does not exist on table %s", name, table.Name)
+	}
+	table.Schema.Indexes = kept
+	table.MarkDirtyUnsafe()
+	table.Unlock()
+
+	slog.Info("index dropped",
+		slog.String("table", table.Name),
+		slog.String("index", name))
+
+	return BuildIndexes(table)
+}
+
+// populateIndex fills idx from the table rows, enforcing NOT NULL and
+// uniqueness for the column
+// The rows of a paged table are streamed from disk.
+// Must be called while holding the table write lock
+func populateIndex(table *schema.Table, idx *data.Index, col *schema.Column) error {
+	var firstType string
+
+	err := table.ForEachRowUnsafe(func(rowPos int, row data.Row) error {
+		val, ok := row.Data[col.Name]
+		if !ok || val == nil {
+			if col.NotNull {
+				return errors.NewNotNullViolation(table.Name, col.Name, rowPos)
 			}
+			return nil
+		}
 
-			// Check type consistency (very useful during development)
-			if len(idx.Data) > 0 {
-				for existing := range idx.Data {
-					if fmt.Sprintf("%T", existing) != fmt.Sprintf("%T", val) {
-						slog.Warn("type inconsistency in column",
-							slog.String("column", col.Name),
-							slog.Any("previous_type", fmt.Sprintf("%T", existing)),
-							slog.Any("new_type", fmt.Sprintf("%T", val)),
-							slog.Int("row", rowPos))
-					}
-					break // only check once
-				}
+		// Optional: normalize numeric keys for auto-increment
+		if col.AutoIncrement && col.PrimaryKey {
+			switch v := val.(type) {
+			case float64:
+				val = int64(v) // JSON numbers come as float64
+			case int64, int:
+				// already good
+			default:
+				return fmt.Errorf("invalid auto-increment value in %s row %d: %v (want integer)",
+					col.Name, rowPos, val)
 			}
+		}
 
-			idx.Data[val] = append(idx.Data[val], rowPos)
+		// Check type consistency (very useful during development)
+		valType := fmt.Sprintf("%T", index.NormalizeKey(val))
+		if firstType == "" {
+			firstType = valType
+		} else if firstType != valType {
+			slog.Warn("type inconsistency in column",
+				slog.String("column", col.Name),
+				slog.Any("previous_type", firstType),
+				slog.Any("new_type", valType),
+				slog.Int("row", rowPos))
+		}
 
-			if idx.Unique && len(idx.Data[val]) > 1 {
+		if idx.Unique {
+			if existing := idx.Lookup(val); len(existing) > 0 {
 				return errors.NewUniqueViolation(
 					table.Name,
 					col.Name,
 					val,
-					idx.Data[val],
+					append(append([]int{}, existing...), rowPos),
 				)
 			}
 		}
 
-		table.Indexes[col.Name] = idx
-
-		slog.Debug("index built",
-			slog.String("table", table.Name),
-			slog.String("column", col.Name),
-			slog.Int("unique_values", len(idx.Data)),
-			slog.Bool("unique_constraint", idx.Unique))
+		idx.Add(val, rowPos)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	slog.Debug("index built",
+		slog.String("table", table.Name),
+		slog.String("column", col.Name),
+		slog.String("type", string(idx.Kind())),
+		slog.Int("unique_values", idx.Store.Len()),
+		slog.Bool("unique_constraint", idx.Unique))
+
 	return nil
 }
 
@@ -0,0 +1,227 @@
+package join
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+)
+
+// matchFunc receives the positions of a matching left and right row
+type matchFunc func(leftPos, rightPos int)
+
+// hashJoin builds a hash table on the build side and probes it with every
+// row of the other side
+// An existing index on the build column is reused instead of building one.
+func hashJoin(leftTable, rightTable *schema.Table, leftColumn, rightColumn string, build Side, emit matchFunc) {
+	if build == SideLeft {
+		hashIndex, reusedIndex := buildJoinIndex(leftTable, leftColumn)
+		if reusedIndex {
+			slog.Debug("Reusing existing index", slog.String("column", leftColumn))
+		}
+		probe(rightTable, rightColumn, hashIndex, func(rightPos, leftPos int) {
+			emit(leftPos, rightPos)
+		})
+		return
+	}
+
+	hashIndex, reusedIndex := buildJoinIndex(rightTable, rightColumn)
+	if reusedIndex {
+		slog.Debug("Reusing existing index", slog.String("column", rightColumn))
+	}
+	probe(leftTable, leftColumn, hashIndex, emit)
+}
+
+// compositeHashJoin is a hash join on several key columns at once
+// The hash table is keyed by the encoded values of all key columns; rows with
+// a NULL in any key column never match.
+func compositeHashJoin(leftTable, rightTable *schema.Table, keys []Key, build Side, emit matchFunc) {
+	leftColumns := make([]string, len(keys))
+	rightColumns := make([]string, len(keys))
+	for i, key := range keys {
+		leftColumns[i], rightColumns[i] = key.Left, key.Right
+	}
+
+	buildTable, buildColumns := rightTable, rightColumns
+	probeTable, probeColumns := leftTable, leftColumns
+	if build == SideLeft {
+		buildTable, buildColumns = leftTable, leftColumns
+		probeTable, probeColumns = rightTable, rightColumns
+	}
+
+	hashTable := make(map[string][]int)
+	for pos, row := range buildTable.Rows {
+		if key, ok := compositeKey(row, buildColumns); ok {
+			hashTable[key] = append(hashTable[key], pos)
+		}
+	}
+
+	for probePos, row := range probeTable.Rows {
+		key, ok := compositeKey(row, probeColumns)
+		if !ok {
+			continue
+		}
+		for _, buildPos := range hashTable[key] {
+			if build == SideLeft {
+				emit(buildPos, probePos)
+			} else {
+				emit(probePos, buildPos)
+			}
+		}
+	}
+}
+
+// compositeKey encodes the values of columns as one hash key
+// Returns false if any of them is NULL.
+func compositeKey(row data.Row, columns []string) (string, bool) {
+	var b strings.Builder
+	for _, col := range columns {
+		value, exists := row.Data[col]
+		if !exists || value == nil {
+			return "", false
+		}
+		normalized := index.NormalizeKey(value)
+		fmt.Fprintf(&b, "%T:%v\x00", normalized, normalized)
+	}
+	return b.String(), true
+}
+
+// indexNestedLoopJoin looks up every row of the outer side in an existing
+// index on the inner side's join column
+// Returns false without emitting anything if the inner column has no index.
+func indexNestedLoopJoin(leftTable, rightTable *schema.Table, leftColumn, rightColumn string, inner Side, emit matchFunc) bool {
+	if inner == SideLeft {
+		idx, ok := leftTable.Indexes[leftColumn]
+		if !ok {
+			return false
+		}
+		probe(rightTable, rightColumn, idx.Store, func(rightPos, leftPos int) {
+			emit(leftPos, rightPos)
+		})
+		return true
+	}
+
+	idx, ok := rightTable.Indexes[rightColumn]
+	if !ok {
+		return false
+	}
+	probe(leftTable, leftColumn, idx.Store, emit)
+	return true
+}
+
+// probe looks up the join value of every outer row in idx
+// NULL never equals anything, so rows with a NULL join value are skipped.
+func probe(outer *schema.Table, outerColumn string, idx index.Index, emit func(outerPos, innerPos int)) {
+	for outerPos, row := range outer.Rows {
+		value, exists := row.Data[outerColumn]
+		if !exists || value == nil {
+			continue
+		}
+		for _, innerPos := range idx.Lookup(value) {
+			emit(outerPos, innerPos)
+		}
+	}
+}
+
+// mergeJoin sorts both inputs on the join key and merges them, emitting the
+// cross product of every group of equal keys
+func mergeJoin(leftTable, rightTable *schema.Table, leftColumn, rightColumn string, emit matchFunc) {
+	left := sortedKeys(leftTable, leftColumn)
+	right := sortedKeys(rightTable, rightColumn)
+
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		c := index.Compare(left[i].key, right[j].key)
+		if c < 0 {
+			i++
+			continue
+		}
+		if c > 0 {
+			j++
+			continue
+		}
+
+		iEnd := i + 1
+		for iEnd < len(left) && index.Compare(left[iEnd].key, left[i].key) == 0 {
+			iEnd++
+		}
+		jEnd := j + 1
+		for jEnd < len(right) && index.Compare(right[jEnd].key, right[j].key) == 0 {
+			jEnd++
+		}
+
+		for _, l := range left[i:iEnd] {
+			for _, r := range right[j:jEnd] {
+				emit(l.pos, r.pos)
+			}
+		}
+		i, j = iEnd, jEnd
+	}
+}
+
+// nestedLoopJoin compares every left row with every right row
+// keys, if any, must be equal for a pair to be emitted.
+func nestedLoopJoin(leftTable, rightTable *schema.Table, keys []Key, emit matchFunc) {
+	for leftPos, leftRow := range leftTable.Rows {
+		for rightPos, rightRow := range rightTable.Rows {
+			if keysEqual(leftRow, rightRow, keys) {
+				emit(leftPos, rightPos)
+			}
+		}
+	}
+}
+
+// keysEqual reports whether every key pair holds equal, non-NULL values
+func keysEqual(leftRow, rightRow data.Row, keys []Key) bool {
+	for _, key := range keys {
+		l, lok := leftRow.Data[key.Left]
+		r, rok := rightRow.Data[key.Right]
+		if !lok || !rok || l == nil || r == nil {
+			return false
+		}
+		if index.Compare(index.NormalizeKey(l), index.NormalizeKey(r)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKey is a join key and the position of the row holding it
+type sortedKey struct {
+	key interface{}
+	pos int
+}
+
+// sortedKeys returns the non-NULL join keys of a table in ascending order
+// An ordered index on the column is walked directly instead of sorting.
+func sortedKeys(table *schema.Table, column string) []sortedKey {
+	keys := make([]sortedKey, 0, len(table.Rows))
+
+	if idx, ok := table.Indexes[column]; ok {
+		if ordered, ok := idx.Ordered(); ok {
+			ordered.Ascend(func(key interface{}, positions []int) bool {
+				for _, pos := range positions {
+					keys = append(keys, sortedKey{key: key, pos: pos})
+				}
+				return true
+			})
+			return keys
+		}
+	}
+
+	for pos, row := range table.Rows {
+		value, exists := row.Data[column]
+		if !exists || value == nil {
+			continue
+		}
+		keys = append(keys, sortedKey{key: index.NormalizeKey(value), pos: pos})
+	}
+	sort.SliceStable(keys, func(a, b int) bool {
+		return index.Compare(keys[a].key, keys[b].key) < 0
+	})
+	return keys
+}
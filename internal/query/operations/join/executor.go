@@ -16,6 +16,8 @@ type JoinPredicate func(data.JoinedRow) bool
 // ExecuteJoin performs a JOIN operation with the specified type
 // This is the unified API for all JOIN types (INNER, LEFT, RIGHT, FULL)
 // Supports optional predicate filtering and column projection
+// Matching rows are found with a hash join that builds on the right table;
+// use ExecuteJoinWithStrategy to choose the algorithm.
 func ExecuteJoin(
 	leftTable *schema.Table,
 	rightTable *schema.Table,
@@ -26,35 +28,49 @@ func ExecuteJoin(
 	proj *projection.Projection,
 	tx *transaction.Transaction,
 ) ([]data.JoinedRow, error) {
-	if tx != nil {
-		slog.Debug("ExecuteJoin operation", "type", joinType, "tx_id", tx.ID)
-	}
-	// Validate join condition and resolve qualified names
-	if err := validateJoinCondition(leftTable, rightTable, &leftColumn, &rightColumn); err != nil {
-		return nil, err
-	}
+	return ExecuteJoinWithStrategy(leftTable, rightTable, leftColumn, rightColumn, joinType, Strategy{}, pred, proj, tx)
+}
 
-	var results []data.JoinedRow
-	var err error
+// ExecuteJoinWithStrategy performs a JOIN operation using the given physical
+// algorithm
+func ExecuteJoinWithStrategy(
+	leftTable *schema.Table,
+	rightTable *schema.Table,
+	leftColumn string,
+	rightColumn string,
+	joinType JoinType,
+	strategy Strategy,
+	pred JoinPredicate,
+	proj *projection.Projection,
+	tx *transaction.Transaction,
+) ([]data.JoinedRow, error) {
+	cond := Condition{Keys: []Key{{Left: leftColumn, Right: rightColumn}}}
+	return ExecuteJoinOn(leftTable, rightTable, cond, joinType, strategy, pred, proj, tx)
+}
 
-	// Execute the appropriate JOIN type
-	switch joinType {
-	case JoinTypeInner:
-		results, err = executeInnerJoin(leftTable, rightTable, leftColumn, rightColumn, pred)
-	case JoinTypeLeft:
-		results, err = executeLeftJoin(leftTable, rightTable, leftColumn, rightColumn, pred)
-	case JoinTypeRight:
-		results, err = executeRightJoin(leftTable, rightTable, leftColumn, rightColumn, pred)
-	case JoinTypeFull:
-		results, err = executeFullJoin(leftTable, rightTable, leftColumn, rightColumn, pred)
-	default:
-		return nil, fmt.Errorf("unknown JOIN type: %v", joinType)
+// ExecuteJoinOn performs a JOIN operation with a general ON condition
+// Matching rows are found through the condition's keys with the strategy's
+// algorithm, or with a nested-loop join when there are no keys.
+func ExecuteJoinOn(
+	leftTable *schema.Table,
+	rightTable *schema.Table,
+	cond Condition,
+	joinType JoinType,
+	strategy Strategy,
+	pred JoinPredicate,
+	proj *projection.Projection,
+	tx *transaction.Transaction,
+) ([]data.JoinedRow, error) {
+	if tx != nil {
+		slog.Debug("ExecuteJoin operation", "type", joinType, "algorithm", strategy.Algorithm, "tx_id", tx.ID)
 	}
-
+	cond, err := resolveCondition(leftTable, rightTable, cond, joinType)
 	if err != nil {
 		return nil, err
 	}
 
+	results := executeJoin(leftTable, rightTable, cond, joinType, strategy, pred)
+
 	// Apply projection if specified
 	if proj != nil && !proj.SelectAll {
 		projectedResults := make([]data.JoinedRow, len(results))
@@ -67,231 +83,121 @@ func ExecuteJoin(
 	return results, nil
 }
 
-// executeInnerJoin performs INNER JOIN
-func executeInnerJoin(
-	leftTable *schema.Table,
-	rightTable *schema.Table,
-	leftColumn string,
-	rightColumn string,
-	pred JoinPredicate,
-) ([]data.JoinedRow, error) {
-	// Acquire read locks on both tables
-	leftTable.RLock()
-	defer leftTable.RUnlock()
-	rightTable.RLock()
-	defer rightTable.RUnlock()
-
-	slog.Debug("Starting INNER JOIN",
-		slog.String("left_table", leftTable.Name),
-		slog.String("right_table", rightTable.Name),
-		slog.String("left_column", leftColumn),
-		slog.String("right_column", rightColumn),
-	)
-
-	// Build hash index on right table
-	hashIndex, reusedIndex := buildJoinIndex(rightTable, rightColumn)
-	if reusedIndex {
-		slog.Debug("Reusing existing index", slog.String("column", rightColumn))
+// resolveCondition validates a join and resolves its key columns to the
+// names used by the rows of each table
+func resolveCondition(leftTable, rightTable *schema.Table, cond Condition, joinType JoinType) (Condition, error) {
+	if leftTable == nil {
+		return Condition{}, fmt.Errorf("left table is nil")
 	}
-
-	results := make([]data.JoinedRow, 0)
-	skippedByPredicate := 0
-
-	// Probe left table and combine matches
-	for _, leftRow := range leftTable.Rows {
-		leftValue, exists := leftRow.Data[leftColumn]
-		if !exists {
-			continue // Skip rows with NULL join column
-		}
-
-		rightPositions, found := hashIndex[leftValue]
-		if !found {
-			continue // No matches (INNER JOIN excludes)
-		}
-
-		for _, rightPos := range rightPositions {
-			rightRow := rightTable.Rows[rightPos]
-			joined := combineRows(leftRow, rightRow, leftTable.Name, rightTable.Name)
-
-			if pred != nil && !pred(joined) {
-				skippedByPredicate++
-				continue
-			}
-
-			results = append(results, joined)
-		}
+	if rightTable == nil {
+		return Condition{}, fmt.Errorf("right table is nil")
 	}
-
-	slog.Info("INNER JOIN completed",
-		slog.String("left_table", leftTable.Name),
-		slog.String("right_table", rightTable.Name),
-		slog.Int("result_rows", len(results)),
-		slog.Int("filtered_by_predicate", skippedByPredicate),
-	)
-
-	return results, nil
-}
-
-// executeLeftJoin performs LEFT OUTER JOIN
-func executeLeftJoin(
-	leftTable *schema.Table,
-	rightTable *schema.Table,
-	leftColumn string,
-	rightColumn string,
-	pred JoinPredicate,
-) ([]data.JoinedRow, error) {
-	leftTable.RLock()
-	defer leftTable.RUnlock()
-	rightTable.RLock()
-	defer rightTable.RUnlock()
-
-	slog.Debug("Starting LEFT JOIN",
-		slog.String("left_table", leftTable.Name),
-		slog.String("right_table", rightTable.Name),
-	)
-
-	hashIndex, _ := buildJoinIndex(rightTable, rightColumn)
-	results := make([]data.JoinedRow, 0)
-	matchedLeftRows := make(map[int]bool)
-
-	// Phase 1: INNER JOIN
-	for leftPos, leftRow := range leftTable.Rows {
-		leftValue, exists := leftRow.Data[leftColumn]
-		if !exists {
-			continue
-		}
-
-		rightPositions, found := hashIndex[leftValue]
-		if found {
-			matchedLeftRows[leftPos] = true
-			for _, rightPos := range rightPositions {
-				rightRow := rightTable.Rows[rightPos]
-				joined := combineRows(leftRow, rightRow, leftTable.Name, rightTable.Name)
-
-				if pred == nil || pred(joined) {
-					results = append(results, joined)
-				}
-			}
+	keys := make([]Key, len(cond.Keys))
+	for i, key := range cond.Keys {
+		if err := validateJoinCondition(leftTable, rightTable, &key.Left, &key.Right); err != nil {
+			return Condition{}, err
 		}
+		keys[i] = key
 	}
+	cond.Keys = keys
 
-	// Phase 2: Add unmatched left rows
-	for leftPos, leftRow := range leftTable.Rows {
-		if !matchedLeftRows[leftPos] {
-			joined := combineRowsWithNull(leftRow, data.Row{}, leftTable, rightTable)
-			if pred == nil || pred(joined) {
-				results = append(results, joined)
-			}
-		}
+	switch joinType {
+	case JoinTypeInner, JoinTypeLeft, JoinTypeRight, JoinTypeFull:
+	default:
+		return Condition{}, fmt.Errorf("unknown JOIN type: %v", joinType)
 	}
-
-	slog.Info("LEFT JOIN completed",
-		slog.Int("result_rows", len(results)),
-		slog.Int("unmatched_left", len(leftTable.Rows)-len(matchedLeftRows)),
-	)
-
-	return results, nil
+	return cond, nil
 }
 
-// executeRightJoin performs RIGHT OUTER JOIN
-func executeRightJoin(
+// executeJoin finds matching row pairs with the strategy's algorithm, then
+// adds the NULL-extended rows required by outer joins
+//
+// Hash joins match on every key column at once; the other algorithms find
+// pairs whose first key matches and the remaining keys are checked on each
+// pair. The condition's filter is then applied to the combined row. A row counts as matched
+// when the whole ON condition holds, even if pred rejects the combined row,
+// so pred behaves like a WHERE clause applied after the join.
+func executeJoin(
 	leftTable *schema.Table,
 	rightTable *schema.Table,
-	leftColumn string,
-	rightColumn string,
+	cond Condition,
+	joinType JoinType,
+	strategy Strategy,
 	pred JoinPredicate,
-) ([]data.JoinedRow, error) {
+) []data.JoinedRow {
+	// Acquire read locks on both tables
 	leftTable.RLock()
 	defer leftTable.RUnlock()
 	rightTable.RLock()
 	defer rightTable.RUnlock()
 
-	slog.Debug("Starting RIGHT JOIN",
+	algorithm := strategy.Algorithm
+	if len(cond.Keys) == 0 {
+		algorithm = AlgorithmNestedLoop
+	}
+
+	slog.Debug("Starting "+joinType.String(),
 		slog.String("left_table", leftTable.Name),
 		slog.String("right_table", rightTable.Name),
+		slog.Int("keys", len(cond.Keys)),
+		slog.Bool("filter", cond.Filter != nil),
+		slog.String("algorithm", string(algorithm)),
 	)
 
-	hashIndex, _ := buildJoinIndex(rightTable, rightColumn)
 	results := make([]data.JoinedRow, 0)
+	matchedLeftRows := make(map[int]bool)
 	matchedRightRows := make(map[int]bool)
+	skippedByPredicate := 0
 
 	// Phase 1: INNER JOIN
-	for _, leftRow := range leftTable.Rows {
-		leftValue, exists := leftRow.Data[leftColumn]
-		if !exists {
-			continue
+	emit := func(leftPos, rightPos int) {
+		leftRow, rightRow := leftTable.Rows[leftPos], rightTable.Rows[rightPos]
+		if len(cond.Keys) > 1 && !keysEqual(leftRow, rightRow, cond.Keys[1:]) {
+			return
+		}
+		joined := combineRows(leftRow, rightRow, leftTable.Name, rightTable.Name)
+		if cond.Filter != nil && !cond.Filter(joined) {
+			return
 		}
 
-		rightPositions, found := hashIndex[leftValue]
-		if found {
-			for _, rightPos := range rightPositions {
-				matchedRightRows[rightPos] = true
-				rightRow := rightTable.Rows[rightPos]
-				joined := combineRows(leftRow, rightRow, leftTable.Name, rightTable.Name)
-
-				if pred == nil || pred(joined) {
-					results = append(results, joined)
-				}
-			}
+		matchedLeftRows[leftPos] = true
+		matchedRightRows[rightPos] = true
+		if pred != nil && !pred(joined) {
+			skippedByPredicate++
+			return
 		}
+		results = append(results, joined)
 	}
 
-	// Phase 2: Add unmatched right rows
-	for rightPos, rightRow := range rightTable.Rows {
-		if !matchedRightRows[rightPos] {
-			joined := combineRowsWithNull(data.Row{}, rightRow, leftTable, rightTable)
-			if pred == nil || pred(joined) {
-				results = append(results, joined)
+	if len(cond.Keys) > 0 {
+		key := cond.Keys[0]
+		switch algorithm {
+		case AlgorithmMerge:
+			mergeJoin(leftTable, rightTable, key.Left, key.Right, emit)
+		case AlgorithmIndexNestedLoop:
+			if !indexNestedLoopJoin(leftTable, rightTable, key.Left, key.Right, strategy.Inner, emit) {
+				slog.Debug("No index for index nested-loop join, using hash join",
+					slog.String("inner", strategy.Inner.String()))
+				hashJoin(leftTable, rightTable, key.Left, key.Right, strategy.Inner, emit)
+			}
+		case AlgorithmNestedLoop:
+			nestedLoopJoin(leftTable, rightTable, cond.Keys[:1], emit)
+		default:
+			if len(cond.Keys) > 1 {
+				compositeHashJoin(leftTable, rightTable, cond.Keys, strategy.Inner, emit)
+			} else {
+				hashJoin(leftTable, rightTable, key.Left, key.Right, strategy.Inner, emit)
 			}
 		}
+	} else {
+		nestedLoopJoin(leftTable, rightTable, nil, emit)
 	}
 
-	slog.Info("RIGHT JOIN completed",
-		slog.Int("result_rows", len(results)),
-		slog.Int("unmatched_right", len(rightTable.Rows)-len(matchedRightRows)),
-	)
-
-	return results, nil
-}
-
-// executeFullJoin performs FULL OUTER JOIN
-func executeFullJoin(
-	leftTable *schema.Table,
-	rightTable *schema.Table,
-	leftColumn string,
-	rightColumn string,
-	pred JoinPredicate,
-) ([]data.JoinedRow, error) {
-	leftTable.RLock()
-	defer leftTable.RUnlock()
-	rightTable.RLock()
-	defer rightTable.RUnlock()
-
-	slog.Debug("Starting FULL OUTER JOIN",
-		slog.String("left_table", leftTable.Name),
-		slog.String("right_table", rightTable.Name),
-	)
-
-	hashIndex, _ := buildJoinIndex(rightTable, rightColumn)
-	results := make([]data.JoinedRow, 0)
-	matchedLeftRows := make(map[int]bool)
-	matchedRightRows := make(map[int]bool)
-
-	// Phase 1: INNER JOIN
-	for leftPos, leftRow := range leftTable.Rows {
-		leftValue, exists := leftRow.Data[leftColumn]
-		if !exists {
-			continue
-		}
-
-		rightPositions, found := hashIndex[leftValue]
-		if found {
-			matchedLeftRows[leftPos] = true
-			for _, rightPos := range rightPositions {
-				matchedRightRows[rightPos] = true
-				rightRow := rightTable.Rows[rightPos]
-				joined := combineRows(leftRow, rightRow, leftTable.Name, rightTable.Name)
-
+	// Phase 2: Add unmatched left rows
+	if joinType == JoinTypeLeft || joinType == JoinTypeFull {
+		for leftPos, leftRow := range leftTable.Rows {
+			if !matchedLeftRows[leftPos] {
+				joined := combineRowsWithNull(leftRow, data.Row{}, leftTable, rightTable)
 				if pred == nil || pred(joined) {
 					results = append(results, joined)
 				}
@@ -299,31 +205,27 @@ func executeFullJoin(
 		}
 	}
 
-	// Phase 2: Add unmatched left rows
-	for leftPos, leftRow := range leftTable.Rows {
-		if !matchedLeftRows[leftPos] {
-			joined := combineRowsWithNull(leftRow, data.Row{}, leftTable, rightTable)
-			if pred == nil || pred(joined) {
-				results = append(results, joined)
-			}
-		}
-	}
-
 	// Phase 3: Add unmatched right rows
-	for rightPos, rightRow := range rightTable.Rows {
-		if !matchedRightRows[rightPos] {
-			joined := combineRowsWithNull(data.Row{}, rightRow, leftTable, rightTable)
-			if pred == nil || pred(joined) {
-				results = append(results, joined)
+	if joinType == JoinTypeRight || joinType == JoinTypeFull {
+		for rightPos, rightRow := range rightTable.Rows {
+			if !matchedRightRows[rightPos] {
+				joined := combineRowsWithNull(data.Row{}, rightRow, leftTable, rightTable)
+				if pred == nil || pred(joined) {
+					results = append(results, joined)
+				}
 			}
 		}
 	}
 
-	slog.Info("FULL OUTER JOIN completed",
+	slog.Info(joinType.String()+" completed",
+		slog.String("left_table", leftTable.Name),
+		slog.String("right_table", rightTable.Name),
+		slog.String("algorithm", string(algorithm)),
 		slog.Int("result_rows", len(results)),
+		slog.Int("filtered_by_predicate", skippedByPredicate),
 		slog.Int("unmatched_left", len(leftTable.Rows)-len(matchedLeftRows)),
 		slog.Int("unmatched_right", len(rightTable.Rows)-len(matchedRightRows)),
 	)
 
-	return results, nil
+	return results
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
 )
 
 // validateJoinCondition checks if the join is valid
@@ -24,27 +25,13 @@ func validateJoinCondition(
 	}
 
 	// Find columns in schemas (supporting both qualified and unqualified names)
-	var leftCol, rightCol *schema.Column
-	for i := range leftTable.Schema.Columns {
-		if leftTable.Schema.Columns[i].Name == *leftColumn {
-			leftCol = &leftTable.Schema.Columns[i]
-			break
-		}
-		// Try matching by suffix if it's a join result (e.g., "users.id" matches "id")
-		if strings.HasSuffix(leftTable.Schema.Columns[i].Name, "."+*leftColumn) {
-			leftCol = &leftTable.Schema.Columns[i]
-			break
-		}
+	leftCol, err := findJoinColumn(leftTable, *leftColumn)
+	if err != nil {
+		return err
 	}
-	for i := range rightTable.Schema.Columns {
-		if rightTable.Schema.Columns[i].Name == *rightColumn {
-			rightCol = &rightTable.Schema.Columns[i]
-			break
-		}
-		if strings.HasSuffix(rightTable.Schema.Columns[i].Name, "."+*rightColumn) {
-			rightCol = &rightTable.Schema.Columns[i]
-			break
-		}
+	rightCol, err := findJoinColumn(rightTable, *rightColumn)
+	if err != nil {
+		return err
 	}
 
 	if leftCol == nil {
@@ -68,13 +55,15 @@ func validateJoinCondition(
 	}
 
 	// Warn if joining on non-indexed columns
-	if _, leftIndexed := leftTable.Indexes[leftCol.Name]; !leftIndexed {
+	// The rows of a join result or filtered scan come without the index map
+	// of a stored table, and adding an index would not change how they join.
+	if _, leftIndexed := leftTable.Indexes[leftCol.Name]; !leftIndexed && leftTable.Indexes != nil {
 		slog.Warn("Joining on non-indexed column (consider adding index)",
 			slog.String("table", leftTable.Name),
 			slog.String("column", leftCol.Name),
 		)
 	}
-	if _, rightIndexed := rightTable.Indexes[rightCol.Name]; !rightIndexed {
+	if _, rightIndexed := rightTable.Indexes[rightCol.Name]; !rightIndexed && rightTable.Indexes != nil {
 		slog.Warn("Joining on non-indexed column (consider adding index)",
 			slog.String("table", rightTable.Name),
 			slog.String("column", rightCol.Name),
@@ -84,6 +73,50 @@ func validateJoinCondition(
 	return nil
 }
 
+// findJoinColumn resolves a join column against a table schema
+// Accepts a plain column name, a name qualified with the table's own name
+// ("users.id"), or - for join results whose columns are already qualified -
+// either the qualified name or an unqualified name matching exactly one column.
+// Returns nil (and no error) if the column does not exist.
+func findJoinColumn(table *schema.Table, name string) (*schema.Column, error) {
+	columns := table.Schema.Columns
+	for i := range columns {
+		if columns[i].Name == name {
+			return &columns[i], nil
+		}
+	}
+
+	if prefix, colName, ok := strings.Cut(name, "."); ok && prefix == table.Name {
+		for i := range columns {
+			if columns[i].Name == colName {
+				return &columns[i], nil
+			}
+		}
+	}
+
+	// Try matching by suffix if it's a join result (e.g., "users.id" matches "id")
+	var found *schema.Column
+	for i := range columns {
+		if !strings.HasSuffix(columns[i].Name, "."+name) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("column reference '%s' is ambiguous: matches %s and %s", name, found.Name, columns[i].Name)
+		}
+		found = &columns[i]
+	}
+	return found, nil
+}
+
+// QualifiedName returns colName prefixed with tableName, unless colName is
+// already qualified (columns of a join result)
+func QualifiedName(tableName, colName string) string {
+	if strings.Contains(colName, ".") {
+		return colName
+	}
+	return tableName + "." + colName
+}
+
 // executeJoinWithDisambiguation is a helper used by innerJoin to find the right column name
 func resolveJoinColumn(table *schema.Table, colName string) string {
 	for i := range table.Schema.Columns {
@@ -99,20 +132,20 @@ func resolveJoinColumn(table *schema.Table, colName string) string {
 
 // buildJoinIndex creates a hash index for the join column
 // Returns the index and a boolean indicating if an existing index was reused
-func buildJoinIndex(table *schema.Table, columnName string) (map[interface{}][]int, bool) {
+func buildJoinIndex(table *schema.Table, columnName string) (index.Index, bool) {
 	// Try to reuse existing index
 	if idx, exists := table.Indexes[columnName]; exists {
-		return idx.Data, true
+		return idx.Store, true
 	}
 
 	// Build temporary index
-	hashIndex := make(map[interface{}][]int)
+	hashIndex := index.NewHash()
 	for i, row := range table.Rows {
 		value, exists := row.Data[columnName]
-		if !exists {
+		if !exists || value == nil {
 			continue // Skip NULL values
 		}
-		hashIndex[value] = append(hashIndex[value], i)
+		hashIndex.Insert(value, i)
 	}
 
 	return hashIndex, false
@@ -129,21 +162,34 @@ func combineRows(
 
 	// Add left table columns
 	for colName, value := range leftRow.Data {
-		qualifiedName := colName
-		if !strings.Contains(colName, ".") {
-			qualifiedName = fmt.Sprintf("%s.%s", leftTableName, colName)
-		}
-		joined.Set(qualifiedName, value)
+		joined.Set(QualifiedName(leftTableName, colName), value)
 	}
 
 	// Add right table columns
 	for colName, value := range rightRow.Data {
-		qualifiedName := colName
-		if !strings.Contains(colName, ".") {
-			qualifiedName = fmt.Sprintf("%s.%s", rightTableName, colName)
-		}
-		joined.Set(qualifiedName, value)
+		joined.Set(QualifiedName(rightTableName, colName), value)
 	}
 
 	return joined
 }
+
+// KeyColumns resolves the join key columns of cond to the names used by the
+// rows of each table
+func KeyColumns(leftTable, rightTable *schema.Table, cond Condition, joinType JoinType) (leftColumns, rightColumns []string, err error) {
+	cond, err = resolveCondition(leftTable, rightTable, cond, joinType)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range cond.Keys {
+		leftColumns = append(leftColumns, key.Left)
+		rightColumns = append(rightColumns, key.Right)
+	}
+	return leftColumns, rightColumns, nil
+}
+
+// HashKey encodes the values of a row's join key columns as one key, equal
+// for rows that join
+// Returns false if any of them is NULL, since such a row joins nothing.
+func HashKey(row data.Row, columns []string) (string, bool) {
+	return compositeKey(row, columns)
+}
@@ -0,0 +1,128 @@
+package join
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// minParallelBuildRows is the smallest inner input whose hash table is built
+// in parallel
+const minParallelBuildRows = 4096
+
+// hashTable maps join keys to the positions of the inner rows that have
+// them, split into partitions so workers can fill them independently
+type hashTable struct {
+	partitions []map[string][]int
+}
+
+// hashEntry is one inner row hashed by a build worker
+type hashEntry struct {
+	key string
+	pos int
+}
+
+// buildHashTable hashes the join keys of rows on several workers
+//
+// The build runs in two phases. First each worker hashes a contiguous chunk
+// of rows, sorting the entries by partition. Then each worker fills whole
+// partitions, appending the chunks' entries in chunk order. Positions of
+// equal keys therefore stay ascending, exactly as in a serial build, and
+// probes return matches in the same order. Rows with a NULL key are left out.
+func buildHashTable(rows []data.Row, columns []string, workers int) *hashTable {
+	partitionCount := workers
+	chunkSize := (len(rows) + workers - 1) / workers
+
+	// Phase 1: chunk w → entries[w][partition]
+	entries := make([][][]hashEntry, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			local := make([][]hashEntry, partitionCount)
+			start, end := w*chunkSize, min((w+1)*chunkSize, len(rows))
+			for pos := start; pos < end; pos++ {
+				key, ok := compositeKey(rows[pos], columns)
+				if !ok {
+					continue
+				}
+				p := partitionOf(key, partitionCount)
+				local[p] = append(local[p], hashEntry{key: key, pos: pos})
+			}
+			entries[w] = local
+		}(w)
+	}
+	wg.Wait()
+
+	// Phase 2: partition p ← entries[0][p], entries[1][p], ...
+	table := &hashTable{partitions: make([]map[string][]int, partitionCount)}
+	for p := 0; p < partitionCount; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			partition := make(map[string][]int)
+			for w := 0; w < workers; w++ {
+				for _, e := range entries[w][p] {
+					partition[e.key] = append(partition[e.key], e.pos)
+				}
+			}
+			table.partitions[p] = partition
+		}(p)
+	}
+	wg.Wait()
+	return table
+}
+
+// lookup returns the positions of the inner rows with key
+func (t *hashTable) lookup(key string) []int {
+	return t.partitions[partitionOf(key, len(t.partitions))][key]
+}
+
+// partitionOf returns the hash table partition of key
+func partitionOf(key string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+// ProbeBatch probes a batch of outer rows, returning the joined rows of each
+// outer row at the same index
+//
+// With Strategy.Workers above 1 the rows are probed on several workers. The
+// result is the same as calling Probe on every row in order.
+func (s *Stream) ProbeBatch(outer []data.Row) [][]data.JoinedRow {
+	results := make([][]data.JoinedRow, len(outer))
+	matched := make([][]int, len(outer))
+
+	workers := min(s.workers, len(outer))
+	if workers <= 1 {
+		for i, row := range outer {
+			results[i] = s.Probe(row)
+		}
+		return results
+	}
+
+	chunkSize := (len(outer) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				results[i], matched[i] = s.probe(outer[i])
+			}
+		}(w*chunkSize, min((w+1)*chunkSize, len(outer)))
+	}
+	wg.Wait()
+
+	// Inner rows are marked after the workers finish, so they never write
+	// to the same flags
+	for _, positions := range matched {
+		for _, pos := range positions {
+			s.matched[pos] = true
+		}
+	}
+	return results
+}
@@ -0,0 +1,234 @@
+package join
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+)
+
+// Stream joins the rows of one input, fed in one at a time, against the other
+// input held in memory
+//
+// The inner input (Strategy.Inner) is the hash join build side or the side
+// probed through its index; its table must hold every row. The outer input is
+// never materialized - only its Name and Schema are used - so the memory a
+// join needs is bounded by its inner input. Merge joins read both inputs in
+// sorted order and cannot be streamed.
+//
+// The caller must hold a read lock on a stored inner table for as long as the
+// stream is used, since its indexes are read while probing.
+//
+// With Strategy.Workers above 1, a large hash table is built on several
+// workers and ProbeBatch probes outer rows concurrently; either way the
+// joined rows come out in the same order.
+type Stream struct {
+	left, right *schema.Table
+	inner       Side
+	cond        Condition
+	joinType    JoinType
+	algorithm   Algorithm
+	workers     int
+	lookup      func(outer data.Row) []int
+	matched     []bool // inner rows matched so far
+	unmatched   int    // next inner position checked by NextUnmatched
+}
+
+// NewStream prepares a streaming join of left and right
+func NewStream(left, right *schema.Table, cond Condition, joinType JoinType, strategy Strategy) (*Stream, error) {
+	cond, err := resolveCondition(left, right, cond, joinType)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		left:      left,
+		right:     right,
+		inner:     strategy.Inner,
+		cond:      cond,
+		joinType:  joinType,
+		algorithm: strategy.Algorithm,
+		workers:   strategy.Workers,
+	}
+	innerTable := s.innerTable()
+	s.matched = make([]bool, len(innerTable.Rows))
+
+	if len(cond.Keys) == 0 {
+		s.algorithm = AlgorithmNestedLoop
+	}
+	if s.algorithm == AlgorithmMerge {
+		return nil, fmt.Errorf("merge join cannot stream its inputs")
+	}
+
+	var innerColumns, outerColumns []string
+	for _, key := range cond.Keys {
+		if s.inner == SideLeft {
+			innerColumns, outerColumns = append(innerColumns, key.Left), append(outerColumns, key.Right)
+		} else {
+			innerColumns, outerColumns = append(innerColumns, key.Right), append(outerColumns, key.Left)
+		}
+	}
+
+	if s.algorithm == AlgorithmIndexNestedLoop {
+		if idx, ok := innerTable.Indexes[innerColumns[0]]; ok {
+			s.lookup = indexLookup(idx.Store.Lookup, outerColumns[0])
+			return s, nil
+		}
+		slog.Debug("No index for index nested-loop join, using hash join",
+			slog.String("inner", s.inner.String()))
+		s.algorithm = AlgorithmHash
+	}
+
+	// A stored index on a single join column is reused rather than rebuilt
+	indexed := false
+	if len(innerColumns) == 1 {
+		_, indexed = innerTable.Indexes[innerColumns[0]]
+	}
+	switch {
+	case s.algorithm == AlgorithmNestedLoop:
+		all := make([]int, len(innerTable.Rows))
+		for i := range all {
+			all[i] = i
+		}
+		s.lookup = func(data.Row) []int { return all }
+	case s.workers > 1 && len(innerTable.Rows) >= minParallelBuildRows && !indexed:
+		hashTable := buildHashTable(innerTable.Rows, innerColumns, s.workers)
+		s.lookup = func(outer data.Row) []int {
+			key, ok := compositeKey(outer, outerColumns)
+			if !ok {
+				return nil
+			}
+			return hashTable.lookup(key)
+		}
+	case len(cond.Keys) > 1:
+		hashTable := make(map[string][]int)
+		for pos, row := range innerTable.Rows {
+			if key, ok := compositeKey(row, innerColumns); ok {
+				hashTable[key] = append(hashTable[key], pos)
+			}
+		}
+		s.lookup = func(outer data.Row) []int {
+			key, ok := compositeKey(outer, outerColumns)
+			if !ok {
+				return nil
+			}
+			return hashTable[key]
+		}
+	default:
+		hashIndex, _ := buildJoinIndex(innerTable, innerColumns[0])
+		s.lookup = indexLookup(hashIndex.Lookup, outerColumns[0])
+	}
+	return s, nil
+}
+
+// indexLookup returns a lookup of an outer row's join value in an index
+// NULL never equals anything, so rows with a NULL join value match nothing.
+func indexLookup(lookup func(interface{}) []int, outerColumn string) func(data.Row) []int {
+	return func(outer data.Row) []int {
+		value, exists := outer.Data[outerColumn]
+		if !exists || value == nil {
+			return nil
+		}
+		return lookup(value)
+	}
+}
+
+// Algorithm returns the algorithm the stream matches rows with
+func (s *Stream) Algorithm() Algorithm {
+	return s.algorithm
+}
+
+// Probe returns the joined rows for one row of the outer input
+// When nothing matches and the join preserves the outer side, the result is
+// the outer row NULL-extended.
+func (s *Stream) Probe(outer data.Row) []data.JoinedRow {
+	results, matched := s.probe(outer)
+	for _, pos := range matched {
+		s.matched[pos] = true
+	}
+	return results
+}
+
+// probe joins one outer row without marking the inner rows it matched
+// Returns the joined rows and the positions of the matched inner rows.
+func (s *Stream) probe(outer data.Row) (results []data.JoinedRow, matched []int) {
+	innerRows := s.innerTable().Rows
+	for _, pos := range s.lookup(outer) {
+		leftRow, rightRow := outer, innerRows[pos]
+		if s.inner == SideLeft {
+			leftRow, rightRow = rightRow, leftRow
+		}
+		if !keysEqual(leftRow, rightRow, s.cond.Keys) {
+			continue
+		}
+		joined := combineRows(leftRow, rightRow, s.left.Name, s.right.Name)
+		if s.cond.Filter != nil && !s.cond.Filter(joined) {
+			continue
+		}
+		matched = append(matched, pos)
+		results = append(results, joined)
+	}
+
+	if len(results) == 0 && s.preserves(s.outerSide()) {
+		if s.inner == SideLeft {
+			results = append(results, combineRowsWithNull(data.Row{}, outer, s.left, s.right))
+		} else {
+			results = append(results, combineRowsWithNull(outer, data.Row{}, s.left, s.right))
+		}
+	}
+	return results, matched
+}
+
+// NextUnmatched returns the next inner row that no outer row matched,
+// NULL-extended, if the join preserves the inner side
+// Call it once every outer row has been probed; ok is false when there are
+// no more such rows.
+func (s *Stream) NextUnmatched() (data.JoinedRow, bool) {
+	if !s.preserves(s.inner) {
+		return data.JoinedRow{}, false
+	}
+	innerRows := s.innerTable().Rows
+	for s.unmatched < len(innerRows) {
+		pos := s.unmatched
+		s.unmatched++
+		if s.matched[pos] {
+			continue
+		}
+		if s.inner == SideLeft {
+			return combineRowsWithNull(innerRows[pos], data.Row{}, s.left, s.right), true
+		}
+		return combineRowsWithNull(data.Row{}, innerRows[pos], s.left, s.right), true
+	}
+	return data.JoinedRow{}, false
+}
+
+// innerTable returns the input held in memory
+func (s *Stream) innerTable() *schema.Table {
+	if s.inner == SideLeft {
+		return s.left
+	}
+	return s.right
+}
+
+// outerSide returns the side of the streamed input
+func (s *Stream) outerSide() Side {
+	if s.inner == SideLeft {
+		return SideRight
+	}
+	return SideLeft
+}
+
+// preserves reports whether the join keeps the unmatched rows of side
+func (s *Stream) preserves(side Side) bool {
+	switch s.joinType {
+	case JoinTypeFull:
+		return true
+	case JoinTypeLeft:
+		return side == SideLeft
+	case JoinTypeRight:
+		return side == SideRight
+	default:
+		return false
+	}
+}
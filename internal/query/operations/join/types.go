@@ -1,8 +1,6 @@
 package join
 
 import (
-	"fmt"
-
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 )
@@ -33,6 +31,76 @@ func (jt JoinType) String() string {
 	}
 }
 
+// Algorithm is the physical algorithm used to find matching row pairs
+type Algorithm string
+
+const (
+	// AlgorithmHash builds a hash table on one input and probes it with the other
+	AlgorithmHash Algorithm = "hash"
+	// AlgorithmMerge sorts both inputs on the join key (or walks an ordered
+	// index) and merges them
+	AlgorithmMerge Algorithm = "merge"
+	// AlgorithmIndexNestedLoop probes an existing index on one input once for
+	// every row of the other input
+	AlgorithmIndexNestedLoop Algorithm = "index_nested_loop"
+	// AlgorithmNestedLoop compares every pair of rows; the only algorithm for
+	// joins without an equality key
+	AlgorithmNestedLoop Algorithm = "nested_loop"
+)
+
+// ParseAlgorithm converts an algorithm name into an Algorithm
+func ParseAlgorithm(name string) (Algorithm, bool) {
+	switch Algorithm(name) {
+	case AlgorithmHash, AlgorithmMerge, AlgorithmIndexNestedLoop, AlgorithmNestedLoop:
+		return Algorithm(name), true
+	default:
+		return "", false
+	}
+}
+
+// Side identifies one input of a join
+type Side int
+
+const (
+	SideRight Side = iota
+	SideLeft
+)
+
+// String returns the string representation of the join side
+func (s Side) String() string {
+	if s == SideLeft {
+		return "left"
+	}
+	return "right"
+}
+
+// Strategy describes how a join is physically executed
+// The zero value is a hash join building on the right input.
+type Strategy struct {
+	Algorithm Algorithm
+	// Inner is the hash join build side, or the side probed through its index
+	// by an index nested-loop join. Ignored by merge joins.
+	Inner Side
+	// Workers is the number of workers a streaming join builds its hash
+	// table and probes batches with; 0 or 1 runs serially
+	Workers int
+}
+
+// Key is one equality pair of a join condition: Left = Right
+type Key struct {
+	Left  string
+	Right string
+}
+
+// Condition is a JOIN ON condition
+// Rows match when every key pair is equal (NULL never equals anything) and
+// Filter accepts the combined row. Without keys every pair of rows is
+// compared with a nested-loop join.
+type Condition struct {
+	Keys   []Key
+	Filter JoinPredicate // rest of the ON condition (nil if none)
+}
+
 // combineRowsWithNull combines two rows with table-qualified column names
 // If leftRow is nil, all left columns are set to NULL
 // If rightRow is nil, all right columns are set to NULL
@@ -47,28 +115,24 @@ func combineRowsWithNull(
 	// Add left table columns (or NULLs if leftRow is empty)
 	if len(leftRow.Data) > 0 {
 		for colName, value := range leftRow.Data {
-			qualifiedName := fmt.Sprintf("%s.%s", leftTable.Name, colName)
-			joined.Set(qualifiedName, value)
+			joined.Set(QualifiedName(leftTable.Name, colName), value)
 		}
 	} else {
 		// Add NULL for all left columns
 		for _, col := range leftTable.Schema.Columns {
-			qualifiedName := fmt.Sprintf("%s.%s", leftTable.Name, col.Name)
-			joined.Set(qualifiedName, nil)
+			joined.Set(QualifiedName(leftTable.Name, col.Name), nil)
 		}
 	}
 
 	// Add right table columns (or NULLs if rightRow is empty)
 	if len(rightRow.Data) > 0 {
 		for colName, value := range rightRow.Data {
-			qualifiedName := fmt.Sprintf("%s.%s", rightTable.Name, colName)
-			joined.Set(qualifiedName, value)
+			joined.Set(QualifiedName(rightTable.Name, colName), value)
 		}
 	} else {
 		// Add NULL for all right columns
 		for _, col := range rightTable.Schema.Columns {
-			qualifiedName := fmt.Sprintf("%s.%s", rightTable.Name, col.Name)
-			joined.Set(qualifiedName, nil)
+			joined.Set(QualifiedName(rightTable.Name, col.Name), nil)
 		}
 	}
 
@@ -0,0 +1,123 @@
+package spill
+
+import (
+	"sync"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Budget tracks the memory the operators of one query hold
+// Sorts, hash joins and aggregations reserve memory for the rows they buffer
+// and write them to temporary run files once a reservation is refused. A
+// limit of 0 never refuses.
+type Budget struct {
+	mu      sync.Mutex
+	limit   int64
+	used    int64
+	peak    int64
+	runs    int
+	written int64
+}
+
+// NewBudget creates a budget of limit bytes (0 for no limit)
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Reserve reserves n bytes, returning false without reserving anything if
+// that would exceed the limit
+func (b *Budget) Reserve(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && b.used+n > b.limit {
+		return false
+	}
+	b.grow(n)
+	return true
+}
+
+// Force reserves n bytes even beyond the limit
+// Used for what cannot be spilled, such as a single row larger than the budget.
+func (b *Budget) Force(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.grow(n)
+}
+
+func (b *Budget) grow(n int64) {
+	b.used += n
+	if b.used > b.peak {
+		b.peak = b.used
+	}
+}
+
+// Release returns n reserved bytes
+func (b *Budget) Release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+}
+
+// Limit returns the budget in bytes, 0 if unlimited
+func (b *Budget) Limit() int64 {
+	return b.limit
+}
+
+// Used returns the bytes currently reserved
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Peak returns the most bytes reserved at once
+func (b *Budget) Peak() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.peak
+}
+
+// SpilledRuns returns the number of run files written
+func (b *Budget) SpilledRuns() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs
+}
+
+// SpilledBytes returns the number of bytes written to run files
+func (b *Budget) SpilledBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
+
+// recordRun counts a finished run file of size bytes
+func (b *Budget) recordRun(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.runs++
+	b.written += size
+}
+
+// RowSize estimates the memory a row holds
+// The estimate covers the map, its keys and boxed values; it is meant for
+// budgeting, not exact accounting.
+func RowSize(row data.Row) int64 {
+	const (
+		rowOverhead   = 64 // Row, map header and buckets
+		entryOverhead = 32 // key header, interface and bucket slot
+	)
+	size := int64(rowOverhead)
+	for key, value := range row.Data {
+		size += entryOverhead + int64(len(key))
+		switch v := value.(type) {
+		case string:
+			size += 16 + int64(len(v))
+		case nil:
+		default:
+			size += 8
+		}
+	}
+	return size
+}
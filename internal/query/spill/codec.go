@@ -0,0 +1,143 @@
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Value tags of the run file row encoding
+const (
+	tagNull byte = iota
+	tagInt64
+	tagInt
+	tagFloat64
+	tagString
+	tagFalse
+	tagTrue
+)
+
+// writeRow encodes a row as its column count followed by name/value pairs
+// Values keep their Go type (int64, int, float64, string, bool or nil) so
+// rows read back compare and hash exactly like the originals.
+func writeRow(w *bufio.Writer, row data.Row) error {
+	var buf [binary.MaxVarintLen64]byte
+
+	putUvarint := func(v uint64) {
+		n := binary.PutUvarint(buf[:], v)
+		w.Write(buf[:n])
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		w.WriteString(s)
+	}
+
+	putUvarint(uint64(len(row.Data)))
+	for name, value := range row.Data {
+		putString(name)
+		switch v := value.(type) {
+		case nil:
+			w.WriteByte(tagNull)
+		case int64:
+			w.WriteByte(tagInt64)
+			n := binary.PutVarint(buf[:], v)
+			w.Write(buf[:n])
+		case int:
+			w.WriteByte(tagInt)
+			n := binary.PutVarint(buf[:], int64(v))
+			w.Write(buf[:n])
+		case float64:
+			w.WriteByte(tagFloat64)
+			binary.LittleEndian.PutUint64(buf[:8], math.Float64bits(v))
+			w.Write(buf[:8])
+		case string:
+			w.WriteByte(tagString)
+			putString(v)
+		case bool:
+			if v {
+				w.WriteByte(tagTrue)
+			} else {
+				w.WriteByte(tagFalse)
+			}
+		default:
+			return fmt.Errorf("cannot spill value of type %T in column %s", value, name)
+		}
+	}
+	return nil
+}
+
+// readRow decodes a row written by writeRow
+// Returns io.EOF at the end of the run.
+func readRow(r *bufio.Reader) (data.Row, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return data.Row{}, err
+	}
+
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	values := make(map[string]interface{}, count)
+	for i := uint64(0); i < count; i++ {
+		name, err := readString()
+		if err != nil {
+			return data.Row{}, corrupt(err)
+		}
+		tag, err := r.ReadByte()
+		if err != nil {
+			return data.Row{}, corrupt(err)
+		}
+		switch tag {
+		case tagNull:
+			values[name] = nil
+		case tagInt64, tagInt:
+			v, err := binary.ReadVarint(r)
+			if err != nil {
+				return data.Row{}, corrupt(err)
+			}
+			if tag == tagInt {
+				values[name] = int(v)
+			} else {
+				values[name] = v
+			}
+		case tagFloat64:
+			var b [8]byte
+			if _, err := io.ReadFull(r, b[:]); err != nil {
+				return data.Row{}, corrupt(err)
+			}
+			values[name] = math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+		case tagString:
+			v, err := readString()
+			if err != nil {
+				return data.Row{}, corrupt(err)
+			}
+			values[name] = v
+		case tagFalse, tagTrue:
+			values[name] = tag == tagTrue
+		default:
+			return data.Row{}, fmt.Errorf("corrupt run file: unknown value tag %d", tag)
+		}
+	}
+	return data.NewRow(values), nil
+}
+
+// corrupt reports a run file that ends in the middle of a row
+func corrupt(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("corrupt run file: %w", err)
+}
@@ -0,0 +1,86 @@
+package spill
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Partitions splits rows into run files by the hash of a key
+// Rows with equal keys always land in the same partition, so a grace hash
+// join or a hash aggregation can process one partition at a time. The level
+// seeds the hash: repartitioning an oversized partition with the next level
+// spreads its rows differently.
+type Partitions struct {
+	budget *Budget
+	dir    string
+	level  int
+	runs   []*Run
+}
+
+// NewPartitions creates count empty partitions in dir
+// Run files are only created for partitions that receive rows.
+func NewPartitions(budget *Budget, dir string, count, level int) *Partitions {
+	return &Partitions{budget: budget, dir: dir, level: level, runs: make([]*Run, count)}
+}
+
+// Count returns the number of partitions
+func (p *Partitions) Count() int {
+	return len(p.runs)
+}
+
+// Level returns the hash level the partitions were created with
+func (p *Partitions) Level() int {
+	return p.level
+}
+
+// Of returns the partition of key
+func (p *Partitions) Of(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(p.level)))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.runs)))
+}
+
+// Write appends a row to partition i
+func (p *Partitions) Write(i int, row data.Row) error {
+	if p.runs[i] == nil {
+		run, err := NewRun(p.dir, p.budget)
+		if err != nil {
+			return err
+		}
+		p.runs[i] = run
+	}
+	return p.runs[i].Write(row)
+}
+
+// Run returns the run of partition i, nil if no row was written to it
+func (p *Partitions) Run(i int) *Run {
+	return p.runs[i]
+}
+
+// Remove deletes every partition's run file
+func (p *Partitions) Remove() error {
+	var err error
+	for i, run := range p.runs {
+		if run == nil {
+			continue
+		}
+		if removeErr := run.Remove(); err == nil {
+			err = removeErr
+		}
+		p.runs[i] = nil
+	}
+	return err
+}
+
+// Open returns a reader over the rows of partition i
+// An empty partition reads no rows.
+func (p *Partitions) Open(i int) (Iterator, error) {
+	if p.runs[i] == nil {
+		return &sliceIterator{}, nil
+	}
+	return p.runs[i].Open()
+}
@@ -0,0 +1,102 @@
+package spill
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Run is a temporary file of rows written by a spilling operator
+// Rows are appended with Write and read back in the same order with Open.
+// Remove deletes the file; a run is never kept beyond the query that wrote it.
+type Run struct {
+	budget *Budget
+	file   *os.File
+	w      *bufio.Writer
+	rows   int
+	closed bool
+}
+
+// NewRun creates an empty run file in dir, creating dir if needed
+func NewRun(dir string, budget *Budget) (*Run, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "spill-*.run")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run file: %w", err)
+	}
+	return &Run{budget: budget, file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Write appends a row to the run
+func (r *Run) Write(row data.Row) error {
+	if r.closed {
+		return fmt.Errorf("run file %s is already finished", r.file.Name())
+	}
+	if err := writeRow(r.w, row); err != nil {
+		return err
+	}
+	r.rows++
+	return nil
+}
+
+// Rows returns the number of rows written
+func (r *Run) Rows() int {
+	return r.rows
+}
+
+// finish flushes the rows written so far; the run can no longer be written
+func (r *Run) finish() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write run file: %w", err)
+	}
+	if info, err := r.file.Stat(); err == nil && r.budget != nil {
+		r.budget.recordRun(info.Size())
+	}
+	return nil
+}
+
+// Open finishes the run and returns a reader over its rows from the start
+func (r *Run) Open() (*RunReader, error) {
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read run file: %w", err)
+	}
+	return &RunReader{r: bufio.NewReader(r.file)}, nil
+}
+
+// Remove closes and deletes the run file
+func (r *Run) Remove() error {
+	r.file.Close()
+	if err := os.Remove(r.file.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove run file: %w", err)
+	}
+	return nil
+}
+
+// RunReader reads the rows of a run in the order they were written
+type RunReader struct {
+	r *bufio.Reader
+}
+
+// Next returns the next row; ok is false once the run is exhausted
+func (rr *RunReader) Next() (row data.Row, ok bool, err error) {
+	row, err = readRow(rr.r)
+	if err == io.EOF {
+		return data.Row{}, false, nil
+	}
+	if err != nil {
+		return data.Row{}, false, err
+	}
+	return row, true, nil
+}
@@ -0,0 +1,198 @@
+package spill
+
+import (
+	"container/heap"
+	"log/slog"
+	"sort"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// Sorter sorts rows with an external merge sort
+// Rows are buffered in memory while the budget allows; when a reservation is
+// refused the buffer is sorted and written out as a run, and Sort merges the
+// runs. A sort that fits its budget never touches the disk. The sort is
+// stable: rows that compare equal keep their input order.
+type Sorter struct {
+	budget   *Budget
+	dir      string
+	compare  func(a, b data.Row) int
+	buffer   []data.Row
+	reserved int64
+	runs     []*Run
+}
+
+// NewSorter creates a sorter that orders rows by compare, writing runs to dir
+func NewSorter(budget *Budget, dir string, compare func(a, b data.Row) int) *Sorter {
+	return &Sorter{budget: budget, dir: dir, compare: compare}
+}
+
+// Add adds a row to the sort
+func (s *Sorter) Add(row data.Row) error {
+	size := RowSize(row)
+	if !s.budget.Reserve(size) {
+		if len(s.buffer) > 0 {
+			if err := s.spill(); err != nil {
+				return err
+			}
+		}
+		if !s.budget.Reserve(size) {
+			// Other operators hold the budget; keep at least this row
+			s.budget.Force(size)
+		}
+	}
+	s.reserved += size
+	s.buffer = append(s.buffer, row)
+	return nil
+}
+
+// spill sorts the buffered rows and writes them to a new run
+func (s *Sorter) spill() error {
+	s.sortBuffer()
+	run, err := NewRun(s.dir, s.budget)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	for _, row := range s.buffer {
+		if err := run.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := run.finish(); err != nil {
+		return err
+	}
+
+	slog.Debug("Sort spilled a run",
+		slog.Int("run", len(s.runs)),
+		slog.Int("rows", len(s.buffer)),
+	)
+	s.budget.Release(s.reserved)
+	s.buffer, s.reserved = nil, 0
+	return nil
+}
+
+func (s *Sorter) sortBuffer() {
+	sort.SliceStable(s.buffer, func(i, j int) bool {
+		return s.compare(s.buffer[i], s.buffer[j]) < 0
+	})
+}
+
+// Sort returns the rows added so far in order
+// The sorter must not be added to afterwards; Close releases it.
+func (s *Sorter) Sort() (Iterator, error) {
+	if len(s.runs) == 0 {
+		s.sortBuffer()
+		return &sliceIterator{rows: s.buffer}, nil
+	}
+	if len(s.buffer) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+
+	merge := &mergeIterator{compare: s.compare}
+	for i, run := range s.runs {
+		reader, err := run.Open()
+		if err != nil {
+			return nil, err
+		}
+		row, ok, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			merge.heads = append(merge.heads, &runHead{row: row, run: i, reader: reader})
+		}
+	}
+	heap.Init(merge)
+	return merge, nil
+}
+
+// Runs returns the number of runs the sort spilled
+func (s *Sorter) Runs() int {
+	return len(s.runs)
+}
+
+// Close releases the sorter's memory and deletes its runs
+func (s *Sorter) Close() error {
+	var err error
+	for _, run := range s.runs {
+		if removeErr := run.Remove(); err == nil {
+			err = removeErr
+		}
+	}
+	s.budget.Release(s.reserved)
+	s.buffer, s.reserved, s.runs = nil, 0, nil
+	return err
+}
+
+// Iterator returns rows one at a time
+type Iterator interface {
+	// Next returns the next row; ok is false once the rows are exhausted
+	Next() (row data.Row, ok bool, err error)
+}
+
+// sliceIterator iterates over rows held in memory
+type sliceIterator struct {
+	rows []data.Row
+	pos  int
+}
+
+func (it *sliceIterator) Next() (data.Row, bool, error) {
+	if it.pos >= len(it.rows) {
+		return data.Row{}, false, nil
+	}
+	row := it.rows[it.pos]
+	it.pos++
+	return row, true, nil
+}
+
+// runHead is the next unmerged row of a run
+type runHead struct {
+	row    data.Row
+	run    int
+	reader *RunReader
+}
+
+// mergeIterator merges sorted runs (a min-heap of their next rows)
+// Equal rows come from the earlier run first, which keeps the sort stable.
+type mergeIterator struct {
+	compare func(a, b data.Row) int
+	heads   []*runHead
+}
+
+func (m *mergeIterator) Len() int { return len(m.heads) }
+func (m *mergeIterator) Less(i, j int) bool {
+	if c := m.compare(m.heads[i].row, m.heads[j].row); c != 0 {
+		return c < 0
+	}
+	return m.heads[i].run < m.heads[j].run
+}
+func (m *mergeIterator) Swap(i, j int) { m.heads[i], m.heads[j] = m.heads[j], m.heads[i] }
+func (m *mergeIterator) Push(x any)    { m.heads = append(m.heads, x.(*runHead)) }
+func (m *mergeIterator) Pop() any {
+	last := m.heads[len(m.heads)-1]
+	m.heads = m.heads[:len(m.heads)-1]
+	return last
+}
+
+func (m *mergeIterator) Next() (data.Row, bool, error) {
+	if len(m.heads) == 0 {
+		return data.Row{}, false, nil
+	}
+	head := m.heads[0]
+	row := head.row
+
+	next, ok, err := head.reader.Next()
+	if err != nil {
+		return data.Row{}, false, err
+	}
+	if ok {
+		head.row = next
+		heap.Fix(m, 0)
+	} else {
+		heap.Pop(m)
+	}
+	return row, true, nil
+}
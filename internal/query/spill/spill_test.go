@@ -0,0 +1,129 @@
+package spill
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+func TestSorterSpillsRuns(t *testing.T) {
+	dir := t.TempDir()
+	budget := NewBudget(1 << 10)
+	sorter := NewSorter(budget, dir, func(a, b data.Row) int {
+		return int(a.Data["key"].(int64) - b.Data["key"].(int64))
+	})
+
+	const count = 500
+	for i := 0; i < count; i++ {
+		// Keys repeat, so the seq of equal keys checks stability
+		row := data.NewRow(map[string]interface{}{"key": int64(i * 7 % 50), "seq": i})
+		if err := sorter.Add(row); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if sorter.Runs() < 2 {
+		t.Fatalf("Expected the sort to spill several runs, got %d", sorter.Runs())
+	}
+
+	rows, err := sorter.Sort()
+	if err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	var prev data.Row
+	for i := 0; ; i++ {
+		row, ok, err := rows.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if !ok {
+			if i != count {
+				t.Errorf("Expected %d rows, got %d", count, i)
+			}
+			break
+		}
+		if i > 0 {
+			pk, k := prev.Data["key"].(int64), row.Data["key"].(int64)
+			if pk > k || pk == k && prev.Data["seq"].(int) > row.Data["seq"].(int) {
+				t.Fatalf("Row %d out of order: %v after %v", i, row.Data, prev.Data)
+			}
+		}
+		prev = row
+	}
+
+	if err := sorter.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if budget.Used() != 0 {
+		t.Errorf("Expected the budget to be released, %d bytes used", budget.Used())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected run files to be removed, found %d", len(entries))
+	}
+}
+
+func TestPartitionsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	budget := NewBudget(0)
+	parts := NewPartitions(budget, dir, 4, 0)
+
+	written := make(map[int][]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i%10)
+		row := data.NewRow(map[string]interface{}{
+			"key": key, "int": i, "int64": int64(-i), "float": float64(i) / 4,
+			"bool": i%2 == 0, "null": nil,
+		})
+		p := parts.Of(key)
+		if p != parts.Of(key) || p < 0 || p >= parts.Count() {
+			t.Fatalf("Partition of %q is not stable or out of range: %d", key, p)
+		}
+		if err := parts.Write(p, row); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		written[p] = append(written[p], fmt.Sprint(row.Data))
+	}
+
+	for p := 0; p < parts.Count(); p++ {
+		rows, err := parts.Open(p)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		var read []string
+		for {
+			row, ok, err := rows.Next()
+			if err != nil {
+				t.Fatalf("Next failed: %v", err)
+			}
+			if !ok {
+				break
+			}
+			read = append(read, fmt.Sprint(row.Data))
+		}
+		if fmt.Sprint(read) != fmt.Sprint(written[p]) {
+			t.Errorf("Partition %d: rows changed on disk:\n got  %v\n want %v", p, read, written[p])
+		}
+	}
+	if budget.SpilledRuns() == 0 || budget.SpilledBytes() == 0 {
+		t.Errorf("Expected spilled runs to be recorded, got %d runs of %d bytes", budget.SpilledRuns(), budget.SpilledBytes())
+	}
+
+	if err := parts.Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected partition files to be removed, found %d", len(entries))
+	}
+}
+
+func TestWriteRowRejectsUnknownTypes(t *testing.T) {
+	run, err := NewRun(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("NewRun failed: %v", err)
+	}
+	defer run.Remove()
+	if err := run.Write(data.NewRow(map[string]interface{}{"v": []int{1}})); err == nil {
+		t.Error("Expected an error for a value that cannot be spilled")
+	}
+}
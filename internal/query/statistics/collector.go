@@ -0,0 +1,124 @@
+package statistics
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+)
+
+// DefaultHistogramBuckets is the number of equi-depth buckets built per column
+const DefaultHistogramBuckets = 10
+
+// Analyze scans a table and collects planner statistics for every column
+// The statistics replace table.Stats and the table is marked dirty so they
+// are persisted (stats.json) on the next save. The table is read in one
+// pass, so a paged table is streamed from disk once.
+func Analyze(table *schema.Table) (*schema.TableStatistics, error) {
+	table.RLock()
+	values := make(map[string][]interface{}, len(table.Schema.Columns))
+	nulls := make(map[string]int, len(table.Schema.Columns))
+	rows := 0
+	err := table.ForEachRowUnsafe(func(_ int, row data.Row) error {
+		rows++
+		for _, col := range table.Schema.Columns {
+			val, ok := row.Data[col.Name]
+			if !ok || val == nil {
+				nulls[col.Name]++
+				continue
+			}
+			values[col.Name] = append(values[col.Name], index.NormalizeKey(val))
+		}
+		return nil
+	})
+	if err != nil {
+		table.RUnlock()
+		return nil, err
+	}
+
+	stats := &schema.TableStatistics{
+		RowCount:   int64(rows),
+		AnalyzedAt: time.Now().UTC(),
+		Columns:    make(map[string]*schema.ColumnStatistics, len(table.Schema.Columns)),
+	}
+	for _, col := range table.Schema.Columns {
+		stats.Columns[col.Name] = analyzeColumn(col, values[col.Name], nulls[col.Name], rows)
+	}
+	table.RUnlock()
+
+	table.Lock()
+	table.Stats = stats
+	table.MarkDirtyUnsafe()
+	table.Unlock()
+
+	slog.Info("table analyzed",
+		slog.String("table", table.Name),
+		slog.Int64("rows", stats.RowCount),
+		slog.Int("columns", len(stats.Columns)))
+
+	return stats, nil
+}
+
+// AnalyzeDatabase collects statistics for every table in the database
+func AnalyzeDatabase(db *schema.Database) error {
+	for name, table := range db.Tables {
+		if _, err := Analyze(table); err != nil {
+			return fmt.Errorf("failed to analyze table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// analyzeColumn collects statistics for one column from its non-NULL
+// values, out of rows rows
+func analyzeColumn(col schema.Column, values []interface{}, nulls, rows int) *schema.ColumnStatistics {
+	stats := &schema.ColumnStatistics{}
+	if rows > 0 {
+		stats.NullFraction = float64(nulls) / float64(rows)
+	}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return index.Compare(values[i], values[j]) < 0
+	})
+
+	stats.DistinctCount = 1
+	for i := 1; i < len(values); i++ {
+		if index.Compare(values[i-1], values[i]) != 0 {
+			stats.DistinctCount++
+		}
+	}
+
+	stats.Min = values[0]
+	stats.Max = values[len(values)-1]
+
+	if col.Type != schema.ColumnTypeBool {
+		stats.Histogram = buildHistogram(values, DefaultHistogramBuckets)
+	}
+	return stats
+}
+
+// buildHistogram returns equi-depth bucket boundaries for sorted values
+// Boundary i is the value at quantile i/buckets, so every bucket holds
+// roughly the same number of values.
+func buildHistogram(sorted []interface{}, buckets int) []interface{} {
+	if buckets > len(sorted) {
+		buckets = len(sorted)
+	}
+	if buckets < 1 {
+		return nil
+	}
+
+	bounds := make([]interface{}, buckets+1)
+	last := len(sorted) - 1
+	for i := 0; i <= buckets; i++ {
+		bounds[i] = sorted[i*last/buckets]
+	}
+	return bounds
+}
@@ -0,0 +1,300 @@
+package statistics
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/util/types"
+)
+
+// Default selectivities used when a column has not been analyzed
+const (
+	DefaultEqualitySelectivity = 0.005
+	DefaultRangeSelectivity    = 1.0 / 3.0
+	DefaultSelectivity         = 1.0 / 3.0
+
+	// DefaultDistinctCount is assumed for non-unique columns without statistics
+	DefaultDistinctCount = 200
+)
+
+// ColumnInfo is what the estimator knows about a column referenced in a
+// predicate: its statistics (nil if not analyzed), whether it is unique, and
+// the current row count of its table
+type ColumnInfo struct {
+	Stats    *schema.ColumnStatistics
+	Unique   bool
+	RowCount int64
+}
+
+// Resolver maps an identifier in a WHERE clause to the column it references
+// Returns false if the column is unknown.
+type Resolver func(ident *ast.Identifier) (ColumnInfo, bool)
+
+// TableResolver returns a Resolver for columns of a single table
+func TableResolver(table *schema.Table) Resolver {
+	return func(ident *ast.Identifier) (ColumnInfo, bool) {
+		if ident.Table != "" && ident.Table != table.Name {
+			return ColumnInfo{}, false
+		}
+		return TableColumnInfo(table, ident.Value)
+	}
+}
+
+// TableColumnInfo returns what the estimator knows about table.column
+func TableColumnInfo(table *schema.Table, column string) (ColumnInfo, bool) {
+	col := table.Schema.GetColumn(column)
+	if col == nil {
+		return ColumnInfo{}, false
+	}
+
+	table.RLock()
+	defer table.RUnlock()
+
+	unique := col.PrimaryKey || col.Unique
+	if idx, ok := table.Indexes[column]; ok && idx.Unique {
+		unique = true
+	}
+	return ColumnInfo{
+		Stats:    table.Stats.ColumnStats(column),
+		Unique:   unique,
+		RowCount: int64(table.RowCountUnsafe()),
+	}, true
+}
+
+// Selectivity estimates the fraction of rows that satisfy expr (0..1)
+// AND terms are assumed independent; unknown expressions use a default.
+func Selectivity(expr ast.Expression, resolve Resolver) float64 {
+	switch e := expr.(type) {
+	case nil:
+		return 1.0
+
+	case *ast.LogicalExpression:
+		left := Selectivity(e.Left, resolve)
+		right := Selectivity(e.Right, resolve)
+		if e.Operator == "OR" {
+			return clamp(left + right - left*right)
+		}
+		return clamp(left * right)
+
+	case *ast.BinaryExpression:
+		ident, ok := e.Left.(*ast.Identifier)
+		if !ok {
+			return DefaultSelectivity
+		}
+		lit, ok := e.Right.(*ast.Literal)
+		if !ok {
+			return DefaultSelectivity
+		}
+		info, ok := resolve(ident)
+		if !ok {
+			return DefaultSelectivity
+		}
+
+		switch e.Operator {
+		case "=":
+			return EqualitySelectivity(info, lit.Value)
+		case "!=", "<>":
+			return clamp(1 - EqualitySelectivity(info, lit.Value) - nullFraction(info))
+		case ">":
+			return RangeSelectivity(info, &index.Bound{Value: lit.Value}, nil)
+		case ">=":
+			return RangeSelectivity(info, &index.Bound{Value: lit.Value, Inclusive: true}, nil)
+		case "<":
+			return RangeSelectivity(info, nil, &index.Bound{Value: lit.Value})
+		case "<=":
+			return RangeSelectivity(info, nil, &index.Bound{Value: lit.Value, Inclusive: true})
+		}
+		return DefaultSelectivity
+
+	case *ast.InExpression:
+		ident, ok := e.Left.(*ast.Identifier)
+		if !ok {
+			return DefaultSelectivity
+		}
+		info, ok := resolve(ident)
+		if !ok {
+			return DefaultSelectivity
+		}
+		total := 0.0
+		for _, v := range e.Values {
+			if lit, ok := v.(*ast.Literal); ok {
+				total += EqualitySelectivity(info, lit.Value)
+			}
+		}
+		return clamp(total)
+
+	case *ast.BetweenExpression:
+		ident, ok := e.Left.(*ast.Identifier)
+		if !ok {
+			return DefaultSelectivity
+		}
+		lower, okLower := e.Lower.(*ast.Literal)
+		upper, okUpper := e.Upper.(*ast.Literal)
+		info, ok := resolve(ident)
+		if !ok || !okLower || !okUpper {
+			return DefaultSelectivity
+		}
+		return RangeSelectivity(info,
+			&index.Bound{Value: lower.Value, Inclusive: true},
+			&index.Bound{Value: upper.Value, Inclusive: true})
+	}
+
+	return DefaultSelectivity
+}
+
+// EqualitySelectivity estimates the fraction of rows where column = value
+func EqualitySelectivity(info ColumnInfo, value interface{}) float64 {
+	if value == nil {
+		return 0
+	}
+
+	stats := info.Stats
+	if stats == nil {
+		if info.Unique && info.RowCount > 0 {
+			return 1.0 / float64(info.RowCount)
+		}
+		return DefaultEqualitySelectivity
+	}
+
+	if stats.DistinctCount == 0 {
+		return 0
+	}
+	if comparable(stats.Min, value) &&
+		(index.Compare(value, stats.Min) < 0 || index.Compare(value, stats.Max) > 0) {
+		return 0 // outside the analyzed value range
+	}
+	return (1 - stats.NullFraction) / float64(stats.DistinctCount)
+}
+
+// RangeSelectivity estimates the fraction of rows within [lower, upper]
+// A nil bound is unbounded. Uses the equi-depth histogram when available.
+func RangeSelectivity(info ColumnInfo, lower, upper *index.Bound) float64 {
+	stats := info.Stats
+	if stats == nil || len(stats.Histogram) < 2 {
+		if lower != nil && upper != nil {
+			return DefaultRangeSelectivity * DefaultRangeSelectivity
+		}
+		return DefaultRangeSelectivity
+	}
+
+	// Fractions of non-NULL values strictly below / at most v
+	below := func(v interface{}) float64 {
+		return fractionBelow(stats.Histogram, v)
+	}
+	atMost := func(v interface{}) float64 {
+		return below(v) + EqualitySelectivity(info, v)/(1-stats.NullFraction)
+	}
+
+	lo, hi := 0.0, 1.0
+	if lower != nil {
+		if lower.Inclusive {
+			lo = below(lower.Value)
+		} else {
+			lo = atMost(lower.Value)
+		}
+	}
+	if upper != nil {
+		if upper.Inclusive {
+			hi = atMost(upper.Value)
+		} else {
+			hi = below(upper.Value)
+		}
+	}
+
+	if hi <= lo {
+		return 0
+	}
+	return clamp((hi - lo) * (1 - stats.NullFraction))
+}
+
+// JoinSelectivity estimates the fraction of the cross product that satisfies
+// left.column = right.column, assuming the smaller domain is contained in the
+// larger one: 1 / max(distinct(left), distinct(right))
+func JoinSelectivity(left, right ColumnInfo) float64 {
+	ndv := distinctCount(left)
+	if r := distinctCount(right); r > ndv {
+		ndv = r
+	}
+	if ndv <= 0 {
+		return DefaultEqualitySelectivity
+	}
+	return clamp((1 - nullFraction(left)) * (1 - nullFraction(right)) / ndv)
+}
+
+// distinctCount returns the (estimated) number of distinct values of a column
+func distinctCount(info ColumnInfo) float64 {
+	if info.Stats != nil {
+		return float64(info.Stats.DistinctCount)
+	}
+	if info.Unique || info.RowCount < DefaultDistinctCount {
+		return float64(info.RowCount)
+	}
+	return DefaultDistinctCount
+}
+
+func nullFraction(info ColumnInfo) float64 {
+	if info.Stats == nil {
+		return 0
+	}
+	return info.Stats.NullFraction
+}
+
+// fractionBelow estimates the fraction of non-NULL values strictly below v
+// Values are assumed uniformly distributed inside each bucket; numeric
+// buckets are interpolated linearly, other buckets count as half full.
+func fractionBelow(bounds []interface{}, v interface{}) float64 {
+	if !comparable(bounds[0], v) {
+		return 0
+	}
+
+	buckets := len(bounds) - 1
+	if index.Compare(v, bounds[0]) <= 0 {
+		return 0
+	}
+	if index.Compare(v, bounds[buckets]) > 0 {
+		return 1
+	}
+
+	for i := 0; i < buckets; i++ {
+		lo, hi := bounds[i], bounds[i+1]
+		if index.Compare(v, hi) > 0 {
+			continue
+		}
+
+		within := 0.5
+		if l, ok := types.NormalizeToFloat(index.NormalizeKey(lo)); ok {
+			if h, ok := types.NormalizeToFloat(index.NormalizeKey(hi)); ok && h > l {
+				x, _ := types.NormalizeToFloat(index.NormalizeKey(v))
+				within = (x - l) / (h - l)
+			}
+		}
+		return clamp((float64(i) + within) / float64(buckets))
+	}
+	return 1
+}
+
+// comparable reports whether two values are of the same kind (both numbers,
+// both strings, ...) so that ordering them is meaningful
+func comparable(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	_, aNum := types.NormalizeToFloat(index.NormalizeKey(a))
+	_, bNum := types.NormalizeToFloat(index.NormalizeKey(b))
+	if aNum || bNum {
+		return aNum && bNum
+	}
+	_, aStr := a.(string)
+	_, bStr := b.(string)
+	return aStr == bStr
+}
+
+func clamp(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
@@ -0,0 +1,54 @@
+package vector
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+)
+
+// BatchSize is the number of rows processed together
+const BatchSize = 1024
+
+// Batch is a slice of up to BatchSize rows of a table whose columns are
+// read through typed vectors
+// Sel lists the positions of the rows still selected, in ascending order;
+// filters narrow it instead of moving values around. A column's vector is
+// loaded only for the rows read from it, so a filter on one column does not
+// pay for the columns of the rows it drops.
+type Batch struct {
+	Rows    []data.Row
+	Sel     []int
+	kinds   map[string]Kind
+	vectors map[string]*Vector
+}
+
+// NewBatch creates a batch reading columns with the vector kinds of kinds
+// (KindAny for columns not in it)
+func NewBatch(kinds map[string]Kind) *Batch {
+	return &Batch{Sel: make([]int, 0, BatchSize), kinds: kinds, vectors: make(map[string]*Vector)}
+}
+
+// Load makes rows the content of the batch, selecting all of them
+// The batch's vectors and selection are reused, so values read before must
+// have been copied out.
+func (b *Batch) Load(rows []data.Row) {
+	b.Rows = rows
+	for _, v := range b.vectors {
+		v.reset(len(rows))
+	}
+	b.Sel = b.Sel[:0]
+	for i := range rows {
+		b.Sel = append(b.Sel, i)
+	}
+}
+
+// Vector returns the vector of a column with the values at the positions of
+// sel loaded
+func (b *Batch) Vector(column string, sel []int) *Vector {
+	v, ok := b.vectors[column]
+	if !ok {
+		v = New(b.kinds[column])
+		v.reset(len(b.Rows))
+		b.vectors[column] = v
+	}
+	v.loadAt(b.Rows, column, sel)
+	return v
+}
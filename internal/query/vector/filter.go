@@ -0,0 +1,289 @@
+package vector
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/util/types"
+)
+
+// Filter is a WHERE clause compiled to run on batches
+// It selects exactly the rows the row predicate built by the planner from
+// the same expression matches: a missing column never matches, a NULL only
+// where types.CompareValues says so, and numbers compare as float64.
+type Filter struct {
+	eval evalFunc
+}
+
+// evalFunc narrows sel to the positions of the batch that match
+// It may reuse the array of sel for its result.
+type evalFunc func(b *Batch, sel []int) []int
+
+// CompileFilter compiles a WHERE expression of a single table
+// Column qualifiers are ignored, as the rows of one table have unqualified
+// column names. Supports the expressions of predicate.Build: comparisons,
+// AND / OR, IN and BETWEEN.
+func CompileFilter(expr ast.Expression) (*Filter, error) {
+	eval, err := compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{eval: eval}, nil
+}
+
+// Apply narrows the selection of b to the rows that match
+func (f *Filter) Apply(b *Batch) {
+	b.Sel = f.eval(b, b.Sel)
+}
+
+func compile(expr ast.Expression) (evalFunc, error) {
+	switch e := expr.(type) {
+	case *ast.BinaryExpression:
+		return compileComparison(e.Left, e.Operator, e.Right)
+
+	case *ast.LogicalExpression:
+		left, err := compile(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compile(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case "AND":
+			return func(b *Batch, sel []int) []int {
+				return right(b, left(b, sel))
+			}, nil
+		case "OR":
+			return anyOf(left, right), nil
+		default:
+			return nil, fmt.Errorf("unsupported logical operator: %s", e.Operator)
+		}
+
+	case *ast.InExpression:
+		// col IN (a, b) matches exactly when col = a OR col = b
+		if _, ok := e.Left.(*ast.Identifier); !ok {
+			return nil, fmt.Errorf("left side of IN must be an identifier")
+		}
+		evals := make([]evalFunc, len(e.Values))
+		for i, v := range e.Values {
+			eval, err := compileComparison(e.Left, "=", v)
+			if err != nil {
+				return nil, err
+			}
+			evals[i] = eval
+		}
+		return anyOf(evals...), nil
+
+	case *ast.BetweenExpression:
+		if _, ok := e.Left.(*ast.Identifier); !ok {
+			return nil, fmt.Errorf("left side of BETWEEN must be an identifier")
+		}
+		lower, err := compileComparison(e.Left, ">=", e.Lower)
+		if err != nil {
+			return nil, err
+		}
+		upper, err := compileComparison(e.Left, "<=", e.Upper)
+		if err != nil {
+			return nil, err
+		}
+		return func(b *Batch, sel []int) []int {
+			return upper(b, lower(b, sel))
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported expression type in WHERE clause: %T", expr)
+	}
+}
+
+// compileComparison compiles left op right where one side is a column
+func compileComparison(left ast.Expression, op string, right ast.Expression) (evalFunc, error) {
+	if _, ok := left.(*ast.Literal); ok {
+		left, right, op = right, left, flip(op)
+	}
+	ident, ok := left.(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("comparison must reference a column")
+	}
+	switch r := right.(type) {
+	case *ast.Literal:
+		return compareLiteral(ident.Value, op, r.Value), nil
+	case *ast.Identifier:
+		return compareColumns(ident.Value, op, r.Value), nil
+	default:
+		return nil, fmt.Errorf("right side of comparison must be a column or a literal")
+	}
+}
+
+// compareLiteral selects the rows whose column compares true with a literal
+// Typed vectors compare without boxing; a literal of another type compares
+// the same way with every value of the vector, which is decided once.
+func compareLiteral(column, op string, literal interface{}) evalFunc {
+	nullMatch := types.CompareValues(nil, op, literal)
+	number, isNumber := types.NormalizeToFloat(literal)
+	str, isString := literal.(string)
+	boolean, isBool := literal.(bool)
+	o := parseOperator(op)
+
+	return func(b *Batch, sel []int) []int {
+		v := b.Vector(column, sel)
+		out := sel[:0]
+		keep := func(i int, match bool) {
+			if v.Nulls.Has(i) {
+				match = nullMatch && !v.Missing.Has(i)
+			}
+			if match {
+				out = append(out, i)
+			}
+		}
+
+		switch {
+		case v.Kind == KindInt64 && isNumber:
+			for _, i := range sel {
+				keep(i, holds(o, float64(v.Ints[i]), number))
+			}
+		case v.Kind == KindFloat64 && isNumber:
+			for _, i := range sel {
+				keep(i, holds(o, v.Floats[i], number))
+			}
+		case v.Kind == KindString && isString:
+			for _, i := range sel {
+				keep(i, holds(o, v.Strings[i], str))
+			}
+		case v.Kind == KindBool && isBool:
+			for _, i := range sel {
+				keep(i, o == opEqual && v.Bools[i] == boolean || o == opNotEqual && v.Bools[i] != boolean)
+			}
+		case v.Kind == KindAny:
+			for _, i := range sel {
+				keep(i, !v.Nulls.Has(i) && types.CompareValues(v.Values[i], op, literal))
+			}
+		default:
+			// The literal's type differs from every value's
+			match := types.CompareValues(v.Value(firstNonNull(v, sel)), op, literal)
+			for _, i := range sel {
+				keep(i, match)
+			}
+		}
+		return out
+	}
+}
+
+// firstNonNull returns a selected position whose value is not NULL, or 0
+func firstNonNull(v *Vector, sel []int) int {
+	for _, i := range sel {
+		if !v.Nulls.Has(i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// compareColumns selects the rows where two columns compare true; a NULL on
+// either side never matches
+func compareColumns(left, op, right string) evalFunc {
+	return func(b *Batch, sel []int) []int {
+		lv, rv := b.Vector(left, sel), b.Vector(right, sel)
+		out := sel[:0]
+		for _, i := range sel {
+			if lv.Nulls.Has(i) || rv.Nulls.Has(i) {
+				continue
+			}
+			if types.CompareValues(lv.Value(i), op, rv.Value(i)) {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+}
+
+// anyOf selects the rows matched by at least one of evals
+func anyOf(evals ...evalFunc) evalFunc {
+	return func(b *Batch, sel []int) []int {
+		matched := Bitmap(nil).reset(len(b.Rows))
+		scratch := make([]int, 0, len(sel))
+		for _, eval := range evals {
+			for _, i := range eval(b, append(scratch[:0], sel...)) {
+				matched.Set(i)
+			}
+		}
+		out := sel[:0]
+		for _, i := range sel {
+			if matched.Has(i) {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+}
+
+// operator is a comparison operator resolved once per filter
+type operator int
+
+const (
+	opUnknown operator = iota
+	opEqual
+	opNotEqual
+	opLess
+	opGreater
+	opLessEqual
+	opGreaterEqual
+)
+
+func parseOperator(op string) operator {
+	switch op {
+	case "=":
+		return opEqual
+	case "!=", "<>":
+		return opNotEqual
+	case "<":
+		return opLess
+	case ">":
+		return opGreater
+	case "<=":
+		return opLessEqual
+	case ">=":
+		return opGreaterEqual
+	default:
+		return opUnknown
+	}
+}
+
+// holds reports whether a op b
+func holds[T cmp.Ordered](op operator, a, b T) bool {
+	switch op {
+	case opEqual:
+		return a == b
+	case opNotEqual:
+		return a != b
+	case opLess:
+		return a < b
+	case opGreater:
+		return a > b
+	case opLessEqual:
+		return a <= b
+	case opGreaterEqual:
+		return a >= b
+	default:
+		return false
+	}
+}
+
+// flip returns the operator that gives the same result with the operands
+// swapped
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case ">":
+		return "<"
+	case "<=":
+		return ">="
+	case ">=":
+		return "<="
+	default:
+		return op
+	}
+}
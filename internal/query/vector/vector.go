@@ -0,0 +1,220 @@
+package vector
+
+import (
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+)
+
+// Kind is the physical type of the values of a vector
+type Kind int
+
+const (
+	// KindAny holds boxed values of any type, for columns without a typed
+	// representation and for batches whose values do not match their column
+	// type
+	KindAny Kind = iota
+	KindInt64
+	KindFloat64
+	KindString
+	KindBool
+)
+
+// KindOf returns the vector kind of a column type
+// DATE, TIME and EMAIL values are strings.
+func KindOf(t schema.ColumnType) Kind {
+	switch t {
+	case schema.ColumnTypeInt:
+		return KindInt64
+	case schema.ColumnTypeFloat:
+		return KindFloat64
+	case schema.ColumnTypeText, schema.ColumnTypeDate, schema.ColumnTypeTime, schema.ColumnTypeEmail:
+		return KindString
+	case schema.ColumnTypeBool:
+		return KindBool
+	default:
+		return KindAny
+	}
+}
+
+// Bitmap is a set of row positions in a batch
+type Bitmap []uint64
+
+// Set adds position i
+func (b Bitmap) Set(i int) {
+	b[i>>6] |= 1 << (i & 63)
+}
+
+// Has reports whether position i is in the set
+func (b Bitmap) Has(i int) bool {
+	return b[i>>6]&(1<<(i&63)) != 0
+}
+
+// reset empties the bitmap, resizing it to hold n positions
+func (b Bitmap) reset(n int) Bitmap {
+	words := (n + 63) >> 6
+	if cap(b) < words {
+		return make(Bitmap, words)
+	}
+	b = b[:words]
+	clear(b)
+	return b
+}
+
+// Vector holds the values of one column for the rows of a batch
+//
+// Only the slice of the vector's Kind is used. Nulls marks the positions
+// that are NULL, including rows that do not have the column at all, which
+// Missing marks as well; their slot in the typed slice is the zero value.
+// Values are loaded only for the positions a query reads, recorded in
+// loaded.
+type Vector struct {
+	Kind    Kind
+	Len     int
+	Ints    []int64
+	Floats  []float64
+	Strings []string
+	Bools   []bool
+	Values  []interface{} // KindAny
+	Nulls   Bitmap
+	Missing Bitmap
+
+	column Kind // kind of the column, restored by every reset
+	loaded Bitmap
+}
+
+// New creates an empty vector for a column of the given kind
+func New(kind Kind) *Vector {
+	return &Vector{Kind: kind, column: kind}
+}
+
+// IsNull reports whether the value at i is NULL or missing
+func (v *Vector) IsNull(i int) bool {
+	return v.Nulls.Has(i)
+}
+
+// IsMissing reports whether the row at i does not have the column
+func (v *Vector) IsMissing(i int) bool {
+	return v.Missing.Has(i)
+}
+
+// Value returns the value at i with its original Go type, nil if NULL or
+// missing
+func (v *Vector) Value(i int) interface{} {
+	if v.Nulls.Has(i) {
+		return nil
+	}
+	switch v.Kind {
+	case KindInt64:
+		return v.Ints[i]
+	case KindFloat64:
+		return v.Floats[i]
+	case KindString:
+		return v.Strings[i]
+	case KindBool:
+		return v.Bools[i]
+	default:
+		return v.Values[i]
+	}
+}
+
+// Load fills the vector with a column of rows
+func (v *Vector) Load(rows []data.Row, column string) {
+	v.reset(len(rows))
+	for i := range rows {
+		v.load(rows, column, i)
+	}
+}
+
+// loadAt loads the values at the positions of sel not loaded yet
+func (v *Vector) loadAt(rows []data.Row, column string, sel []int) {
+	for _, i := range sel {
+		if !v.loaded.Has(i) {
+			v.load(rows, column, i)
+		}
+	}
+}
+
+// load reads the value of row i
+// A value whose Go type does not match the column kind - such as an int
+// inserted into an INT column, which is stored as int64 when loaded from disk
+// - turns the vector into KindAny for this batch, so values always come back
+// exactly as stored.
+func (v *Vector) load(rows []data.Row, column string, i int) {
+	v.loaded.Set(i)
+	value, ok := rows[i].Data[column]
+	if !ok {
+		v.Missing.Set(i)
+		v.Nulls.Set(i)
+		return
+	}
+	if value == nil {
+		v.Nulls.Set(i)
+		return
+	}
+	if !v.set(i, value) {
+		v.box()
+		v.Values[i] = value
+	}
+}
+
+// reset empties the vector and sizes it for n values of the column kind
+func (v *Vector) reset(n int) {
+	v.Kind, v.Len = v.column, n
+	v.Nulls = v.Nulls.reset(n)
+	v.Missing = v.Missing.reset(n)
+	v.loaded = v.loaded.reset(n)
+	switch v.Kind {
+	case KindInt64:
+		v.Ints = resize(v.Ints, n)
+	case KindFloat64:
+		v.Floats = resize(v.Floats, n)
+	case KindString:
+		v.Strings = resize(v.Strings, n)
+	case KindBool:
+		v.Bools = resize(v.Bools, n)
+	default:
+		v.Values = resize(v.Values, n)
+	}
+}
+
+// set stores value at i, returning false if it does not have the Go type of
+// the vector's kind
+func (v *Vector) set(i int, value interface{}) bool {
+	var ok bool
+	switch v.Kind {
+	case KindInt64:
+		v.Ints[i], ok = value.(int64)
+	case KindFloat64:
+		v.Floats[i], ok = value.(float64)
+	case KindString:
+		v.Strings[i], ok = value.(string)
+	case KindBool:
+		v.Bools[i], ok = value.(bool)
+	default:
+		v.Values[i], ok = value, true
+	}
+	return ok
+}
+
+// box converts the vector to KindAny, keeping the values stored so far
+func (v *Vector) box() {
+	if v.Kind == KindAny {
+		return
+	}
+	values := resize(v.Values, v.Len)
+	for i := range values {
+		values[i] = v.Value(i)
+	}
+	v.Kind, v.Values = KindAny, values
+}
+
+// resize returns s with length n and zero values, reusing its array when it
+// is large enough
+func resize[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	s = s[:n]
+	clear(s)
+	return s
+}
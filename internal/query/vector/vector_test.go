@@ -0,0 +1,150 @@
+package vector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/parser"
+	"github.com/leengari/mini-rdbms/internal/parser/ast"
+	"github.com/leengari/mini-rdbms/internal/parser/lexer"
+	"github.com/leengari/mini-rdbms/internal/planner/predicate"
+)
+
+// testRows returns rows with NULLs, missing columns and values whose Go type
+// differs from their column's
+func testRows() []data.Row {
+	var rows []data.Row
+	for i := 0; i < 3000; i++ {
+		values := map[string]interface{}{
+			"id":     int64(i),
+			"score":  float64(i%13) / 4,
+			"name":   string(rune('a' + i%7)),
+			"active": i%3 == 0,
+			"other":  int64(i % 11),
+		}
+		switch {
+		case i%17 == 0:
+			values["score"] = nil
+			values["name"] = nil
+		case i%19 == 0:
+			delete(values, "active")
+			delete(values, "other")
+		case i%1500 == 7:
+			values["id"] = i // int instead of int64
+		}
+		rows = append(rows, data.NewRow(values))
+	}
+	return rows
+}
+
+// whereOf parses the WHERE clause of a query
+func whereOf(t *testing.T, where string) ast.Expression {
+	t.Helper()
+	tokens, err := lexer.Tokenize("SELECT * FROM t WHERE " + where)
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+	stmt, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	return stmt.(*ast.SelectStatement).Where
+}
+
+func TestFilterMatchesPredicate(t *testing.T) {
+	rows := testRows()
+	kinds := map[string]Kind{"id": KindInt64, "score": KindFloat64, "name": KindString, "active": KindBool, "other": KindInt64}
+
+	wheres := []string{
+		"id > 1000",
+		"id = 1507",
+		"1000 >= id",
+		"score <= 1.5",
+		"score <> 2",
+		"score != 'x'",
+		"name = 'c'",
+		"name < 'd' AND id < 2000",
+		"name <> 'a'",
+		"active = true",
+		"active != false",
+		"active < true",
+		"other = 3 OR name = 'b'",
+		"other <> 4",
+		"id IN (3, 34, 1507, 2999) OR score IN (0.5, 1)",
+		"id BETWEEN 100 AND 200 AND score BETWEEN 1 AND 2",
+		"other BETWEEN id AND 10",
+		"other = id",
+		"score < other OR other > id",
+		"id = 'abc'",
+		"name != 5",
+	}
+
+	for _, where := range wheres {
+		t.Run(where, func(t *testing.T) {
+			expr := whereOf(t, where)
+			pred, err := predicate.Build(expr)
+			if err != nil {
+				t.Fatalf("predicate.Build failed: %v", err)
+			}
+			filter, err := CompileFilter(expr)
+			if err != nil {
+				t.Fatalf("CompileFilter failed: %v", err)
+			}
+
+			var expected, got []int
+			for i, row := range rows {
+				if pred(row) {
+					expected = append(expected, i)
+				}
+			}
+			batch := NewBatch(kinds)
+			for start := 0; start < len(rows); start += BatchSize {
+				batch.Load(rows[start:min(start+BatchSize, len(rows))])
+				filter.Apply(batch)
+				for _, i := range batch.Sel {
+					got = append(got, start+i)
+				}
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Filter selected %d rows, the predicate %d", len(got), len(expected))
+			}
+		})
+	}
+}
+
+func TestVectorLoad(t *testing.T) {
+	rows := []data.Row{
+		data.NewRow(map[string]interface{}{"n": int64(1)}),
+		data.NewRow(map[string]interface{}{"n": nil}),
+		data.NewRow(map[string]interface{}{}),
+		data.NewRow(map[string]interface{}{"n": int64(4)}),
+	}
+
+	v := New(KindInt64)
+	v.Load(rows, "n")
+	if v.Kind != KindInt64 || v.Ints[3] != 4 {
+		t.Fatalf("Expected an int64 vector, got kind %d: %v", v.Kind, v.Ints)
+	}
+	if v.IsNull(0) || !v.IsNull(1) || v.IsMissing(1) || !v.IsNull(2) || !v.IsMissing(2) {
+		t.Error("Expected NULL at 1 and a missing NULL at 2")
+	}
+
+	// A value of another Go type boxes the batch, keeping every value as is
+	rows = append(rows, data.NewRow(map[string]interface{}{"n": 5}))
+	v.Load(rows, "n")
+	if v.Kind != KindAny {
+		t.Fatalf("Expected the vector to be boxed, got kind %d", v.Kind)
+	}
+	for i, want := range []interface{}{int64(1), nil, nil, int64(4), 5} {
+		if got := v.Value(i); got != want {
+			t.Errorf("Value(%d) = %#v, expected %#v", i, got, want)
+		}
+	}
+
+	// The next batch is typed again
+	v.Load(rows[:1], "n")
+	if v.Kind != KindInt64 || v.Len != 1 || v.Value(0) != int64(1) {
+		t.Errorf("Expected a one-value int64 vector, got kind %d with %d values", v.Kind, v.Len)
+	}
+}
@@ -2,6 +2,7 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,8 +14,30 @@ import (
 	"github.com/leengari/mini-rdbms/internal/storage/manager"
 )
 
+// Start runs the REPL on standard input until exit or the end of input
 func Start(registry *manager.Registry) {
-	scanner := bufio.NewScanner(os.Stdin)
+	StartContext(context.Background(), registry)
+}
+
+// StartContext runs the REPL until exit, the end of input or ctx is done
+// A statement running when ctx ends is finished first, so its result is
+// printed and its changes are there to be saved.
+func StartContext(ctx context.Context, registry *manager.Registry) {
+	// Lines are read on their own goroutine so ctx is seen while waiting
+	// for input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	fmt.Println("Welcome to JoyDB")
 	fmt.Println("Type 'exit' or '\\q' to quit.")
 
@@ -27,10 +50,17 @@ func Start(registry *manager.Registry) {
 
 	for {
 		fmt.Print("> ")
-		if !scanner.Scan() {
+		var line string
+		select {
+		case <-ctx.Done():
+			fmt.Println()
 			return
+		case next, ok := <-lines:
+			if !ok {
+				return
+			}
+			line = next
 		}
-		line := scanner.Text()
 
 		if strings.TrimSpace(line) == "" {
 			continue
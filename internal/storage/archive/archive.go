@@ -0,0 +1,397 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/query/validation"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// WALDir is the directory of a database holding its active WAL segment,
+// hidden so it is not loaded as a table
+const WALDir = ".wal"
+
+// Log writes the changes of one statement to the WAL as a transaction
+// The caller holds the WAL's writer lock from making the changes until Log
+// returns, which makes the next LSN free to serve as transaction ID.
+func Log(w *wal.WAL, db *schema.Database, changes []transaction.Change) error {
+	txID := w.NextLSN()
+	if _, err := w.BeginTransaction(txID); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if err := logChange(w, txID, db, change); err != nil {
+			w.Abort(txID)
+			return err
+		}
+	}
+	_, err := w.Commit(txID)
+	return err
+}
+
+// LogCreateTable writes the creation of a table with the given schema to
+// the WAL as a transaction
+// Like Log, it must be called with the WAL's writer lock held since before
+// the table was added, so no change to the table is logged ahead of it.
+func LogCreateTable(w *wal.WAL, tableSchema *schema.TableSchema) error {
+	definition, err := json.Marshal(tableSchema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema of %s: %w", tableSchema.TableName, err)
+	}
+	return logSchema(w, func(txID uint64) error {
+		_, err := w.LogCreateTable(txID, tableSchema.TableName, definition)
+		return err
+	})
+}
+
+// LogDropTable writes the removal of a table to the WAL as a transaction
+func LogDropTable(w *wal.WAL, tableName string) error {
+	return logSchema(w, func(txID uint64) error {
+		_, err := w.LogDropTable(txID, tableName)
+		return err
+	})
+}
+
+// LogCreateIndex writes the creation of an index on a table to the WAL as
+// a transaction
+func LogCreateIndex(w *wal.WAL, tableName string, def schema.IndexDefinition) error {
+	definition, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("failed to encode index %s: %w", def.Name, err)
+	}
+	return logSchema(w, func(txID uint64) error {
+		_, err := w.LogCreateIndex(txID, tableName, def.Name, definition)
+		return err
+	})
+}
+
+// LogDropIndex writes the removal of an index from a table to the WAL as a
+// transaction
+func LogDropIndex(w *wal.WAL, tableName, indexName string) error {
+	return logSchema(w, func(txID uint64) error {
+		_, err := w.LogDropIndex(txID, tableName, indexName)
+		return err
+	})
+}
+
+// LogSetSequence writes the auto-increment sequence set for a table to the
+// WAL as a transaction
+func LogSetSequence(w *wal.WAL, tableName string, sequence int64) error {
+	value, err := json.Marshal(sequence)
+	if err != nil {
+		return err
+	}
+	return logSchema(w, func(txID uint64) error {
+		_, err := w.LogSetSequence(txID, tableName, value)
+		return err
+	})
+}
+
+// logSchema writes one schema change, written by logRecord, as a
+// transaction
+func logSchema(w *wal.WAL, logRecord func(txID uint64) error) error {
+	txID := w.NextLSN()
+	if _, err := w.BeginTransaction(txID); err != nil {
+		return err
+	}
+	if err := logRecord(txID); err != nil {
+		w.Abort(txID)
+		return err
+	}
+	_, err := w.Commit(txID)
+	return err
+}
+
+// logChange writes one row change of transaction txID
+func logChange(w *wal.WAL, txID uint64, db *schema.Database, change transaction.Change) error {
+	table, ok := db.Tables[change.Table]
+	if !ok {
+		return fmt.Errorf("table %s not found", change.Table)
+	}
+
+	switch change.Type {
+	case transaction.ChangeTypeInsert:
+		key, value, err := encode(table, change.Data)
+		if err != nil {
+			return err
+		}
+		_, err = w.LogInsert(txID, table.Name, key, value)
+		return err
+
+	case transaction.ChangeTypeUpdate:
+		key, oldValue, err := encode(table, change.OldData)
+		if err != nil {
+			return err
+		}
+		newValue, err := json.Marshal(change.Data)
+		if err != nil {
+			return err
+		}
+		_, err = w.LogUpdate(txID, table.Name, key, oldValue, newValue)
+		return err
+
+	case transaction.ChangeTypeDelete:
+		key, oldValue, err := encode(table, change.OldData)
+		if err != nil {
+			return err
+		}
+		_, err = w.LogDelete(txID, table.Name, key, oldValue)
+		return err
+	}
+	return fmt.Errorf("unknown change type %q", change.Type)
+}
+
+// encode returns the key of a row and the row as JSON
+func encode(table *schema.Table, row map[string]interface{}) (string, json.RawMessage, error) {
+	value, err := json.Marshal(row)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode row of %s: %w", table.Name, err)
+	}
+	key, err := Key(table, row)
+	return key, value, err
+}
+
+// Key returns the key a row is logged under: its primary key value, or the
+// whole row as JSON in a table without a primary key
+// JSON objects are written with sorted keys and numbers the same whether
+// they were decoded as float64 or not, so equal rows have equal keys.
+func Key(table *schema.Table, row map[string]interface{}) (string, error) {
+	if pk := table.Schema.GetPrimaryKeyColumn(); pk != nil {
+		return fmt.Sprint(row[pk.Name]), nil
+	}
+	value, err := json.Marshal(row)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode row of %s: %w", table.Name, err)
+	}
+	return string(value), nil
+}
+
+// Target replays WAL records onto a loaded database through its tables'
+// Insert, Update and Delete, like the statements that made them
+type Target struct {
+	db *schema.Database
+}
+
+// NewTarget returns a replay target for db
+func NewTarget(db *schema.Database) *Target {
+	return &Target{db: db}
+}
+
+// ReplayInsert inserts a logged row
+// The row keeps its logged auto-increment value, which may be one the
+// sequence had passed when a deleted row was put back (see Undo).
+func (t *Target) ReplayInsert(tableName string, key string, value json.RawMessage) error {
+	table, row, err := t.row(tableName, value)
+	if err != nil {
+		return err
+	}
+	return table.Reinsert(row, nil)
+}
+
+// ReplayUpdate replaces the row with the given key by the logged one
+func (t *Target) ReplayUpdate(tableName string, key string, newValue json.RawMessage) error {
+	table, row, err := t.row(tableName, newValue)
+	if err != nil {
+		return err
+	}
+	n, err := table.Update(matchKey(table, key), row, nil)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("table %s has no row with key %s to update", tableName, key)
+	}
+	return nil
+}
+
+// ReplayDelete deletes the row with the given key
+func (t *Target) ReplayDelete(tableName string, key string) error {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	n, err := table.Delete(matchKey(table, key), nil)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("table %s has no row with key %s to delete", tableName, key)
+	}
+	return nil
+}
+
+// ReplayCreateTable adds a new, empty table with the logged schema, as
+// Registry.CreateTable does
+// The table is saved along with the rest of the database.
+func (t *Target) ReplayCreateTable(tableName string, tableSchema json.RawMessage) error {
+	if _, ok := t.db.Tables[tableName]; ok {
+		return fmt.Errorf("table %s already exists", tableName)
+	}
+	var s schema.TableSchema
+	if err := json.Unmarshal(tableSchema, &s); err != nil {
+		return fmt.Errorf("unreadable schema for table %s: %w", tableName, err)
+	}
+
+	tablePath := filepath.Join(t.db.Path, tableName)
+	if err := os.Mkdir(tablePath, 0755); err != nil {
+		return fmt.Errorf("failed to create table directory: %w", err)
+	}
+	table := &schema.Table{
+		Name:    tableName,
+		Path:    tablePath,
+		Schema:  &s,
+		Rows:    []data.Row{},
+		Indexes: make(map[string]*data.Index),
+	}
+	if err := indexing.BuildIndexes(table); err != nil {
+		return err
+	}
+	table.MarkDirty()
+	t.db.Tables[tableName] = table
+	return nil
+}
+
+// ReplayDropTable removes a table and deletes its files
+func (t *Target) ReplayDropTable(tableName string) error {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	delete(t.db.Tables, tableName)
+	if err := os.RemoveAll(table.Path); err != nil {
+		return fmt.Errorf("failed to remove table directory: %w", err)
+	}
+	return nil
+}
+
+// ReplayCreateIndex creates an index from its logged definition
+func (t *Target) ReplayCreateIndex(tableName string, definition json.RawMessage) error {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	var def schema.IndexDefinition
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return fmt.Errorf("unreadable index definition for table %s: %w", tableName, err)
+	}
+	return indexing.CreateIndex(table, def)
+}
+
+// ReplayDropIndex removes an index
+func (t *Target) ReplayDropIndex(tableName string, indexName string) error {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	return indexing.DropIndex(table, indexName)
+}
+
+// ReplaySetSequence sets the auto-increment sequence of a table
+func (t *Target) ReplaySetSequence(tableName string, sequence int64) error {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return fmt.Errorf("table %s not found", tableName)
+	}
+	table.SetSequence(sequence)
+	return nil
+}
+
+// row decodes a logged row of a table, converting its values to the
+// column types as loading a table does
+func (t *Target) row(tableName string, value json.RawMessage) (*schema.Table, data.Row, error) {
+	table, ok := t.db.Tables[tableName]
+	if !ok {
+		return nil, data.Row{}, fmt.Errorf("table %s not found", tableName)
+	}
+	var row data.Row
+	if err := json.Unmarshal(value, &row); err != nil {
+		return nil, data.Row{}, fmt.Errorf("unreadable row for table %s: %w", tableName, err)
+	}
+	if err := validation.ValidateRow(table, row, -1); err != nil {
+		return nil, data.Row{}, err
+	}
+	return table, row, nil
+}
+
+// Undo reverts one recorded change of db through its table's Insert, Update
+// and Delete, recording the reverting change in tx
+// Changes must be undone in the reverse of the order they were made in. A
+// deleted row is put back with its auto-increment value; the sequence is
+// not moved back.
+func Undo(db *schema.Database, change transaction.Change, tx *transaction.Transaction) error {
+	table, ok := db.Tables[change.Table]
+	if !ok {
+		return fmt.Errorf("table %s not found", change.Table)
+	}
+
+	switch change.Type {
+	case transaction.ChangeTypeInsert:
+		return undoInsert(table, change.Data, tx)
+
+	case transaction.ChangeTypeUpdate:
+		// An update that set a column the row did not have cannot be undone
+		// by another update, as there is no NULL to set it back to
+		for col := range change.Data {
+			if _, ok := change.OldData[col]; !ok {
+				if err := undoInsert(table, change.Data, tx); err != nil {
+					return err
+				}
+				return table.Reinsert(data.NewRow(change.OldData), tx)
+			}
+		}
+		key, err := Key(table, change.Data)
+		if err != nil {
+			return err
+		}
+		n, err := table.Update(matchKey(table, key), data.NewRow(change.OldData), tx)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("table %s has no row with key %s to restore", table.Name, key)
+		}
+		return nil
+
+	case transaction.ChangeTypeDelete:
+		return table.Reinsert(data.NewRow(change.OldData), tx)
+	}
+	return fmt.Errorf("unknown change type %q", change.Type)
+}
+
+// undoInsert deletes the row an insert or update left
+func undoInsert(table *schema.Table, row map[string]interface{}, tx *transaction.Transaction) error {
+	key, err := Key(table, row)
+	if err != nil {
+		return err
+	}
+	n, err := table.Delete(matchKey(table, key), tx)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("table %s has no row with key %s to remove", table.Name, key)
+	}
+	return nil
+}
+
+// matchKey returns a predicate matching the first row with the given key
+// Each logged change is for one row, even among identical rows of a table
+// without a primary key.
+func matchKey(table *schema.Table, key string) func(data.Row) bool {
+	matched := false
+	return func(row data.Row) bool {
+		if matched {
+			return false
+		}
+		k, err := Key(table, row.Data)
+		matched = err == nil && k == key
+		return matched
+	}
+}
@@ -0,0 +1,280 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// ManifestName is the manifest of a backup directory, written last so a
+// backup without one is incomplete
+const ManifestName = "manifest.json"
+
+// Manifest describes a backup and the checksum of every file in it
+type Manifest struct {
+	Version   int       `json:"version"`
+	Database  string    `json:"database"`
+	Storage   string    `json:"storage,omitempty"` // format of the database backed up
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+	Files     []File    `json:"files"`
+
+	// WALLSN is the last WAL LSN whose changes the backup holds, present when
+	// the database was logged; point-in-time recovery replays from the next
+	WALLSN *uint64 `json:"wal_lsn,omitempty"`
+}
+
+// File is one file of a backup, relative to its directory
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Create writes a consistent snapshot of db to the directory dir, which must
+// not exist or be empty
+//
+// Every table is read-locked at once while the snapshot is taken, so it
+// holds the database as it was between two statements; the files are written
+// once the locks are released. Tables are stored as JSON storage keeps them,
+// whatever the database's format, with the format recorded in the manifest.
+// walLSN, if not nil, returns the WAL position the snapshot corresponds to;
+// the caller keeps it from moving until Create returns.
+func Create(db *schema.Database, dir string, walLSN func() uint64) (*Manifest, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("backup directory %s is not empty", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	manifest, files, err := snapshot(db)
+	if err != nil {
+		return nil, err
+	}
+	if walLSN != nil {
+		lsn := walLSN()
+		manifest.WALLSN = &lsn
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		if err := writeSynced(path, f.Data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+		sum := sha256.Sum256(f.Data)
+		manifest.Files = append(manifest.Files, File{
+			Path:   filepath.ToSlash(f.Name),
+			Size:   int64(len(f.Data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeSynced(filepath.Join(dir, ManifestName), manifestBytes); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	slog.Info("Database backed up",
+		slog.String("database", db.Name),
+		slog.String("path", dir),
+		slog.Int("tables", len(manifest.Tables)),
+		slog.Int("files", len(manifest.Files)))
+	return manifest, nil
+}
+
+// snapshot returns the files of every table of db and the database meta,
+// read with all tables locked
+func snapshot(db *schema.Database) (*Manifest, []writer.File, error) {
+	names := make([]string, 0, len(db.Tables))
+	for name := range db.Tables {
+		names = append(names, name)
+	}
+	// Locks are always taken in name order
+	sort.Strings(names)
+
+	for _, name := range names {
+		db.Tables[name].RLock()
+	}
+	defer func() {
+		for _, name := range names {
+			db.Tables[name].RUnlock()
+		}
+	}()
+
+	var files []writer.File
+	for _, name := range names {
+		tableFiles, err := writer.SnapshotTable(db.Tables[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range tableFiles {
+			files = append(files, writer.File{Name: filepath.Join(name, f.Name), Data: f.Data})
+		}
+	}
+
+	metaBytes, err := json.MarshalIndent(metadata.DatabaseMeta{
+		Name:    db.Name,
+		Version: 1,
+		Tables:  names,
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal database meta: %w", err)
+	}
+	files = append(files, writer.File{Name: "meta.json", Data: metaBytes})
+
+	return &Manifest{
+		Version:   1,
+		Database:  db.Name,
+		Storage:   db.Storage,
+		CreatedAt: time.Now().UTC(),
+		Tables:    names,
+	}, files, nil
+}
+
+// Verify reads the manifest of a backup and checks every file it lists
+// against its size and checksum
+func Verify(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s has no %s: not a backup, or an incomplete one", dir, ManifestName)
+		}
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("unreadable backup manifest: %w", err)
+	}
+	if manifest.Version != 1 {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	listed := make(map[string]bool, len(manifest.Files))
+	for _, f := range manifest.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return nil, fmt.Errorf("backup file %q is outside the backup", f.Path)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("backup file %s: %w", f.Path, err)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("backup file %s does not match its checksum", f.Path)
+		}
+		listed[f.Path] = true
+	}
+
+	required := []string{"meta.json"}
+	for _, table := range manifest.Tables {
+		required = append(required, table+"/data.json", table+"/meta.json")
+	}
+	for _, path := range required {
+		if !listed[path] {
+			return nil, fmt.Errorf("backup is missing %s", path)
+		}
+	}
+	return &manifest, nil
+}
+
+// Restore verifies the backup in dir and copies it to a new database
+// directory dbPath, recording the database's new name
+// The files are staged next to dbPath and renamed into place at the end, so
+// a failed restore leaves no database behind.
+func Restore(dir, dbPath string) (*Manifest, error) {
+	manifest, err := Verify(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		return nil, fmt.Errorf("database '%s' already exists", filepath.Base(dbPath))
+	}
+
+	staging := filepath.Join(filepath.Dir(dbPath), "."+filepath.Base(dbPath)+".restore")
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	if err := restoreTo(manifest, dir, staging, filepath.Base(dbPath)); err != nil {
+		os.RemoveAll(staging)
+		return nil, err
+	}
+	if err := os.Rename(staging, dbPath); err != nil {
+		os.RemoveAll(staging)
+		return nil, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	slog.Info("Database restored",
+		slog.String("database", filepath.Base(dbPath)),
+		slog.String("from", dir),
+		slog.Time("backup_created_at", manifest.CreatedAt))
+	return manifest, nil
+}
+
+// restoreTo copies the files of a verified backup to staging, renaming the
+// database to name; the database meta is written last
+func restoreTo(manifest *Manifest, dir, staging, name string) error {
+	for _, f := range manifest.Files {
+		if f.Path == "meta.json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(staging, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := writeSynced(path, data); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+
+	var meta metadata.DatabaseMeta
+	content, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return fmt.Errorf("unreadable database meta in backup: %w", err)
+	}
+	meta.Name = name
+	metaBytes, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSynced(filepath.Join(staging, "meta.json"), metaBytes)
+}
+
+// writeSynced writes a file and syncs it to disk
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
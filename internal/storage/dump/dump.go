@@ -0,0 +1,286 @@
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+)
+
+// BatchSize is the number of rows written per INSERT statement
+const BatchSize = 100
+
+// Write writes db as a SQL script that recreates it: CREATE DATABASE and
+// USE, then for every table its CREATE TABLE, INSERT statements holding its
+// rows, ALTER TABLE setting its auto-increment sequence and CREATE INDEX for
+// its user-defined indexes
+// Each table is read under its read lock, so its rows are consistent, but
+// tables are read one after the other. Rows keep their auto-increment
+// values and are written in key order, and the sequence continues where it
+// was rather than after the largest one, so the keys of deleted rows are
+// not used again.
+func Write(w io.Writer, db *schema.Database) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "-- JoyDB dump of database '%s'\n\n", db.Name)
+	if db.Storage != "" && db.Storage != engine.StorageJSON {
+		fmt.Fprintf(out, "CREATE DATABASE %s STORAGE = %s;\n", db.Name, db.Storage)
+	} else {
+		fmt.Fprintf(out, "CREATE DATABASE %s;\n", db.Name)
+	}
+	fmt.Fprintf(out, "USE %s;\n", db.Name)
+
+	names := make([]string, 0, len(db.Tables))
+	for name := range db.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		out.WriteString("\n")
+		if err := writeTable(out, db.Tables[name]); err != nil {
+			return fmt.Errorf("failed to dump table %s: %w", name, err)
+		}
+	}
+	return out.Flush()
+}
+
+// writeTable writes the statements recreating one table
+func writeTable(out *bufio.Writer, table *schema.Table) error {
+	table.RLock()
+	defer table.RUnlock()
+
+	defs := make([]string, len(table.Schema.Columns))
+	for i, col := range table.Schema.Columns {
+		defs[i] = columnDefinition(col)
+	}
+	fmt.Fprintf(out, "CREATE TABLE %s (\n  %s\n);\n", table.Name, strings.Join(defs, ",\n  "))
+
+	// A batch holds consecutive rows with values for the same columns, as
+	// there is no NULL to write for a missing value
+	var batch []data.Row
+	var batchColumns []string
+	add := func(_ int, row data.Row) error {
+		columns := rowColumns(table, row)
+		if len(batch) == BatchSize || (len(batch) > 0 && !sameColumns(columns, batchColumns)) {
+			if err := writeInsert(out, table, batchColumns, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		if len(batch) == 0 {
+			batchColumns = columns
+		}
+		batch = append(batch, row)
+		return nil
+	}
+
+	// An explicit auto-increment key below one already inserted is refused,
+	// so those rows are written in key order
+	key := autoIncrementColumn(table)
+	ordered := true
+	if key != "" {
+		var err error
+		if ordered, err = inKeyOrder(table, key); err != nil {
+			return err
+		}
+	}
+	if ordered {
+		if err := table.ForEachRowUnsafe(add); err != nil {
+			return err
+		}
+	} else {
+		rows := make([]data.Row, 0, table.RowCountUnsafe())
+		if err := table.ForEachRowUnsafe(func(_ int, row data.Row) error {
+			rows = append(rows, row)
+			return nil
+		}); err != nil {
+			return err
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return intKey(rows[i].Data[key]) < intKey(rows[j].Data[key])
+		})
+		for i, row := range rows {
+			if err := add(i, row); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := writeInsert(out, table, batchColumns, batch); err != nil {
+			return err
+		}
+	}
+
+	// The sequence is set after the rows, whose keys may not be below it
+	if key != "" && table.LastInsertID > 0 {
+		fmt.Fprintf(out, "ALTER TABLE %s AUTO_INCREMENT = %d;\n", table.Name, table.LastInsertID+1)
+	}
+
+	for _, def := range table.Schema.Indexes {
+		unique := ""
+		if def.Unique {
+			unique = "UNIQUE "
+		}
+		fmt.Fprintf(out, "CREATE %sINDEX %s ON %s (%s) USING %s;\n",
+			unique, def.Name, table.Name, def.Column, strings.ToUpper(string(def.Kind)))
+	}
+	return nil
+}
+
+// autoIncrementColumn returns the name of the auto-increment key of a
+// table, or "" if it has none
+func autoIncrementColumn(table *schema.Table) string {
+	for _, col := range table.Schema.Columns {
+		if col.AutoIncrement && col.PrimaryKey {
+			return col.Name
+		}
+	}
+	return ""
+}
+
+// inKeyOrder reports whether the rows of a table are stored in ascending
+// order of column key, reading only the keys
+// Rows put back by undoing a delete are stored after the others, so a table
+// that had some can only be written in key order by sorting it in memory.
+func inKeyOrder(table *schema.Table, key string) (bool, error) {
+	ordered := true
+	var last int64
+	err := table.ForEachRowUnsafe(func(pos int, row data.Row) error {
+		k := intKey(row.Data[key])
+		if pos > 0 && k < last {
+			ordered = false
+			return errUnordered
+		}
+		last = k
+		return nil
+	})
+	if err == errUnordered {
+		err = nil
+	}
+	return ordered, err
+}
+
+// errUnordered stops inKeyOrder at the first key out of order
+var errUnordered = errors.New("rows out of key order")
+
+// intKey returns an auto-increment key, which is read back from JSON as a
+// float64
+func intKey(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// columnDefinition returns a column as written in CREATE TABLE
+func columnDefinition(col schema.Column) string {
+	def := col.Name + " " + string(col.Type)
+	if col.PrimaryKey {
+		def += " PRIMARY KEY"
+	}
+	if col.AutoIncrement {
+		def += " AUTO_INCREMENT"
+	}
+	if col.Unique {
+		def += " UNIQUE"
+	}
+	if col.NotNull {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// rowColumns returns the columns a row has a value for, in schema order
+func rowColumns(table *schema.Table, row data.Row) []string {
+	var columns []string
+	for _, col := range table.Schema.Columns {
+		if v, ok := row.Data[col.Name]; ok && v != nil {
+			columns = append(columns, col.Name)
+		}
+	}
+	return columns
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeInsert writes one INSERT statement for a batch of rows
+func writeInsert(out *bufio.Writer, table *schema.Table, columns []string, rows []data.Row) error {
+	fmt.Fprintf(out, "INSERT INTO %s (%s) VALUES\n", table.Name, strings.Join(columns, ", "))
+	for i, row := range rows {
+		values := make([]string, len(columns))
+		for j, name := range columns {
+			value, err := Literal(table.Schema.GetColumn(name).Type, row.Data[name])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", name, err)
+			}
+			values[j] = value
+		}
+		end := ","
+		if i == len(rows)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(out, "  (%s)%s\n", strings.Join(values, ", "), end)
+	}
+	return nil
+}
+
+// Literal returns a value of a column of the given type as a SQL literal
+// Strings are quoted with embedded quotes doubled; DATE, TIME and EMAIL
+// values are written as strings, which INSERT converts to the column type.
+func Literal(colType schema.ColumnType, value interface{}) (string, error) {
+	switch colType {
+	case schema.ColumnTypeInt:
+		switch v := value.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			if v == float64(int64(v)) {
+				return strconv.FormatInt(int64(v), 10), nil
+			}
+		}
+	case schema.ColumnTypeFloat:
+		switch v := value.(type) {
+		case float64:
+			// A whole number keeps its decimal point, so it is read back as a FLOAT
+			lit := strconv.FormatFloat(v, 'f', -1, 64)
+			if !strings.Contains(lit, ".") {
+				lit += ".0"
+			}
+			return lit, nil
+		}
+	case schema.ColumnTypeBool:
+		if v, ok := value.(bool); ok {
+			return strings.ToUpper(strconv.FormatBool(v)), nil
+		}
+	case schema.ColumnTypeText, schema.ColumnTypeDate, schema.ColumnTypeTime, schema.ColumnTypeEmail:
+		if v, ok := value.(string); ok {
+			return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+		}
+	}
+	return "", fmt.Errorf("cannot write %T value %v as %s", value, value, colType)
+}
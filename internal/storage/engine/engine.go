@@ -36,3 +36,19 @@ type StorageEngine interface {
 	// SaveTable persists a single table to disk
 	SaveTable(table *schema.Table, tx *transaction.Transaction) error
 }
+
+// Storage formats a database can be recorded with in its meta.json
+const (
+	StorageJSON = "json"
+	StoragePage = "page"
+)
+
+// StorageSelector is implemented by engines that store each database in the
+// format recorded in its meta.json
+type StorageSelector interface {
+	// CreateDatabaseAs creates a new database stored in the given format
+	CreateDatabaseAs(name, basePath, storage string) error
+
+	// ConvertDatabase rewrites a loaded database in the given format
+	ConvertDatabase(db *schema.Database, storage string, tx *transaction.Transaction) error
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+)
+
+// FormatEngine implements StorageEngine by delegating every database to the
+// engine of the storage format recorded in its meta.json
+// Databases without a format are JSON; new ones use the default format.
+type FormatEngine struct {
+	engines        map[string]StorageEngine
+	defaultStorage string
+}
+
+// dataFiles are the files holding the rows of a table in each format
+var dataFiles = map[string]string{
+	StorageJSON: "data.json",
+	StoragePage: page.FileName,
+}
+
+// NewFormatEngine creates an engine creating databases in defaultStorage
+// Page storage reads through a buffer pool of bufferPages pages.
+func NewFormatEngine(defaultStorage string, bufferPages int) (*FormatEngine, error) {
+	e := &FormatEngine{
+		engines: map[string]StorageEngine{
+			StorageJSON: NewJSONEngine(),
+			StoragePage: NewPageEngine(page.NewBufferPool(bufferPages)),
+		},
+		defaultStorage: defaultStorage,
+	}
+	if _, err := e.engine(defaultStorage); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// engine returns the engine of a storage format
+func (e *FormatEngine) engine(storage string) (StorageEngine, error) {
+	if storage == "" {
+		storage = StorageJSON
+	}
+	eng, ok := e.engines[storage]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage format %q (expected %s or %s)", storage, StorageJSON, StoragePage)
+	}
+	return eng, nil
+}
+
+// engineAt returns the engine of the database at dbPath
+func (e *FormatEngine) engineAt(dbPath string) (StorageEngine, error) {
+	data, err := os.ReadFile(filepath.Join(dbPath, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database meta: %w", err)
+	}
+	var meta metadata.DatabaseMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse database meta: %w", err)
+	}
+	return e.engine(meta.Storage)
+}
+
+// LoadDatabase loads a database with the engine of its format
+func (e *FormatEngine) LoadDatabase(dbPath string) (*schema.Database, error) {
+	eng, err := e.engineAt(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return eng.LoadDatabase(dbPath)
+}
+
+// SaveDatabase saves a database with the engine of its format
+func (e *FormatEngine) SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
+	eng, err := e.engine(db.Storage)
+	if err != nil {
+		return err
+	}
+	return eng.SaveDatabase(db, tx)
+}
+
+// CreateDatabase creates a new database in the default format
+func (e *FormatEngine) CreateDatabase(name, basePath string) error {
+	return e.CreateDatabaseAs(name, basePath, e.defaultStorage)
+}
+
+// CreateDatabaseAs creates a new database in the given format
+func (e *FormatEngine) CreateDatabaseAs(name, basePath, storage string) error {
+	eng, err := e.engine(storage)
+	if err != nil {
+		return err
+	}
+	return eng.CreateDatabase(name, basePath)
+}
+
+// DropDatabase removes a database with the engine of its format
+func (e *FormatEngine) DropDatabase(name, basePath string) error {
+	eng, err := e.engineAt(filepath.Join(basePath, name))
+	if err != nil {
+		eng = e.engines[StorageJSON] // no readable meta.json, nothing cached
+	}
+	return eng.DropDatabase(name, basePath)
+}
+
+// RenameDatabase renames a database with the engine of its format
+func (e *FormatEngine) RenameDatabase(oldName, newName, basePath string) error {
+	eng, err := e.engineAt(filepath.Join(basePath, oldName))
+	if err != nil {
+		return err
+	}
+	return eng.RenameDatabase(oldName, newName, basePath)
+}
+
+// ListDatabases returns all available databases, whatever their format
+func (e *FormatEngine) ListDatabases(basePath string) ([]string, error) {
+	return e.engines[StorageJSON].ListDatabases(basePath)
+}
+
+// LoadTable loads a table with the engine of its database's format
+func (e *FormatEngine) LoadTable(tablePath string) (*schema.Table, error) {
+	eng, err := e.engineAt(filepath.Dir(tablePath))
+	if err != nil {
+		return nil, err
+	}
+	return eng.LoadTable(tablePath)
+}
+
+// SaveTable saves a table with the engine of its database's format
+func (e *FormatEngine) SaveTable(table *schema.Table, tx *transaction.Transaction) error {
+	eng, err := e.engineAt(filepath.Dir(table.Path))
+	if err != nil {
+		return err
+	}
+	return eng.SaveTable(table, tx)
+}
+
+// ConvertDatabase saves every table of a loaded database in the given
+// format, records it in meta.json and removes the data files of the old one
+// On failure the database keeps its format; files already written in the
+// new one are ignored by it.
+func (e *FormatEngine) ConvertDatabase(db *schema.Database, storage string, tx *transaction.Transaction) error {
+	if storage == "" {
+		storage = StorageJSON
+	}
+	eng, err := e.engine(storage)
+	if err != nil {
+		return err
+	}
+
+	// Rows still on disk are read before the files they live in change, and
+	// every table is written in the new format
+	for _, table := range db.Tables {
+		if err := table.LoadRows(); err != nil {
+			return fmt.Errorf("failed to load table %s: %w", table.Name, err)
+		}
+		table.MarkDirty()
+	}
+
+	// Heaps cached from an earlier conversion to pages are stale
+	e.engines[StoragePage].(*PageEngine).forget(db.Path)
+
+	old := db.Storage
+	db.Storage = storage
+	if err := eng.SaveDatabase(db, tx); err != nil {
+		db.Storage = old
+		return fmt.Errorf("failed to convert database %s to %s storage: %w", db.Name, storage, err)
+	}
+
+	for format, name := range dataFiles {
+		if format == storage {
+			continue
+		}
+		for _, table := range db.Tables {
+			if err := os.Remove(filepath.Join(table.Path, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s of table %s: %w", name, table.Name, err)
+			}
+		}
+	}
+	return nil
+}
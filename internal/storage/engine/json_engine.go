@@ -33,6 +33,12 @@ func (e *JSONEngine) SaveDatabase(db *schema.Database, tx *transaction.Transacti
 
 // CreateDatabase creates a new database directory with JSON metadata
 func (e *JSONEngine) CreateDatabase(name, basePath string) error {
+	return createDatabase(name, basePath, "")
+}
+
+// createDatabase creates a database directory whose meta.json records the
+// given storage format
+func createDatabase(name, basePath, storage string) error {
 	dbPath := filepath.Join(basePath, name)
 
 	// Check if exists
@@ -49,6 +55,7 @@ func (e *JSONEngine) CreateDatabase(name, basePath string) error {
 	meta := metadata.DatabaseMeta{
 		Name:    name,
 		Version: 1,
+		Storage: storage,
 		Tables:  []string{},
 	}
 
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/domain/transaction"
+	"github.com/leengari/mini-rdbms/internal/storage/loader"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// PageEngine implements StorageEngine storing the rows of each table in a
+// heap file of slotted pages (data.pages) instead of data.json
+// Schemas, indexes and statistics stay in the JSON files of the table. A
+// loaded table's rows stay on disk, read through a buffer pool shared by all
+// heaps (see schema.Table.Source), until an operation needs them in memory.
+// The engine keeps the heap of every table it loaded or saved, so a save
+// writes only the pages whose rows changed.
+type PageEngine struct {
+	JSONEngine
+	pool  *page.BufferPool
+	mu    sync.Mutex
+	heaps map[string]*page.Heap // by table path
+}
+
+// NewPageEngine creates a new page storage engine reading through pool
+func NewPageEngine(pool *page.BufferPool) *PageEngine {
+	return &PageEngine{pool: pool, heaps: make(map[string]*page.Heap)}
+}
+
+// LoadDatabase loads a database whose tables are stored in pages
+func (e *PageEngine) LoadDatabase(dbPath string) (*schema.Database, error) {
+	return loader.LoadDatabaseWith(dbPath, e.LoadTable)
+}
+
+// SaveDatabase saves a database, writing the changed pages of every table
+func (e *PageEngine) SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
+	return writer.SaveDatabaseWith(db, tx, e.SaveTable)
+}
+
+// CreateDatabase creates a new database directory recorded as page storage
+func (e *PageEngine) CreateDatabase(name, basePath string) error {
+	return createDatabase(name, basePath, StoragePage)
+}
+
+// DropDatabase removes a database directory and forgets its heaps
+func (e *PageEngine) DropDatabase(name, basePath string) error {
+	e.forget(filepath.Join(basePath, name))
+	return e.JSONEngine.DropDatabase(name, basePath)
+}
+
+// RenameDatabase renames a database directory and forgets the heaps under
+// the old path, which are read again from the new one
+func (e *PageEngine) RenameDatabase(oldName, newName, basePath string) error {
+	e.forget(filepath.Join(basePath, oldName))
+	return e.JSONEngine.RenameDatabase(oldName, newName, basePath)
+}
+
+// LoadTable loads a table, leaving its rows in its heap file
+func (e *PageEngine) LoadTable(tablePath string) (*schema.Table, error) {
+	return loader.LoadTableWith(tablePath, e.readRows)
+}
+
+// SaveTable saves a table, writing the pages whose rows changed
+// The changed pages are staged in a journal that replaces meta.json with
+// it, and only then written in place; a crash in between leaves the journal
+// for the next load to apply. Saves are serialized, as two transactions may
+// save the same table under its read lock.
+func (e *PageEngine) SaveTable(table *schema.Table, tx *transaction.Transaction) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := writer.SaveTableWith(table, tx, e.writeRows); err != nil {
+		e.drop(table.Path)
+		return err
+	}
+	heap, ok := e.heaps[table.Path]
+	if !ok {
+		return nil
+	}
+	if err := heap.Apply(); err != nil {
+		// The journal is committed; opening the heap again applies it
+		e.drop(table.Path)
+		return fmt.Errorf("failed to write pages for %s: %w", table.Name, err)
+	}
+	return nil
+}
+
+// readRows opens the heap file of a table as the source of its rows, which
+// are read as they are scanned
+// A table that only has a data.json, such as one whose database meta.json
+// was switched to page storage by hand, is read from it; its first save
+// moves the rows to pages.
+func (e *PageEngine) readRows(table *schema.Table) ([]data.Row, error) {
+	heapPath := filepath.Join(table.Path, page.FileName)
+	if err := page.Recover(heapPath); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(heapPath); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(table.Path, "data.json")); err == nil {
+			return loader.ReadJSONRows(table)
+		}
+	}
+
+	heap, err := page.OpenHeap(heapPath, e.pool, table.Schema.Columns)
+	if err != nil {
+		return nil, err
+	}
+	table.Source = heap
+
+	e.mu.Lock()
+	e.heaps[table.Path] = heap
+	e.mu.Unlock()
+	return nil, nil
+}
+
+// writeRows stages the rows of a table as the content of its heap file and
+// returns the journal of the pages that change, to replace meta.json with
+// A table whose rows are still on disk has not changed and is skipped.
+// Must be called while holding e.mu.
+func (e *PageEngine) writeRows(table *schema.Table) ([]writer.File, error) {
+	if table.Source != nil {
+		return nil, nil
+	}
+
+	heap, ok := e.heaps[table.Path]
+	if !ok {
+		heapPath := filepath.Join(table.Path, page.FileName)
+		if err := page.Recover(heapPath); err != nil {
+			return nil, err
+		}
+		var err error
+		if heap, err = page.OpenHeap(heapPath, e.pool, table.Schema.Columns); err != nil {
+			return nil, err
+		}
+		e.heaps[table.Path] = heap
+	}
+
+	journal, written, err := heap.Stage(table.Rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pages for %s: %w", table.Name, err)
+	}
+
+	slog.Debug("table pages staged",
+		slog.String("table", table.Name),
+		slog.Int("written", written),
+	)
+	return []writer.File{{Name: page.JournalName, Data: journal}}, nil
+}
+
+// drop forgets the heap of a table whose save failed, with its pages in the
+// pool, so it is read again from the file
+// Must be called while holding e.mu.
+func (e *PageEngine) drop(tablePath string) {
+	if _, ok := e.heaps[tablePath]; !ok {
+		return
+	}
+	delete(e.heaps, tablePath)
+	e.pool.Discard(filepath.Join(tablePath, page.FileName))
+}
+
+// forget drops the heaps of the tables under a database path and their
+// pages from the pool
+func (e *PageEngine) forget(dbPath string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pool.Discard(dbPath)
+	for path := range e.heaps {
+		if strings.HasPrefix(path, dbPath+string(filepath.Separator)) {
+			delete(e.heaps, path)
+		}
+	}
+}
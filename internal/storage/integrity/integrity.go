@@ -0,0 +1,197 @@
+package integrity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// Options control a check
+type Options struct {
+	// ArchiveDir is the WAL archive of the database, checked with its active
+	// WAL; empty checks only the active WAL
+	ArchiveDir string
+
+	// Repair applies the safe fixes of the problems found
+	Repair bool
+}
+
+// Problem is one inconsistency found in a database
+type Problem struct {
+	Object   string // the table, "database" or the WAL segment it was found in
+	Message  string
+	Fix      string // the safe repair, empty when the problem needs a person
+	Repaired bool
+}
+
+// Report is the outcome of checking a database
+type Report struct {
+	Database string
+	Tables   int
+	Rows     int
+	Segments int // WAL segments read
+	Problems []Problem
+}
+
+// OK reports whether every problem found was repaired
+func (r *Report) OK() bool {
+	return len(r.Remaining()) == 0
+}
+
+// Remaining returns the problems that were not repaired
+func (r *Report) Remaining() []Problem {
+	var remaining []Problem
+	for _, p := range r.Problems {
+		if !p.Repaired {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining
+}
+
+// checker collects the problems of one database
+type checker struct {
+	dbPath string
+	opts   Options
+	report *Report
+}
+
+// add records a problem and returns its position in the report
+func (c *checker) add(object, fix, format string, args ...interface{}) int {
+	c.report.Problems = append(c.report.Problems, Problem{
+		Object:  object,
+		Message: fmt.Sprintf(format, args...),
+		Fix:     fix,
+	})
+	return len(c.report.Problems) - 1
+}
+
+// repaired marks problems as repaired
+func (c *checker) repaired(problems ...int) {
+	for _, i := range problems {
+		c.report.Problems[i].Repaired = true
+	}
+}
+
+// Check verifies the files of the database in dbPath and reports every
+// problem found rather than stopping at the first
+//
+// Every table is checked for an interrupted save, files that do not parse,
+// rows that do not match the schema, duplicate primary and unique keys, a
+// last_insert_id behind the rows and a row_count that differs from them;
+// the WAL for records failing their checksum and gaps between segments.
+// With opts.Repair the fixes that lose nothing are applied: finishing an
+// interrupted save as loading does, correcting counters and lists in
+// meta.json files, removing unreadable statistics and truncating a torn WAL
+// tail. Duplicate keys, invalid rows and corrupt files are only reported.
+// Nothing else may have the database open while it is repaired.
+func Check(dbPath string, opts Options) (*Report, error) {
+	if info, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a database directory", dbPath)
+	}
+
+	c := &checker{dbPath: dbPath, opts: opts, report: &Report{Database: filepath.Base(dbPath)}}
+
+	meta, metaOK, err := c.checkDatabaseMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database directory: %w", err)
+	}
+	pool := page.NewBufferPool(64)
+	defer pool.Discard(dbPath)
+
+	var tableNames []string
+	dirs := make(map[string]string) // directory of each table name
+	for _, entry := range entries {
+		// Hidden directories hold working files, such as the WAL
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		tablePath := filepath.Join(dbPath, entry.Name())
+		name, err := c.checkTable(tablePath, meta.Storage, pool)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := dirs[name]; ok {
+			c.add(name, "", "directories %s and %s both hold table %s; only one is loaded", other, entry.Name(), name)
+			continue
+		}
+		dirs[name] = entry.Name()
+		tableNames = append(tableNames, name)
+		c.report.Tables++
+	}
+	sort.Strings(tableNames)
+
+	// The list of tables is only informative, loading reads the directories
+	if metaOK && len(meta.Tables) > 0 && strings.Join(meta.Tables, ",") != strings.Join(tableNames, ",") {
+		p := c.add("database", "list the tables found", "meta.json lists tables %v, the directories hold %v", meta.Tables, tableNames)
+		if opts.Repair {
+			meta.Tables = tableNames
+			if err := c.writeDatabaseMeta(meta); err != nil {
+				return nil, err
+			}
+			c.repaired(p)
+		}
+	}
+
+	if err := c.checkWAL(); err != nil {
+		return nil, err
+	}
+	return c.report, nil
+}
+
+// checkDatabaseMeta checks the meta.json of the database, returning it and
+// whether it was read
+func (c *checker) checkDatabaseMeta() (metadata.DatabaseMeta, bool, error) {
+	var meta metadata.DatabaseMeta
+	content, err := os.ReadFile(filepath.Join(c.dbPath, "meta.json"))
+	if err != nil {
+		c.add("database", "", "cannot read meta.json: %v", err)
+		return meta, false, nil
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		c.add("database", "", "meta.json does not parse: %v", err)
+		return meta, false, nil
+	}
+
+	if meta.Storage != "" && meta.Storage != engine.StorageJSON && meta.Storage != engine.StoragePage {
+		c.add("database", "", "meta.json has unknown storage %q", meta.Storage)
+	}
+	if dir := filepath.Base(c.dbPath); meta.Name != dir {
+		p := c.add("database", "set the name to "+dir, "meta.json names the database %q, its directory is %q", meta.Name, dir)
+		if c.opts.Repair {
+			meta.Name = dir
+			if err := c.writeDatabaseMeta(meta); err != nil {
+				return meta, true, err
+			}
+			c.repaired(p)
+		}
+	}
+	return meta, true, nil
+}
+
+// writeDatabaseMeta replaces the meta.json of the database
+func (c *checker) writeDatabaseMeta(meta metadata.DatabaseMeta) error {
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writer.ReplaceFile(filepath.Join(c.dbPath, "meta.json"), content); err != nil {
+		return fmt.Errorf("failed to write database meta: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package integrity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
+	"github.com/leengari/mini-rdbms/internal/query/validation"
+	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/page"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
+)
+
+// knownTypes are the column types a table may have
+var knownTypes = map[schema.ColumnType]bool{
+	schema.ColumnTypeInt:   true,
+	schema.ColumnTypeFloat: true,
+	schema.ColumnTypeText:  true,
+	schema.ColumnTypeBool:  true,
+	schema.ColumnTypeDate:  true,
+	schema.ColumnTypeTime:  true,
+	schema.ColumnTypeEmail: true,
+}
+
+// checkTable checks the table in path and returns its name
+// A table whose meta.json cannot be read is named after its directory.
+func (c *checker) checkTable(path, storage string, pool *page.BufferPool) (string, error) {
+	name := filepath.Base(path)
+
+	if err := c.checkInterruptedSave(path, name); err != nil {
+		return name, err
+	}
+
+	metaPath := filepath.Join(path, "meta.json")
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		c.add(name, "", "cannot read meta.json: %v", err)
+		return name, nil
+	}
+	var meta metadata.TableMeta
+	if err := json.Unmarshal(content, &meta); err != nil {
+		c.add(name, "", "meta.json does not parse: %v", err)
+		return name, nil
+	}
+	if meta.Name != name {
+		c.add(name, "", "meta.json names the table %q, its directory is %q", meta.Name, name)
+	}
+	if meta.Name != "" {
+		name = meta.Name
+	}
+
+	table, ok := c.tableFromMeta(path, meta)
+	if !ok {
+		return name, nil
+	}
+
+	rows, err := readRows(table, storage, pool)
+	if err != nil {
+		c.add(name, "", "cannot read rows: %v", err)
+		return name, nil
+	}
+	c.report.Rows += len(rows)
+
+	valid := c.checkRows(table, rows)
+	c.checkKeys(table, rows, valid)
+
+	// Counters that drift from the rows are corrected in meta.json
+	var fixes []int
+	if pk := table.Schema.GetPrimaryKeyColumn(); pk != nil && pk.AutoIncrement {
+		var maxID int64
+		for i, row := range rows {
+			if id, ok := row.Data[pk.Name].(int64); ok && valid[i] && id > maxID {
+				maxID = id
+			}
+		}
+		if meta.LastInsertID < maxID {
+			fixes = append(fixes, c.add(name, fmt.Sprintf("set last_insert_id to %d", maxID),
+				"last_insert_id is %d but rows go up to id %d; the next insert would reuse an id", meta.LastInsertID, maxID))
+			meta.LastInsertID = maxID
+		}
+	}
+	if meta.RowCount != int64(len(rows)) {
+		fixes = append(fixes, c.add(name, fmt.Sprintf("set row_count to %d", len(rows)),
+			"row_count is %d but the table holds %d rows", meta.RowCount, len(rows)))
+		meta.RowCount = int64(len(rows))
+	}
+	if len(fixes) > 0 && c.opts.Repair {
+		content, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return name, err
+		}
+		if err := writer.ReplaceFile(metaPath, content); err != nil {
+			return name, fmt.Errorf("failed to write table meta for %s: %w", name, err)
+		}
+		c.repaired(fixes...)
+	}
+
+	// Statistics only guide the planner, so a broken file is safe to drop
+	statsPath := filepath.Join(path, "stats.json")
+	if content, err := os.ReadFile(statsPath); err == nil {
+		var stats metadata.TableStatsMeta
+		if err := json.Unmarshal(content, &stats); err != nil {
+			p := c.add(name, "remove stats.json; ANALYZE writes it again", "stats.json does not parse: %v", err)
+			if c.opts.Repair {
+				if err := os.Remove(statsPath); err != nil {
+					return name, err
+				}
+				c.repaired(p)
+			}
+		}
+	}
+	return name, nil
+}
+
+// checkInterruptedSave reports a save of the table's files that a crash
+// interrupted, which loading the table finishes or undoes
+func (c *checker) checkInterruptedSave(path, name string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("failed to read table directory: %w", err)
+	}
+	var leftovers []string
+	for _, entry := range entries {
+		if !entry.IsDir() && (entry.Name() == "commit.json" || entry.Name() == page.JournalName || strings.HasSuffix(entry.Name(), ".tmp")) {
+			leftovers = append(leftovers, entry.Name())
+		}
+	}
+	if len(leftovers) == 0 {
+		return nil
+	}
+
+	p := c.add(name, "finish or undo the save, as loading does", "a save was interrupted, leaving %s", strings.Join(leftovers, ", "))
+	if c.opts.Repair {
+		if err := writer.Recover(path); err != nil {
+			return err
+		}
+		if err := page.Recover(filepath.Join(path, page.FileName)); err != nil {
+			return err
+		}
+		c.repaired(p)
+	}
+	return nil
+}
+
+// tableFromMeta returns the table meta.json describes, reporting columns
+// and indexes that would keep it from loading
+func (c *checker) tableFromMeta(path string, meta metadata.TableMeta) (*schema.Table, bool) {
+	ok := true
+	tableSchema := &schema.TableSchema{TableName: meta.Name}
+	seen := make(map[string]bool, len(meta.Columns))
+	primaryKeys := 0
+	for _, cm := range meta.Columns {
+		col := schema.Column{
+			Name:          cm.Name,
+			Type:          schema.ColumnType(cm.Type),
+			PrimaryKey:    cm.PrimaryKey,
+			Unique:        cm.Unique,
+			NotNull:       cm.NotNull,
+			AutoIncrement: cm.AutoIncrement,
+		}
+		if !knownTypes[col.Type] {
+			c.add(meta.Name, "", "column %s has unknown type %q", col.Name, cm.Type)
+			ok = false
+		}
+		if seen[col.Name] {
+			c.add(meta.Name, "", "column %s is defined more than once", col.Name)
+			ok = false
+		}
+		seen[col.Name] = true
+		if col.PrimaryKey {
+			primaryKeys++
+		}
+		tableSchema.Columns = append(tableSchema.Columns, col)
+	}
+	if primaryKeys > 1 {
+		c.add(meta.Name, "", "%d columns are marked primary key", primaryKeys)
+	}
+
+	for _, im := range meta.Indexes {
+		kind, known := index.ParseKind(im.Type)
+		if !known {
+			c.add(meta.Name, "", "index %s has unknown type %q", im.Name, im.Type)
+			ok = false
+			continue
+		}
+		if !seen[im.Column] {
+			c.add(meta.Name, "", "index %s is on column %s, which does not exist", im.Name, im.Column)
+			ok = false
+			continue
+		}
+		tableSchema.Indexes = append(tableSchema.Indexes, schema.IndexDefinition{
+			Name:   im.Name,
+			Column: im.Column,
+			Kind:   kind,
+			Unique: im.Unique,
+		})
+	}
+
+	return &schema.Table{
+		Name:         meta.Name,
+		Path:         path,
+		Schema:       tableSchema,
+		LastInsertID: meta.LastInsertID,
+	}, ok
+}
+
+// readRows reads the rows of a table as its storage engine would
+// A page database reads the heap file, or data.json if it has no heap yet;
+// every page read has its checksum verified.
+func readRows(table *schema.Table, storage string, pool *page.BufferPool) ([]data.Row, error) {
+	heapPath := filepath.Join(table.Path, page.FileName)
+	if storage == engine.StoragePage {
+		if _, err := os.Stat(heapPath); err == nil {
+			heap, err := page.OpenHeap(heapPath, pool, table.Schema.Columns)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := heap.Rows()
+			if err != nil {
+				return nil, err
+			}
+			if heap.Len() != len(rows) {
+				return nil, fmt.Errorf("%s: header counts %d rows, the pages hold %d", page.FileName, heap.Len(), len(rows))
+			}
+			return rows, nil
+		}
+	}
+
+	rows := []data.Row{}
+	content, err := os.ReadFile(filepath.Join(table.Path, "data.json"))
+	if os.IsNotExist(err) {
+		return rows, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return nil, fmt.Errorf("data.json does not parse: %w", err)
+	}
+	return rows, nil
+}
+
+// checkRows validates every row against the schema and returns which rows
+// are valid
+func (c *checker) checkRows(table *schema.Table, rows []data.Row) []bool {
+	valid := make([]bool, len(rows))
+	for i, row := range rows {
+		if err := validation.ValidateRow(table, row, i); err != nil {
+			c.add(table.Name, "", "%v", err)
+			continue
+		}
+		valid[i] = true
+	}
+	return valid
+}
+
+// checkKeys reports rows without a primary key and values repeated in a
+// primary key, unique column or unique index, which keep the table's
+// indexes from being built
+// Rows that failed validation were reported already and are skipped.
+func (c *checker) checkKeys(table *schema.Table, rows []data.Row, valid []bool) {
+	unique := make(map[string]bool)
+	for _, col := range table.Schema.Columns {
+		if col.PrimaryKey || col.Unique {
+			unique[col.Name] = true
+		}
+	}
+	for _, def := range table.Schema.Indexes {
+		if def.Unique {
+			unique[def.Column] = true
+		}
+	}
+
+	for _, col := range table.Schema.Columns {
+		if !unique[col.Name] {
+			continue
+		}
+		first := make(map[interface{}]int)
+		for i, row := range rows {
+			if !valid[i] {
+				continue
+			}
+			value, ok := row.Data[col.Name]
+			if !ok || value == nil {
+				if col.PrimaryKey {
+					c.add(table.Name, "", "row %d has no value for primary key %s", i, col.Name)
+				}
+				continue
+			}
+			key := index.NormalizeKey(value)
+			if j, dup := first[key]; dup {
+				c.add(table.Name, "", "rows %d and %d have the same %s %v", j, i, col.Name, value)
+				continue
+			}
+			first[key] = i
+		}
+	}
+}
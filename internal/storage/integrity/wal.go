@@ -0,0 +1,103 @@
+package integrity
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/wal"
+)
+
+// checkWAL reads every record of the archived and active WAL segments,
+// verifying their checksums and that LSNs run on from segment to segment
+// Only the tail of the active segment can be torn by a crash; opening the
+// WAL truncates it, and so does a repair. Damage anywhere else is reported.
+func (c *checker) checkWAL() error {
+	var segments []string
+	if c.opts.ArchiveDir != "" {
+		archived, err := wal.ListSegments(c.opts.ArchiveDir)
+		if err != nil {
+			return fmt.Errorf("failed to list archived WAL segments: %w", err)
+		}
+		segments = append(segments, archived...)
+	}
+	active, err := wal.ListSegments(filepath.Join(c.dbPath, archive.WALDir))
+	if err != nil {
+		return fmt.Errorf("failed to list WAL segments: %w", err)
+	}
+	segments = append(segments, active...)
+
+	var next uint64 // LSN the next segment should start at
+	for i, path := range segments {
+		last := i == len(segments)-1 && len(active) > 0
+		end, err := c.checkSegment(path, next, last)
+		if err != nil {
+			return err
+		}
+		next = end
+		c.report.Segments++
+	}
+	return nil
+}
+
+// checkSegment checks one WAL segment whose first LSN should be next,
+// unless next is 0, and returns the LSN after its last readable record
+// A torn tail of the active segment is repairable; in any other segment
+// the records after a damaged one cannot be read.
+func (c *checker) checkSegment(path string, next uint64, active bool) (uint64, error) {
+	name := filepath.Base(path)
+	reader, err := wal.NewWALReader(path)
+	if err != nil {
+		c.add(name, "", "%v", err)
+		return 0, nil
+	}
+	defer reader.Close()
+
+	header, err := reader.ReadFileHeader()
+	if err != nil {
+		c.add(name, "", "%v", err)
+		return 0, nil
+	}
+	if want := wal.SegmentName(header.InitialLSN); name != want {
+		c.add(name, "", "segment header starts at LSN %d, so it should be named %s", header.InitialLSN, want)
+	}
+	if next != 0 && header.InitialLSN != next {
+		if header.InitialLSN > next {
+			c.add(name, "", "LSNs %d to %d are missing before this segment; point-in-time recovery cannot replay past them",
+				next, header.InitialLSN-1)
+		} else {
+			c.add(name, "", "segment starts at LSN %d, which the segment before it already reached", header.InitialLSN)
+		}
+	}
+
+	expected := header.InitialLSN
+	for {
+		record, err := reader.ReadNextRecord()
+		if err == io.EOF {
+			return expected, nil
+		}
+		if err != nil {
+			offset := reader.CurrentPosition()
+			if !active {
+				c.add(name, "", "%v; the records after it cannot be read", err)
+				return 0, nil
+			}
+			p := c.add(name, fmt.Sprintf("truncate the segment to its last complete record (%d bytes)", offset),
+				"%v; the records from offset %d on are lost", err, offset)
+			if c.opts.Repair {
+				if err := os.Truncate(path, int64(offset)); err != nil {
+					return 0, fmt.Errorf("failed to truncate %s: %w", path, err)
+				}
+				c.repaired(p)
+			}
+			return expected, nil
+		}
+		h := record.GetHeader()
+		if h.LSN != expected {
+			c.add(name, "", "record at offset %d has LSN %d, expected %d", h.FileOffset, h.LSN, expected)
+		}
+		expected = h.LSN + 1
+	}
+}
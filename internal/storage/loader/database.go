@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/storage/metadata"
@@ -13,6 +14,11 @@ import (
 
 // LoadDatabase loads the database from the given directory path
 func LoadDatabase(dbPath string) (*schema.Database, error) {
+	return LoadDatabaseWith(dbPath, LoadTable)
+}
+
+// LoadDatabaseWith loads a database whose tables are loaded by loadTable
+func LoadDatabaseWith(dbPath string, loadTable func(path string) (*schema.Table, error)) (*schema.Database, error) {
 	metaPath := filepath.Join(dbPath, "meta.json")
 
 	data, err := os.ReadFile(metaPath)
@@ -26,9 +32,10 @@ func LoadDatabase(dbPath string) (*schema.Database, error) {
 	}
 
 	db := &schema.Database{
-		Name:   meta.Name,
-		Path:   dbPath,
-		Tables: make(map[string]*schema.Table),
+		Name:    meta.Name,
+		Path:    dbPath,
+		Storage: meta.Storage,
+		Tables:  make(map[string]*schema.Table),
 	}
 
 	// Read all entries in the database directory
@@ -38,14 +45,15 @@ func LoadDatabase(dbPath string) (*schema.Database, error) {
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Hidden directories hold working files, such as spilled query runs
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
 		tableName := entry.Name()
 		tablePath := filepath.Join(dbPath, tableName)
 
-		table, err := LoadTable(tablePath)
+		table, err := loadTable(tablePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load table %s: %w", tableName, err)
 		}
@@ -9,14 +9,48 @@ import (
 
 	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
+	"github.com/leengari/mini-rdbms/internal/index"
 	"github.com/leengari/mini-rdbms/internal/query/validation"
 	"github.com/leengari/mini-rdbms/internal/storage/metadata"
+	"github.com/leengari/mini-rdbms/internal/storage/writer"
 )
 
 // LoadTable loads a table from the given directory path
 func LoadTable(path string) (*schema.Table, error) {
+	return LoadTableWith(path, ReadJSONRows)
+}
+
+// RowReader reads the rows of a table whose schema has been loaded
+// A reader that leaves the rows on disk sets table.Source instead.
+type RowReader func(table *schema.Table) ([]data.Row, error)
+
+// ReadJSONRows reads the rows of a table from its data.json, if any
+func ReadJSONRows(table *schema.Table) ([]data.Row, error) {
+	rows := []data.Row{}
+	dataPath := filepath.Join(table.Path, "data.json")
+	if _, err := os.Stat(dataPath); err == nil {
+		dataBytes, err := os.ReadFile(dataPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(dataBytes, &rows); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// LoadTableWith loads a table whose rows are read by readRows
+// The schema, indexes and statistics always come from the JSON files of the
+// table directory; storage engines differ only in how rows are stored.
+func LoadTableWith(path string, readRows RowReader) (*schema.Table, error) {
+	// Finish or undo a save interrupted by a crash before reading anything
+	if err := writer.Recover(path); err != nil {
+		return nil, fmt.Errorf("failed to recover table files: %w", err)
+	}
+
 	metaPath := filepath.Join(path, "meta.json")
-	dataPath := filepath.Join(path, "data.json")
 
 	metaBytes, err := os.ReadFile(metaPath)
 	if err != nil {
@@ -45,27 +79,47 @@ func LoadTable(path string) (*schema.Table, error) {
 		tableSchema.Columns = append(tableSchema.Columns, col)
 	}
 
-	rows := []data.Row{}
-	if _, err := os.Stat(dataPath); err == nil {
-		dataBytes, err := os.ReadFile(dataPath)
-		if err != nil {
-			return nil, err
-		}
-
-		if err := json.Unmarshal(dataBytes, &rows); err != nil {
-			return nil, err
+	for _, im := range meta.Indexes {
+		kind, ok := index.ParseKind(im.Type)
+		if !ok {
+			return nil, fmt.Errorf("table %s: index %s has unknown type %q", meta.Name, im.Name, im.Type)
 		}
+		tableSchema.Indexes = append(tableSchema.Indexes, schema.IndexDefinition{
+			Name:   im.Name,
+			Column: im.Column,
+			Kind:   kind,
+			Unique: im.Unique,
+		})
 	}
 
 	table := &schema.Table{
 		Name:         meta.Name,
 		Path:         path,
 		Schema:       tableSchema,
-		Rows:         rows,
 		Indexes:      make(map[string]*data.Index),
 		LastInsertID: meta.LastInsertID,
 	}
 
+	rows, err := readRows(table)
+	if err != nil {
+		return nil, err
+	}
+	table.Rows = rows
+
+	// Load planner statistics if the table has been analyzed
+	statsPath := filepath.Join(path, "stats.json")
+	if statsBytes, err := os.ReadFile(statsPath); err == nil {
+		var statsMeta metadata.TableStatsMeta
+		if err := json.Unmarshal(statsBytes, &statsMeta); err != nil {
+			// Statistics only guide the planner - a broken file is not fatal
+			slog.Warn("ignoring unreadable table statistics",
+				slog.String("table", meta.Name),
+				slog.Any("error", err))
+		} else {
+			table.Stats = statsFromMeta(statsMeta, tableSchema)
+		}
+	}
+
 	// Validate all loaded rows against schema
 	for i, row := range table.Rows {
 		if err := validation.ValidateRow(table, row, i); err != nil {
@@ -75,9 +129,47 @@ func LoadTable(path string) (*schema.Table, error) {
 
 	slog.Info("table loaded",
 		slog.String("table", table.Name),
-		slog.Int("rows", len(rows)),
+		slog.Int("rows", table.RowCount()),
+		slog.Bool("paged", table.Source != nil),
 		slog.String("path", path),
 	)
 
 	return table, nil
 }
+
+// statsFromMeta converts stats.json content into planner statistics
+// JSON numbers decode as float64, so values of INT columns are converted back
+// to int64 to match the index key type.
+func statsFromMeta(meta metadata.TableStatsMeta, tableSchema *schema.TableSchema) *schema.TableStatistics {
+	stats := &schema.TableStatistics{
+		RowCount:   meta.RowCount,
+		AnalyzedAt: meta.AnalyzedAt,
+		Columns:    make(map[string]*schema.ColumnStatistics, len(meta.Columns)),
+	}
+
+	for name, c := range meta.Columns {
+		col := tableSchema.GetColumn(name)
+		if col == nil {
+			continue // column no longer exists
+		}
+
+		convert := func(v interface{}) interface{} {
+			if f, ok := v.(float64); ok && col.Type == schema.ColumnTypeInt && f == float64(int64(f)) {
+				return int64(f)
+			}
+			return v
+		}
+
+		colStats := &schema.ColumnStatistics{
+			DistinctCount: c.DistinctCount,
+			NullFraction:  c.NullFraction,
+			Min:           convert(c.Min),
+			Max:           convert(c.Max),
+		}
+		for _, b := range c.Histogram {
+			colStats.Histogram = append(colStats.Histogram, convert(b))
+		}
+		stats.Columns[name] = colStats
+	}
+	return stats
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LockFileName is the lock file in a database directory, held by the JoyDB
+// process that has the database loaded and holding its PID
+const LockFileName = "joydb.lock"
+
+// ErrLocked is returned when another process holds a database's lock
+var ErrLocked = errors.New("database is locked")
+
+// ErrReadOnly is returned for changes through a read-only registry
+var ErrReadOnly = errors.New("databases are open read-only")
+
+// dirLock is a lock file held by this process
+type dirLock struct {
+	file *os.File
+	refs int // registries of this process holding it
+}
+
+// Locks are advisory and held per process, so registries of one process
+// share them and only another process is kept out
+var (
+	locksMu sync.Mutex
+	locks   = make(map[string]*dirLock)
+)
+
+// lockDir takes the lock of a database directory, failing with ErrLocked
+// and the holder's PID when another process has it
+// Returns false without error when the directory does not exist, leaving
+// the caller to report the missing database.
+func lockDir(dir string) (bool, error) {
+	path, err := filepath.Abs(filepath.Join(dir, LockFileName))
+	if err != nil {
+		return false, err
+	}
+
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	if l, ok := locks[path]; ok {
+		l.refs++
+		return true, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := tryLock(f); err != nil {
+		holder, _ := os.ReadFile(path)
+		f.Close()
+		if errors.Is(err, errWouldBlock) {
+			pid := strings.TrimSpace(string(holder))
+			if pid == "" {
+				pid = "unknown"
+			}
+			return false, fmt.Errorf("%w: '%s' is in use by JoyDB process %s; start with -read-only to open it alongside",
+				ErrLocked, filepath.Base(dir), pid)
+		}
+		return false, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the holder for the error other processes report
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	locks[path] = &dirLock{file: f, refs: 1}
+	return true, nil
+}
+
+// unlockDir releases a lock taken with lockDir
+// The lock file stays behind; an unheld lock file does not block anyone.
+func unlockDir(dir string) {
+	path, err := filepath.Abs(filepath.Join(dir, LockFileName))
+	if err != nil {
+		return
+	}
+
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	l, ok := locks[path]
+	if !ok {
+		return
+	}
+	if l.refs--; l.refs == 0 {
+		// Closing the file releases the lock
+		l.file.Close()
+		delete(locks, path)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package manager
+
+import (
+	"errors"
+	"os"
+)
+
+var errWouldBlock = errors.New("lock is held")
+
+// tryLock does nothing: database locks are only taken on Unix systems
+func tryLock(f *os.File) error {
+	return nil
+}
@@ -1,65 +1,179 @@
 package manager
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
 	"github.com/leengari/mini-rdbms/internal/query/indexing"
+	"github.com/leengari/mini-rdbms/internal/storage/archive"
+	"github.com/leengari/mini-rdbms/internal/storage/backup"
 	"github.com/leengari/mini-rdbms/internal/storage/engine"
+	"github.com/leengari/mini-rdbms/internal/storage/integrity"
+	"github.com/leengari/mini-rdbms/internal/wal"
 )
 
 // Registry manages loaded databases in a thread-safe way
 type Registry struct {
 	mu            sync.RWMutex
+	saveMu        sync.Mutex // serializes SaveAll, which runs under the read lock
 	loaded        map[string]*schema.Database
 	basePath      string
 	storageEngine engine.StorageEngine
+	readOnly      bool
+	locked        map[string]bool // databases whose directory lock is held
+
+	// Write-ahead logging, enabled by EnableWAL
+	walArchive     string // directory holding each database's WAL archive
+	walSegmentSize uint64
+	wals           map[string]*wal.WAL
 }
 
 // NewRegistry creates a new database registry with the given storage engine
+// Loading a database takes the lock of its directory, so another JoyDB
+// process cannot load it until the registry is closed.
 func NewRegistry(basePath string, storageEngine engine.StorageEngine) *Registry {
 	return &Registry{
 		loaded:        make(map[string]*schema.Database),
 		basePath:      basePath,
 		storageEngine: storageEngine,
+		locked:        make(map[string]bool),
+		wals:          make(map[string]*wal.WAL),
 	}
 }
 
+// NewReadOnlyRegistry creates a registry that loads databases without
+// locking them, to read databases another process has open
+// Nothing is ever saved: statements that change data and database
+// management statements fail with ErrReadOnly.
+func NewReadOnlyRegistry(basePath string, storageEngine engine.StorageEngine) *Registry {
+	r := NewRegistry(basePath, storageEngine)
+	r.readOnly = true
+	return r
+}
+
+// EnableWAL makes the registry log the changes to every database it loads
+// in a segmented write-ahead log, archiving full segments to
+// archiveRoot/<database> for point-in-time recovery
+// A read-only registry changes nothing and logs nothing.
+func (r *Registry) EnableWAL(archiveRoot string, segmentSize uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.walArchive = archiveRoot
+	r.walSegmentSize = segmentSize
+}
+
+// WAL returns the write-ahead log of a loaded database, or nil if the
+// database is not logged
+func (r *Registry) WAL(name string) *wal.WAL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.wals[name]
+}
+
+// ReadOnly reports whether the registry was opened read-only
+func (r *Registry) ReadOnly() bool {
+	return r.readOnly
+}
+
 // Get loads a database (or returns cached one) and ensures indexes are built
 func (r *Registry) Get(name string) (*schema.Database, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.get(name)
+}
 
+// get is Get with the registry lock held
+func (r *Registry) get(name string) (*schema.Database, error) {
 	// Check cache
 	if db, ok := r.loaded[name]; ok {
 		return db, nil
 	}
 
+	if err := r.lock(name); err != nil {
+		return nil, err
+	}
+
 	// Load from disk using storage engine
 	dbPath := filepath.Join(r.basePath, name)
 	db, err := r.storageEngine.LoadDatabase(dbPath)
 	if err != nil {
+		r.unlock(name)
 		return nil, err
 	}
 
 	// Build Indexes
 	if err := indexing.BuildDatabaseIndexes(db); err != nil {
+		r.unlock(name)
 		return nil, fmt.Errorf("failed to build indexes: %w", err)
 	}
 
+	if r.walArchive != "" && !r.readOnly {
+		log, err := wal.OpenSegmented(filepath.Join(dbPath, archive.WALDir), filepath.Join(r.walArchive, name), name, r.walSegmentSize)
+		if err != nil {
+			r.unlock(name)
+			return nil, fmt.Errorf("failed to open WAL: %w", err)
+		}
+		r.wals[name] = log
+	}
+
 	r.loaded[name] = db
 	return db, nil
 }
 
+// closeWAL closes the write-ahead log of a database, if it has one
+// Must be called with the registry lock held.
+func (r *Registry) closeWAL(name string) {
+	if log, ok := r.wals[name]; ok {
+		if err := log.Close(); err != nil {
+			slog.Error("failed to close WAL", "database", name, "error", err)
+		}
+		delete(r.wals, name)
+	}
+}
+
+// lock takes the directory lock of a database unless the registry holds it
+// already or is read-only
+// Must be called with the registry lock held.
+func (r *Registry) lock(name string) error {
+	if r.readOnly || r.locked[name] {
+		return nil
+	}
+	held, err := lockDir(filepath.Join(r.basePath, name))
+	if err != nil {
+		return err
+	}
+	if held {
+		r.locked[name] = true
+	}
+	return nil
+}
+
+// unlock releases the directory lock of a database
+// Must be called with the registry lock held.
+func (r *Registry) unlock(name string) {
+	if r.locked[name] {
+		unlockDir(filepath.Join(r.basePath, name))
+		delete(r.locked, name)
+	}
+}
+
 // Create creates a new database
 func (r *Registry) Create(name string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.readOnly {
+		return ErrReadOnly
+	}
+
 	if _, ok := r.loaded[name]; ok {
 		return fmt.Errorf("database '%s' already exists (loaded)", name)
 	}
@@ -67,11 +181,66 @@ func (r *Registry) Create(name string) error {
 	return r.storageEngine.CreateDatabase(name, r.basePath)
 }
 
+// CreateWithStorage creates a new database stored in the given format
+func (r *Registry) CreateWithStorage(name, storage string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readOnly {
+		return ErrReadOnly
+	}
+
+	selector, ok := r.storageEngine.(engine.StorageSelector)
+	if !ok {
+		return fmt.Errorf("storage engine does not support choosing a storage format")
+	}
+	if _, ok := r.loaded[name]; ok {
+		return fmt.Errorf("database '%s' already exists (loaded)", name)
+	}
+
+	return selector.CreateDatabaseAs(name, r.basePath, storage)
+}
+
+// SetStorage converts a database to the given storage format, loading it
+// if needed
+func (r *Registry) SetStorage(name, storage string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readOnly {
+		return ErrReadOnly
+	}
+
+	selector, ok := r.storageEngine.(engine.StorageSelector)
+	if !ok {
+		return fmt.Errorf("storage engine does not support choosing a storage format")
+	}
+
+	db, err := r.get(name)
+	if err != nil {
+		return err
+	}
+
+	tx := transaction.NewTransaction()
+	defer tx.Close()
+	return selector.ConvertDatabase(db, storage, tx)
+}
+
 // Drop unloads and deletes a database
+// A database another process has loaded is left alone.
 func (r *Registry) Drop(name string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.readOnly {
+		return ErrReadOnly
+	}
+	if err := r.lock(name); err != nil {
+		return err
+	}
+	defer r.unlock(name)
+
+	r.closeWAL(name)
 	delete(r.loaded, name)
 	return r.storageEngine.DropDatabase(name, r.basePath)
 }
@@ -81,6 +250,14 @@ func (r *Registry) Rename(oldName, newName string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.readOnly {
+		return ErrReadOnly
+	}
+	if err := r.lock(oldName); err != nil {
+		return err
+	}
+	defer r.unlock(oldName)
+
 	// If loaded, we must unload/save
 	if db, ok := r.loaded[oldName]; ok {
 		// Create a transaction for the save operation
@@ -90,16 +267,290 @@ func (r *Registry) Rename(oldName, newName string) error {
 		if err := r.storageEngine.SaveDatabase(db, tx); err != nil {
 			return fmt.Errorf("failed to save database before rename: %w", err)
 		}
+		r.closeWAL(oldName)
 		delete(r.loaded, oldName)
 	}
 
-	return r.storageEngine.RenameDatabase(oldName, newName, r.basePath)
+	if err := r.storageEngine.RenameDatabase(oldName, newName, r.basePath); err != nil {
+		return err
+	}
+	// The archive follows the database, whose LSNs it continues
+	if r.walArchive != "" {
+		oldArchive := filepath.Join(r.walArchive, oldName)
+		if _, err := os.Stat(oldArchive); err == nil {
+			if err := os.Rename(oldArchive, filepath.Join(r.walArchive, newName)); err != nil {
+				return fmt.Errorf("database renamed but its WAL archive was not: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateTable adds a new, empty table with the given schema to a loaded
+// database and saves it
+// The table map of the database is replaced rather than changed in place,
+// so statements already reading it are not disturbed.
+func (r *Registry) CreateTable(db *schema.Database, tableSchema *schema.TableSchema) (*schema.Table, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readOnly {
+		return nil, ErrReadOnly
+	}
+	name := tableSchema.TableName
+	if _, ok := db.Tables[name]; ok {
+		return nil, fmt.Errorf("table '%s' already exists", name)
+	}
+
+	tablePath := filepath.Join(db.Path, name)
+	if err := os.Mkdir(tablePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create table directory: %w", err)
+	}
+	table := &schema.Table{
+		Name:    name,
+		Path:    tablePath,
+		Schema:  tableSchema,
+		Rows:    []data.Row{},
+		Indexes: make(map[string]*data.Index),
+	}
+	if err := indexing.BuildIndexes(table); err != nil {
+		os.RemoveAll(tablePath)
+		return nil, err
+	}
+	table.MarkDirty()
+
+	previous := db.Tables
+	db.Tables = withTable(previous, name, table)
+
+	tx := transaction.NewTransaction()
+	defer tx.Close()
+	if err := r.storageEngine.SaveDatabase(db, tx); err != nil {
+		db.Tables = previous
+		os.RemoveAll(tablePath)
+		return nil, fmt.Errorf("failed to save new table: %w", err)
+	}
+
+	slog.Info("table created", slog.String("database", db.Name), slog.String("table", name))
+	return table, nil
 }
 
-// SaveAll saves all currently loaded databases
+// DropTable removes a table from a loaded database and deletes its files
+func (r *Registry) DropTable(db *schema.Database, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readOnly {
+		return ErrReadOnly
+	}
+	table, ok := db.Tables[name]
+	if !ok {
+		return fmt.Errorf("table '%s' not found", name)
+	}
+
+	db.Tables = withTable(db.Tables, name, nil)
+	tx := transaction.NewTransaction()
+	defer tx.Close()
+	if err := r.storageEngine.SaveDatabase(db, tx); err != nil {
+		return fmt.Errorf("failed to save database without table: %w", err)
+	}
+	if err := os.RemoveAll(table.Path); err != nil {
+		return fmt.Errorf("failed to remove table directory: %w", err)
+	}
+
+	slog.Info("table dropped", slog.String("database", db.Name), slog.String("table", name))
+	return nil
+}
+
+// withTable returns a copy of tables with name set to table, or removed if
+// table is nil
+func withTable(tables map[string]*schema.Table, name string, table *schema.Table) map[string]*schema.Table {
+	copied := make(map[string]*schema.Table, len(tables)+1)
+	for n, t := range tables {
+		copied[n] = t
+	}
+	if table == nil {
+		delete(copied, name)
+	} else {
+		copied[name] = table
+	}
+	return copied
+}
+
+// Backup writes a consistent snapshot of a database, loading it if needed,
+// to the directory dir
+func (r *Registry) Backup(name, dir string) (*backup.Manifest, error) {
+	r.mu.Lock()
+	db, err := r.get(name)
+	log := r.wals[name]
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if log == nil {
+		return backup.Create(db, dir, nil)
+	}
+
+	// With no writer between changing data and logging it, the backup holds
+	// exactly the changes logged up to the LSN it records
+	log.LockWriter()
+	defer log.UnlockWriter()
+	return backup.Create(db, dir, func() uint64 { return log.NextLSN() - 1 })
+}
+
+// Restore creates the database name from the backup in dir and loads it
+// The backup's manifest and checksums are verified before anything is
+// written. A database backed up in page storage is converted back to it.
+func (r *Registry) Restore(name, dir string) (*backup.Manifest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readOnly {
+		return nil, ErrReadOnly
+	}
+	if _, ok := r.loaded[name]; ok {
+		return nil, fmt.Errorf("database '%s' already exists (loaded)", name)
+	}
+
+	dbPath := filepath.Join(r.basePath, name)
+	manifest, err := backup.Restore(dir, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := r.get(name)
+	if err != nil {
+		os.RemoveAll(dbPath)
+		return nil, fmt.Errorf("failed to load restored database: %w", err)
+	}
+
+	// Backups hold JSON tables whatever the format of the database
+	if manifest.Storage != "" && manifest.Storage != engine.StorageJSON {
+		selector, ok := r.storageEngine.(engine.StorageSelector)
+		if !ok {
+			slog.Warn("restored database kept in json storage",
+				slog.String("database", name),
+				slog.String("backup_storage", manifest.Storage))
+			return manifest, nil
+		}
+		tx := transaction.NewTransaction()
+		defer tx.Close()
+		if err := selector.ConvertDatabase(db, manifest.Storage, tx); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// RestoreUntil creates the database name from the base backup in dir, like
+// Restore, then replays the WAL segments, in log order, up to target and
+// saves the result
+// The backup must have been taken with the WAL enabled: replay starts after
+// the LSN its manifest records. The log is read in full before anything is
+// written, and a replay that fails removes the restored database again.
+func (r *Registry) RestoreUntil(name, dir string, segments []string, target wal.RecoveryTarget) (*backup.Manifest, *wal.RecoveryResult, error) {
+	manifest, err := backup.Verify(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.WALLSN == nil {
+		return nil, nil, fmt.Errorf("backup of '%s' was taken without the WAL enabled, so no log continues it", manifest.Database)
+	}
+	if target.LSN != 0 && target.LSN < *manifest.WALLSN {
+		return nil, nil, fmt.Errorf("target LSN %d is before the backup, taken at LSN %d", target.LSN, *manifest.WALLSN)
+	}
+	if !target.Time.IsZero() && target.Time.Before(manifest.CreatedAt) {
+		return nil, nil, fmt.Errorf("target time %s is before the backup, taken at %s",
+			target.Time.Format(time.RFC3339), manifest.CreatedAt.Format(time.RFC3339))
+	}
+
+	recovery := wal.NewSegmentRecoveryManager(segments, filepath.Join(r.basePath, name))
+	defer recovery.Close()
+	result, err := recovery.RecoverUntil(*manifest.WALLSN, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := r.Restore(name, dir); err != nil {
+		return nil, nil, err
+	}
+	db, err := r.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := result.ReplayAll(archive.NewTarget(db)); err != nil {
+		if dropErr := r.Drop(name); dropErr != nil {
+			slog.Error("failed to remove partly recovered database", "database", name, "error", dropErr)
+		}
+		return nil, nil, err
+	}
+
+	tx := transaction.NewTransaction()
+	defer tx.Close()
+	if err := r.storageEngine.SaveDatabase(db, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to save recovered database: %w", err)
+	}
+
+	slog.Info("Database recovered to a point in time",
+		slog.String("database", name),
+		slog.Uint64("backup_lsn", *manifest.WALLSN),
+		slog.Uint64("stop_lsn", result.StopLSN),
+		slog.Int("transactions", result.TransactionsReplay))
+	return manifest, result, nil
+}
+
+// Check verifies the files of a database and its WAL, repairing what is
+// safe to if repair is set (see integrity.Check)
+// A loaded database is checked as it was last saved, with its WAL writer
+// held so no record is half written. It cannot be repaired: its next save
+// would write its counters back. A repair holds the database's lock, so no
+// other process can load it meanwhile.
+func (r *Registry) Check(name string, repair bool) (*integrity.Report, error) {
+	r.mu.Lock()
+	dbPath := filepath.Join(r.basePath, name)
+	opts := integrity.Options{Repair: repair}
+	if r.walArchive != "" {
+		opts.ArchiveDir = filepath.Join(r.walArchive, name)
+	}
+
+	if _, ok := r.loaded[name]; ok {
+		log := r.wals[name]
+		r.mu.Unlock()
+		if repair {
+			return nil, fmt.Errorf("database '%s' is in use; repair it while no session has it loaded", name)
+		}
+		if log != nil {
+			log.LockWriter()
+			defer log.UnlockWriter()
+		}
+		return integrity.Check(dbPath, opts)
+	}
+	defer r.mu.Unlock()
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("database '%s' does not exist", name)
+	}
+	if repair {
+		if r.readOnly {
+			return nil, ErrReadOnly
+		}
+		if err := r.lock(name); err != nil {
+			return nil, err
+		}
+		defer r.unlock(name)
+	}
+	return integrity.Check(dbPath, opts)
+}
+
+// SaveAll saves the changes of all currently loaded databases
+// A read-only registry saves nothing.
 func (r *Registry) SaveAll(tx *transaction.Transaction) {
+	if r.readOnly {
+		return
+	}
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
 
 	for _, db := range r.loaded {
 		if err := r.storageEngine.SaveDatabase(db, tx); err != nil {
@@ -108,6 +559,43 @@ func (r *Registry) SaveAll(tx *transaction.Transaction) {
 	}
 }
 
+// FlushEvery saves the changes of the loaded databases every interval until
+// ctx is done
+// It runs in the background while the server or REPL serves queries, so a
+// crash loses at most one interval of changes.
+func (r *Registry) FlushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("background flusher started", "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tx := transaction.NewTransaction()
+			r.SaveAll(tx)
+			tx.Close()
+		}
+	}
+}
+
+// Close unloads every database, closing their write-ahead logs, and
+// releases their directory locks
+// Changes not saved yet are lost; call SaveAll first.
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name := range r.wals {
+		r.closeWAL(name)
+	}
+	for name := range r.locked {
+		r.unlock(name)
+	}
+	r.loaded = make(map[string]*schema.Database)
+}
+
 // List returns a list of all available databases
 func (r *Registry) List() ([]string, error) {
 	return r.storageEngine.ListDatabases(r.basePath)
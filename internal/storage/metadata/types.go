@@ -1,9 +1,12 @@
 package metadata
 
+import "time"
+
 // DatabaseMeta represents the database-level metadata from meta.json
 type DatabaseMeta struct {
 	Name    string   `json:"name"`
 	Version int      `json:"version"`
+	Storage string   `json:"storage,omitempty"` // "json" when empty, or "page"
 	Tables  []string `json:"tables,omitempty"`
 }
 
@@ -13,6 +16,7 @@ type TableMeta struct {
 	Columns      []ColumnMeta `json:"columns"`
 	LastInsertID int64        `json:"last_insert_id,omitempty"`
 	RowCount     int64        `json:"row_count,omitempty"`
+	Indexes      []IndexMeta  `json:"indexes,omitempty"`
 }
 
 // ColumnMeta represents column metadata for JSON serialization
@@ -24,3 +28,27 @@ type ColumnMeta struct {
 	NotNull       bool   `json:"not_null"`
 	AutoIncrement bool   `json:"auto_increment,omitempty"`
 }
+
+// IndexMeta represents a user-defined index for JSON serialization
+type IndexMeta struct {
+	Name   string `json:"name"`
+	Column string `json:"column"`
+	Type   string `json:"type"` // HASH or BTREE
+	Unique bool   `json:"unique,omitempty"`
+}
+
+// TableStatsMeta represents planner statistics from stats.json (written by ANALYZE)
+type TableStatsMeta struct {
+	RowCount   int64                      `json:"row_count"`
+	AnalyzedAt time.Time                  `json:"analyzed_at"`
+	Columns    map[string]ColumnStatsMeta `json:"columns"`
+}
+
+// ColumnStatsMeta represents per-column statistics for JSON serialization
+type ColumnStatsMeta struct {
+	DistinctCount int64         `json:"distinct_count"`
+	NullFraction  float64       `json:"null_fraction"`
+	Min           interface{}   `json:"min,omitempty"`
+	Max           interface{}   `json:"max,omitempty"`
+	Histogram     []interface{} `json:"histogram,omitempty"`
+}
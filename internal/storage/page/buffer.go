@@ -0,0 +1,292 @@
+package page
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultBufferPages is the default page budget of a buffer pool (8 MiB)
+const DefaultBufferPages = 1024
+
+// BufferPool caches the data pages of heap files within a fixed budget
+//
+// A page is pinned while it is used and may only be evicted once every pin
+// is released. Eviction follows the clock algorithm: the hand sweeps the
+// frames, giving pages referenced since its last pass a second chance, and
+// evicts the first unpinned page that was not. An evicted dirty page is
+// written back to its file first; Flush writes the dirty pages of a file
+// without evicting them.
+type BufferPool struct {
+	mu     sync.Mutex
+	frames []frame
+	lookup map[pageKey]int // frame of each cached page
+	hand   int
+	files  map[string]*os.File
+	stats  PoolStats
+}
+
+// PoolStats counts the work of a buffer pool
+type PoolStats struct {
+	Hits       int64 // fetches served from the pool
+	Misses     int64 // fetches that read the page from disk
+	Evictions  int64
+	WriteBacks int64 // dirty pages written to disk
+	Resident   int   // pages currently cached
+}
+
+type pageKey struct {
+	path string
+	id   uint32
+}
+
+type frame struct {
+	key   pageKey
+	page  *Page
+	pins  int
+	dirty bool
+	ref   bool // referenced since the clock hand last passed
+	used  bool
+}
+
+// NewBufferPool creates a pool caching at most budget pages
+func NewBufferPool(budget int) *BufferPool {
+	return &BufferPool{
+		frames: make([]frame, max(budget, 1)),
+		lookup: make(map[pageKey]int),
+		files:  make(map[string]*os.File),
+	}
+}
+
+// Budget returns the number of pages the pool holds at most
+func (p *BufferPool) Budget() int {
+	return len(p.frames)
+}
+
+// Stats returns the pool's counters
+func (p *BufferPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Resident = len(p.lookup)
+	return stats
+}
+
+// Fetch returns a data page of a heap file, pinned, reading and verifying
+// it on a miss
+// Every Fetch must be paired with an Unpin.
+func (p *BufferPool) Fetch(path string, id uint32) (*Page, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pageKey{path, id}
+	if i, ok := p.lookup[key]; ok {
+		p.stats.Hits++
+		return p.pin(i), nil
+	}
+	p.stats.Misses++
+
+	i, err := p.victim()
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.file(path)
+	if err != nil {
+		return nil, err
+	}
+	page := p.frames[i].page
+	if page == nil {
+		page = new(Page)
+	}
+	if _, err := f.ReadAt(page[:], int64(id)*Size); err != nil {
+		return nil, fmt.Errorf("%s: failed to read page %d: %w", path, id, err)
+	}
+	if err := page.Verify(id); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if page.kind() != kindData {
+		return nil, fmt.Errorf("%s: page %d is not a data page", path, id)
+	}
+
+	p.install(i, key, page)
+	return p.pin(i), nil
+}
+
+// NewPage returns a new empty data page of a heap file, pinned and dirty,
+// without reading the file
+func (p *BufferPool) NewPage(path string, id uint32) (*Page, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pageKey{path, id}
+	if _, ok := p.lookup[key]; ok {
+		return nil, fmt.Errorf("%s: page %d is already cached", path, id)
+	}
+	i, err := p.victim()
+	if err != nil {
+		return nil, err
+	}
+	p.install(i, key, NewDataPage(id))
+	p.frames[i].dirty = true
+	return p.pin(i), nil
+}
+
+// Unpin releases a pin taken by Fetch or NewPage; dirty marks the page as
+// changed, to be written back before it is evicted
+func (p *BufferPool) Unpin(path string, id uint32, dirty bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i, ok := p.lookup[pageKey{path, id}]
+	if !ok || p.frames[i].pins == 0 {
+		panic(fmt.Sprintf("buffer pool: unpin of page %d of %s, which is not pinned", id, path))
+	}
+	p.frames[i].pins--
+	p.frames[i].dirty = p.frames[i].dirty || dirty
+}
+
+// Flush writes the dirty pages of a file and syncs it, returning the number
+// of pages written
+func (p *BufferPool) Flush(path string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	written := 0
+	for i := range p.frames {
+		fr := &p.frames[i]
+		if !fr.used || fr.key.path != path || !fr.dirty {
+			continue
+		}
+		if err := p.writeBack(fr); err != nil {
+			return written, err
+		}
+		written++
+	}
+	if f, ok := p.files[path]; ok {
+		if err := f.Sync(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Truncate drops the cached pages of a file numbered above count without
+// writing them, for a heap that gave its last pages back
+func (p *BufferPool) Truncate(path string, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drop(func(key pageKey) bool { return key.path == path && key.id > uint32(count) })
+}
+
+// Invalidate drops cached pages of a file that were written to it without
+// going through the pool, so they are read again
+func (p *BufferPool) Invalidate(path string, ids []uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	written := make(map[uint32]bool, len(ids))
+	for _, id := range ids {
+		written[id] = true
+	}
+	p.drop(func(key pageKey) bool { return key.path == path && written[key.id] })
+}
+
+// Discard drops every cached page of the files under a path without
+// writing them and closes the files, for a database that is dropped,
+// renamed or converted
+func (p *BufferPool) Discard(prefix string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	under := func(path string) bool {
+		return path == prefix || len(path) > len(prefix) && path[:len(prefix)] == prefix && os.IsPathSeparator(path[len(prefix)])
+	}
+	p.drop(func(key pageKey) bool { return under(key.path) })
+	for path, f := range p.files {
+		if under(path) {
+			f.Close()
+			delete(p.files, path)
+		}
+	}
+}
+
+// drop frees the unpinned frames whose key matches
+func (p *BufferPool) drop(match func(pageKey) bool) {
+	for i := range p.frames {
+		fr := &p.frames[i]
+		if fr.used && match(fr.key) && fr.pins == 0 {
+			delete(p.lookup, fr.key)
+			fr.used, fr.dirty, fr.ref = false, false, false
+		}
+	}
+}
+
+func (p *BufferPool) pin(i int) *Page {
+	p.frames[i].pins++
+	p.frames[i].ref = true
+	return p.frames[i].page
+}
+
+func (p *BufferPool) install(i int, key pageKey, page *Page) {
+	p.frames[i] = frame{key: key, page: page, used: true}
+	p.lookup[key] = i
+}
+
+// victim returns a free frame, evicting a page if the pool is full
+func (p *BufferPool) victim() (int, error) {
+	// Two passes clear every reference bit, a third finds nothing new
+	for range 3 * len(p.frames) {
+		i := p.hand
+		p.hand = (p.hand + 1) % len(p.frames)
+
+		fr := &p.frames[i]
+		if !fr.used {
+			return i, nil
+		}
+		if fr.pins > 0 {
+			continue
+		}
+		if fr.ref {
+			fr.ref = false
+			continue
+		}
+
+		if fr.dirty {
+			if err := p.writeBack(fr); err != nil {
+				return 0, err
+			}
+		}
+		delete(p.lookup, fr.key)
+		fr.used = false
+		p.stats.Evictions++
+		return i, nil
+	}
+	return 0, fmt.Errorf("buffer pool: all %d pages are pinned", len(p.frames))
+}
+
+// writeBack writes a dirty page to its file
+func (p *BufferPool) writeBack(fr *frame) error {
+	f, err := p.file(fr.key.path)
+	if err != nil {
+		return err
+	}
+	fr.page.Seal()
+	if _, err := f.WriteAt(fr.page[:], int64(fr.key.id)*Size); err != nil {
+		return fmt.Errorf("%s: failed to write page %d: %w", fr.key.path, fr.key.id, err)
+	}
+	fr.dirty = false
+	p.stats.WriteBacks++
+	return nil
+}
+
+// file returns the open file of a heap, opening it on first use
+func (p *BufferPool) file(path string) (*os.File, error) {
+	if f, ok := p.files[path]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	p.files[path] = f
+	return f, nil
+}
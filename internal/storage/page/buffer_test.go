@@ -0,0 +1,124 @@
+package page
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fillPool creates pages 1..count of a file through pool, each holding its
+// id as a tuple
+func fillPool(t *testing.T, pool *BufferPool, path string, count int) {
+	t.Helper()
+	for id := uint32(1); id <= uint32(count); id++ {
+		p, err := pool.NewPage(path, id)
+		if err != nil {
+			t.Fatalf("NewPage %d failed: %v", id, err)
+		}
+		p.Insert([]byte{byte(id)})
+		pool.Unpin(path, id, true)
+	}
+}
+
+func TestBufferPoolEviction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	pool := NewBufferPool(3)
+	fillPool(t, pool, path, 8)
+
+	stats := pool.Stats()
+	if stats.Resident != 3 || stats.Evictions != 5 || stats.WriteBacks != 5 {
+		t.Fatalf("Expected 3 resident pages after 5 evictions written back, got %+v", stats)
+	}
+
+	// Evicted pages are read back from disk with their content
+	for id := uint32(1); id <= 8; id++ {
+		p, err := pool.Fetch(path, id)
+		if err != nil {
+			t.Fatalf("Fetch %d failed: %v", id, err)
+		}
+		if got := p.Tuple(0); len(got) != 1 || got[0] != byte(id) {
+			t.Errorf("Expected page %d to hold its id, got %v", id, got)
+		}
+		pool.Unpin(path, id, false)
+	}
+	if stats := pool.Stats(); stats.Resident > 3 {
+		t.Errorf("Expected at most 3 resident pages, got %d", stats.Resident)
+	}
+}
+
+func TestBufferPoolClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	pool := NewBufferPool(3)
+	fillPool(t, pool, path, 4) // page 4 evicted page 1, clearing every reference
+
+	// Page 2 is used again, so the next eviction passes it over for page 3
+	pool.Fetch(path, 2)
+	pool.Unpin(path, 2, false)
+	if _, err := pool.NewPage(path, 5); err != nil {
+		t.Fatalf("NewPage failed: %v", err)
+	}
+	pool.Unpin(path, 5, true)
+
+	before := pool.Stats()
+	pool.Fetch(path, 2)
+	pool.Unpin(path, 2, false)
+	if after := pool.Stats(); after.Hits != before.Hits+1 {
+		t.Errorf("Expected the recently used page to stay cached, got %+v", after)
+	}
+	pool.Fetch(path, 3)
+	pool.Unpin(path, 3, false)
+	if after := pool.Stats(); after.Misses != before.Misses+1 {
+		t.Errorf("Expected the unused page to be evicted, got %+v", after)
+	}
+}
+
+func TestBufferPoolPins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	pool := NewBufferPool(2)
+	fillPool(t, pool, path, 2)
+
+	// Pinned pages are never evicted
+	pool.Fetch(path, 1)
+	pool.Fetch(path, 2)
+	if _, err := pool.NewPage(path, 3); err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Fatalf("Expected an error with every page pinned, got %v", err)
+	}
+
+	pool.Unpin(path, 2, false)
+	if _, err := pool.NewPage(path, 3); err != nil {
+		t.Fatalf("Expected the unpinned page to make room, got %v", err)
+	}
+	pool.Unpin(path, 3, true)
+	pool.Unpin(path, 1, false)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected unpinning an unpinned page to panic")
+		}
+	}()
+	pool.Unpin(path, 1, false)
+}
+
+func TestBufferPoolFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	pool := NewBufferPool(4)
+	fillPool(t, pool, path, 3)
+
+	if written, err := pool.Flush(path); err != nil || written != 3 {
+		t.Fatalf("Expected 3 dirty pages written, got %d (%v)", written, err)
+	}
+	if written, _ := pool.Flush(path); written != 0 {
+		t.Errorf("Expected clean pages not to be written again, got %d", written)
+	}
+
+	// Dropped pages are read from disk again, as written
+	pool.Discard(filepath.Dir(path))
+	if stats := pool.Stats(); stats.Resident != 0 {
+		t.Fatalf("Expected no resident pages after Discard, got %d", stats.Resident)
+	}
+	p, err := pool.Fetch(path, 3)
+	if err != nil || p.Tuple(0)[0] != 3 {
+		t.Fatalf("Expected page 3 back from disk, got %v", err)
+	}
+	pool.Unpin(path, 3, false)
+}
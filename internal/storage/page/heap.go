@@ -0,0 +1,529 @@
+package page
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+)
+
+// FileName is the name of a table's heap file in its directory
+const FileName = "data.pages"
+
+// Header page layout, after the common page header
+const (
+	offMagic     = headerSize      // [8]byte
+	offVersion   = headerSize + 8  // uint16
+	offPageSize  = headerSize + 12 // uint32
+	offPageCount = headerSize + 16 // uint32, data pages
+	offRowCount  = headerSize + 20 // uint64
+)
+
+const version = 1
+
+var magic = []byte("JOYPAGES")
+
+// RID identifies a tuple: a data page and a slot in it
+type RID struct {
+	Page uint32
+	Slot int
+}
+
+// Heap is the data file of a table stored in pages
+//
+// Page 0 is a header holding the format version, page size, page count and
+// row count; data pages follow. Data pages are read through a buffer pool,
+// so a heap is scanned with only the pool's budget of pages in memory.
+//
+// The first save reads every page once to learn where each tuple lives and
+// how much space each page has free; from then on a save only inserts the
+// rows that are new, deletes the ones that are gone and writes the pages
+// that changed - appending a row rewrites one page, not the whole table.
+// The changed pages go to a journal first (see JournalPath), so a save is
+// on disk whole or not at all. Tuples
+// have no identity beyond their content: a row updated in place is a delete
+// plus an insert, and rows come back in page order, which after a delete can
+// differ from the order they were saved in.
+type Heap struct {
+	mu      sync.RWMutex // held for reading by cursors, for writing by saves
+	path    string
+	pool    *BufferPool
+	columns []schema.Column
+	count   int // data pages; page i has ID i, from 1
+	rows    int
+
+	// Known once Save has read every page
+	seed   maphash.Seed
+	tuples map[uint64][]RID // where the tuples of each content hash live
+	free   []int            // free space of each data page, free[i] for page i+1
+
+	pending *staged // staged by Stage, until Apply
+}
+
+// OpenHeap opens a heap file reading its pages through pool; a missing file
+// is an empty heap
+// Only the header is read and verified here, data pages as they are read. A
+// heap whose journal is still there is refused until Recover applies it.
+func OpenHeap(path string, pool *BufferPool, columns []schema.Column) (*Heap, error) {
+	h := &Heap{path: path, pool: pool, columns: columns, seed: maphash.MakeSeed()}
+
+	if _, err := os.Stat(JournalPath(path)); err == nil {
+		return nil, fmt.Errorf("%s: a save was interrupted and its journal is not applied", path)
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < Size {
+		return nil, fmt.Errorf("%s: file is shorter than its header page", path)
+	}
+	header := new(Page)
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		return nil, err
+	}
+	if err := verifyHeader(header, int(info.Size())); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	h.count = int(binary.LittleEndian.Uint32(header[offPageCount:]))
+	h.rows = int(binary.LittleEndian.Uint64(header[offRowCount:]))
+	return h, nil
+}
+
+func verifyHeader(header *Page, fileSize int) error {
+	if err := header.Verify(0); err != nil {
+		return err
+	}
+	if header.kind() != kindHeader || !bytes.Equal(header[offMagic:offMagic+len(magic)], magic) {
+		return fmt.Errorf("not a page file")
+	}
+	if v := binary.LittleEndian.Uint16(header[offVersion:]); v != version {
+		return fmt.Errorf("unsupported page file version %d", v)
+	}
+	if size := binary.LittleEndian.Uint32(header[offPageSize:]); size != Size {
+		return fmt.Errorf("page size is %d, expected %d", size, Size)
+	}
+	count := int(binary.LittleEndian.Uint32(header[offPageCount:]))
+	if fileSize != (count+1)*Size {
+		return fmt.Errorf("file has %d bytes, expected %d pages of %d", fileSize, count+1, Size)
+	}
+	return nil
+}
+
+// Len returns the number of rows in the heap
+func (h *Heap) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rows
+}
+
+// PageCount returns the number of data pages
+func (h *Heap) PageCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.count
+}
+
+// Cursor returns a cursor reading the rows of the heap in page and slot
+// order, one page at a time
+func (h *Heap) Cursor() schema.RowCursor {
+	return &Cursor{heap: h, next: 1}
+}
+
+// Rows decodes every row of the heap, in page and slot order
+func (h *Heap) Rows() ([]data.Row, error) {
+	rows := make([]data.Row, 0, h.Len())
+	c := h.Cursor()
+	defer c.Close()
+	for {
+		row, ok, err := c.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return rows, nil
+		}
+		rows = append(rows, row)
+	}
+}
+
+// Cursor reads the rows of a heap
+// It holds no pin between calls: each page is decoded whole and released, so
+// a scan that is abandoned part way leaves nothing behind. A page is read as
+// it is when the cursor reaches it, so a scan running while the heap is
+// saved can see some pages before the save and some after.
+type Cursor struct {
+	heap *Heap
+	next uint32 // next page to read
+	rows []data.Row
+	pos  int
+}
+
+// Next returns the next row; ok is false once the heap is exhausted
+func (c *Cursor) Next() (data.Row, bool, error) {
+	for c.pos >= len(c.rows) {
+		more, err := c.read()
+		if err != nil || !more {
+			return data.Row{}, false, err
+		}
+	}
+	row := c.rows[c.pos]
+	c.pos++
+	return row, true, nil
+}
+
+// Close releases the rows of the current page
+func (c *Cursor) Close() {
+	c.rows, c.pos = nil, 0
+	c.next = ^uint32(0)
+}
+
+// read decodes the rows of the next page
+func (c *Cursor) read() (bool, error) {
+	h := c.heap
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if c.next == 0 || int(c.next) > h.count {
+		return false, nil
+	}
+	id := c.next
+	c.next++
+
+	p, err := h.pool.Fetch(h.path, id)
+	if err != nil {
+		return false, err
+	}
+	defer h.pool.Unpin(h.path, id, false)
+
+	c.rows, c.pos = c.rows[:0], 0
+	for slot := 0; slot < p.SlotCount(); slot++ {
+		tuple := p.Tuple(slot)
+		if tuple == nil {
+			continue
+		}
+		row, err := DecodeRow(h.columns, tuple)
+		if err != nil {
+			return false, fmt.Errorf("%s: page %d slot %d: %w", h.path, id, slot, err)
+		}
+		c.rows = append(c.rows, row)
+	}
+	return true, nil
+}
+
+// Save makes rows the content of the heap on its own: it stages the save,
+// commits the journal next to the heap file and applies it. Returns the
+// number of data pages written.
+// A table saves its heap with Stage and Apply instead, committing the
+// journal together with its meta.json.
+func (h *Heap) Save(rows []data.Row) (int, error) {
+	journal, written, err := h.Stage(rows)
+	if err != nil {
+		return 0, err
+	}
+	if err := commitJournal(JournalPath(h.path), journal); err != nil {
+		h.drop()
+		return 0, fmt.Errorf("%s: %w", h.path, err)
+	}
+	if err := h.Apply(); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// staged is a save prepared by Stage: the new images of the data pages that
+// change and the state of the heap once they are written
+type staged struct {
+	pages  map[uint32]*Page
+	count  int
+	rows   int
+	tuples map[uint64][]RID
+	free   []int
+}
+
+// Stage prepares making rows the content of the heap and returns its
+// journal - the new header page followed by the data pages that change -
+// and the number of data pages it writes
+// Nothing is written: the pool keeps the pages as they are in the file, so
+// neither eviction nor a crash can put part of the save on disk. Once the
+// caller has committed the journal at JournalPath, Apply writes the pages
+// in place; until then the heap reads as before.
+func (h *Heap) Stage(rows []data.Row) ([]byte, int, error) {
+	tuples := make([][]byte, len(rows))
+	wanted := make(map[uint64][]int, len(rows)) // new tuples by hash
+	for i, row := range rows {
+		tuple, err := EncodeRow(h.columns, row)
+		if err != nil {
+			return nil, 0, fmt.Errorf("row %d: %w", i, err)
+		}
+		if len(tuple) > MaxTuple {
+			return nil, 0, fmt.Errorf("row %d takes %d bytes, more than a page holds (%d)", i, len(tuple), MaxTuple)
+		}
+		tuples[i] = tuple
+		key := maphash.Bytes(h.seed, tuple)
+		wanted[key] = append(wanted[key], i)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending != nil {
+		return nil, 0, fmt.Errorf("%s: a staged save has not been applied", h.path)
+	}
+	if err := h.index(); err != nil {
+		return nil, 0, err
+	}
+	s := &staged{
+		pages:  make(map[uint32]*Page),
+		count:  h.count,
+		rows:   h.rows,
+		tuples: make(map[uint64][]RID, len(h.tuples)),
+		free:   slices.Clone(h.free),
+	}
+
+	// Keep the tuples still wanted where they are and delete the others;
+	// hashes only find candidates, the bytes decide
+	for key, rids := range h.tuples {
+		candidates := wanted[key]
+		kept := make([]RID, 0, len(rids))
+		for _, rid := range rids {
+			match, err := h.match(s, rid, candidates, tuples)
+			if err != nil {
+				return nil, 0, err
+			}
+			if match >= 0 {
+				candidates[match] = candidates[len(candidates)-1]
+				candidates = candidates[:len(candidates)-1]
+				kept = append(kept, rid)
+				continue
+			}
+			p, err := h.image(s, rid.Page)
+			if err != nil {
+				return nil, 0, err
+			}
+			p.Delete(rid.Slot)
+			s.free[rid.Page-1] = p.FreeSpace()
+			s.rows--
+		}
+		wanted[key] = candidates
+		if len(kept) > 0 {
+			s.tuples[key] = kept
+		}
+	}
+
+	insert := make([]bool, len(tuples))
+	for _, candidates := range wanted {
+		for _, i := range candidates {
+			insert[i] = true
+		}
+	}
+
+	// Insert the new ones first-fit, from a cursor that only moves forward
+	// so a save stays linear in the number of pages
+	cursor := 0
+	for i, tuple := range tuples {
+		if !insert[i] {
+			continue
+		}
+		for cursor < s.count && s.free[cursor] < len(tuple) {
+			cursor++
+		}
+		id := uint32(cursor + 1)
+		var p *Page
+		if cursor == s.count {
+			p = NewDataPage(id)
+			s.pages[id] = p
+			s.count++
+			s.free = append(s.free, 0)
+		} else {
+			var err error
+			if p, err = h.image(s, id); err != nil {
+				return nil, 0, err
+			}
+		}
+		slot, _ := p.Insert(tuple)
+		s.free[cursor] = p.FreeSpace()
+
+		key := maphash.Bytes(h.seed, tuple)
+		s.tuples[key] = append(s.tuples[key], RID{Page: id, Slot: slot})
+		s.rows++
+	}
+
+	// Give trailing empty pages back to the file system
+	for s.count > 0 && s.free[s.count-1] == MaxTuple {
+		delete(s.pages, uint32(s.count))
+		s.count--
+		s.free = s.free[:s.count]
+	}
+
+	journal := make([]byte, 0, (len(s.pages)+1)*Size)
+	journal = append(journal, newHeader(s.count, s.rows)[:]...)
+	for _, id := range slices.Sorted(maps.Keys(s.pages)) {
+		p := s.pages[id]
+		p.Seal()
+		journal = append(journal, p[:]...)
+	}
+	h.pending = s
+	return journal, len(s.pages), nil
+}
+
+// match returns the candidate whose tuple is the one stored at rid, -1 if
+// none is
+func (h *Heap) match(s *staged, rid RID, candidates []int, tuples [][]byte) (int, error) {
+	p, ok := s.pages[rid.Page]
+	if !ok {
+		var err error
+		if p, err = h.pool.Fetch(h.path, rid.Page); err != nil {
+			return 0, err
+		}
+		defer h.pool.Unpin(h.path, rid.Page, false)
+	}
+	for j, i := range candidates {
+		if bytes.Equal(p.Tuple(rid.Slot), tuples[i]) {
+			return j, nil
+		}
+	}
+	return -1, nil
+}
+
+// image returns the new image of a data page in a staged save, copying the
+// page from the pool the first time it changes
+func (h *Heap) image(s *staged, id uint32) (*Page, error) {
+	if p, ok := s.pages[id]; ok {
+		return p, nil
+	}
+	p, err := h.pool.Fetch(h.path, id)
+	if err != nil {
+		return nil, err
+	}
+	image := new(Page)
+	*image = *p
+	h.pool.Unpin(h.path, id, false)
+	s.pages[id] = image
+	return image, nil
+}
+
+// Apply writes the pages of the staged save in place, then removes its
+// journal, which the caller committed at JournalPath
+// A crash part way leaves the journal, which Recover applies again. On an
+// error the heap forgets what it knows of its pages, to read them again.
+func (h *Heap) Apply() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.pending
+	if s == nil {
+		return nil
+	}
+	h.pending = nil
+
+	ids := slices.Collect(maps.Keys(s.pages))
+	err := writePages(h.path, newHeader(s.count, s.rows), s.pages)
+	if err == nil {
+		err = removeJournal(h.path)
+	}
+	// The pool may hold the old images of the pages written
+	h.pool.Invalidate(h.path, ids)
+	h.pool.Truncate(h.path, s.count)
+	if err != nil {
+		h.tuples, h.free = nil, nil
+		return fmt.Errorf("%s: %w", h.path, err)
+	}
+	h.count, h.rows, h.tuples, h.free = s.count, s.rows, s.tuples, s.free
+	return nil
+}
+
+// drop discards a staged save whose journal was not committed
+func (h *Heap) drop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = nil
+}
+
+// index reads every page to learn where each tuple lives and how much space
+// each page has free, the first time a save is staged
+// Must be called while holding the write lock.
+func (h *Heap) index() error {
+	if h.tuples != nil {
+		return nil
+	}
+	tuples := make(map[uint64][]RID)
+	free := make([]int, h.count)
+	rows := 0
+	for i := range free {
+		id := uint32(i + 1)
+		p, err := h.pool.Fetch(h.path, id)
+		if err != nil {
+			return err
+		}
+		for slot := 0; slot < p.SlotCount(); slot++ {
+			if tuple := p.Tuple(slot); tuple != nil {
+				key := maphash.Bytes(h.seed, tuple)
+				tuples[key] = append(tuples[key], RID{Page: id, Slot: slot})
+				rows++
+			}
+		}
+		free[i] = p.FreeSpace()
+		h.pool.Unpin(h.path, id, false)
+	}
+	if rows != h.rows {
+		return fmt.Errorf("%s: header records %d rows, pages hold %d", h.path, h.rows, rows)
+	}
+	h.tuples, h.free = tuples, free
+	return nil
+}
+
+// newHeader returns the sealed header page of a heap of count data pages
+// holding rows rows
+func newHeader(count, rows int) *Page {
+	header := newPage(0, kindHeader)
+	copy(header[offMagic:], magic)
+	binary.LittleEndian.PutUint16(header[offVersion:], version)
+	binary.LittleEndian.PutUint32(header[offPageSize:], Size)
+	binary.LittleEndian.PutUint32(header[offPageCount:], uint32(count))
+	binary.LittleEndian.PutUint64(header[offRowCount:], uint64(rows))
+	header.Seal()
+	return header
+}
+
+// writePages writes data pages and then the header in place, truncates the
+// file to the header's page count and syncs it
+func writePages(path string, header *Page, pages map[uint32]*Page) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for id, p := range pages {
+		p.Seal()
+		if _, err := f.WriteAt(p[:], int64(id)*Size); err != nil {
+			return fmt.Errorf("failed to write page %d: %w", id, err)
+		}
+	}
+	if _, err := f.WriteAt(header[:], 0); err != nil {
+		return err
+	}
+	count := binary.LittleEndian.Uint32(header[offPageCount:])
+	if err := f.Truncate(int64(count+1) * Size); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,126 @@
+package page
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// JournalName is the name of the journal of a table's heap file in its
+// directory
+const JournalName = FileName + ".journal"
+
+// JournalPath returns the path of the journal of a heap file
+//
+// A journal holds the header page of a save followed by the data pages it
+// changes, each sealed. It is committed whole before any page is written in
+// place - by a rename, alone or together with the table's meta.json - so a
+// crash while the pages are written leaves it to be applied again, and a
+// crash before it is committed leaves the heap as it was.
+func JournalPath(path string) string {
+	return path + ".journal"
+}
+
+// Recover applies the journal a crash left next to a heap file, finishing
+// the save it records; a heap without one is left as it is
+// Loaders call it before opening the heap.
+func Recover(path string) error {
+	journalPath := JournalPath(path)
+	content, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	header, pages, err := readJournal(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", journalPath, err)
+	}
+	if err := writePages(path, header, pages); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := removeJournal(path); err != nil {
+		return err
+	}
+	slog.Warn("applied the journal of an interrupted save",
+		slog.String("path", path),
+		slog.Int("pages", len(pages)))
+	return nil
+}
+
+// readJournal verifies a journal and returns its header and data pages
+func readJournal(content []byte) (*Page, map[uint32]*Page, error) {
+	if len(content) < Size || len(content)%Size != 0 {
+		return nil, nil, fmt.Errorf("journal has %d bytes, not a whole number of pages", len(content))
+	}
+	header := new(Page)
+	copy(header[:], content)
+	count := binary.LittleEndian.Uint32(header[offPageCount:])
+	if err := verifyHeader(header, int(count+1)*Size); err != nil {
+		return nil, nil, err
+	}
+
+	pages := make(map[uint32]*Page, len(content)/Size-1)
+	for off := Size; off < len(content); off += Size {
+		p := new(Page)
+		copy(p[:], content[off:off+Size])
+		id := p.ID()
+		if err := p.Verify(id); err != nil {
+			return nil, nil, err
+		}
+		if p.kind() != kindData || id == 0 || id > count {
+			return nil, nil, fmt.Errorf("page %d is not a data page of the heap", id)
+		}
+		pages[id] = p
+	}
+	return header, pages, nil
+}
+
+// commitJournal puts a journal in place through a synced temp file and a
+// rename
+func commitJournal(path string, journal []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(journal); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// removeJournal removes the journal of a heap file once its pages are in
+// place
+func removeJournal(path string) error {
+	if err := os.Remove(JournalPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory, making the entries renamed and removed in it
+// durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
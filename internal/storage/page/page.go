@@ -0,0 +1,206 @@
+package page
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// Size is the size in bytes of every page of a heap file
+const Size = 8192
+
+// Page layout
+//
+//	0   checksum   uint32  CRC-32C of bytes 4..Size
+//	4   id         uint32  page number in the file
+//	8   kind       uint8
+//	10  slot count uint16
+//	12  upper      uint16  start of the tuple area
+//	16  slot array, 4 bytes per slot: tuple offset and length (uint16 each)
+//
+// Tuples are stored from the end of the page towards the slot array; the
+// bytes between the end of the slot array and upper are free. A deleted
+// slot has length 0 and is reused by the next insert, so slot numbers of the
+// remaining tuples never change.
+const (
+	headerSize = 16
+	slotSize   = 4
+
+	offChecksum = 0
+	offID       = 4
+	offKind     = 8
+	offSlots    = 10
+	offUpper    = 12
+)
+
+// MaxTuple is the largest tuple a page can hold
+const MaxTuple = Size - headerSize - slotSize
+
+// Kinds of pages
+const (
+	kindHeader byte = iota + 1
+	kindData
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Page is one fixed-size page of a heap file
+type Page [Size]byte
+
+// newPage returns an empty page of the given kind
+func newPage(id uint32, kind byte) *Page {
+	p := new(Page)
+	binary.LittleEndian.PutUint32(p[offID:], id)
+	p[offKind] = kind
+	p.setUpper(Size)
+	return p
+}
+
+// NewDataPage returns an empty data page
+func NewDataPage(id uint32) *Page {
+	return newPage(id, kindData)
+}
+
+// ID returns the page number
+func (p *Page) ID() uint32 {
+	return binary.LittleEndian.Uint32(p[offID:])
+}
+
+// SlotCount returns the number of slots, including deleted ones
+func (p *Page) SlotCount() int {
+	return int(binary.LittleEndian.Uint16(p[offSlots:]))
+}
+
+// FreeSpace returns the size of the largest tuple that can be inserted
+func (p *Page) FreeSpace() int {
+	free := p.upper() - p.lower()
+	if p.deadSlot() < 0 {
+		free -= slotSize
+	}
+	return max(free, 0)
+}
+
+// Tuple returns the tuple in a slot, nil if the slot is deleted
+// The tuple shares the page's memory.
+func (p *Page) Tuple(slot int) []byte {
+	if slot < 0 || slot >= p.SlotCount() {
+		return nil
+	}
+	off, length := p.slot(slot)
+	if length == 0 {
+		return nil
+	}
+	return p[off : off+length]
+}
+
+// Insert stores a tuple, returning its slot, or false if it does not fit
+func (p *Page) Insert(tuple []byte) (int, bool) {
+	if len(tuple) == 0 || len(tuple) > p.FreeSpace() {
+		return 0, false
+	}
+	slot := p.deadSlot()
+	if slot < 0 {
+		slot = p.SlotCount()
+		binary.LittleEndian.PutUint16(p[offSlots:], uint16(slot+1))
+	}
+	upper := p.upper() - len(tuple)
+	copy(p[upper:], tuple)
+	p.setUpper(upper)
+	p.setSlot(slot, upper, len(tuple))
+	return slot, true
+}
+
+// Delete removes the tuple in a slot
+// The tuples stored below it move up so the free space stays contiguous.
+func (p *Page) Delete(slot int) {
+	if slot < 0 || slot >= p.SlotCount() {
+		return
+	}
+	off, length := p.slot(slot)
+	if length == 0 {
+		return
+	}
+	upper := p.upper()
+	copy(p[upper+length:off+length], p[upper:off])
+	clear(p[upper : upper+length])
+	for i := 0; i < p.SlotCount(); i++ {
+		if o, l := p.slot(i); l > 0 && o < off {
+			p.setSlot(i, o+length, l)
+		}
+	}
+	p.setSlot(slot, 0, 0)
+	p.setUpper(upper + length)
+
+	// Trailing deleted slots give their space back
+	n := p.SlotCount()
+	for n > 0 {
+		if _, l := p.slot(n - 1); l > 0 {
+			break
+		}
+		n--
+	}
+	binary.LittleEndian.PutUint16(p[offSlots:], uint16(n))
+}
+
+// Seal stores the page checksum; call it before writing the page
+func (p *Page) Seal() {
+	binary.LittleEndian.PutUint32(p[offChecksum:], crc32.Checksum(p[offChecksum+4:], castagnoli))
+}
+
+// Verify checks a page read from disk: its checksum, that it is the page
+// expected at that position, and that its slots point inside the page
+func (p *Page) Verify(id uint32) error {
+	if sum := crc32.Checksum(p[offChecksum+4:], castagnoli); sum != binary.LittleEndian.Uint32(p[offChecksum:]) {
+		return fmt.Errorf("page %d: checksum mismatch", id)
+	}
+	if p.ID() != id {
+		return fmt.Errorf("page %d: found page %d at its position", id, p.ID())
+	}
+	if p.upper() < p.lower() || p.upper() > Size {
+		return fmt.Errorf("page %d: corrupt header", id)
+	}
+	for i := 0; i < p.SlotCount(); i++ {
+		if off, length := p.slot(i); length > 0 && (off < p.upper() || off+length > Size) {
+			return fmt.Errorf("page %d: slot %d points outside the tuple area", id, i)
+		}
+	}
+	return nil
+}
+
+func (p *Page) kind() byte {
+	return p[offKind]
+}
+
+// lower returns the end of the slot array
+func (p *Page) lower() int {
+	return headerSize + p.SlotCount()*slotSize
+}
+
+func (p *Page) upper() int {
+	return int(binary.LittleEndian.Uint16(p[offUpper:]))
+}
+
+func (p *Page) setUpper(upper int) {
+	binary.LittleEndian.PutUint16(p[offUpper:], uint16(upper))
+}
+
+func (p *Page) slot(i int) (off, length int) {
+	at := headerSize + i*slotSize
+	return int(binary.LittleEndian.Uint16(p[at:])), int(binary.LittleEndian.Uint16(p[at+2:]))
+}
+
+func (p *Page) setSlot(i, off, length int) {
+	at := headerSize + i*slotSize
+	binary.LittleEndian.PutUint16(p[at:], uint16(off))
+	binary.LittleEndian.PutUint16(p[at+2:], uint16(length))
+}
+
+// deadSlot returns the first deleted slot, or -1
+func (p *Page) deadSlot() int {
+	for i := 0; i < p.SlotCount(); i++ {
+		if _, length := p.slot(i); length == 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,331 @@
+package page
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+)
+
+var testColumns = []schema.Column{
+	{Name: "id", Type: schema.ColumnTypeInt, PrimaryKey: true},
+	{Name: "name", Type: schema.ColumnTypeText},
+	{Name: "score", Type: schema.ColumnTypeFloat},
+	{Name: "active", Type: schema.ColumnTypeBool},
+	{Name: "joined", Type: schema.ColumnTypeDate},
+}
+
+func testRow(i int) data.Row {
+	values := map[string]interface{}{
+		"id":     int64(i),
+		"name":   fmt.Sprintf("user %d %s", i, strings.Repeat("x", i%50)),
+		"score":  float64(i) / 4,
+		"active": i%2 == 0,
+		"joined": "2024-01-02",
+	}
+	switch i % 5 {
+	case 1:
+		values["score"] = nil
+	case 2:
+		delete(values, "joined")
+	}
+	return data.NewRow(values)
+}
+
+func TestPageInsertDelete(t *testing.T) {
+	p := NewDataPage(1)
+	empty := p.FreeSpace()
+	if empty != MaxTuple {
+		t.Fatalf("Expected %d bytes free in an empty page, got %d", MaxTuple, empty)
+	}
+
+	a, _ := p.Insert([]byte("aaaa"))
+	b, _ := p.Insert([]byte("bbbbbbbb"))
+	c, _ := p.Insert([]byte("cc"))
+	p.Delete(b)
+
+	if got := string(p.Tuple(a)) + string(p.Tuple(c)); got != "aaaacc" {
+		t.Fatalf("Expected the other tuples to survive a delete, got %q", got)
+	}
+	if p.Tuple(b) != nil {
+		t.Error("Expected the deleted slot to be empty")
+	}
+
+	// The deleted slot is reused and its space is reclaimed
+	if slot, ok := p.Insert([]byte("dddddd")); !ok || slot != b {
+		t.Errorf("Expected the insert to reuse slot %d, got %d", b, slot)
+	}
+	if free := p.FreeSpace(); free != empty-3*slotSize-12 {
+		t.Errorf("Expected %d bytes free, got %d", empty-3*slotSize-12, free)
+	}
+
+	// A page fills up
+	for {
+		if _, ok := p.Insert(make([]byte, 100)); !ok {
+			break
+		}
+	}
+	if p.FreeSpace() >= 100 {
+		t.Errorf("Expected a full page, %d bytes free", p.FreeSpace())
+	}
+	if _, ok := p.Insert(make([]byte, MaxTuple+1)); ok {
+		t.Error("Expected a tuple larger than a page to be refused")
+	}
+}
+
+func TestPageChecksum(t *testing.T) {
+	p := NewDataPage(3)
+	p.Insert([]byte("hello"))
+	p.Seal()
+	if err := p.Verify(3); err != nil {
+		t.Fatalf("Verify failed on a sealed page: %v", err)
+	}
+	if err := p.Verify(4); err == nil {
+		t.Error("Expected a page at the wrong position to fail verification")
+	}
+
+	p[Size-1] ^= 0xff
+	if err := p.Verify(3); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+}
+
+func TestRowEncoding(t *testing.T) {
+	row := data.NewRow(map[string]interface{}{
+		"id":     7, // inserted rows may hold int
+		"name":   "ann",
+		"score":  nil,
+		"active": false,
+	})
+	tuple, err := EncodeRow(testColumns, row)
+	if err != nil {
+		t.Fatalf("EncodeRow failed: %v", err)
+	}
+	decoded, err := DecodeRow(testColumns, tuple)
+	if err != nil {
+		t.Fatalf("DecodeRow failed: %v", err)
+	}
+
+	// Values come back with the Go type of their column type; NULL and
+	// missing columns stay distinct
+	expected := map[string]interface{}{"id": int64(7), "name": "ann", "score": nil, "active": false}
+	if !reflect.DeepEqual(decoded.Data, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded.Data)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"id": "seven"},
+		{"id": 1.5},
+		{"id": int64(1), "unknown": 1},
+	} {
+		if _, err := EncodeRow(testColumns, data.NewRow(bad)); err == nil {
+			t.Errorf("Expected EncodeRow to refuse %v", bad)
+		}
+	}
+	if _, err := DecodeRow(testColumns, tuple[:len(tuple)-1]); err == nil {
+		t.Error("Expected a truncated tuple to fail")
+	}
+}
+
+// sortedRows returns the rows' values ordered by id, as the heap does not
+// keep the order rows were saved in
+func sortedRows(rows []data.Row) []map[string]interface{} {
+	values := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row.Data
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i]["id"].(int64) < values[j]["id"].(int64)
+	})
+	return values
+}
+
+func TestHeapSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	var rows []data.Row
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, testRow(i))
+	}
+
+	// A pool smaller than the heap makes saves and reads evict pages
+	heap, err := OpenHeap(path, NewBufferPool(4), testColumns)
+	if err != nil {
+		t.Fatalf("OpenHeap failed: %v", err)
+	}
+	written, err := heap.Save(rows)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if written != heap.PageCount() || written < 8 {
+		t.Fatalf("Expected the first save to write all %d pages, wrote %d", heap.PageCount(), written)
+	}
+
+	reopen := func() *Heap {
+		t.Helper()
+		heap, err := OpenHeap(path, NewBufferPool(4), testColumns)
+		if err != nil {
+			t.Fatalf("OpenHeap failed: %v", err)
+		}
+		got, err := heap.Rows()
+		if err != nil {
+			t.Fatalf("Rows failed: %v", err)
+		}
+		if !reflect.DeepEqual(sortedRows(got), sortedRows(rows)) {
+			t.Fatalf("Expected the %d saved rows back, got %d", len(rows), len(got))
+		}
+		return heap
+	}
+	heap = reopen()
+
+	// Appending, updating and deleting a row only touch the pages involved
+	rows = append(rows, testRow(1000))
+	rows[10] = testRow(10)
+	rows[10].Data["name"] = "renamed"
+	rows = append(rows[:20], rows[21:]...)
+	if written, err = heap.Save(rows); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if written > 3 {
+		t.Errorf("Expected at most 3 pages written, got %d", written)
+	}
+	heap = reopen()
+
+	// Saving unchanged rows writes no page
+	if written, err = heap.Save(rows); err != nil || written != 0 {
+		t.Errorf("Expected no page written, got %d (%v)", written, err)
+	}
+
+	// Emptying the table gives the pages back
+	rows = nil
+	if _, err = heap.Save(rows); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != Size {
+		t.Errorf("Expected only the header page left, got %v (%v)", info.Size(), err)
+	}
+	reopen()
+}
+
+func TestHeapCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	heap, _ := OpenHeap(path, NewBufferPool(4), testColumns)
+	if _, err := heap.Save([]data.Row{testRow(1), testRow(2)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Data pages are verified as they are read
+	content, _ := os.ReadFile(path)
+	content[Size+Size/2] ^= 0x01
+	os.WriteFile(path, content, 0644)
+	heap, err := OpenHeap(path, NewBufferPool(4), testColumns)
+	if err != nil {
+		t.Fatalf("OpenHeap failed: %v", err)
+	}
+	if _, err := heap.Rows(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+	if _, err := heap.Save(nil); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected Save to report the checksum error, got %v", err)
+	}
+
+	os.WriteFile(path, content[:Size+100], 0644)
+	if _, err := OpenHeap(path, NewBufferPool(4), testColumns); err == nil {
+		t.Error("Expected a truncated file to fail")
+	}
+}
+
+func TestHeapJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	var rows []data.Row
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, testRow(i))
+	}
+	heap, _ := OpenHeap(path, NewBufferPool(4), testColumns)
+	if _, err := heap.Save(rows); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	changed := append([]data.Row{}, rows[100:]...)
+	for i := 1000; i < 1100; i++ {
+		changed = append(changed, testRow(i))
+	}
+	readBack := func(want []data.Row) {
+		t.Helper()
+		heap, err := OpenHeap(path, NewBufferPool(4), testColumns)
+		if err != nil {
+			t.Fatalf("OpenHeap failed: %v", err)
+		}
+		got, err := heap.Rows()
+		if err != nil {
+			t.Fatalf("Rows failed: %v", err)
+		}
+		if !reflect.DeepEqual(sortedRows(got), sortedRows(want)) {
+			t.Fatalf("Expected %d rows, got %d", len(want), len(got))
+		}
+	}
+
+	// Staging writes nothing, even when the pool evicts pages meanwhile
+	journal, written, err := heap.Stage(changed)
+	if err != nil {
+		t.Fatalf("Stage failed: %v", err)
+	}
+	if len(journal) != (written+1)*Size {
+		t.Errorf("Expected a journal of the header and %d pages, got %d bytes", written, len(journal))
+	}
+	if _, err := heap.Rows(); err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Fatal("Expected staging to leave the heap file as it was")
+	}
+	if _, _, err := heap.Stage(changed); err == nil {
+		t.Error("Expected a second Stage before Apply to fail")
+	}
+
+	// A crash before the journal is committed keeps the old rows
+	readBack(rows)
+
+	// A crash after it is committed is finished by Recover
+	if err := commitJournal(JournalPath(path), journal); err != nil {
+		t.Fatalf("commitJournal failed: %v", err)
+	}
+	if _, err := OpenHeap(path, NewBufferPool(4), testColumns); err == nil || !strings.Contains(err.Error(), "journal") {
+		t.Errorf("Expected a heap with a journal to be refused, got %v", err)
+	}
+	if err := Recover(path); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if _, err := os.Stat(JournalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("Expected Recover to remove the journal, got %v", err)
+	}
+	readBack(changed)
+
+	// Applying it again, as after a crash during Recover, changes nothing
+	if err := commitJournal(JournalPath(path), journal); err != nil {
+		t.Fatalf("commitJournal failed: %v", err)
+	}
+	if err := heap.Apply(); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	readBack(changed)
+	if got, err := heap.Rows(); err != nil || len(got) != len(changed) {
+		t.Errorf("Expected the applied heap to read %d rows, got %d (%v)", len(changed), len(got), err)
+	}
+
+	// A damaged journal is reported instead of applied
+	journal[Size+100] ^= 0x01
+	if err := commitJournal(JournalPath(path), journal); err != nil {
+		t.Fatalf("commitJournal failed: %v", err)
+	}
+	if err := Recover(path); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package page
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/leengari/mini-rdbms/internal/domain/data"
+	"github.com/leengari/mini-rdbms/internal/domain/schema"
+)
+
+// Column states of the tuple encoding
+const (
+	stateMissing byte = iota // the row does not have the column
+	stateNull
+	stateValue
+)
+
+// EncodeRow encodes a row as a tuple of the table's columns, in schema order
+//
+// Each column is a state byte followed, for a value, by its encoding for the
+// column type: a zigzag varint for INT, 8 bytes for FLOAT, one byte for BOOL
+// and a length-prefixed string for TEXT, DATE, TIME and EMAIL. Decoding
+// therefore gives back the Go type of the column type - int64, float64, bool
+// or string - whatever the row held; a row with a column not in the schema
+// is rejected rather than losing it.
+func EncodeRow(columns []schema.Column, row data.Row) ([]byte, error) {
+	known := 0
+	buf := make([]byte, 0, 16*len(columns))
+	for _, col := range columns {
+		value, ok := row.Data[col.Name]
+		if !ok {
+			buf = append(buf, stateMissing)
+			continue
+		}
+		known++
+		if value == nil {
+			buf = append(buf, stateNull)
+			continue
+		}
+		buf = append(buf, stateValue)
+
+		var err error
+		if buf, err = appendValue(buf, col, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if known != len(row.Data) {
+		for name := range row.Data {
+			if !hasColumn(columns, name) {
+				return nil, fmt.Errorf("row has column %s, which is not in the schema", name)
+			}
+		}
+	}
+	return buf, nil
+}
+
+func appendValue(buf []byte, col schema.Column, value interface{}) ([]byte, error) {
+	switch col.Type {
+	case schema.ColumnTypeInt:
+		switch v := value.(type) {
+		case int64:
+			return binary.AppendVarint(buf, v), nil
+		case int:
+			return binary.AppendVarint(buf, int64(v)), nil
+		case float64:
+			if v == float64(int64(v)) {
+				return binary.AppendVarint(buf, int64(v)), nil
+			}
+		}
+	case schema.ColumnTypeFloat:
+		if v, ok := value.(float64); ok {
+			return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), nil
+		}
+	case schema.ColumnTypeBool:
+		if v, ok := value.(bool); ok {
+			if v {
+				return append(buf, 1), nil
+			}
+			return append(buf, 0), nil
+		}
+	case schema.ColumnTypeText, schema.ColumnTypeDate, schema.ColumnTypeTime, schema.ColumnTypeEmail:
+		if v, ok := value.(string); ok {
+			buf = binary.AppendUvarint(buf, uint64(len(v)))
+			return append(buf, v...), nil
+		}
+	default:
+		return nil, fmt.Errorf("column %s has unknown type %q", col.Name, col.Type)
+	}
+	return nil, fmt.Errorf("column %s: cannot store %T as %s", col.Name, value, col.Type)
+}
+
+// DecodeRow decodes a tuple written by EncodeRow for the same columns
+func DecodeRow(columns []schema.Column, tuple []byte) (data.Row, error) {
+	values := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		if len(tuple) == 0 {
+			return data.Row{}, fmt.Errorf("tuple ends before column %s", col.Name)
+		}
+		state := tuple[0]
+		tuple = tuple[1:]
+
+		switch state {
+		case stateMissing:
+			continue
+		case stateNull:
+			values[col.Name] = nil
+			continue
+		case stateValue:
+		default:
+			return data.Row{}, fmt.Errorf("column %s has invalid state %d", col.Name, state)
+		}
+
+		var (
+			value interface{}
+			n     int
+		)
+		switch col.Type {
+		case schema.ColumnTypeInt:
+			value, n = binary.Varint(tuple)
+		case schema.ColumnTypeFloat:
+			if len(tuple) >= 8 {
+				value, n = math.Float64frombits(binary.LittleEndian.Uint64(tuple)), 8
+			}
+		case schema.ColumnTypeBool:
+			if len(tuple) >= 1 {
+				value, n = tuple[0] == 1, 1
+			}
+		case schema.ColumnTypeText, schema.ColumnTypeDate, schema.ColumnTypeTime, schema.ColumnTypeEmail:
+			length, m := binary.Uvarint(tuple)
+			if m > 0 && uint64(len(tuple)-m) >= length {
+				value, n = string(tuple[m:m+int(length)]), m+int(length)
+			}
+		default:
+			return data.Row{}, fmt.Errorf("column %s has unknown type %q", col.Name, col.Type)
+		}
+		if n <= 0 {
+			return data.Row{}, fmt.Errorf("column %s: truncated %s value", col.Name, col.Type)
+		}
+		values[col.Name] = value
+		tuple = tuple[n:]
+	}
+
+	if len(tuple) != 0 {
+		return data.Row{}, fmt.Errorf("%d bytes left after the last column", len(tuple))
+	}
+	return data.NewRow(values), nil
+}
+
+func hasColumn(columns []schema.Column, name string) bool {
+	for _, col := range columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File is the new content of one file of a table directory
+type File struct {
+	Name string
+	Data []byte
+}
+
+// commitName is the commit record of a table directory whose files are
+// being replaced
+const commitName = "commit.json"
+
+const tmpSuffix = ".tmp"
+
+type commitRecord struct {
+	Files []string `json:"files"`
+}
+
+// replaceFiles replaces files of a directory as one change
+//
+// Every file is first written and synced to a temp file. A commit record
+// naming them is then put in place atomically, the temp files are renamed
+// over the originals and the record is removed. A crash before the record
+// exists leaves all the old files; a crash after it leaves a record that
+// Recover completes, so a table never comes back with a new data.json and
+// an old meta.json or the other way round.
+func replaceFiles(dir string, files []File) error {
+	names := make([]string, len(files))
+	for i, f := range files {
+		if err := writeSynced(filepath.Join(dir, f.Name+tmpSuffix), f.Data); err != nil {
+			return fmt.Errorf("failed to write temp file %s: %w", f.Name, err)
+		}
+		names[i] = f.Name
+	}
+	if len(files) == 1 {
+		// A single rename is atomic by itself
+		return renameTemps(dir, names)
+	}
+
+	record, err := json.Marshal(commitRecord{Files: names})
+	if err != nil {
+		return err
+	}
+	if err := ReplaceFile(filepath.Join(dir, commitName), record); err != nil {
+		return fmt.Errorf("failed to write commit record: %w", err)
+	}
+	if err := renameTemps(dir, names); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, commitName)); err != nil {
+		return fmt.Errorf("failed to remove commit record: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// ReplaceFile replaces one file through a synced temp file and a rename
+func ReplaceFile(path string, data []byte) error {
+	if err := writeSynced(path+tmpSuffix, data); err != nil {
+		return err
+	}
+	return renameTemps(filepath.Dir(path), []string{filepath.Base(path)})
+}
+
+// renameTemps renames the temp files of names over them and syncs the
+// directory so the renames are durable
+// A missing temp file was renamed already, by an earlier attempt.
+func renameTemps(dir string, names []string) error {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.Rename(path+tmpSuffix, path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rename temp → %s: %w", name, err)
+		}
+	}
+	return syncDir(dir)
+}
+
+// writeSynced writes a file and syncs it to disk
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs a directory, making the entries created, renamed and
+// removed in it durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// Recover completes or undoes a replacement of table files interrupted by a
+// crash: with a commit record the renames it names are finished, without
+// one the temp files left behind are removed
+// Loaders call it before reading a table directory.
+func Recover(dir string) error {
+	recordPath := filepath.Join(dir, commitName)
+	content, err := os.ReadFile(recordPath)
+	if err == nil {
+		var record commitRecord
+		if err := json.Unmarshal(content, &record); err != nil {
+			return fmt.Errorf("unreadable commit record in %s: %w", dir, err)
+		}
+		if err := renameTemps(dir, record.Files); err != nil {
+			return err
+		}
+		if err := os.Remove(recordPath); err != nil {
+			return err
+		}
+		slog.Warn("completed an interrupted save",
+			slog.String("path", dir),
+			slog.Any("files", record.Files))
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), tmpSuffix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+		slog.Warn("discarded a file of an interrupted save",
+			slog.String("path", dir),
+			slog.String("file", entry.Name()))
+	}
+	return nil
+}
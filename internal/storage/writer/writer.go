@@ -1,6 +1,7 @@
 package writer
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 
+	"github.com/leengari/mini-rdbms/internal/domain/data"
 	"github.com/leengari/mini-rdbms/internal/domain/schema"
 	"github.com/leengari/mini-rdbms/internal/domain/transaction"
 	"github.com/leengari/mini-rdbms/internal/storage/metadata"
@@ -15,10 +17,45 @@ import (
 
 // SaveTable persists both data.json and meta.json atomically
 func SaveTable(t *schema.Table, tx *transaction.Transaction) error {
+	return SaveTableWith(t, tx, writeJSONRows)
+}
+
+// RowWriter writes the rows of a table
+// It is called with the table read-locked, before meta.json is replaced.
+// Rows kept in a file of the table directory are returned as its content,
+// which is replaced together with meta.json; a writer that updates its own
+// files in place returns none.
+type RowWriter func(t *schema.Table) ([]File, error)
+
+// writeJSONRows returns the rows of a table as its data.json
+func writeJSONRows(t *schema.Table) ([]File, error) {
+	dataBytes, err := json.MarshalIndent(t.Rows, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rows for %s: %w", t.Name, err)
+	}
+	return []File{{Name: "data.json", Data: dataBytes}}, nil
+}
+
+// SaveTableWith persists a table whose rows are written by writeRows, then
+// its meta.json and stats.json atomically
+// The table's dirty flag is cleared once its files are in place, unless it
+// changed during the save.
+func SaveTableWith(t *schema.Table, tx *transaction.Transaction, writeRows RowWriter) error {
 	if t == nil || t.Path == "" {
 		return fmt.Errorf("cannot save table: nil or missing path")
 	}
 
+	changes, err := saveTable(t, tx, writeRows)
+	if err != nil {
+		return err
+	}
+	t.MarkClean(changes)
+	return nil
+}
+
+// saveTable writes the files of a table under its read lock, returning the
+// change count of the state written
+func saveTable(t *schema.Table, tx *transaction.Transaction, writeRows RowWriter) (uint64, error) {
 	tableName := t.Name
 	basePath := t.Path
 
@@ -29,12 +66,44 @@ func SaveTable(t *schema.Table, tx *transaction.Transaction) error {
 	if tx != nil {
 		slog.Debug("SaveTable operation", "table", tableName, "tx_id", tx.ID)
 	}
+	changes := t.ChangesUnsafe()
+
+	// 1. Prepare meta and statistics from the current in-memory state
+	meta, err := metaFiles(t)
+	if err != nil {
+		return 0, err
+	}
 
-	// 1. Prepare meta (updated from current in-memory state)
+	// 2. Write rows
+	files, err := writeRows(t)
+	if err != nil {
+		return 0, err
+	}
+	files = append(files, meta...)
+
+	// 3. Replace the files together, through synced temp files
+	if err := replaceFiles(basePath, files); err != nil {
+		return 0, fmt.Errorf("failed to save table %s: %w", tableName, err)
+	}
+
+	slog.Info("Table saved successfully",
+		slog.String("table", tableName),
+		slog.String("path", basePath),
+		slog.Int64("last_insert_id", t.LastInsertID),
+		slog.Int("row_count", t.RowCountUnsafe()),
+	)
+
+	return changes, nil
+}
+
+// metaFiles returns the meta.json of a table and, once it has been analyzed,
+// its stats.json
+// Must be called while holding the table lock.
+func metaFiles(t *schema.Table) ([]File, error) {
 	meta := metadata.TableMeta{
-		Name:         tableName,
+		Name:         t.Name,
 		LastInsertID: t.LastInsertID,
-		RowCount:     int64(len(t.Rows)), 
+		RowCount:     int64(t.RowCountUnsafe()),
 		Columns:      make([]metadata.ColumnMeta, len(t.Schema.Columns)),
 	}
 
@@ -49,54 +118,85 @@ func SaveTable(t *schema.Table, tx *transaction.Transaction) error {
 		}
 	}
 
-	// 2. Marshal meta
-	metaBytes, err := json.MarshalIndent(meta, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal table meta for %s: %w", tableName, err)
+	for _, def := range t.Schema.Indexes {
+		meta.Indexes = append(meta.Indexes, metadata.IndexMeta{
+			Name:   def.Name,
+			Column: def.Column,
+			Type:   string(def.Kind),
+			Unique: def.Unique,
+		})
 	}
 
-	// 3. Marshal data (rows)
-	dataBytes, err := json.MarshalIndent(t.Rows, "", "  ")
+	metaBytes, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal rows for %s: %w", tableName, err)
+		return nil, fmt.Errorf("failed to marshal table meta for %s: %w", t.Name, err)
 	}
+	files := []File{{Name: "meta.json", Data: metaBytes}}
 
-	// 4. Write both files using temp + atomic rename
-	files := []struct {
-		path string
-		data []byte
-		name string
-	}{
-		{filepath.Join(basePath, "meta.json"), metaBytes, "meta.json"},
-		{filepath.Join(basePath, "data.json"), dataBytes, "data.json"},
+	// Statistics are only present once the table has been analyzed
+	if t.Stats != nil {
+		statsBytes, err := json.MarshalIndent(statsToMeta(t.Stats), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal statistics for %s: %w", t.Name, err)
+		}
+		files = append(files, File{Name: "stats.json", Data: statsBytes})
 	}
+	return files, nil
+}
 
-	for _, f := range files {
-		tmpPath := f.path + ".tmp"
+// SnapshotTable returns the files of a table as JSON storage keeps them:
+// every row in data.json, then meta.json and stats.json
+// Rows of a paged table are read through its source. Must be called while
+// holding the table lock.
+func SnapshotTable(t *schema.Table) ([]File, error) {
+	rows := make([]data.Row, 0, t.RowCountUnsafe())
+	if err := t.ForEachRowUnsafe(func(_ int, row data.Row) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read rows of %s: %w", t.Name, err)
+	}
+	dataBytes, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rows for %s: %w", t.Name, err)
+	}
 
-		// Write to temp
-		if err := os.WriteFile(tmpPath, f.data, 0644); err != nil {
-			return fmt.Errorf("failed to write temp file %s for table %s: %w", f.name, tableName, err)
-		}
+	files, err := metaFiles(t)
+	if err != nil {
+		return nil, err
+	}
+	return append([]File{{Name: "data.json", Data: dataBytes}}, files...), nil
+}
 
-		// Atomic replace
-		if err := os.Rename(tmpPath, f.path); err != nil {
-			return fmt.Errorf("failed to rename temp → %s for table %s: %w", f.name, tableName, err)
+// statsToMeta converts planner statistics to their JSON representation
+func statsToMeta(stats *schema.TableStatistics) metadata.TableStatsMeta {
+	meta := metadata.TableStatsMeta{
+		RowCount:   stats.RowCount,
+		AnalyzedAt: stats.AnalyzedAt,
+		Columns:    make(map[string]metadata.ColumnStatsMeta, len(stats.Columns)),
+	}
+	for name, col := range stats.Columns {
+		meta.Columns[name] = metadata.ColumnStatsMeta{
+			DistinctCount: col.DistinctCount,
+			NullFraction:  col.NullFraction,
+			Min:           col.Min,
+			Max:           col.Max,
+			Histogram:     col.Histogram,
 		}
 	}
-
-	slog.Info("Table saved successfully",
-		slog.String("table", tableName),
-		slog.String("path", basePath),
-		slog.Int64("last_insert_id", t.LastInsertID),
-		slog.Int("row_count", len(t.Rows)),
-	)
-
-	return nil
+	return meta
 }
 
-// SaveDatabase saves all tables and database metadata
+// SaveDatabase saves the changed tables and the database metadata
 func SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
+	return SaveDatabaseWith(db, tx, SaveTable)
+}
+
+// SaveDatabaseWith saves the dirty tables with saveTable, then the database
+// metadata if it changed
+// Clean tables are skipped, so saving a database that was only read writes
+// nothing.
+func SaveDatabaseWith(db *schema.Database, tx *transaction.Transaction, saveTable func(*schema.Table, *transaction.Transaction) error) error {
 	if db == nil {
 		return fmt.Errorf("cannot save nil database")
 	}
@@ -105,15 +205,20 @@ func SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
 		slog.Debug("SaveDatabase operation", "database", db.Name, "tx_id", tx.ID)
 	}
 
-	// 1. Save all tables first
+	// 1. Save the dirty tables first
+	saved := 0
 	for name, table := range db.Tables {
-		if err := SaveTable(table, tx); err != nil {
+		if !table.IsDirty() {
+			continue
+		}
+		if err := saveTable(table, tx); err != nil {
 			slog.Error("failed to save table during database save",
 				slog.String("table", name),
 				slog.Any("error", err),
 			)
 			return fmt.Errorf("failed to save table %s: %w", name, err)
 		}
+		saved++
 	}
 
 	// 2. Build table list from current state
@@ -121,12 +226,13 @@ func SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
 	for name := range db.Tables {
 		tableNames = append(tableNames, name)
 	}
-	sort.Strings(tableNames) 
+	sort.Strings(tableNames)
 
 	// 3. Create database metadata
 	dbMeta := metadata.DatabaseMeta{
 		Name:    db.Name,
-		Version: 1, 
+		Version: 1,
+		Storage: db.Storage,
 		Tables:  tableNames,
 	}
 
@@ -136,22 +242,22 @@ func SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
 		return fmt.Errorf("failed to marshal database meta: %w", err)
 	}
 
-	// 5. Save database meta.json atomically
+	// 5. Save database meta.json atomically, unless it is unchanged
 	dbMetaPath := filepath.Join(db.Path, "meta.json")
-	tmpPath := dbMetaPath + ".tmp"
-
-	if err := os.WriteFile(tmpPath, metaBytes, 0644); err != nil {
-		return fmt.Errorf("failed to write temp database meta: %w", err)
-	}
-
-	if err := os.Rename(tmpPath, dbMetaPath); err != nil {
-		return fmt.Errorf("failed to rename temp → database meta.json: %w", err)
+	if current, err := os.ReadFile(dbMetaPath); err == nil && bytes.Equal(current, metaBytes) {
+		if saved == 0 {
+			slog.Debug("database unchanged, nothing saved", slog.String("name", db.Name))
+			return nil
+		}
+	} else if err := ReplaceFile(dbMetaPath, metaBytes); err != nil {
+		return fmt.Errorf("failed to write database meta: %w", err)
 	}
 
 	slog.Info("Database saved successfully",
 		slog.String("name", db.Name),
 		slog.String("path", db.Path),
 		slog.Int("table_count", len(db.Tables)),
+		slog.Int("tables_saved", saved),
 	)
 
 	return nil
@@ -159,23 +265,9 @@ func SaveDatabase(db *schema.Database, tx *transaction.Transaction) error {
 
 // FlushTableIfDirty saves the table only if it has unsaved changes
 func FlushTableIfDirty(table *schema.Table, tx *transaction.Transaction) error {
-	// Check dirty flag (needs lock)
-	table.RLock()
-	isDirty := table.Dirty
-	table.RUnlock()
-
-	if !isDirty {
+	if !table.IsDirty() {
 		return nil
 	}
-
-	if err := SaveTable(table, tx); err != nil {
-		return err
-	}
-
-	// Clear dirty flag
-	table.Lock()
-	table.Dirty = false
-	table.Unlock()
-
-	return nil
-}
\ No newline at end of file
+	// SaveTable clears the dirty flag
+	return SaveTable(table, tx)
+}
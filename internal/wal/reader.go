@@ -151,19 +151,11 @@ func (r *WALReader) ReadNextRecord() (WALRecord, error) {
 		}
 	}
 
-	// Calculate actual payload size (before padding)
-	// The payload includes padding bytes at the end to reach alignment
-	actualPayloadSize := payloadSize
-	// Remove padding bytes from CRC calculation
-	unalignedSize := RecordHeaderSize + payloadSize
-	paddingSize := int(header.Length) - unalignedSize
-	if paddingSize > 0 {
-		actualPayloadSize = payloadSize - paddingSize
-	}
-
-	// Verify CRC32 of actual payload (excluding padding)
-	if actualPayloadSize > 0 {
-		if err := verifyCRC32(payload[:actualPayloadSize], header.CRC32); err != nil {
+	// Verify CRC32 of the payload, padding included
+	// The padding bytes are zero and every payload field is length-prefixed,
+	// so decoders ignore them.
+	if payloadSize > 0 {
+		if err := verifyCRC32(payload, header.CRC32); err != nil {
 			return nil, fmt.Errorf("CRC mismatch at offset %d: %w", r.currentPos, err)
 		}
 	}
@@ -172,7 +164,7 @@ func (r *WALReader) ReadNextRecord() (WALRecord, error) {
 	r.currentPos += uint64(header.Length)
 
 	// Decode payload based on record type
-	return r.decodeRecord(header, payload[:actualPayloadSize])
+	return r.decodeRecord(header, payload)
 }
 
 // ReadRecordAt reads a WAL record at the specified file offset
@@ -227,8 +219,8 @@ func (r *WALReader) validateHeader(h WALRecordHeader) error {
 			h.Length, MinRecordSize, r.currentPos)
 	}
 
-	// Check Type is valid (1-7)
-	if h.Type < RecordBeginTxn || h.Type > RecordCheckpoint {
+	// Check Type is valid (1-12)
+	if h.Type < RecordBeginTxn || h.Type > RecordSetSequence {
 		return fmt.Errorf("invalid record type %d at offset %d (possible corruption)",
 			h.Type, r.currentPos)
 	}
@@ -272,6 +264,8 @@ func (r *WALReader) decodeRecord(header WALRecordHeader, payload []byte) (WALRec
 		return decodeAbortPayload(header, payload)
 	case RecordCheckpoint:
 		return decodeCheckpointPayload(header, payload)
+	case RecordCreateTable, RecordDropTable, RecordCreateIndex, RecordDropIndex, RecordSetSequence:
+		return decodeSchemaPayload(header, payload)
 	default:
 		return nil, fmt.Errorf("unknown record type: %d", header.Type)
 	}
@@ -428,17 +422,63 @@ func decodeDeletePayload(header WALRecordHeader, payload []byte) (*DeleteRecord,
 	}, nil
 }
 
+// decodeSchemaPayload decodes a CreateTable, DropTable, CreateIndex, DropIndex or SetSequence record payload
+// Format: TxID(8) + TableNameLen(2) + TableName + NameLen(2) + Name + DefinitionLen(4) + Definition
+func decodeSchemaPayload(header WALRecordHeader, payload []byte) (*SchemaRecord, error) {
+	if len(payload) < 8 {
+		return nil, fmt.Errorf("%s payload too short: %d bytes", header.Type, len(payload))
+	}
+
+	offset := 0
+
+	// TxID (8 bytes)
+	txID := ByteOrder.Uint64(payload[offset:])
+	offset += 8
+
+	// TableName
+	tableName, newOffset, err := decodeString(payload, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode TableName: %w", err)
+	}
+	offset = newOffset
+
+	// Name
+	name, newOffset, err := decodeString(payload, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Name: %w", err)
+	}
+	offset = newOffset
+
+	// Definition
+	definition, _, err := decodeBytes(payload, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Definition: %w", err)
+	}
+
+	return &SchemaRecord{
+		Header:     header,
+		TxID:       txID,
+		TableName:  tableName,
+		Name:       name,
+		Definition: definition,
+	}, nil
+}
+
 // decodeCommitPayload decodes a Commit record payload
-// Format: TxID (8 bytes)
+// Format: TxID (8 bytes) + Timestamp (8 bytes, absent in older records)
 func decodeCommitPayload(header WALRecordHeader, payload []byte) (*CommitRecord, error) {
 	if len(payload) < 8 {
 		return nil, fmt.Errorf("Commit payload too short: %d bytes", len(payload))
 	}
 
-	return &CommitRecord{
+	record := &CommitRecord{
 		Header: header,
 		TxID:   ByteOrder.Uint64(payload[0:8]),
-	}, nil
+	}
+	if len(payload) >= 16 {
+		record.Timestamp = int64(ByteOrder.Uint64(payload[8:16]))
+	}
+	return record, nil
 }
 
 // decodeAbortPayload decodes an Abort record payload
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 )
 
 // ===========================================================================
@@ -43,17 +44,24 @@ type RecoveryResult struct {
 	InsertOps []*InsertRecord // Insert operations to replay
 	UpdateOps []*UpdateRecord // Update operations to replay
 	DeleteOps []*DeleteRecord // Delete operations to replay
+	SchemaOps []*SchemaRecord // Table and index creations and removals, and sequences set, to replay
 
 	// State after recovery
 	NextLSN        uint64 // Next LSN to use after recovery
 	LastFlushedLSN uint64 // Last flushed LSN
+
+	// Point-in-time recovery (RecoverUntil)
+	StopLSN       uint64    // Commit LSN of the last transaction replayed
+	StopTime      time.Time // Commit time of the last transaction replayed
+	TargetReached bool      // Whether the log went past the target rather than ending first
 }
 
 // RecoveryManager handles WAL recovery operations
 type RecoveryManager struct {
-	walPath string     // Path to WAL file
-	reader  *WALReader // WAL reader
-	dbPath  string     // Path to database directory
+	walPath  string     // Path to WAL file
+	reader   *WALReader // WAL reader
+	dbPath   string     // Path to database directory
+	segments []string   // Segment files in log order, for RecoverUntil
 }
 
 // NewRecoveryManager creates a new recovery manager
@@ -70,6 +78,15 @@ func NewRecoveryManager(walPath string, dbPath string) (*RecoveryManager, error)
 	}, nil
 }
 
+// NewSegmentRecoveryManager creates a recovery manager reading the segments
+// of a segmented WAL, given in log order, for point-in-time recovery
+func NewSegmentRecoveryManager(segments []string, dbPath string) *RecoveryManager {
+	return &RecoveryManager{
+		segments: segments,
+		dbPath:   dbPath,
+	}
+}
+
 // Close closes the recovery manager
 func (rm *RecoveryManager) Close() error {
 	if rm.reader != nil {
@@ -115,6 +132,7 @@ func (rm *RecoveryManager) RecoverFromCheckpoint(checkpoint *CheckpointRecord) (
 		InsertOps:       []*InsertRecord{},
 		UpdateOps:       []*UpdateRecord{},
 		DeleteOps:       []*DeleteRecord{},
+		SchemaOps:       []*SchemaRecord{},
 		NextLSN:         checkpoint.CheckpointLSN + 1,
 	}
 
@@ -167,6 +185,7 @@ func (rm *RecoveryManager) RecoverFromScratch() (*RecoveryResult, error) {
 		InsertOps:       []*InsertRecord{},
 		UpdateOps:       []*UpdateRecord{},
 		DeleteOps:       []*DeleteRecord{},
+		SchemaOps:       []*SchemaRecord{},
 		NextLSN:         1,
 	}
 
@@ -216,6 +235,127 @@ func (rm *RecoveryManager) RecoverFromScratch() (*RecoveryResult, error) {
 	return result, nil
 }
 
+// RecoveryTarget is the point a point-in-time recovery stops at
+// The zero value replays the whole log.
+type RecoveryTarget struct {
+	LSN  uint64    // Last LSN to replay (0 means no limit)
+	Time time.Time // Latest commit time to replay (zero means no limit)
+}
+
+// RecoverUntil collects the transactions committed after afterLSN, the LSN
+// a base backup was taken at, up to the target
+// Segments are read in order and must continue one another. Reading stops at
+// the first record past the target LSN or the first commit past the target
+// time, so only whole transactions committed before the target are replayed.
+// A record cut short at the end of the last segment ends the log, as it does
+// when the WAL is reopened; anywhere else it is an error.
+func (rm *RecoveryManager) RecoverUntil(afterLSN uint64, target RecoveryTarget) (*RecoveryResult, error) {
+	segments := rm.segments
+	if len(segments) == 0 && rm.walPath != "" {
+		segments = []string{rm.walPath}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no WAL segments to recover from")
+	}
+
+	result := &RecoveryResult{
+		InsertOps: []*InsertRecord{},
+		UpdateOps: []*UpdateRecord{},
+		DeleteOps: []*DeleteRecord{},
+		SchemaOps: []*SchemaRecord{},
+		NextLSN:   afterLSN + 1,
+		StopLSN:   afterLSN,
+	}
+	tracker := NewTxnTracker()
+
+	var nextLSN uint64 // LSN the next segment must start at
+	for i, path := range segments {
+		reached, next, err := rm.scanSegment(path, i == len(segments)-1, afterLSN, nextLSN, target, tracker, result)
+		if err != nil {
+			return nil, err
+		}
+		nextLSN = next
+		if reached {
+			result.TargetReached = true
+			break
+		}
+	}
+
+	committed := tracker.GetCommittedTransactions()
+	for _, txn := range committed {
+		if txn.EndLSN <= afterLSN {
+			continue // already in the base backup
+		}
+		result.TransactionsReplay++
+		result.InsertOps = append(result.InsertOps, txn.Inserts...)
+		result.UpdateOps = append(result.UpdateOps, txn.Updates...)
+		result.DeleteOps = append(result.DeleteOps, txn.Deletes...)
+		result.SchemaOps = append(result.SchemaOps, txn.Schemas...)
+		result.StopLSN = txn.EndLSN
+		result.StopTime = time.Unix(0, txn.CommitTimestamp)
+	}
+	result.TransactionsSkipped = len(tracker.GetUncommittedTransactions())
+	result.TransactionsFound = result.TransactionsReplay + result.TransactionsSkipped
+	result.LastFlushedLSN = result.NextLSN - 1
+
+	return result, nil
+}
+
+// scanSegment feeds the records of one segment up to the target to tracker
+// It returns whether the target was reached and the LSN after the segment's
+// last record.
+func (rm *RecoveryManager) scanSegment(path string, last bool, afterLSN, expectedLSN uint64, target RecoveryTarget, tracker *TxnTracker, result *RecoveryResult) (bool, uint64, error) {
+	reader, err := NewWALReader(path)
+	if err != nil {
+		return false, 0, err
+	}
+	defer reader.Close()
+
+	header, err := reader.ReadFileHeader()
+	if err != nil {
+		return false, 0, fmt.Errorf("segment %s: %w", filepath.Base(path), err)
+	}
+	if expectedLSN == 0 && header.InitialLSN > afterLSN+1 {
+		return false, 0, fmt.Errorf("WAL starts at LSN %d but recovery starts after LSN %d: segments are missing",
+			header.InitialLSN, afterLSN)
+	}
+	if expectedLSN != 0 && header.InitialLSN != expectedLSN {
+		return false, 0, fmt.Errorf("segment %s starts at LSN %d, expected %d: a segment is missing",
+			filepath.Base(path), header.InitialLSN, expectedLSN)
+	}
+
+	nextLSN := header.InitialLSN
+	for {
+		record, err := reader.ReadNextRecord()
+		if err == io.EOF {
+			return false, nextLSN, nil
+		}
+		if err != nil {
+			if last {
+				return false, nextLSN, nil
+			}
+			return false, 0, fmt.Errorf("segment %s: %w", filepath.Base(path), err)
+		}
+
+		h := record.GetHeader()
+		if target.LSN > 0 && h.LSN > target.LSN {
+			return true, nextLSN, nil
+		}
+		if commit, ok := record.(*CommitRecord); ok && !target.Time.IsZero() && time.Unix(0, commit.Timestamp).After(target.Time) {
+			return true, nextLSN, nil
+		}
+
+		result.RecordsScanned++
+		nextLSN = h.LSN + 1
+		if nextLSN > result.NextLSN {
+			result.NextLSN = nextLSN
+		}
+		if err := tracker.ProcessRecord(record); err != nil {
+			return false, 0, fmt.Errorf("error processing record: %w", err)
+		}
+	}
+}
+
 // collectCommittedOps extracts operations from committed transactions
 func (rm *RecoveryManager) collectCommittedOps(tracker *TxnTracker, result *RecoveryResult) {
 	committed := tracker.GetCommittedTransactions()
@@ -229,6 +369,7 @@ func (rm *RecoveryManager) collectCommittedOps(tracker *TxnTracker, result *Reco
 		result.InsertOps = append(result.InsertOps, txn.Inserts...)
 		result.UpdateOps = append(result.UpdateOps, txn.Updates...)
 		result.DeleteOps = append(result.DeleteOps, txn.Deletes...)
+		result.SchemaOps = append(result.SchemaOps, txn.Schemas...)
 	}
 }
 
@@ -304,6 +445,9 @@ type TxnRecoveryState struct {
 	Inserts  []*InsertRecord
 	Updates  []*UpdateRecord
 	Deletes  []*DeleteRecord
+	Schemas  []*SchemaRecord
+
+	CommitTimestamp int64 // Unix nanoseconds of the commit, if recorded
 }
 
 // NewTxnTracker creates a new transaction tracker
@@ -324,6 +468,8 @@ func (t *TxnTracker) ProcessRecord(record WALRecord) error {
 		return t.AddUpdate(rec)
 	case *DeleteRecord:
 		return t.AddDelete(rec)
+	case *SchemaRecord:
+		return t.AddSchema(rec)
 	case *CommitRecord:
 		return t.CommitTransaction(rec)
 	case *AbortRecord:
@@ -343,6 +489,7 @@ func (t *TxnTracker) BeginTransaction(record *BeginTxnRecord) {
 		Inserts:  []*InsertRecord{},
 		Updates:  []*UpdateRecord{},
 		Deletes:  []*DeleteRecord{},
+		Schemas:  []*SchemaRecord{},
 	}
 }
 
@@ -359,6 +506,7 @@ func (t *TxnTracker) getOrCreateTxn(txID uint64, lsn uint64) *TxnRecoveryState {
 			Inserts:  []*InsertRecord{},
 			Updates:  []*UpdateRecord{},
 			Deletes:  []*DeleteRecord{},
+			Schemas:  []*SchemaRecord{},
 		}
 		t.transactions[txID] = txn
 	}
@@ -395,11 +543,23 @@ func (t *TxnTracker) AddDelete(record *DeleteRecord) error {
 	return nil
 }
 
+// AddSchema adds a table or index creation or removal, or a sequence set,
+// to a transaction
+func (t *TxnTracker) AddSchema(record *SchemaRecord) error {
+	txn := t.getOrCreateTxn(record.TxID, record.Header.LSN)
+	if txn.State != TxnActive {
+		return fmt.Errorf("cannot add %s to non-active transaction %d", record.Header.Type, record.TxID)
+	}
+	txn.Schemas = append(txn.Schemas, record)
+	return nil
+}
+
 // CommitTransaction marks a transaction as committed
 func (t *TxnTracker) CommitTransaction(record *CommitRecord) error {
 	txn := t.getOrCreateTxn(record.TxID, record.Header.LSN)
 	txn.State = TxnCommitted
 	txn.EndLSN = record.Header.LSN
+	txn.CommitTimestamp = record.Timestamp
 	return nil
 }
 
@@ -412,6 +572,7 @@ func (t *TxnTracker) AbortTransaction(record *AbortRecord) error {
 	txn.Inserts = nil
 	txn.Updates = nil
 	txn.Deletes = nil
+	txn.Schemas = nil
 	return nil
 }
 
@@ -467,6 +628,21 @@ type ReplayTarget interface {
 
 	// ReplayDelete applies a delete operation
 	ReplayDelete(tableName string, key string) error
+
+	// ReplayCreateTable creates a table from its schema
+	ReplayCreateTable(tableName string, tableSchema json.RawMessage) error
+
+	// ReplayDropTable removes a table
+	ReplayDropTable(tableName string) error
+
+	// ReplayCreateIndex creates an index from its definition
+	ReplayCreateIndex(tableName string, definition json.RawMessage) error
+
+	// ReplayDropIndex removes an index
+	ReplayDropIndex(tableName string, indexName string) error
+
+	// ReplaySetSequence sets the auto-increment sequence of a table
+	ReplaySetSequence(tableName string, sequence int64) error
 }
 
 // ReplayAll replays all operations in the recovery result to the target
@@ -490,16 +666,42 @@ func (result *RecoveryResult) ReplayAll(target ReplayTarget) error {
 			if err := target.ReplayDelete(rec.TableName, rec.Key); err != nil {
 				return fmt.Errorf("failed to replay delete at LSN %d: %w", rec.Header.LSN, err)
 			}
+		case *SchemaRecord:
+			if err := replaySchema(target, rec); err != nil {
+				return fmt.Errorf("failed to replay %s at LSN %d: %w", rec.Header.Type, rec.Header.LSN, err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// replaySchema applies a table or index creation or removal, or a sequence
+// set
+func replaySchema(target ReplayTarget, rec *SchemaRecord) error {
+	switch rec.Header.Type {
+	case RecordCreateTable:
+		return target.ReplayCreateTable(rec.TableName, rec.Definition)
+	case RecordDropTable:
+		return target.ReplayDropTable(rec.TableName)
+	case RecordCreateIndex:
+		return target.ReplayCreateIndex(rec.TableName, rec.Definition)
+	case RecordDropIndex:
+		return target.ReplayDropIndex(rec.TableName, rec.Name)
+	case RecordSetSequence:
+		var sequence int64
+		if err := json.Unmarshal(rec.Definition, &sequence); err != nil {
+			return fmt.Errorf("unreadable sequence for table %s: %w", rec.TableName, err)
+		}
+		return target.ReplaySetSequence(rec.TableName, sequence)
+	}
+	return fmt.Errorf("unknown schema record type %d", rec.Header.Type)
+}
+
 // GetAllOperations returns all operations sorted by LSN
 func (result *RecoveryResult) GetAllOperations() []WALRecord {
 	// Combine all operations
-	ops := make([]WALRecord, 0, len(result.InsertOps)+len(result.UpdateOps)+len(result.DeleteOps))
+	ops := make([]WALRecord, 0, len(result.InsertOps)+len(result.UpdateOps)+len(result.DeleteOps)+len(result.SchemaOps))
 
 	for _, op := range result.InsertOps {
 		ops = append(ops, op)
@@ -510,6 +712,9 @@ func (result *RecoveryResult) GetAllOperations() []WALRecord {
 	for _, op := range result.DeleteOps {
 		ops = append(ops, op)
 	}
+	for _, op := range result.SchemaOps {
+		ops = append(ops, op)
+	}
 
 	// Sort by LSN for correct replay order
 	sort.Slice(ops, func(i, j int) bool {
@@ -534,6 +739,8 @@ func getTxIDFromRecord(r WALRecord) uint64 {
 		return rec.TxID
 	case *DeleteRecord:
 		return rec.TxID
+	case *SchemaRecord:
+		return rec.TxID
 	case *CommitRecord:
 		return rec.TxID
 	case *AbortRecord:
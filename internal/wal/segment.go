@@ -0,0 +1,259 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ===========================================================================
+// WAL SEGMENTS
+// ===========================================================================
+//
+// A segmented WAL is a directory holding one active segment file. When a
+// commit takes the segment past its size, a new segment is started and the
+// full one is moved to the archive directory. Segments are named after the
+// LSN of their first record, so names sort in log order:
+//
+//   wal/00000000000000000412.wal        active segment
+//   archive/00000000000000000001.wal    archived segments
+//   archive/00000000000000000207.wal
+//
+// Segments are only switched between transactions, so a transaction never
+// spans two segments.
+//
+// ===========================================================================
+
+// SegmentExt is the file extension of WAL segments
+const SegmentExt = ".wal"
+
+// DefaultSegmentSize is the size past which a segment is archived (16MB)
+const DefaultSegmentSize = 16 * 1024 * 1024
+
+// SegmentName returns the file name of the segment starting at initialLSN
+func SegmentName(initialLSN uint64) string {
+	return fmt.Sprintf("%020d%s", initialLSN, SegmentExt)
+}
+
+// segmentInitialLSN parses the initial LSN out of a segment file name
+func segmentInitialLSN(path string) (uint64, bool) {
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, SegmentExt) {
+		return 0, false
+	}
+	lsn, err := strconv.ParseUint(strings.TrimSuffix(name, SegmentExt), 10, 64)
+	return lsn, err == nil
+}
+
+// ListSegments returns the paths of the segments in dir in log order
+// A directory that does not exist holds no segments.
+func ListSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := segmentInitialLSN(entry.Name()); ok {
+			segments = append(segments, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// OpenSegmented opens the segmented WAL in dir, moving segments that grow
+// past segmentSize to archiveDir
+// A new log continues after the last LSN of the archive, so the archive
+// stays one sequence of LSNs across restarts.
+func OpenSegmented(dir, archiveDir, dbName string, segmentSize uint64) (*WAL, error) {
+	for _, d := range []string{dir, archiveDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+		}
+	}
+
+	segments, err := ListSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	// A crash during a rotation can leave the full segment behind
+	for len(segments) > 1 {
+		if err := archiveSegment(segments[0], archiveDir); err != nil {
+			return nil, err
+		}
+		segments = segments[1:]
+	}
+
+	var path string
+	var initialLSN uint64
+	if len(segments) == 1 {
+		path = segments[0]
+		initialLSN, _ = segmentInitialLSN(path)
+	} else {
+		last, err := archivedLSN(archiveDir)
+		if err != nil {
+			return nil, err
+		}
+		initialLSN = last + 1
+		path = filepath.Join(dir, SegmentName(initialLSN))
+	}
+
+	w, err := openWAL(path, dbName, initialLSN)
+	if err != nil {
+		return nil, err
+	}
+	w.archiveDir = archiveDir
+	w.segmentSize = segmentSize
+	return w, nil
+}
+
+// archivedLSN returns the last LSN in the archive, 0 if it is empty
+func archivedLSN(archiveDir string) (uint64, error) {
+	segments, err := ListSegments(archiveDir)
+	if err != nil || len(segments) == 0 {
+		return 0, err
+	}
+	return LastLSN(segments[len(segments)-1])
+}
+
+// LastLSN returns the LSN of the last record of a WAL file, or the LSN
+// before its first one if it holds no records
+func LastLSN(path string) (uint64, error) {
+	reader, err := NewWALReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	header, err := reader.ReadFileHeader()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	last := header.InitialLSN - 1
+	for {
+		record, err := reader.ReadNextRecord()
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		last = record.GetHeader().LSN
+	}
+}
+
+// Rotate starts a new segment and archives the current one
+// It fails while a transaction is in progress.
+func (w *WAL) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.archiveDir == "" {
+		return fmt.Errorf("WAL %s is not segmented", w.walPath)
+	}
+	if len(w.activeTxns) > 0 {
+		return fmt.Errorf("cannot rotate WAL with %d transactions in progress", len(w.activeTxns))
+	}
+	return w.rotate()
+}
+
+// rotateIfFull rotates a segmented WAL whose segment has reached its size
+// once no transaction is in progress
+// Must be called with mutex held
+func (w *WAL) rotateIfFull() error {
+	if w.archiveDir == "" || w.segmentSize == 0 || w.currentOffset < w.segmentSize || len(w.activeTxns) > 0 {
+		return nil
+	}
+	return w.rotate()
+}
+
+// rotate starts a new segment at nextLSN, then archives the full one
+// A crash in between leaves both in the WAL directory, and OpenSegmented
+// archives the older one.
+// Must be called with mutex held
+func (w *WAL) rotate() error {
+	if err := w.flushAndSync(); err != nil {
+		return err
+	}
+
+	full, fullFile := w.walPath, w.file
+	path := filepath.Join(filepath.Dir(full), SegmentName(w.nextLSN))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment: %w", err)
+	}
+	w.file = file
+	w.walPath = path
+	w.buf.Reset(file)
+	if err := w.writeFileHeader(); err != nil {
+		return err
+	}
+
+	if err := fullFile.Close(); err != nil {
+		return err
+	}
+	return archiveSegment(full, w.archiveDir)
+}
+
+// archiveSegment moves a full segment to the archive directory
+// An archive on another file system gets a synced copy instead.
+func archiveSegment(path, archiveDir string) error {
+	dest := filepath.Join(archiveDir, filepath.Base(path))
+	if err := os.Rename(path, dest); err != nil {
+		if err := copySegment(path, dest); err != nil {
+			return fmt.Errorf("failed to archive WAL segment %s: %w", filepath.Base(path), err)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := syncDir(archiveDir); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// copySegment copies a segment file and syncs the copy
+func copySegment(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir syncs a directory so the files moved into it survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
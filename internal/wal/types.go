@@ -85,6 +85,11 @@ const (
 	RecordCommit
 	RecordAbort
 	RecordCheckpoint
+	RecordCreateTable
+	RecordDropTable
+	RecordCreateIndex
+	RecordDropIndex
+	RecordSetSequence
 )
 
 // String returns a human-readable name for the record type
@@ -104,6 +109,16 @@ func (rt RecordType) String() string {
 		return "Abort"
 	case RecordCheckpoint:
 		return "Checkpoint"
+	case RecordCreateTable:
+		return "CreateTable"
+	case RecordDropTable:
+		return "DropTable"
+	case RecordCreateIndex:
+		return "CreateIndex"
+	case RecordDropIndex:
+		return "DropIndex"
+	case RecordSetSequence:
+		return "SetSequence"
 	default:
 		return "Unknown"
 	}
@@ -127,7 +142,7 @@ type WALRecordHeader struct {
 	_          uint8      // Padding for alignment (1 byte) - offset 1
 	Length     uint32     // Total record length including header and padding - offset 2
 	LSN        uint64     // Log Sequence Number - monotonically increasing - offset 6
-	CRC32      uint32     // CRC32 checksum of payload (after header, before padding) - offset 14
+	CRC32      uint32     // CRC32 checksum of payload and padding (after header) - offset 14
 	FileOffset uint64     // Byte offset in WAL file where this record starts - offset 18
 	_          [6]byte    // Padding to reach 32 bytes - offset 26
 }
@@ -153,10 +168,11 @@ type BeginTxnRecord struct {
 }
 
 // CommitRecord marks a transaction as committed
-// Payload: TxID (8 bytes)
+// Payload: TxID (8 bytes) + Timestamp (8 bytes)
 type CommitRecord struct {
-	Header WALRecordHeader
-	TxID   uint64
+	Header    WALRecordHeader
+	TxID      uint64
+	Timestamp int64 // Unix nanoseconds of the commit (0 in records without one)
 }
 
 // AbortRecord marks a transaction as aborted/rolled back
@@ -203,6 +219,22 @@ type DeleteRecord struct {
 	OldValue  json.RawMessage // Deleted row data (for UNDO during abort)
 }
 
+// ===========================================================================
+// DDL RECORDS (Data Definition)
+// ===========================================================================
+
+// SchemaRecord logs a change to the schema of a database (REDO only): the
+// creation or removal of a table or an index, or the setting of a table's
+// auto-increment sequence, as given by the header type
+// Payload: TxID (8) + TableNameLen (2) + TableName + NameLen (2) + Name + DefinitionLen (4) + Definition
+type SchemaRecord struct {
+	Header     WALRecordHeader
+	TxID       uint64
+	TableName  string
+	Name       string          // Index name (CreateIndex, DropIndex)
+	Definition json.RawMessage // Table schema, index definition or sequence as JSON (CreateTable, CreateIndex, SetSequence)
+}
+
 // ===========================================================================
 // CHECKPOINT RECORD
 // ===========================================================================
@@ -283,3 +315,4 @@ func (r DeleteRecord) GetHeader() WALRecordHeader     { return r.Header }
 func (r CommitRecord) GetHeader() WALRecordHeader     { return r.Header }
 func (r AbortRecord) GetHeader() WALRecordHeader      { return r.Header }
 func (r CheckpointRecord) GetHeader() WALRecordHeader { return r.Header }
+func (r SchemaRecord) GetHeader() WALRecordHeader     { return r.Header }
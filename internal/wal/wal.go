@@ -3,6 +3,8 @@ package wal
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
@@ -16,6 +18,13 @@ type WAL struct {
 	walPath string        // Path to WAL file
 	dbName  string        // Database name this WAL belongs to
 
+	// Segment tracking (see segment.go); archiveDir is empty for a single file
+	archiveDir  string // Directory full segments are moved to
+	segmentSize uint64 // Size past which the segment is switched
+
+	// writerMu is held by a writer across changing data and logging it
+	writerMu sync.Mutex
+
 	// LSN tracking
 	nextLSN        uint64 // Next LSN to assign
 	flushedLSN     uint64 // Last LSN guaranteed to be fsynced to disk
@@ -30,9 +39,15 @@ type WAL struct {
 
 // NewWAL creates or opens a WAL at the specified path
 func NewWAL(walPath string, dbName string) (*WAL, error) {
-	// Check if WAL file exists
+	return openWAL(walPath, dbName, 1) // LSN starts at 1
+}
+
+// openWAL opens the WAL file at walPath, creating it with its first record
+// at initialLSN if it does not exist
+func openWAL(walPath string, dbName string, initialLSN uint64) (*WAL, error) {
+	// Check if WAL file exists (a crash can leave one without its header)
 	fileExists := false
-	if _, err := os.Stat(walPath); err == nil {
+	if info, err := os.Stat(walPath); err == nil && info.Size() > 0 {
 		fileExists = true
 	}
 
@@ -48,19 +63,15 @@ func NewWAL(walPath string, dbName string) (*WAL, error) {
 		walPath:    walPath,
 		dbName:     dbName,
 		activeTxns: make(map[uint64]*TxnState),
-		nextLSN:    1, // LSN starts at 1
-		flushedLSN: 0, // Nothing flushed yet
+		nextLSN:    initialLSN,
+		flushedLSN: initialLSN - 1, // Nothing flushed yet
 	}
 
 	if fileExists {
-		// TODO: Scan existing WAL to recover state (nextLSN, flushedLSN, activeTxns, currentOffset)
-		// For now, seek to end
-		offset, err := file.Seek(0, os.SEEK_END)
-		if err != nil {
+		if err := wal.recoverState(); err != nil {
 			file.Close()
-			return nil, fmt.Errorf("failed to seek to end of WAL: %w", err)
+			return nil, err
 		}
-		wal.currentOffset = uint64(offset)
 	} else {
 		// Write file header for new WAL
 		if err := wal.writeFileHeader(); err != nil {
@@ -72,6 +83,48 @@ func NewWAL(walPath string, dbName string) (*WAL, error) {
 	return wal, nil
 }
 
+// recoverState scans an existing WAL file to continue after its last record
+// A record cut short by a crash ends the log: the file is truncated to the
+// last complete record, which is where writing continues. Transactions left
+// without a Commit record stay uncommitted, so recovery skips them.
+func (w *WAL) recoverState() error {
+	reader := &WALReader{file: w.file, walPath: w.walPath}
+	header, err := reader.ReadFileHeader()
+	if err != nil {
+		return fmt.Errorf("failed to read WAL header: %w", err)
+	}
+	w.nextLSN = header.InitialLSN
+
+	for {
+		record, err := reader.ReadNextRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Warn("truncating WAL after its last complete record",
+				slog.String("path", w.walPath),
+				slog.Uint64("offset", reader.CurrentPosition()),
+				slog.Any("error", err))
+			if err := w.file.Truncate(int64(reader.CurrentPosition())); err != nil {
+				return fmt.Errorf("failed to truncate WAL: %w", err)
+			}
+			break
+		}
+		h := record.GetHeader()
+		w.nextLSN = h.LSN + 1
+		if h.Type == RecordCheckpoint {
+			w.lastCheckpoint = h.LSN
+		}
+	}
+
+	w.currentOffset = reader.CurrentPosition()
+	w.flushedLSN = w.nextLSN - 1
+	if _, err := w.file.Seek(int64(w.currentOffset), io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to end of WAL: %w", err)
+	}
+	return nil
+}
+
 // writeFileHeader writes the WAL file header
 func (w *WAL) writeFileHeader() error {
 	header := WALFileHeader{
@@ -240,3 +293,15 @@ func (w *WAL) allocateLSN() uint64 {
 	w.nextLSN++
 	return lsn
 }
+
+// LockWriter serializes writers: a writer holds it from changing data until
+// its changes are logged, so records reach the log in the order the changes
+// were made
+func (w *WAL) LockWriter() {
+	w.writerMu.Lock()
+}
+
+// UnlockWriter releases the lock taken by LockWriter
+func (w *WAL) UnlockWriter() {
+	w.writerMu.Unlock()
+}
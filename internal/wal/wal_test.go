@@ -1 +1,192 @@
 package wal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// logInsert writes a transaction inserting one row with the given key and
+// returns its commit LSN
+func logInsert(t *testing.T, w *WAL, key string) uint64 {
+	t.Helper()
+	txID := w.NextLSN()
+	if _, err := w.BeginTransaction(txID); err != nil {
+		t.Fatalf("BeginTransaction failed: %v", err)
+	}
+	value := json.RawMessage(`{"id": "` + key + `", "note": "padded to an odd length"}`)
+	if _, err := w.LogInsert(txID, "items", key, value); err != nil {
+		t.Fatalf("LogInsert failed: %v", err)
+	}
+	lsn, err := w.Commit(txID)
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	return lsn
+}
+
+// insertedKeys returns the keys of the insert operations of a recovery
+func insertedKeys(result *RecoveryResult) []string {
+	var keys []string
+	for _, op := range result.GetAllOperations() {
+		if insert, ok := op.(*InsertRecord); ok {
+			keys = append(keys, insert.Key)
+		}
+	}
+	return keys
+}
+
+func TestReopenContinuesLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wal")
+	w, err := NewWAL(path, "test")
+	if err != nil {
+		t.Fatalf("NewWAL failed: %v", err)
+	}
+	last := logInsert(t, w, "a")
+	w.Close()
+
+	// A record cut short by a crash is dropped on reopening
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{byte(RecordBeginTxn), 0, 40, 0})
+	f.Close()
+
+	w, err = NewWAL(path, "test")
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	if w.NextLSN() != last+1 {
+		t.Fatalf("Expected the reopened log to continue at LSN %d, got %d", last+1, w.NextLSN())
+	}
+	logInsert(t, w, "b")
+	w.Close()
+
+	reader, err := NewWALReader(path)
+	if err != nil {
+		t.Fatalf("NewWALReader failed: %v", err)
+	}
+	defer reader.Close()
+	records, err := reader.ScanAll()
+	if err != nil {
+		t.Fatalf("Expected the log to read back cleanly, got %v", err)
+	}
+	if len(records) != 6 {
+		t.Errorf("Expected 6 records, got %d", len(records))
+	}
+}
+
+func TestSegmentRotation(t *testing.T) {
+	dir := t.TempDir()
+	walDir, archiveDir := filepath.Join(dir, "wal"), filepath.Join(dir, "archive")
+
+	// Every commit fills a segment this small
+	w, err := OpenSegmented(walDir, archiveDir, "test", 64)
+	if err != nil {
+		t.Fatalf("OpenSegmented failed: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		logInsert(t, w, key)
+	}
+	next := w.NextLSN()
+	w.Close()
+
+	archived, _ := ListSegments(archiveDir)
+	if len(archived) != 3 {
+		t.Fatalf("Expected 3 archived segments, got %v", archived)
+	}
+	active, _ := ListSegments(walDir)
+	if len(active) != 1 || filepath.Base(active[0]) != SegmentName(next) {
+		t.Fatalf("Expected the active segment %s, got %v", SegmentName(next), active)
+	}
+
+	// A log whose active segment was lost continues after the archive
+	os.RemoveAll(walDir)
+	w, err = OpenSegmented(walDir, archiveDir, "test", 64)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	if w.NextLSN() != next {
+		t.Errorf("Expected the log to continue at LSN %d, got %d", next, w.NextLSN())
+	}
+	w.Close()
+}
+
+func TestRecoverUntil(t *testing.T) {
+	dir := t.TempDir()
+	walDir, archiveDir := filepath.Join(dir, "wal"), filepath.Join(dir, "archive")
+	w, err := OpenSegmented(walDir, archiveDir, "test", 200)
+	if err != nil {
+		t.Fatalf("OpenSegmented failed: %v", err)
+	}
+	commits := map[string]uint64{}
+	var middle time.Time
+	for _, key := range []string{"a", "b", "c", "d"} {
+		commits[key] = logInsert(t, w, key)
+		if key == "b" {
+			time.Sleep(10 * time.Millisecond)
+			middle = time.Now()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// A transaction never committed is not replayed
+	txID := w.NextLSN()
+	w.BeginTransaction(txID)
+	w.LogInsert(txID, "items", "e", json.RawMessage(`{}`))
+	w.Close()
+
+	archived, _ := ListSegments(archiveDir)
+	active, _ := ListSegments(walDir)
+	segments := append(archived, active...)
+	if len(archived) == 0 {
+		t.Fatal("Expected some segments to be archived")
+	}
+
+	recoverUntil := func(t *testing.T, afterLSN uint64, target RecoveryTarget) *RecoveryResult {
+		t.Helper()
+		result, err := NewSegmentRecoveryManager(segments, dir).RecoverUntil(afterLSN, target)
+		if err != nil {
+			t.Fatalf("RecoverUntil failed: %v", err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		afterLSN uint64
+		target   RecoveryTarget
+		keys     string
+		reached  bool
+	}{
+		{"Whole log", 0, RecoveryTarget{}, "abcd", false},
+		{"Up to an LSN", 0, RecoveryTarget{LSN: commits["c"]}, "abc", true},
+		{"Inside a transaction", 0, RecoveryTarget{LSN: commits["c"] - 1}, "ab", true},
+		{"Up to a time", 0, RecoveryTarget{Time: middle}, "ab", true},
+		{"After a base backup", commits["b"], RecoveryTarget{}, "cd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := recoverUntil(t, tt.afterLSN, tt.target)
+			keys := ""
+			for _, key := range insertedKeys(result) {
+				keys += key
+			}
+			if keys != tt.keys {
+				t.Errorf("Expected inserts %q, got %q", tt.keys, keys)
+			}
+			if result.TargetReached != tt.reached {
+				t.Errorf("Expected TargetReached %v, got %v", tt.reached, result.TargetReached)
+			}
+			if last := tt.keys[len(tt.keys)-1:]; result.StopLSN != commits[last] {
+				t.Errorf("Expected to stop at the commit of %s (LSN %d), got %d", last, commits[last], result.StopLSN)
+			}
+		})
+	}
+
+	t.Run("Missing segment", func(t *testing.T) {
+		gap := append([]string{segments[0]}, segments[2:]...)
+		if _, err := NewSegmentRecoveryManager(gap, dir).RecoverUntil(0, RecoveryTarget{}); err == nil {
+			t.Error("Expected a gap in the segments to fail recovery")
+		}
+	})
+}
@@ -120,6 +120,61 @@ func (w *WAL) LogDelete(txID uint64, tableName string, key string, oldValue json
 	return lsn, nil
 }
 
+// LogCreateTable writes a CreateTable record to the WAL, holding the table
+// schema as JSON
+// Returns the LSN assigned to this record
+func (w *WAL) LogCreateTable(txID uint64, tableName string, tableSchema json.RawMessage) (uint64, error) {
+	return w.logSchema(txID, RecordCreateTable, tableName, "", tableSchema)
+}
+
+// LogDropTable writes a DropTable record to the WAL
+// Returns the LSN assigned to this record
+func (w *WAL) LogDropTable(txID uint64, tableName string) (uint64, error) {
+	return w.logSchema(txID, RecordDropTable, tableName, "", nil)
+}
+
+// LogCreateIndex writes a CreateIndex record to the WAL, holding the index
+// definition as JSON
+// Returns the LSN assigned to this record
+func (w *WAL) LogCreateIndex(txID uint64, tableName string, indexName string, definition json.RawMessage) (uint64, error) {
+	return w.logSchema(txID, RecordCreateIndex, tableName, indexName, definition)
+}
+
+// LogDropIndex writes a DropIndex record to the WAL
+// Returns the LSN assigned to this record
+func (w *WAL) LogDropIndex(txID uint64, tableName string, indexName string) (uint64, error) {
+	return w.logSchema(txID, RecordDropIndex, tableName, indexName, nil)
+}
+
+// LogSetSequence writes a SetSequence record to the WAL, holding the
+// table's auto-increment sequence as JSON
+// Returns the LSN assigned to this record
+func (w *WAL) LogSetSequence(txID uint64, tableName string, sequence json.RawMessage) (uint64, error) {
+	return w.logSchema(txID, RecordSetSequence, tableName, "", sequence)
+}
+
+// logSchema writes a schema change record of the given type
+func (w *WAL) logSchema(txID uint64, recordType RecordType, tableName string, name string, definition json.RawMessage) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Verify transaction is active
+	if err := w.verifyActiveTxn(txID); err != nil {
+		return 0, err
+	}
+
+	// Encode payload: TxID + TableName + Name + Definition
+	payload := w.encodeSchemaPayload(txID, tableName, name, definition)
+
+	// Write record
+	lsn, err := w.writeRecord(recordType, payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s record: %w", recordType, err)
+	}
+
+	return lsn, nil
+}
+
 // Commit writes a Commit record to the WAL and fsyncs
 // This makes the transaction durable
 // Returns the LSN assigned to this record
@@ -132,9 +187,10 @@ func (w *WAL) Commit(txID uint64) (uint64, error) {
 		return 0, err
 	}
 
-	// Encode payload: TxID (8 bytes)
-	payload := make([]byte, 8)
-	ByteOrder.PutUint64(payload, txID)
+	// Encode payload: TxID (8 bytes) + Timestamp (8 bytes)
+	payload := make([]byte, 16)
+	ByteOrder.PutUint64(payload[0:8], txID)
+	ByteOrder.PutUint64(payload[8:16], uint64(time.Now().UnixNano()))
 
 	// Write record
 	lsn, err := w.writeRecord(RecordCommit, payload)
@@ -154,6 +210,11 @@ func (w *WAL) Commit(txID uint64) (uint64, error) {
 	w.activeTxns[txID].State = TxnCommitted
 	delete(w.activeTxns, txID)
 
+	// Segments are only switched between transactions
+	if err := w.rotateIfFull(); err != nil {
+		return lsn, fmt.Errorf("failed to rotate WAL segment: %w", err)
+	}
+
 	return lsn, nil
 }
 
@@ -236,15 +297,15 @@ func (w *WAL) writeRecord(recordType RecordType, payload []byte) (uint64, error)
 	// Allocate LSN
 	lsn := w.allocateLSN()
 
-	// Calculate CRC32 of payload
-	crc := crc32.ChecksumIEEE(payload)
-
 	// Calculate total length with alignment
 	payloadLen := len(payload)
 	totalLen := RecordHeaderSize + payloadLen
 	alignedLen := AlignTo8(totalLen)
 	paddingLen := alignedLen - totalLen
 
+	// Calculate CRC32 of payload and padding, which the reader cannot tell apart
+	crc := crc32.Update(crc32.ChecksumIEEE(payload), crc32.IEEETable, make([]byte, paddingLen))
+
 	// Build header
 	header := WALRecordHeader{
 		Type:       recordType,
@@ -413,6 +474,39 @@ func (w *WAL) encodeDeletePayload(txID uint64, tableName string, key string, old
 	return buf
 }
 
+// encodeSchemaPayload encodes the payload for a CreateTable, DropTable,
+// CreateIndex, DropIndex or SetSequence record
+// Format: TxID(8) + TableNameLen(2) + TableName + NameLen(2) + Name + DefinitionLen(4) + Definition
+func (w *WAL) encodeSchemaPayload(txID uint64, tableName string, name string, definition json.RawMessage) []byte {
+	// Calculate total size
+	size := 8 + 2 + len(tableName) + 2 + len(name) + 4 + len(definition)
+	buf := make([]byte, size)
+	offset := 0
+
+	// TxID (8 bytes)
+	ByteOrder.PutUint64(buf[offset:], txID)
+	offset += 8
+
+	// TableName with length prefix (2 bytes)
+	ByteOrder.PutUint16(buf[offset:], uint16(len(tableName)))
+	offset += 2
+	copy(buf[offset:], tableName)
+	offset += len(tableName)
+
+	// Name with length prefix (2 bytes)
+	ByteOrder.PutUint16(buf[offset:], uint16(len(name)))
+	offset += 2
+	copy(buf[offset:], name)
+	offset += len(name)
+
+	// Definition with length prefix (4 bytes)
+	ByteOrder.PutUint32(buf[offset:], uint32(len(definition)))
+	offset += 4
+	copy(buf[offset:], definition)
+
+	return buf
+}
+
 // encodeCheckpointPayload encodes the payload for a Checkpoint record
 // Format: CheckpointLSN(8) + CheckpointOffset(8) + LastFlushedLSN(8) + Timestamp(8) +
 //